@@ -26,7 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/beacon/types"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common/mclock"
-	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/urfave/cli/v2"
@@ -44,7 +44,10 @@ type Client struct {
 	engineClient *engineClient
 }
 
-func NewClient(ctx *cli.Context) *Client {
+// NewClient creates a beacon light client storing the committee chain and the
+// latest finalized header in the given database. If the database holds the
+// chain of a previous run, it is resumed without the need of a checkpoint.
+func NewClient(ctx *cli.Context, db ethdb.KeyValueStore) *Client {
 	if !ctx.IsSet(utils.BeaconApiFlag.Name) {
 		utils.Fatalf("Beacon node light client API URL not specified")
 	}
@@ -62,16 +65,22 @@ func NewClient(ctx *cli.Context) *Client {
 
 	// create data structures
 	var (
-		db             = memorydb.New()
 		threshold      = ctx.Int(utils.BeaconThresholdFlag.Name)
 		committeeChain = light.NewCommitteeChain(db, chainConfig.ChainConfig, threshold, !ctx.Bool(utils.BeaconNoFilterFlag.Name))
 		headTracker    = light.NewHeadTracker(committeeChain, threshold)
 	)
+	committeeChain.SetRetention(ctx.Uint64(utils.BeaconRetentionFlag.Name))
 	headSync := sync.NewHeadSync(headTracker, committeeChain)
 
+	// the finalized header of a previous run is a more recent checkpoint than
+	// the built-in one, in case the committee chain has to be resynced
+	checkpoint := chainConfig.Checkpoint
+	if header, ok := light.ReadFinalizedHeader(db); ok && !ctx.IsSet(utils.BeaconCheckpointFlag.Name) {
+		checkpoint = header.Hash()
+	}
 	// set up scheduler and sync modules
 	scheduler := request.NewScheduler()
-	checkpointInit := sync.NewCheckpointInit(committeeChain, chainConfig.Checkpoint)
+	checkpointInit := sync.NewCheckpointInit(committeeChain, checkpoint)
 	forwardSync := sync.NewForwardUpdateSync(committeeChain)
 	beaconBlockSync := newBeaconBlockSync(headTracker)
 	scheduler.RegisterTarget(headTracker)
@@ -94,6 +103,12 @@ func (c *Client) SetEngineRPC(engine *rpc.Client) {
 	c.engineRPC = engine
 }
 
+// SubscribeChainHead subscribes to the verified execution blocks belonging to
+// the beacon chain heads.
+func (c *Client) SubscribeChainHead(ch chan<- types.ChainHeadEvent) event.Subscription {
+	return c.blockSync.SubscribeChainHead(ch)
+}
+
 func (c *Client) Start() error {
 	headCh := make(chan types.ChainHeadEvent, 16)
 	c.chainHeadSub = c.blockSync.SubscribeChainHead(headCh)
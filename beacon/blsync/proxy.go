@@ -0,0 +1,634 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	ctypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
+)
+
+const (
+	proxyBlockCache  = 128             // Number of recent verified blocks the proxy answers queries about
+	proxyCallGasCap  = 50_000_000      // Gas cap of the locally executed calls
+	proxyCallTimeout = 5 * time.Second // Execution timeout of the locally executed calls
+)
+
+var (
+	errBlockNotVerified = errors.New("block not verified by the light client")
+	errCallDisabled     = errors.New("eth_call requires a known execution chain config")
+)
+
+// Proxy serves the state queries of wallets by retrieving the data from an
+// untrusted upstream RPC endpoint. Every answer is checked with Merkle proofs
+// against the state root of an execution block verified by the light client,
+// calls are executed locally on the proven state.
+type Proxy struct {
+	upstream *rpc.Client
+	config   *params.ChainConfig // Execution chain config, nil disables eth_call
+
+	lock      sync.RWMutex
+	blocks    map[common.Hash]*ctypes.Block // Recent verified blocks by hash
+	canonical map[uint64]common.Hash        // Hashes of the blocks in the chain of the head
+	head      *ctypes.Block
+	finalized common.Hash
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewProxy creates a verifying proxy in front of the given upstream endpoint.
+func NewProxy(upstream *rpc.Client, config *params.ChainConfig) *Proxy {
+	return &Proxy{
+		upstream:  upstream,
+		config:    config,
+		blocks:    make(map[common.Hash]*ctypes.Block),
+		canonical: make(map[uint64]common.Hash),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start starts tracking the verified execution blocks delivered on the channel.
+func (p *Proxy) Start(headCh <-chan types.ChainHeadEvent) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case event := <-headCh:
+				p.addHead(event)
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops tracking the verified execution blocks.
+func (p *Proxy) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// APIs returns the RPC services served by the proxy.
+func (p *Proxy) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "eth",
+		Service:   &ProxyAPI{proxy: p},
+	}}
+}
+
+// addHead adds a new verified head block and drops the blocks which are too
+// old to be served.
+func (p *Proxy) addHead(event types.ChainHeadEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	block := event.Block
+	p.blocks[block.Hash()] = block
+	p.head = block
+	p.finalized = event.Finalized
+
+	// Reorgs may leave stale mappings above the head and, if the parent is
+	// known, below it as well
+	number := block.NumberU64()
+	for n := range p.canonical {
+		if n > number {
+			delete(p.canonical, n)
+		}
+	}
+	for parent := block; parent != nil; parent = p.blocks[parent.ParentHash()] {
+		if p.canonical[parent.NumberU64()] == parent.Hash() {
+			break
+		}
+		p.canonical[parent.NumberU64()] = parent.Hash()
+	}
+	if number >= proxyBlockCache {
+		for hash, b := range p.blocks {
+			if b.NumberU64() <= number-proxyBlockCache {
+				delete(p.blocks, hash)
+			}
+		}
+		for n := range p.canonical {
+			if n <= number-proxyBlockCache {
+				delete(p.canonical, n)
+			}
+		}
+	}
+	log.Debug("Proxy head updated", "number", number, "hash", block.Hash(), "finalized", event.Finalized)
+}
+
+// block resolves a block reference to one of the recent verified blocks.
+func (p *Proxy) block(blockNrOrHash rpc.BlockNumberOrHash) (*ctypes.Block, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.head == nil {
+		return nil, errors.New("no verified head yet")
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block := p.blocks[hash]
+		if block == nil {
+			return nil, errBlockNotVerified
+		}
+		if blockNrOrHash.RequireCanonical && p.canonical[block.NumberU64()] != hash {
+			return nil, errors.New("hash is not currently canonical")
+		}
+		return block, nil
+	}
+	number, _ := blockNrOrHash.Number()
+	switch number {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		return p.head, nil
+	case rpc.FinalizedBlockNumber, rpc.SafeBlockNumber:
+		if block := p.blocks[p.finalized]; block != nil {
+			return block, nil
+		}
+		return nil, errors.New("finalized block not verified yet")
+	case rpc.EarliestBlockNumber:
+		return nil, errBlockNotVerified
+	}
+	if block := p.blocks[p.canonical[uint64(number)]]; block != nil {
+		return block, nil
+	}
+	return nil, errBlockNotVerified
+}
+
+// GetHeader implements core.ChainContext, serving the verified headers to the
+// BLOCKHASH opcode.
+func (p *Proxy) GetHeader(hash common.Hash, number uint64) *ctypes.Header {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if block := p.blocks[hash]; block != nil && block.NumberU64() == number {
+		return block.Header()
+	}
+	return nil
+}
+
+// Engine implements core.ChainContext. The coinbase of the blocks is always
+// set explicitly, so no consensus engine is needed.
+func (p *Proxy) Engine() consensus.Engine {
+	return nil
+}
+
+// proofResult is the upstream response to eth_getProof.
+type proofResult = ethapi.AccountResult
+
+// proveAccounts retrieves the proofs of the given accounts and storage slots
+// in a single batch and verifies them against the state root of the block.
+func (p *Proxy) proveAccounts(ctx context.Context, block *ctypes.Block, addresses []common.Address, slots [][]common.Hash) ([]*provenAccount, error) {
+	var (
+		batch   = make([]rpc.BatchElem, len(addresses))
+		results = make([]proofResult, len(addresses))
+		ref     = rpc.BlockNumberOrHashWithHash(block.Hash(), false)
+	)
+	for i, address := range addresses {
+		keys := make([]string, len(slots[i]))
+		for j, slot := range slots[i] {
+			keys[j] = slot.Hex()
+		}
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getProof",
+			Args:   []interface{}{address, keys, ref},
+			Result: &results[i],
+		}
+	}
+	if err := p.upstream.BatchCallContext(ctx, batch); err != nil {
+		return nil, err
+	}
+	accounts := make([]*provenAccount, len(addresses))
+	for i, elem := range batch {
+		if elem.Error != nil {
+			return nil, fmt.Errorf("proof of %x: %w", addresses[i], elem.Error)
+		}
+		account, err := verifyAccountProof(block.Root(), addresses[i], slots[i], &results[i])
+		if err != nil {
+			return nil, fmt.Errorf("proof of %x: %w", addresses[i], err)
+		}
+		accounts[i] = account
+	}
+	return accounts, nil
+}
+
+// provenAccount is an account and some of its storage slots proven against a
+// state root, along with the proof nodes.
+type provenAccount struct {
+	address common.Address
+	account *ctypes.StateAccount
+	storage map[common.Hash]common.Hash
+	proof   *trienode.ProofSet // Trie nodes of the account and storage proofs
+}
+
+// verifyAccountProof checks the account and storage proofs of a proof result.
+// The values are taken from the proofs, the plain fields of the result are
+// only used as a cross-check.
+func verifyAccountProof(root common.Hash, address common.Address, slots []common.Hash, result *proofResult) (*provenAccount, error) {
+	if len(result.StorageProof) != len(slots) {
+		return nil, fmt.Errorf("storage proof count mismatch: have %d, want %d", len(result.StorageProof), len(slots))
+	}
+	proof := trienode.NewProofSet()
+	accountProof, err := decodeProof(result.AccountProof, proof)
+	if err != nil {
+		return nil, err
+	}
+	value, err := trie.VerifyProof(root, crypto.Keccak256(address.Bytes()), accountProof)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %w", err)
+	}
+	account := &ctypes.StateAccount{
+		Balance:  new(uint256.Int),
+		Root:     ctypes.EmptyRootHash,
+		CodeHash: ctypes.EmptyCodeHash.Bytes(),
+	}
+	if value != nil {
+		if err := rlp.DecodeBytes(value, account); err != nil {
+			return nil, fmt.Errorf("invalid account: %w", err)
+		}
+	}
+	if result.Balance == nil || result.Balance.ToInt().Cmp(account.Balance.ToBig()) != 0 || uint64(result.Nonce) != account.Nonce {
+		return nil, errors.New("account fields don't match the proof")
+	}
+	proven := &provenAccount{
+		address: address,
+		account: account,
+		storage: make(map[common.Hash]common.Hash, len(slots)),
+		proof:   proof,
+	}
+	for i, slot := range slots {
+		if account.Root == ctypes.EmptyRootHash {
+			proven.storage[slot] = common.Hash{}
+			continue
+		}
+		storageProof, err := decodeProof(result.StorageProof[i].Proof, proof)
+		if err != nil {
+			return nil, err
+		}
+		value, err := trie.VerifyProof(account.Root, crypto.Keccak256(slot.Bytes()), storageProof)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage proof of slot %x: %w", slot, err)
+		}
+		var content []byte
+		if value != nil {
+			if _, content, _, err = rlp.Split(value); err != nil {
+				return nil, fmt.Errorf("invalid storage value of slot %x: %w", slot, err)
+			}
+		}
+		proven.storage[slot] = common.BytesToHash(content)
+	}
+	return proven, nil
+}
+
+// decodeProof decodes a list of hex encoded trie nodes into a proof set, also
+// adding them to the given set of all nodes.
+func decodeProof(nodes []string, all *trienode.ProofSet) (*trienode.ProofSet, error) {
+	proof := trienode.NewProofSet()
+	for _, node := range nodes {
+		blob, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof node: %w", err)
+		}
+		hash := crypto.Keccak256(blob)
+		proof.Put(hash, blob)
+		all.Put(hash, blob)
+	}
+	return proof, nil
+}
+
+// code retrieves the code of a proven account and checks it against the code
+// hash of the account.
+func (p *Proxy) code(ctx context.Context, block *ctypes.Block, account *provenAccount) ([]byte, error) {
+	codeHash := common.BytesToHash(account.account.CodeHash)
+	if codeHash == ctypes.EmptyCodeHash {
+		return nil, nil
+	}
+	var code hexutil.Bytes
+	if err := p.upstream.CallContext(ctx, &code, "eth_getCode", account.address, rpc.BlockNumberOrHashWithHash(block.Hash(), false)); err != nil {
+		return nil, err
+	}
+	if crypto.Keccak256Hash(code) != codeHash {
+		return nil, fmt.Errorf("code of %x doesn't match the code hash", account.address)
+	}
+	return code, nil
+}
+
+// call executes a call locally on the state of the given block, which is built
+// from the proofs of the accounts and storage slots accessed by the call. The
+// accessed state is learnt from the upstream, an incomplete list results in
+// missing trie nodes and the failure of the call.
+func (p *Proxy) call(ctx context.Context, args ethapi.TransactionArgs, block *ctypes.Block) (*core.ExecutionResult, error) {
+	if p.config == nil {
+		return nil, errCallDisabled
+	}
+	var (
+		header = block.Header()
+		ref    = rpc.BlockNumberOrHashWithHash(block.Hash(), false)
+	)
+	var accessList struct {
+		AccessList ctypes.AccessList `json:"accessList"`
+	}
+	if err := p.upstream.CallContext(ctx, &accessList, "eth_createAccessList", args, ref); err != nil {
+		return nil, fmt.Errorf("failed to retrieve the accessed state: %w", err)
+	}
+	// The sender, the recipient, the coinbase and the precompiles are left out
+	// of the access lists, but touched by the execution
+	var (
+		addresses []common.Address
+		slots     [][]common.Hash
+		included  = make(map[common.Address]int)
+	)
+	add := func(address common.Address, keys ...common.Hash) {
+		index, ok := included[address]
+		if !ok {
+			index = len(addresses)
+			included[address] = index
+			addresses = append(addresses, address)
+			slots = append(slots, nil)
+		}
+		slots[index] = append(slots[index], keys...)
+	}
+	for _, tuple := range accessList.AccessList {
+		add(tuple.Address, tuple.StorageKeys...)
+	}
+	if args.From != nil {
+		add(*args.From)
+	} else {
+		add(common.Address{})
+	}
+	if args.To != nil {
+		add(*args.To)
+	}
+	add(header.Coinbase)
+	rules := p.config.Rules(header.Number, true, header.Time)
+	for _, address := range vm.ActivePrecompiles(rules) {
+		add(address)
+	}
+	accounts, err := p.proveAccounts(ctx, block, addresses, slots)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble the proven part of the state and execute the call on it
+	db := rawdb.NewMemoryDatabase()
+	for _, account := range accounts {
+		account.proof.Store(db)
+		code, err := p.code(ctx, block, account)
+		if err != nil {
+			return nil, err
+		}
+		if code != nil {
+			rawdb.WriteCode(db, crypto.Keccak256Hash(code), code)
+		}
+	}
+	statedb, err := state.New(header.Root, state.NewDatabaseWithConfig(db, triedb.HashDefaults), nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, proxyCallTimeout)
+	defer cancel()
+
+	blockCtx := core.NewEVMBlockContext(header, p, &header.Coinbase)
+	if err := args.CallDefaults(proxyCallGasCap, blockCtx.BaseFee, p.config.ChainID); err != nil {
+		return nil, err
+	}
+	msg := args.ToMessage(blockCtx.BaseFee)
+	evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, p.config, vm.Config{NoBaseFee: true})
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err := statedb.Error(); err != nil {
+		return nil, fmt.Errorf("incomplete state proofs: %w", err)
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", proxyCallTimeout)
+	}
+	if err != nil {
+		return result, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+	}
+	return result, nil
+}
+
+// receipt retrieves the receipt of a transaction included in one of the recent
+// verified blocks. The receipts of the whole block are retrieved and checked
+// against the receipt root, the derived fields are recomputed locally.
+func (p *Proxy) receipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	var located *struct {
+		BlockHash common.Hash `json:"blockHash"`
+	}
+	if err := p.upstream.CallContext(ctx, &located, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if located == nil {
+		return nil, nil
+	}
+	block, err := p.block(rpc.BlockNumberOrHashWithHash(located.BlockHash, false))
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for i, tx := range block.Transactions() {
+		if tx.Hash() == hash {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.New("transaction not included in the block")
+	}
+	var receipts ctypes.Receipts
+	if err := p.upstream.CallContext(ctx, &receipts, "eth_getBlockReceipts", rpc.BlockNumberOrHashWithHash(block.Hash(), false)); err != nil {
+		return nil, err
+	}
+	if receipts.Len() != block.Transactions().Len() {
+		return nil, errors.New("receipt count mismatch")
+	}
+	header := block.Header()
+	var blobGasPrice *big.Int
+	if header.ExcessBlobGas != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*header.ExcessBlobGas)
+	}
+	if err := receipts.DeriveFields(p.signerConfig(block), block.Hash(), block.NumberU64(), block.Time(), block.BaseFee(), blobGasPrice, block.Transactions()); err != nil {
+		return nil, err
+	}
+	if root := ctypes.DeriveSha(receipts, trie.NewStackTrie(nil)); root != block.ReceiptHash() {
+		return nil, fmt.Errorf("receipt root mismatch: have %x, want %x", root, block.ReceiptHash())
+	}
+	tx := block.Transactions()[index]
+	signer := ctypes.MakeSigner(p.signerConfig(block), block.Number(), block.Time())
+	return ethapi.MarshalReceipt(receipts[index], block.Hash(), block.NumberU64(), signer, tx, index), nil
+}
+
+// signerConfig returns the chain config used for recovering the senders of the
+// transactions. Without a known execution chain config, the latest signer is
+// used with the chain ID of the transactions.
+func (p *Proxy) signerConfig(block *ctypes.Block) *params.ChainConfig {
+	if p.config != nil {
+		return p.config
+	}
+	config := *params.AllDevChainProtocolChanges
+	config.ChainID = new(big.Int)
+	for _, tx := range block.Transactions() {
+		if tx.Protected() {
+			config.ChainID = tx.ChainId()
+			break
+		}
+	}
+	return &config
+}
+
+// ProxyAPI is the eth namespace of the verifying proxy.
+type ProxyAPI struct {
+	proxy *Proxy
+}
+
+// ChainId returns the chain ID of the execution chain.
+func (api *ProxyAPI) ChainId() (*hexutil.Big, error) {
+	if api.proxy.config == nil {
+		return nil, errors.New("unknown execution chain config")
+	}
+	return (*hexutil.Big)(api.proxy.config.ChainID), nil
+}
+
+// BlockNumber returns the number of the latest verified block.
+func (api *ProxyAPI) BlockNumber() (hexutil.Uint64, error) {
+	block, err := api.proxy.block(rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(block.NumberU64()), nil
+}
+
+// GetBalance returns the proven balance of an account at the given block.
+func (api *ProxyAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	account, _, err := api.prove(ctx, address, nil, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(account.account.Balance.ToBig()), nil
+}
+
+// GetStorageAt returns the proven value of a storage slot at the given block.
+func (api *ProxyAPI) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeHash(hexKey)
+	if err != nil {
+		return nil, err
+	}
+	account, _, err := api.prove(ctx, address, []common.Hash{key}, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	value := account.storage[key]
+	return value[:], nil
+}
+
+// GetCode returns the code of an account at the given block, checked against
+// the proven code hash.
+func (api *ProxyAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	account, block, err := api.prove(ctx, address, nil, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return api.proxy.code(ctx, block, account)
+}
+
+// Call executes the given transaction locally on the proven state of the given
+// block.
+func (api *ProxyAPI) Call(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	block, err := api.proxy.block(*blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.proxy.call(ctx, args, block)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Revert()) > 0 {
+		return nil, ethapi.NewRevertError(result.Revert())
+	}
+	return result.Return(), result.Err
+}
+
+// GetTransactionReceipt returns the verified receipt of a transaction included
+// in one of the recent verified blocks. The absence of a transaction can't be
+// proven, nil is returned if the upstream doesn't know it.
+func (api *ProxyAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	return api.proxy.receipt(ctx, hash)
+}
+
+// prove resolves the block reference and retrieves the proven account.
+func (api *ProxyAPI) prove(ctx context.Context, address common.Address, slots []common.Hash, blockNrOrHash rpc.BlockNumberOrHash) (*provenAccount, *ctypes.Block, error) {
+	block, err := api.proxy.block(blockNrOrHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	accounts, err := api.proxy.proveAccounts(ctx, block, []common.Address{address}, [][]common.Hash{slots})
+	if err != nil {
+		return nil, nil, err
+	}
+	return accounts[0], block, nil
+}
+
+// decodeHash parses a hex-encoded storage slot of up to 32 bytes. The input may
+// optionally be prefixed by 0x.
+func decodeHash(s string) (common.Hash, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if (len(s) & 1) > 0 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return common.Hash{}, errors.New("hex string invalid")
+	}
+	if len(b) > 32 {
+		return common.Hash{}, errors.New("hex string too long, want at most 32 bytes")
+	}
+	return common.BytesToHash(b), nil
+}
@@ -0,0 +1,297 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	ctypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	proxyTestKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	proxyTestAddr    = crypto.PubkeyToAddress(proxyTestKey.PublicKey)
+	proxyTestTarget  = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	proxyTestCode    = common.FromHex("0x60005460005260206000f3") // return sload(0)
+	proxyTestStorage = common.BigToHash(big.NewInt(42))
+)
+
+// testUpstream is an upstream RPC endpoint serving the state of a generated
+// chain, with optional manipulation of its answers.
+type testUpstream struct {
+	db         ethdb.Database
+	blocks     map[common.Hash]*ctypes.Block
+	receipts   map[common.Hash]ctypes.Receipts
+	accessList ctypes.AccessList
+	tamper     func(result *ethapi.AccountResult)
+}
+
+// proofList collects the hex encoded trie nodes of a proof.
+type proofList []string
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, hexutil.Encode(value))
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+func (u *testUpstream) block(ref rpc.BlockNumberOrHash) (*ctypes.Block, error) {
+	hash, _ := ref.Hash()
+	if block := u.blocks[hash]; block != nil {
+		return block, nil
+	}
+	return nil, errors.New("unknown block")
+}
+
+func (u *testUpstream) GetProof(address common.Address, keys []string, ref rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	block, err := u.block(ref)
+	if err != nil {
+		return nil, err
+	}
+	sdb := state.NewDatabase(u.db)
+	statedb, err := state.New(block.Root(), sdb, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := &ethapi.AccountResult{
+		Address:     address,
+		Balance:     (*hexutil.Big)(statedb.GetBalance(address).ToBig()),
+		CodeHash:    statedb.GetCodeHash(address),
+		Nonce:       hexutil.Uint64(statedb.GetNonce(address)),
+		StorageHash: statedb.GetStorageRoot(address),
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(block.Root()), sdb.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	var accountProof proofList
+	if err := tr.Prove(crypto.Keccak256(address.Bytes()), &accountProof); err != nil {
+		return nil, err
+	}
+	result.AccountProof = accountProof
+	for _, key := range keys {
+		slot := common.HexToHash(key)
+		storageProof := proofList{}
+		if root := statedb.GetStorageRoot(address); root != (common.Hash{}) && root != ctypes.EmptyRootHash {
+			st, err := trie.NewStateTrie(trie.StorageTrieID(block.Root(), crypto.Keccak256Hash(address.Bytes()), root), sdb.TrieDB())
+			if err != nil {
+				return nil, err
+			}
+			if err := st.Prove(crypto.Keccak256(slot.Bytes()), &storageProof); err != nil {
+				return nil, err
+			}
+		}
+		value := statedb.GetState(address, slot)
+		result.StorageProof = append(result.StorageProof, ethapi.StorageResult{Key: key, Value: (*hexutil.Big)(value.Big()), Proof: storageProof})
+	}
+	if u.tamper != nil {
+		u.tamper(result)
+	}
+	return result, nil
+}
+
+func (u *testUpstream) GetCode(address common.Address, ref rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	block, err := u.block(ref)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabase(u.db), nil)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(address), nil
+}
+
+func (u *testUpstream) CreateAccessList(args ethapi.TransactionArgs, ref rpc.BlockNumberOrHash) (map[string]interface{}, error) {
+	return map[string]interface{}{"accessList": u.accessList, "gasUsed": hexutil.Uint64(0)}, nil
+}
+
+func (u *testUpstream) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
+	for blockHash, receipts := range u.receipts {
+		for _, receipt := range receipts {
+			if receipt.TxHash == hash {
+				return map[string]interface{}{"blockHash": blockHash, "transactionHash": hash}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (u *testUpstream) GetBlockReceipts(ref rpc.BlockNumberOrHash) (ctypes.Receipts, error) {
+	block, err := u.block(ref)
+	if err != nil {
+		return nil, err
+	}
+	receipts := u.receipts[block.Hash()]
+	for _, receipt := range receipts {
+		if receipt.Logs == nil {
+			receipt.Logs = []*ctypes.Log{}
+		}
+	}
+	return receipts, nil
+}
+
+// newTestProxy generates a chain with a transfer and a contract, and creates a
+// proxy which verified all its blocks.
+func newTestProxy(t *testing.T) (*ProxyAPI, *testUpstream, []*ctypes.Block) {
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: ctypes.GenesisAlloc{
+			proxyTestAddr: {Balance: big.NewInt(params.Ether)},
+			proxyTestTarget: {
+				Code:    proxyTestCode,
+				Storage: map[common.Hash]common.Hash{{}: proxyTestStorage},
+			},
+		},
+	}
+	signer := ctypes.LatestSigner(gspec.Config)
+	db, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			tx, _ := ctypes.SignNewTx(proxyTestKey, signer, &ctypes.LegacyTx{
+				Nonce:    0,
+				To:       &common.Address{0x22},
+				Value:    big.NewInt(1000),
+				Gas:      params.TxGas,
+				GasPrice: b.BaseFee(),
+			})
+			b.AddTx(tx)
+		}
+	})
+	upstream := &testUpstream{
+		db:       db,
+		blocks:   make(map[common.Hash]*ctypes.Block),
+		receipts: make(map[common.Hash]ctypes.Receipts),
+	}
+	for i, block := range blocks {
+		upstream.blocks[block.Hash()] = block
+		upstream.receipts[block.Hash()] = receipts[i]
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", upstream); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Stop)
+
+	proxy := NewProxy(rpc.DialInProc(server), params.TestChainConfig)
+	for _, block := range blocks {
+		proxy.addHead(types.ChainHeadEvent{Block: block, Finalized: blocks[0].Hash()})
+	}
+	return &ProxyAPI{proxy: proxy}, upstream, blocks
+}
+
+func TestProxyState(t *testing.T) {
+	api, upstream, blocks := newTestProxy(t)
+	var (
+		ctx    = context.Background()
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	)
+	balance, err := api.GetBalance(ctx, common.Address{0x22}, latest)
+	if err != nil {
+		t.Fatalf("Failed to retrieve balance: %v", err)
+	}
+	if balance.ToInt().Int64() != 1000 {
+		t.Fatalf("Balance mismatch: have %v, want 1000", balance)
+	}
+	// The genesis block is unknown to the proxy
+	if _, err := api.GetBalance(ctx, proxyTestAddr, rpc.BlockNumberOrHashWithNumber(0)); !errors.Is(err, errBlockNotVerified) {
+		t.Fatalf("Unverified block served: %v", err)
+	}
+	value, err := api.GetStorageAt(ctx, proxyTestTarget, "0x0", rpc.BlockNumberOrHashWithNumber(rpc.FinalizedBlockNumber))
+	if err != nil {
+		t.Fatalf("Failed to retrieve storage: %v", err)
+	}
+	if common.BytesToHash(value) != proxyTestStorage {
+		t.Fatalf("Storage mismatch: have %x, want %x", value, proxyTestStorage)
+	}
+	code, err := api.GetCode(ctx, proxyTestTarget, rpc.BlockNumberOrHashWithHash(blocks[1].Hash(), false))
+	if err != nil {
+		t.Fatalf("Failed to retrieve code: %v", err)
+	}
+	if !bytes.Equal(code, proxyTestCode) {
+		t.Fatalf("Code mismatch: have %x, want %x", code, proxyTestCode)
+	}
+	// Manipulated answers must be rejected
+	upstream.tamper = func(result *ethapi.AccountResult) {
+		result.Balance = (*hexutil.Big)(big.NewInt(1))
+	}
+	if _, err := api.GetBalance(ctx, common.Address{0x22}, latest); err == nil {
+		t.Fatal("Manipulated balance accepted")
+	}
+	upstream.tamper = func(result *ethapi.AccountResult) {
+		result.StorageProof[0].Proof = result.AccountProof
+	}
+	if _, err := api.GetStorageAt(ctx, proxyTestTarget, "0x0", latest); err == nil {
+		t.Fatal("Manipulated storage proof accepted")
+	}
+}
+
+func TestProxyCall(t *testing.T) {
+	api, upstream, _ := newTestProxy(t)
+	var (
+		ctx  = context.Background()
+		args = ethapi.TransactionArgs{From: &proxyTestAddr, To: &proxyTestTarget}
+	)
+	// Without the accessed slot the state is incomplete
+	if _, err := api.Call(ctx, args, nil); err == nil {
+		t.Fatal("Call succeeded on incomplete state")
+	}
+	upstream.accessList = ctypes.AccessList{{Address: proxyTestTarget, StorageKeys: []common.Hash{{}}}}
+	result, err := api.Call(ctx, args, nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if common.BytesToHash(result) != proxyTestStorage {
+		t.Fatalf("Call result mismatch: have %x, want %x", result, proxyTestStorage)
+	}
+}
+
+func TestProxyReceipt(t *testing.T) {
+	api, upstream, blocks := newTestProxy(t)
+	tx := blocks[0].Transactions()[0]
+
+	receipt, err := api.GetTransactionReceipt(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve receipt: %v", err)
+	}
+	if receipt["blockHash"] != blocks[0].Hash() || receipt["from"] != proxyTestAddr || receipt["status"] != hexutil.Uint(ctypes.ReceiptStatusSuccessful) {
+		t.Fatalf("Invalid receipt: %v", receipt)
+	}
+	// Manipulated receipts must be rejected
+	upstream.receipts[blocks[0].Hash()][0].CumulativeGasUsed++
+	if _, err := api.GetTransactionReceipt(context.Background(), tx.Hash()); err == nil {
+		t.Fatal("Manipulated receipt accepted")
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/donovanhide/eventsource"
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const fakeEventChannel = "events"
+
+// FakeServer is a local beacon node REST API endpoint serving canned responses.
+// It is intended for testing light clients against one or more (possibly
+// misbehaving) beacon API servers.
+type FakeServer struct {
+	server *httptest.Server
+	events *eventsource.Server
+
+	lock      sync.Mutex
+	updates   map[uint64][]byte
+	responses map[string][]byte
+	status    map[string]int
+	requests  map[string]int
+	delay     time.Duration
+	eventID   int
+}
+
+// NewFakeServer creates and starts a fake beacon API server.
+func NewFakeServer() *FakeServer {
+	s := &FakeServer{
+		events:    eventsource.NewServer(),
+		updates:   make(map[uint64][]byte),
+		responses: make(map[string][]byte),
+		status:    make(map[string]int),
+		requests:  make(map[string]int),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eth/v1/events", s.events.Handler(fakeEventChannel))
+	mux.HandleFunc(updatesPath, s.serveUpdates)
+	mux.HandleFunc("/", s.serve)
+	s.server = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the base URL of the server.
+func (s *FakeServer) URL() string {
+	return s.server.URL
+}
+
+// Close shuts down the server.
+func (s *FakeServer) Close() {
+	s.events.Close()
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// SetDelay sets the delay applied before answering each request.
+func (s *FakeServer) SetDelay(delay time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.delay = delay
+}
+
+// SetResponse sets the raw response served at the given path.
+func (s *FakeServer) SetResponse(path string, response []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.responses[path] = response
+}
+
+// SetStatus sets the HTTP status code returned at the given path. Non-200 codes
+// are returned without a response body.
+func (s *FakeServer) SetStatus(path string, status int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.status[path] = status
+}
+
+// RequestCount returns the number of requests received at the given path.
+func (s *FakeServer) RequestCount(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.requests[path]
+}
+
+func (s *FakeServer) serve(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	s.lock.Lock()
+	s.requests[path]++
+	var (
+		delay         = s.delay
+		status, isSet = s.status[path]
+		response, ok  = s.responses[path]
+	)
+	s.lock.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return
+		}
+	}
+	switch {
+	case isSet && status != http.StatusOK:
+		w.WriteHeader(status)
+	case !ok:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}
+}
+
+// serveUpdates serves the available committee updates of the requested range.
+func (s *FakeServer) serveUpdates(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	s.requests[updatesPath]++
+	var (
+		query       = req.URL.Query()
+		start, err1 = strconv.ParseUint(query.Get("start_period"), 10, 64)
+		count, err2 = strconv.ParseUint(query.Get("count"), 10, 64)
+		updates     []json.RawMessage
+	)
+	for period := start; period < start+count && err1 == nil && err2 == nil; period++ {
+		enc, ok := s.updates[period]
+		if !ok {
+			break
+		}
+		updates = append(updates, enc)
+	}
+	s.lock.Unlock()
+
+	if len(updates) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	enc, _ := json.Marshal(updates)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(enc)
+}
+
+func (s *FakeServer) setJSON(path string, v any) {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	s.SetResponse(path, enc)
+}
+
+// SetHeader makes the given header available at its block root. If head is true
+// then it is also served as the current head.
+func (s *FakeServer) SetHeader(header types.Header, canonical, finalized, head bool) {
+	var data struct {
+		Finalized bool `json:"finalized"`
+		Data      struct {
+			Root      common.Hash `json:"root"`
+			Canonical bool        `json:"canonical"`
+			Header    struct {
+				Message types.Header `json:"message"`
+			} `json:"header"`
+		} `json:"data"`
+	}
+	data.Finalized = finalized
+	data.Data.Root = header.Hash()
+	data.Data.Canonical = canonical
+	data.Data.Header.Message = header
+
+	s.setJSON("/eth/v1/beacon/headers/"+header.Hash().Hex(), &data)
+	if head {
+		s.setJSON("/eth/v1/beacon/headers/head", &data)
+	}
+}
+
+// fakeOptimisticUpdate is the JSON encoding of an optimistic update.
+type fakeOptimisticUpdate struct {
+	Header        jsonBeaconHeader    `json:"attested_header"`
+	Aggregate     types.SyncAggregate `json:"sync_aggregate"`
+	SignatureSlot string              `json:"signature_slot"`
+}
+
+func newFakeOptimisticUpdate(head types.SignedHeader) fakeOptimisticUpdate {
+	return fakeOptimisticUpdate{
+		Header:        jsonBeaconHeader{Beacon: head.Header},
+		Aggregate:     head.Signature,
+		SignatureSlot: strconv.FormatUint(head.SignatureSlot, 10),
+	}
+}
+
+// SetOptimisticUpdate sets the optimistic update served by the server.
+func (s *FakeServer) SetOptimisticUpdate(head types.SignedHeader) {
+	s.setJSON(optimisticPath, map[string]any{
+		"data": newFakeOptimisticUpdate(head),
+	})
+}
+
+// SetCommitteeUpdate adds a committee update along with the next sync committee
+// to the served ones.
+func (s *FakeServer) SetCommitteeUpdate(update *types.LightClientUpdate, nextCommittee *types.SerializedSyncCommittee) {
+	data := committeeUpdateData{
+		Header:                  jsonBeaconHeader{Beacon: update.AttestedHeader.Header},
+		NextSyncCommittee:       *nextCommittee,
+		NextSyncCommitteeBranch: update.NextSyncCommitteeBranch,
+		FinalityBranch:          update.FinalityBranch,
+		SyncAggregate:           update.AttestedHeader.Signature,
+		SignatureSlot:           common.Decimal(update.AttestedHeader.SignatureSlot),
+	}
+	if update.FinalizedHeader != nil {
+		data.FinalizedHeader = &jsonBeaconHeader{Beacon: *update.FinalizedHeader}
+	}
+	enc, err := json.Marshal(&committeeUpdateJson{Version: "deneb", Data: data})
+	if err != nil {
+		panic(err)
+	}
+	s.lock.Lock()
+	s.updates[update.AttestedHeader.Header.SyncPeriod()] = enc
+	s.lock.Unlock()
+}
+
+// SetBootstrap makes the given bootstrap data available at its header root.
+func (s *FakeServer) SetBootstrap(bootstrap *types.BootstrapData) {
+	var data struct {
+		Data struct {
+			Header          jsonBeaconHeader               `json:"header"`
+			Committee       *types.SerializedSyncCommittee `json:"current_sync_committee"`
+			CommitteeBranch merkle.Values                  `json:"current_sync_committee_branch"`
+		} `json:"data"`
+	}
+	data.Data.Header.Beacon = bootstrap.Header
+	data.Data.Committee = bootstrap.Committee
+	data.Data.CommitteeBranch = bootstrap.CommitteeBranch
+	s.setJSON(bootstrapPath+bootstrap.Header.Hash().Hex(), &data)
+}
+
+// fakeFinalityUpdate is the JSON encoding of a finality update.
+type fakeFinalityUpdate struct {
+	Version string `json:"version"`
+	Data    struct {
+		Attested       jsonHeaderWithExecProof `json:"attested_header"`
+		Finalized      jsonHeaderWithExecProof `json:"finalized_header"`
+		FinalityBranch merkle.Values           `json:"finality_branch"`
+		Aggregate      types.SyncAggregate     `json:"sync_aggregate"`
+		SignatureSlot  string                  `json:"signature_slot"`
+	} `json:"data"`
+}
+
+func newFakeFinalityUpdate(version string, update types.FinalityUpdate) *fakeFinalityUpdate {
+	encodeHeader := func(h types.HeaderWithExecProof) jsonHeaderWithExecProof {
+		exec, err := json.Marshal(h.PayloadHeader)
+		if err != nil {
+			panic(err)
+		}
+		return jsonHeaderWithExecProof{Beacon: h.Header, Execution: exec, ExecutionBranch: h.PayloadBranch}
+	}
+	enc := &fakeFinalityUpdate{Version: version}
+	enc.Data.Attested = encodeHeader(update.Attested)
+	enc.Data.Finalized = encodeHeader(update.Finalized)
+	enc.Data.FinalityBranch = update.FinalityBranch
+	enc.Data.Aggregate = update.Signature
+	enc.Data.SignatureSlot = strconv.FormatUint(update.SignatureSlot, 10)
+	return enc
+}
+
+// SetFinalityUpdate sets the finality update served by the server. The version
+// is the fork name determining the format of the execution payload headers.
+func (s *FakeServer) SetFinalityUpdate(version string, update types.FinalityUpdate) {
+	s.setJSON(finalityPath, newFakeFinalityUpdate(version, update))
+}
+
+// fakeEvent is a server-sent event published by FakeServer.
+type fakeEvent struct {
+	id, event, data string
+}
+
+func (e *fakeEvent) Id() string    { return e.id }
+func (e *fakeEvent) Event() string { return e.event }
+func (e *fakeEvent) Data() string  { return e.data }
+
+func (s *FakeServer) publish(event string, v any) {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	s.lock.Lock()
+	s.eventID++
+	id := strconv.Itoa(s.eventID)
+	s.lock.Unlock()
+
+	s.events.Publish([]string{fakeEventChannel}, &fakeEvent{id: id, event: event, data: string(enc)})
+}
+
+// SendHead sends a head event to the subscribed clients.
+func (s *FakeServer) SendHead(slot uint64, blockRoot common.Hash) {
+	s.publish("head", map[string]any{
+		"slot":  strconv.FormatUint(slot, 10),
+		"block": blockRoot,
+	})
+}
+
+// SendOptimisticUpdate sends an optimistic update event to the subscribed clients.
+func (s *FakeServer) SendOptimisticUpdate(head types.SignedHeader) {
+	s.publish("light_client_optimistic_update", map[string]any{
+		"data": newFakeOptimisticUpdate(head),
+	})
+}
+
+// SendFinalityUpdate sends a finality update event to the subscribed clients.
+func (s *FakeServer) SendFinalityUpdate(version string, update types.FinalityUpdate) {
+	s.publish("light_client_finality_update", newFakeFinalityUpdate(version, update))
+}
@@ -96,6 +96,21 @@ func (u *CommitteeUpdate) UnmarshalJSON(input []byte) error {
 	return nil
 }
 
+// validate checks the format of the update and whether it belongs to the given
+// sync committee period. Note that the signature is not verified.
+func (u *CommitteeUpdate) validate(period uint64) error {
+	if u.Update.AttestedHeader.Header.SyncPeriod() != period {
+		return errors.New("wrong committee update header period")
+	}
+	if err := u.Update.Validate(); err != nil {
+		return err
+	}
+	if u.NextSyncCommittee.Root() != u.Update.NextSyncCommitteeRoot {
+		return errors.New("wrong sync committee root")
+	}
+	return nil
+}
+
 // fetcher is an interface useful for debug-harnessing the http api.
 type fetcher interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -169,15 +184,9 @@ func (api *BeaconLightApi) GetBestUpdatesAndCommittees(firstPeriod, count uint64
 	updates := make([]*types.LightClientUpdate, int(count))
 	committees := make([]*types.SerializedSyncCommittee, int(count))
 	for i, d := range data {
-		if d.Update.AttestedHeader.Header.SyncPeriod() != firstPeriod+uint64(i) {
-			return nil, nil, errors.New("wrong committee update header period")
-		}
-		if err := d.Update.Validate(); err != nil {
+		if err := d.validate(firstPeriod + uint64(i)); err != nil {
 			return nil, nil, err
 		}
-		if d.NextSyncCommittee.Root() != d.Update.NextSyncCommitteeRoot {
-			return nil, nil, errors.New("wrong sync committee root")
-		}
 		updates[i], committees[i] = new(types.LightClientUpdate), new(types.SerializedSyncCommittee)
 		*updates[i], *committees[i] = d.Update, d.NextSyncCommittee
 	}
@@ -335,7 +344,10 @@ func (api *BeaconLightApi) GetCheckpointData(checkpointHash common.Hash) (*types
 	if err != nil {
 		return nil, err
 	}
+	return decodeCheckpointData(resp, checkpointHash)
+}
 
+func decodeCheckpointData(enc []byte, checkpointHash common.Hash) (*types.BootstrapData, error) {
 	// See data structure definition here:
 	// https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/light-client/sync-protocol.md#lightclientbootstrap
 	type bootstrapData struct {
@@ -347,7 +359,7 @@ func (api *BeaconLightApi) GetCheckpointData(checkpointHash common.Hash) (*types
 	}
 
 	var data bootstrapData
-	if err := json.Unmarshal(resp, &data); err != nil {
+	if err := json.Unmarshal(enc, &data); err != nil {
 		return nil, err
 	}
 	if data.Data.Committee == nil {
@@ -422,9 +434,10 @@ type HeadEventListener struct {
 // They are never called concurrently.
 func (api *BeaconLightApi) StartHeadListener(listener HeadEventListener) func() {
 	var (
-		ctx, closeCtx = context.WithCancel(context.Background())
-		streamCh      = make(chan *eventsource.Stream, 1)
-		wg            sync.WaitGroup
+		ctx, closeCtx       = context.WithCancel(context.Background())
+		reqCtx, closeReqCtx = context.WithCancel(context.Background())
+		streamCh            = make(chan *eventsource.Stream, 1)
+		wg                  sync.WaitGroup
 	)
 
 	// When connected to a Lodestar node the subscription blocks until the first actual
@@ -433,14 +446,21 @@ func (api *BeaconLightApi) StartHeadListener(listener HeadEventListener) func()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		stream := api.startEventStream(ctx, &listener)
+		defer closeReqCtx()
+
+		// Abort pending subscription requests when closed.
+		stopAbort := context.AfterFunc(ctx, closeReqCtx)
+		stream := api.startEventStream(ctx, reqCtx, &listener)
 		if stream == nil {
 			// This case happens when the context was closed.
 			return
 		}
 		// Stream was opened, wait for close signal.
+		stopAbort()
 		streamCh <- stream
 		<-ctx.Done()
+		// The stream is closed before its request is aborted. Otherwise the
+		// stream might try to report the failed request on its closed channels.
 		stream.Close()
 	}()
 
@@ -519,10 +539,11 @@ func (api *BeaconLightApi) StartHeadListener(listener HeadEventListener) func()
 
 // startEventStream establishes an event stream. This will keep retrying until the stream has been
 // established. It can only return nil when the context is canceled.
-func (api *BeaconLightApi) startEventStream(ctx context.Context, listener *HeadEventListener) *eventsource.Stream {
+// The subscription request is bound to reqCtx which should outlive the stream.
+func (api *BeaconLightApi) startEventStream(ctx, reqCtx context.Context, listener *HeadEventListener) *eventsource.Stream {
 	for retry := true; retry; retry = ctxSleep(ctx, 5*time.Second) {
 		path := "/eth/v1/events?topics=head&topics=light_client_optimistic_update&topics=light_client_finality_update"
-		req, err := http.NewRequestWithContext(ctx, "GET", api.url+path, nil)
+		req, err := http.NewRequestWithContext(reqCtx, "GET", api.url+path, nil)
 		if err != nil {
 			listener.OnError(fmt.Errorf("error creating event subscription request: %v", err))
 			continue
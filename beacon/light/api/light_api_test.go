@@ -0,0 +1,140 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	testHeader1 = types.Header{Slot: 123, ProposerIndex: 1, StateRoot: common.Hash{1}}
+	testHeader2 = types.Header{Slot: 124, ProposerIndex: 2, StateRoot: common.Hash{2}}
+)
+
+func TestGetHeader(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	api := NewBeaconLightApi(server.URL(), nil)
+
+	server.SetHeader(testHeader1, true, true, false)
+	server.SetHeader(testHeader2, true, false, true)
+
+	header, canonical, finalized, err := api.GetHeader(testHeader1.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve header: %v", err)
+	}
+	if header != testHeader1 || !canonical || !finalized {
+		t.Fatalf("Wrong header retrieved (header %v canonical %v finalized %v)", header, canonical, finalized)
+	}
+	header, _, finalized, err = api.GetHeader(common.Hash{})
+	if err != nil {
+		t.Fatalf("Failed to retrieve head header: %v", err)
+	}
+	if header != testHeader2 || finalized {
+		t.Fatalf("Wrong head header retrieved (header %v finalized %v)", header, finalized)
+	}
+	if _, _, _, err := api.GetHeader(common.Hash{3}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Unexpected error for unknown header (expected %v, got %v)", ErrNotFound, err)
+	}
+	server.SetStatus("/eth/v1/beacon/headers/head", http.StatusInternalServerError)
+	if _, _, _, err := api.GetHeader(common.Hash{}); !errors.Is(err, ErrInternal) {
+		t.Fatalf("Unexpected error for failing endpoint (expected %v, got %v)", ErrInternal, err)
+	}
+	if count := server.RequestCount("/eth/v1/beacon/headers/head"); count != 2 {
+		t.Fatalf("Wrong request count (expected 2, got %d)", count)
+	}
+}
+
+func TestHeadListener(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	api := NewBeaconLightApi(server.URL(), nil)
+
+	signedHead := types.SignedHeader{
+		Header:        testHeader2,
+		Signature:     types.SyncAggregate{Signature: [96]byte{1}},
+		SignatureSlot: 125,
+	}
+	server.SetHeader(testHeader1, true, false, true)
+	server.SetOptimisticUpdate(signedHead)
+
+	var (
+		headCh       = make(chan common.Hash, 10)
+		signedHeadCh = make(chan types.SignedHeader, 10)
+	)
+	stop := api.StartHeadListener(HeadEventListener{
+		OnNewHead:    func(slot uint64, blockRoot common.Hash) { headCh <- blockRoot },
+		OnSignedHead: func(head types.SignedHeader) { signedHeadCh <- head },
+		OnFinality:   func(head types.FinalityUpdate) {},
+		OnError:      func(err error) {},
+	})
+	defer stop()
+
+	expHead := func(exp common.Hash) {
+		t.Helper()
+		select {
+		case head := <-headCh:
+			if head != exp {
+				t.Fatalf("Wrong head received (expected %x, got %x)", exp, head)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Head not received")
+		}
+	}
+	expSignedHead := func(exp types.SignedHeader) {
+		t.Helper()
+		select {
+		case head := <-signedHeadCh:
+			if head != exp {
+				t.Fatalf("Wrong signed head received (expected %v, got %v)", exp, head)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Signed head not received")
+		}
+	}
+	// initial state is requested at startup
+	expHead(testHeader1.Hash())
+	expSignedHead(signedHead)
+
+	// later heads are received as events; the stream is subscribed in the
+	// background so events are resent until received
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.SendHead(testHeader2.Slot, testHeader2.Hash())
+		select {
+		case head := <-headCh:
+			if head != testHeader2.Hash() {
+				t.Fatalf("Wrong head received (expected %x, got %x)", testHeader2.Hash(), head)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatalf("Head event not received")
+			}
+			continue
+		}
+		break
+	}
+	signedHead.SignatureSlot++
+	server.SendOptimisticUpdate(signedHead)
+	expSignedHead(signedHead)
+}
@@ -0,0 +1,421 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/donovanhide/eventsource"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	maxServedUpdates  = 128              // MAX_REQUEST_LIGHT_CLIENT_UPDATES of the spec
+	refreshInterval   = 12 * time.Second // refresh period if the upstream event stream is silent
+	lightEventChannel = "light_client"
+
+	updatesPath    = "/eth/v1/beacon/light_client/updates"
+	bootstrapPath  = "/eth/v1/beacon/light_client/bootstrap/"
+	optimisticPath = "/eth/v1/beacon/light_client/optimistic_update"
+	finalityPath   = "/eth/v1/beacon/light_client/finality_update"
+	eventsPath     = "/eth/v1/events"
+)
+
+// LightServer caches the light client data of a trusted beacon node and serves
+// it through the standard beacon light client REST API, so that other light
+// clients can sync from this node instead of the consensus client itself.
+//
+// Committee updates and bootstrap data of the retained sync committee periods
+// are persisted in the database, while the latest optimistic and finality
+// updates are kept in memory. All data is checked for consistency before being
+// served but signatures are not verified; that is the job of the light clients.
+type LightServer struct {
+	upstream  *BeaconLightApi
+	db        ethdb.KeyValueStore
+	retention uint64
+	events    *eventsource.Server
+	triggerCh chan struct{}
+	closeCtx  context.CancelFunc
+	wg        sync.WaitGroup
+
+	lock          sync.RWMutex
+	optimistic    []byte                 // latest optimistic update response
+	finality      []byte                 // latest finality update response
+	finalizedRoot common.Hash            // finalized header root of the latest finality update
+	bootstraps    map[common.Hash][]byte // block root -> database key of bootstrap data
+}
+
+// NewLightServer creates a light client data server fed from the given upstream
+// beacon API. Committee updates and bootstrap data are retained for the given
+// number of the latest sync committee periods (0 = all).
+func NewLightServer(upstream *BeaconLightApi, db ethdb.KeyValueStore, retention uint64) *LightServer {
+	s := &LightServer{
+		upstream:   upstream,
+		db:         db,
+		retention:  retention,
+		triggerCh:  make(chan struct{}, 1),
+		bootstraps: make(map[common.Hash][]byte),
+	}
+	it := db.NewIterator(rawdb.LightServerBootstrapKey, nil)
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(rawdb.LightServerBootstrapKey)+8+common.HashLength {
+			continue
+		}
+		s.bootstraps[common.BytesToHash(key[len(key)-common.HashLength:])] = common.CopyBytes(key)
+	}
+	it.Release()
+	return s
+}
+
+// Start implements node.Lifecycle.
+func (s *LightServer) Start() error {
+	var ctx context.Context
+	ctx, s.closeCtx = context.WithCancel(context.Background())
+	s.events = eventsource.NewServer()
+	s.wg.Add(2)
+	go s.eventLoop(ctx)
+	go s.syncLoop(ctx)
+	return nil
+}
+
+// Stop implements node.Lifecycle.
+func (s *LightServer) Stop() error {
+	s.closeCtx()
+	s.wg.Wait()
+	s.events.Close()
+	return nil
+}
+
+// eventLoop relays the events of the upstream beacon API to the subscribed
+// light clients and triggers the refreshing of the cached data.
+func (s *LightServer) eventLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	reqCtx, closeReqCtx := context.WithCancel(context.Background())
+	defer closeReqCtx()
+	stopAbort := context.AfterFunc(ctx, closeReqCtx)
+	stream := s.upstream.startEventStream(ctx, reqCtx, &HeadEventListener{
+		OnError: func(err error) { log.Debug("Failed to subscribe to upstream beacon events", "error", err) },
+	})
+	if stream == nil {
+		return
+	}
+	stopAbort()
+	for {
+		select {
+		case <-ctx.Done():
+			// Keep draining the stream until its channels are closed.
+			stream.Close()
+
+		case event, ok := <-stream.Events:
+			if !ok {
+				return
+			}
+			var err error
+			switch event.Event() {
+			case "head":
+				_, _, err = decodeHeadEvent([]byte(event.Data()))
+			case "light_client_optimistic_update":
+				_, err = decodeOptimisticHeadUpdate([]byte(event.Data()))
+			case "light_client_finality_update":
+				_, err = decodeFinalityUpdate([]byte(event.Data()))
+			default:
+				continue
+			}
+			if err != nil {
+				log.Debug("Invalid upstream beacon event", "type", event.Event(), "error", err)
+				continue
+			}
+			s.events.Publish([]string{lightEventChannel}, event)
+			if event.Event() != "head" {
+				select {
+				case s.triggerCh <- struct{}{}:
+				default:
+				}
+			}
+
+		case err, ok := <-stream.Errors:
+			if !ok {
+				return
+			}
+			log.Debug("Upstream beacon event stream error", "error", err)
+		}
+	}
+}
+
+// syncLoop refreshes the cached data whenever new updates are announced by the
+// upstream or the refresh period has passed.
+func (s *LightServer) syncLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		s.refresh()
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.triggerCh:
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches the latest optimistic and finality updates. If the finalized
+// header has changed then the committee updates and the bootstrap data of the
+// new finalized checkpoint are also fetched.
+func (s *LightServer) refresh() {
+	if enc, err := s.upstream.httpGet(optimisticPath); err == nil {
+		if _, err := decodeOptimisticHeadUpdate(enc); err == nil {
+			s.lock.Lock()
+			s.optimistic = enc
+			s.lock.Unlock()
+		} else {
+			log.Warn("Invalid optimistic update from upstream beacon API", "error", err)
+		}
+	}
+	enc, err := s.upstream.httpGet(finalityPath)
+	if err != nil {
+		return
+	}
+	update, err := decodeFinalityUpdate(enc)
+	if err != nil {
+		log.Warn("Invalid finality update from upstream beacon API", "error", err)
+		return
+	}
+	finalized := update.Finalized.Header
+	s.lock.Lock()
+	s.finality = enc
+	changed := finalized.Hash() != s.finalizedRoot
+	s.finalizedRoot = finalized.Hash()
+	s.lock.Unlock()
+
+	if !changed {
+		return
+	}
+	period := types.SyncPeriod(update.SignatureSlot)
+	first := uint64(0)
+	if s.retention != 0 && period >= s.retention {
+		first = period - s.retention + 1
+	}
+	s.syncUpdates(first, period)
+	s.syncBootstrap(finalized)
+	s.prune(first)
+}
+
+func updateKey(period uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, rawdb.LightServerUpdateKey...), period)
+}
+
+// syncUpdates fetches the missing committee updates between the given periods.
+// The updates of the last two periods are always fetched as the best update
+// of a period might change until the next period is finalized.
+func (s *LightServer) syncUpdates(first, last uint64) {
+	start := first
+	for start+1 < last {
+		if has, _ := s.db.Has(updateKey(start)); !has {
+			break
+		}
+		start++
+	}
+	for start <= last {
+		count := min(last+1-start, maxServedUpdates)
+		resp, err := s.upstream.httpGetf("%s?start_period=%d&count=%d", updatesPath, start, count)
+		if err != nil {
+			log.Debug("Failed to fetch committee updates", "start", start, "count", count, "error", err)
+			return
+		}
+		var updates []json.RawMessage
+		if err := json.Unmarshal(resp, &updates); err != nil {
+			log.Warn("Invalid committee updates from upstream beacon API", "error", err)
+			return
+		}
+		if len(updates) == 0 {
+			return
+		}
+		batch := s.db.NewBatch()
+		for i, enc := range updates {
+			var update CommitteeUpdate
+			if err := json.Unmarshal(enc, &update); err == nil {
+				err = update.validate(start + uint64(i))
+			}
+			if err != nil {
+				log.Warn("Invalid committee update from upstream beacon API", "period", start+uint64(i), "error", err)
+				break
+			}
+			batch.Put(updateKey(start+uint64(i)), enc)
+		}
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to store committee updates", "error", err)
+			return
+		}
+		start += uint64(len(updates))
+	}
+}
+
+// syncBootstrap fetches the bootstrap data belonging to the given finalized
+// checkpoint header.
+func (s *LightServer) syncBootstrap(header types.Header) {
+	root := header.Hash()
+	s.lock.RLock()
+	_, ok := s.bootstraps[root]
+	s.lock.RUnlock()
+	if ok {
+		return
+	}
+	enc, err := s.upstream.httpGetf("%s0x%x", bootstrapPath, root[:])
+	if err != nil {
+		log.Debug("Failed to fetch bootstrap data", "slot", header.Slot, "root", root, "error", err)
+		return
+	}
+	if _, err := decodeCheckpointData(enc, root); err != nil {
+		log.Warn("Invalid bootstrap data from upstream beacon API", "slot", header.Slot, "root", root, "error", err)
+		return
+	}
+	key := binary.BigEndian.AppendUint64(append([]byte{}, rawdb.LightServerBootstrapKey...), header.Slot)
+	key = append(key, root[:]...)
+	if err := s.db.Put(key, enc); err != nil {
+		log.Error("Failed to store bootstrap data", "error", err)
+		return
+	}
+	s.lock.Lock()
+	s.bootstraps[root] = key
+	s.lock.Unlock()
+}
+
+// prune removes the committee updates and bootstrap data of the periods before
+// the given one.
+func (s *LightServer) prune(first uint64) {
+	batch := s.db.NewBatch()
+	it := s.db.NewIterator(rawdb.LightServerUpdateKey, nil)
+	for it.Next() && bytes.Compare(it.Key(), updateKey(first)) < 0 {
+		batch.Delete(it.Key())
+	}
+	it.Release()
+
+	s.lock.Lock()
+	for root, key := range s.bootstraps {
+		slot := binary.BigEndian.Uint64(key[len(rawdb.LightServerBootstrapKey):])
+		if types.SyncPeriod(slot) < first {
+			batch.Delete(key)
+			delete(s.bootstraps, root)
+		}
+	}
+	s.lock.Unlock()
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to prune light client data", "error", err)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *LightServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := req.URL.Path
+	switch {
+	case path == updatesPath:
+		s.serveUpdates(w, req)
+	case strings.HasPrefix(path, bootstrapPath):
+		s.serveBootstrap(w, strings.TrimPrefix(path, bootstrapPath))
+	case path == optimisticPath:
+		s.lock.RLock()
+		enc := s.optimistic
+		s.lock.RUnlock()
+		writeResponse(w, enc)
+	case path == finalityPath:
+		s.lock.RLock()
+		enc := s.finality
+		s.lock.RUnlock()
+		writeResponse(w, enc)
+	case path == eventsPath:
+		// All relayed topics are sent regardless of the requested ones.
+		s.events.Handler(lightEventChannel)(w, req)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (s *LightServer) serveUpdates(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	start, err1 := strconv.ParseUint(query.Get("start_period"), 10, 64)
+	count, err2 := strconv.ParseUint(query.Get("count"), 10, 64)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid start_period or count", http.StatusBadRequest)
+		return
+	}
+	count = min(count, maxServedUpdates)
+	var updates []json.RawMessage
+	for period := start; period < start+count; period++ {
+		enc, err := s.db.Get(updateKey(period))
+		if err != nil || len(enc) == 0 {
+			break
+		}
+		updates = append(updates, enc)
+	}
+	if len(updates) == 0 {
+		http.NotFound(w, req)
+		return
+	}
+	enc, err := json.Marshal(updates)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, enc)
+}
+
+func (s *LightServer) serveBootstrap(w http.ResponseWriter, id string) {
+	root, err := hexutil.Decode(id)
+	if err != nil || len(root) != common.HashLength {
+		http.Error(w, "invalid block root", http.StatusBadRequest)
+		return
+	}
+	s.lock.RLock()
+	key, ok := s.bootstraps[common.BytesToHash(root)]
+	s.lock.RUnlock()
+	var enc []byte
+	if ok {
+		enc, _ = s.db.Get(key)
+	}
+	writeResponse(w, enc)
+}
+
+// writeResponse writes a JSON response or a 404 error if it is not available.
+func writeResponse(w http.ResponseWriter, enc []byte) {
+	if len(enc) == 0 {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(enc)
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/light"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/protolambda/zrnt/eth2/beacon/deneb"
+)
+
+// lightServerTest is an upstream beacon API serving the committee updates of
+// the first few sync committee periods and the bootstrap data of the finalized
+// checkpoint.
+type lightServerTest struct {
+	upstream   *FakeServer
+	committees []*types.SerializedSyncCommittee
+	updates    []*types.LightClientUpdate
+	checkpoint *types.BootstrapData
+	finality   types.FinalityUpdate
+	optimistic types.SignedHeader
+}
+
+func newLightServerTest(t *testing.T, periods int) *lightServerTest {
+	test := &lightServerTest{upstream: NewFakeServer()}
+	t.Cleanup(test.upstream.Close)
+
+	config := &types.ChainConfig{}
+	for i := 0; i <= periods; i++ {
+		test.committees = append(test.committees, light.GenerateTestCommittee())
+	}
+	for period := 0; period < periods; period++ {
+		update := light.GenerateTestUpdate(config, uint64(period), test.committees[period], test.committees[period+1], 400, false)
+		test.updates = append(test.updates, update)
+		test.upstream.SetCommitteeUpdate(update, test.committees[period+1])
+	}
+	last := uint64(periods - 1)
+	test.checkpoint = light.GenerateTestCheckpoint(last, test.committees[last])
+	test.upstream.SetBootstrap(test.checkpoint)
+
+	test.optimistic = test.updates[last].AttestedHeader
+	test.upstream.SetOptimisticUpdate(test.optimistic)
+	test.finality = test.newFinality(test.checkpoint.Header)
+	test.upstream.SetFinalityUpdate("deneb", test.finality)
+	return test
+}
+
+// newFinality creates a finality update finalizing the given header.
+func (test *lightServerTest) newFinality(finalized types.Header) types.FinalityUpdate {
+	attested := types.Header{Slot: finalized.Slot + 64, ParentRoot: finalized.Hash()}
+	return types.FinalityUpdate{
+		Attested:      types.HeaderWithExecProof{Header: attested, PayloadHeader: types.NewExecutionHeader(new(deneb.ExecutionPayloadHeader))},
+		Finalized:     types.HeaderWithExecProof{Header: finalized, PayloadHeader: types.NewExecutionHeader(new(deneb.ExecutionPayloadHeader))},
+		Signature:     test.optimistic.Signature,
+		SignatureSlot: attested.Slot + 1,
+	}
+}
+
+func TestLightServerCache(t *testing.T) {
+	test := newLightServerTest(t, 4)
+	db := memorydb.New()
+	// an update of a period out of the retention range should be pruned
+	db.Put(updateKey(0), []byte("{}"))
+
+	server := NewLightServer(NewBeaconLightApi(test.upstream.URL(), nil), db, 2)
+	server.refresh()
+	if has, _ := db.Has(updateKey(0)); has {
+		t.Fatalf("Committee update of period 0 not pruned")
+	}
+	if has, _ := db.Has(updateKey(1)); has {
+		t.Fatalf("Committee update of period 1 fetched out of the retention range")
+	}
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	client := NewBeaconLightApi(httpServer.URL, nil)
+
+	updates, committees, err := client.GetBestUpdatesAndCommittees(2, 2)
+	if err != nil {
+		t.Fatalf("Failed to retrieve committee updates: %v", err)
+	}
+	for i := range updates {
+		if updates[i].AttestedHeader != test.updates[i+2].AttestedHeader || *committees[i] != *test.committees[i+3] {
+			t.Fatalf("Wrong committee update of period %d", i+2)
+		}
+	}
+	if _, _, err := client.GetBestUpdatesAndCommittees(0, 1); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Unexpected error for unavailable committee update (expected %v, got %v)", ErrNotFound, err)
+	}
+	checkpoint, err := client.GetCheckpointData(test.checkpoint.Header.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve bootstrap data: %v", err)
+	}
+	if checkpoint.Header != test.checkpoint.Header || checkpoint.CommitteeRoot != test.checkpoint.CommitteeRoot {
+		t.Fatalf("Wrong bootstrap data")
+	}
+	if _, err := client.GetCheckpointData(common.Hash{1}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Unexpected error for unavailable bootstrap data (expected %v, got %v)", ErrNotFound, err)
+	}
+	if head, err := client.GetOptimisticHeadUpdate(); err != nil || head != test.optimistic {
+		t.Fatalf("Wrong optimistic update (error %v)", err)
+	}
+	finality, err := client.GetFinalityUpdate()
+	if err != nil {
+		t.Fatalf("Failed to retrieve finality update: %v", err)
+	}
+	if finality.Finalized.Header != test.finality.Finalized.Header || finality.SignatureSlot != test.finality.SignatureSlot {
+		t.Fatalf("Wrong finality update")
+	}
+
+	// the committee updates and bootstrap data are only fetched again if the
+	// finalized header has changed
+	requests := test.upstream.RequestCount(updatesPath)
+	server.refresh()
+	if count := test.upstream.RequestCount(updatesPath); count != requests {
+		t.Fatalf("Committee updates fetched without new finalized header")
+	}
+
+	// the stored data is served after a restart
+	server = NewLightServer(NewBeaconLightApi(test.upstream.URL(), nil), db, 2)
+	httpServer2 := httptest.NewServer(server)
+	defer httpServer2.Close()
+	client = NewBeaconLightApi(httpServer2.URL, nil)
+	if _, err := client.GetCheckpointData(test.checkpoint.Header.Hash()); err != nil {
+		t.Fatalf("Failed to retrieve bootstrap data after restart: %v", err)
+	}
+	if _, _, err := client.GetBestUpdatesAndCommittees(2, 2); err != nil {
+		t.Fatalf("Failed to retrieve committee updates after restart: %v", err)
+	}
+}
+
+func TestLightServerEvents(t *testing.T) {
+	test := newLightServerTest(t, 2)
+	server := NewLightServer(NewBeaconLightApi(test.upstream.URL(), nil), memorydb.New(), 0)
+	server.Start()
+	defer server.Stop()
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	finalityCh := make(chan types.FinalityUpdate, 10)
+	stop := NewBeaconLightApi(httpServer.URL, nil).StartHeadListener(HeadEventListener{
+		OnNewHead:    func(slot uint64, blockRoot common.Hash) {},
+		OnSignedHead: func(head types.SignedHeader) {},
+		OnFinality:   func(update types.FinalityUpdate) { finalityCh <- update },
+		OnError:      func(err error) {},
+	})
+	defer stop()
+
+	// upstream events are relayed to the subscribed clients; the streams are
+	// subscribed in the background so the event is resent until received
+	finalized := types.Header{Slot: test.checkpoint.Header.Slot + 32, ParentRoot: test.checkpoint.Header.Hash()}
+	finality := test.newFinality(finalized)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		test.upstream.SendFinalityUpdate("deneb", finality)
+		select {
+		case update := <-finalityCh:
+			if update.Finalized.Header == finalized {
+				return
+			}
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatalf("Finality update event not relayed")
+			}
+		}
+	}
+}
@@ -101,6 +101,17 @@ func (cs *canonicalStore[T]) deleteFrom(db ethdb.KeyValueWriter, fromPeriod uint
 	return deleteRange
 }
 
+// deleteBefore removes items before the given period.
+func (cs *canonicalStore[T]) deleteBefore(db ethdb.KeyValueWriter, period uint64) (deleted periodRange) {
+	deleteRange, keepRange := cs.periods.split(period)
+	deleteRange.each(func(period uint64) {
+		db.Delete(cs.databaseKey(period))
+		cs.cache.Remove(period)
+	})
+	cs.periods = keepRange
+	return deleteRange
+}
+
 // get returns the item at the given period or the null value of the given type
 // if no item is present.
 func (cs *canonicalStore[T]) get(backend ethdb.KeyValueReader, period uint64) (T, bool) {
@@ -39,6 +39,8 @@ var (
 	ErrInvalidPeriod      = errors.New("invalid update period")
 	ErrWrongCommitteeRoot = errors.New("wrong committee root")
 	ErrCannotReorg        = errors.New("can not reorg committee chain")
+	ErrInvalidSignature   = errors.New("invalid header signature")
+	ErrInvalidFinality    = errors.New("invalid finality proof")
 )
 
 // CommitteeChain is a passive data structure that can validate, hold and update
@@ -79,7 +81,8 @@ type CommitteeChain struct {
 	config             *types.ChainConfig
 	signerThreshold    int
 	minimumUpdateScore types.UpdateScore
-	enforceTime        bool // enforceTime specifies whether the age of a signed header should be checked
+	enforceTime        bool   // enforceTime specifies whether the age of a signed header should be checked
+	retention          uint64 // number of periods to retain when new updates are inserted (0 = all)
 }
 
 // NewCommitteeChain creates a new CommitteeChain.
@@ -395,6 +398,68 @@ func (s *CommitteeChain) InsertUpdate(update *types.LightClientUpdate, nextCommi
 		return err
 	}
 	log.Info("Inserted new committee update", "period", period, "next committee root", update.NextSyncCommitteeRoot)
+	if s.retention != 0 && period+2 > s.retention {
+		if err := s.prune(period + 2 - s.retention); err != nil {
+			log.Error("Error pruning committee chain", "error", err)
+		}
+	}
+	return nil
+}
+
+// SetRetention sets the number of the latest periods to retain, the older
+// committees, updates and fixed roots are pruned when new updates are inserted.
+// Zero means that the entire chain is retained.
+func (s *CommitteeChain) SetRetention(periods uint64) {
+	s.chainmu.Lock()
+	defer s.chainmu.Unlock()
+
+	s.retention = periods
+}
+
+// Prune removes the committees, updates and fixed roots before the given
+// period. The latest committee is always retained.
+func (s *CommitteeChain) Prune(period uint64) error {
+	s.chainmu.Lock()
+	defer s.chainmu.Unlock()
+
+	return s.prune(period)
+}
+
+// prune removes the committees, updates and fixed roots before the given period.
+// The committee roots up to the first retained period are fixed, as they are
+// proven by the pruned updates which can't be verified again afterwards.
+func (s *CommitteeChain) prune(period uint64) error {
+	if s.committees.periods.isEmpty() {
+		return nil
+	}
+	if period >= s.committees.periods.End {
+		period = s.committees.periods.End - 1
+	}
+	if period <= s.committees.periods.Start {
+		return nil
+	}
+	batch := s.db.NewBatch()
+	for p := s.fixedCommitteeRoots.periods.End; p <= period; p++ {
+		root := s.getCommitteeRoot(p)
+		if root == (common.Hash{}) {
+			return ErrInvalidPeriod
+		}
+		if err := s.fixedCommitteeRoots.add(batch, p, root); err != nil {
+			return err
+		}
+	}
+	s.fixedCommitteeRoots.deleteBefore(batch, period)
+	s.updates.deleteBefore(batch, period)
+	deleted := s.committees.deleteBefore(batch, period)
+	deleted.each(func(period uint64) {
+		s.committeeCache.Remove(period)
+	})
+	if err := batch.Write(); err != nil {
+		log.Error("Error writing batch into chain database", "error", err)
+		return err
+	}
+	s.changeCounter++
+	log.Debug("Pruned committee chain", "first period", period)
 	return nil
 }
 
@@ -222,6 +222,32 @@ func TestCommitteeChainFork(t *testing.T) {
 	c.verifyRange(tcBase, 0, 10)
 }
 
+func TestCommitteeChainPrune(t *testing.T) {
+	for _, reload := range []bool{false, true} {
+		c := newCommitteeChainTest(t, tfBase, 300, true)
+		c.setClockPeriod(11)
+		c.chain.SetRetention(4)
+		c.addFixedCommitteeRoot(tcBase, 3, nil)
+		c.addFixedCommitteeRoot(tcBase, 4, nil)
+		c.addCommittee(tcBase, 3, nil)
+		for period := uint64(3); period < 10; period++ {
+			c.insertUpdate(tcBase, period, true, nil)
+		}
+		if reload {
+			// the pruned chain should pass the consistency checks on startup
+			c.reloadChain()
+		}
+		// committees 7..10 are retained, the update at period 9 proves committee 10
+		c.verifyRange(tcBase, 7, 10)
+		if next, ok := c.chain.NextSyncPeriod(); !ok || next != 10 {
+			c.t.Errorf("Incorrect next sync period (expected 10, got %d)", next)
+		}
+		// the pruned periods can't be updated anymore
+		c.insertUpdate(tcBase, 6, true, ErrInvalidPeriod)
+		c.verifyRange(tcBase, 7, 10)
+	}
+}
+
 type committeeChainTest struct {
 	t               *testing.T
 	db              *memorydb.Database
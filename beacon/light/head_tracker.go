@@ -18,11 +18,15 @@ package light
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 // HeadTracker keeps track of the latest validated head and the "prefetch" head
@@ -80,18 +84,55 @@ func (h *HeadTracker) ValidateHead(head types.SignedHeader) (bool, error) {
 	return replace, err
 }
 
+// ValidateFinality validates the given finality update. If the update is
+// successfully validated and it is better than the old validated update then
+// ValidatedFinality is updated. The boolean return flag signals if
+// ValidatedFinality has been changed.
 func (h *HeadTracker) ValidateFinality(update types.FinalityUpdate) (bool, error) {
+	if err := update.Validate(); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidFinality, err)
+	}
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
 	replace, err := h.validate(update.SignedHeader(), h.finalityUpdate.SignedHeader())
 	if replace {
+		if h.finalityUpdate.Finalized.Header != update.Finalized.Header {
+			writeFinalizedHeader(h.committeeChain.db, update.Finalized.Header)
+		}
 		h.finalityUpdate, h.hasFinalityUpdate = update, true
 		h.changeCounter++
 	}
 	return replace, err
 }
 
+// ReadFinalizedHeader retrieves the latest validated finalized header persisted
+// by a previous run, which can serve as the checkpoint for resyncing.
+func ReadFinalizedHeader(db ethdb.KeyValueReader) (types.Header, bool) {
+	var header types.Header
+	enc, err := db.Get(rawdb.FinalizedBeaconKey)
+	if err != nil || len(enc) == 0 {
+		return header, false
+	}
+	if err := rlp.DecodeBytes(enc, &header); err != nil {
+		log.Error("Error decoding finalized beacon header", "error", err)
+		return header, false
+	}
+	return header, true
+}
+
+// writeFinalizedHeader persists the latest validated finalized header.
+func writeFinalizedHeader(db ethdb.KeyValueWriter, header types.Header) {
+	enc, err := rlp.EncodeToBytes(&header)
+	if err != nil {
+		log.Error("Error encoding finalized beacon header", "error", err)
+		return
+	}
+	if err := db.Put(rawdb.FinalizedBeaconKey, enc); err != nil {
+		log.Error("Error writing finalized beacon header", "error", err)
+	}
+}
+
 func (h *HeadTracker) validate(head, oldHead types.SignedHeader) (bool, error) {
 	signerCount := head.Signature.SignerCount()
 	if signerCount < h.minSignerCount {
@@ -111,7 +152,7 @@ func (h *HeadTracker) validate(head, oldHead types.SignedHeader) (bool, error) {
 		log.Warn("Old signed head received", "age", age)
 	}
 	if !sigOk {
-		return false, errors.New("invalid header signature")
+		return false, ErrInvalidSignature
 	}
 	return true, nil
 }
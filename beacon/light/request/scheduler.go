@@ -18,8 +18,20 @@ package request
 
 import (
 	"sync"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// disconnectDelay is the time after which a server disconnected because of its
+// low score is registered again.
+const disconnectDelay = time.Minute * 5
+
+var (
+	serverDemotedMeter      = metrics.NewRegisteredMeter("beacon/request/demoted", nil)
+	serverDisconnectedMeter = metrics.NewRegisteredMeter("beacon/request/disconnected", nil)
 )
 
 // Module represents a mechanism which is typically responsible for downloading
@@ -65,11 +77,15 @@ type Requester interface {
 // allow new operations.
 type Scheduler struct {
 	lock    sync.Mutex
+	clock   mclock.Clock
 	modules []Module // first has the highest priority
 	names   map[Module]string
 	servers map[server]struct{}
 	targets map[targetData]uint64
 
+	demoted      map[server]struct{}     // servers with a score below demoteScore
+	disconnected map[server]mclock.Timer // servers waiting to be registered again
+
 	requesterLock sync.RWMutex
 	serverOrder   []server
 	pending       map[ServerAndID]pendingRequest
@@ -121,11 +137,14 @@ type pendingRequest struct {
 // NewScheduler creates a new Scheduler.
 func NewScheduler() *Scheduler {
 	s := &Scheduler{
-		servers: make(map[server]struct{}),
-		names:   make(map[Module]string),
-		pending: make(map[ServerAndID]pendingRequest),
-		targets: make(map[targetData]uint64),
-		stopCh:  make(chan chan struct{}),
+		clock:        mclock.System{},
+		demoted:      make(map[server]struct{}),
+		disconnected: make(map[server]mclock.Timer),
+		servers:      make(map[server]struct{}),
+		names:        make(map[Module]string),
+		pending:      make(map[ServerAndID]pendingRequest),
+		targets:      make(map[targetData]uint64),
+		stopCh:       make(chan chan struct{}),
 		// Note: testWaitCh should not have capacity in order to ensure
 		// that after a trigger happens testWaitCh will block until the resulting
 		// processing round has been finished
@@ -161,6 +180,11 @@ func (s *Scheduler) RegisterServer(server server) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.registerServer(server)
+}
+
+// registerServer registers a server and subscribes to its events.
+func (s *Scheduler) registerServer(server server) {
 	s.addEvent(Event{Type: EvRegistered, Server: server})
 	server.subscribe(func(event Event) {
 		event.Server = server
@@ -173,6 +197,11 @@ func (s *Scheduler) UnregisterServer(server server) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	if timer, ok := s.disconnected[server]; ok {
+		timer.Stop()
+		delete(s.disconnected, server)
+		return
+	}
 	server.unsubscribe()
 	s.addEvent(Event{Type: EvUnregistered, Server: server})
 }
@@ -190,9 +219,14 @@ func (s *Scheduler) Stop() {
 	<-stop
 	s.lock.Lock()
 	for server := range s.servers {
-		server.unsubscribe()
+		if _, ok := s.disconnected[server]; !ok {
+			server.unsubscribe()
+		}
 	}
-	s.servers = nil
+	for _, timer := range s.disconnected {
+		timer.Stop()
+	}
+	s.servers, s.disconnected = nil, nil
 	s.lock.Unlock()
 }
 
@@ -237,6 +271,7 @@ func (s *Scheduler) targetChanged() (changed bool) {
 // Once all events have been processed and a stable state has been achieved,
 // requests are generated and sent if necessary and possible.
 func (s *Scheduler) processRound() {
+	s.checkScores()
 	for {
 		log.Trace("Processing modules")
 		filteredEvents := s.filterEvents()
@@ -250,6 +285,48 @@ func (s *Scheduler) processRound() {
 	}
 }
 
+// checkScores demotes the servers with a low score, so that they are only used
+// when no other server is available. The servers with a very low score are
+// disconnected and registered again after a delay, with a demoted score.
+func (s *Scheduler) checkScores() {
+	for server := range s.servers {
+		if _, ok := s.disconnected[server]; ok {
+			continue
+		}
+		score := server.score()
+		if score < disconnectScore {
+			log.Warn("Disconnecting misbehaving beacon server", "server", server.Name(), "score", score, "delay", disconnectDelay)
+			serverDisconnectedMeter.Mark(1)
+			server := server
+			server.unsubscribe()
+			s.addEvent(Event{Type: EvUnregistered, Server: server})
+			s.disconnected[server] = s.clock.AfterFunc(disconnectDelay, func() {
+				s.lock.Lock()
+				defer s.lock.Unlock()
+
+				if _, ok := s.disconnected[server]; !ok {
+					return // unregistered or stopped in the meantime
+				}
+				delete(s.disconnected, server)
+				log.Info("Reconnecting demoted beacon server", "server", server.Name())
+				server.setScore(demoteScore)
+				s.registerServer(server)
+			})
+			continue
+		}
+		_, demoted := s.demoted[server]
+		if score < demoteScore && !demoted {
+			log.Warn("Demoting misbehaving beacon server", "server", server.Name(), "score", score)
+			serverDemotedMeter.Mark(1)
+			s.demoted[server] = struct{}{}
+		}
+		if score >= demoteScore && demoted {
+			log.Info("Beacon server no longer demoted", "server", server.Name(), "score", score)
+			delete(s.demoted, server)
+		}
+	}
+}
+
 // Trigger starts a new processing round. If fired during processing, it ensures
 // another full round of processing all modules.
 func (s *Scheduler) Trigger() {
@@ -313,6 +390,7 @@ func (s *Scheduler) filterEvents() map[Module][]Event {
 			case EvUnregistered:
 				s.closePending(event.Server, filteredEvents)
 				delete(s.servers, server)
+				delete(s.demoted, server)
 				for i, srv := range s.serverOrder {
 					if srv == server {
 						copy(s.serverOrder[i:len(s.serverOrder)-1], s.serverOrder[i+1:])
@@ -359,17 +437,25 @@ type requester struct {
 // CanSendTo returns the list of currently available servers. It also returns
 // them in an order of least to most recently used, ensuring a round-robin usage
 // of suitable servers if the module always chooses the first suitable one.
+// Demoted servers are listed after all the others.
 func (s requester) CanSendTo() []Server {
 	s.requesterLock.RLock()
 	defer s.requesterLock.RUnlock()
 
-	list := make([]Server, 0, len(s.serverOrder))
+	var (
+		list    = make([]Server, 0, len(s.serverOrder))
+		demoted []Server
+	)
 	for _, server := range s.serverOrder {
 		if server.canRequestNow() {
-			list = append(list, server)
+			if _, ok := s.demoted[server]; ok {
+				demoted = append(demoted, server)
+			} else {
+				list = append(list, server)
+			}
 		}
 	}
-	return list
+	return append(list, demoted...)
 }
 
 // Send sends a request and adds an entry to Scheduler.pending map, ensuring that
@@ -3,6 +3,8 @@ package request
 import (
 	"reflect"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
 )
 
 func TestEventFilter(t *testing.T) {
@@ -64,14 +66,69 @@ func TestEventFilter(t *testing.T) {
 	s.Stop()
 }
 
+func TestSchedulerScoring(t *testing.T) {
+	s := NewScheduler()
+	clock := &mclock.Simulated{}
+	s.clock = clock
+	module := &testModule{name: "module"}
+	s.RegisterModule(module, "module")
+	// process rounds are run directly in order to avoid timing issues
+	round := func() {
+		s.lock.Lock()
+		s.processRound()
+		s.lock.Unlock()
+	}
+	srv1, srv2 := &testServer{name: "srv1"}, &testServer{name: "srv2"}
+	s.RegisterServer(srv1)
+	s.RegisterServer(srv2)
+	round()
+	module.expEvents(t, []Event{
+		{Type: EvRegistered, Server: srv1},
+		{Type: EvRegistered, Server: srv2},
+	})
+	// the most recently registered server comes first, unless it's demoted
+	srv1.canRequest, srv2.canRequest = 1, 1
+	srv2.scoreValue = demoteScore - 1
+	module.sendReq = testRequest
+	round()
+	if srv1.canRequest != 0 || srv2.canRequest != 1 {
+		t.Fatalf("Request not sent to the non-demoted server")
+	}
+	// demoted servers are still used if no others are available
+	round()
+	if srv2.canRequest != 0 {
+		t.Fatalf("Request not sent to the demoted server")
+	}
+	module.sendReq = nil
+	module.expEvents(t, nil)
+
+	// servers with a very low score are disconnected and registered again later
+	srv2.scoreValue = disconnectScore - 1
+	round()
+	module.expEvents(t, []Event{
+		{Type: EvFail, Server: srv2, Data: RequestResponse{ID: 1, Request: testRequest}},
+		{Type: EvUnregistered, Server: srv2},
+	})
+	clock.Run(disconnectDelay)
+	round()
+	module.expEvents(t, []Event{
+		{Type: EvRegistered, Server: srv2},
+	})
+	if srv2.scoreValue != demoteScore {
+		t.Fatalf("Wrong score of reconnected server (expected %d, got %f)", demoteScore, srv2.scoreValue)
+	}
+}
+
 type testServer struct {
+	name       string
 	eventCb    func(Event)
 	lastID     ID
 	canRequest int
+	scoreValue float64
 }
 
 func (s *testServer) Name() string {
-	return ""
+	return s.name
 }
 
 func (s *testServer) subscribe(eventCb func(Event)) {
@@ -88,8 +145,10 @@ func (s *testServer) sendRequest(req Request) ID {
 	return s.lastID
 }
 
-func (s *testServer) fail(string)  {}
-func (s *testServer) unsubscribe() {}
+func (s *testServer) fail(string)            {}
+func (s *testServer) unsubscribe()           {}
+func (s *testServer) score() float64         { return s.scoreValue }
+func (s *testServer) setScore(score float64) { s.scoreValue = score }
 
 type testModule struct {
 	name      string
@@ -107,6 +166,7 @@ func (m *testModule) Process(requester Requester, events []Event) {
 }
 
 func (m *testModule) expProcess(t *testing.T, expEvents []Event) {
+	t.Helper()
 	if len(m.processed) == 0 {
 		t.Errorf("Missing call to %s.Process", m.name)
 		return
@@ -118,6 +178,20 @@ func (m *testModule) expProcess(t *testing.T, expEvents []Event) {
 	}
 }
 
+// expEvents checks the events received in all the process rounds since the
+// last check.
+func (m *testModule) expEvents(t *testing.T, expEvents []Event) {
+	t.Helper()
+	var events []Event
+	for _, round := range m.processed {
+		events = append(events, round...)
+	}
+	m.processed = nil
+	if !reflect.DeepEqual(events, expEvents) {
+		t.Errorf("Wrong events received by %s (expected %v, got %v)", m.name, expEvents, events)
+	}
+}
+
 func (m *testModule) expNoMoreProcess(t *testing.T) {
 	for len(m.processed) > 0 {
 		t.Errorf("Unexpected call to %s.Process with events %v", m.name, m.processed[0])
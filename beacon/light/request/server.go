@@ -53,6 +53,18 @@ const (
 	maxServerEventRate   = time.Second            // server event allowance buffer recharge rate
 )
 
+const (
+	// server scoring parameters
+	scoreTimeout    = -1               // score change in case of a soft timeout
+	scoreFailure    = -4               // score change in case of a failed request
+	scoreInvalid    = -10              // score change in case of invalid data reported by a module
+	scoreResponse   = 0.5              // score change in case of a successful response
+	maxScore        = 10               // score upper bound
+	scoreHalfLife   = time.Minute * 10 // half-life of the score decaying towards zero
+	demoteScore     = -20              // servers below this score are only used if no others are available
+	disconnectScore = -50              // servers below this score are temporarily disconnected
+)
+
 // requestServer can send requests in a non-blocking way and feed back events
 // through the event callback. After each request it should send back either
 // EvResponse or EvFail. Additionally, it may also send application-defined
@@ -75,6 +87,8 @@ type server interface {
 	canRequestNow() bool
 	sendRequest(Request) ID
 	fail(string)
+	score() float64
+	setScore(float64)
 	unsubscribe()
 }
 
@@ -254,6 +268,8 @@ type serverWithLimits struct {
 	failureDelay               float64
 	serverEventBuffer          int
 	eventBufferUpdated         mclock.AbsTime
+	scoreValue                 float64
+	scoreUpdated               mclock.AbsTime
 }
 
 // init initializes serverWithLimits
@@ -287,6 +303,7 @@ func (s *serverWithLimits) eventCallback(event Event) {
 		if s.parallelLimit < minParallelLimit {
 			s.parallelLimit = minParallelLimit
 		}
+		s.addScore(scoreTimeout)
 		log.Debug("Server timeout", "count", s.timeoutCount, "parallelLimit", s.parallelLimit)
 	case EvResponse, EvFail:
 		id := event.Data.(RequestResponse).ID
@@ -304,7 +321,10 @@ func (s *serverWithLimits) eventCallback(event Event) {
 			s.sendEvent = false
 		}
 		if event.Type == EvFail {
+			s.addScore(scoreFailure)
 			s.failLocked("failed request")
+		} else {
+			s.addScore(scoreResponse)
 		}
 	default:
 		// server event; check rate limit
@@ -427,9 +447,47 @@ func (s *serverWithLimits) fail(desc string) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.addScore(scoreInvalid)
 	s.failLocked(desc)
 }
 
+// score returns the current score of the server. The score decays towards zero
+// over time, so that past misbehavior is eventually forgiven.
+func (s *serverWithLimits) score() float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.decayScore()
+	return s.scoreValue
+}
+
+// setScore overrides the current score of the server.
+func (s *serverWithLimits) setScore(score float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.scoreValue, s.scoreUpdated = score, s.clock.Now()
+}
+
+// addScore applies the decay and the given change to the score.
+func (s *serverWithLimits) addScore(change float64) {
+	s.decayScore()
+	s.scoreValue += change
+	if s.scoreValue > maxScore {
+		s.scoreValue = maxScore
+	}
+}
+
+// decayScore decays the score according to the time passed since the last
+// update.
+func (s *serverWithLimits) decayScore() {
+	now := s.clock.Now()
+	if now > s.scoreUpdated {
+		s.scoreValue *= math.Pow(2, -float64(now-s.scoreUpdated)/float64(scoreHalfLife))
+	}
+	s.scoreUpdated = now
+}
+
 // failLocked calculates the dynamic failure delay and applies it.
 func (s *serverWithLimits) failLocked(desc string) {
 	log.Debug("Server error", "description", desc)
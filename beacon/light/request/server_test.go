@@ -1,6 +1,7 @@
 package request
 
 import (
+	"math"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common/mclock"
@@ -123,6 +124,41 @@ func TestServerFail(t *testing.T) {
 	srv.unsubscribe()
 }
 
+func TestServerScore(t *testing.T) {
+	rs := &testRequestServer{}
+	clock := &mclock.Simulated{}
+	srv := NewServer(rs, clock)
+	srv.subscribe(func(event Event) {})
+	expScore := func(expScore float64) {
+		t.Helper()
+		if score := srv.score(); math.Abs(score-expScore) > 1e-9 {
+			t.Errorf("Wrong server score (expected %f, got %f)", expScore, score)
+		}
+	}
+	expScore(0)
+	// successful responses raise the score up to its limit
+	for id := ID(1); id <= 30; id++ {
+		srv.sendRequest(testRequest)
+		rs.eventCb(Event{Type: EvResponse, Data: RequestResponse{ID: id, Request: testRequest, Response: testResponse}})
+	}
+	expScore(maxScore)
+	// invalid responses, failures and timeouts lower it
+	srv.fail("")
+	expScore(maxScore + scoreInvalid)
+	srv.sendRequest(testRequest)
+	rs.eventCb(Event{Type: EvFail, Data: RequestResponse{ID: 31, Request: testRequest}})
+	expScore(maxScore + scoreInvalid + scoreFailure)
+	// the score decays towards zero
+	clock.Run(scoreHalfLife)
+	expScore((maxScore + scoreInvalid + scoreFailure) / 2)
+	srv.setScore(demoteScore)
+	srv.sendRequest(testRequest)
+	clock.WaitForTimers(1)
+	clock.Run(softRequestTimeout)
+	expScore(demoteScore*math.Pow(2, -float64(softRequestTimeout)/float64(scoreHalfLife)) + scoreTimeout)
+	srv.unsubscribe()
+}
+
 func TestServerEventRateLimit(t *testing.T) {
 	rs := &testRequestServer{}
 	clock := &mclock.Simulated{}
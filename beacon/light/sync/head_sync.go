@@ -17,13 +17,24 @@
 package sync
 
 import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/beacon/light"
 	"github.com/ethereum/go-ethereum/beacon/light/request"
 	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	finalityDisagreementMeter = metrics.NewRegisteredMeter("beacon/sync/finality/disagreement", nil)
+	finalityInconsistentMeter = metrics.NewRegisteredMeter("beacon/sync/finality/inconsistent", nil)
 )
 
 type headTracker interface {
 	ValidateHead(head types.SignedHeader) (bool, error)
 	ValidateFinality(head types.FinalityUpdate) (bool, error)
+	ValidatedFinality() (types.FinalityUpdate, bool)
 	SetPrefetchHead(head types.HeadInfo)
 }
 
@@ -32,6 +43,9 @@ type headTracker interface {
 // registered servers.
 // It can also postpone the validation of the latest announced signed head
 // until the committee chain is synced up to at least the required period.
+// Servers announcing heads with invalid signatures or finality updates which
+// are inconsistent with the validated one are reported as failed, while the
+// finalized headers announced by different servers are cross-checked.
 type HeadSync struct {
 	headTracker         headTracker
 	chain               committeeChain
@@ -40,6 +54,7 @@ type HeadSync struct {
 	unvalidatedHeads    map[request.Server]types.SignedHeader
 	unvalidatedFinality map[request.Server]types.FinalityUpdate
 	serverHeads         map[request.Server]types.HeadInfo
+	serverFinalized     map[request.Server]types.Header // latest finalized header announced by each server
 	headServerCount     map[types.HeadInfo]headServerCount
 	headCounter         uint64
 	prefetchHead        types.HeadInfo
@@ -62,6 +77,7 @@ func NewHeadSync(headTracker headTracker, chain committeeChain) *HeadSync {
 		unvalidatedHeads:    make(map[request.Server]types.SignedHeader),
 		unvalidatedFinality: make(map[request.Server]types.FinalityUpdate),
 		serverHeads:         make(map[request.Server]types.HeadInfo),
+		serverFinalized:     make(map[request.Server]types.Header),
 		headServerCount:     make(map[types.HeadInfo]headServerCount),
 	}
 	return s
@@ -74,63 +90,108 @@ func (s *HeadSync) Process(requester request.Requester, events []request.Event)
 		case EvNewHead:
 			s.setServerHead(event.Server, event.Data.(types.HeadInfo))
 		case EvNewSignedHead:
-			s.newSignedHead(event.Server, event.Data.(types.SignedHeader))
+			s.newSignedHead(requester, event.Server, event.Data.(types.SignedHeader))
 		case EvNewFinalityUpdate:
-			s.newFinalityUpdate(event.Server, event.Data.(types.FinalityUpdate))
+			s.newFinalityUpdate(requester, event.Server, event.Data.(types.FinalityUpdate))
 		case request.EvUnregistered:
 			s.setServerHead(event.Server, types.HeadInfo{})
 			delete(s.serverHeads, event.Server)
+			delete(s.serverFinalized, event.Server)
 			delete(s.unvalidatedHeads, event.Server)
+			delete(s.unvalidatedFinality, event.Server)
 		}
 	}
 
 	nextPeriod, chainInit := s.chain.NextSyncPeriod()
 	if nextPeriod != s.nextSyncPeriod || chainInit != s.chainInit {
 		s.nextSyncPeriod, s.chainInit = nextPeriod, chainInit
-		s.processUnvalidated()
+		s.processUnvalidated(requester)
 	}
 }
 
 // newSignedHead handles received signed head; either validates it if the chain
 // is properly synced or stores it for further validation.
-func (s *HeadSync) newSignedHead(server request.Server, signedHead types.SignedHeader) {
+func (s *HeadSync) newSignedHead(requester request.Requester, server request.Server, signedHead types.SignedHeader) {
 	if !s.chainInit || types.SyncPeriod(signedHead.SignatureSlot) > s.nextSyncPeriod {
 		s.unvalidatedHeads[server] = signedHead
 		return
 	}
-	s.headTracker.ValidateHead(signedHead)
+	s.validateHead(requester, server, signedHead)
 }
 
 // newFinalityUpdate handles received finality update; either validates it if the chain
 // is properly synced or stores it for further validation.
-func (s *HeadSync) newFinalityUpdate(server request.Server, finalityUpdate types.FinalityUpdate) {
+func (s *HeadSync) newFinalityUpdate(requester request.Requester, server request.Server, finalityUpdate types.FinalityUpdate) {
+	s.crossCheckFinality(server, finalityUpdate.Finalized.Header)
 	if !s.chainInit || types.SyncPeriod(finalityUpdate.SignatureSlot) > s.nextSyncPeriod {
 		s.unvalidatedFinality[server] = finalityUpdate
 		return
 	}
-	s.headTracker.ValidateFinality(finalityUpdate)
+	s.validateFinality(requester, server, finalityUpdate)
 }
 
 // processUnvalidated iterates the list of unvalidated heads and validates
 // those which can be validated.
-func (s *HeadSync) processUnvalidated() {
+func (s *HeadSync) processUnvalidated(requester request.Requester) {
 	if !s.chainInit {
 		return
 	}
 	for server, signedHead := range s.unvalidatedHeads {
 		if types.SyncPeriod(signedHead.SignatureSlot) <= s.nextSyncPeriod {
-			s.headTracker.ValidateHead(signedHead)
+			s.validateHead(requester, server, signedHead)
 			delete(s.unvalidatedHeads, server)
 		}
 	}
 	for server, finalityUpdate := range s.unvalidatedFinality {
 		if types.SyncPeriod(finalityUpdate.SignatureSlot) <= s.nextSyncPeriod {
-			s.headTracker.ValidateFinality(finalityUpdate)
+			s.validateFinality(requester, server, finalityUpdate)
 			delete(s.unvalidatedFinality, server)
 		}
 	}
 }
 
+// validateHead validates a signed head and reports the server as failed if the
+// signature is invalid.
+func (s *HeadSync) validateHead(requester request.Requester, server request.Server, signedHead types.SignedHeader) {
+	if _, err := s.headTracker.ValidateHead(signedHead); errors.Is(err, light.ErrInvalidSignature) {
+		requester.Fail(server, "invalid signed head")
+	}
+}
+
+// validateFinality validates a finality update and reports the server as failed
+// if either the update is invalid or its finalized header contradicts the one
+// of the validated finality update.
+func (s *HeadSync) validateFinality(requester request.Requester, server request.Server, finalityUpdate types.FinalityUpdate) {
+	_, err := s.headTracker.ValidateFinality(finalityUpdate)
+	if errors.Is(err, light.ErrInvalidSignature) || errors.Is(err, light.ErrInvalidFinality) {
+		requester.Fail(server, "invalid finality update")
+		return
+	}
+	validated, ok := s.headTracker.ValidatedFinality()
+	if !ok {
+		return
+	}
+	finalized := finalityUpdate.Finalized.Header
+	if validated.Finalized.Header.Slot == finalized.Slot && validated.Finalized.Header != finalized {
+		log.Warn("Inconsistent finality update", "server", server.Name(), "slot", finalized.Slot, "have", finalized.Hash(), "want", validated.Finalized.Header.Hash())
+		finalityInconsistentMeter.Mark(1)
+		requester.Fail(server, "inconsistent finality update")
+	}
+}
+
+// crossCheckFinality compares the finalized header announced by a server with
+// the ones announced by the other servers, reporting any disagreement about
+// the header finalized at the same slot.
+func (s *HeadSync) crossCheckFinality(server request.Server, finalized types.Header) {
+	for other, header := range s.serverFinalized {
+		if other != server && header.Slot == finalized.Slot && header != finalized {
+			log.Warn("Beacon servers disagree on finalized header", "slot", finalized.Slot, "server", server.Name(), "hash", finalized.Hash(), "other", other.Name(), "otherHash", header.Hash())
+			finalityDisagreementMeter.Mark(1)
+		}
+	}
+	s.serverFinalized[server] = finalized
+}
+
 // setServerHead processes non-validated server head announcements and updates
 // the prefetch head if necessary.
 func (s *HeadSync) setServerHead(server request.Server, head types.HeadInfo) bool {
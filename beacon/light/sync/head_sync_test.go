@@ -155,3 +155,76 @@ func TestPrefetchHead(t *testing.T) {
 	ts.Run(10)
 	ht.ExpPrefetch(t, 10, testHead0) // no servers registered
 }
+
+func TestInvalidHead(t *testing.T) {
+	chain := &TestCommitteeChain{}
+	ht := &TestHeadTracker{}
+	headSync := NewHeadSync(ht, chain)
+	ts := NewTestScheduler(t, headSync)
+
+	chain.SetNextSyncPeriod(2)
+	ht.SetInvalid(testSHead2.Header)
+	ts.AddServer(testServer1, 1)
+	ts.AddServer(testServer2, 1)
+	ts.ServerEvent(EvNewSignedHead, testServer1, testSHead1)
+	ts.ServerEvent(EvNewSignedHead, testServer2, testSHead2)
+	ts.ExpFail(testServer2)
+	ts.Run(1)
+	ht.ExpValidated(t, 1, []types.SignedHeader{testSHead1})
+
+	// queued heads are also checked when validated later
+	chain.SetNextSyncPeriod(1)
+	ts.Run(2)
+	ts.ServerEvent(EvNewSignedHead, testServer2, testSHead3)
+	ht.SetInvalid(testSHead3.Header)
+	ts.Run(3)
+	ht.ExpValidated(t, 3, nil)
+	chain.SetNextSyncPeriod(2)
+	ts.ExpFail(testServer2)
+	ts.Run(4)
+	ht.ExpValidated(t, 4, nil)
+}
+
+func TestFinalityCrossCheck(t *testing.T) {
+	chain := &TestCommitteeChain{}
+	ht := &TestHeadTracker{}
+	headSync := NewHeadSync(ht, chain)
+	ts := NewTestScheduler(t, headSync)
+
+	var (
+		finalized1 = types.Header{Slot: 0x0100, StateRoot: common.Hash{1}}
+		finalized2 = types.Header{Slot: 0x0100, StateRoot: common.Hash{2}}
+		update1    = types.FinalityUpdate{
+			Attested:      types.HeaderWithExecProof{Header: testSHead1.Header},
+			Finalized:     types.HeaderWithExecProof{Header: finalized1},
+			SignatureSlot: testSHead1.SignatureSlot,
+		}
+		update2 = types.FinalityUpdate{
+			Attested:      types.HeaderWithExecProof{Header: types.Header{Slot: 0x0123, StateRoot: common.Hash{5}}},
+			Finalized:     types.HeaderWithExecProof{Header: finalized2},
+			SignatureSlot: testSHead1.SignatureSlot,
+		}
+	)
+	chain.SetNextSyncPeriod(0)
+	ts.AddServer(testServer1, 1)
+	ts.AddServer(testServer2, 1)
+	ts.AddServer(testServer3, 1)
+	ts.ServerEvent(EvNewFinalityUpdate, testServer1, update1)
+	ts.Run(1)
+	if meter := finalityDisagreementMeter.Snapshot().Count(); meter != 0 {
+		t.Errorf("Unexpected finality disagreement (count %d)", meter)
+	}
+	// an update with a validly signed but contradicting finalized header is
+	// reported as disagreement and inconsistency
+	ts.ServerEvent(EvNewFinalityUpdate, testServer2, update2)
+	ts.ExpFail(testServer2)
+	ts.Run(2)
+	if finality, _ := ht.ValidatedFinality(); finality.Finalized.Header != finalized1 {
+		t.Errorf("Wrong validated finality (expected %x, got %x)", finalized1.Hash(), finality.Finalized.Header.Hash())
+	}
+	// an invalid update is reported as failed
+	ht.SetInvalid(update2.Attested.Header)
+	ts.ServerEvent(EvNewFinalityUpdate, testServer3, update2)
+	ts.ExpFail(testServer3)
+	ts.Run(3)
+}
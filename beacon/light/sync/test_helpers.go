@@ -211,20 +211,44 @@ func (tc *TestCommitteeChain) ExpNextSyncPeriod(t *testing.T, expNsp uint64) {
 }
 
 type TestHeadTracker struct {
-	phead     types.HeadInfo
-	validated []types.SignedHeader
+	phead       types.HeadInfo
+	validated   []types.SignedHeader
+	finality    types.FinalityUpdate
+	hasFinality bool
+	invalid     map[types.Header]struct{}
+}
+
+// SetInvalid marks the signature of the given header as invalid.
+func (ht *TestHeadTracker) SetInvalid(header types.Header) {
+	if ht.invalid == nil {
+		ht.invalid = make(map[types.Header]struct{})
+	}
+	ht.invalid[header] = struct{}{}
 }
 
 func (ht *TestHeadTracker) ValidateHead(head types.SignedHeader) (bool, error) {
+	if _, ok := ht.invalid[head.Header]; ok {
+		return false, light.ErrInvalidSignature
+	}
 	ht.validated = append(ht.validated, head)
 	return true, nil
 }
 
-// TODO add test case for finality
-func (ht *TestHeadTracker) ValidateFinality(head types.FinalityUpdate) (bool, error) {
+func (ht *TestHeadTracker) ValidateFinality(update types.FinalityUpdate) (bool, error) {
+	if _, ok := ht.invalid[update.Attested.Header]; ok {
+		return false, light.ErrInvalidSignature
+	}
+	if ht.hasFinality && update.Attested.Header.Slot <= ht.finality.Attested.Header.Slot {
+		return false, nil
+	}
+	ht.finality, ht.hasFinality = update, true
 	return true, nil
 }
 
+func (ht *TestHeadTracker) ValidatedFinality() (types.FinalityUpdate, bool) {
+	return ht.finality, ht.hasFinality
+}
+
 func (ht *TestHeadTracker) ExpValidated(t *testing.T, tci int, expHeads []types.SignedHeader) {
 	for i, expHead := range expHeads {
 		if i >= len(ht.validated) {
@@ -70,11 +70,15 @@ type serverState struct {
 	hasHeader, canonical, finalized bool // stored per server because not validated
 }
 
-// NewCheckpointInit creates a new CheckpointInit.
+// NewCheckpointInit creates a new CheckpointInit. If the committee chain has
+// already been initialized by a previous run, the checkpoint is not needed and
+// no bootstrap data is requested.
 func NewCheckpointInit(chain committeeChain, checkpointHash common.Hash) *CheckpointInit {
+	_, initialized := chain.NextSyncPeriod()
 	return &CheckpointInit{
 		chain:          chain,
 		checkpointHash: checkpointHash,
+		initialized:    initialized,
 		serverState:    make(map[request.Server]serverState),
 	}
 }
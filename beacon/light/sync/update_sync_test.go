@@ -61,6 +61,17 @@ func TestCheckpointInit(t *testing.T) {
 	chain.ExpInit(t, true)
 }
 
+func TestCheckpointInitResumed(t *testing.T) {
+	// chain already initialized by a previous run; expect no bootstrap request
+	chain := &TestCommitteeChain{}
+	chain.SetNextSyncPeriod(4)
+	checkpoint := types.Header{Slot: 0x2000 * 4}
+	chkInit := NewCheckpointInit(chain, checkpoint.Hash())
+	ts := NewTestScheduler(t, chkInit)
+	ts.AddServer(testServer1, 1)
+	ts.Run(1)
+}
+
 func TestUpdateSyncParallel(t *testing.T) {
 	chain := &TestCommitteeChain{}
 	chain.SetNextSyncPeriod(0)
@@ -64,6 +64,11 @@ func NewExecutionHeader(obj headerObject) *ExecutionHeader {
 	return &ExecutionHeader{obj: obj}
 }
 
+// MarshalJSON encodes the execution header in the format of the beacon chain API.
+func (eh *ExecutionHeader) MarshalJSON() ([]byte, error) {
+	return json.Marshal(eh.obj)
+}
+
 func (eh *ExecutionHeader) PayloadRoot() merkle.Value {
 	return merkle.Value(eh.obj.HashTreeRoot(tree.GetHashFn()))
 }
@@ -20,13 +20,21 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 
 	"github.com/ethereum/go-ethereum/beacon/blsync"
+	"github.com/ethereum/go-ethereum/beacon/types"
 	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
@@ -60,12 +68,17 @@ func main() {
 		utils.BeaconGenesisRootFlag,
 		utils.BeaconGenesisTimeFlag,
 		utils.BeaconCheckpointFlag,
-		//TODO datadir for optional permanent database
+		utils.BeaconRetentionFlag,
+		utils.DataDirFlag,
 		utils.MainnetFlag,
 		utils.SepoliaFlag,
 		utils.GoerliFlag,
 		utils.BlsyncApiFlag,
 		utils.BlsyncJWTSecretFlag,
+		utils.BlsyncProxyUpstreamFlag,
+		utils.BlsyncProxyAddrFlag,
+		utils.HTTPCORSDomainFlag,
+		utils.HTTPVirtualHostsFlag,
 		verbosityFlag,
 		vmoduleFlag,
 	}
@@ -87,8 +100,16 @@ func sync(ctx *cli.Context) error {
 	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(output, verbosity, usecolor)))
 
 	// set up blsync
-	client := blsync.NewClient(ctx)
-	client.SetEngineRPC(makeRPCClient(ctx))
+	db := makeDatabase(ctx)
+	defer db.Close()
+
+	client := blsync.NewClient(ctx, db)
+	if ctx.IsSet(utils.BlsyncProxyUpstreamFlag.Name) {
+		stop := startProxy(ctx, client)
+		defer stop()
+	} else {
+		client.SetEngineRPC(makeRPCClient(ctx))
+	}
 	client.Start()
 
 	// run until stopped
@@ -97,6 +118,79 @@ func sync(ctx *cli.Context) error {
 	return nil
 }
 
+// startProxy starts the verified RPC proxy serving the wallets from the
+// untrusted upstream endpoint, in place of driving an execution client. It
+// returns a function stopping the proxy.
+func startProxy(ctx *cli.Context, client *blsync.Client) func() {
+	upstream, err := rpc.Dial(ctx.String(utils.BlsyncProxyUpstreamFlag.Name))
+	if err != nil {
+		utils.Fatalf("Could not connect to upstream RPC: %v", err)
+	}
+	// The execution chain config is only known for the built-in networks,
+	// calls can't be executed on the custom ones
+	var config *params.ChainConfig
+	switch {
+	case ctx.Bool(utils.SepoliaFlag.Name):
+		config = params.SepoliaChainConfig
+	case ctx.Bool(utils.GoerliFlag.Name):
+		config = params.GoerliChainConfig
+	case !ctx.IsSet(utils.BeaconConfigFlag.Name):
+		config = params.MainnetChainConfig
+	default:
+		log.Warn("Unknown execution chain config, eth_call is disabled")
+	}
+	proxy := blsync.NewProxy(upstream, config)
+	headCh := make(chan types.ChainHeadEvent, 16)
+	sub := client.SubscribeChainHead(headCh)
+	proxy.Start(headCh)
+
+	server := rpc.NewServer()
+	for _, api := range proxy.APIs() {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			utils.Fatalf("Could not register proxy API: %v", err)
+		}
+	}
+	var (
+		cors   = utils.SplitAndTrim(ctx.String(utils.HTTPCORSDomainFlag.Name))
+		vhosts = utils.SplitAndTrim(ctx.String(utils.HTTPVirtualHostsFlag.Name))
+		addr   = ctx.String(utils.BlsyncProxyAddrFlag.Name)
+	)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		utils.Fatalf("Could not listen on %s: %v", addr, err)
+	}
+	httpServer := &http.Server{Handler: node.NewHTTPHandlerStack(server, cors, vhosts, nil)}
+	go httpServer.Serve(listener)
+	log.Info("Verified RPC proxy started", "addr", listener.Addr(), "upstream", ctx.String(utils.BlsyncProxyUpstreamFlag.Name))
+
+	return func() {
+		httpServer.Close()
+		server.Stop()
+		sub.Unsubscribe()
+		proxy.Stop()
+		upstream.Close()
+	}
+}
+
+// makeDatabase opens the database persisting the committee chain in the data
+// directory, if one is specified. Otherwise the chain is kept in memory and
+// every run starts from the checkpoint.
+func makeDatabase(ctx *cli.Context) ethdb.KeyValueStore {
+	if !ctx.IsSet(utils.DataDirFlag.Name) {
+		return memorydb.New()
+	}
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory: filepath.Join(ctx.String(utils.DataDirFlag.Name), "blsync"),
+		Namespace: "blsync/db/",
+		Cache:     16,
+		Handles:   16,
+	})
+	if err != nil {
+		utils.Fatalf("Could not open database: %v", err)
+	}
+	return db
+}
+
 func makeRPCClient(ctx *cli.Context) *rpc.Client {
 	if !ctx.IsSet(utils.BlsyncApiFlag.Name) {
 		log.Warn("No engine API target specified, performing a dry run")
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/urfave/cli/v2"
 )
 
@@ -38,6 +39,8 @@ var (
 			discv5CrawlCommand,
 			discv5TestCommand,
 			discv5ListenCommand,
+			discv5RegisterCommand,
+			discv5TopicSearchCommand,
 		},
 	}
 	discv5PingCommand = &cli.Command{
@@ -77,8 +80,30 @@ var (
 		Action: discv5Listen,
 		Flags:  discoveryNodeFlags,
 	}
+	discv5RegisterCommand = &cli.Command{
+		Name:      "register",
+		Usage:     "Runs a node advertising itself under a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5Register,
+		Flags:     discoveryNodeFlags,
+	}
+	discv5TopicSearchCommand = &cli.Command{
+		Name:      "topicsearch",
+		Usage:     "Finds nodes advertised under a topic",
+		ArgsUsage: "<topic>",
+		Action:    discv5TopicSearch,
+		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
+			topicSearchTimeoutFlag,
+		}),
+	}
 )
 
+var topicSearchTimeoutFlag = &cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "Time limit for the search.",
+	Value: time.Minute,
+}
+
 func discv5Ping(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
 	disc, _ := startV5(ctx)
@@ -138,6 +163,40 @@ func discv5Listen(ctx *cli.Context) error {
 	select {}
 }
 
+func discv5Register(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need topic as argument")
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	disc.RegisterTopic(ctx.Args().First())
+	fmt.Println(disc.Self())
+	select {}
+}
+
+func discv5TopicSearch(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need topic as argument")
+	}
+	disc, _ := startV5(ctx)
+	defer disc.Close()
+
+	it := disc.TopicSearch(ctx.Args().First())
+	defer it.Close()
+	timeout := time.AfterFunc(ctx.Duration(topicSearchTimeoutFlag.Name), it.Close)
+	defer timeout.Stop()
+
+	seen := make(map[enode.ID]bool)
+	for it.Next() {
+		if n := it.Node(); !seen[n.ID()] {
+			seen[n.ID()] = true
+			fmt.Println(n)
+		}
+	}
+	return nil
+}
+
 // startV5 starts an ephemeral discovery v5 node.
 func startV5(ctx *cli.Context) (*discover.UDPv5, discover.Config) {
 	ln, config := makeDiscoveryConfig(ctx)
@@ -67,11 +67,24 @@ func (s *Suite) dialAs(key *ecdsa.PrivateKey) (*Conn, error) {
 	conn.caps = []p2p.Cap{
 		{Name: "eth", Version: 67},
 		{Name: "eth", Version: 68},
+		{Name: "eth", Version: 69},
 	}
-	conn.ourHighestProtoVersion = 68
+	conn.ourHighestProtoVersion = 69
 	return &conn, nil
 }
 
+// dialEth68 creates a connection which only advertises eth/68, in order to test
+// that the node falls back to the older protocol version.
+func (s *Suite) dialEth68() (*Conn, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn.caps = []p2p.Cap{{Name: "eth", Version: 68}}
+	conn.ourHighestProtoVersion = 68
+	return conn, nil
+}
+
 // dialSnap creates a connection with snap/1 capability.
 func (s *Suite) dialSnap() (*Conn, error) {
 	conn, err := s.dial()
@@ -112,7 +125,7 @@ func (c *Conn) ReadMsg(proto Proto, code uint64, msg any) error {
 		if err != nil {
 			return err
 		}
-		if protoOffset(proto)+code == got {
+		if c.protoOffset(proto)+code == got {
 			return rlp.DecodeBytes(data, msg)
 		}
 	}
@@ -125,7 +138,7 @@ func (c *Conn) Write(proto Proto, code uint64, msg any) error {
 	if err != nil {
 		return err
 	}
-	_, err = c.Conn.Write(protoOffset(proto)+code, payload)
+	_, err = c.Conn.Write(c.protoOffset(proto)+code, payload)
 	return err
 }
 
@@ -141,7 +154,7 @@ func (c *Conn) ReadEth() (any, error) {
 			c.Write(baseProto, pongMsg, []byte{})
 			continue
 		}
-		if getProto(code) != ethProto {
+		if c.getProto(code) != ethProto {
 			// Read until eth message.
 			continue
 		}
@@ -150,7 +163,11 @@ func (c *Conn) ReadEth() (any, error) {
 		var msg any
 		switch int(code) {
 		case eth.StatusMsg:
-			msg = new(eth.StatusPacket)
+			if c.negotiatedProtoVersion >= eth.ETH69 {
+				msg = new(eth.StatusPacket69)
+			} else {
+				msg = new(eth.StatusPacket)
+			}
 		case eth.GetBlockHeadersMsg:
 			msg = new(eth.GetBlockHeadersPacket)
 		case eth.BlockHeadersMsg:
@@ -171,6 +188,16 @@ func (c *Conn) ReadEth() (any, error) {
 			msg = new(eth.GetPooledTransactionsPacket)
 		case eth.PooledTransactionsMsg:
 			msg = new(eth.PooledTransactionsPacket)
+		case eth.GetReceiptsMsg:
+			msg = new(eth.GetReceiptsPacket)
+		case eth.ReceiptsMsg:
+			if c.negotiatedProtoVersion >= eth.ETH69 {
+				msg = new(eth.ReceiptsPacket69)
+			} else {
+				msg = new(eth.ReceiptsPacket)
+			}
+		case eth.BlockRangeUpdateMsg:
+			msg = new(eth.BlockRangeUpdatePacket)
 		default:
 			panic(fmt.Sprintf("unhandled eth msg code %d", code))
 		}
@@ -189,11 +216,11 @@ func (c *Conn) ReadSnap() (any, error) {
 		if err != nil {
 			return nil, err
 		}
-		if getProto(code) != snapProto {
+		if c.getProto(code) != snapProto {
 			// Read until snap message.
 			continue
 		}
-		code -= baseProtoLen + ethProtoLen
+		code -= baseProtoLen + c.ethProtoLen()
 
 		var msg any
 		switch int(code) {
@@ -225,7 +252,7 @@ func (c *Conn) ReadSnap() (any, error) {
 
 // peer performs both the protocol handshake and the status message
 // exchange with the node in order to peer with it.
-func (c *Conn) peer(chain *Chain, status *eth.StatusPacket) error {
+func (c *Conn) peer(chain *Chain, status any) error {
 	if err := c.handshake(); err != nil {
 		return fmt.Errorf("handshake failed: %v", err)
 	}
@@ -297,8 +324,10 @@ func (c *Conn) negotiateEthProtocol(caps []p2p.Cap) {
 	c.negotiatedSnapProtoVersion = highestSnapVersion
 }
 
-// statusExchange performs a `Status` message exchange with the given node.
-func (c *Conn) statusExchange(chain *Chain, status *eth.StatusPacket) error {
+// statusExchange performs a `Status` message exchange with the given node. The
+// status packet to send must match the negotiated protocol version, if nil, the
+// default status of the chain is sent.
+func (c *Conn) statusExchange(chain *Chain, status any) error {
 loop:
 	for {
 		code, data, err := c.Read()
@@ -306,23 +335,9 @@ loop:
 			return fmt.Errorf("failed to read from connection: %w", err)
 		}
 		switch code {
-		case eth.StatusMsg + protoOffset(ethProto):
-			msg := new(eth.StatusPacket)
-			if err := rlp.DecodeBytes(data, &msg); err != nil {
-				return fmt.Errorf("error decoding status packet: %w", err)
-			}
-			if have, want := msg.Head, chain.blocks[chain.Len()-1].Hash(); have != want {
-				return fmt.Errorf("wrong head block in status, want:  %#x (block %d) have %#x",
-					want, chain.blocks[chain.Len()-1].NumberU64(), have)
-			}
-			if have, want := msg.TD.Cmp(chain.TD()), 0; have != want {
-				return fmt.Errorf("wrong TD in status: have %v want %v", have, want)
-			}
-			if have, want := msg.ForkID, chain.ForkID(); !reflect.DeepEqual(have, want) {
-				return fmt.Errorf("wrong fork ID in status: have %v, want %v", have, want)
-			}
-			if have, want := msg.ProtocolVersion, c.ourHighestProtoVersion; have != uint32(want) {
-				return fmt.Errorf("wrong protocol version: have %v, want %v", have, want)
+		case eth.StatusMsg + c.protoOffset(ethProto):
+			if err := c.checkStatus(chain, data); err != nil {
+				return err
 			}
 			break loop
 		case discMsg:
@@ -344,18 +359,81 @@ loop:
 		return errors.New("eth protocol version must be set in Conn")
 	}
 	if status == nil {
-		// default status message
-		status = &eth.StatusPacket{
+		status = c.defaultStatus(chain)
+	}
+	if err := c.Write(ethProto, eth.StatusMsg, status); err != nil {
+		return fmt.Errorf("write to connection failed: %v", err)
+	}
+	return nil
+}
+
+// checkStatus verifies the status message received from the node against the
+// chain, decoding it according to the negotiated protocol version.
+func (c *Conn) checkStatus(chain *Chain, data []byte) error {
+	var (
+		head    = chain.Head()
+		version uint32
+		forkID  any
+	)
+	if c.negotiatedProtoVersion >= eth.ETH69 {
+		msg := new(eth.StatusPacket69)
+		if err := rlp.DecodeBytes(data, &msg); err != nil {
+			return fmt.Errorf("error decoding status packet: %w", err)
+		}
+		if have, want := msg.LatestBlockHash, head.Hash(); have != want {
+			return fmt.Errorf("wrong head block in status, want:  %#x (block %d) have %#x",
+				want, head.NumberU64(), have)
+		}
+		if have, want := msg.LatestBlock, head.NumberU64(); have != want {
+			return fmt.Errorf("wrong latest block in status: have %d, want %d", have, want)
+		}
+		if msg.EarliestBlock > msg.LatestBlock {
+			return fmt.Errorf("invalid block range in status: earliest %d > latest %d", msg.EarliestBlock, msg.LatestBlock)
+		}
+		version, forkID = msg.ProtocolVersion, msg.ForkID
+	} else {
+		msg := new(eth.StatusPacket)
+		if err := rlp.DecodeBytes(data, &msg); err != nil {
+			return fmt.Errorf("error decoding status packet: %w", err)
+		}
+		if have, want := msg.Head, head.Hash(); have != want {
+			return fmt.Errorf("wrong head block in status, want:  %#x (block %d) have %#x",
+				want, head.NumberU64(), have)
+		}
+		if have, want := msg.TD.Cmp(chain.TD()), 0; have != want {
+			return fmt.Errorf("wrong TD in status: have %v want %v", have, want)
+		}
+		version, forkID = msg.ProtocolVersion, msg.ForkID
+	}
+	if have, want := forkID, chain.ForkID(); !reflect.DeepEqual(have, want) {
+		return fmt.Errorf("wrong fork ID in status: have %v, want %v", have, want)
+	}
+	if have, want := version, c.negotiatedProtoVersion; have != uint32(want) {
+		return fmt.Errorf("wrong protocol version: have %v, want %v", have, want)
+	}
+	return nil
+}
+
+// defaultStatus creates the status message of the chain for the negotiated
+// protocol version.
+func (c *Conn) defaultStatus(chain *Chain) any {
+	if c.negotiatedProtoVersion >= eth.ETH69 {
+		return &eth.StatusPacket69{
 			ProtocolVersion: uint32(c.negotiatedProtoVersion),
 			NetworkID:       chain.config.ChainID.Uint64(),
-			TD:              chain.TD(),
-			Head:            chain.blocks[chain.Len()-1].Hash(),
 			Genesis:         chain.blocks[0].Hash(),
 			ForkID:          chain.ForkID(),
+			EarliestBlock:   0,
+			LatestBlock:     chain.Head().NumberU64(),
+			LatestBlockHash: chain.Head().Hash(),
 		}
 	}
-	if err := c.Write(ethProto, eth.StatusMsg, status); err != nil {
-		return fmt.Errorf("write to connection failed: %v", err)
+	return &eth.StatusPacket{
+		ProtocolVersion: uint32(c.negotiatedProtoVersion),
+		NetworkID:       chain.config.ChainID.Uint64(),
+		TD:              chain.TD(),
+		Head:            chain.Head().Hash(),
+		Genesis:         chain.blocks[0].Hash(),
+		ForkID:          chain.ForkID(),
 	}
-	return nil
 }
@@ -17,9 +17,11 @@
 package ethtest
 
 import (
+	"bytes"
 	"crypto/rand"
 	"math/big"
 	"reflect"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
@@ -30,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
 )
 
@@ -65,6 +68,7 @@ func (s *Suite) EthTests() []utesting.Test {
 	return []utesting.Test{
 		// status
 		{Name: "Status", Fn: s.TestStatus},
+		{Name: "StatusFallback68", Fn: s.TestStatusFallback68},
 		// get block headers
 		{Name: "GetBlockHeaders", Fn: s.TestGetBlockHeaders},
 		{Name: "SimultaneousRequests", Fn: s.TestSimultaneousRequests},
@@ -72,6 +76,10 @@ func (s *Suite) EthTests() []utesting.Test {
 		{Name: "ZeroRequestID", Fn: s.TestZeroRequestID},
 		// get block bodies
 		{Name: "GetBlockBodies", Fn: s.TestGetBlockBodies},
+		// get receipts
+		{Name: "GetReceipts", Fn: s.TestGetReceipts},
+		// block range announcement
+		{Name: "BlockRangeUpdate", Fn: s.TestBlockRangeUpdate},
 		// // malicious handshakes + status
 		{Name: "MaliciousHandshake", Fn: s.TestMaliciousHandshake},
 		{Name: "MaliciousStatus", Fn: s.TestMaliciousStatus},
@@ -107,6 +115,23 @@ func (s *Suite) TestStatus(t *utesting.T) {
 	}
 }
 
+func (s *Suite) TestStatusFallback68(t *utesting.T) {
+	t.Log(`This test performs an eth protocol handshake only advertising eth/68, and
+checks that the node falls back to the older protocol version.`)
+
+	conn, err := s.dialEth68()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if conn.negotiatedProtoVersion != eth.ETH68 {
+		t.Fatalf("wrong protocol version negotiated: have %d, want %d", conn.negotiatedProtoVersion, eth.ETH68)
+	}
+}
+
 // headersMatch returns whether the received headers match the given request
 func headersMatch(expected []*types.Header, headers []*types.Header) bool {
 	return reflect.DeepEqual(expected, headers)
@@ -371,6 +396,142 @@ func (s *Suite) TestGetBlockBodies(t *utesting.T) {
 	}
 }
 
+func (s *Suite) TestGetReceipts(t *utesting.T) {
+	t.Log(`This test sends GetReceipts requests to the node for known blocks in the test chain.
+On eth/69, the receipts are sent without bloom filters, which are recomputed locally
+in order to verify the receipts against the block headers.`)
+
+	conn, err := s.dial()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if conn.negotiatedProtoVersion < eth.ETH69 {
+		t.Fatalf("eth/69 not negotiated: have eth/%d", conn.negotiatedProtoVersion)
+	}
+	// Request the receipts of a few blocks containing transactions.
+	var blocks []*types.Block
+	for _, block := range s.chain.blocks {
+		if len(block.Transactions()) > 0 {
+			blocks = append(blocks, block)
+		}
+		if len(blocks) == 4 {
+			break
+		}
+	}
+	if len(blocks) == 0 {
+		t.Fatalf("no blocks with transactions in the test chain")
+	}
+	req := &eth.GetReceiptsPacket{RequestId: 66}
+	for _, block := range blocks {
+		req.GetReceiptsRequest = append(req.GetReceiptsRequest, block.Hash())
+	}
+	if err := conn.Write(ethProto, eth.GetReceiptsMsg, req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	// Wait for response, receipts with bloom filters fail to decode.
+	resp := new(eth.ReceiptsPacket69)
+	if err := conn.ReadMsg(ethProto, eth.ReceiptsMsg, &resp); err != nil {
+		t.Fatalf("error reading receipts msg: %v", err)
+	}
+	if got, want := resp.RequestId, req.RequestId; got != want {
+		t.Fatalf("unexpected request id in response: have %d, want %d", got, want)
+	}
+	if len(resp.ReceiptsResponse69) != len(blocks) {
+		t.Fatalf("wrong receipts in response: expected %d lists, got %d", len(blocks), len(resp.ReceiptsResponse69))
+	}
+	for i, list := range resp.ReceiptsResponse69 {
+		receipts := make(types.Receipts, len(list))
+		for j, enc := range list {
+			receipt := &types.Receipt{
+				Type:              enc.TxType,
+				CumulativeGasUsed: enc.CumulativeGasUsed,
+				Logs:              enc.Logs,
+			}
+			switch {
+			case bytes.Equal(enc.PostStateOrStatus, []byte{0x01}):
+				receipt.Status = types.ReceiptStatusSuccessful
+			case len(enc.PostStateOrStatus) == 0:
+				receipt.Status = types.ReceiptStatusFailed
+			default:
+				receipt.PostState = enc.PostStateOrStatus
+			}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipts[j] = receipt
+		}
+		if have, want := types.DeriveSha(receipts, trie.NewStackTrie(nil)), blocks[i].ReceiptHash(); have != want {
+			t.Fatalf("wrong receipts of block %d: root %v, want %v", blocks[i].NumberU64(), have, want)
+		}
+	}
+}
+
+func (s *Suite) TestBlockRangeUpdate(t *utesting.T) {
+	t.Log(`This test sends BlockRangeUpdate messages to the node. A valid range is accepted,
+while an invalid range with the earliest block after the latest one causes a disconnect.`)
+
+	conn, err := s.dial()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	if conn.negotiatedProtoVersion < eth.ETH69 {
+		t.Fatalf("eth/69 not negotiated: have eth/%d", conn.negotiatedProtoVersion)
+	}
+	// Announce a valid range and check the connection is still usable.
+	update := &eth.BlockRangeUpdatePacket{
+		EarliestBlock:   1,
+		LatestBlock:     s.chain.Head().NumberU64(),
+		LatestBlockHash: s.chain.Head().Hash(),
+	}
+	if err := conn.Write(ethProto, eth.BlockRangeUpdateMsg, update); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	req := &eth.GetBlockHeadersPacket{
+		RequestId: 77,
+		GetBlockHeadersRequest: &eth.GetBlockHeadersRequest{
+			Origin: eth.HashOrNumber{Number: 1},
+			Amount: 1,
+		},
+	}
+	if err := conn.Write(ethProto, eth.GetBlockHeadersMsg, req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	headers := new(eth.BlockHeadersPacket)
+	if err := conn.ReadMsg(ethProto, eth.BlockHeadersMsg, &headers); err != nil {
+		t.Fatalf("error reading block headers msg after valid range update: %v", err)
+	}
+	// Announce an invalid range and wait for the disconnect.
+	update.EarliestBlock = update.LatestBlock + 1
+	if err := conn.Write(ethProto, eth.BlockRangeUpdateMsg, update); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		code, _, _, err := conn.Conn.Read()
+		if err != nil {
+			// Client may have disconnected without sending disconnect msg.
+			return
+		}
+		switch code {
+		case discMsg:
+			return
+		case pingMsg:
+			conn.Write(baseProto, pongMsg, []byte{})
+		default:
+			if conn.getProto(code) == ethProto && code-baseProtoLen == eth.BlockRangeUpdateMsg {
+				continue
+			}
+			t.Fatalf("expected disconnect, got: %d", code)
+		}
+	}
+}
+
 // randBuf makes a random buffer size kilobytes large.
 func randBuf(size int) []byte {
 	buf := make([]byte, size*1024)
@@ -464,8 +625,9 @@ func (s *Suite) TestMaliciousStatus(t *utesting.T) {
 	if err := conn.handshake(); err != nil {
 		t.Fatalf("handshake failed: %v", err)
 	}
-	// Create status with large total difficulty.
-	status := &eth.StatusPacket{
+	// Create status with large total difficulty, or with an invalid block range
+	// on eth/69 which doesn't contain the total difficulty anymore.
+	var status any = &eth.StatusPacket{
 		ProtocolVersion: uint32(conn.negotiatedProtoVersion),
 		NetworkID:       s.chain.config.ChainID.Uint64(),
 		TD:              new(big.Int).SetBytes(randBuf(2048)),
@@ -473,6 +635,17 @@ func (s *Suite) TestMaliciousStatus(t *utesting.T) {
 		Genesis:         s.chain.GetBlock(0).Hash(),
 		ForkID:          s.chain.ForkID(),
 	}
+	if conn.negotiatedProtoVersion >= eth.ETH69 {
+		status = &eth.StatusPacket69{
+			ProtocolVersion: uint32(conn.negotiatedProtoVersion),
+			NetworkID:       s.chain.config.ChainID.Uint64(),
+			Genesis:         s.chain.GetBlock(0).Hash(),
+			ForkID:          s.chain.ForkID(),
+			EarliestBlock:   s.chain.Head().NumberU64() + 1,
+			LatestBlock:     s.chain.Head().NumberU64(),
+			LatestBlockHash: s.chain.Head().Hash(),
+		}
+	}
 	if err := conn.statusExchange(s.chain, status); err != nil {
 		t.Fatalf("status exchange failed: %v", err)
 	}
@@ -42,12 +42,12 @@ var app = flags.NewApp("go-ethereum era tool")
 var (
 	dirFlag = &cli.StringFlag{
 		Name:  "dir",
-		Usage: "directory storing all relevant era1 files",
+		Usage: "directory storing all relevant era files",
 		Value: "eras",
 	}
 	networkFlag = &cli.StringFlag{
 		Name:  "network",
-		Usage: "network name associated with era1 files",
+		Usage: "network name associated with era files",
 		Value: "mainnet",
 	}
 	eraSizeFlag = &cli.IntFlag{
@@ -80,7 +80,7 @@ var (
 	verifyCommand = &cli.Command{
 		Name:      "verify",
 		ArgsUsage: "<expected>",
-		Usage:     "verifies each era1 against expected accumulator root",
+		Usage:     "verifies each era1 against expected accumulator root and each post-merge era against expected last block hash",
 		Action:    verify,
 	}
 )
@@ -105,21 +105,29 @@ func main() {
 	}
 }
 
-// block prints the specified block from an era1 store.
+// block prints the specified block from an era store.
 func block(ctx *cli.Context) error {
 	num, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
 	if err != nil {
 		return fmt.Errorf("invalid block number: %w", err)
 	}
-	e, err := open(ctx, num/uint64(ctx.Int(eraSizeFlag.Name)))
+	eras, err := open(ctx, num/uint64(ctx.Int(eraSizeFlag.Name)))
 	if err != nil {
-		return fmt.Errorf("error opening era1: %w", err)
+		return fmt.Errorf("error opening era: %w", err)
 	}
-	defer e.Close()
-	// Read block with number.
-	block, err := e.GetBlockByNumber(num)
-	if err != nil {
-		return fmt.Errorf("error reading block %d: %w", num, err)
+	defer closeAll(eras)
+
+	// Read block with number from the era covering it.
+	var block *types.Block
+	for _, e := range eras {
+		if e.Start() <= num && num < e.Start()+e.Count() {
+			if block, err = e.GetBlockByNumber(num); err != nil {
+				return fmt.Errorf("error reading block %d: %w", num, err)
+			}
+		}
+	}
+	if block == nil {
+		return fmt.Errorf("block %d not found", num)
 	}
 	// Convert block to JSON and print.
 	val := ethapi.RPCMarshalBlock(block, ctx.Bool(txsFlag.Name), ctx.Bool(txsFlag.Name), params.MainnetChainConfig)
@@ -131,56 +139,95 @@ func block(ctx *cli.Context) error {
 	return nil
 }
 
-// info prints some high-level information about the era1 file.
+// info prints some high-level information about the era files of an epoch.
 func info(ctx *cli.Context) error {
 	epoch, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
 	if err != nil {
 		return fmt.Errorf("invalid epoch number: %w", err)
 	}
-	e, err := open(ctx, epoch)
+	eras, err := open(ctx, epoch)
 	if err != nil {
 		return err
 	}
-	defer e.Close()
-	acc, err := e.Accumulator()
-	if err != nil {
-		return fmt.Errorf("error reading accumulator: %w", err)
-	}
-	td, err := e.InitialTD()
-	if err != nil {
-		return fmt.Errorf("error reading total difficulty: %w", err)
-	}
-	info := struct {
-		Accumulator     common.Hash `json:"accumulator"`
-		TotalDifficulty *big.Int    `json:"totalDifficulty"`
-		StartBlock      uint64      `json:"startBlock"`
-		Count           uint64      `json:"count"`
-	}{
-		acc, td, e.Start(), e.Count(),
+	defer closeAll(eras)
+
+	for _, e := range eras {
+		info := struct {
+			Accumulator     *common.Hash `json:"accumulator,omitempty"`
+			TotalDifficulty *big.Int     `json:"totalDifficulty,omitempty"`
+			PostMerge       bool         `json:"postMerge"`
+			StartBlock      uint64       `json:"startBlock"`
+			Count           uint64       `json:"count"`
+		}{
+			PostMerge:  e.PostMerge(),
+			StartBlock: e.Start(),
+			Count:      e.Count(),
+		}
+		if !e.PostMerge() {
+			acc, err := e.Accumulator()
+			if err != nil {
+				return fmt.Errorf("error reading accumulator: %w", err)
+			}
+			td, err := e.InitialTD()
+			if err != nil {
+				return fmt.Errorf("error reading total difficulty: %w", err)
+			}
+			info.Accumulator, info.TotalDifficulty = &acc, td
+		}
+		b, _ := json.MarshalIndent(info, "", "  ")
+		fmt.Println(string(b))
 	}
-	b, _ := json.MarshalIndent(info, "", "  ")
-	fmt.Println(string(b))
 	return nil
 }
 
-// open opens an era1 file at a certain epoch.
-func open(ctx *cli.Context, epoch uint64) (*era.Era, error) {
+// open opens the era files at a certain epoch. The epoch of the merge is
+// split between an era1 and a post-merge file.
+func open(ctx *cli.Context, epoch uint64) ([]*era.Era, error) {
 	var (
 		dir     = ctx.String(dirFlag.Name)
 		network = ctx.String(networkFlag.Name)
+		names   []string
 	)
 	entries, err := era.ReadDir(dir, network)
 	if err != nil {
 		return nil, fmt.Errorf("error reading era dir: %w", err)
 	}
-	if epoch >= uint64(len(entries)) {
-		return nil, fmt.Errorf("epoch out-of-bounds: last %d, want %d", len(entries)-1, epoch)
+	merged, first, err := era.ReadPostMergeDir(dir, network)
+	if err != nil {
+		return nil, fmt.Errorf("error reading era dir: %w", err)
+	}
+	if epoch < uint64(len(entries)) {
+		names = append(names, entries[epoch])
+	}
+	if epoch >= uint64(first) && epoch < uint64(first+len(merged)) {
+		names = append(names, merged[epoch-uint64(first)])
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("epoch %d not found", epoch)
+	}
+	var eras []*era.Era
+	for _, name := range names {
+		e, err := era.Open(filepath.Join(dir, name))
+		if err != nil {
+			closeAll(eras)
+			return nil, err
+		}
+		eras = append(eras, e)
+	}
+	return eras, nil
+}
+
+// closeAll closes the given era files.
+func closeAll(eras []*era.Era) {
+	for _, e := range eras {
+		e.Close()
 	}
-	return era.Open(filepath.Join(dir, entries[epoch]))
 }
 
-// verify checks each era1 file in a directory to ensure it is well-formed and
-// that the accumulator matches the expected value.
+// verify checks each era file in a directory to ensure it is well-formed and
+// that it matches the expected root. The roots are listed for the era1 files
+// first, followed by the post-merge ones. For era1 the root is the accumulator,
+// for the post-merge files it's the hash of the last block.
 func verify(ctx *cli.Context) error {
 	if ctx.Args().Len() != 1 {
 		return errors.New("missing accumulators file")
@@ -202,9 +249,14 @@ func verify(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", dir, err)
 	}
+	merged, _, err := era.ReadPostMergeDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	entries = append(entries, merged...)
 
 	if len(entries) != len(roots) {
-		return errors.New("number of era1 files should match the number of accumulator hashes")
+		return errors.New("number of era files should match the number of expected roots")
 	}
 
 	// Verify each epoch matches the expected root.
@@ -214,22 +266,29 @@ func verify(ctx *cli.Context) error {
 			name := entries[i]
 			e, err := era.Open(filepath.Join(dir, name))
 			if err != nil {
-				return fmt.Errorf("error opening era1 file %s: %w", name, err)
+				return fmt.Errorf("error opening era file %s: %w", name, err)
 			}
 			defer e.Close()
-			// Read accumulator and check against expected.
-			if got, err := e.Accumulator(); err != nil {
-				return fmt.Errorf("error retrieving accumulator for %s: %w", name, err)
-			} else if got != want {
-				return fmt.Errorf("invalid root %s: got %s, want %s", name, got, want)
-			}
-			// Recompute accumulator.
-			if err := checkAccumulator(e); err != nil {
-				return fmt.Errorf("error verify era1 file %s: %w", name, err)
+			if e.PostMerge() {
+				// Post-merge files are verified against their last block.
+				if _, err := era.VerifyCheckpoint(e, want); err != nil {
+					return fmt.Errorf("error verify era file %s: %w", name, err)
+				}
+			} else {
+				// Read accumulator and check against expected.
+				if got, err := e.Accumulator(); err != nil {
+					return fmt.Errorf("error retrieving accumulator for %s: %w", name, err)
+				} else if got != want {
+					return fmt.Errorf("invalid root %s: got %s, want %s", name, got, want)
+				}
+				// Recompute accumulator.
+				if err := checkAccumulator(e); err != nil {
+					return fmt.Errorf("error verify era1 file %s: %w", name, err)
+				}
 			}
 			// Give the user some feedback that something is happening.
 			if time.Since(reported) >= 8*time.Second {
-				fmt.Printf("Verifying Era1 files \t\t verified=%d,\t elapsed=%s\n", i, common.PrettyDuration(time.Since(start)))
+				fmt.Printf("Verifying Era files \t\t verified=%d,\t elapsed=%s\n", i, common.PrettyDuration(time.Since(start)))
 				reported = time.Now()
 			}
 			return nil
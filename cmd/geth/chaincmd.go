@@ -140,7 +140,8 @@ be gzipped.`,
 		),
 		Description: `
 The import-history command will import blocks and their corresponding receipts
-from Era archives.
+from Era archives. The post-merge archives (.erae) are verified against the
+hashes of their last blocks listed in the checkpoints.txt file of the directory.
 `,
 	}
 	exportHistoryCommand = &cli.Command{
@@ -151,7 +152,10 @@ from Era archives.
 		Flags:     flags.Merge(utils.DatabaseFlags),
 		Description: `
 The export-history command will export blocks and their corresponding receipts
-into Era archives. Eras are typically packaged in steps of 8192 blocks.
+into Era archives. Eras are typically packaged in steps of 8192 blocks. The
+pre-merge blocks are exported into era1 archives, the post-merge ones into
+archives without total difficulty (.erae), alongside with a checkpoints.txt
+file listing the hashes of their last blocks.
 `,
 	}
 	importPreimagesCommand = &cli.Command{
@@ -438,12 +442,16 @@ func importHistory(ctx *cli.Context) error {
 			if err != nil {
 				return fmt.Errorf("error reading %s: %w", dir, err)
 			}
-			if len(entries) > 0 {
+			merged, _, err := era.ReadPostMergeDir(dir, n)
+			if err != nil {
+				return fmt.Errorf("error reading %s: %w", dir, err)
+			}
+			if len(entries) > 0 || len(merged) > 0 {
 				networks = append(networks, n)
 			}
 		}
 		if len(networks) == 0 {
-			return fmt.Errorf("no era1 files found in %s", dir)
+			return fmt.Errorf("no era files found in %s", dir)
 		}
 		if len(networks) > 1 {
 			return errors.New("multiple networks found, use a network flag to specify desired network")
@@ -258,7 +258,11 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		// Start blsync mode.
 		srv := rpc.NewServer()
 		srv.RegisterName("engine", catalyst.NewConsensusAPI(eth))
-		blsyncer := blsync.NewClient(ctx)
+		db, err := stack.OpenDatabase("blsync", 16, 16, "eth/db/blsync/", false)
+		if err != nil {
+			utils.Fatalf("Failed to open beacon light client database: %v", err)
+		}
+		blsyncer := blsync.NewClient(ctx, db)
 		blsyncer.SetEngineRPC(rpc.DialInProc(srv))
 		stack.RegisterLifecycle(blsyncer)
 	} else {
@@ -267,6 +271,10 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		if err != nil {
 			utils.Fatalf("failed to register catalyst service: %v", err)
 		}
+		if ctx.IsSet(utils.BeaconLightServerFlag.Name) {
+			url := ctx.String(utils.BeaconLightServerFlag.Name)
+			utils.RegisterBeaconLightServer(stack, url, ctx.Uint64(utils.BeaconRetentionFlag.Name))
+		}
 	}
 	return stack
 }
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -54,6 +55,14 @@ var (
 		Name:  "remove.chain",
 		Usage: "If set, selects the state data for removal",
 	}
+	dbVerifyRepairFlag = &cli.BoolFlag{
+		Name:  "repair",
+		Usage: "If set, truncates or rebuilds the inconsistent parts of the database",
+	}
+	dbVerifyReportFlag = &cli.StringFlag{
+		Name:  "report",
+		Usage: "File to write the JSON verification report to",
+	}
 
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
@@ -83,6 +92,9 @@ Remove blockchain and state databases`,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
 			dbInspectHistoryCmd,
+			dbLogIndexCmd,
+			dbVerifyCmd,
+			dbMigrateKeyspacesCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -229,6 +241,54 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command queries the history of the account or storage slot within the specified block range",
 	}
+	dbLogIndexCmd = &cli.Command{
+		Action:    inspectLogIndex,
+		Name:      "logindex",
+		Usage:     "Look up the blocks referencing an address or topic in the log index",
+		ArgsUsage: "<address|topic>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			&cli.Uint64Flag{
+				Name:  "start",
+				Usage: "block number of the range start, zero means the index tail",
+			},
+			&cli.Uint64Flag{
+				Name:  "end",
+				Usage: "block number of the range end(included), zero means the chain head",
+			},
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: "This command queries the log index for the blocks containing logs emitted by the given address or carrying the given topic.",
+	}
+	dbVerifyCmd = &cli.Command{
+		Action: dbVerify,
+		Name:   "verify",
+		Usage:  "Verify the consistency of the database, optionally repairing it",
+		Flags: flags.Merge([]cli.Flag{
+			dbVerifyRepairFlag,
+			dbVerifyReportFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command checks the chain freezer against the header chain and the head
+markers in the key-value store. For the path-based state scheme, it also walks the
+persistent state against the snapshot and validates the continuity of the state
+histories.
+
+With --repair, the freezer is truncated to the last consistent item, the head markers
+are rewound, an inconsistent snapshot is dropped for regeneration and the unusable
+state histories are truncated. The command fails if inconsistencies remain.`,
+	}
+	dbMigrateKeyspacesCmd = &cli.Command{
+		Action:    migrateKeyspaces,
+		Name:      "migrate-keyspaces",
+		Usage:     "Split the chain database into keyspaces tuned for the stored data",
+		ArgsUsage: "",
+		Flags: flags.Merge([]cli.Flag{
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command moves the chain data, trie nodes and snapshot entries of a pebble
+chain database into separate keyspace databases, each one tuned for the kind of data
+it stores. An interrupted migration is resumed by running the command again.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -263,6 +323,11 @@ func removeFolder(dir string) {
 		if path == dir {
 			return nil
 		}
+		// The keyspace databases are part of the key-value store
+		if info.IsDir() && info.Name() == rawdb.KeyspaceDirectory {
+			os.RemoveAll(path)
+			return filepath.SkipDir
+		}
 		// Delete all the files, but not subfolders
 		if !info.IsDir() {
 			os.Remove(path)
@@ -786,6 +851,87 @@ func showMetaData(ctx *cli.Context) error {
 	return nil
 }
 
+// logIndexLookupStep is the number of blocks looked up at once in the log index
+// by the logindex command, between two progress reports.
+const logIndexLookupStep = 100_000
+
+func inspectLogIndex(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	key, err := hexutil.Decode(ctx.Args().Get(0))
+	if err != nil {
+		return fmt.Errorf("invalid address or topic: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	tail := rawdb.ReadLogIndexTail(db)
+	if tail == nil {
+		return errors.New("log index is not available")
+	}
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		return errors.New("head header is not available")
+	}
+	start, end := ctx.Uint64("start"), ctx.Uint64("end")
+	if start < *tail {
+		start = *tail
+	}
+	if end == 0 {
+		end = head.Number.Uint64()
+	}
+	var lookup func(from, to uint64) []rawdb.LogIndexEntry
+	switch len(key) {
+	case common.AddressLength:
+		lookup = func(from, to uint64) []rawdb.LogIndexEntry {
+			return rawdb.ReadLogIndexByAddress(db, common.BytesToAddress(key), from, to)
+		}
+	case common.HashLength:
+		lookup = func(from, to uint64) []rawdb.LogIndexEntry {
+			return rawdb.ReadLogIndexByTopic(db, common.BytesToHash(key), from, to)
+		}
+	default:
+		return fmt.Errorf("invalid address or topic length: %d", len(key))
+	}
+	// Look up the range in chunks, reporting the progress of wide lookups
+	var (
+		entries []rawdb.LogIndexEntry
+		total   = end - start + 1
+		begin   = time.Now()
+		logged  = time.Now()
+	)
+	for from := start; from <= end; from += logIndexLookupStep {
+		to := min(from+logIndexLookupStep-1, end)
+		entries = append(entries, lookup(from, to)...)
+
+		if time.Since(logged) > 8*time.Second {
+			var (
+				done  = to - start + 1
+				speed = done/uint64(time.Since(begin)/time.Millisecond+1) + 1 // +1 to avoid division by zero
+				eta   = time.Duration((total-done)/speed) * time.Millisecond
+			)
+			log.Info("Looking up log index", "blocks", done, "total", total, "percent", fmt.Sprintf("%.2f%%", float64(done)*100/float64(total)),
+				"matches", len(entries), "elapsed", common.PrettyDuration(time.Since(begin)), "eta", common.PrettyDuration(eta))
+			logged = time.Now()
+		}
+		if to == end {
+			break // Avoid overflowing at the end of the number space
+		}
+	}
+	fmt.Printf("Log index:\n\tkey: %#x\n\tindexed: [#%d-#%d]\n\tblockrange: [#%d-#%d]\n\tblocks: %d\n", key, *tail, head.Number.Uint64(), start, end, len(entries))
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Block", "Log positions"})
+	for _, entry := range entries {
+		table.Append([]string{fmt.Sprintf("%d", entry.Number), fmt.Sprintf("%v", entry.Logs)})
+	}
+	table.Render()
+	return nil
+}
+
 func inspectAccount(db *triedb.Database, start uint64, end uint64, address common.Address, raw bool) error {
 	stats, err := db.AccountHistory(address, start, end)
 	if err != nil {
@@ -927,3 +1073,22 @@ func inspectHistory(ctx *cli.Context) error {
 	}
 	return inspectStorage(triedb, start, end, address, slot, ctx.Bool("raw"))
 }
+
+func migrateKeyspaces(ctx *cli.Context) error {
+	if ctx.NArg() != 0 {
+		return fmt.Errorf("this command requires no arguments")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		cache   = ctx.Int(utils.CacheFlag.Name) * ctx.Int(utils.CacheDatabaseFlag.Name) / 100
+		handles = utils.MakeDatabaseHandles(ctx.Int(utils.FDLimitFlag.Name))
+		start   = time.Now()
+	)
+	if err := rawdb.MigrateKeyspaces(stack.ResolvePath("chaindata"), cache, handles, ""); err != nil {
+		return err
+	}
+	log.Info("Split database into keyspaces", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
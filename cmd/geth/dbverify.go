@@ -0,0 +1,484 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/urfave/cli/v2"
+)
+
+// maxVerifyIssues is the maximum number of individual issues recorded per
+// check, the rest is only counted.
+const maxVerifyIssues = 32
+
+// Possible outcomes of a verification check.
+const (
+	verifyStatusOK        = "ok"
+	verifyStatusSkipped   = "skipped"
+	verifyStatusCorrupted = "corrupted"
+	verifyStatusRepaired  = "repaired"
+	verifyStatusFailed    = "failed"
+)
+
+// verifyReport is the machine-readable result of the database verification.
+type verifyReport struct {
+	Time   time.Time       `json:"time"`
+	Repair bool            `json:"repair"`
+	Scheme string          `json:"scheme"`
+	Checks []*verifyResult `json:"checks"`
+}
+
+// verifyResult is the outcome of a single verification check.
+type verifyResult struct {
+	Name    string      `json:"name"`
+	Status  string      `json:"status"`
+	Issues  []string    `json:"issues,omitempty"`
+	Omitted int         `json:"omitted,omitempty"` // Number of issues not listed
+	Actions []string    `json:"actions,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+
+	fatal bool // Whether an issue can't be repaired
+}
+
+// issue records an inconsistency found by the check.
+func (r *verifyResult) issue(format string, args ...interface{}) {
+	if len(r.Issues) >= maxVerifyIssues {
+		r.Omitted++
+		return
+	}
+	r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+}
+
+// fail records an inconsistency which can't be repaired.
+func (r *verifyResult) fail(format string, args ...interface{}) {
+	r.issue(format, args...)
+	r.fatal = true
+}
+
+// finish sets the status of the check after the optional repair.
+func (r *verifyResult) finish() *verifyResult {
+	switch {
+	case r.Status != "":
+	case len(r.Issues) == 0:
+		r.Status = verifyStatusOK
+	case r.fatal:
+		r.Status = verifyStatusFailed
+	case len(r.Actions) > 0:
+		r.Status = verifyStatusRepaired
+	default:
+		r.Status = verifyStatusCorrupted
+	}
+	log.Info("Verified database", "check", r.Name, "status", r.Status, "issues", len(r.Issues)+r.Omitted, "actions", len(r.Actions))
+	return r
+}
+
+func dbVerify(ctx *cli.Context) error {
+	repair := ctx.Bool(dbVerifyRepairFlag.Name)
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, !repair)
+	defer db.Close()
+
+	report := &verifyReport{
+		Time:   time.Now().UTC(),
+		Repair: repair,
+		Scheme: rawdb.ReadStateScheme(db),
+	}
+	report.Checks = append(report.Checks, verifyChainFreezer(db, repair).finish())
+	if report.Scheme == rawdb.PathScheme {
+		triedb := utils.MakeTrieDatabase(ctx, db, false, true, false)
+		report.Checks = append(report.Checks, verifyDiskLayer(db, triedb, repair).finish())
+		triedb.Close()
+
+		report.Checks = append(report.Checks, verifyStateHistory(db, repair).finish())
+	}
+	if path := ctx.String(dbVerifyReportFlag.Name); path != "" {
+		blob, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, append(blob, '\n'), 0644); err != nil {
+			return err
+		}
+		log.Info("Wrote verification report", "file", path)
+	}
+	for _, check := range report.Checks {
+		if check.Status == verifyStatusCorrupted || check.Status == verifyStatusFailed {
+			for _, issue := range check.Issues {
+				log.Warn("Database inconsistency", "check", check.Name, "issue", issue)
+			}
+			return errors.New("database verification failed")
+		}
+	}
+	return nil
+}
+
+// verifyChainFreezer checks the items of the chain freezer, as well as their
+// link to the chain segment in the key-value store and the head markers. The
+// repair truncates the freezer to the last consistent item and rewinds the
+// head markers beyond it.
+func verifyChainFreezer(db ethdb.Database, repair bool) *verifyResult {
+	res := &verifyResult{Name: "freezer"}
+
+	frozen, err := db.Ancients()
+	if err != nil {
+		res.Status = verifyStatusSkipped
+		return res
+	}
+	// Walk the headers and hashes, checking that they are linked together
+	var (
+		limit  = frozen // Number of the first inconsistent item
+		parent common.Hash
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for number := uint64(0); number < limit; {
+		count := min(limit-number, 1024)
+		hashes, err := db.AncientRange(rawdb.ChainFreezerHashTable, number, count, 0)
+		if err != nil {
+			res.issue("failed to read hashes from %d: %v", number, err)
+			limit = number
+			break
+		}
+		headers, err := db.AncientRange(rawdb.ChainFreezerHeaderTable, number, count, 0)
+		if err != nil {
+			res.issue("failed to read headers from %d: %v", number, err)
+			limit = number
+			break
+		}
+		for i := 0; i < len(hashes) && i < len(headers); i++ {
+			var header types.Header
+			if err := rlp.DecodeBytes(headers[i], &header); err != nil {
+				res.issue("invalid header %d: %v", number, err)
+				limit = number
+				break
+			}
+			if hash := crypto.Keccak256Hash(headers[i]); !bytes.Equal(hash[:], hashes[i]) {
+				res.issue("hash mismatch of block %d: have %x, want %x", number, hashes[i], hash)
+				limit = number
+				break
+			}
+			if header.Number.Uint64() != number {
+				res.issue("header %d numbered %d", number, header.Number)
+				limit = number
+				break
+			}
+			if number > 0 && header.ParentHash != parent {
+				res.issue("block %d not linked to its parent: have %x, want %x", number, header.ParentHash, parent)
+				limit = number
+				break
+			}
+			parent = common.BytesToHash(hashes[i])
+			number++
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain freezer", "number", number, "frozen", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// Ensure the last item of every table is available
+	if limit == frozen && frozen > 0 {
+		for _, kind := range []string{rawdb.ChainFreezerBodiesTable, rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable} {
+			if _, err := db.Ancient(kind, frozen-1); err != nil {
+				res.issue("missing %s item %d: %v", kind, frozen-1, err)
+				limit = frozen - 1
+			}
+		}
+	}
+	// Ensure the key-value store continues the frozen chain
+	head := rawdb.ReadHeadHeader(db)
+	if head == nil {
+		res.fail("head header missing")
+		return res
+	}
+	if limit == frozen && frozen > 0 && head.Number.Uint64() >= frozen {
+		next := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, frozen), frozen)
+		switch {
+		case next == nil:
+			res.issue("gap between the freezer and the key-value store at block %d", frozen)
+			limit = frozen - 1
+		case next.ParentHash != parent:
+			res.issue("block %d in the key-value store not linked to the freezer", frozen)
+			limit = frozen - 1
+		}
+	}
+	// Check the head markers, they must not point beyond the consistent chain
+	markers := []struct {
+		name  string
+		hash  common.Hash
+		write func(ethdb.KeyValueWriter, common.Hash)
+	}{
+		{"header", rawdb.ReadHeadHeaderHash(db), rawdb.WriteHeadHeaderHash},
+		{"snap block", rawdb.ReadHeadFastBlockHash(db), rawdb.WriteHeadFastBlockHash},
+		{"block", rawdb.ReadHeadBlockHash(db), rawdb.WriteHeadBlockHash},
+	}
+	var rewind bool
+	for _, marker := range markers {
+		number := rawdb.ReadHeaderNumber(db, marker.hash)
+		if number == nil {
+			res.fail("head %s %x unknown", marker.name, marker.hash)
+			continue
+		}
+		if canon := rawdb.ReadCanonicalHash(db, *number); canon != marker.hash {
+			res.fail("head %s %d not canonical: have %x, want %x", marker.name, *number, marker.hash, canon)
+		}
+		if limit < frozen && *number >= limit {
+			rewind = true
+		}
+	}
+	res.Details = map[string]uint64{"frozen": frozen, "consistent": limit, "head": head.Number.Uint64()}
+	if !repair || res.fatal || len(res.Issues) == 0 {
+		return res
+	}
+	if limit == 0 {
+		res.fail("genesis block is corrupted")
+		return res
+	}
+	// Truncate the inconsistent items and rewind the head markers before them
+	if limit < frozen {
+		if _, err := db.TruncateHead(limit); err != nil {
+			res.fail("failed to truncate freezer: %v", err)
+			return res
+		}
+		res.Actions = append(res.Actions, fmt.Sprintf("truncated freezer from %d to %d items", frozen, limit))
+	}
+	if rewind || limit < frozen {
+		target := rawdb.ReadCanonicalHash(db, limit-1)
+		batch := db.NewBatch()
+		for _, marker := range markers {
+			if number := rawdb.ReadHeaderNumber(db, marker.hash); number != nil && *number >= limit {
+				marker.write(batch, target)
+				res.Actions = append(res.Actions, fmt.Sprintf("rewound head %s from %d to %d", marker.name, *number, limit-1))
+			}
+		}
+		if err := batch.Write(); err != nil {
+			res.fail("failed to rewind head markers: %v", err)
+		}
+	}
+	return res
+}
+
+// verifyDiskLayer walks the head state of the path-based trie database against
+// the state snapshot, checking that they hold the same accounts and storage
+// slots. The repair drops the snapshot, so it's regenerated from the tries on
+// startup.
+//
+// Note the head state is checked rather than the persistent one, the snapshot
+// layers are built on top of the head and the persistent state lags behind by
+// the transitions buffered in the trie database.
+func verifyDiskLayer(db ethdb.Database, triedb *triedb.Database, repair bool) *verifyResult {
+	res := &verifyResult{Name: "state"}
+
+	head := rawdb.ReadHeadBlock(db)
+	if head == nil || rawdb.ReadSnapshotRoot(db) == (common.Hash{}) {
+		res.Status = verifyStatusSkipped
+		return res
+	}
+	root := head.Root()
+	res.Details = map[string]common.Hash{"root": root}
+
+	snaptree, err := snapshot.New(snapshot.Config{CacheSize: 256, NoBuild: true}, db, triedb, head.Root())
+	if err != nil {
+		res.issue("snapshot unavailable: %v", err)
+	} else {
+		if err := walkDiskLayer(res, snaptree, triedb, root); err != nil {
+			res.issue("snapshot incomplete: %v", err)
+		}
+		snaptree.Release()
+	}
+	if !repair || res.fatal || len(res.Issues) == 0 {
+		return res
+	}
+	// The tries are intact, regenerate the snapshot from them
+	batch := db.NewBatch()
+	rawdb.DeleteSnapshotRoot(batch)
+	rawdb.DeleteSnapshotJournal(batch)
+	rawdb.DeleteSnapshotGenerator(batch)
+	if err := batch.Write(); err != nil {
+		res.fail("failed to drop snapshot: %v", err)
+		return res
+	}
+	res.Actions = append(res.Actions, "dropped the snapshot for regeneration on startup")
+	return res
+}
+
+// walkDiskLayer compares the state tries with the snapshot. The missing trie
+// nodes are reported as unrepairable, the error is returned if the snapshot
+// doesn't cover the state.
+func walkDiskLayer(res *verifyResult, snaptree *snapshot.Tree, triedb *triedb.Database, root common.Hash) error {
+	accIt, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), triedb)
+	if err != nil {
+		res.fail("state trie unavailable: %v", err)
+		return nil
+	}
+	nodeIt, err := tr.NodeIterator(nil)
+	if err != nil {
+		res.fail("state trie unavailable: %v", err)
+		return nil
+	}
+	var (
+		trieIt   = trie.NewIterator(nodeIt)
+		accounts uint64
+		start    = time.Now()
+		logged   = time.Now()
+	)
+	compare(res, "account", trieIt, accIt, func(hash common.Hash, blob []byte) {
+		accounts++
+		var account types.StateAccount
+		if err := rlp.DecodeBytes(blob, &account); err != nil || account.Root == types.EmptyRootHash {
+			return
+		}
+		stTrie, err := trie.NewStateTrie(trie.StorageTrieID(root, hash, account.Root), triedb)
+		if err != nil {
+			res.fail("storage trie of %x unavailable: %v", hash, err)
+			return
+		}
+		stNodeIt, err := stTrie.NodeIterator(nil)
+		if err != nil {
+			res.fail("storage trie of %x unavailable: %v", hash, err)
+			return
+		}
+		stIt, err := snaptree.StorageIterator(root, hash, common.Hash{})
+		if err != nil {
+			res.issue("storage snapshot of %x unavailable: %v", hash, err)
+			return
+		}
+		compare(res, fmt.Sprintf("slot of %x", hash), trie.NewIterator(stNodeIt), stIt, nil)
+		stIt.Release()
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state", "at", hash, "accounts", accounts, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	})
+	return nil
+}
+
+// compare merge-walks the leaves of a trie and the entries of the snapshot,
+// recording the differences. The callback is invoked on every matching entry.
+func compare(res *verifyResult, kind string, trieIt *trie.Iterator, snapIt snapshot.Iterator, onMatch func(common.Hash, []byte)) {
+	value := func(it snapshot.Iterator) []byte {
+		switch it := it.(type) {
+		case snapshot.AccountIterator:
+			blob, err := types.FullAccountRLP(it.Account())
+			if err != nil {
+				return nil
+			}
+			return blob
+		case snapshot.StorageIterator:
+			return it.Slot()
+		}
+		return nil
+	}
+	inTrie, inSnap := trieIt.Next(), snapIt.Next()
+	for inTrie || inSnap {
+		var cmp int
+		switch {
+		case !inSnap:
+			cmp = -1
+		case !inTrie:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(trieIt.Key, snapIt.Hash().Bytes())
+		}
+		switch {
+		case cmp < 0:
+			res.issue("%s %x missing from the snapshot", kind, trieIt.Key)
+			inTrie = trieIt.Next()
+		case cmp > 0:
+			res.issue("%s %x missing from the trie", kind, snapIt.Hash())
+			inSnap = snapIt.Next()
+		default:
+			if !bytes.Equal(trieIt.Value, value(snapIt)) {
+				res.issue("%s %x differs: trie %x, snapshot %x", kind, trieIt.Key, trieIt.Value, value(snapIt))
+			} else if onMatch != nil {
+				onMatch(common.BytesToHash(trieIt.Key), trieIt.Value)
+			}
+			inTrie, inSnap = trieIt.Next(), snapIt.Next()
+		}
+	}
+	if trieIt.Err != nil {
+		res.fail("%s trie corrupted: %v", kind, trieIt.Err)
+	}
+	if err := snapIt.Error(); err != nil {
+		res.issue("%s snapshot corrupted: %v", kind, err)
+	}
+}
+
+// verifyStateHistory checks the continuity of the state histories. The repair
+// truncates the unusable histories and restores the missing lookups.
+func verifyStateHistory(db ethdb.Database, repair bool) *verifyResult {
+	res := &verifyResult{Name: "history"}
+
+	ancient, err := db.AncientDatadir()
+	if err != nil || ancient == "" {
+		res.Status = verifyStatusSkipped
+		return res
+	}
+	freezer, err := rawdb.NewStateFreezer(ancient, !repair)
+	if err != nil {
+		res.fail("failed to open state history: %v", err)
+		return res
+	}
+	defer freezer.Close()
+
+	report, err := pathdb.VerifyStateHistory(db, freezer)
+	if err != nil {
+		res.fail("failed to read state history: %v", err)
+		return res
+	}
+	res.Details = report
+	for _, issue := range report.Issues {
+		res.issue("%s", issue)
+	}
+	if !repair || report.Healthy() {
+		return res
+	}
+	actions, err := pathdb.RepairStateHistory(db, freezer, report)
+	if err != nil {
+		res.fail("failed to repair state history: %v", err)
+		return res
+	}
+	res.Actions = append(res.Actions, actions...)
+	return res
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// newVerifyTestChain creates a path-based database with a short chain of value
+// transfers, the state of which is still partially buffered in memory and
+// journaled on shutdown.
+func newVerifyTestChain(t *testing.T) ethdb.Database {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000)
+		gspec   = &core.Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer = types.LatestSigner(gspec.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 8, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i)}, big.NewInt(1000), params.TxGas, block.BaseFee(), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.PathScheme), gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	chain.Stop()
+	return db
+}
+
+func TestVerifyCleanDatabase(t *testing.T) {
+	db := newVerifyTestChain(t)
+	defer db.Close()
+
+	// The trie database must hold unflushed state, so the persistent state
+	// lags behind the head.
+	_, root := rawdb.ReadAccountTrieNode(db, nil)
+	if head := rawdb.ReadHeadBlock(db); types.TrieRootHash(root) == head.Root() {
+		t.Fatal("head state is persisted")
+	}
+	for _, repair := range []bool{false, true} {
+		if res := verifyChainFreezer(db, repair).finish(); res.Status != verifyStatusOK {
+			t.Fatalf("freezer check failed (repair %v): %s %v", repair, res.Status, res.Issues)
+		}
+		tdb := triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.ReadOnly})
+		res := verifyDiskLayer(db, tdb, repair).finish()
+		tdb.Close()
+		if res.Status != verifyStatusOK {
+			t.Fatalf("state check failed (repair %v): %s %v", repair, res.Status, res.Issues)
+		}
+		if res := verifyStateHistory(db, repair).finish(); res.Status != verifyStatusOK {
+			t.Fatalf("history check failed (repair %v): %s %v", repair, res.Status, res.Issues)
+		}
+	}
+	// Nothing must be touched by the repair of a healthy database
+	if rawdb.ReadSnapshotRoot(db) == (common.Hash{}) {
+		t.Fatal("snapshot dropped")
+	}
+	if len(rawdb.ReadTrieJournal(db)) == 0 {
+		t.Fatal("trie journal dropped")
+	}
+}
@@ -78,6 +78,14 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolRemoteJournalFlag,
+		utils.TxPoolRemoteRejournalFlag,
+		utils.TxPoolRemoteJournalSizeFlag,
+		utils.TxPoolRemoteJournalAgeFlag,
+		utils.TxPropPolicyFlag,
+		utils.TxPropRelaysFlag,
+		utils.TxPropStemEpochFlag,
+		utils.TxPropStemEmbargoFlag,
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
@@ -89,6 +97,12 @@ var (
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
 		utils.StateHistoryFlag,
+		utils.StateHistoryIndexFlag,
+		utils.PartialStateFlag,
+		utils.HistoryExpiryFlag,
+		utils.HistoryArchiveFlag,
+		utils.LogIndexFlag,
+		utils.LogHistoryFlag,
 		utils.LightServeFlag,    // deprecated
 		utils.LightIngressFlag,  // deprecated
 		utils.LightEgressFlag,   // deprecated
@@ -128,6 +142,7 @@ var (
 		utils.DiscoveryV4Flag,
 		utils.DiscoveryV5Flag,
 		utils.LegacyDiscoveryV5Flag, // deprecated
+		utils.DiscoveryTopicsFlag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
@@ -145,6 +160,7 @@ var (
 		utils.GpoPercentileFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
+		utils.GpoMempoolFlag,
 		configFileFlag,
 		utils.LogDebugFlag,
 		utils.LogBacktraceAtFlag,
@@ -156,6 +172,8 @@ var (
 		utils.BeaconGenesisRootFlag,
 		utils.BeaconGenesisTimeFlag,
 		utils.BeaconCheckpointFlag,
+		utils.BeaconRetentionFlag,
+		utils.BeaconLightServerFlag,
 		utils.ServerModeFlag,
 		utils.ClientModeFlag,
 	}, utils.NetworkFlags, utils.DatabaseFlags)
@@ -159,6 +159,34 @@ block is used.
 				Description: `
 The export-preimages command exports hash preimages to a flat file, in exactly
 the expected order for the overlay tree migration.
+`,
+			},
+			{
+				Action:    snapshotExport,
+				Name:      "export",
+				Usage:     "Export the state of the head block into a snapshot archive",
+				ArgsUsage: "<archive>",
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+The export command writes the flat state of the head block from the snapshot,
+along with the contract codes, the head block itself and the headers of its
+ancestors, into a chunked, checksummed and compressed archive. The snapshot
+must be fully generated.
+`,
+			},
+			{
+				Action:    snapshotImport,
+				Name:      "import",
+				Usage:     "Import the state from a snapshot archive",
+				ArgsUsage: "<archive>",
+				Flags:     flags.Merge(utils.NetworkFlags, utils.DatabaseFlags),
+				Description: `
+The import command regenerates the state tries of the configured state scheme
+from a snapshot archive, verifies them against the state root of the archived
+block and sets that block as the chain head. The header chain below it is
+restored too, while the bodies and receipts of the ancestors are treated as
+pruned history. The database must be initialized with the genesis block of the
+same network, but contain no further blocks.
 `,
 			},
 		},
@@ -661,6 +689,57 @@ func snapshotExportPreimages(ctx *cli.Context) error {
 	return utils.ExportSnapshotPreimages(chaindb, snaptree, ctx.Args().First(), root)
 }
 
+// snapshotExport exports the state of the head block into a snapshot archive.
+func snapshotExport(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	defer chaindb.Close()
+
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	triedb := utils.MakeTrieDatabase(ctx, chaindb, false, true, false)
+	defer triedb.Close()
+
+	snapConfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapConfig, chaindb, triedb, headBlock.Root())
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	return utils.ExportSnapshot(chaindb, snaptree, ctx.Args().First(), headBlock)
+}
+
+// snapshotImport imports the state from a snapshot archive.
+func snapshotImport(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	defer chaindb.Close()
+
+	scheme, err := rawdb.ParseStateScheme(ctx.String(utils.StateSchemeFlag.Name), chaindb)
+	if err != nil {
+		return err
+	}
+	return utils.ImportSnapshot(chaindb, ctx.Args().First(), scheme)
+}
+
 // checkAccount iterates the snap data layers, and looks up the given account
 // across all layers.
 func checkAccount(ctx *cli.Context) error {
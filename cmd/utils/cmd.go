@@ -25,6 +25,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -241,8 +242,10 @@ func readList(filename string) ([]string, error) {
 	return strings.Split(string(b), "\n"), nil
 }
 
-// ImportHistory imports Era1 files containing historical block information,
-// starting from genesis.
+// ImportHistory imports Era files containing historical block information,
+// starting from genesis. The era1 archives are imported first, followed by the
+// post-merge ones, which are verified against the checkpointed hashes of their
+// last blocks listed in checkpoints.txt.
 func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
 	if chain.CurrentSnapBlock().Number.BitLen() != 0 {
 		return errors.New("history import only supported when starting from genesis")
@@ -251,12 +254,26 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", dir, err)
 	}
+	merged, _, err := era.ReadPostMergeDir(dir, network)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", dir, err)
+	}
 	checksums, err := readList(filepath.Join(dir, "checksums.txt"))
 	if err != nil {
 		return fmt.Errorf("unable to read checksums.txt: %w", err)
 	}
-	if len(checksums) != len(entries) {
-		return fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries))
+	if len(checksums) != len(entries)+len(merged) {
+		return fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries)+len(merged))
+	}
+	var checkpoints []string
+	if len(merged) > 0 {
+		checkpoints, err = readList(filepath.Join(dir, "checkpoints.txt"))
+		if err != nil {
+			return fmt.Errorf("unable to read checkpoints.txt: %w", err)
+		}
+		if len(checkpoints) != len(merged) {
+			return fmt.Errorf("expected equal number of checkpoints and post-merge entries, have: %d checkpoints, %d entries", len(checkpoints), len(merged))
+		}
 	}
 	var (
 		start    = time.Now()
@@ -266,7 +283,7 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 		h        = sha256.New()
 		buf      = bytes.NewBuffer(nil)
 	)
-	for i, filename := range entries {
+	for i, filename := range append(entries, merged...) {
 		err := func() error {
 			f, err := os.Open(filepath.Join(dir, filename))
 			if err != nil {
@@ -284,11 +301,21 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 			h.Reset()
 			buf.Reset()
 
-			// Import all block data from Era1.
+			// Import all block data from Era.
 			e, err := era.From(f)
 			if err != nil {
 				return fmt.Errorf("error opening era: %w", err)
 			}
+			if postMerge := i >= len(entries); e.PostMerge() != postMerge {
+				return fmt.Errorf("unexpected era variant %s, post-merge: %v", filename, e.PostMerge())
+			} else if postMerge {
+				// There is no accumulator after the merge, verify the entire
+				// archive against the checkpoint before importing it.
+				checkpoint := common.HexToHash(checkpoints[i-len(entries)])
+				if _, err := era.VerifyCheckpoint(e, checkpoint); err != nil {
+					return fmt.Errorf("error verifying era %s: %w", filename, err)
+				}
+			}
 			it, err := era.NewIterator(e)
 			if err != nil {
 				return fmt.Errorf("error making era reader: %w", err)
@@ -402,7 +429,9 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 }
 
 // ExportHistory exports blockchain history into the specified directory,
-// following the Era format.
+// following the Era format. The blocks are packaged per epoch of step blocks,
+// the pre-merge ones into era1 archives and the post-merge ones into the
+// post-merge variant. The epoch of the merge is split between the two.
 func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) error {
 	log.Info("Exporting blockchain history", "dir", dir)
 	if head := bc.CurrentBlock().Number.Uint64(); head < last {
@@ -417,77 +446,114 @@ func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) er
 		return fmt.Errorf("error creating output directory: %w", err)
 	}
 	var (
-		start     = time.Now()
-		reported  = time.Now()
-		h         = sha256.New()
-		buf       = bytes.NewBuffer(nil)
-		checksums []string
+		start       = time.Now()
+		reported    = time.Now()
+		checksums   []string
+		checkpoints []string
 	)
-	for i := first; i <= last; i += step {
-		err := func() error {
-			filename := filepath.Join(dir, era.Filename(network, int(i/step), common.Hash{}))
-			f, err := os.Create(filename)
-			if err != nil {
-				return fmt.Errorf("could not create era file: %w", err)
-			}
-			defer f.Close()
-
-			w := era.NewBuilder(f)
-			for j := uint64(0); j < step && j <= last-i; j++ {
-				var (
-					n     = i + j
-					block = bc.GetBlockByNumber(n)
-				)
-				if block == nil {
-					return fmt.Errorf("export failed on #%d: not found", n)
-				}
-				receipts := bc.GetReceiptsByHash(block.Hash())
-				if receipts == nil {
-					return fmt.Errorf("export failed on #%d: receipts not found", n)
-				}
-				td := bc.GetTd(block.Hash(), block.NumberU64())
-				if td == nil {
-					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
-				}
-				if err := w.Add(block, receipts, td); err != nil {
-					return err
+	for i := first; i <= last; {
+		// Determine the range of the next archive, cutting it at the merge.
+		var (
+			epoch     = i / step
+			end       = min((epoch+1)*step-1, last)
+			postMerge = isPostMerge(bc, i)
+		)
+		if !postMerge {
+			for n := i + 1; n <= end; n++ {
+				if isPostMerge(bc, n) {
+					end = n - 1
+					break
 				}
 			}
-			root, err := w.Finalize()
-			if err != nil {
-				return fmt.Errorf("export failed to finalize %d: %w", step/i, err)
-			}
-			// Set correct filename with root.
-			os.Rename(filename, filepath.Join(dir, era.Filename(network, int(i/step), root)))
-
-			// Compute checksum of entire Era1.
-			if _, err := f.Seek(0, io.SeekStart); err != nil {
-				return err
-			}
-			if _, err := io.Copy(h, f); err != nil {
-				return fmt.Errorf("unable to calculate checksum: %w", err)
-			}
-			checksums = append(checksums, common.BytesToHash(h.Sum(buf.Bytes()[:])).Hex())
-			h.Reset()
-			buf.Reset()
-			return nil
-		}()
+		}
+		root, checksum, err := exportEra(bc, dir, network, epoch, i, end, postMerge)
 		if err != nil {
 			return err
 		}
+		checksums = append(checksums, checksum)
+		if postMerge {
+			checkpoints = append(checkpoints, root.Hex())
+		}
 		if time.Since(reported) >= 8*time.Second {
 			log.Info("Exporting blocks", "exported", i, "elapsed", common.PrettyDuration(time.Since(start)))
 			reported = time.Now()
 		}
+		i = end + 1
 	}
 
 	os.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm)
-
+	if len(checkpoints) > 0 {
+		os.WriteFile(filepath.Join(dir, "checkpoints.txt"), []byte(strings.Join(checkpoints, "\n")), os.ModePerm)
+	}
 	log.Info("Exported blockchain to", "dir", dir)
 
 	return nil
 }
 
+// isPostMerge reports whether the block with the given number was produced
+// after the merge, which zeroed the difficulty of the blocks.
+func isPostMerge(bc *core.BlockChain, number uint64) bool {
+	header := bc.GetHeaderByNumber(number)
+	return header != nil && header.Difficulty.Sign() == 0
+}
+
+// exportEra exports the blocks [first, last] into a single era archive of the
+// given epoch, returning its root and checksum.
+func exportEra(bc *core.BlockChain, dir, network string, epoch, first, last uint64, postMerge bool) (common.Hash, string, error) {
+	filename := filepath.Join(dir, era.Filename(network, int(epoch), common.Hash{}))
+	if postMerge {
+		filename = filepath.Join(dir, era.PostMergeFilename(network, int(epoch), common.Hash{}))
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return common.Hash{}, "", fmt.Errorf("could not create era file: %w", err)
+	}
+	defer f.Close()
+
+	w := era.NewBuilder(f)
+	if postMerge {
+		w = era.NewPostMergeBuilder(f)
+	}
+	for n := first; n <= last; n++ {
+		block := bc.GetBlockByNumber(n)
+		if block == nil {
+			return common.Hash{}, "", fmt.Errorf("export failed on #%d: not found", n)
+		}
+		receipts := bc.GetReceiptsByHash(block.Hash())
+		if receipts == nil {
+			return common.Hash{}, "", fmt.Errorf("export failed on #%d: receipts not found", n)
+		}
+		var td *big.Int
+		if !postMerge {
+			if td = bc.GetTd(block.Hash(), block.NumberU64()); td == nil {
+				return common.Hash{}, "", fmt.Errorf("export failed on #%d: total difficulty not found", n)
+			}
+		}
+		if err := w.Add(block, receipts, td); err != nil {
+			return common.Hash{}, "", err
+		}
+	}
+	root, err := w.Finalize()
+	if err != nil {
+		return common.Hash{}, "", fmt.Errorf("export failed to finalize %d: %w", epoch, err)
+	}
+	// Set correct filename with root.
+	if postMerge {
+		os.Rename(filename, filepath.Join(dir, era.PostMergeFilename(network, int(epoch), root)))
+	} else {
+		os.Rename(filename, filepath.Join(dir, era.Filename(network, int(epoch), root)))
+	}
+	// Compute checksum of entire Era.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return common.Hash{}, "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return common.Hash{}, "", fmt.Errorf("unable to calculate checksum: %w", err)
+	}
+	return root, common.BytesToHash(h.Sum(nil)).Hex(), nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
@@ -37,11 +37,13 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/beacon/light/api"
 	bparams "github.com/ethereum/go-ethereum/beacon/params"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -55,6 +57,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
+	"github.com/ethereum/go-ethereum/ethdb/s3"
 	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/graphql"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -105,11 +108,21 @@ var (
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
+	DBKeyspacesFlag = &cli.BoolFlag{
+		Name:     "db.keyspaces",
+		Usage:    "Split new pebble databases into keyspaces tuned for the stored data (existing ones need 'geth db migrate-keyspaces')",
+		Category: flags.EthCategory,
+	}
 	AncientFlag = &flags.DirectoryFlag{
 		Name:     "datadir.ancient",
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientRemoteFlag = &cli.StringFlag{
+		Name:     "datadir.ancient.remote",
+		Usage:    "URL of an S3-compatible bucket to offload ancient data into (http(s)://<endpoint>/<bucket>/<prefix>)",
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -273,7 +286,7 @@ var (
 	}
 	GCModeFlag = &cli.StringFlag{
 		Name:     "gcmode",
-		Usage:    `Blockchain garbage collection mode, only relevant in state.scheme=hash ("full", "archive")`,
+		Usage:    `Blockchain garbage collection mode ("full", "archive"), archive mode with state.scheme=path retains indexed state histories`,
 		Value:    "full",
 		Category: flags.StateCategory,
 	}
@@ -288,12 +301,44 @@ var (
 		Value:    ethconfig.Defaults.StateHistory,
 		Category: flags.StateCategory,
 	}
+	StateHistoryIndexFlag = &cli.BoolFlag{
+		Name:     "history.stateindex",
+		Usage:    "Index the state histories to serve historical state requests within the retained range (path scheme only)",
+		Category: flags.StateCategory,
+	}
+	PartialStateFlag = &cli.StringFlag{
+		Name:     "state.partial",
+		Usage:    "Comma separated list of contract addresses whose storage is tracked, run as partial state node (hash scheme only)",
+		Category: flags.StateCategory,
+	}
 	TransactionHistoryFlag = &cli.Uint64Flag{
 		Name:     "history.transactions",
 		Usage:    "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	LogIndexFlag = &cli.BoolFlag{
+		Name:     "history.logindex",
+		Usage:    "Enable the address and topic log index for fast log filtering",
+		Category: flags.StateCategory,
+	}
+	LogHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.logs",
+		Usage:    "Number of recent blocks to maintain the log index for (default = about one year, 0 = entire chain)",
+		Value:    ethconfig.Defaults.LogHistory,
+		Category: flags.StateCategory,
+	}
+	HistoryExpiryFlag = &cli.Uint64Flag{
+		Name:     "history.chain",
+		Usage:    "Number of recent blocks to retain block bodies and receipts for (default = 0, entire chain)",
+		Value:    ethconfig.Defaults.HistoryExpiry,
+		Category: flags.StateCategory,
+	}
+	HistoryArchiveFlag = &flags.DirectoryFlag{
+		Name:     "history.era",
+		Usage:    "Directory of era files to serve the expired block bodies and receipts from",
+		Category: flags.StateCategory,
+	}
 	// Beacon client light sync settings
 	BeaconApiFlag = &cli.StringSliceFlag{
 		Name:     "beacon.api",
@@ -336,6 +381,17 @@ var (
 		Usage:    "Beacon chain weak subjectivity checkpoint block hash",
 		Category: flags.BeaconCategory,
 	}
+	BeaconRetentionFlag = &cli.Uint64Flag{
+		Name:     "beacon.retention",
+		Usage:    "Number of the latest sync committee periods to retain in the database (0 = all)",
+		Value:    8,
+		Category: flags.BeaconCategory,
+	}
+	BeaconLightServerFlag = &cli.StringFlag{
+		Name:     "beacon.lightserver",
+		Usage:    "Consensus client beacon API URL whose light client data is cached and served on the HTTP-RPC server",
+		Category: flags.BeaconCategory,
+	}
 	BlsyncApiFlag = &cli.StringFlag{
 		Name:     "blsync.engine.api",
 		Usage:    "Target EL engine API URL",
@@ -346,6 +402,17 @@ var (
 		Usage:    "Path to a JWT secret to use for target engine API endpoint",
 		Category: flags.BeaconCategory,
 	}
+	BlsyncProxyUpstreamFlag = &cli.StringFlag{
+		Name:     "blsync.proxy.upstream",
+		Usage:    "Untrusted execution RPC URL queried by the verified RPC proxy",
+		Category: flags.BeaconCategory,
+	}
+	BlsyncProxyAddrFlag = &cli.StringFlag{
+		Name:     "blsync.proxy.addr",
+		Usage:    "Listening address of the verified RPC proxy",
+		Value:    "127.0.0.1:8545",
+		Category: flags.BeaconCategory,
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = &cli.StringFlag{
 		Name:     "txpool.locals",
@@ -411,6 +478,53 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolRemoteJournalFlag = &cli.StringFlag{
+		Name:     "txpool.remotejournal",
+		Usage:    "Disk journal for remote transactions of all subpools to survive node restarts (disabled if empty)",
+		Value:    ethconfig.Defaults.TxPoolJournal.Path,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolRemoteRejournalFlag = &cli.DurationFlag{
+		Name:     "txpool.remotejournal.rejournal",
+		Usage:    "Time interval to regenerate the remote transaction journal",
+		Value:    ethconfig.Defaults.TxPoolJournal.Rejournal,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolRemoteJournalSizeFlag = &cli.Uint64Flag{
+		Name:     "txpool.remotejournal.maxsize",
+		Usage:    "Maximum size in bytes of the remote transaction journal (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPoolJournal.MaxSize,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolRemoteJournalAgeFlag = &cli.DurationFlag{
+		Name:     "txpool.remotejournal.maxage",
+		Usage:    "Maximum age of transactions retained in the remote transaction journal (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPoolJournal.MaxAge,
+		Category: flags.TxPoolCategory,
+	}
+	TxPropPolicyFlag = &cli.StringFlag{
+		Name:     "txprop.policy",
+		Usage:    `Propagation policy of local transactions ("broadcast", "stem" or "private")`,
+		Value:    ethconfig.Defaults.TxPropagation.Policy,
+		Category: flags.TxPoolCategory,
+	}
+	TxPropRelaysFlag = &cli.StringFlag{
+		Name:     "txprop.relays",
+		Usage:    "Comma separated enode URLs of relays receiving local transactions with the private policy, besides trusted peers",
+		Category: flags.TxPoolCategory,
+	}
+	TxPropStemEpochFlag = &cli.DurationFlag{
+		Name:     "txprop.stemepoch",
+		Usage:    "Time interval after which the stem peers of local transactions are reselected",
+		Value:    ethconfig.Defaults.TxPropagation.StemEpoch,
+		Category: flags.TxPoolCategory,
+	}
+	TxPropStemEmbargoFlag = &cli.DurationFlag{
+		Name:     "txprop.stemembargo",
+		Usage:    "Minimum time after which a stemmed local transaction not seen from the network is broadcast",
+		Value:    ethconfig.Defaults.TxPropagation.StemEmbargo,
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -820,6 +934,11 @@ var (
 		Usage:    "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 		Category: flags.NetworkingCategory,
 	}
+	DiscoveryTopicsFlag = &cli.StringFlag{
+		Name:     "discovery.topics",
+		Usage:    "Comma separated topics to advertise the node under and to find peers by in V5 discovery",
+		Category: flags.NetworkingCategory,
+	}
 	NetrestrictFlag = &cli.StringFlag{
 		Name:     "netrestrict",
 		Usage:    "Restricts network communication to the given IP networks (CIDR masks)",
@@ -876,6 +995,11 @@ var (
 		Value:    ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 		Category: flags.GasPriceCategory,
 	}
+	GpoMempoolFlag = &cli.BoolFlag{
+		Name:     "gpo.mempool",
+		Usage:    "Sample the transaction pool for forward-looking fee suggestions",
+		Category: flags.GasPriceCategory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
@@ -981,8 +1105,10 @@ var (
 	DatabaseFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientRemoteFlag,
 		RemoteDBFlag,
 		DBEngineFlag,
+		DBKeyspacesFlag,
 		StateSchemeFlag,
 		HttpHeaderFlag,
 	}
@@ -1344,6 +1470,22 @@ func setEtherbase(ctx *cli.Context, cfg *ethconfig.Config) {
 	cfg.Miner.PendingFeeRecipient = common.BytesToAddress(b)
 }
 
+// MakePartialState parses the contract addresses specified by the --state.partial
+// flag into the set of accounts tracked by a partial state node.
+func MakePartialState(ctx *cli.Context) []common.Address {
+	var addresses []common.Address
+	for _, addr := range SplitAndTrim(ctx.String(PartialStateFlag.Name)) {
+		if !common.IsHexAddress(addr) {
+			Fatalf("--%s: invalid contract address %q", PartialStateFlag.Name, addr)
+		}
+		addresses = append(addresses, common.HexToAddress(addr))
+	}
+	if len(addresses) == 0 {
+		Fatalf("--%s: no contract address specified", PartialStateFlag.Name)
+	}
+	return addresses
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.Path(PasswordFileFlag.Name)
@@ -1386,6 +1528,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	CheckExclusive(ctx, DiscoveryV5Flag, NoDiscoverFlag)
 	cfg.DiscoveryV4 = ctx.Bool(DiscoveryV4Flag.Name)
 	cfg.DiscoveryV5 = ctx.Bool(DiscoveryV5Flag.Name)
+	if ctx.IsSet(DiscoveryTopicsFlag.Name) {
+		cfg.DiscoveryTopics = SplitAndTrim(ctx.String(DiscoveryTopicsFlag.Name))
+	}
 
 	if netrestrict := ctx.String(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
@@ -1454,6 +1599,9 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	if ctx.IsSet(DBKeyspacesFlag.Name) {
+		cfg.DBKeyspaces = ctx.Bool(DBKeyspacesFlag.Name)
+	}
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
 		log.Warn("log.backtrace flag is deprecated")
@@ -1511,6 +1659,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.IsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.Int64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.IsSet(GpoMempoolFlag.Name) {
+		cfg.Mempool = ctx.Bool(GpoMempoolFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
@@ -1556,6 +1707,41 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	}
 }
 
+func setTxPoolJournal(ctx *cli.Context, cfg *txpool.JournalConfig) {
+	if ctx.IsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.Path = ctx.String(TxPoolRemoteJournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolRemoteRejournalFlag.Name) {
+		cfg.Rejournal = ctx.Duration(TxPoolRemoteRejournalFlag.Name)
+	}
+	if ctx.IsSet(TxPoolRemoteJournalSizeFlag.Name) {
+		cfg.MaxSize = ctx.Uint64(TxPoolRemoteJournalSizeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolRemoteJournalAgeFlag.Name) {
+		cfg.MaxAge = ctx.Duration(TxPoolRemoteJournalAgeFlag.Name)
+	}
+}
+
+func setTxPropagation(ctx *cli.Context, cfg *ethconfig.TxPropagationConfig) {
+	if ctx.IsSet(TxPropPolicyFlag.Name) {
+		cfg.Policy = ctx.String(TxPropPolicyFlag.Name)
+		switch cfg.Policy {
+		case ethconfig.TxPropBroadcast, ethconfig.TxPropStem, ethconfig.TxPropPrivate:
+		default:
+			Fatalf("Invalid --%s: %q", TxPropPolicyFlag.Name, cfg.Policy)
+		}
+	}
+	if ctx.IsSet(TxPropRelaysFlag.Name) {
+		cfg.Relays = SplitAndTrim(ctx.String(TxPropRelaysFlag.Name))
+	}
+	if ctx.IsSet(TxPropStemEpochFlag.Name) {
+		cfg.StemEpoch = ctx.Duration(TxPropStemEpochFlag.Name)
+	}
+	if ctx.IsSet(TxPropStemEmbargoFlag.Name) {
+		cfg.StemEmbargo = ctx.Duration(TxPropStemEmbargoFlag.Name)
+	}
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.Bool(MiningEnabledFlag.Name) {
 		log.Warn("The flag --mine is deprecated and will be removed")
@@ -1657,6 +1843,8 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
+	setTxPoolJournal(ctx, &cfg.TxPoolJournal)
+	setTxPropagation(ctx, &cfg.TxPropagation)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
@@ -1696,6 +1884,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.String(AncientFlag.Name)
 	}
+	if ctx.IsSet(AncientRemoteFlag.Name) {
+		cfg.DatabaseRemote = ctx.String(AncientRemoteFlag.Name)
+	}
 
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1715,9 +1906,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateHistoryFlag.Name) {
 		cfg.StateHistory = ctx.Uint64(StateHistoryFlag.Name)
 	}
+	if ctx.IsSet(StateHistoryIndexFlag.Name) {
+		cfg.StateHistoryIndex = ctx.Bool(StateHistoryIndexFlag.Name)
+	}
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
+	if ctx.IsSet(PartialStateFlag.Name) {
+		cfg.PartialState = MakePartialState(ctx)
+		if cfg.NoPruning {
+			Fatalf("--%s is not supported in archive mode", PartialStateFlag.Name)
+		}
+		switch cfg.StateScheme {
+		case "":
+			cfg.StateScheme = rawdb.HashScheme
+			log.Info("Using hash state-scheme for partial state node")
+		case rawdb.PathScheme:
+			Fatalf("--%s is not supported in path state-scheme", PartialStateFlag.Name)
+		}
+	}
 	// Parse transaction history flag, if user is still using legacy config
 	// file with 'TxLookupLimit' configured, copy the value to 'TransactionHistory'.
 	if cfg.TransactionHistory == ethconfig.Defaults.TransactionHistory && cfg.TxLookupLimit != ethconfig.Defaults.TxLookupLimit {
@@ -1730,12 +1937,34 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Warn("The flag --txlookuplimit is deprecated and will be removed, please use --history.transactions")
 		cfg.TransactionHistory = ctx.Uint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.IsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.Bool(LogIndexFlag.Name)
+	}
+	if ctx.IsSet(LogHistoryFlag.Name) {
+		cfg.LogHistory = ctx.Uint64(LogHistoryFlag.Name)
+	}
+	if ctx.IsSet(HistoryExpiryFlag.Name) {
+		cfg.HistoryExpiry = ctx.Uint64(HistoryExpiryFlag.Name)
+	}
+	if ctx.IsSet(HistoryArchiveFlag.Name) {
+		cfg.HistoryArchive = ctx.String(HistoryArchiveFlag.Name)
+	}
 	if ctx.String(GCModeFlag.Name) == "archive" && cfg.TransactionHistory != 0 {
 		cfg.TransactionHistory = 0
 		log.Warn("Disabled transaction unindexing for archive node")
 
-		cfg.StateScheme = rawdb.HashScheme
-		log.Warn("Forcing hash state-scheme for archive mode")
+		// The path scheme serves the archive state from the indexed state
+		// histories, retaining all of them unless configured otherwise.
+		if cfg.StateScheme == rawdb.PathScheme {
+			cfg.StateHistoryIndex = true
+			if !ctx.IsSet(StateHistoryFlag.Name) {
+				cfg.StateHistory = 0
+			}
+			log.Info("Enabled indexed state histories for path-based archive mode", "history", cfg.StateHistory)
+		} else {
+			cfg.StateScheme = rawdb.HashScheme
+			log.Warn("Forcing hash state-scheme for archive mode")
+		}
 	}
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.Int(CacheFlag.Name) * ctx.Int(CacheTrieFlag.Name) / 100
@@ -1975,6 +2204,20 @@ func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconf
 	return filterSystem
 }
 
+// RegisterBeaconLightServer adds a beacon light client data server into node,
+// caching the data of the given consensus client and serving it through the
+// beacon light client REST API endpoints of the HTTP-RPC server.
+func RegisterBeaconLightServer(stack *node.Node, url string, retention uint64) {
+	db, err := stack.OpenDatabase("lightserver", 16, 16, "eth/db/lightserver/", false)
+	if err != nil {
+		Fatalf("Failed to open beacon light server database: %v", err)
+	}
+	server := api.NewLightServer(api.NewBeaconLightApi(url, nil), db, retention)
+	stack.RegisterHandler("Beacon light client API", "/eth/v1/", server)
+	stack.RegisterLifecycle(server)
+	log.Info("Registered beacon light client data server", "upstream", url)
+}
+
 // RegisterFullSyncTester adds the full-sync tester service into node.
 func RegisterFullSyncTester(stack *node.Node, eth *eth.Ethereum, target common.Hash) {
 	catalyst.RegisterFullSyncTester(stack, eth, target)
@@ -2077,6 +2320,12 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 		chainDb = remotedb.New(client)
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
+	case ctx.IsSet(AncientRemoteFlag.Name):
+		config, perr := s3.ParseURL(ctx.String(AncientRemoteFlag.Name))
+		if perr != nil {
+			Fatalf("Invalid --%s: %v", AncientRemoteFlag.Name, perr)
+		}
+		chainDb, err = stack.OpenDatabaseWithRemoteFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), s3.New(config), "", readonly)
 	default:
 		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), "", readonly)
 	}
@@ -2180,6 +2429,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
 		StateScheme:         scheme,
 		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		StateHistoryIndex:   ctx.Bool(StateHistoryIndexFlag.Name),
+		HistoryArchive:      ctx.String(HistoryArchiveFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -2188,6 +2439,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	if !ctx.Bool(SnapshotFlag.Name) {
 		cache.SnapshotLimit = 0 // Disabled
 	}
+	if ctx.IsSet(PartialStateFlag.Name) {
+		cache.PartialState = MakePartialState(ctx)
+	}
 	// If we're in readonly, do not bother generating snapshot data.
 	if readonly {
 		cache.SnapshotNoBuild = true
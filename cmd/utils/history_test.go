@@ -27,16 +27,19 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
 )
 
 var (
@@ -183,3 +186,179 @@ func TestHistoryImportAndExport(t *testing.T) {
 		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", have.Number, have.Hash(), want.Number, want.Hash())
 	}
 }
+
+func TestHistoryImportAndExportPostMerge(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		config  = *params.TestChainConfig // needs copy because it is modified below
+		genesis = &core.Genesis{
+			Config: &config,
+			Alloc:  types.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		mergeBlock = uint64(20)
+		mergeTime  = mergeBlock * 10 // fixed 10 sec block time in blockgen
+		engine     = beacon.New(ethash.NewFaker())
+		td         = params.GenesisDifficulty.Uint64()
+
+		emptyBlob          = kzg4844.Blob{}
+		emptyBlobCommit, _ = kzg4844.BlobToCommitment(&emptyBlob)
+		emptyBlobVHash     = kzg4844.CalcBlobHashV1(sha256.New(), &emptyBlobCommit)
+	)
+	config.ShanghaiTime = &mergeTime
+	config.CancunTime = &mergeTime
+	signer := types.LatestSigner(genesis.Config)
+
+	// Generate a chain transitioning to proof-of-stake in the middle of an epoch,
+	// including withdrawals and blob transactions after the merge.
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, engine, int(count), func(i int, g *core.BlockGen) {
+		g.AddTx(types.MustSignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   genesis.Config.ChainID,
+			Nonce:     g.TxNonce(address),
+			GasTipCap: common.Big0,
+			GasFeeCap: g.BaseFee(),
+			Gas:       50000,
+			To:        &common.Address{0xaa},
+			Value:     big.NewInt(int64(i)),
+		}))
+		if g.Number().Uint64() >= mergeBlock {
+			g.SetPoS()
+			g.AddWithdrawal(&types.Withdrawal{Validator: uint64(i), Address: common.Address{0xbb}, Amount: 1})
+			g.AddTx(types.MustSignNewTx(key, signer, &types.BlobTx{
+				ChainID:    uint256.MustFromBig(genesis.Config.ChainID),
+				Nonce:      g.TxNonce(address),
+				To:         common.Address{0xaa},
+				Gas:        30000,
+				GasFeeCap:  uint256.NewInt(100 * params.GWei),
+				BlobFeeCap: uint256.NewInt(1),
+				BlobHashes: []common.Hash{emptyBlobVHash},
+			}))
+		}
+		td += g.Difficulty().Uint64()
+	})
+	config.TerminalTotalDifficulty = new(big.Int).SetUint64(td)
+
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, count, step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	// The epoch of the merge is split between an era1 and a post-merge archive.
+	entries, _ := era.ReadDir(dir, "mainnet")
+	if want := int(mergeBlock/step) + 1; len(entries) != want {
+		t.Fatalf("unexpected number of era1 files: have %d, want %d", len(entries), want)
+	}
+	merged, first, _ := era.ReadPostMergeDir(dir, "mainnet")
+	if first != int(mergeBlock/step) || len(merged) != int(count/step)+1-first {
+		t.Fatalf("unexpected post-merge files: first %d, have %d", first, len(merged))
+	}
+	for _, filename := range merged {
+		e, err := era.Open(filepath.Join(dir, filename))
+		if err != nil {
+			t.Fatalf("error opening era: %v", err)
+		}
+		if !e.PostMerge() {
+			t.Fatalf("era %s not post-merge", filename)
+		}
+		if _, err := e.Accumulator(); err == nil {
+			t.Fatalf("era %s has accumulator", filename)
+		}
+		last := chain.GetHeaderByNumber(e.Start() + e.Count() - 1)
+		if _, err := era.VerifyCheckpoint(e, last.Hash()); err != nil {
+			t.Fatalf("error verifying era %s: %v", filename, err)
+		}
+		if _, err := era.VerifyCheckpoint(e, last.ParentHash); err == nil {
+			t.Fatalf("era %s verified against wrong checkpoint", filename)
+		}
+		e.Close()
+	}
+
+	// Import the history into a fresh chain.
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db2.Close()
+
+	imported, err := core.NewBlockChain(db2, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := imported.CurrentHeader(), chain.CurrentHeader(); have.Hash() != want.Hash() {
+		t.Fatalf("imported chain does not match expected, have (%d, %s) want (%d, %s)", have.Number, have.Hash(), want.Number, want.Hash())
+	}
+	for n := uint64(1); n <= count; n++ {
+		want := chain.GetBlockByNumber(n)
+		have := imported.GetBlockByNumber(n)
+		if have == nil || have.Hash() != want.Hash() || len(have.Withdrawals()) != len(want.Withdrawals()) {
+			t.Fatalf("block %d mismatch", n)
+		}
+		wantReceipts, haveReceipts := chain.GetReceiptsByHash(want.Hash()), imported.GetReceiptsByHash(want.Hash())
+		if len(haveReceipts) != len(wantReceipts) {
+			t.Fatalf("receipts %d mismatch: have %d, want %d", n, len(haveReceipts), len(wantReceipts))
+		}
+		for i := range wantReceipts {
+			if haveReceipts[i].TxHash != wantReceipts[i].TxHash || haveReceipts[i].BlobGasUsed != wantReceipts[i].BlobGasUsed {
+				t.Fatalf("receipt %d of block %d mismatch", i, n)
+			}
+		}
+	}
+}
+
+func TestHistoryImportPostMergeBadCheckpoint(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		config  = *params.MergedTestChainConfig
+		genesis = &core.Genesis{
+			Config: &config,
+			Alloc:  types.GenesisAlloc{address: {Balance: big.NewInt(1000000000000000000)}},
+		}
+		engine = beacon.New(ethash.NewFaker())
+	)
+	db, blocks, _ := core.GenerateChainWithGenesis(genesis, engine, int(step), func(i int, g *core.BlockGen) {
+		g.SetPoS()
+	})
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, step, step); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	// Replace the checkpoint of the last archive.
+	b, err := os.ReadFile(filepath.Join(dir, "checkpoints.txt"))
+	if err != nil {
+		t.Fatalf("failed to read checkpoints: %v", err)
+	}
+	checkpoints := strings.Split(string(b), "\n")
+	checkpoints[len(checkpoints)-1] = blocks[0].Hash().Hex()
+	os.WriteFile(filepath.Join(dir, "checkpoints.txt"), []byte(strings.Join(checkpoints, "\n")), os.ModePerm)
+
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db2.Close()
+
+	imported, err := core.NewBlockChain(db2, nil, genesis, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if err := ImportHistory(imported, db2, dir, "mainnet"); err == nil {
+		t.Fatal("expected checkpoint verification failure")
+	}
+}
@@ -0,0 +1,683 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/golang/snappy"
+)
+
+// The snapshot archive contains the flat state of a block together with the
+// block itself, allowing to seed a node without syncing the state from the
+// network. It's a sequence of chunks following a magic prefix:
+//
+//	chunk := kind (1 byte) || length (4 bytes) || keccak256(payload) || payload
+//
+// The payload is snappy compressed. The archive starts with a metadata chunk,
+// followed by the header chunks, the state chunks and terminated by an end
+// chunk holding the totals. The header chunks contain the canonical ancestors
+// of the archived block, excluding genesis, in ascending order. The state
+// chunks contain the list of records in the snapshot order: every account is
+// followed by its contract code (on first occurrence) and its storage slots.
+var snapshotArchiveMagic = []byte("gethsnap")
+
+const (
+	snapshotArchiveVersion = 2
+
+	snapshotChunkMeta    = 0 // Metadata chunk, the first one
+	snapshotChunkState   = 1 // Chunk of state records
+	snapshotChunkEnd     = 2 // Trailing chunk with the totals
+	snapshotChunkHeaders = 3 // Chunk of ancestor headers, following the metadata
+
+	snapshotRecordAccount = 0 // Key: account hash, value: slim account
+	snapshotRecordStorage = 1 // Key: account hash || slot hash, value: slot
+	snapshotRecordCode    = 2 // Key: code hash, value: contract code
+
+	// snapshotChunkSize is the size of the uncompressed state records
+	// collected into a chunk.
+	snapshotChunkSize = 4 * 1024 * 1024
+
+	// snapshotHeaderBatch is the number of ancestor headers collected into
+	// a chunk.
+	snapshotHeaderBatch = 2048
+
+	// snapshotMaxChunkSize is the maximum accepted size of a compressed
+	// chunk, protecting against corrupted length fields.
+	snapshotMaxChunkSize = 64 * 1024 * 1024
+)
+
+// snapshotArchiveMeta is the content of the metadata chunk.
+type snapshotArchiveMeta struct {
+	Version  uint64
+	Genesis  common.Hash
+	Header   rlp.RawValue
+	Body     rlp.RawValue
+	Receipts rlp.RawValue
+	TD       *big.Int
+}
+
+// snapshotArchiveEnd is the content of the end chunk.
+type snapshotArchiveEnd struct {
+	Chunks   uint64
+	Headers  uint64
+	Accounts uint64
+	Slots    uint64
+	Codes    uint64
+}
+
+// snapshotRecord is a single state entry in the archive.
+type snapshotRecord struct {
+	Kind  uint8
+	Key   []byte
+	Value []byte
+}
+
+// snapshotArchiveWriter writes the chunks of a snapshot archive.
+type snapshotArchiveWriter struct {
+	w       *bufio.Writer
+	records []snapshotRecord
+	size    int
+	chunks  uint64
+}
+
+// writeChunk compresses and writes a chunk of the given kind.
+func (w *snapshotArchiveWriter) writeChunk(kind byte, val interface{}) error {
+	blob, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	payload := snappy.Encode(nil, blob)
+
+	var header [5]byte
+	header[0] = kind
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(crypto.Keccak256(payload)); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(payload); err != nil {
+		return err
+	}
+	if kind == snapshotChunkState {
+		w.chunks++
+	}
+	return nil
+}
+
+// add appends a state record, writing out a chunk if enough are collected.
+func (w *snapshotArchiveWriter) add(kind uint8, key []byte, value []byte) error {
+	w.records = append(w.records, snapshotRecord{Kind: kind, Key: key, Value: value})
+	w.size += len(key) + len(value)
+	if w.size >= snapshotChunkSize {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush writes out the collected state records.
+func (w *snapshotArchiveWriter) flush() error {
+	if len(w.records) == 0 {
+		return nil
+	}
+	if err := w.writeChunk(snapshotChunkState, w.records); err != nil {
+		return err
+	}
+	w.records, w.size = w.records[:0], 0
+	return nil
+}
+
+// snapshotArchiveReader reads and verifies the chunks of a snapshot archive.
+type snapshotArchiveReader struct {
+	r      *bufio.Reader
+	chunks uint64
+}
+
+// readChunk reads the next chunk, verifying its checksum.
+func (r *snapshotArchiveReader) readChunk() (byte, []byte, error) {
+	var header [5 + common.HashLength]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.EOF {
+			return 0, nil, errors.New("unexpected end of archive")
+		}
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:5])
+	if size > snapshotMaxChunkSize {
+		return 0, nil, fmt.Errorf("chunk %d too large: %d bytes", r.chunks, size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("truncated chunk %d: %v", r.chunks, err)
+	}
+	if !bytes.Equal(crypto.Keccak256(payload), header[5:]) {
+		return 0, nil, fmt.Errorf("checksum mismatch in chunk %d", r.chunks)
+	}
+	blob, err := snappy.Decode(nil, payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid compression in chunk %d: %v", r.chunks, err)
+	}
+	if header[0] == snapshotChunkState {
+		r.chunks++
+	}
+	return header[0], blob, nil
+}
+
+// ExportSnapshot writes the flat state of the given block from the snapshot,
+// together with the contract codes and the block itself, into a snapshot
+// archive.
+func ExportSnapshot(db ethdb.Database, snaptree *snapshot.Tree, fn string, block *types.Block) error {
+	log.Info("Exporting snapshot", "file", fn, "number", block.Number(), "hash", block.Hash(), "root", block.Root())
+
+	var (
+		hash   = block.Hash()
+		number = block.NumberU64()
+	)
+	meta := &snapshotArchiveMeta{
+		Version:  snapshotArchiveVersion,
+		Genesis:  rawdb.ReadCanonicalHash(db, 0),
+		Header:   rawdb.ReadHeaderRLP(db, hash, number),
+		Body:     rawdb.ReadBodyRLP(db, hash, number),
+		Receipts: rawdb.ReadReceiptsRLP(db, hash, number),
+		TD:       rawdb.ReadTd(db, hash, number),
+	}
+	if len(meta.Header) == 0 || len(meta.Body) == 0 || len(meta.Receipts) == 0 || meta.TD == nil {
+		return fmt.Errorf("block %d is incomplete", number)
+	}
+	accIt, err := snaptree.AccountIterator(block.Root(), common.Hash{})
+	if err != nil {
+		return err
+	}
+	defer accIt.Release()
+
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	w := &snapshotArchiveWriter{w: bufio.NewWriter(fh)}
+	if _, err := w.w.Write(snapshotArchiveMagic); err != nil {
+		return err
+	}
+	if err := w.writeChunk(snapshotChunkMeta, meta); err != nil {
+		return err
+	}
+	var (
+		end    snapshotArchiveEnd
+		codes  = make(map[common.Hash]struct{})
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// Ship the ancestor headers, allowing the importer to restore the
+	// canonical chain below the archived block
+	var headers []rlp.RawValue
+	for n := uint64(1); n < number; n++ {
+		header := rawdb.ReadHeaderRLP(db, rawdb.ReadCanonicalHash(db, n), n)
+		if len(header) == 0 {
+			return fmt.Errorf("header %d missing", n)
+		}
+		headers = append(headers, header)
+		end.Headers++
+
+		if len(headers) == snapshotHeaderBatch || n == number-1 {
+			if err := w.writeChunk(snapshotChunkHeaders, headers); err != nil {
+				return err
+			}
+			headers = headers[:0]
+		}
+	}
+	for accIt.Next() {
+		account, err := types.FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		if err := w.add(snapshotRecordAccount, accIt.Hash().Bytes(), common.CopyBytes(accIt.Account())); err != nil {
+			return err
+		}
+		end.Accounts++
+
+		codeHash := common.BytesToHash(account.CodeHash)
+		if _, ok := codes[codeHash]; codeHash != types.EmptyCodeHash && !ok {
+			code := rawdb.ReadCode(db, codeHash)
+			if len(code) == 0 {
+				return fmt.Errorf("missing code %x", codeHash)
+			}
+			if err := w.add(snapshotRecordCode, codeHash.Bytes(), code); err != nil {
+				return err
+			}
+			codes[codeHash] = struct{}{}
+			end.Codes++
+		}
+		if account.Root != types.EmptyRootHash {
+			stIt, err := snaptree.StorageIterator(block.Root(), accIt.Hash(), common.Hash{})
+			if err != nil {
+				return err
+			}
+			for stIt.Next() {
+				key := append(accIt.Hash().Bytes(), stIt.Hash().Bytes()...)
+				if err := w.add(snapshotRecordStorage, key, common.CopyBytes(stIt.Slot())); err != nil {
+					stIt.Release()
+					return err
+				}
+				end.Slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting snapshot", "at", accIt.Hash(), "accounts", end.Accounts, "slots", end.Slots,
+				"codes", end.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	end.Chunks = w.chunks
+	if err := w.writeChunk(snapshotChunkEnd, &end); err != nil {
+		return err
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	log.Info("Exported snapshot", "file", fn, "chunks", end.Chunks, "accounts", end.Accounts, "slots", end.Slots,
+		"codes", end.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// snapshotImporter rebuilds the state tries from the records of an archive.
+type snapshotImporter struct {
+	batch  ethdb.Batch
+	scheme string
+	stats  snapshotArchiveEnd
+	codes  map[common.Hash]struct{}
+
+	accTrie *trie.StackTrie // Trie of all accounts
+	account common.Hash     // Hash of the account being imported
+	state   *types.StateAccount
+	stTrie  *trie.StackTrie // Storage trie of the account being imported
+}
+
+// write flushes the batch if it reached the ideal size.
+func (imp *snapshotImporter) write() error {
+	if imp.batch.ValueSize() < ethdb.IdealBatchSize {
+		return nil
+	}
+	if err := imp.batch.Write(); err != nil {
+		return err
+	}
+	imp.batch.Reset()
+	return nil
+}
+
+// finishAccount verifies the storage and the code of the account being
+// imported and adds it to the account trie.
+func (imp *snapshotImporter) finishAccount() error {
+	if imp.state == nil {
+		return nil
+	}
+	if root := imp.stTrie.Hash(); root != imp.state.Root {
+		return fmt.Errorf("storage root mismatch of account %x: have %x, want %x", imp.account, root, imp.state.Root)
+	}
+	if codeHash := common.BytesToHash(imp.state.CodeHash); codeHash != types.EmptyCodeHash {
+		if _, ok := imp.codes[codeHash]; !ok {
+			return fmt.Errorf("missing code %x of account %x", codeHash, imp.account)
+		}
+	}
+	blob, err := rlp.EncodeToBytes(imp.state)
+	if err != nil {
+		return err
+	}
+	if err := imp.accTrie.Update(imp.account.Bytes(), blob); err != nil {
+		return fmt.Errorf("account %x: %v", imp.account, err)
+	}
+	imp.state = nil
+	return nil
+}
+
+// process imports a single state record.
+func (imp *snapshotImporter) process(record *snapshotRecord) error {
+	switch record.Kind {
+	case snapshotRecordAccount:
+		if len(record.Key) != common.HashLength {
+			return fmt.Errorf("invalid account key %x", record.Key)
+		}
+		if err := imp.finishAccount(); err != nil {
+			return err
+		}
+		account, err := types.FullAccount(record.Value)
+		if err != nil {
+			return fmt.Errorf("invalid account %x: %v", record.Key, err)
+		}
+		imp.account, imp.state = common.BytesToHash(record.Key), account
+
+		owner := imp.account
+		imp.stTrie = trie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+			rawdb.WriteTrieNode(imp.batch, owner, path, hash, blob, imp.scheme)
+		})
+		rawdb.WriteAccountSnapshot(imp.batch, imp.account, record.Value)
+		imp.stats.Accounts++
+
+	case snapshotRecordStorage:
+		if len(record.Key) != 2*common.HashLength {
+			return fmt.Errorf("invalid storage key %x", record.Key)
+		}
+		if imp.state == nil || common.BytesToHash(record.Key[:common.HashLength]) != imp.account {
+			return fmt.Errorf("storage slot %x out of order", record.Key)
+		}
+		if err := imp.stTrie.Update(record.Key[common.HashLength:], record.Value); err != nil {
+			return fmt.Errorf("storage slot %x: %v", record.Key, err)
+		}
+		rawdb.WriteStorageSnapshot(imp.batch, imp.account, common.BytesToHash(record.Key[common.HashLength:]), record.Value)
+		imp.stats.Slots++
+
+	case snapshotRecordCode:
+		hash := common.BytesToHash(record.Key)
+		if crypto.Keccak256Hash(record.Value) != hash {
+			return fmt.Errorf("code hash mismatch: %x", record.Key)
+		}
+		rawdb.WriteCode(imp.batch, hash, record.Value)
+		imp.codes[hash] = struct{}{}
+		imp.stats.Codes++
+
+	default:
+		return fmt.Errorf("unknown record kind %d", record.Kind)
+	}
+	return imp.write()
+}
+
+// ImportSnapshot imports a snapshot archive into the database, regenerating
+// the state tries of the given scheme, verifying them against the state root
+// of the archived block and setting that block as the chain head. The
+// database must only contain the genesis block of the same network.
+func ImportSnapshot(db ethdb.Database, fn string, scheme string) error {
+	log.Info("Importing snapshot", "file", fn, "scheme", scheme)
+
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	r := &snapshotArchiveReader{r: bufio.NewReader(fh)}
+	magic := make([]byte, len(snapshotArchiveMagic))
+	if _, err := io.ReadFull(r.r, magic); err != nil || !bytes.Equal(magic, snapshotArchiveMagic) {
+		return errors.New("not a snapshot archive")
+	}
+	kind, blob, err := r.readChunk()
+	if err != nil {
+		return err
+	}
+	if kind != snapshotChunkMeta {
+		return fmt.Errorf("unexpected chunk kind %d, want metadata", kind)
+	}
+	var meta snapshotArchiveMeta
+	if err := rlp.DecodeBytes(blob, &meta); err != nil {
+		return fmt.Errorf("invalid metadata: %v", err)
+	}
+	if meta.Version != snapshotArchiveVersion {
+		return fmt.Errorf("unsupported archive version %d", meta.Version)
+	}
+	var header types.Header
+	if err := rlp.DecodeBytes(meta.Header, &header); err != nil {
+		return fmt.Errorf("invalid header: %v", err)
+	}
+	var body types.Body
+	if err := rlp.DecodeBytes(meta.Body, &body); err != nil {
+		return fmt.Errorf("invalid body: %v", err)
+	}
+	var receipts []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(meta.Receipts, &receipts); err != nil {
+		return fmt.Errorf("invalid receipts: %v", err)
+	}
+	block := types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("tx root mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	// The receipt types aren't part of the storage encoding, restore them from
+	// the transactions before verifying the receipt root
+	blockReceipts := convertStoredReceipts(receipts)
+	if len(blockReceipts) != len(block.Transactions()) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(blockReceipts), len(block.Transactions()))
+	}
+	for i, tx := range block.Transactions() {
+		blockReceipts[i].Type = tx.Type()
+	}
+	if hash := types.DeriveSha(blockReceipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", hash, header.ReceiptHash)
+	}
+	// Ensure the database is fresh and belongs to the same network
+	genesis := rawdb.ReadCanonicalHash(db, 0)
+	if genesis == (common.Hash{}) {
+		return errors.New("database not initialized with the genesis block")
+	}
+	if genesis != meta.Genesis {
+		return fmt.Errorf("genesis mismatch: have %x, archive %x", genesis, meta.Genesis)
+	}
+	if head := rawdb.ReadHeadBlock(db); head == nil || head.NumberU64() != 0 {
+		return errors.New("database already contains blocks beyond genesis")
+	}
+	if err := wipeSnapshotState(db, scheme); err != nil {
+		return err
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+		imp    = &snapshotImporter{
+			batch:  db.NewBatch(),
+			scheme: scheme,
+			codes:  make(map[common.Hash]struct{}),
+		}
+	)
+	imp.accTrie = trie.NewStackTrie(func(path []byte, hash common.Hash, blob []byte) {
+		rawdb.WriteTrieNode(imp.batch, common.Hash{}, path, hash, blob, scheme)
+	})
+	// Restore the canonical ancestors of the archived block, verifying that
+	// they link the genesis to it. Their bodies and receipts aren't shipped,
+	// the freezer skips them as a gap in the chain history.
+	var (
+		parent = rawdb.ReadHeader(db, genesis, 0)
+		td     = rawdb.ReadTd(db, genesis, 0)
+	)
+	if parent == nil || td == nil {
+		return errors.New("genesis block incomplete")
+	}
+	for kind, blob, err = r.readChunk(); err == nil && kind == snapshotChunkHeaders; kind, blob, err = r.readChunk() {
+		var headers []*types.Header
+		if err := rlp.DecodeBytes(blob, &headers); err != nil {
+			return fmt.Errorf("invalid header chunk: %v", err)
+		}
+		for _, h := range headers {
+			if h.ParentHash != parent.Hash() || h.Number.Uint64() != parent.Number.Uint64()+1 {
+				return fmt.Errorf("header %d not linked to its parent", h.Number)
+			}
+			td = new(big.Int).Add(td, h.Difficulty)
+
+			hash, number := h.Hash(), h.Number.Uint64()
+			rawdb.WriteHeader(imp.batch, h)
+			rawdb.WriteTd(imp.batch, hash, number, td)
+			rawdb.WriteCanonicalHash(imp.batch, hash, number)
+			imp.stats.Headers++
+
+			if err := imp.write(); err != nil {
+				return err
+			}
+			parent = h
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if header.Number.Sign() == 0 {
+		if header.Hash() != genesis {
+			return errors.New("archived block is not the genesis")
+		}
+	} else {
+		if header.ParentHash != parent.Hash() || header.Number.Uint64() != parent.Number.Uint64()+1 {
+			return fmt.Errorf("archived block not linked to the header chain at %d", parent.Number)
+		}
+		if td = new(big.Int).Add(td, header.Difficulty); td.Cmp(meta.TD) != 0 {
+			return fmt.Errorf("total difficulty mismatch: have %v, want %v", td, meta.TD)
+		}
+	}
+	var end snapshotArchiveEnd
+	for ; ; kind, blob, err = r.readChunk() {
+		if err != nil {
+			return err
+		}
+		if kind == snapshotChunkEnd {
+			if err := rlp.DecodeBytes(blob, &end); err != nil {
+				return fmt.Errorf("invalid end chunk: %v", err)
+			}
+			break
+		}
+		if kind != snapshotChunkState {
+			return fmt.Errorf("unexpected chunk kind %d", kind)
+		}
+		var records []snapshotRecord
+		if err := rlp.DecodeBytes(blob, &records); err != nil {
+			return fmt.Errorf("invalid chunk %d: %v", r.chunks-1, err)
+		}
+		for i := range records {
+			if err := imp.process(&records[i]); err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing snapshot", "at", imp.account, "accounts", imp.stats.Accounts, "slots", imp.stats.Slots,
+				"codes", imp.stats.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if _, err := r.r.ReadByte(); err != io.EOF {
+		return errors.New("trailing data after end of archive")
+	}
+	if err := imp.finishAccount(); err != nil {
+		return err
+	}
+	imp.stats.Chunks = r.chunks
+	if imp.stats != end {
+		return fmt.Errorf("archive totals mismatch: have %+v, want %+v", imp.stats, end)
+	}
+	if root := imp.accTrie.Hash(); root != header.Root {
+		return fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	// The state is verified, set the archived block as the chain head. The
+	// snapshot is regenerated on top of the imported flat state on startup.
+	hash, number := header.Hash(), header.Number.Uint64()
+	rawdb.WriteHeader(imp.batch, &header)
+	rawdb.WriteBodyRLP(imp.batch, hash, number, meta.Body)
+	rawdb.WriteReceipts(imp.batch, hash, number, blockReceipts)
+	rawdb.WriteTd(imp.batch, hash, number, meta.TD)
+	rawdb.WriteCanonicalHash(imp.batch, hash, number)
+	rawdb.WriteTxLookupEntriesByBlock(imp.batch, block)
+	rawdb.WriteHeadHeaderHash(imp.batch, hash)
+	rawdb.WriteHeadFastBlockHash(imp.batch, hash)
+	rawdb.WriteHeadBlockHash(imp.batch, hash)
+	if number > 1 {
+		rawdb.WriteChainHistoryGap(imp.batch, number)
+	}
+	if err := imp.batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Imported snapshot", "number", number, "hash", hash, "root", header.Root, "headers", imp.stats.Headers, "accounts", imp.stats.Accounts,
+		"slots", imp.stats.Slots, "codes", imp.stats.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// wipeSnapshotState deletes the flat state, the snapshot metadata and, in the
+// path scheme, the trie nodes of the genesis state, which would otherwise be
+// mixed up with the imported state.
+func wipeSnapshotState(db ethdb.Database, scheme string) error {
+	batch := db.NewBatch()
+	wipe := func(prefix []byte, match func([]byte) bool) error {
+		it := db.NewIterator(prefix, nil)
+		defer it.Release()
+
+		for it.Next() {
+			if !match(it.Key()) {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		return it.Error()
+	}
+	keyLen := func(n int) func([]byte) bool {
+		return func(key []byte) bool { return len(key) == n }
+	}
+	if err := wipe(rawdb.SnapshotAccountPrefix, keyLen(len(rawdb.SnapshotAccountPrefix)+common.HashLength)); err != nil {
+		return err
+	}
+	if err := wipe(rawdb.SnapshotStoragePrefix, keyLen(len(rawdb.SnapshotStoragePrefix)+2*common.HashLength)); err != nil {
+		return err
+	}
+	if scheme == rawdb.PathScheme {
+		if err := wipe(rawdb.TrieNodeAccountPrefix, rawdb.IsAccountTrieNode); err != nil {
+			return err
+		}
+		if err := wipe(rawdb.TrieNodeStoragePrefix, rawdb.IsStorageTrieNode); err != nil {
+			return err
+		}
+		rawdb.DeleteTrieJournal(batch)
+	}
+	rawdb.DeleteSnapshotRoot(batch)
+	rawdb.DeleteSnapshotJournal(batch)
+	rawdb.DeleteSnapshotGenerator(batch)
+	rawdb.DeleteSnapshotRecoveryNumber(batch)
+	return batch.Write()
+}
+
+// convertStoredReceipts converts the receipts from their storage encoding.
+func convertStoredReceipts(stored []*types.ReceiptForStorage) types.Receipts {
+	receipts := make(types.Receipts, len(stored))
+	for i, receipt := range stored {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	return receipts
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+func TestSnapshotExportImport(t *testing.T) {
+	t.Run("hash", func(t *testing.T) { testSnapshotExportImport(t, rawdb.HashScheme) })
+	t.Run("path", func(t *testing.T) { testSnapshotExportImport(t, rawdb.PathScheme) })
+}
+
+func testSnapshotExportImport(t *testing.T, scheme string) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000000000000)},
+				// Stores the calldata into slot 0 on every call
+				contract: {
+					Code:    []byte{byte(vm.PUSH1), 0x0, byte(vm.CALLDATALOAD), byte(vm.PUSH1), 0x0, byte(vm.SSTORE)},
+					Storage: map[common.Hash]common.Hash{{0x1}: {0x1}},
+				},
+			},
+		}
+		signer = types.LatestSigner(genesis.Config)
+	)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 17, func(i int, g *core.BlockGen) {
+		to := common.Address{byte(i + 1)}
+		if i%2 == 0 {
+			to = contract
+		}
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   genesis.Config.ChainID,
+			Nonce:     uint64(i),
+			GasTipCap: common.Big0,
+			GasFeeCap: g.PrevBlock(-1).BaseFee(),
+			Gas:       50000,
+			To:        &to,
+			Value:     big.NewInt(int64(i + 1)),
+			Data:      common.LeftPadBytes([]byte{byte(i + 1)}, 32),
+		})
+		if err != nil {
+			t.Fatalf("error creating tx: %v", err)
+		}
+		g.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(scheme), genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks[:16]); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	head := chain.GetBlockByHash(chain.CurrentBlock().Hash())
+	want, err := chain.StateAt(head.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "state.snap")
+	if err := ExportSnapshot(db, chain.Snapshots(), archive, head); err != nil {
+		t.Fatalf("failed to export snapshot: %v", err)
+	}
+	// Export the same block with corrupted receipts, which must be rejected
+	receipts := chain.GetReceiptsByHash(head.Hash())
+	receipts[0].CumulativeGasUsed++
+	rawdb.WriteReceipts(db, head.Hash(), head.NumberU64(), receipts)
+
+	corrupted := filepath.Join(t.TempDir(), "corrupted.snap")
+	if err := ExportSnapshot(db, chain.Snapshots(), corrupted, head); err != nil {
+		t.Fatalf("failed to export snapshot: %v", err)
+	}
+	chain.Stop()
+
+	// Import the archive into a database containing only the genesis block
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db2.Close()
+
+	chain2, err := core.NewBlockChain(db2, core.DefaultCacheConfigWithScheme(scheme), genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	chain2.Stop()
+
+	if err := ImportSnapshot(db2, corrupted, scheme); err == nil || !strings.Contains(err.Error(), "receipt root mismatch") {
+		t.Fatalf("corrupted receipts error mismatch: have %v", err)
+	}
+	if err := ImportSnapshot(db2, archive, scheme); err != nil {
+		t.Fatalf("failed to import snapshot: %v", err)
+	}
+	chain2, err = core.NewBlockChain(db2, core.DefaultCacheConfigWithScheme(scheme), genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to reopen chain: %v", err)
+	}
+	defer chain2.Stop()
+
+	if have := chain2.CurrentBlock().Hash(); have != head.Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", have, head.Hash())
+	}
+	have, err := chain2.StateAt(head.Root())
+	if err != nil {
+		t.Fatalf("imported state not available: %v", err)
+	}
+	for _, addr := range []common.Address{address, contract, {0x2}, {0x10}} {
+		if have.GetBalance(addr).Cmp(want.GetBalance(addr)) != 0 {
+			t.Errorf("balance mismatch of %x: have %v, want %v", addr, have.GetBalance(addr), want.GetBalance(addr))
+		}
+		if have.GetNonce(addr) != want.GetNonce(addr) {
+			t.Errorf("nonce mismatch of %x: have %d, want %d", addr, have.GetNonce(addr), want.GetNonce(addr))
+		}
+		if !bytes.Equal(have.GetCode(addr), want.GetCode(addr)) {
+			t.Errorf("code mismatch of %x", addr)
+		}
+	}
+	for _, slot := range []common.Hash{{}, {0x1}} {
+		if have.GetState(contract, slot) != want.GetState(contract, slot) {
+			t.Errorf("storage mismatch of slot %x: have %x, want %x", slot, have.GetState(contract, slot), want.GetState(contract, slot))
+		}
+	}
+	if receipts := chain2.GetReceiptsByHash(head.Hash()); len(receipts) != len(head.Transactions()) {
+		t.Errorf("receipt count mismatch: have %d, want %d", len(receipts), len(head.Transactions()))
+	}
+	// The canonical header chain below the archived block is restored
+	for _, block := range blocks[:15] {
+		if header := chain2.GetHeaderByNumber(block.NumberU64()); header == nil || header.Hash() != block.Hash() {
+			t.Fatalf("canonical header %d missing", block.NumberU64())
+		}
+	}
+	// The imported chain must be extendable and freezable, skipping the
+	// missing history of the ancestors
+	if _, err := chain2.InsertChain(blocks[16:]); err != nil {
+		t.Fatalf("failed to extend imported chain: %v", err)
+	}
+	rawdb.WriteFinalizedBlockHash(db2, blocks[16].Hash())
+	if err := db2.(interface{ Freeze() error }).Freeze(); err != nil {
+		t.Fatalf("failed to freeze chain: %v", err)
+	}
+	if frozen, _ := db2.Ancients(); frozen != 18 {
+		t.Fatalf("frozen blocks mismatch: have %d, want %d", frozen, 18)
+	}
+	if tail, _ := db2.Tail(); tail != head.NumberU64() {
+		t.Fatalf("history tail mismatch: have %d, want %d", tail, head.NumberU64())
+	}
+	if rawdb.ReadChainHistoryGap(db2) != nil {
+		t.Fatal("history gap not cleared after freezing")
+	}
+	if chain2.GetBlockByNumber(1) != nil {
+		t.Fatal("placeholder body served for skipped history")
+	}
+	for _, block := range []*types.Block{head, blocks[16]} {
+		if have := chain2.GetBlockByNumber(block.NumberU64()); have == nil || have.Hash() != block.Hash() {
+			t.Fatalf("frozen block %d missing", block.NumberU64())
+		}
+	}
+	// Importing again must be rejected, the database is not fresh anymore
+	if err := ImportSnapshot(db2, archive, scheme); err == nil {
+		t.Fatal("expected error importing into a non-fresh database")
+	}
+}
+
+func TestSnapshotImportCorrupted(t *testing.T) {
+	var (
+		genesis = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{common.Address{0x1}: {Balance: big.NewInt(1)}},
+		}
+	)
+	db := rawdb.NewMemoryDatabase()
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.HashScheme), genesis, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	head := chain.GetBlockByHash(chain.CurrentBlock().Hash())
+	archive := filepath.Join(t.TempDir(), "state.snap")
+	if err := ExportSnapshot(db, chain.Snapshots(), archive, head); err != nil {
+		t.Fatalf("failed to export snapshot: %v", err)
+	}
+	chain.Stop()
+
+	// Flip a byte in the last chunk and check that the import fails
+	blob, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob[len(blob)-1] ^= 0xff
+	if err := os.WriteFile(archive, blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	db2 := rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db2, triedb.NewDatabase(db2, triedb.HashDefaults))
+	if err := ImportSnapshot(db2, archive, rawdb.HashScheme); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("corrupted archive error mismatch: have %v", err)
+	}
+}
@@ -463,6 +463,11 @@ func isString(input []byte) bool {
 	return len(input) >= 2 && input[0] == '"' && input[len(input)-1] == '"'
 }
 
+// MarshalJSON encodes the number as a decimal string.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(d), 10))
+}
+
 // UnmarshalJSON parses a hash in hex syntax.
 func (d *Decimal) UnmarshalJSON(input []byte) error {
 	if !isString(input) {
@@ -36,12 +36,14 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/syncx"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/ethereum/go-ethereum/log"
@@ -94,6 +96,12 @@ var (
 	errChainStopped         = errors.New("blockchain is stopped")
 	errInvalidOldChain      = errors.New("invalid old chain")
 	errInvalidNewChain      = errors.New("invalid new chain")
+
+	errPruningUnsupported = errors.New("online state pruning is only supported in hash scheme without archive mode or partial state")
+
+	// errPartialResolverMissing is returned if a partial state node needs the
+	// storage of an untracked contract before a remote resolver is configured.
+	errPartialResolverMissing = errors.New("no resolver for untracked partial state")
 )
 
 const (
@@ -132,15 +140,21 @@ const (
 // CacheConfig contains the configuration values for the trie database
 // and state snapshot these are resident in a blockchain.
 type CacheConfig struct {
-	TrieCleanLimit      int           // Memory allowance (MB) to use for caching trie nodes in memory
-	TrieCleanNoPrefetch bool          // Whether to disable heuristic state prefetching for followup blocks
-	TrieDirtyLimit      int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
-	TrieDirtyDisabled   bool          // Whether to disable trie write caching and GC altogether (archive node)
-	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
-	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
-	Preimages           bool          // Whether to store preimage of trie key to the disk
-	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
-	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	TrieCleanLimit      int              // Memory allowance (MB) to use for caching trie nodes in memory
+	TrieCleanNoPrefetch bool             // Whether to disable heuristic state prefetching for followup blocks
+	TrieDirtyLimit      int              // Memory limit (MB) at which to start flushing dirty trie nodes to disk
+	TrieDirtyDisabled   bool             // Whether to disable trie write caching and GC altogether (archive node)
+	TrieTimeLimit       time.Duration    // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit       int              // Memory allowance (MB) to use for caching snapshot entries in memory
+	Preimages           bool             // Whether to store preimage of trie key to the disk
+	StateHistory        uint64           // Number of blocks from head whose state histories are reserved.
+	StateHistoryIndex   bool             // Whether to index state histories for historical state reads (path scheme only)
+	StateScheme         string           // Scheme used to store ethereum states and merkle tree nodes on top
+	LogIndex            bool             // Whether to maintain the address and topic log index
+	LogHistory          uint64           // Number of blocks from head whose logs are indexed (0 = entire chain)
+	PartialState        []common.Address // Contracts whose storage is tracked by a partial state node, empty for full state
+	HistoryExpiry       uint64           // Number of blocks from head whose bodies and receipts are retained (0 = entire chain)
+	HistoryArchive      string           // Directory of era files serving the expired chain history, empty if not available
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -160,6 +174,7 @@ func (c *CacheConfig) triedbConfig(isVerkle bool) *triedb.Config {
 	if c.StateScheme == rawdb.PathScheme {
 		config.PathDB = &pathdb.Config{
 			StateHistory:   c.StateHistory,
+			HistoryIndex:   c.StateHistoryIndex,
 			CleanCacheSize: c.TrieCleanLimit * 1024 * 1024,
 			DirtyCacheSize: c.TrieDirtyLimit * 1024 * 1024,
 		}
@@ -220,6 +235,13 @@ type BlockChain struct {
 	triedb        *triedb.Database                 // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 	txIndexer     *txIndexer                       // Transaction indexer, might be nil if not enabled
+	logIndexer    *logIndexer                      // Log indexer, might be nil if not enabled
+	pruner        *pruner.OnlinePruner             // Online state pruner, nil if not supported
+	expirer       *historyExpirer                  // Chain history expirer, nil if not enabled
+	eraStore      *era.Store                       // Era1 archive of the expired chain history, nil if not available
+	partial       *state.PartialState              // Tracked contracts of the partial state node, nil for full state
+	execCache     state.Database                   // State database for block execution, resolving untracked partial state remotely
+	resolver      atomic.Value                     // Resolver of the untracked partial state nodes (state.NodeResolver)
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -306,7 +328,21 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	}
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
-	bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
+	if len(cacheConfig.PartialState) > 0 {
+		if cacheConfig.TrieDirtyDisabled {
+			return nil, errors.New("partial state is not supported in archive mode")
+		}
+		if bc.triedb.Scheme() != rawdb.HashScheme {
+			return nil, errors.New("partial state is only supported in hash scheme")
+		}
+		bc.partial = state.NewPartialState(cacheConfig.PartialState)
+		bc.stateCache = state.NewPartialDatabase(bc.db, bc.triedb, bc.partial, nil)
+		bc.execCache = state.NewPartialDatabase(bc.db, bc.triedb, bc.partial, bc.resolvePartialNode)
+		log.Info("Running as partial state node", "contracts", len(cacheConfig.PartialState))
+	} else {
+		bc.stateCache = state.NewDatabaseWithNodeDB(bc.db, bc.triedb)
+		bc.execCache = bc.stateCache
+	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine)
@@ -467,6 +503,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 			NoBuild:    bc.cacheConfig.SnapshotNoBuild,
 			AsyncBuild: !bc.cacheConfig.SnapshotWait,
 		}
+		if bc.partial != nil {
+			snapconfig.Tracked = bc.partial.TrackedHash
+		}
 		bc.snaps, _ = snapshot.New(snapconfig, bc.db, bc.triedb, head.Root)
 	}
 	// Rewind the chain in case of an incompatible config upgrade.
@@ -480,10 +519,58 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
 
+	// The transaction and log indexes are derived from the block bodies and
+	// receipts, restrict them to the retained chain history if it's expired.
+	if limit := cacheConfig.HistoryExpiry; limit != 0 {
+		if txLookupLimit != nil && (*txLookupLimit == 0 || *txLookupLimit > limit) {
+			log.Warn("Restricting transaction index to retained chain history", "provided", *txLookupLimit, "updated", limit)
+			txLookupLimit = &limit
+		}
+		if cacheConfig.LogIndex && (cacheConfig.LogHistory == 0 || cacheConfig.LogHistory > limit) {
+			log.Warn("Restricting log index to retained chain history", "provided", cacheConfig.LogHistory, "updated", limit)
+			cacheConfig.LogHistory = limit
+		}
+	}
 	// Start tx indexer if it's enabled.
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
 	}
+	// Start log indexer if it's enabled, otherwise invalidate any stale index
+	// left behind by a previous run, as it's not maintained anymore.
+	if cacheConfig.LogIndex {
+		bc.logIndexer = newLogIndexer(cacheConfig.LogHistory, bc)
+	} else if rawdb.ReadLogIndexTail(db) != nil {
+		log.Warn("Log index disabled, dropping stale index marker")
+		rawdb.DeleteLogIndexTail(db)
+	}
+	// Start the chain history expirer if it's enabled, and open the era archive
+	// for serving the expired history if it's configured.
+	if cacheConfig.HistoryExpiry != 0 {
+		bc.expirer = newHistoryExpirer(cacheConfig.HistoryExpiry, bc)
+	}
+	if cacheConfig.HistoryArchive != "" {
+		network := "unknown"
+		if name, ok := params.NetworkNames[chainConfig.ChainID.String()]; ok {
+			network = name
+		}
+		bc.eraStore, err = era.OpenStore(cacheConfig.HistoryArchive, network)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open era archive: %w", err)
+		}
+		log.Info("Opened era archive of chain history", "dir", cacheConfig.HistoryArchive, "network", network)
+	}
+	// Set up the online state pruner if the state is maintained in hash scheme
+	// with garbage collection, and resume the interrupted pruning if any. It's
+	// not supported by the partial state node, whose storage tries are only
+	// complete for the tracked contracts.
+	if bc.triedb.Scheme() == rawdb.HashScheme && !cacheConfig.TrieDirtyDisabled && bc.partial == nil {
+		bc.pruner = pruner.NewOnlinePruner(db, bc.triedb, bc.recentStateRoots)
+		if rawdb.ReadSnapSyncStatusFlag(db) != rawdb.StateSyncRunning {
+			if err := bc.pruner.Resume(); err != nil {
+				log.Error("Failed to resume online state pruning", "err", err)
+			}
+		}
+	}
 	return bc, nil
 }
 
@@ -501,6 +588,22 @@ func (bc *BlockChain) empty() bool {
 	return true
 }
 
+// SetPartialResolver sets the resolver used by the partial state node to fetch
+// the storage trie nodes of untracked contracts touched by block execution.
+func (bc *BlockChain) SetPartialResolver(resolver state.NodeResolver) {
+	bc.resolver.Store(resolver)
+}
+
+// resolvePartialNode retrieves an untracked trie node through the configured
+// partial state resolver.
+func (bc *BlockChain) resolvePartialNode(stateRoot, owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	resolver, _ := bc.resolver.Load().(state.NodeResolver)
+	if resolver == nil {
+		return nil, errPartialResolverMissing
+	}
+	return resolver(stateRoot, owner, path, hash)
+}
+
 // loadLastState loads the last known chain state from the database. This method
 // assumes that the chain manager mutex is held.
 func (bc *BlockChain) loadLastState() error {
@@ -1073,6 +1176,9 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 	rawdb.WriteHeadFastBlockHash(batch, block.Hash())
 	rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
 	rawdb.WriteTxLookupEntriesByBlock(batch, block)
+	if bc.logIndexer != nil {
+		rawdb.WriteLogIndexEntries(batch, block.NumberU64(), rawdb.ReadRawReceipts(bc.db, block.Hash(), block.NumberU64()))
+	}
 	rawdb.WriteHeadBlockHash(batch, block.Hash())
 
 	// Flush the whole batch into the disk, exit the node if failed
@@ -1103,6 +1209,18 @@ func (bc *BlockChain) stopWithoutSaving() {
 	if bc.txIndexer != nil {
 		bc.txIndexer.close()
 	}
+	// Signal shutdown log indexer.
+	if bc.logIndexer != nil {
+		bc.logIndexer.close()
+	}
+	// Signal shutdown online state pruner.
+	if bc.pruner != nil {
+		bc.pruner.Close()
+	}
+	// Signal shutdown chain history expirer.
+	if bc.expirer != nil {
+		bc.expirer.close()
+	}
 	// Unsubscribe all subscriptions registered from blockchain.
 	bc.scope.Close()
 
@@ -1150,10 +1268,10 @@ func (bc *BlockChain) Stop() {
 
 			for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
 				if number := bc.CurrentBlock().Number.Uint64(); number > offset {
-					recent := bc.GetBlockByNumber(number - offset)
+					recent := bc.GetHeaderByNumber(number - offset)
 
-					log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
-					if err := triedb.Commit(recent.Root(), true); err != nil {
+					log.Info("Writing cached state to disk", "block", recent.Number, "hash", recent.Hash(), "root", recent.Root)
+					if err := triedb.Commit(recent.Root, true); err != nil {
 						log.Error("Failed to commit recent state trie", "err", err)
 					}
 				}
@@ -1172,7 +1290,13 @@ func (bc *BlockChain) Stop() {
 			}
 		}
 	}
-	// Close the trie database, release all the held resources as the last step.
+	// Close the era archive and the trie database, release all the held
+	// resources as the last step.
+	if bc.eraStore != nil {
+		if err := bc.eraStore.Close(); err != nil {
+			log.Error("Failed to close era archive", "err", err)
+		}
+	}
 	if err := bc.triedb.Close(); err != nil {
 		log.Error("Failed to close trie database", "err", err)
 	}
@@ -1647,7 +1771,6 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 	if bc.insertStopped() {
 		return 0, nil
 	}
-
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
 	SenderCacher.RecoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number(), chain[0].Time()), chain)
 
@@ -1821,7 +1944,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		if parent == nil {
 			parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
 		}
-		statedb, err := state.New(parent.Root, bc.stateCache, bc.snaps)
+		statedb, err := state.New(parent.Root, bc.execCache, bc.snaps)
 		if err != nil {
 			return it.index, err
 		}
@@ -1836,7 +1959,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		var followupInterrupt atomic.Bool
 		if !bc.cacheConfig.TrieCleanNoPrefetch {
 			if followup, err := it.peek(); followup != nil && err == nil {
-				throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
+				throwaway, _ := state.New(parent.Root, bc.execCache, bc.snaps)
 
 				go func(start time.Time, followup *types.Block, throwaway *state.StateDB) {
 					// Disable tracing for prefetcher executions.
@@ -1936,6 +2059,9 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 	pstart := time.Now()
 	receipts, logs, usedGas, err := bc.processor.Process(block, statedb, bc.vmConfig)
 	if err != nil {
+		if err := statedb.Error(); errors.Is(err, state.ErrStateUnavailable) {
+			return nil, err // the block is not bad, the partial state is just incomplete
+		}
 		bc.reportBlock(block, receipts, err)
 		return nil, err
 	}
@@ -1943,6 +2069,9 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 
 	vstart := time.Now()
 	if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		if err := statedb.Error(); errors.Is(err, state.ErrStateUnavailable) {
+			return nil, err
+		}
 		bc.reportBlock(block, receipts, err)
 		return nil, err
 	}
@@ -2554,3 +2683,54 @@ func (bc *BlockChain) SetTrieFlushInterval(interval time.Duration) {
 func (bc *BlockChain) GetTrieFlushInterval() time.Duration {
 	return time.Duration(bc.flushInterval.Load())
 }
+
+// StartStatePruning launches the online state pruning in the background, or
+// resumes the paused one.
+func (bc *BlockChain) StartStatePruning(config pruner.OnlineConfig) error {
+	if bc.pruner == nil {
+		return errPruningUnsupported
+	}
+	if rawdb.ReadSnapSyncStatusFlag(bc.db) == rawdb.StateSyncRunning {
+		return errors.New("state is being synced")
+	}
+	return bc.pruner.Start(config)
+}
+
+// PauseStatePruning pauses the running online state pruning.
+func (bc *BlockChain) PauseStatePruning() error {
+	if bc.pruner == nil {
+		return errPruningUnsupported
+	}
+	return bc.pruner.Pause()
+}
+
+// StatePruningProgress returns the progress report of the online state pruning.
+func (bc *BlockChain) StatePruningProgress() (*pruner.OnlineProgress, error) {
+	if bc.pruner == nil {
+		return nil, errPruningUnsupported
+	}
+	return bc.pruner.Progress(), nil
+}
+
+// recentStateRoots returns the roots of the available states of the recent
+// canonical blocks which must be retained by the online state pruning, the
+// head state first.
+func (bc *BlockChain) recentStateRoots() []common.Hash {
+	var (
+		roots []common.Hash
+		head  = bc.CurrentBlock().Number.Uint64()
+	)
+	for number := head; ; number-- {
+		header := bc.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		if bc.HasState(header.Root) {
+			roots = append(roots, header.Root)
+		}
+		if number == 0 || head-number >= TriesInMemory {
+			break
+		}
+	}
+	return roots
+}
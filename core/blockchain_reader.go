@@ -106,7 +106,10 @@ func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 	}
 	body := rawdb.ReadBody(bc.db, hash, *number)
 	if body == nil {
-		return nil
+		// The body might be expired, try serving it from the era archive
+		if body, _ = bc.readArchivedBodyRLP(hash, *number); body == nil {
+			return nil
+		}
 	}
 	// Cache the found body for next time and return
 	bc.bodyCache.Add(hash, body)
@@ -126,7 +129,10 @@ func (bc *BlockChain) GetBodyRLP(hash common.Hash) rlp.RawValue {
 	}
 	body := rawdb.ReadBodyRLP(bc.db, hash, *number)
 	if len(body) == 0 {
-		return nil
+		// The body might be expired, try serving it from the era archive
+		if _, body = bc.readArchivedBodyRLP(hash, *number); len(body) == 0 {
+			return nil
+		}
 	}
 	// Cache the found body for next time and return
 	bc.bodyRLPCache.Add(hash, body)
@@ -164,7 +170,12 @@ func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	}
 	block := rawdb.ReadBlock(bc.db, hash, number)
 	if block == nil {
-		return nil
+		// The body might be expired, try serving it from the era archive
+		body, _ := bc.readArchivedBodyRLP(hash, number)
+		if body == nil {
+			return nil
+		}
+		block = types.NewBlockWithHeader(bc.GetHeader(hash, number)).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
 	}
 	// Cache the found block for next time and return
 	bc.blockCache.Add(block.Hash(), block)
@@ -224,7 +235,10 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	}
 	receipts := rawdb.ReadReceipts(bc.db, hash, *number, header.Time, bc.chainConfig)
 	if receipts == nil {
-		return nil
+		// The receipts might be expired, try serving them from the era archive
+		if receipts = bc.readArchivedReceipts(header); receipts == nil {
+			return nil
+		}
 	}
 	bc.receiptsCache.Add(hash, receipts)
 	return receipts
@@ -385,6 +399,12 @@ func (bc *BlockChain) StateCache() state.Database {
 	return bc.stateCache
 }
 
+// PartialState returns the set of contracts whose storage is tracked if the
+// node is running as a partial state node, or nil if the full state is kept.
+func (bc *BlockChain) PartialState() *state.PartialState {
+	return bc.partial
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (bc *BlockChain) GasLimit() uint64 {
 	return bc.CurrentBlock().GasLimit
@@ -408,6 +428,24 @@ func (bc *BlockChain) TxIndexProgress() (TxIndexProgress, error) {
 	return bc.txIndexer.txIndexProgress()
 }
 
+// LogIndexTail returns the number of the oldest block whose logs are indexed,
+// meaning that the log index covers the range [tail, head]. Nil is returned if
+// the log indexer is not enabled or the initial indexing is not yet finished.
+func (bc *BlockChain) LogIndexTail() *uint64 {
+	if bc.logIndexer == nil {
+		return nil
+	}
+	return rawdb.ReadLogIndexTail(bc.db)
+}
+
+// LogIndexProgress returns the log indexing progress.
+func (bc *BlockChain) LogIndexProgress() (LogIndexProgress, error) {
+	if bc.logIndexer == nil {
+		return LogIndexProgress{}, errors.New("log indexer is not enabled")
+	}
+	return bc.logIndexer.logIndexProgress()
+}
+
 // TrieDB retrieves the low level trie database used for data storage.
 func (bc *BlockChain) TrieDB() *triedb.Database {
 	return bc.triedb
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -40,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
 	"github.com/holiman/uint256"
 )
 
@@ -4321,3 +4323,319 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that the historical states are accessible in path scheme with state
+// history indexing enabled, once they have been flushed out of the layer tree.
+func TestHistoricalStateRead(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		bb     = common.HexToAddress("0x000000000000000000000000000000000000bbbb")
+		cc     = common.HexToAddress("0x000000000000000000000000000000000000cccc")
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				addr: {Balance: big.NewInt(params.Ether)},
+				// The address 0xCCCC stores the block number in slot 0x00
+				cc: {
+					Code: []byte{
+						byte(vm.NUMBER),
+						byte(vm.PUSH1), 0,
+						byte(vm.SSTORE),
+					},
+					Balance: big.NewInt(0),
+				},
+			},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		blocks = 2 * TriesInMemory
+	)
+	_, chain, _ := GenerateChainWithGenesis(gspec, engine, blocks, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), bb, big.NewInt(1), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(b.TxNonce(addr), cc, big.NewInt(0), 50000, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	defer db.Close()
+
+	config := DefaultCacheConfigWithScheme(rawdb.PathScheme)
+	config.StateHistoryIndex = true
+	bc, err := NewBlockChain(db, config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, number := range []int{1, TriesInMemory / 2, blocks - TriesInMemory - 1} {
+		block := chain[number-1]
+		statedb, err := bc.StateAt(block.Root())
+		if err != nil {
+			t.Fatalf("failed to open historical state %d: %v", number, err)
+		}
+		if balance := statedb.GetBalance(bb); balance.Uint64() != uint64(number) {
+			t.Fatalf("block %d: balance mismatch, want %d, got %d", number, number, balance)
+		}
+		if slot := statedb.GetState(cc, common.Hash{}); slot != common.BigToHash(big.NewInt(int64(number))) {
+			t.Fatalf("block %d: storage mismatch, want %d, got %x", number, number, slot)
+		}
+	}
+}
+
+// Tests that the online state pruning deletes the stale states while retaining
+// the recent states, the genesis state and the states created in the meantime.
+func TestOnlineStatePruning(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(gspec.Config)
+		blocks = 2 * TriesInMemory
+	)
+	_, chain, _ := GenerateChainWithGenesis(gspec, engine, blocks+10, func(i int, b *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), common.Address{byte(i)}, big.NewInt(1), params.TxGas, b.header.BaseFee, nil), signer, key)
+		b.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	defer db.Close()
+
+	bc, err := NewBlockChain(db, DefaultCacheConfigWithScheme(rawdb.HashScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer bc.Stop()
+
+	if _, err := bc.InsertChain(chain[:blocks]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Persist a few stale states which are supposed to be pruned
+	stale := []common.Hash{chain[9].Root(), chain[19].Root()}
+	for _, root := range stale {
+		if err := bc.triedb.Commit(root, false); err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+	}
+	if err := bc.StartStatePruning(pruner.OnlineConfig{BloomSize: 256}); err != nil {
+		t.Fatalf("failed to start pruning: %v", err)
+	}
+	// Extend the chain while pruning
+	if _, err := bc.InsertChain(chain[blocks:]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i := 0; ; i++ {
+		progress, _ := bc.StatePruningProgress()
+		if progress.Status == pruner.OnlineStatusDone {
+			break
+		}
+		if progress.Status == pruner.OnlineStatusFailed || i == 100 {
+			t.Fatalf("pruning is not finished, status: %s, err: %s", progress.Status, progress.Error)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if rawdb.ReadOnlinePruning(db) != nil {
+		t.Fatal("pruning progress marker is not deleted")
+	}
+	for _, root := range stale {
+		if bc.HasState(root) {
+			t.Fatalf("stale state %x is not pruned", root)
+		}
+	}
+	// Persist the recent states and ensure they are complete
+	head := bc.CurrentBlock()
+	for _, number := range []uint64{0, head.Number.Uint64() - TriesInMemory + 1, head.Number.Uint64()} {
+		root := bc.GetHeaderByNumber(number).Root
+		if err := bc.triedb.Commit(root, false); err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		tr, err := trie.NewStateTrie(trie.StateTrieID(root), triedb.NewDatabase(db, triedb.HashDefaults))
+		if err != nil {
+			t.Fatalf("state %d is not available: %v", number, err)
+		}
+		it, err := tr.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("state %d is not available: %v", number, err)
+		}
+		for it.Next(true) {
+		}
+		if it.Error() != nil {
+			t.Fatalf("state %d is not complete: %v", number, it.Error())
+		}
+	}
+}
+
+// Tests that the partial state node keeps following the chain after the sync:
+// the blocks on top of the synced state are executed, resolving the untracked
+// storage remotely while only persisting the tracked one.
+func TestPartialStateImport(t *testing.T) {
+	var (
+		engine    = ethash.NewFaker()
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr      = crypto.PubkeyToAddress(key.PublicKey)
+		tracked   = common.Address{0xaa}
+		untracked = common.Address{0xbb}
+
+		// Store the block number both at slot zero and at its own slot
+		code = []byte{byte(vm.NUMBER), byte(vm.NUMBER), byte(vm.SSTORE), byte(vm.NUMBER), byte(vm.PUSH1), 0x0, byte(vm.SSTORE), byte(vm.STOP)}
+
+		// Keep the storage tries distinct, the nodes are shared otherwise
+		trackedStorage   = make(map[common.Hash]common.Hash)
+		untrackedStorage = make(map[common.Hash]common.Hash)
+	)
+	for i := 0; i < 32; i++ {
+		trackedStorage[common.Hash{byte(i + 1)}] = common.Hash{0xaa}
+		untrackedStorage[common.Hash{byte(i + 1)}] = common.Hash{0xbb}
+	}
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			addr:      {Balance: big.NewInt(params.Ether)},
+			tracked:   {Code: code, Storage: trackedStorage},
+			untracked: {Code: code, Storage: untrackedStorage},
+		},
+	}
+	signer := types.LatestSigner(gspec.Config)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *BlockGen) {
+		for _, to := range []common.Address{tracked, untracked} {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(addr), to, new(big.Int), 100000, b.header.BaseFee, nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	// Create an archive node to sync and resolve the state from
+	archiveConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	archiveConfig.TrieDirtyDisabled = true
+
+	archive, err := NewBlockChain(rawdb.NewMemoryDatabase(), archiveConfig, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create archive chain: %v", err)
+	}
+	defer archive.Stop()
+
+	if _, err := archive.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert archive chain: %v", err)
+	}
+	// Snap sync the partial state node up to the pivot block
+	config := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	config.PartialState = []common.Address{tracked}
+
+	db := rawdb.NewMemoryDatabase()
+	bc, err := NewBlockChain(db, config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create partial chain: %v", err)
+	}
+	defer bc.Stop()
+
+	pivot := 4
+	headers := make([]*types.Header, pivot)
+	for i, block := range blocks[:pivot] {
+		headers[i] = block.Header()
+	}
+	if _, err := bc.InsertHeaderChain(headers); err != nil {
+		t.Fatalf("failed to insert header chain: %v", err)
+	}
+	if _, err := bc.InsertReceiptChain(blocks[:pivot], receipts[:pivot], 0); err != nil {
+		t.Fatalf("failed to insert receipt chain: %v", err)
+	}
+	root := blocks[pivot-1].Root()
+	reader, err := archive.triedb.Reader(root)
+	if err != nil {
+		t.Fatalf("failed to open archive state: %v", err)
+	}
+	sched := state.NewPartialStateSync(root, db, nil, rawdb.HashScheme, bc.PartialState())
+	for paths, nodes, codes := sched.Missing(0); len(paths)+len(codes) > 0; paths, nodes, codes = sched.Missing(0) {
+		for _, hash := range codes {
+			if err := sched.ProcessCode(trie.CodeSyncResult{Hash: hash, Data: rawdb.ReadCode(archive.db, hash)}); err != nil {
+				t.Fatalf("failed to process code: %v", err)
+			}
+		}
+		for i, path := range paths {
+			owner, inner := trie.ResolvePath([]byte(path))
+			blob, err := reader.Node(owner, inner, nodes[i])
+			if err != nil {
+				t.Fatalf("failed to retrieve node: %v", err)
+			}
+			if err := sched.ProcessNode(trie.NodeSyncResult{Path: path, Data: blob}); err != nil {
+				t.Fatalf("failed to process node: %v", err)
+			}
+		}
+		batch := db.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		batch.Write()
+	}
+	if err := bc.SnapSyncCommitHead(blocks[pivot-1].Hash()); err != nil {
+		t.Fatalf("failed to commit head: %v", err)
+	}
+	// Execution must fail without marking the block bad if the untracked
+	// storage can't be resolved
+	bc.SetPartialResolver(func(stateRoot, owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+		return nil, errors.New("no peers")
+	})
+	if _, err := bc.InsertChain(blocks[pivot:]); !errors.Is(err, state.ErrStateUnavailable) {
+		t.Fatalf("block execution error mismatch: have %v, want %v", err, state.ErrStateUnavailable)
+	}
+	if rawdb.ReadBadBlock(db, blocks[pivot].Hash()) != nil {
+		t.Fatal("block with unavailable state marked as bad")
+	}
+	if head := bc.CurrentBlock().Number.Uint64(); head != uint64(pivot) {
+		t.Fatalf("head mismatch: have %d, want %d", head, pivot)
+	}
+	// Import the blocks after the pivot with the untracked storage resolved
+	// from the archive node
+	var resolved int
+	bc.SetPartialResolver(func(stateRoot, owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+		if owner != crypto.Keccak256Hash(untracked.Bytes()) {
+			t.Errorf("unexpected resolution of %x", owner)
+		}
+		resolved++
+		reader, err := archive.triedb.Reader(stateRoot)
+		if err != nil {
+			return nil, err
+		}
+		return reader.Node(owner, path, hash)
+	})
+	if _, err := bc.InsertChain(blocks[pivot:]); err != nil {
+		t.Fatalf("failed to import blocks after the sync: %v", err)
+	}
+	if head := bc.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head mismatch: have %d, want %d", head.Number, len(blocks))
+	}
+	if resolved == 0 {
+		t.Fatal("untracked storage not resolved")
+	}
+	// The tracked storage is maintained, the untracked one is not stored
+	statedb, err := bc.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	number := common.BigToHash(big.NewInt(int64(len(blocks))))
+	if value := statedb.GetState(tracked, common.Hash{}); value != number {
+		t.Fatalf("tracked slot mismatch: have %x, want %x", value, number)
+	}
+	if value := statedb.GetState(tracked, number); value != number {
+		t.Fatalf("tracked slot mismatch: have %x, want %x", value, number)
+	}
+	if err := statedb.Error(); err != nil {
+		t.Fatalf("failed to read tracked storage: %v", err)
+	}
+	statedb.GetState(untracked, common.Hash{})
+	if err := statedb.Error(); !errors.Is(err, state.ErrStateNotTracked) {
+		t.Fatalf("untracked storage error mismatch: have %v, want %v", err, state.ErrStateNotTracked)
+	}
+	headReader, err := bc.triedb.Reader(bc.CurrentBlock().Root)
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	owner := crypto.Keccak256Hash(untracked.Bytes())
+	if blob, _ := headReader.Node(owner, nil, statedb.GetStorageRoot(untracked)); len(blob) != 0 {
+		t.Fatal("untracked storage persisted")
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// historyExpirer is the module responsible for dropping the block bodies and
+// receipts older than the configured retention range from the ancient store
+// (EIP-4444). Headers, hashes and total difficulties are always retained.
+type historyExpirer struct {
+	// limit is the number of blocks from head whose bodies and receipts
+	// are retained, i.e. the latest N blocks [HEAD-N+1, HEAD].
+	limit  uint64
+	db     ethdb.Database
+	txs    bool // Whether the transaction indexes are maintained
+	logs   bool // Whether the log indexes are maintained
+	term   chan chan struct{}
+	closed chan struct{}
+}
+
+// newHistoryExpirer initializes the chain history expirer.
+func newHistoryExpirer(limit uint64, chain *BlockChain) *historyExpirer {
+	expirer := &historyExpirer{
+		limit:  limit,
+		db:     chain.db,
+		txs:    chain.txIndexer != nil,
+		logs:   chain.logIndexer != nil,
+		term:   make(chan chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go expirer.loop(chain)
+
+	log.Info("Initialized chain history expiry", "range", limit)
+	return expirer
+}
+
+// target returns the new tail of the chain history according to the given
+// chain head. The indexes derived from the chain history are not allowed to
+// refer to expired data, the tail is capped by them until they are shortened
+// by the indexers.
+func (expirer *historyExpirer) target(head uint64) (uint64, bool) {
+	if head < expirer.limit {
+		return 0, false
+	}
+	target := head - expirer.limit + 1
+
+	// Only the chain segments already moved to the ancient store can be expired
+	frozen, err := expirer.db.Ancients()
+	if err != nil {
+		return 0, false
+	}
+	if target > frozen {
+		target = frozen
+	}
+	if expirer.txs {
+		tail := rawdb.ReadTxIndexTail(expirer.db)
+		if tail == nil {
+			return 0, false
+		}
+		if target > *tail {
+			target = *tail
+		}
+	}
+	if expirer.logs {
+		tail := rawdb.ReadLogIndexTail(expirer.db)
+		if tail == nil {
+			return 0, false
+		}
+		if target > *tail {
+			target = *tail
+		}
+	}
+	return target, true
+}
+
+// run expires the chain history below the retention range of the given head.
+func (expirer *historyExpirer) run(head uint64) {
+	target, ok := expirer.target(head)
+	if !ok {
+		return
+	}
+	tail, err := expirer.db.Tail()
+	if err != nil || tail >= target {
+		return
+	}
+	if _, err := expirer.db.TruncateTail(target); err != nil {
+		log.Error("Failed to expire chain history", "tail", tail, "target", target, "err", err)
+		return
+	}
+	log.Debug("Expired chain history", "from", tail, "to", target)
+}
+
+// loop is the scheduler of the expirer, expiring the chain history as the
+// chain head progresses.
+func (expirer *historyExpirer) loop(chain *BlockChain) {
+	defer close(expirer.closed)
+
+	var (
+		headCh = make(chan ChainHeadEvent)
+		sub    = chain.SubscribeChainHeadEvent(headCh)
+	)
+	defer sub.Unsubscribe()
+
+	if head := rawdb.ReadHeadBlock(expirer.db); head != nil {
+		expirer.run(head.NumberU64())
+	}
+	for {
+		select {
+		case head := <-headCh:
+			expirer.run(head.Block.NumberU64())
+		case ch := <-expirer.term:
+			close(ch)
+			return
+		}
+	}
+}
+
+// close shutdown the expirer. Safe to be called for multiple times.
+func (expirer *historyExpirer) close() {
+	ch := make(chan struct{})
+	select {
+	case expirer.term <- ch:
+		<-ch
+	case <-expirer.closed:
+	}
+}
+
+// HistoryTail returns the number of the first block whose body and receipts
+// are retained in the database, the earlier ones have been expired.
+func (bc *BlockChain) HistoryTail() uint64 {
+	tail, err := bc.db.Tail()
+	if err != nil {
+		return 0
+	}
+	return tail
+}
+
+// EarliestServedBlock returns the number of the first block whose body and
+// receipts can be served to remote peers. Expired history is still available
+// if the era1 archive covers it up to the database tail.
+func (bc *BlockChain) EarliestServedBlock() uint64 {
+	tail := bc.HistoryTail()
+	if bc.eraStore == nil {
+		return tail
+	}
+	first, next, err := bc.eraStore.Bounds()
+	if err != nil || first > tail || next < tail {
+		return tail
+	}
+	return first
+}
+
+// HistoryPruned reports whether the body and receipts of the given block have
+// been expired from the database. The genesis block is always retained.
+func (bc *BlockChain) HistoryPruned(number uint64) bool {
+	return number != 0 && number < bc.HistoryTail()
+}
+
+// readArchivedBodyRLP retrieves the body of an expired block from the era1
+// archive, verifying it against the header. Nil is returned if the body is
+// not available.
+func (bc *BlockChain) readArchivedBodyRLP(hash common.Hash, number uint64) (*types.Body, rlp.RawValue) {
+	if bc.eraStore == nil || !bc.HistoryPruned(number) {
+		return nil, nil
+	}
+	header := bc.GetHeader(hash, number)
+	if header == nil {
+		return nil, nil
+	}
+	data, err := bc.eraStore.GetRawBody(number)
+	if err != nil {
+		log.Debug("Failed to read archived block body", "number", number, "hash", hash, "err", err)
+		return nil, nil
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(data, body); err != nil {
+		log.Error("Invalid archived block body RLP", "number", number, "hash", hash, "err", err)
+		return nil, nil
+	}
+	if err := verifyArchivedBody(header, body); err != nil {
+		log.Error("Invalid archived block body", "number", number, "hash", hash, "err", err)
+		return nil, nil
+	}
+	return body, data
+}
+
+// readArchivedReceipts retrieves the receipts of an expired block from the
+// era archive, verifying them against the header and deriving the metadata
+// fields. Nil is returned if the receipts are not available.
+func (bc *BlockChain) readArchivedReceipts(header *types.Header) types.Receipts {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	if bc.eraStore == nil || !bc.HistoryPruned(number) {
+		return nil
+	}
+	body := bc.GetBody(hash)
+	if body == nil {
+		return nil
+	}
+	data, err := bc.eraStore.GetRawReceipts(number)
+	if err != nil {
+		log.Debug("Failed to read archived receipts", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(data, &receipts); err != nil {
+		log.Error("Invalid archived receipts RLP", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	if root := types.DeriveSha(receipts, trie.NewStackTrie(nil)); root != header.ReceiptHash {
+		log.Error("Invalid archived receipts", "number", number, "hash", hash, "have", root, "want", header.ReceiptHash)
+		return nil
+	}
+	baseFee := header.BaseFee
+	if baseFee == nil {
+		baseFee = big.NewInt(0)
+	}
+	var blobGasPrice *big.Int
+	if header.ExcessBlobGas != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*header.ExcessBlobGas)
+	}
+	if err := receipts.DeriveFields(bc.chainConfig, hash, number, header.Time, baseFee, blobGasPrice, body.Transactions); err != nil {
+		log.Error("Failed to derive archived receipts fields", "number", number, "hash", hash, "err", err)
+		return nil
+	}
+	return receipts
+}
+
+// verifyArchivedBody checks that the body read from the era archive belongs
+// to the given header.
+func verifyArchivedBody(header *types.Header, body *types.Body) error {
+	if hash := types.CalcUncleHash(body.Uncles); hash != header.UncleHash {
+		return errors.New("uncle root hash mismatch")
+	}
+	if hash := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return errors.New("transaction root hash mismatch")
+	}
+	if header.WithdrawalsHash != nil {
+		if body.Withdrawals == nil {
+			return errors.New("missing withdrawals in block body")
+		}
+		if hash := types.DeriveSha(types.Withdrawals(body.Withdrawals), trie.NewStackTrie(nil)); hash != *header.WithdrawalsHash {
+			return errors.New("withdrawals root hash mismatch")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TestHistoryExpirer tests that the chain history is expired according to the
+// configured limit, capped by the transaction indexes.
+func TestHistoryExpirer(t *testing.T) {
+	var (
+		testBankKey, _  = crypto.GenerateKey()
+		testBankAddress = crypto.PubkeyToAddress(testBankKey.PublicKey)
+		testBankFunds   = big.NewInt(1000000000000000000)
+
+		gspec = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		engine    = ethash.NewFaker()
+		nonce     = uint64(0)
+		chainHead = uint64(128)
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, engine, int(chainHead), func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.HexToAddress("0xdeadbeef"), big.NewInt(1000), params.TxGas, big.NewInt(10*params.InitialBaseFee), nil), types.HomesteadSigner{}, testBankKey)
+		gen.AddTx(tx)
+		nonce += 1
+	})
+	var cases = []struct {
+		limit  uint64
+		txTail uint64 // Transaction index tail, 0 if not maintained
+		tail   uint64
+	}{
+		// History retained for the entire chain
+		{limit: 129, tail: 0},
+		// Block [65, 128] retained
+		{limit: 64, tail: 65},
+		// Block-128 retained
+		{limit: 1, tail: 128},
+		// Block [65, 128] retained, transactions indexed for [100, 128]
+		{limit: 64, txTail: 100, tail: 65},
+		// Block [40, 128] retained, transactions indexed for [40, 128]
+		{limit: 64, txTail: 40, tail: 40},
+	}
+	for i, c := range cases {
+		frdir := t.TempDir()
+		db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "", false)
+		rawdb.WriteAncientBlocks(db, append([]*types.Block{gspec.ToBlock()}, blocks...), append([]types.Receipts{{}}, receipts...), big.NewInt(0))
+		if c.txTail != 0 {
+			rawdb.WriteTxIndexTail(db, c.txTail)
+		}
+		expirer := &historyExpirer{
+			limit: c.limit,
+			db:    db,
+			txs:   c.txTail != 0,
+		}
+		expirer.run(chainHead)
+
+		if tail, _ := db.Tail(); tail != c.tail {
+			t.Fatalf("case %d: unexpected history tail, want %d, got %d", i, c.tail, tail)
+		}
+		for number := uint64(1); number <= chainHead; number++ {
+			hash := blocks[number-1].Hash()
+			if header := rawdb.ReadHeader(db, hash, number); header == nil {
+				t.Fatalf("case %d: missing header %d", i, number)
+			}
+			body, receipts := rawdb.ReadBodyRLP(db, hash, number), rawdb.ReadReceiptsRLP(db, hash, number)
+			if number < c.tail && (body != nil || receipts != nil) {
+				t.Fatalf("case %d: unexpected body or receipts %d", i, number)
+			}
+			if number >= c.tail && (body == nil || receipts == nil) {
+				t.Fatalf("case %d: missing body or receipts %d", i, number)
+			}
+		}
+		db.Close()
+		os.RemoveAll(frdir)
+	}
+}
+
+// TestHistoryExpiryArchive tests that the expired chain history is reported as
+// pruned, and served from the era1 archive if it's available.
+func TestHistoryExpiryArchive(t *testing.T) {
+	var (
+		testBankKey, _  = crypto.GenerateKey()
+		testBankAddress = crypto.PubkeyToAddress(testBankKey.PublicKey)
+		testBankFunds   = big.NewInt(1000000000000000000)
+
+		gspec = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   types.GenesisAlloc{testBankAddress: {Balance: testBankFunds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		engine    = ethash.NewFaker()
+		nonce     = uint64(0)
+		chainHead = uint64(128)
+		config    = *defaultCacheConfig
+	)
+	_, blocks, receipts := GenerateChainWithGenesis(gspec, engine, int(chainHead), func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.HexToAddress("0xdeadbeef"), big.NewInt(1000), params.TxGas, big.NewInt(10*params.InitialBaseFee), nil), types.HomesteadSigner{}, testBankKey)
+		gen.AddTx(tx)
+		nonce += 1
+	})
+	db, _ := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	defer db.Close()
+
+	config.HistoryExpiry = 32
+	chain, err := NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Move the finalized chain segment into the ancient store and expire it
+	type freezer interface {
+		Freeze() error
+	}
+	chain.SetFinalized(blocks[119].Header())
+	db.(freezer).Freeze()
+	chain.expirer.run(chainHead)
+
+	if tail := chain.HistoryTail(); tail != chainHead-config.HistoryExpiry+1 {
+		t.Fatalf("unexpected history tail, want %d, got %d", chainHead-config.HistoryExpiry+1, tail)
+	}
+	// Export the chain history into an era1 archive before closing the chain
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "history.era1"))
+	if err != nil {
+		t.Fatalf("failed to create era1 file: %v", err)
+	}
+	builder := era.NewBuilder(f)
+	if err := builder.Add(chain.Genesis(), nil, chain.GetTd(chain.Genesis().Hash(), 0)); err != nil {
+		t.Fatalf("failed to add genesis to era1: %v", err)
+	}
+	for i, block := range blocks {
+		if err := builder.Add(block, receipts[i], chain.GetTd(block.Hash(), block.NumberU64())); err != nil {
+			t.Fatalf("failed to add block %d to era1: %v", block.NumberU64(), err)
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize era1: %v", err)
+	}
+	f.Close()
+	os.Rename(f.Name(), filepath.Join(dir, era.Filename(params.NetworkNames[params.TestChainConfig.ChainID.String()], 0, root)))
+
+	chain.Stop()
+
+	// Reopen the chain without archive, the expired history should be reported
+	// as pruned
+	chain, err = NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	for number := uint64(0); number <= chainHead; number++ {
+		pruned := number != 0 && number < chain.HistoryTail()
+		if chain.HistoryPruned(number) != pruned {
+			t.Fatalf("block %d: unexpected pruned flag, want %v", number, pruned)
+		}
+		if chain.GetHeaderByNumber(number) == nil {
+			t.Fatalf("block %d: missing header", number)
+		}
+		if block := chain.GetBlockByNumber(number); (block == nil) != pruned {
+			t.Fatalf("block %d: unexpected block availability, pruned %v", number, pruned)
+		}
+	}
+	chain.Stop()
+
+	// Reopen the chain with the era1 archive, the expired history should be served
+	config.HistoryArchive = dir
+	chain, err = NewBlockChain(db, &config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	for i, block := range blocks {
+		have := chain.GetBlockByNumber(block.NumberU64())
+		if have == nil || have.Hash() != block.Hash() || have.Transactions().Len() != block.Transactions().Len() {
+			t.Fatalf("block %d: mismatched block", block.NumberU64())
+		}
+		if body := chain.GetBodyRLP(block.Hash()); len(body) == 0 {
+			t.Fatalf("block %d: missing body", block.NumberU64())
+		}
+		haveReceipts := chain.GetReceiptsByHash(block.Hash())
+		if len(haveReceipts) != len(receipts[i]) {
+			t.Fatalf("block %d: mismatched receipts, want %d, got %d", block.NumberU64(), len(receipts[i]), len(haveReceipts))
+		}
+		for j, receipt := range haveReceipts {
+			if receipt.TxHash != receipts[i][j].TxHash || receipt.BlockHash != block.Hash() || receipt.GasUsed != receipts[i][j].GasUsed {
+				t.Fatalf("block %d: mismatched receipt %d", block.NumberU64(), j)
+			}
+		}
+	}
+}
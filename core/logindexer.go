@@ -0,0 +1,208 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>
+
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// LogIndexProgress is the struct describing the progress for log indexing.
+type LogIndexProgress struct {
+	Indexed   uint64 // number of blocks whose logs are indexed
+	Remaining uint64 // number of blocks whose logs are not indexed yet
+}
+
+// Done returns an indicator if the log indexing is finished.
+func (progress LogIndexProgress) Done() bool {
+	return progress.Remaining == 0
+}
+
+// logIndexer is the module responsible for maintaining the address and topic
+// log indexes according to the configured indexing range by users.
+type logIndexer struct {
+	// limit is the maximum number of blocks from head whose log indexes
+	// are reserved:
+	//  * 0: means the entire chain should be indexed
+	//  * N: means the latest N blocks [HEAD-N+1, HEAD] should be indexed
+	//       and all others shouldn't.
+	limit    uint64
+	db       ethdb.Database
+	progress chan chan LogIndexProgress
+	term     chan chan struct{}
+	closed   chan struct{}
+}
+
+// newLogIndexer initializes the log indexer.
+func newLogIndexer(limit uint64, chain *BlockChain) *logIndexer {
+	indexer := &logIndexer{
+		limit:    limit,
+		db:       chain.db,
+		progress: make(chan chan LogIndexProgress),
+		term:     make(chan chan struct{}),
+		closed:   make(chan struct{}),
+	}
+	go indexer.loop(chain)
+
+	var msg string
+	if limit == 0 {
+		msg = "entire chain"
+	} else {
+		msg = fmt.Sprintf("last %d blocks", limit)
+	}
+	log.Info("Initialized log indexer", "range", msg)
+
+	return indexer
+}
+
+// run executes the scheduled indexing/unindexing task in a separate thread.
+// If the stop channel is closed, the task should be terminated as soon as
+// possible, the done channel will be closed once the task is finished.
+func (indexer *logIndexer) run(tail *uint64, head uint64, stop chan struct{}, done chan struct{}) {
+	defer func() { close(done) }()
+
+	// Short circuit if chain is empty and nothing to index.
+	if head == 0 {
+		return
+	}
+	// The tail flag is not existent, index the chain according to the
+	// configured limit.
+	if tail == nil {
+		from := uint64(0)
+		if indexer.limit != 0 && head >= indexer.limit {
+			from = head - indexer.limit + 1
+		}
+		rawdb.IndexLogs(indexer.db, from, head+1, stop, true)
+		return
+	}
+	// The tail flag is existent, while the whole chain is requested for indexing.
+	if indexer.limit == 0 || head < indexer.limit {
+		if *tail > 0 {
+			end := *tail
+			if end > head+1 {
+				end = head + 1
+			}
+			rawdb.IndexLogs(indexer.db, 0, end, stop, true)
+		}
+		return
+	}
+	// The tail flag is existent, adjust the index range according to configured
+	// limit and the latest chain head.
+	if head-indexer.limit+1 < *tail {
+		rawdb.IndexLogs(indexer.db, head-indexer.limit+1, *tail, stop, true)
+	} else {
+		rawdb.UnindexLogs(indexer.db, *tail, head-indexer.limit+1, stop, false)
+	}
+}
+
+// loop is the scheduler of the indexer, assigning indexing/unindexing tasks depending
+// on the received chain event.
+func (indexer *logIndexer) loop(chain *BlockChain) {
+	defer close(indexer.closed)
+
+	var (
+		stop     chan struct{}                        // Non-nil if background routine is active.
+		done     chan struct{}                        // Non-nil if background routine is active.
+		lastHead uint64                               // The latest announced chain head (whose log indexes are assumed created)
+		lastTail = rawdb.ReadLogIndexTail(indexer.db) // The oldest indexed block, nil means nothing indexed
+
+		headCh = make(chan ChainHeadEvent)
+		sub    = chain.SubscribeChainHeadEvent(headCh)
+	)
+	defer sub.Unsubscribe()
+
+	// Launch the initial processing if chain is not empty (head != genesis).
+	if head := rawdb.ReadHeadBlock(indexer.db); head != nil && head.Number().Uint64() != 0 {
+		stop = make(chan struct{})
+		done = make(chan struct{})
+		lastHead = head.Number().Uint64()
+		go indexer.run(rawdb.ReadLogIndexTail(indexer.db), head.NumberU64(), stop, done)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				stop = make(chan struct{})
+				done = make(chan struct{})
+				go indexer.run(rawdb.ReadLogIndexTail(indexer.db), head.Block.NumberU64(), stop, done)
+			}
+			lastHead = head.Block.NumberU64()
+		case <-done:
+			stop = nil
+			done = nil
+			lastTail = rawdb.ReadLogIndexTail(indexer.db)
+		case ch := <-indexer.progress:
+			ch <- indexer.report(lastHead, lastTail)
+		case ch := <-indexer.term:
+			if stop != nil {
+				close(stop)
+			}
+			if done != nil {
+				log.Info("Waiting background log indexer to exit")
+				<-done
+			}
+			close(ch)
+			return
+		}
+	}
+}
+
+// report returns the log indexing progress.
+func (indexer *logIndexer) report(head uint64, tail *uint64) LogIndexProgress {
+	total := indexer.limit
+	if indexer.limit == 0 || total > head {
+		total = head + 1 // genesis included
+	}
+	var indexed uint64
+	if tail != nil {
+		indexed = head - *tail + 1
+	}
+	var remaining uint64
+	if indexed < total {
+		remaining = total - indexed
+	}
+	return LogIndexProgress{
+		Indexed:   indexed,
+		Remaining: remaining,
+	}
+}
+
+// logIndexProgress retrieves the log indexing progress, or an error if the
+// background log indexer is already stopped.
+func (indexer *logIndexer) logIndexProgress() (LogIndexProgress, error) {
+	ch := make(chan LogIndexProgress, 1)
+	select {
+	case indexer.progress <- ch:
+		return <-ch, nil
+	case <-indexer.closed:
+		return LogIndexProgress{}, errors.New("indexer is closed")
+	}
+}
+
+// close shutdown the indexer. Safe to be called for multiple times.
+func (indexer *logIndexer) close() {
+	ch := make(chan struct{})
+	select {
+	case indexer.term <- ch:
+		<-ch
+	case <-indexer.closed:
+	}
+}
@@ -278,6 +278,33 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadChainHistoryGap retrieves the number of the first block whose body and
+// receipts are available, if the blocks below it (except genesis) were never
+// stored, e.g. because the chain was seeded from a state snapshot archive.
+func ReadChainHistoryGap(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(chainHistoryGapKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteChainHistoryGap stores the number of the first block whose body and
+// receipts are available into database.
+func WriteChainHistoryGap(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(chainHistoryGapKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the chain history gap", "err", err)
+	}
+}
+
+// DeleteChainHistoryGap removes the chain history gap marker from database.
+func DeleteChainHistoryGap(db ethdb.KeyValueWriter) {
+	if err := db.Delete(chainHistoryGapKey); err != nil {
+		log.Crit("Failed to delete the chain history gap", "err", err)
+	}
+}
+
 // ReadHeaderRange returns the rlp-encoded headers, starting at 'number', and going
 // backwards towards genesis. This method assumes that the caller already has
 // placed a cap on count, to prevent DoS issues.
@@ -433,9 +460,12 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerBodiesTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, or if it's expired from the ancients (the genesis is always
+		// retained in leveldb), try reading from leveldb
 		data, _ = db.Get(blockBodyKey(number, hash))
 		return nil
 	})
@@ -576,9 +606,12 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(ChainFreezerReceiptTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, or if it's expired from the ancients (the genesis is always
+		// retained in leveldb), try reading from leveldb
 		data, _ = db.Get(blockReceiptsKey(number, hash))
 		return nil
 	})
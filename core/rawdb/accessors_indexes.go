@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -179,3 +180,146 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// LogIndexEntry is the log index entry of an address or topic within a single
+// block, listing the positions of the logs referencing it.
+type LogIndexEntry struct {
+	Number uint64   // Number of the block containing the logs
+	Logs   []uint32 // Positions of the logs within the block
+}
+
+// ReadLogIndexTail retrieves the number of the oldest block whose logs have
+// been indexed.
+func ReadLogIndexTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(logIndexTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteLogIndexTail stores the number of the oldest block whose logs have been
+// indexed into the database.
+func WriteLogIndexTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(logIndexTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the log index tail", "err", err)
+	}
+}
+
+// DeleteLogIndexTail removes the log index tail marker, invalidating the index.
+func DeleteLogIndexTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(logIndexTailKey); err != nil {
+		log.Crit("Failed to delete the log index tail", "err", err)
+	}
+}
+
+// logIndexPositions groups the positions of the logs in a block by the address
+// and the topics they reference.
+func logIndexPositions(receipts types.Receipts) (map[common.Address][]uint32, map[common.Hash][]uint32) {
+	var (
+		addresses = make(map[common.Address][]uint32)
+		topics    = make(map[common.Hash][]uint32)
+		position  uint32
+	)
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if list := addresses[log.Address]; len(list) == 0 || list[len(list)-1] != position {
+				addresses[log.Address] = append(list, position)
+			}
+			for _, topic := range log.Topics {
+				if list := topics[topic]; len(list) == 0 || list[len(list)-1] != position {
+					topics[topic] = append(list, position)
+				}
+			}
+			position++
+		}
+	}
+	return addresses, topics
+}
+
+// WriteLogIndexEntries stores the log index entries of all the addresses and
+// topics referenced by the logs of the given block.
+func WriteLogIndexEntries(db ethdb.KeyValueWriter, number uint64, receipts types.Receipts) {
+	addresses, topics := logIndexPositions(receipts)
+	for address, positions := range addresses {
+		blob, err := rlp.EncodeToBytes(positions)
+		if err != nil {
+			log.Crit("Failed to encode log index entry", "err", err)
+		}
+		if err := db.Put(logIndexAddressKey(address, number), blob); err != nil {
+			log.Crit("Failed to store log index entry", "err", err)
+		}
+	}
+	for topic, positions := range topics {
+		blob, err := rlp.EncodeToBytes(positions)
+		if err != nil {
+			log.Crit("Failed to encode log index entry", "err", err)
+		}
+		if err := db.Put(logIndexTopicKey(topic, number), blob); err != nil {
+			log.Crit("Failed to store log index entry", "err", err)
+		}
+	}
+}
+
+// DeleteLogIndexEntries removes the log index entries of all the addresses and
+// topics referenced by the logs of the given block.
+func DeleteLogIndexEntries(db ethdb.KeyValueWriter, number uint64, receipts types.Receipts) {
+	addresses, topics := logIndexPositions(receipts)
+	for address := range addresses {
+		if err := db.Delete(logIndexAddressKey(address, number)); err != nil {
+			log.Crit("Failed to delete log index entry", "err", err)
+		}
+	}
+	for topic := range topics {
+		if err := db.Delete(logIndexTopicKey(topic, number)); err != nil {
+			log.Crit("Failed to delete log index entry", "err", err)
+		}
+	}
+}
+
+// ReadLogIndexByAddress retrieves the log index entries of an address within
+// the block range [from, to], in ascending block order.
+//
+// Note, the index is keyed by block number and entries of reorged blocks may be
+// left behind, so the returned blocks must be verified against the canonical logs.
+func ReadLogIndexByAddress(db ethdb.Iteratee, address common.Address, from uint64, to uint64) []LogIndexEntry {
+	return readLogIndex(db, logIndexAddressKey(address, from), logIndexAddressKey(address, to))
+}
+
+// ReadLogIndexByTopic retrieves the log index entries of a topic within the
+// block range [from, to], in ascending block order.
+//
+// Note, the index is keyed by block number and entries of reorged blocks may be
+// left behind, so the returned blocks must be verified against the canonical logs.
+func ReadLogIndexByTopic(db ethdb.Iteratee, topic common.Hash, from uint64, to uint64) []LogIndexEntry {
+	return readLogIndex(db, logIndexTopicKey(topic, from), logIndexTopicKey(topic, to))
+}
+
+// readLogIndex iterates the log index entries between the start and the end
+// key, both included.
+func readLogIndex(db ethdb.Iteratee, start []byte, end []byte) []LogIndexEntry {
+	var (
+		prefix  = start[:len(start)-8]
+		entries []LogIndexEntry
+	)
+	it := db.NewIterator(prefix, start[len(prefix):])
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(start) || bytes.Compare(key, end) > 0 {
+			break
+		}
+		var positions []uint32
+		if err := rlp.DecodeBytes(it.Value(), &positions); err != nil {
+			log.Error("Invalid log index entry RLP", "key", key, "err", err)
+			continue
+		}
+		entries = append(entries, LogIndexEntry{
+			Number: binary.BigEndian.Uint64(key[len(prefix):]),
+			Logs:   positions,
+		})
+	}
+	return entries
+}
@@ -137,6 +137,117 @@ func WritePersistentStateID(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadOnlinePruning retrieves the serialized online state pruning progress
+// saved at the last batch.
+func ReadOnlinePruning(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(onlinePruningKey)
+	return data
+}
+
+// WriteOnlinePruning stores the serialized online state pruning progress.
+func WriteOnlinePruning(db ethdb.KeyValueWriter, progress []byte) {
+	if err := db.Put(onlinePruningKey, progress); err != nil {
+		log.Crit("Failed to store online pruning progress", "err", err)
+	}
+}
+
+// DeleteOnlinePruning deletes the online state pruning progress.
+func DeleteOnlinePruning(db ethdb.KeyValueWriter) {
+	if err := db.Delete(onlinePruningKey); err != nil {
+		log.Crit("Failed to remove online pruning progress", "err", err)
+	}
+}
+
+// ReadStateHistoryIndexHead retrieves the id of the latest indexed state history.
+// Nil is returned if the state histories are not indexed.
+func ReadStateHistoryIndexHead(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(stateHistoryIndexHeadKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteStateHistoryIndexHead stores the id of the latest indexed state history.
+func WriteStateHistoryIndexHead(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Put(stateHistoryIndexHeadKey, encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store the state history index head", "err", err)
+	}
+}
+
+// DeleteStateHistoryIndexHead removes the state history index head marker,
+// invalidating the indexes.
+func DeleteStateHistoryIndexHead(db ethdb.KeyValueWriter) {
+	if err := db.Delete(stateHistoryIndexHeadKey); err != nil {
+		log.Crit("Failed to delete the state history index head", "err", err)
+	}
+}
+
+// WriteAccountHistoryIndex marks the account as mutated in the state history
+// with the given id.
+func WriteAccountHistoryIndex(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Put(stateHistoryAccountIndexKey(address, id), nil); err != nil {
+		log.Crit("Failed to store account history index", "err", err)
+	}
+}
+
+// DeleteAccountHistoryIndex removes the account history index entry of the
+// state history with the given id.
+func DeleteAccountHistoryIndex(db ethdb.KeyValueWriter, address common.Address, id uint64) {
+	if err := db.Delete(stateHistoryAccountIndexKey(address, id)); err != nil {
+		log.Crit("Failed to delete account history index", "err", err)
+	}
+}
+
+// WriteStorageHistoryIndex marks the storage slot as mutated in the state
+// history with the given id.
+func WriteStorageHistoryIndex(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, id uint64) {
+	if err := db.Put(stateHistoryStorageIndexKey(address, slot, id), nil); err != nil {
+		log.Crit("Failed to store storage history index", "err", err)
+	}
+}
+
+// DeleteStorageHistoryIndex removes the storage history index entry of the
+// state history with the given id.
+func DeleteStorageHistoryIndex(db ethdb.KeyValueWriter, address common.Address, slot common.Hash, id uint64) {
+	if err := db.Delete(stateHistoryStorageIndexKey(address, slot, id)); err != nil {
+		log.Crit("Failed to delete storage history index", "err", err)
+	}
+}
+
+// ReadAccountHistoryIndexNext retrieves the id of the first state history in
+// the range (after, limit] which mutated the given account.
+func ReadAccountHistoryIndexNext(db ethdb.Iteratee, address common.Address, after uint64, limit uint64) (uint64, bool) {
+	return readHistoryIndexNext(db, stateHistoryAccountIndexKey(address, 0), after, limit)
+}
+
+// ReadStorageHistoryIndexNext retrieves the id of the first state history in
+// the range (after, limit] which mutated the given storage slot.
+func ReadStorageHistoryIndexNext(db ethdb.Iteratee, address common.Address, slot common.Hash, after uint64, limit uint64) (uint64, bool) {
+	return readHistoryIndexNext(db, stateHistoryStorageIndexKey(address, slot, 0), after, limit)
+}
+
+// readHistoryIndexNext seeks the first state history index entry with the given
+// prefix (the key of the zero id) in the range (after, limit].
+func readHistoryIndexNext(db ethdb.Iteratee, key []byte, after uint64, limit uint64) (uint64, bool) {
+	if after >= limit {
+		return 0, false
+	}
+	prefix := key[:len(key)-8]
+	it := db.NewIterator(prefix, encodeBlockNumber(after+1))
+	defer it.Release()
+
+	if !it.Next() || len(it.Key()) != len(key) {
+		return 0, false
+	}
+	id := binary.BigEndian.Uint64(it.Key()[len(prefix):])
+	if id > limit {
+		return 0, false
+	}
+	return id, true
+}
+
 // ReadTrieJournal retrieves the serialized in-memory trie nodes of layers saved at
 // the last shutdown.
 func ReadTrieJournal(db ethdb.KeyValueReader) []byte {
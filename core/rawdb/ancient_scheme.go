@@ -46,6 +46,14 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerDifficultyTable: true,
 }
 
+// chainFreezerPrunable configures whether the ancient-tables are subject to
+// history expiry. Block bodies and receipts can be dropped from the tail, while
+// headers, hashes and difficulties are always retained.
+var chainFreezerPrunable = map[string]bool{
+	ChainFreezerBodiesTable:  true,
+	ChainFreezerReceiptTable: true,
+}
+
 const (
 	// stateHistoryTableSize defines the maximum size of freezer data files.
 	stateHistoryTableSize = 2 * 1000 * 1000 * 1000
@@ -25,8 +25,9 @@ import (
 )
 
 type tableSize struct {
-	name string
-	size common.StorageSize
+	name     string
+	size     common.StorageSize
+	prunable bool // Whether the table is subject to tail truncation
 }
 
 // freezerInfo contains the basic information of the freezer.
@@ -42,6 +43,15 @@ func (info *freezerInfo) count() uint64 {
 	return info.head - info.tail + 1
 }
 
+// items returns the number of stored items in the specified table. The tables
+// which are not subject to tail truncation retain all items since genesis.
+func (info *freezerInfo) items(table tableSize) uint64 {
+	if table.prunable {
+		return info.count()
+	}
+	return info.head + 1
+}
+
 // size returns the storage size of the entire freezer.
 func (info *freezerInfo) size() common.StorageSize {
 	var total common.StorageSize
@@ -51,14 +61,20 @@ func (info *freezerInfo) size() common.StorageSize {
 	return total
 }
 
-func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
+// inspect collects the information of the freezer. The tables marked in
+// 'prunable' are subject to tail truncation, nil means all of them are.
+func inspect(name string, order map[string]bool, prunable map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
 	info := freezerInfo{name: name}
 	for t := range order {
 		size, err := reader.AncientSize(t)
 		if err != nil {
 			return freezerInfo{}, err
 		}
-		info.sizes = append(info.sizes, tableSize{name: t, size: common.StorageSize(size)})
+		info.sizes = append(info.sizes, tableSize{
+			name:     t,
+			size:     common.StorageSize(size),
+			prunable: prunable == nil || prunable[t],
+		})
 	}
 	// Retrieve the number of last stored item
 	ancients, err := reader.Ancients()
@@ -82,7 +98,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	for _, freezer := range freezers {
 		switch freezer {
 		case ChainFreezerName:
-			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db)
+			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, chainFreezerPrunable, db)
 			if err != nil {
 				return nil, err
 			}
@@ -102,7 +118,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 			}
 			defer f.Close()
 
-			info, err := inspect(StateFreezerName, stateFreezerNoSnappy, f)
+			info, err := inspect(StateFreezerName, stateFreezerNoSnappy, nil, f)
 			if err != nil {
 				return nil, err
 			}
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
 )
 
 const (
@@ -43,22 +44,33 @@ const (
 // The background thread will keep moving ancient chain segments from key-value
 // database to flat files for saving space on live database.
 type chainFreezer struct {
-	*Freezer
-	quit    chan struct{}
-	wg      sync.WaitGroup
-	trigger chan chan struct{} // Manual blocking freeze trigger, test determinism
+	ethdb.AncientStore
+	readonly bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+	trigger  chan chan struct{} // Manual blocking freeze trigger, test determinism
 }
 
-// newChainFreezer initializes the freezer for ancient chain data.
-func newChainFreezer(datadir string, namespace string, readonly bool) (*chainFreezer, error) {
-	freezer, err := NewChainFreezer(datadir, namespace, readonly)
+// newChainFreezer initializes the freezer for ancient chain data. If a remote
+// object store is given, the completed segments are offloaded into it.
+func newChainFreezer(datadir string, namespace string, readonly bool, remote ethdb.ObjectStore) (*chainFreezer, error) {
+	var (
+		freezer ethdb.AncientStore
+		err     error
+	)
+	if remote != nil {
+		freezer, err = newRemoteFreezer(datadir, namespace, readonly, chainFreezerNoSnappy, chainFreezerPrunable, remote, remoteSegmentItems)
+	} else {
+		freezer, err = NewChainFreezer(datadir, namespace, readonly)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return &chainFreezer{
-		Freezer: freezer,
-		quit:    make(chan struct{}),
-		trigger: make(chan chan struct{}),
+		AncientStore: freezer,
+		readonly:     readonly,
+		quit:         make(chan struct{}),
+		trigger:      make(chan chan struct{}),
 	}, nil
 }
 
@@ -70,7 +82,7 @@ func (f *chainFreezer) Close() error {
 		close(f.quit)
 	}
 	f.wg.Wait()
-	return f.Freezer.Close()
+	return f.AncientStore.Close()
 }
 
 // readHeadNumber returns the number of chain head block. 0 is returned if the
@@ -167,7 +179,7 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 			log.Debug("Current full block not old enough to freeze", "err", err)
 			continue
 		}
-		frozen := f.frozen.Load()
+		frozen, _ := f.Ancients()
 
 		// Short circuit if the blocks below threshold are already frozen.
 		if frozen != 0 && frozen-1 >= threshold {
@@ -194,6 +206,17 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 		if err := f.Sync(); err != nil {
 			log.Crit("Failed to flush frozen tables", "err", err)
 		}
+		// Hide the placeholders frozen for the history gap, and drop the gap
+		// marker once it's entirely frozen
+		if gap := ReadChainHistoryGap(nfdb); gap != nil {
+			tail := min(*gap, first+uint64(len(ancients)))
+			if _, err := f.TruncateTail(tail); err != nil {
+				log.Crit("Failed to skip chain history gap", "err", err)
+			}
+			if tail == *gap {
+				DeleteChainHistoryGap(db)
+			}
+		}
 		// Wipe out all data from the active database
 		batch := db.NewBatch()
 		for i := 0; i < len(ancients); i++ {
@@ -210,7 +233,7 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 
 		// Wipe out side chains also and track dangling side chains
 		var dangling []common.Hash
-		frozen = f.frozen.Load() // Needs reload after during freezeRange
+		frozen, _ = f.Ancients() // Needs reload after during freezeRange
 		for number := first; number < frozen; number++ {
 			// Always keep the genesis block in active database
 			if number != 0 {
@@ -282,6 +305,12 @@ func (f *chainFreezer) freeze(db ethdb.KeyValueStore) {
 func (f *chainFreezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hashes []common.Hash, err error) {
 	hashes = make([]common.Hash, 0, limit-number+1)
 
+	// The bodies and receipts below the history gap were never stored, they're
+	// frozen as placeholders and hidden by the tail truncation afterwards.
+	var gap uint64
+	if n := ReadChainHistoryGap(nfdb); n != nil {
+		gap = *n
+	}
 	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
 		for ; number <= limit; number++ {
 			// Retrieve all the components of the canonical block.
@@ -294,10 +323,13 @@ func (f *chainFreezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hash
 				return fmt.Errorf("block header missing, can't freeze block %d", number)
 			}
 			body := ReadBodyRLP(nfdb, hash, number)
+			receipts := ReadReceiptsRLP(nfdb, hash, number)
+			if number > 0 && number < gap {
+				body, receipts = rlp.EmptyList, rlp.EmptyList
+			}
 			if len(body) == 0 {
 				return fmt.Errorf("block body missing, can't freeze block %d", number)
 			}
-			receipts := ReadReceiptsRLP(nfdb, hash, number)
 			if len(receipts) == 0 {
 				return fmt.Errorf("block receipts missing, can't freeze block %d", number)
 			}
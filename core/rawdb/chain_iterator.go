@@ -361,3 +361,150 @@ func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	unindexTransactions(db, from, to, interrupt, hook, false)
 }
+
+// IndexLogs creates log index entries for the specified block range. The from
+// is included while to is excluded.
+//
+// Similarly to the transaction indexer, the canonical chain is iterated in
+// reverse order, so that the tail flag can be written periodically and an
+// interrupted indexing can be resumed quickly. If the receipts of a block are
+// missing (e.g. pruned history), the indexing stops at that block.
+func IndexLogs(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, report bool) {
+	// short circuit for invalid range
+	if from >= to {
+		return
+	}
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start.Add(-7 * time.Second)
+
+		tail         = to
+		blocks, logs = 0, 0 // for stats reporting
+		aborted      bool
+	)
+loop:
+	for next := to; next > from; next-- {
+		select {
+		case <-interrupt:
+			aborted = true
+			break loop
+		default:
+		}
+		number := next - 1
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			break
+		}
+		receipts := ReadRawReceipts(db, hash, number)
+		if receipts == nil {
+			log.Warn("Missing receipts for log indexing", "number", number, "hash", hash)
+			break
+		}
+		WriteLogIndexEntries(batch, number, receipts)
+		for _, receipt := range receipts {
+			logs += len(receipt.Logs)
+		}
+		blocks++
+		tail = number
+
+		// If enough data was accumulated in memory, dump to disk
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			WriteLogIndexTail(batch, tail) // Also write the tail here
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed writing batch to db", "error", err)
+				return
+			}
+			batch.Reset()
+		}
+		// If we've spent too much time already, notify the user of what we're doing
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing logs", "blocks", blocks, "logs", logs, "tail", tail, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// Flush the new indexing tail and the last committed data
+	WriteLogIndexTail(batch, tail)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed writing batch to db", "error", err)
+		return
+	}
+	logger := log.Debug
+	if report {
+		logger = log.Info
+	}
+	if aborted {
+		logger("Log indexing interrupted", "blocks", blocks, "logs", logs, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	} else {
+		logger("Indexed logs", "blocks", blocks, "logs", logs, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// UnindexLogs removes the log index entries of the specified block range. The
+// from is included while to is excluded.
+//
+// If the receipts of a block are missing (e.g. pruned history), its entries
+// cannot be located and are left behind. This is harmless, as the entries are
+// only ever used as hints and verified against the canonical logs.
+func UnindexLogs(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, report bool) {
+	// short circuit for invalid range
+	if from >= to {
+		return
+	}
+	var (
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start.Add(-7 * time.Second)
+
+		tail    = from
+		blocks  = 0 // for stats reporting
+		aborted bool
+	)
+loop:
+	for number := from; number < to; number++ {
+		select {
+		case <-interrupt:
+			aborted = true
+			break loop
+		default:
+		}
+		if hash := ReadCanonicalHash(db, number); hash != (common.Hash{}) {
+			if receipts := ReadRawReceipts(db, hash, number); receipts != nil {
+				DeleteLogIndexEntries(batch, number, receipts)
+			}
+		}
+		blocks++
+		tail = number + 1
+
+		// A batch counts the size of deletion as '1', so we need to flush more
+		// often than that.
+		if blocks%1000 == 0 {
+			WriteLogIndexTail(batch, tail)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed writing batch to db", "error", err)
+				return
+			}
+			batch.Reset()
+		}
+		// If we've spent too much time already, notify the user of what we're doing
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Unindexing logs", "blocks", blocks, "total", to-from, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	// Flush the new indexing tail and the last committed data
+	WriteLogIndexTail(batch, tail)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed writing batch to db", "error", err)
+		return
+	}
+	logger := log.Debug
+	if report {
+		logger = log.Info
+	}
+	if aborted {
+		logger("Log unindexing interrupted", "blocks", blocks, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	} else {
+		logger("Unindexed logs", "blocks", blocks, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
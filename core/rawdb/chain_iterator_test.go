@@ -206,3 +206,59 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestIndexLogs(t *testing.T) {
+	// Construct test chain db, every block emitting a single log from one of
+	// two alternating addresses with a topic unique to the block.
+	chainDb := NewMemoryDatabase()
+
+	addresses := []common.Address{{0x01}, {0x02}}
+	for i := uint64(0); i <= 10; i++ {
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, nil, nil, nil, newTestHasher())
+		WriteBlock(chainDb, block)
+		WriteCanonicalHash(chainDb, block.Hash(), block.NumberU64())
+
+		receipt := &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			Logs: []*types.Log{
+				{Address: addresses[i%2], Topics: []common.Hash{{byte(i)}}},
+			},
+		}
+		WriteReceipts(chainDb, block.Hash(), block.NumberU64(), types.Receipts{receipt})
+	}
+	verify := func(from, to uint64, exist bool, tail uint64) {
+		for i := from; i < to; i++ {
+			entries := ReadLogIndexByTopic(chainDb, common.Hash{byte(i)}, 0, 10)
+			if exist && (len(entries) != 1 || entries[0].Number != i || !reflect.DeepEqual(entries[0].Logs, []uint32{0})) {
+				t.Fatalf("Log index %d mismatch: %v", i, entries)
+			}
+			if !exist && len(entries) != 0 {
+				t.Fatalf("Log index %d is not deleted", i)
+			}
+			entries = ReadLogIndexByAddress(chainDb, addresses[i%2], i, i)
+			if exist != (len(entries) == 1) {
+				t.Fatalf("Address log index %d mismatch: %v", i, entries)
+			}
+		}
+		number := ReadLogIndexTail(chainDb)
+		if number == nil || *number != tail {
+			t.Fatalf("Log index tail mismatch")
+		}
+	}
+	IndexLogs(chainDb, 5, 11, nil, false)
+	verify(5, 11, true, 5)
+	verify(0, 5, false, 5)
+
+	IndexLogs(chainDb, 0, 5, nil, false)
+	verify(0, 11, true, 0)
+
+	if entries := ReadLogIndexByAddress(chainDb, addresses[0], 3, 8); len(entries) != 3 || entries[0].Number != 4 || entries[2].Number != 8 {
+		t.Fatalf("Address log index range mismatch: %v", entries)
+	}
+	UnindexLogs(chainDb, 0, 5, nil, false)
+	verify(5, 11, true, 5)
+	verify(0, 5, false, 5)
+
+	UnindexLogs(chainDb, 5, 11, nil, false)
+	verify(0, 11, false, 11)
+}
@@ -192,8 +192,17 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return NewDatabaseWithRemoteFreezer(db, ancient, namespace, readonly, nil)
+}
+
+// NewDatabaseWithRemoteFreezer creates a high level database on top of a given
+// key-value data store with a freezer moving immutable chain segments into cold
+// storage. If the remote object store is set, the completed segments of the
+// freezer are further offloaded into it, only the recent ones are retained in
+// the ancient directory.
+func NewDatabaseWithRemoteFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool, remote ethdb.ObjectStore) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly)
+	frdb, err := newChainFreezer(resolveChainFreezerDir(ancient), namespace, readonly, remote)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
@@ -349,13 +358,15 @@ func PreexistingDatabase(path string) string {
 // OpenOptions contains the options to apply when opening a database.
 // OBS: If AncientsDirectory is empty, it indicates that no freezer is to be used.
 type OpenOptions struct {
-	Type              string // "leveldb" | "pebble"
-	Directory         string // the datadir
-	AncientsDirectory string // the ancients-dir
-	Namespace         string // the namespace for database relevant metrics
-	Cache             int    // the capacity(in megabytes) of the data caching
-	Handles           int    // number of files to be open simultaneously
+	Type              string            // "leveldb" | "pebble"
+	Directory         string            // the datadir
+	AncientsDirectory string            // the ancients-dir
+	AncientsRemote    ethdb.ObjectStore // the object store to offload the ancients into, if any
+	Namespace         string            // the namespace for database relevant metrics
+	Cache             int               // the capacity(in megabytes) of the data caching
+	Handles           int               // number of files to be open simultaneously
 	ReadOnly          bool
+	Keyspaces         bool // whether to split a new pebble database into tuned keyspaces
 	// Ephemeral means that filesystem sync operations should be avoided: data integrity in the face of
 	// a crash is not important. This option should typically be used in tests.
 	Ephemeral bool
@@ -380,15 +391,18 @@ func openKeyValueDatabase(o OpenOptions) (ethdb.Database, error) {
 	}
 	if o.Type == dbPebble || existingDb == dbPebble {
 		log.Info("Using pebble as the backing database")
-		return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Ephemeral)
+		return openPebbleDatabase(o)
 	}
 	if o.Type == dbLeveldb || existingDb == dbLeveldb {
+		if o.Keyspaces {
+			return nil, errors.New("keyspaces are only supported by pebble databases")
+		}
 		log.Info("Using leveldb as the backing database")
 		return NewLevelDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
 	}
 	// No pre-existing database, no user-requested one either. Default to Pebble.
 	log.Info("Defaulting to pebble as the backing database")
-	return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Ephemeral)
+	return openPebbleDatabase(o)
 }
 
 // Open opens both a disk-based key-value database such as leveldb or pebble, but also
@@ -404,7 +418,7 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if len(o.AncientsDirectory) == 0 {
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	frdb, err := NewDatabaseWithRemoteFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly, o.AncientsRemote)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -470,6 +484,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
+		stateIndex      stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
 
@@ -530,6 +546,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexAddressPrefix) && len(key) == (len(logIndexAddressPrefix)+common.AddressLength+8):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, logIndexTopicPrefix) && len(key) == (len(logIndexTopicPrefix)+common.HashLength+8):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, StateHistoryAccountIndexPrefix) && len(key) == (len(StateHistoryAccountIndexPrefix)+common.AddressLength+8):
+			stateIndex.Add(size)
+		case bytes.HasPrefix(key, StateHistoryStorageIndexPrefix) && len(key) == (len(StateHistoryStorageIndexPrefix)+common.AddressLength+common.HashLength+8):
+			stateIndex.Add(size)
 		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
@@ -547,7 +571,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			for _, meta := range [][]byte{
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey, logIndexTailKey, stateHistoryIndexHeadKey, onlinePruningKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 				persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
 			} {
@@ -577,6 +601,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
+		{"Key-Value store", "State history index", stateIndex.Size(), stateIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Hash trie nodes", legacyTries.Size(), legacyTries.Count()},
 		{"Key-Value store", "Path trie state lookups", stateLookups.Size(), stateLookups.Count()},
@@ -596,13 +622,17 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 	if err != nil {
 		return err
 	}
+	var expired uint64
 	for _, ancient := range ancients {
+		if ancient.name == ChainFreezerName {
+			expired = ancient.tail
+		}
 		for _, table := range ancient.sizes {
 			stats = append(stats, []string{
 				fmt.Sprintf("Ancient store (%s)", strings.Title(ancient.name)),
 				strings.Title(table.name),
 				table.size.String(),
-				fmt.Sprintf("%d", ancient.count()),
+				fmt.Sprintf("%d", ancient.items(table)),
 			})
 		}
 		total += ancient.size()
@@ -613,6 +643,9 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 	table.AppendBulk(stats)
 	table.Render()
 
+	if expired > 0 {
+		log.Info("Chain history expired from ancient store", "bodies", expired, "receipts", expired)
+	}
 	if unaccounted.size > 0 {
 		log.Error("Database contains unaccounted data", "size", unaccounted.size, "count", unaccounted.count)
 	}
@@ -650,6 +683,7 @@ func ReadChainMetadata(db ethdb.KeyValueStore) [][]string {
 		{"snapshotRecoveryNumber", pp(ReadSnapshotRecoveryNumber(db))},
 		{"snapshotRoot", fmt.Sprintf("%v", ReadSnapshotRoot(db))},
 		{"txIndexTail", pp(ReadTxIndexTail(db))},
+		{"logIndexTail", pp(ReadLogIndexTail(db))},
 	}
 	if b := ReadSkeletonSyncStatus(db); b != nil {
 		data = append(data, []string{"SkeletonSyncStatus", string(b)})
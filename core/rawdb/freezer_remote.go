@@ -0,0 +1,640 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/golang/snappy"
+)
+
+const (
+	// remoteSegmentItems is the number of items in a segment offloaded into the
+	// remote object store.
+	remoteSegmentItems = 8192
+
+	// remoteCacheSize is the maximum size of the recently read items retained
+	// in memory.
+	remoteCacheSize = 64 * 1024 * 1024
+
+	// remoteIndexCacheItems is the number of segment indexes retained in memory.
+	remoteIndexCacheItems = 256
+
+	// remoteMetaFile is the name of the file tracking the state of the remote
+	// store in the freezer directory.
+	remoteMetaFile = "REMOTE"
+)
+
+// errTruncateOffloaded is returned if the head truncation reaches into the
+// segments already offloaded into the remote store.
+var errTruncateOffloaded = errors.New("truncation into offloaded segments")
+
+// remoteMeta is the persisted state of the remote freezer.
+type remoteMeta struct {
+	Tail      uint64                       `json:"tail"`      // Logical tail of the prunable tables
+	Offloaded uint64                       `json:"offloaded"` // Number of items offloaded into the remote store
+	Sizes     map[string]map[uint64]uint64 `json:"sizes"`     // Bytes offloaded per table and segment
+}
+
+// remoteItemKey identifies an item cached from the remote store.
+type remoteItemKey struct {
+	kind   string
+	number uint64
+}
+
+// remoteIndex is the index of an offloaded segment, containing the number of
+// the first item and the offsets of all items in the segment data, followed by
+// the end offset of the last one.
+type remoteIndex struct {
+	first   uint64
+	offsets []uint64
+}
+
+// remoteFreezer is an ancient store which offloads the completed segments of
+// the local freezer into a remote object store, such as an S3-compatible
+// bucket. The recent items are kept in the local freezer, the offloaded ones
+// are dropped from its tail and served from the remote store, with a cache of
+// the recently read items in front of it.
+//
+// Each segment covers remoteSegmentItems items of a table and is stored as
+// two objects: <table>/<segment>.dat holding the concatenated items, snappy
+// compressed as in the local table, and <table>/<segment>.idx holding their
+// offsets. The index is uploaded last, its presence marks a complete segment.
+// Ranges of items are retrieved from the data object with range requests.
+//
+// The tail truncation requested by the user (e.g. chain history expiry) is
+// tracked separately from the offloading, as a logical tail hiding the items
+// of the prunable tables. The local freezer only truncates the prunable tables
+// on its own, the offloaded items are dropped from all tables once uploaded.
+type remoteFreezer struct {
+	*Freezer // Local freezer holding the recent items
+
+	remote   ethdb.ObjectStore
+	segment  uint64          // Number of items per segment
+	prunable map[string]bool // Tables subject to the logical tail truncation
+	metaPath string
+
+	// This lock synchronizes the reads with the truncation of the local tail
+	// and the remote segments.
+	lock    sync.RWMutex
+	meta    remoteMeta
+	heads   uint64 // Number of head truncations, for detecting those racing with an upload
+	cache   *lru.SizeConstrainedCache[remoteItemKey, []byte]
+	indexes *lru.Cache[string, *remoteIndex]
+
+	trigger chan struct{}
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newRemoteFreezer creates a freezer for the given tables, offloading its
+// completed segments into the remote store.
+func newRemoteFreezer(datadir string, namespace string, readonly bool, tables map[string]bool, prunable map[string]bool, remote ethdb.ObjectStore, segment uint64) (*remoteFreezer, error) {
+	// The local freezer retains the items of the non-prunable tables below its
+	// tail, they are dropped only when offloaded.
+	local, err := newFreezer(datadir, namespace, readonly, freezerTableSize, tables, prunable)
+	if err != nil {
+		return nil, err
+	}
+	f := &remoteFreezer{
+		Freezer:  local,
+		remote:   remote,
+		segment:  segment,
+		prunable: prunable,
+		metaPath: filepath.Join(datadir, remoteMetaFile),
+		meta:     remoteMeta{Sizes: make(map[string]map[uint64]uint64)},
+		cache:    lru.NewSizeConstrainedCache[remoteItemKey, []byte](remoteCacheSize),
+		indexes:  lru.NewCache[string, *remoteIndex](remoteIndexCacheItems),
+		trigger:  make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+	}
+	if blob, err := os.ReadFile(f.metaPath); err == nil {
+		if err := json.Unmarshal(blob, &f.meta); err != nil {
+			local.Close()
+			return nil, fmt.Errorf("invalid remote freezer metadata: %v", err)
+		}
+		if f.meta.Sizes == nil {
+			f.meta.Sizes = make(map[string]map[uint64]uint64)
+		}
+	} else if os.IsNotExist(err) {
+		// The local freezer is switched to remote, its items below the tail
+		// might have been pruned already.
+		f.meta.Tail, _ = local.Tail()
+	} else {
+		local.Close()
+		return nil, err
+	}
+	if !readonly {
+		f.wg.Add(1)
+		go f.loop()
+	}
+	log.Info("Opened remote ancient store", "database", datadir, "segment", segment)
+	return f, nil
+}
+
+// loop offloads the completed segments whenever new items are frozen.
+func (f *remoteFreezer) loop() {
+	defer f.wg.Done()
+
+	for {
+		if err := f.offload(); err != nil {
+			log.Error("Failed to offload ancient segments", "err", err)
+		}
+		select {
+		case <-f.trigger:
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// offload uploads all completed segments retained locally into the remote
+// store and drops them from the local freezer.
+func (f *remoteFreezer) offload() error {
+	for {
+		f.lock.RLock()
+		offloaded, heads := f.meta.Offloaded, f.heads
+		f.lock.RUnlock()
+
+		frozen, _ := f.Freezer.Ancients()
+		segment := offloaded / f.segment
+		end := (segment + 1) * f.segment
+		if end > frozen {
+			return nil
+		}
+		sizes := make(map[string]uint64)
+		for kind := range f.tables {
+			size, err := f.upload(kind, segment, offloaded, end)
+			if err != nil {
+				return err
+			}
+			sizes[kind] = size
+		}
+		// All tables of the segment are uploaded, drop them locally. If the head
+		// was truncated meanwhile, the uploaded items might be outdated, retry.
+		f.lock.Lock()
+		if f.heads != heads {
+			f.lock.Unlock()
+			continue
+		}
+		for kind, size := range sizes {
+			if f.meta.Sizes[kind] == nil {
+				f.meta.Sizes[kind] = make(map[uint64]uint64)
+			}
+			f.meta.Sizes[kind][segment] = size
+		}
+		f.meta.Offloaded = end
+		if err := f.writeMeta(); err != nil {
+			f.meta.Offloaded = offloaded
+			f.lock.Unlock()
+			return err
+		}
+		err := f.Freezer.truncateTailAll(end)
+		f.lock.Unlock()
+		if err != nil {
+			return err
+		}
+		log.Debug("Offloaded ancient segment", "segment", segment, "items", end-offloaded)
+	}
+}
+
+// upload uploads the items [start, end) of the given table as a segment,
+// returning the number of bytes uploaded.
+func (f *remoteFreezer) upload(kind string, segment, start, end uint64) (uint64, error) {
+	// Read the items while holding the lock, so that they are not truncated
+	// in the middle of the segment.
+	f.lock.RLock()
+	var (
+		data    []byte
+		offsets []byte
+		table   = f.tables[kind]
+	)
+	// Items hidden by the logical tail are never served, don't upload them
+	if f.prunable[kind] && f.meta.Tail > start {
+		start = min(f.meta.Tail, end)
+	}
+	offsets = make([]byte, 16, 16+(end-start+1)*8)
+	binary.BigEndian.PutUint64(offsets, start)
+	binary.BigEndian.PutUint64(offsets[8:], end-start)
+	for number := start; number < end; {
+		items, err := f.Freezer.AncientRange(kind, number, end-number, 0)
+		if err != nil {
+			f.lock.RUnlock()
+			return 0, err
+		}
+		for _, item := range items {
+			if !table.noCompression {
+				item = snappy.Encode(nil, item)
+			}
+			offsets = binary.BigEndian.AppendUint64(offsets, uint64(len(data)))
+			data = append(data, item...)
+		}
+		number += uint64(len(items))
+	}
+	f.lock.RUnlock()
+	offsets = binary.BigEndian.AppendUint64(offsets, uint64(len(data)))
+
+	name := remoteSegmentName(kind, segment)
+	if err := f.remote.Put(name+".dat", data); err != nil {
+		return 0, err
+	}
+	if err := f.remote.Put(name+".idx", offsets); err != nil {
+		return 0, err
+	}
+	return uint64(len(data) + len(offsets)), nil
+}
+
+// remoteSegmentName returns the object name of the segment of a table,
+// without extension.
+func remoteSegmentName(kind string, segment uint64) string {
+	return fmt.Sprintf("%s/%010d", kind, segment)
+}
+
+// writeMeta persists the state of the remote store. The caller must hold
+// the write lock.
+func (f *remoteFreezer) writeMeta() error {
+	blob, err := json.Marshal(f.meta)
+	if err != nil {
+		return err
+	}
+	tmp := f.metaPath + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.metaPath)
+}
+
+// segmentIndex retrieves the index of the given segment.
+func (f *remoteFreezer) segmentIndex(kind string, segment uint64) (*remoteIndex, error) {
+	name := remoteSegmentName(kind, segment)
+	if index, ok := f.indexes.Get(name); ok {
+		return index, nil
+	}
+	blob, err := f.remote.Get(name + ".idx")
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) < 16 {
+		return nil, fmt.Errorf("invalid segment index %s", name)
+	}
+	index := &remoteIndex{first: binary.BigEndian.Uint64(blob)}
+	count := binary.BigEndian.Uint64(blob[8:])
+	if uint64(len(blob)) != 16+(count+1)*8 {
+		return nil, fmt.Errorf("invalid segment index %s: %d items, %d bytes", name, count, len(blob))
+	}
+	for i := uint64(0); i <= count; i++ {
+		index.offsets = append(index.offsets, binary.BigEndian.Uint64(blob[16+i*8:]))
+	}
+	f.indexes.Add(name, index)
+	return index, nil
+}
+
+// retrieveRemote retrieves the items [start, start+count) of the given table
+// from the remote store, stopping at the segment boundary or if the items
+// exceed maxBytes, but returning at least one item.
+func (f *remoteFreezer) retrieveRemote(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	// Serve the leading items from the cache as long as possible
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for ; count > 0; count-- {
+		item, ok := f.cache.Get(remoteItemKey{kind, start})
+		if !ok {
+			break
+		}
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+			return items, nil
+		}
+		items, size = append(items, item), size+uint64(len(item))
+		start++
+	}
+	if count == 0 {
+		return items, nil
+	}
+	segment := start / f.segment
+	index, err := f.segmentIndex(kind, segment)
+	if err != nil {
+		return nil, err
+	}
+	if start < index.first || start-index.first >= uint64(len(index.offsets)-1) {
+		return nil, errOutOfBounds
+	}
+	// Retrieve the uncached items of the segment with a single range request
+	var (
+		first = start - index.first
+		last  = min(first+count, uint64(len(index.offsets)-1))
+	)
+	blob, err := f.remote.GetRange(remoteSegmentName(kind, segment)+".dat", index.offsets[first], index.offsets[last]-index.offsets[first])
+	if err != nil {
+		return nil, err
+	}
+	for i := first; i < last; i++ {
+		item := blob[index.offsets[i]-index.offsets[first] : index.offsets[i+1]-index.offsets[first]]
+		if !f.tables[kind].noCompression {
+			if item, err = snappy.Decode(nil, item); err != nil {
+				return nil, err
+			}
+		}
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+			break
+		}
+		f.cache.Add(remoteItemKey{kind, index.first + i}, item)
+		items, size = append(items, item), size+uint64(len(item))
+	}
+	return items, nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (f *remoteFreezer) HasAncient(kind string, number uint64) (bool, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.hasAncient(kind, number)
+}
+
+func (f *remoteFreezer) hasAncient(kind string, number uint64) (bool, error) {
+	if _, ok := f.tables[kind]; !ok {
+		return false, nil
+	}
+	if f.prunable[kind] && number < f.meta.Tail {
+		return false, nil
+	}
+	frozen, _ := f.Freezer.Ancients()
+	return number < frozen, nil
+}
+
+// Ancient retrieves an ancient binary blob, either from the local freezer or
+// from the remote store.
+func (f *remoteFreezer) Ancient(kind string, number uint64) ([]byte, error) {
+	items, err := f.AncientRange(kind, number, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'. The items are served from the local freezer and the remote store
+// according to where they are held.
+func (f *remoteFreezer) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.ancientRange(kind, start, count, maxBytes)
+}
+
+func (f *remoteFreezer) ancientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if _, ok := f.tables[kind]; !ok {
+		return nil, errUnknownTable
+	}
+	if f.prunable[kind] && start < f.meta.Tail {
+		return nil, errOutOfBounds
+	}
+	local := f.meta.Offloaded
+	if start >= local {
+		return f.Freezer.AncientRange(kind, start, count, maxBytes)
+	}
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for count > 0 && start < local {
+		var budget uint64
+		if maxBytes != 0 {
+			budget = maxBytes - size
+		}
+		batch, err := f.retrieveRemote(kind, start, min(count, local-start), budget)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range batch {
+			if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+				return items, nil
+			}
+			items, size = append(items, item), size+uint64(len(item))
+		}
+		start, count = start+uint64(len(batch)), count-uint64(len(batch))
+		if maxBytes != 0 && size >= maxBytes {
+			return items, nil
+		}
+	}
+	if count > 0 {
+		rest, err := f.Freezer.AncientRange(kind, start, count, 0)
+		if err != nil && len(items) == 0 {
+			return nil, err
+		}
+		for _, item := range rest {
+			if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+				break
+			}
+			items, size = append(items, item), size+uint64(len(item))
+		}
+	}
+	return items, nil
+}
+
+// Tail returns the logical tail of the prunable tables.
+func (f *remoteFreezer) Tail() (uint64, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.meta.Tail, nil
+}
+
+// AncientSize returns the ancient size of the specified category, including
+// the segments offloaded into the remote store.
+func (f *remoteFreezer) AncientSize(kind string) (uint64, error) {
+	size, err := f.Freezer.AncientSize(kind)
+	if err != nil {
+		return 0, err
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return size + f.remoteSize(kind), nil
+}
+
+// remoteSize returns the total size of the offloaded segments of a table. The
+// caller must hold the lock.
+func (f *remoteFreezer) remoteSize(kind string) uint64 {
+	var size uint64
+	for _, n := range f.meta.Sizes[kind] {
+		size += n
+	}
+	return size
+}
+
+// ReadAncients runs the given read operation while ensuring that no writes
+// take place on the local freezer and no segments are offloaded.
+func (f *remoteFreezer) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.Freezer.ReadAncients(func(ethdb.AncientReaderOp) error {
+		return fn(&remoteFreezerOp{f})
+	})
+}
+
+// ModifyAncients runs the given write operation on the local freezer and
+// schedules the offloading of the completed segments.
+func (f *remoteFreezer) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (int64, error) {
+	size, err := f.Freezer.ModifyAncients(fn)
+	if err == nil {
+		select {
+		case f.trigger <- struct{}{}:
+		default:
+		}
+	}
+	return size, err
+}
+
+// TruncateHead discards any recent data above the provided threshold number.
+// The offloaded segments are immutable, the truncation is rejected if it
+// reaches into them.
+func (f *remoteFreezer) TruncateHead(items uint64) (uint64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if items < f.meta.Offloaded {
+		return 0, fmt.Errorf("%w: target %d, offloaded %d", errTruncateOffloaded, items, f.meta.Offloaded)
+	}
+	f.heads++
+	return f.Freezer.TruncateHead(items)
+}
+
+// TruncateTail moves the logical tail of the prunable tables, deleting their
+// offloaded segments which are fully below it.
+func (f *remoteFreezer) TruncateTail(tail uint64) (uint64, error) {
+	if f.readonly {
+		return 0, errReadOnly
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	old := f.meta.Tail
+	if old >= tail {
+		return old, nil
+	}
+	// Forget the sizes of the remote segments which become unreachable, they
+	// are deleted below.
+	var (
+		offloaded = f.meta.Offloaded
+		deleted   []uint64
+	)
+	for segment := old / f.segment; (segment+1)*f.segment <= min(tail, offloaded); segment++ {
+		deleted = append(deleted, segment)
+	}
+	sizes := make(map[string]map[uint64]uint64)
+	for kind := range f.prunable {
+		sizes[kind] = maps.Clone(f.meta.Sizes[kind])
+		for _, segment := range deleted {
+			delete(f.meta.Sizes[kind], segment)
+		}
+	}
+	f.meta.Tail = tail
+	if err := f.writeMeta(); err != nil {
+		f.meta.Tail = old
+		for kind, segments := range sizes {
+			f.meta.Sizes[kind] = segments
+		}
+		return 0, err
+	}
+	// Delete the remote segments which became unreachable. It's best effort,
+	// a leftover segment is never served anyway.
+	for _, segment := range deleted {
+		for kind := range f.prunable {
+			name := remoteSegmentName(kind, segment)
+			if err := f.remote.Delete(name + ".dat"); err != nil {
+				log.Warn("Failed to delete offloaded segment", "name", name, "err", err)
+				continue
+			}
+			f.remote.Delete(name + ".idx")
+			f.indexes.Remove(name)
+		}
+	}
+	// Drop the hidden items retained locally too
+	frozen, _ := f.Freezer.Ancients()
+	if _, err := f.Freezer.TruncateTail(min(tail, frozen)); err != nil {
+		return 0, err
+	}
+	return old, nil
+}
+
+// MigrateTable is not supported, the offloaded segments can't be rewritten.
+func (f *remoteFreezer) MigrateTable(kind string, convert convertLegacyFn) error {
+	return errNotSupported
+}
+
+// Close stops the offloading and closes the local freezer.
+func (f *remoteFreezer) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+	return f.Freezer.Close()
+}
+
+// remoteFreezerOp is the reader passed to the ReadAncients callback, which
+// accesses the remote freezer without locking it again.
+type remoteFreezerOp struct {
+	f *remoteFreezer
+}
+
+func (op *remoteFreezerOp) HasAncient(kind string, number uint64) (bool, error) {
+	return op.f.hasAncient(kind, number)
+}
+
+func (op *remoteFreezerOp) Ancient(kind string, number uint64) ([]byte, error) {
+	items, err := op.f.ancientRange(kind, number, 1, 0)
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+func (op *remoteFreezerOp) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return op.f.ancientRange(kind, start, count, maxBytes)
+}
+
+func (op *remoteFreezerOp) Ancients() (uint64, error) {
+	return op.f.Freezer.Ancients()
+}
+
+func (op *remoteFreezerOp) Tail() (uint64, error) {
+	return op.f.meta.Tail, nil
+}
+
+func (op *remoteFreezerOp) AncientSize(kind string) (uint64, error) {
+	size, err := op.f.Freezer.AncientSize(kind)
+	if err != nil {
+		return 0, err
+	}
+	return size + op.f.remoteSize(kind), nil
+}
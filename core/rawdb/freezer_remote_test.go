@@ -0,0 +1,318 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// memoryObjectStore is an in-memory object store counting the requests.
+type memoryObjectStore struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	gets    int
+}
+
+func newMemoryObjectStore() *memoryObjectStore {
+	return &memoryObjectStore{objects: make(map[string][]byte)}
+}
+
+func (s *memoryObjectStore) Put(key string, data []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.objects[key] = bytes.Clone(data)
+	return nil
+}
+
+func (s *memoryObjectStore) Get(key string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.gets++
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return bytes.Clone(data), nil
+}
+
+func (s *memoryObjectStore) GetRange(key string, offset, length uint64) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.gets++
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	if offset+length > uint64(len(data)) {
+		return nil, errors.New("invalid range")
+	}
+	return bytes.Clone(data[offset : offset+length]), nil
+}
+
+func (s *memoryObjectStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memoryObjectStore) requests() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.gets
+}
+
+// waitOffloaded waits until the remote freezer offloaded the given number of
+// items.
+func waitOffloaded(t *testing.T, f *remoteFreezer, items uint64) {
+	t.Helper()
+
+	offloaded := func() uint64 {
+		f.lock.RLock()
+		defer f.lock.RUnlock()
+		return f.meta.Offloaded
+	}
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		if offloaded() == items {
+			return
+		}
+	}
+	t.Fatalf("offloaded items mismatch: have %d, want %d", offloaded(), items)
+}
+
+// remoteObjectsSize returns the total size of the objects of a table.
+func (s *memoryObjectStore) remoteObjectsSize(kind string) uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var size uint64
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, kind+"/") {
+			size += uint64(len(data))
+		}
+	}
+	return size
+}
+
+func TestRemoteFreezer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir      = t.TempDir()
+		remote   = newMemoryObjectStore()
+		tables   = map[string]bool{"compressed": false, "raw": true}
+		prunable = map[string]bool{"compressed": true}
+	)
+	f, err := newRemoteFreezer(dir, "", false, tables, prunable, remote, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 35; i++ {
+			if err := op.AppendRaw("compressed", uint64(i), getChunk(64, i)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("raw", uint64(i), getChunk(16, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The three complete segments should be offloaded, the rest retained
+	waitOffloaded(t, f, 30)
+	if len(remote.objects) != 12 {
+		t.Fatalf("remote object count mismatch: have %d, want 12", len(remote.objects))
+	}
+	if frozen, _ := f.Ancients(); frozen != 35 {
+		t.Fatalf("ancients mismatch: have %d, want 35", frozen)
+	}
+	for i := 0; i < 35; i++ {
+		for kind, size := range map[string]int{"compressed": 64, "raw": 16} {
+			blob, err := f.Ancient(kind, uint64(i))
+			if err != nil {
+				t.Fatalf("failed to read %s item %d: %v", kind, i, err)
+			}
+			if !bytes.Equal(blob, getChunk(size, i)) {
+				t.Fatalf("%s item %d mismatch", kind, i)
+			}
+		}
+	}
+	// The offloaded items should be served from the cache from now on
+	requests := remote.requests()
+	if _, err := f.Ancient("raw", 5); err != nil {
+		t.Fatal(err)
+	}
+	if remote.requests() != requests {
+		t.Fatalf("cached item retrieved remotely")
+	}
+	// Ranges should cross the segment and local boundaries, honoring the size limit
+	items, err := f.AncientRange("raw", 8, 25, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 25 {
+		t.Fatalf("range length mismatch: have %d, want 25", len(items))
+	}
+	for i, item := range items {
+		if !bytes.Equal(item, getChunk(16, 8+i)) {
+			t.Fatalf("range item %d mismatch", 8+i)
+		}
+	}
+	if items, err = f.AncientRange("raw", 8, 25, 40); err != nil || len(items) != 2 {
+		t.Fatalf("limited range mismatch: have %d items (%v), want 2", len(items), err)
+	}
+	// Truncating the tail should hide the prunable items and drop their segments
+	if _, err := f.TruncateTail(22); err != nil {
+		t.Fatal(err)
+	}
+	if tail, _ := f.Tail(); tail != 22 {
+		t.Fatalf("tail mismatch: have %d, want 22", tail)
+	}
+	if _, err := f.Ancient("compressed", 21); !errors.Is(err, errOutOfBounds) {
+		t.Fatalf("truncated item error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	if _, err := f.Ancient("raw", 0); err != nil {
+		t.Fatalf("failed to read non-prunable item: %v", err)
+	}
+	if _, ok := remote.objects["compressed/0000000001.dat"]; ok {
+		t.Fatal("truncated segment not deleted")
+	}
+	if _, ok := remote.objects["raw/0000000001.dat"]; !ok {
+		t.Fatal("non-prunable segment deleted")
+	}
+	// The head can't be truncated into the offloaded segments
+	if _, err := f.TruncateHead(25); !errors.Is(err, errTruncateOffloaded) {
+		t.Fatalf("head truncation error mismatch: have %v, want %v", err, errTruncateOffloaded)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen the freezer and check that the state is retained
+	f, err = newRemoteFreezer(dir, "", false, tables, prunable, remote, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if tail, _ := f.Tail(); tail != 22 {
+		t.Fatalf("reopened tail mismatch: have %d, want 22", tail)
+	}
+	blob, err := f.Ancient("compressed", 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blob, getChunk(64, 25)) {
+		t.Fatal("reopened item mismatch")
+	}
+	if size, _ := f.AncientSize("raw"); size < 30*16 {
+		t.Fatalf("ancient size too small: %d", size)
+	}
+}
+
+// Tests that switching a pruned local freezer to remote retains its tail and
+// offloads the items of the non-prunable tables below it.
+func TestRemoteFreezerPrunedSwitch(t *testing.T) {
+	t.Parallel()
+
+	var (
+		dir      = t.TempDir()
+		remote   = newMemoryObjectStore()
+		tables   = map[string]bool{"compressed": false, "raw": true}
+		prunable = map[string]bool{"compressed": true}
+	)
+	local, err := newFreezer(dir, "", false, freezerTableSize, tables, prunable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = local.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := 0; i < 35; i++ {
+			if err := op.AppendRaw("compressed", uint64(i), getChunk(64, i)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("raw", uint64(i), getChunk(16, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := local.TruncateTail(15); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := newRemoteFreezer(dir, "", false, tables, prunable, remote, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if tail, _ := f.Tail(); tail != 15 {
+		t.Fatalf("tail mismatch: have %d, want 15", tail)
+	}
+	waitOffloaded(t, f, 30)
+
+	if _, err := f.Ancient("compressed", 14); !errors.Is(err, errOutOfBounds) {
+		t.Fatalf("pruned item error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	for i := 0; i < 35; i++ {
+		blob, err := f.Ancient("raw", uint64(i))
+		if err != nil {
+			t.Fatalf("failed to read raw item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, getChunk(16, i)) {
+			t.Fatalf("raw item %d mismatch", i)
+		}
+		if i < 15 {
+			continue
+		}
+		if blob, err = f.Ancient("compressed", uint64(i)); err != nil {
+			t.Fatalf("failed to read compressed item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, getChunk(64, i)) {
+			t.Fatalf("compressed item %d mismatch", i)
+		}
+	}
+	// The offloaded sizes must match the remote objects exactly
+	for kind := range tables {
+		f.lock.RLock()
+		size := f.remoteSize(kind)
+		f.lock.RUnlock()
+		if want := remote.remoteObjectsSize(kind); size != want {
+			t.Fatalf("%s offloaded size mismatch: have %d, want %d", kind, size, want)
+		}
+	}
+}
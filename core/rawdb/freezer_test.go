@@ -480,3 +480,66 @@ func TestFreezerCloseSync(t *testing.T) {
 		t.Fatalf("want %v, have %v", have, want)
 	}
 }
+
+func TestFreezerPrunableTables(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		tables   = map[string]bool{"a": true, "b": true}
+		prunable = map[string]bool{"b": true}
+		item     = make([]byte, 1024)
+	)
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			require.NoError(t, op.AppendRaw("a", i, item))
+			require.NoError(t, op.AppendRaw("b", i, item))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Truncate the tail, only the prunable table should be affected
+	_, err = f.TruncateTail(5)
+	require.NoError(t, err)
+
+	check := func(f *Freezer) {
+		t.Helper()
+
+		if tail, _ := f.Tail(); tail != 5 {
+			t.Fatalf("unexpected tail, want 5, got %d", tail)
+		}
+		checkAncientCount(t, f, "a", 10)
+		for i := uint64(0); i < 10; i++ {
+			if _, err := f.Ancient("a", i); err != nil {
+				t.Fatalf("missing item %d in unprunable table: %v", i, err)
+			}
+			_, err := f.Ancient("b", i)
+			if i < 5 && err == nil {
+				t.Fatalf("unexpected item %d in prunable table", i)
+			}
+			if i >= 5 && err != nil {
+				t.Fatalf("missing item %d in prunable table: %v", i, err)
+			}
+		}
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopen the freezer, the differing tails must be retained
+	f, err = newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	f, err = newFreezer(dir, "", true, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen readonly freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+}
@@ -0,0 +1,291 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/keyspace"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The keyspaces the key-value database is split into, if enabled.
+const (
+	KeyspaceDefault  = "default"  // Metadata and everything not listed below
+	KeyspaceChain    = "chain"    // Headers, bodies, receipts and the chain indexes
+	KeyspaceState    = "state"    // Trie nodes and contract codes
+	KeyspaceSnapshot = "snapshot" // Flat state snapshot entries
+)
+
+// KeyspaceDirectory is the directory within the key-value database containing
+// the databases of the non-default keyspaces.
+const KeyspaceDirectory = "keyspaces"
+
+// keyspaceConfig is the tuning of a keyspace database.
+type keyspaceConfig struct {
+	share  int // Percentage of the cache and file handles allocated to the keyspace
+	config pebble.Config
+}
+
+// keyspaceConfigs contains the tuning of the keyspaces. The chain data is
+// mostly appended in order and read rarely, thus it's kept in larger files. The
+// trie nodes and snapshot entries are read randomly and are mostly hashes, so
+// they get the largest caches and skip the futile compression.
+var keyspaceConfigs = map[string]keyspaceConfig{
+	KeyspaceDefault:  {share: 10},
+	KeyspaceChain:    {share: 20, config: pebble.Config{TargetFileSize: 8 * 1024 * 1024}},
+	KeyspaceState:    {share: 45, config: pebble.Config{NoCompression: true}},
+	KeyspaceSnapshot: {share: 25, config: pebble.Config{TargetFileSize: 4 * 1024 * 1024, NoCompression: true}},
+}
+
+// KeyspaceOf returns the keyspace storing the given database key. The key
+// lengths are checked along with the prefixes, as some unrelated keys share
+// the first bytes with the listed ones.
+func KeyspaceOf(key []byte) string {
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength+len(headerTDSuffix)) && bytes.HasSuffix(key, headerTDSuffix):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+len(headerHashSuffix)) && bytes.HasSuffix(key, headerHashSuffix):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, logIndexAddressPrefix) && len(key) == (len(logIndexAddressPrefix)+common.AddressLength+8):
+		return KeyspaceChain
+	case bytes.HasPrefix(key, logIndexTopicPrefix) && len(key) == (len(logIndexTopicPrefix)+common.HashLength+8):
+		return KeyspaceChain
+	case IsAccountTrieNode(key), IsStorageTrieNode(key), len(key) == common.HashLength:
+		return KeyspaceState
+	case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
+		return KeyspaceState
+	case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
+		return KeyspaceSnapshot
+	case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
+		return KeyspaceSnapshot
+	default:
+		return KeyspaceDefault
+	}
+}
+
+// keyspaceAllowance returns the cache and file handle allowance of a keyspace.
+func keyspaceAllowance(name string, cache, handles int) (int, int) {
+	share := keyspaceConfigs[name].share
+	return cache * share / 100, handles * share / 100
+}
+
+// openPebbleKeyspaces opens a pebble database split into keyspaces. The default
+// keyspace resides in the directory itself, the others in the subdirectories of
+// the keyspace directory.
+func openPebbleKeyspaces(o OpenOptions) (ethdb.KeyValueStore, error) {
+	stores := make(map[string]ethdb.KeyValueStore)
+	closeAll := func() {
+		for _, store := range stores {
+			store.Close()
+		}
+	}
+	for name, ks := range keyspaceConfigs {
+		var (
+			dir    = o.Directory
+			config = ks.config
+		)
+		if name != KeyspaceDefault {
+			dir = filepath.Join(o.Directory, KeyspaceDirectory, name)
+		}
+		config.Cache, config.Handles = keyspaceAllowance(name, o.Cache, o.Handles)
+
+		db, err := pebble.NewWithConfig(dir, config, o.Namespace+name+"/", o.ReadOnly, o.Ephemeral)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("keyspace %s: %w", name, err)
+		}
+		stores[name] = db
+	}
+	db, err := keyspace.New(stores, KeyspaceDefault, KeyspaceOf)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return db, nil
+}
+
+// openPebbleDatabase opens a pebble database, split into keyspaces if it was
+// migrated or if a new database with keyspaces is requested.
+func openPebbleDatabase(o OpenOptions) (ethdb.Database, error) {
+	if common.FileExist(filepath.Join(o.Directory, KeyspaceDirectory)) {
+		log.Info("Using pebble with keyspaces as the backing database")
+		db, err := openPebbleKeyspaces(o)
+		if err != nil {
+			return nil, err
+		}
+		return NewDatabase(db), nil
+	}
+	if o.Keyspaces {
+		if PreexistingDatabase(o.Directory) != "" {
+			log.Warn("Database without keyspaces, run 'geth db migrate-keyspaces' to split it")
+		} else if !o.ReadOnly {
+			if err := os.MkdirAll(filepath.Join(o.Directory, KeyspaceDirectory), 0755); err != nil {
+				return nil, err
+			}
+			return openPebbleDatabase(o)
+		}
+	}
+	return NewPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly, o.Ephemeral)
+}
+
+// MigrateKeyspaces splits an existing pebble database into keyspaces. The
+// entries are first copied into the keyspace databases, which are activated
+// atomically by renaming their directory, then deleted from the original one.
+// An interrupted migration is resumed by calling it again.
+func MigrateKeyspaces(directory string, cache, handles int, namespace string) error {
+	if PreexistingDatabase(directory) != dbPebble {
+		return errors.New("keyspaces are only supported by pebble databases")
+	}
+	cache, handles = keyspaceAllowance(KeyspaceDefault, cache, handles)
+	db, err := pebble.New(directory, cache, handles, namespace, false, false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	final := filepath.Join(directory, KeyspaceDirectory)
+	if !common.FileExist(final) {
+		temp := final + ".tmp"
+		if err := os.RemoveAll(temp); err != nil {
+			return err
+		}
+		if err := copyKeyspaces(db, temp, cache, handles, namespace); err != nil {
+			return err
+		}
+		if err := os.Rename(temp, final); err != nil {
+			return err
+		}
+	}
+	// The keyspaces are in use, drop the migrated entries from the original
+	var (
+		it      = db.NewIterator(nil, nil)
+		batch   = db.NewBatch()
+		deleted int
+		start   = time.Now()
+		logged  = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		if KeyspaceOf(it.Key()) == KeyspaceDefault {
+			continue
+		}
+		batch.Delete(it.Key())
+		deleted++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Deleting migrated entries", "deleted", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Deleted migrated entries", "deleted", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+	return db.Compact(nil, nil)
+}
+
+// copyKeyspaces copies the entries of the non-default keyspaces from the given
+// database into new keyspace databases created in the given directory.
+func copyKeyspaces(db ethdb.KeyValueStore, directory string, cache, handles int, namespace string) error {
+	var (
+		stores  = make(map[string]ethdb.KeyValueStore)
+		batches = make(map[string]ethdb.Batch)
+	)
+	defer func() {
+		for _, store := range stores {
+			store.Close()
+		}
+	}()
+	for name, ks := range keyspaceConfigs {
+		if name == KeyspaceDefault {
+			continue
+		}
+		config := ks.config
+		config.Cache, config.Handles = keyspaceAllowance(name, cache, handles)
+
+		store, err := pebble.NewWithConfig(filepath.Join(directory, name), config, namespace+name+"/", false, false)
+		if err != nil {
+			return err
+		}
+		stores[name], batches[name] = store, store.NewBatch()
+	}
+	var (
+		it     = db.NewIterator(nil, nil)
+		copied = make(map[string]int)
+		start  = time.Now()
+		logged = time.Now()
+	)
+	defer it.Release()
+
+	for it.Next() {
+		name := KeyspaceOf(it.Key())
+		if name == KeyspaceDefault {
+			continue
+		}
+		batch := batches[name]
+		batch.Put(it.Key(), it.Value())
+		copied[name]++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Migrating keyspaces", "at", fmt.Sprintf("%x", it.Key()), "chain", copied[KeyspaceChain], "state", copied[KeyspaceState], "snapshot", copied[KeyspaceSnapshot], "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	log.Info("Migrated keyspaces", "chain", copied[KeyspaceChain], "state", copied[KeyspaceState], "snapshot", copied[KeyspaceSnapshot], "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
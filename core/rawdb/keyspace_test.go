@@ -0,0 +1,137 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+)
+
+func TestKeyspaceOf(t *testing.T) {
+	hash := common.Hash{0x1}
+	tests := []struct {
+		key  []byte
+		want string
+	}{
+		{headerKey(1, hash), KeyspaceChain},
+		{headerTDKey(1, hash), KeyspaceChain},
+		{headerHashKey(1), KeyspaceChain},
+		{headerNumberKey(hash), KeyspaceChain},
+		{blockBodyKey(1, hash), KeyspaceChain},
+		{blockReceiptsKey(1, hash), KeyspaceChain},
+		{txLookupKey(hash), KeyspaceChain},
+		{accountTrieNodeKey([]byte{0x1, 0x2}), KeyspaceState},
+		{storageTrieNodeKey(hash, nil), KeyspaceState},
+		{hash.Bytes(), KeyspaceState},
+		{codeKey(hash), KeyspaceState},
+		{accountSnapshotKey(hash), KeyspaceSnapshot},
+		{storageSnapshotKey(hash, hash), KeyspaceSnapshot},
+		{headHeaderKey, KeyspaceDefault},
+		{stateIDKey(hash), KeyspaceDefault},
+		{skeletonHeaderKey(1), KeyspaceDefault},
+		{SnapshotRootKey, KeyspaceDefault},
+		{append(ChtPrefix, make([]byte, 8)...), KeyspaceDefault}, // Shares the code prefix
+		{append(BloomTriePrefix, make([]byte, 8)...), KeyspaceDefault},
+	}
+	for i, tt := range tests {
+		if have := KeyspaceOf(tt.key); have != tt.want {
+			t.Errorf("test %d: keyspace of %x mismatch: have %s, want %s", i, tt.key, have, tt.want)
+		}
+	}
+}
+
+func TestMigrateKeyspaces(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewPebbleDBDatabase(dir, 16, 16, "", false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		hash    = common.Hash{0x1}
+		entries = map[string][]byte{
+			string(headerKey(1, hash)):             {0x1},
+			string(accountTrieNodeKey(nil)):        {0x2},
+			string(accountSnapshotKey(hash)):       {0x3},
+			string(headHeaderKey):                  hash.Bytes(),
+			string(codeKey(hash)):                  {0x4},
+			string(storageSnapshotKey(hash, hash)): {0x5},
+		}
+	)
+	for key, value := range entries {
+		if err := db.Put([]byte(key), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	if err := MigrateKeyspaces(dir, 16, 16, ""); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	// The entries should be moved into their keyspace databases
+	for _, name := range []string{KeyspaceDefault, KeyspaceChain, KeyspaceState, KeyspaceSnapshot} {
+		path := dir
+		if name != KeyspaceDefault {
+			path = filepath.Join(dir, KeyspaceDirectory, name)
+		}
+		store, err := pebble.New(path, 16, 16, "", true, true)
+		if err != nil {
+			t.Fatalf("failed to open keyspace %s: %v", name, err)
+		}
+		for key := range entries {
+			has, _ := store.Has([]byte(key))
+			if want := KeyspaceOf([]byte(key)) == name; has != want {
+				t.Errorf("presence of %x in keyspace %s mismatch: have %v, want %v", key, name, has, want)
+			}
+		}
+		store.Close()
+	}
+	// The database should be opened with the keyspaces, serving all entries
+	db, err = Open(OpenOptions{Directory: dir, Cache: 16, Handles: 16, Ephemeral: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for key, value := range entries {
+		have, err := db.Get([]byte(key))
+		if err != nil || !bytes.Equal(have, value) {
+			t.Errorf("entry %x mismatch: have %x (%v), want %x", key, have, err, value)
+		}
+	}
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	var (
+		count int
+		last  []byte
+	)
+	for it.Next() {
+		if last != nil && bytes.Compare(last, it.Key()) >= 0 {
+			t.Fatalf("iteration out of order: %x after %x", it.Key(), last)
+		}
+		last = common.CopyBytes(it.Key())
+		count++
+	}
+	if count != len(entries) {
+		t.Fatalf("iterated entry count mismatch: have %d, want %d", count, len(entries))
+	}
+}
@@ -67,6 +67,9 @@ var (
 	// snapshotRecoveryKey tracks the snapshot recovery marker across restarts.
 	snapshotRecoveryKey = []byte("SnapshotRecovery")
 
+	// onlinePruningKey tracks the online state pruning progress across restarts.
+	onlinePruningKey = []byte("OnlinePruning")
+
 	// snapshotSyncStatusKey tracks the snapshot sync status across restarts.
 	snapshotSyncStatusKey = []byte("SnapshotSyncStatus")
 
@@ -79,6 +82,17 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// logIndexTailKey tracks the oldest block whose logs have been indexed.
+	logIndexTailKey = []byte("LogIndexTail")
+
+	// chainHistoryGapKey tracks the first block whose body and receipts are
+	// available if the chain was seeded without the history below it.
+	chainHistoryGapKey = []byte("ChainHistoryGap")
+
+	// stateHistoryIndexHeadKey tracks the id of the latest state history whose
+	// mutated accounts and storage slots have been indexed.
+	stateHistoryIndexHeadKey = []byte("LastStateHistoryIndex")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	// This flag is deprecated, it's kept to avoid reporting errors when inspect
 	// database.
@@ -106,6 +120,8 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
+	logIndexAddressPrefix = []byte("x") // logIndexAddressPrefix + address + num (uint64 big endian) -> log positions
+	logIndexTopicPrefix   = []byte("X") // logIndexTopicPrefix + topic + num (uint64 big endian) -> log positions
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
@@ -117,6 +133,10 @@ var (
 	TrieNodeStoragePrefix = []byte("O") // TrieNodeStoragePrefix + accountHash + hexPath -> trie node
 	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
 
+	// Path-based state history indexes, referencing the histories mutating an account or storage slot.
+	StateHistoryAccountIndexPrefix = []byte("m") // StateHistoryAccountIndexPrefix + address + id (uint64 big endian) -> nil
+	StateHistoryStorageIndexPrefix = []byte("M") // StateHistoryStorageIndexPrefix + address + slot hash + id (uint64 big endian) -> nil
+
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
 	genesisPrefix  = []byte("ethereum-genesis-") // genesis state prefix for the db
@@ -137,6 +157,10 @@ var (
 	BestUpdateKey         = []byte("update-")    // bigEndian64(syncPeriod) -> RLP(types.LightClientUpdate)  (nextCommittee only referenced by root hash)
 	FixedCommitteeRootKey = []byte("fixedRoot-") // bigEndian64(syncPeriod) -> committee root hash
 	SyncCommitteeKey      = []byte("committee-") // bigEndian64(syncPeriod) -> serialized committee
+	FinalizedBeaconKey    = []byte("finalized")  // RLP(types.Header) of the latest validated finalized beacon header
+
+	LightServerUpdateKey    = []byte("lsUpdate-")    // bigEndian64(syncPeriod) -> JSON encoded committee update served to light clients
+	LightServerBootstrapKey = []byte("lsBootstrap-") // bigEndian64(slot) + block root -> JSON encoded bootstrap data served to light clients
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -216,6 +240,16 @@ func storageSnapshotsKey(accountHash common.Hash) []byte {
 	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
 }
 
+// logIndexAddressKey = logIndexAddressPrefix + address + num (uint64 big endian)
+func logIndexAddressKey(address common.Address, number uint64) []byte {
+	return append(append(append([]byte{}, logIndexAddressPrefix...), address.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// logIndexTopicKey = logIndexTopicPrefix + topic + num (uint64 big endian)
+func logIndexTopicKey(topic common.Hash, number uint64) []byte {
+	return append(append(append([]byte{}, logIndexTopicPrefix...), topic.Bytes()...), encodeBlockNumber(number)...)
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -265,6 +299,25 @@ func stateIDKey(root common.Hash) []byte {
 	return append(stateIDPrefix, root.Bytes()...)
 }
 
+// stateHistoryAccountIndexKey = StateHistoryAccountIndexPrefix + address + id (uint64 big endian)
+func stateHistoryAccountIndexKey(address common.Address, id uint64) []byte {
+	key := make([]byte, len(StateHistoryAccountIndexPrefix)+common.AddressLength+8)
+	copy(key, StateHistoryAccountIndexPrefix)
+	copy(key[len(StateHistoryAccountIndexPrefix):], address.Bytes())
+	binary.BigEndian.PutUint64(key[len(StateHistoryAccountIndexPrefix)+common.AddressLength:], id)
+	return key
+}
+
+// stateHistoryStorageIndexKey = StateHistoryStorageIndexPrefix + address + slot hash + id (uint64 big endian)
+func stateHistoryStorageIndexKey(address common.Address, slot common.Hash, id uint64) []byte {
+	key := make([]byte, len(StateHistoryStorageIndexPrefix)+common.AddressLength+common.HashLength+8)
+	copy(key, StateHistoryStorageIndexPrefix)
+	copy(key[len(StateHistoryStorageIndexPrefix):], address.Bytes())
+	copy(key[len(StateHistoryStorageIndexPrefix)+common.AddressLength:], slot.Bytes())
+	binary.BigEndian.PutUint64(key[len(StateHistoryStorageIndexPrefix)+common.AddressLength+common.HashLength:], id)
+	return key
+}
+
 // accountTrieNodeKey = TrieNodeAccountPrefix + nodePath.
 func accountTrieNodeKey(path []byte) []byte {
 	return append(TrieNodeAccountPrefix, path...)
@@ -170,11 +170,32 @@ func NewDatabaseWithNodeDB(db ethdb.Database, triedb *triedb.Database) Database
 	}
 }
 
+// NewPartialDatabase creates a state database with an already initialized node
+// database, in which only the storage of the tracked contracts is available.
+//
+// If a resolver is given, the storage of the untracked contracts is resolved
+// via it instead of being rejected, which is meant for block execution only.
+func NewPartialDatabase(db ethdb.Database, triedb *triedb.Database, partial *PartialState, resolver NodeResolver) Database {
+	cdb := &cachingDB{
+		disk:          db,
+		codeSizeCache: lru.NewCache[common.Hash, int](codeSizeCacheSize),
+		codeCache:     lru.NewSizeConstrainedCache[common.Hash, []byte](codeCacheSize),
+		triedb:        triedb,
+		partial:       partial,
+	}
+	if resolver != nil {
+		cdb.remote = newRemoteNodeDatabase(triedb, resolver)
+	}
+	return cdb
+}
+
 type cachingDB struct {
 	disk          ethdb.KeyValueStore
 	codeSizeCache *lru.Cache[common.Hash, int]
 	codeCache     *lru.SizeConstrainedCache[common.Hash, []byte]
 	triedb        *triedb.Database
+	partial       *PartialState       // The set of contracts whose storage is available, nil for all
+	remote        *remoteNodeDatabase // Resolver of the untracked storage, nil if rejected
 }
 
 // OpenTrie opens the main account trie at a specific root hash.
@@ -184,6 +205,11 @@ func (db *cachingDB) OpenTrie(root common.Hash) (Trie, error) {
 	}
 	tr, err := trie.NewStateTrie(trie.StateTrieID(root), db.triedb)
 	if err != nil {
+		// The state is not available in the trie database, try to resolve
+		// it via the indexed state histories if supported.
+		if reader, herr := db.triedb.HistoricReader(root); herr == nil {
+			return newHistoricTrie(root, reader, db.triedb), nil
+		}
 		return nil, err
 	}
 	return tr, nil
@@ -197,6 +223,18 @@ func (db *cachingDB) OpenStorageTrie(stateRoot common.Hash, address common.Addre
 	if db.triedb.IsVerkle() {
 		return self, nil
 	}
+	// The storage of the untracked contracts is not available in the partial
+	// state. Resolve it from the network if allowed, otherwise reject the access
+	// explicitly instead of reporting a missing node.
+	if root != types.EmptyRootHash && !db.partial.Tracked(address) {
+		if db.remote == nil {
+			return nil, fmt.Errorf("%w: storage of %x", ErrStateNotTracked, address)
+		}
+		return trie.NewStateTrie(trie.StorageTrieID(stateRoot, crypto.Keccak256Hash(address.Bytes()), root), db.remote)
+	}
+	if self, ok := self.(*historicTrie); ok {
+		return self.storageTrie(address, root), nil
+	}
 	tr, err := trie.NewStateTrie(trie.StorageTrieID(stateRoot, crypto.Keccak256Hash(address.Bytes()), root), db.triedb)
 	if err != nil {
 		return nil, err
@@ -211,6 +249,10 @@ func (db *cachingDB) CopyTrie(t Trie) Trie {
 		return t.Copy()
 	case *trie.VerkleTrie:
 		return t.Copy()
+	case *historicTrie:
+		return t.copy()
+	case *historicStorageTrie:
+		return t.copy()
 	default:
 		panic(fmt.Errorf("unknown trie type %T", t))
 	}
@@ -0,0 +1,323 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// errHistoricTrieReadOnly is returned if an operation requiring the trie nodes,
+// such as committing, iterating or proving, is attempted on a historic trie.
+var errHistoricTrieReadOnly = errors.New("historical state is read-only")
+
+// historicTrie is the account trie of a historical state which is no longer
+// available in the trie database, resolved via the indexed state histories of
+// the path-based scheme. The accounts unchanged since the historical state are
+// read from the trie of the persistent disk state.
+//
+// Mutations are kept in memory only, allowing transactions to be executed on
+// top of the historical state, but the root hash is never recomputed.
+type historicTrie struct {
+	root     common.Hash
+	reader   *pathdb.HistoricalStateReader
+	triedb   *triedb.Database
+	disk     *trie.StateTrie                        // Account trie of the disk state, opened lazily
+	accounts map[common.Address]*types.StateAccount // Mutated accounts, nil means deleted
+}
+
+// newHistoricTrie constructs the account trie of the given historical state.
+func newHistoricTrie(root common.Hash, reader *pathdb.HistoricalStateReader, db *triedb.Database) *historicTrie {
+	return &historicTrie{
+		root:     root,
+		reader:   reader,
+		triedb:   db,
+		accounts: make(map[common.Address]*types.StateAccount),
+	}
+}
+
+// diskTrie returns the account trie of the disk state.
+func (t *historicTrie) diskTrie() (*trie.StateTrie, error) {
+	if t.disk == nil {
+		tr, err := trie.NewStateTrie(trie.StateTrieID(t.reader.DiskRoot()), t.triedb)
+		if err != nil {
+			return nil, err
+		}
+		t.disk = tr
+	}
+	return t.disk, nil
+}
+
+// GetKey returns the sha3 preimage of a hashed key.
+func (t *historicTrie) GetKey(key []byte) []byte {
+	return t.triedb.Preimage(common.BytesToHash(key))
+}
+
+// GetAccount retrieves the account at the historical state.
+func (t *historicTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	if account, ok := t.accounts[address]; ok {
+		return account, nil
+	}
+	blob, found, err := t.reader.Account(address)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		tr, err := t.diskTrie()
+		if err != nil {
+			return nil, err
+		}
+		return tr.GetAccount(address)
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	return types.FullAccount(blob)
+}
+
+// GetStorage is not supported by the account trie.
+func (t *historicTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	return nil, errors.New("storage is not available in account trie")
+}
+
+// UpdateAccount records the account mutation in memory.
+func (t *historicTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	t.accounts[address] = account.Copy()
+	return nil
+}
+
+// UpdateStorage is not supported by the account trie.
+func (t *historicTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	return errors.New("storage is not available in account trie")
+}
+
+// DeleteAccount records the account deletion in memory.
+func (t *historicTrie) DeleteAccount(address common.Address) error {
+	t.accounts[address] = nil
+	return nil
+}
+
+// DeleteStorage is not supported by the account trie.
+func (t *historicTrie) DeleteStorage(addr common.Address, key []byte) error {
+	return errors.New("storage is not available in account trie")
+}
+
+// UpdateContractCode is a noop, the code is stored separately.
+func (t *historicTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash returns the root hash of the historical state, the in-memory mutations
+// are not reflected.
+func (t *historicTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit is not supported by the historic trie.
+func (t *historicTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errHistoricTrieReadOnly
+}
+
+// NodeIterator is not supported by the historic trie.
+func (t *historicTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errHistoricTrieReadOnly
+}
+
+// Prove is not supported by the historic trie.
+func (t *historicTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errHistoricTrieReadOnly
+}
+
+// storageTrie constructs the storage trie of the given account at the
+// historical state.
+func (t *historicTrie) storageTrie(address common.Address, root common.Hash) *historicStorageTrie {
+	return &historicStorageTrie{
+		address: address,
+		root:    root,
+		reader:  t.reader,
+		triedb:  t.triedb,
+		slots:   make(map[string][]byte),
+	}
+}
+
+// copy returns an independent copy of the historic trie.
+func (t *historicTrie) copy() *historicTrie {
+	cpy := &historicTrie{
+		root:     t.root,
+		reader:   t.reader,
+		triedb:   t.triedb,
+		accounts: make(map[common.Address]*types.StateAccount, len(t.accounts)),
+	}
+	if t.disk != nil {
+		cpy.disk = t.disk.Copy()
+	}
+	for address, account := range t.accounts {
+		if account != nil {
+			account = account.Copy()
+		}
+		cpy.accounts[address] = account
+	}
+	return cpy
+}
+
+// historicStorageTrie is the storage trie of an account at a historical state,
+// the counterpart of historicTrie.
+type historicStorageTrie struct {
+	address common.Address
+	root    common.Hash // Storage root of the account at the historical state
+	reader  *pathdb.HistoricalStateReader
+	triedb  *triedb.Database
+	disk    *trie.StateTrie   // Storage trie of the account in the disk state, opened lazily
+	opened  bool              // Whether the disk storage trie was resolved, nil if empty
+	slots   map[string][]byte // Mutated storage slots, nil means deleted
+}
+
+// diskTrie returns the storage trie of the account in the disk state, or nil
+// if the account has no storage there.
+func (t *historicStorageTrie) diskTrie() (*trie.StateTrie, error) {
+	if t.opened {
+		return t.disk, nil
+	}
+	tr, err := trie.NewStateTrie(trie.StateTrieID(t.reader.DiskRoot()), t.triedb)
+	if err != nil {
+		return nil, err
+	}
+	account, err := tr.GetAccount(t.address)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil && account.Root != types.EmptyRootHash {
+		id := trie.StorageTrieID(t.reader.DiskRoot(), crypto.Keccak256Hash(t.address.Bytes()), account.Root)
+		if t.disk, err = trie.NewStateTrie(id, t.triedb); err != nil {
+			return nil, err
+		}
+	}
+	t.opened = true
+	return t.disk, nil
+}
+
+// GetKey returns the sha3 preimage of a hashed key.
+func (t *historicStorageTrie) GetKey(key []byte) []byte {
+	return t.triedb.Preimage(common.BytesToHash(key))
+}
+
+// GetAccount is not supported by the storage trie.
+func (t *historicStorageTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	return nil, errors.New("account is not available in storage trie")
+}
+
+// GetStorage retrieves the storage slot at the historical state.
+func (t *historicStorageTrie) GetStorage(addr common.Address, key []byte) ([]byte, error) {
+	if value, ok := t.slots[string(key)]; ok {
+		return value, nil
+	}
+	if t.root == types.EmptyRootHash {
+		return nil, nil
+	}
+	blob, found, err := t.reader.Storage(t.address, crypto.Keccak256Hash(key))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		tr, err := t.diskTrie()
+		if err != nil || tr == nil {
+			return nil, err
+		}
+		return tr.GetStorage(addr, key)
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	_, content, _, err := rlp.Split(blob)
+	return content, err
+}
+
+// UpdateAccount is not supported by the storage trie.
+func (t *historicStorageTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	return errors.New("account is not available in storage trie")
+}
+
+// UpdateStorage records the storage mutation in memory.
+func (t *historicStorageTrie) UpdateStorage(addr common.Address, key, value []byte) error {
+	t.slots[string(key)] = common.CopyBytes(value)
+	return nil
+}
+
+// DeleteAccount is not supported by the storage trie.
+func (t *historicStorageTrie) DeleteAccount(address common.Address) error {
+	return errors.New("account is not available in storage trie")
+}
+
+// DeleteStorage records the storage deletion in memory.
+func (t *historicStorageTrie) DeleteStorage(addr common.Address, key []byte) error {
+	t.slots[string(key)] = nil
+	return nil
+}
+
+// UpdateContractCode is a noop, the code is stored separately.
+func (t *historicStorageTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash returns the storage root of the account at the historical state, the
+// in-memory mutations are not reflected.
+func (t *historicStorageTrie) Hash() common.Hash {
+	return t.root
+}
+
+// Commit is not supported by the historic trie.
+func (t *historicStorageTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet, error) {
+	return common.Hash{}, nil, errHistoricTrieReadOnly
+}
+
+// NodeIterator is not supported by the historic trie.
+func (t *historicStorageTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errHistoricTrieReadOnly
+}
+
+// Prove is not supported by the historic trie.
+func (t *historicStorageTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errHistoricTrieReadOnly
+}
+
+// copy returns an independent copy of the historic storage trie.
+func (t *historicStorageTrie) copy() *historicStorageTrie {
+	cpy := &historicStorageTrie{
+		address: t.address,
+		root:    t.root,
+		reader:  t.reader,
+		triedb:  t.triedb,
+		opened:  t.opened,
+		slots:   make(map[string][]byte, len(t.slots)),
+	}
+	if t.disk != nil {
+		cpy.disk = t.disk.Copy()
+	}
+	for key, value := range t.slots {
+		cpy.slots[key] = common.CopyBytes(value)
+	}
+	return cpy
+}
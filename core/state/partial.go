@@ -0,0 +1,170 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+// remoteNodeCacheSize is the memory allowance of the trie nodes resolved from
+// the network, shared by all the states.
+const remoteNodeCacheSize = 32 * 1024 * 1024
+
+var (
+	// ErrStateNotTracked is returned if the storage of an account which is not
+	// tracked by a partial state node is accessed.
+	ErrStateNotTracked = errors.New("state not tracked")
+
+	// ErrStateUnavailable is returned if the storage of an account which is not
+	// tracked by a partial state node can't be resolved from the network while
+	// executing a block.
+	ErrStateUnavailable = errors.New("untracked state unavailable")
+)
+
+// NodeResolver retrieves a trie node of a recent state from a remote source,
+// such as the peers of a partial state node. The owner and path identify the
+// node the same way as in the trie database.
+type NodeResolver func(stateRoot common.Hash, owner common.Hash, path []byte, hash common.Hash) ([]byte, error)
+
+// PartialState defines the set of contracts whose storage is retained by a
+// partial state node. The account trie is always retained in full, so that
+// the state root can be verified and the account fields are available for
+// all the accounts, but the storage tries of the untracked contracts are
+// neither synced nor stored.
+//
+// Blocks are still executed on top of the partial state: the storage trie
+// nodes of the untracked contracts touched by a block are resolved from the
+// network, verified against their hashes and discarded after execution.
+//
+// A nil PartialState tracks the entire state.
+type PartialState struct {
+	addresses []common.Address
+	tracked   map[common.Hash]struct{} // Hashes of the tracked addresses
+}
+
+// NewPartialState constructs the partial state definition tracking the storage
+// of the given contracts.
+func NewPartialState(addresses []common.Address) *PartialState {
+	tracked := make(map[common.Hash]struct{}, len(addresses))
+	for _, address := range addresses {
+		tracked[crypto.Keccak256Hash(address.Bytes())] = struct{}{}
+	}
+	return &PartialState{
+		addresses: addresses,
+		tracked:   tracked,
+	}
+}
+
+// Addresses returns the addresses of the tracked contracts.
+func (p *PartialState) Addresses() []common.Address {
+	if p == nil {
+		return nil
+	}
+	return p.addresses
+}
+
+// Tracked reports whether the storage of the given account is retained.
+func (p *PartialState) Tracked(address common.Address) bool {
+	if p == nil {
+		return true
+	}
+	return p.TrackedHash(crypto.Keccak256Hash(address.Bytes()))
+}
+
+// TrackedHash reports whether the storage of the account specified by the
+// address hash is retained.
+func (p *PartialState) TrackedHash(addrHash common.Hash) bool {
+	if p == nil {
+		return true
+	}
+	_, ok := p.tracked[addrHash]
+	return ok
+}
+
+// remoteNodeDatabase is a trie node database resolving the nodes missing from
+// the local partial state via a NodeResolver.
+type remoteNodeDatabase struct {
+	triedb  *triedb.Database
+	resolve NodeResolver
+	cache   *lru.SizeConstrainedCache[common.Hash, []byte] // Recently resolved nodes, by hash
+}
+
+// newRemoteNodeDatabase creates a trie node database falling back to the given
+// resolver for the nodes missing locally.
+func newRemoteNodeDatabase(db *triedb.Database, resolve NodeResolver) *remoteNodeDatabase {
+	return &remoteNodeDatabase{
+		triedb:  db,
+		resolve: resolve,
+		cache:   lru.NewSizeConstrainedCache[common.Hash, []byte](remoteNodeCacheSize),
+	}
+}
+
+// Reader returns a node reader of the given state, which must be available
+// locally, at least its account trie.
+func (db *remoteNodeDatabase) Reader(stateRoot common.Hash) (database.Reader, error) {
+	reader, err := db.triedb.Reader(stateRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &remoteNodeReader{root: stateRoot, reader: reader, db: db}, nil
+}
+
+// Preimage retrieves the preimage of the specified hash.
+func (db *remoteNodeDatabase) Preimage(hash common.Hash) []byte {
+	return db.triedb.Preimage(hash)
+}
+
+// InsertPreimage commits a set of preimages along with their hashes.
+func (db *remoteNodeDatabase) InsertPreimage(preimages map[common.Hash][]byte) {
+	db.triedb.InsertPreimage(preimages)
+}
+
+// remoteNodeReader is a node reader of a single state, resolving the nodes
+// missing locally from the network.
+type remoteNodeReader struct {
+	root   common.Hash
+	reader database.Reader
+	db     *remoteNodeDatabase
+}
+
+// Node retrieves the trie node blob with the provided trie identifier, node
+// path and the corresponding node hash, resolving it remotely if it's missing
+// locally. The remotely resolved nodes are verified against the hash.
+func (r *remoteNodeReader) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	if blob, err := r.reader.Node(owner, path, hash); err == nil && len(blob) > 0 {
+		return blob, nil
+	}
+	if blob, ok := r.db.cache.Get(hash); ok {
+		return blob, nil
+	}
+	blob, err := r.db.resolve(r.root, owner, path, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStateUnavailable, err)
+	}
+	if crypto.Keccak256Hash(blob) != hash {
+		return nil, fmt.Errorf("%w: node %x resolved with invalid content", ErrStateUnavailable, hash)
+	}
+	r.db.cache.Add(hash, blob)
+	return blob, nil
+}
@@ -0,0 +1,691 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+const (
+	// onlineBatchSize is the number of database entries checked by the online
+	// pruner in a single batch. The deletions of a batch are flushed together
+	// with the progress marker, so that the pruning can be resumed after a crash.
+	onlineBatchSize = 10000
+)
+
+var (
+	onlineMarkedMeter     = metrics.NewRegisteredMeter("state/prune/online/marked", nil)
+	onlineCheckedMeter    = metrics.NewRegisteredMeter("state/prune/online/checked", nil)
+	onlinePrunedMeter     = metrics.NewRegisteredMeter("state/prune/online/pruned", nil)
+	onlinePrunedSizeMeter = metrics.NewRegisteredMeter("state/prune/online/size", nil)
+	onlineProgressGauge   = metrics.NewRegisteredGauge("state/prune/online/progress", nil)
+)
+
+var (
+	// errPruningRunning is returned if the online pruning is requested to be
+	// started while it's already running.
+	errPruningRunning = errors.New("state pruning is already running")
+
+	// errPruningNotRunning is returned if the online pruning is requested to be
+	// paused while it's not running.
+	errPruningNotRunning = errors.New("state pruning is not running")
+
+	// errPruningAborted is returned if the online pruning is interrupted.
+	errPruningAborted = errors.New("state pruning aborted")
+)
+
+// The statuses of the online pruner.
+const (
+	OnlineStatusIdle     = "idle"     // No pruning has been started
+	OnlineStatusMarking  = "marking"  // The live states are being marked
+	OnlineStatusSweeping = "sweeping" // The stale states are being deleted
+	OnlineStatusPaused   = "paused"   // The pruning is paused and can be resumed
+	OnlineStatusFailed   = "failed"   // The pruning is aborted due to an error
+	OnlineStatusDone     = "done"     // The pruning is finished
+)
+
+// OnlineConfig includes the configurations for online pruning.
+type OnlineConfig struct {
+	BloomSize uint64 `json:"bloomSize"` // The Megabytes of memory allocated to bloom-filter
+	Rate      uint64 `json:"rate"`      // The maximum number of nodes marked or entries swept per second, 0 means unlimited
+}
+
+// DefaultOnlineConfig is the default setting for online pruning.
+var DefaultOnlineConfig = OnlineConfig{
+	BloomSize: 2048,
+	Rate:      100000,
+}
+
+// OnlineProgress is the progress report of the online pruning.
+type OnlineProgress struct {
+	Status     string             `json:"status"`          // The current status of the pruner
+	Root       common.Hash        `json:"root"`            // The head state root when the marking was started
+	Marked     uint64             `json:"marked"`          // The number of nodes marked as live
+	Checked    uint64             `json:"checked"`         // The number of database entries checked by the sweep
+	Pruned     uint64             `json:"pruned"`          // The number of stale entries deleted
+	PrunedSize common.StorageSize `json:"prunedSize"`      // The total size of stale entries deleted
+	Position   hexutil.Bytes      `json:"position"`        // The database key the sweep continues from
+	Progress   float64            `json:"progress"`        // The approximate sweep progress in range [0, 1]
+	Error      string             `json:"error,omitempty"` // The error aborting the pruning, if any
+}
+
+// onlineJournal is the progress marker of the online pruning persisted in the
+// database. It's updated atomically along with each batch of deletions.
+type onlineJournal struct {
+	Position  []byte
+	BloomSize uint64
+	Rate      uint64
+}
+
+// OnlinePruner is a background pruner deleting the stale state of the hash-based
+// trie database while the node keeps running. The workflow is as below:
+//
+//   - register a tracker in the trie database which marks every trie node
+//     cached as dirty or inserted afterwards in the bloom filter
+//   - mark all the nodes and codes belonging to the recent states, and the
+//     genesis state in the bloom filter
+//   - iterate the database in batches, delete all other trie nodes which are
+//     not marked. The progress marker is persisted with each batch
+//
+// Since the newly created trie nodes always reach the dirty cache before they
+// are flushed to disk, all the nodes belonging to the states produced during
+// the pruning are protected by the tracker. Contract codes are never deleted,
+// except the ones stored with the legacy scheme which are unreferenced.
+//
+// The bloom filter is kept in memory only. If the node is restarted during
+// the pruning, the live states are marked again and the sweep is resumed from
+// the persisted progress marker.
+type OnlinePruner struct {
+	db     ethdb.Database
+	triedb *triedb.Database
+	roots  func() []common.Hash // Callback to retrieve the recent state roots, the head state first
+
+	config OnlineConfig
+	bloom  *stateBloom   // The state bloom of current pruning, nil if not started
+	marked bool          // Flag whether the live states are fully marked
+	term   chan struct{} // Termination channel to stop the running pruner
+	done   chan struct{} // Channel closed when the running pruner exits
+	status string        // The current status of the pruner
+	root   common.Hash   // The head state root when the marking was started
+	err    error         // The error aborting the pruning
+	lock   sync.Mutex    // Lock protecting the fields above
+
+	// markLock serializes the node marking and the batched deletion, ensuring
+	// the nodes marked concurrently are never deleted.
+	markLock sync.Mutex
+
+	nmarked  atomic.Uint64
+	nchecked atomic.Uint64
+	npruned  atomic.Uint64
+	size     atomic.Uint64
+	position atomic.Pointer[[]byte]
+}
+
+// NewOnlinePruner creates the online pruner on top of the given hash-based
+// trie database. The roots callback is used to retrieve the recent state roots
+// to be retained, with the head state first.
+func NewOnlinePruner(db ethdb.Database, triedb *triedb.Database, roots func() []common.Hash) *OnlinePruner {
+	return &OnlinePruner{
+		db:     db,
+		triedb: triedb,
+		roots:  roots,
+		status: OnlineStatusIdle,
+	}
+}
+
+// Resume restarts the online pruning interrupted by the node shutdown or crash,
+// with the configurations it was started with. Nothing happens if there is no
+// pruning in progress.
+func (p *OnlinePruner) Resume() error {
+	blob := rawdb.ReadOnlinePruning(p.db)
+	if len(blob) == 0 {
+		return nil
+	}
+	var journal onlineJournal
+	if err := rlp.DecodeBytes(blob, &journal); err != nil {
+		log.Warn("Failed to decode online pruning progress", "err", err)
+		rawdb.DeleteOnlinePruning(p.db)
+		return nil
+	}
+	log.Info("Resuming online state pruning", "position", hexutil.Bytes(journal.Position))
+	return p.Start(OnlineConfig{BloomSize: journal.BloomSize, Rate: journal.Rate})
+}
+
+// Start launches the online pruning in the background, or resumes the paused
+// one. The bloom filter size is ignored for resuming a paused pruning.
+func (p *OnlinePruner) Start(config OnlineConfig) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.term != nil {
+		return errPruningRunning
+	}
+	if p.done != nil {
+		select {
+		case <-p.done:
+		default:
+			return errors.New("state pruning is being paused")
+		}
+	}
+	if p.bloom != nil {
+		config.BloomSize = p.config.BloomSize
+	} else {
+		// Sanitize the bloom filter size if it's too small.
+		if config.BloomSize < 256 {
+			log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+			config.BloomSize = 256
+		}
+		bloom, err := newStateBloomWithSize(config.BloomSize)
+		if err != nil {
+			return err
+		}
+		// Load the progress marker of the interrupted pruning if there is
+		// any, otherwise start from the very beginning.
+		var journal onlineJournal
+		if blob := rawdb.ReadOnlinePruning(p.db); len(blob) != 0 {
+			if err := rlp.DecodeBytes(blob, &journal); err != nil {
+				log.Warn("Failed to decode online pruning progress", "err", err)
+			}
+		}
+		journal.BloomSize, journal.Rate = config.BloomSize, config.Rate
+		blob, err := rlp.EncodeToBytes(journal)
+		if err != nil {
+			return err
+		}
+		// Register the tracker before persisting the marker, all the nodes
+		// in the dirty cache are marked right away.
+		if err := p.triedb.Track(func(hash common.Hash) {
+			p.markLock.Lock()
+			bloom.Put(hash.Bytes(), nil)
+			p.markLock.Unlock()
+		}); err != nil {
+			return fmt.Errorf("online pruning is not supported: %w", err)
+		}
+		rawdb.WriteOnlinePruning(p.db, blob)
+
+		p.bloom, p.marked, p.err = bloom, false, nil
+		p.nmarked.Store(0)
+		p.nchecked.Store(0)
+		p.npruned.Store(0)
+		p.size.Store(0)
+		p.position.Store(&journal.Position)
+	}
+	p.config = config
+	p.term, p.done = make(chan struct{}), make(chan struct{})
+	if p.marked {
+		p.status = OnlineStatusSweeping
+	} else {
+		p.status = OnlineStatusMarking
+	}
+	go p.run(p.bloom, p.marked, config, p.term, p.done)
+
+	log.Info("Started online state pruning", "bloom(MB)", p.config.BloomSize, "rate", config.Rate)
+	return nil
+}
+
+// Pause stops the running pruning, which can be resumed later by Start.
+func (p *OnlinePruner) Pause() error {
+	p.lock.Lock()
+	if p.term == nil {
+		p.lock.Unlock()
+		return errPruningNotRunning
+	}
+	close(p.term)
+	p.term = nil
+	done := p.done
+	p.lock.Unlock()
+
+	<-done
+	log.Info("Paused online state pruning")
+	return nil
+}
+
+// Close terminates the running pruning and releases all resources. The progress
+// marker is retained in the database for resuming the pruning after restart.
+func (p *OnlinePruner) Close() {
+	p.lock.Lock()
+	if p.term != nil {
+		close(p.term)
+		p.term = nil
+	}
+	done := p.done
+	p.lock.Unlock()
+
+	if done != nil {
+		<-done
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		p.triedb.Track(nil)
+		p.bloom = nil
+	}
+}
+
+// Progress returns the progress report of the online pruning.
+func (p *OnlinePruner) Progress() *OnlineProgress {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	progress := &OnlineProgress{
+		Status:     p.status,
+		Root:       p.root,
+		Marked:     p.nmarked.Load(),
+		Checked:    p.nchecked.Load(),
+		Pruned:     p.npruned.Load(),
+		PrunedSize: common.StorageSize(p.size.Load()),
+	}
+	if p.status == OnlineStatusDone {
+		progress.Progress = 1
+	} else if position := p.position.Load(); position != nil {
+		progress.Position = common.CopyBytes(*position)
+		progress.Progress = sweepProgress(*position)
+	}
+	if p.err != nil {
+		progress.Error = p.err.Error()
+	}
+	return progress
+}
+
+// sweepProgress returns the approximate progress of the sweep at the given
+// position, assuming the database keys are distributed evenly.
+func sweepProgress(position []byte) float64 {
+	var prefix [8]byte
+	copy(prefix[:], position)
+	return float64(binary.BigEndian.Uint64(prefix[:])) / math.MaxUint64
+}
+
+// run marks the live states if they are not marked yet and then sweeps the
+// stale states. It's supposed to be launched in a separate goroutine.
+func (p *OnlinePruner) run(bloom *stateBloom, marked bool, config OnlineConfig, term, done chan struct{}) {
+	defer close(done)
+
+	throttle := newThrottler(config.Rate)
+	err := func() error {
+		if !marked {
+			if err := p.markStates(bloom, throttle, term); err != nil {
+				return err
+			}
+			p.lock.Lock()
+			p.marked, p.status = true, OnlineStatusSweeping
+			p.lock.Unlock()
+		}
+		return p.sweep(bloom, config, throttle, term)
+	}()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.term == term {
+		p.term = nil
+	}
+	switch {
+	case errors.Is(err, errPruningAborted):
+		p.status = OnlineStatusPaused
+		return
+	case err != nil:
+		log.Error("Online state pruning failed", "err", err)
+		p.status, p.err = OnlineStatusFailed, err
+	default:
+		p.status = OnlineStatusDone
+		rawdb.DeleteOnlinePruning(p.db)
+	}
+	// Release the bloom filter, a new one will be created if the pruning
+	// is restarted.
+	p.triedb.Track(nil)
+	p.bloom = nil
+}
+
+// markStates marks all the trie nodes and contract codes of the recent states
+// and the genesis state in the bloom filter.
+func (p *OnlinePruner) markStates(bloom *stateBloom, throttle *throttler, term chan struct{}) error {
+	roots := p.roots()
+	if len(roots) == 0 {
+		return errors.New("no state available")
+	}
+	// Pin the recent states in the dirty cache, preventing them from being
+	// garbage collected before the marking is finished.
+	for _, root := range roots {
+		p.triedb.Reference(root, common.Hash{})
+	}
+	defer func() {
+		for _, root := range roots {
+			p.triedb.Dereference(root)
+		}
+	}()
+	p.lock.Lock()
+	p.root = roots[0]
+	p.lock.Unlock()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	if err := extractGenesis(p.db, bloom); err != nil {
+		return err
+	}
+	// Mark the entire head state, the other recent states are marked by the
+	// differences against the head state.
+	for i, root := range roots {
+		var base common.Hash
+		if i != 0 {
+			base = roots[0]
+		}
+		err := p.markState(bloom, root, base, func() error {
+			if !throttle.wait(term) {
+				return errPruningAborted
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Marking live state", "states", fmt.Sprintf("%d/%d", i+1, len(roots)), "nodes", p.nmarked.Load(), "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Marked live state", "states", len(roots), "nodes", p.nmarked.Load(), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// markState marks the trie nodes and contract codes of the state specified by
+// root in the bloom filter. If the base is not empty, only the nodes which are
+// not present in the base state are marked.
+func (p *OnlinePruner) markState(bloom *stateBloom, root common.Hash, base common.Hash, onNode func() error) error {
+	mark := func(hash []byte) error {
+		bloom.Put(hash, nil)
+		p.nmarked.Add(1)
+		onlineMarkedMeter.Mark(1)
+		return onNode()
+	}
+	t, err := trie.NewStateTrie(trie.StateTrieID(root), p.triedb)
+	if err != nil {
+		return err
+	}
+	accIter, err := t.NodeIterator(nil)
+	if err != nil {
+		return err
+	}
+	var baseTrie *trie.StateTrie
+	if base != (common.Hash{}) {
+		baseIterTrie, err := trie.NewStateTrie(trie.StateTrieID(base), p.triedb)
+		if err != nil {
+			return err
+		}
+		baseIter, err := baseIterTrie.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		accIter, _ = trie.NewDifferenceIterator(baseIter, accIter)
+
+		// Use a dedicated trie for account lookups, not interfering with
+		// the iteration.
+		baseTrie, err = trie.NewStateTrie(trie.StateTrieID(base), p.triedb)
+		if err != nil {
+			return err
+		}
+	}
+	for accIter.Next(true) {
+		// Embedded nodes don't have hash.
+		if hash := accIter.Hash(); hash != (common.Hash{}) {
+			if err := mark(hash.Bytes()); err != nil {
+				return err
+			}
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return err
+		}
+		if !bytes.Equal(acc.CodeHash, types.EmptyCodeHash.Bytes()) {
+			bloom.Put(acc.CodeHash, nil)
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		owner := common.BytesToHash(accIter.LeafKey())
+		storageTrie, err := trie.NewStateTrie(trie.StorageTrieID(root, owner, acc.Root), p.triedb)
+		if err != nil {
+			return err
+		}
+		storageIter, err := storageTrie.NodeIterator(nil)
+		if err != nil {
+			return err
+		}
+		if baseTrie != nil {
+			baseAcc, err := baseTrie.GetAccountByHash(owner)
+			if err != nil {
+				return err
+			}
+			if baseAcc != nil && baseAcc.Root != types.EmptyRootHash {
+				baseStorage, err := trie.NewStateTrie(trie.StorageTrieID(base, owner, baseAcc.Root), p.triedb)
+				if err != nil {
+					return err
+				}
+				baseIter, err := baseStorage.NodeIterator(nil)
+				if err != nil {
+					return err
+				}
+				storageIter, _ = trie.NewDifferenceIterator(baseIter, storageIter)
+			}
+		}
+		for storageIter.Next(true) {
+			if hash := storageIter.Hash(); hash != (common.Hash{}) {
+				if err := mark(hash.Bytes()); err != nil {
+					return err
+				}
+			}
+		}
+		if storageIter.Error() != nil {
+			return storageIter.Error()
+		}
+	}
+	return accIter.Error()
+}
+
+// sweep iterates the database from the persisted progress marker and deletes
+// all the trie nodes which are not marked in the bloom filter.
+func (p *OnlinePruner) sweep(bloom *stateBloom, config OnlineConfig, throttle *throttler, term chan struct{}) error {
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		position = *p.position.Load()
+	)
+	for {
+		select {
+		case <-term:
+			return errPruningAborted
+		default:
+		}
+		// Collect the deletion candidates of the batch. Recreate the iterator
+		// for every batch in order to not pin the database for too long.
+		var (
+			keys    [][]byte
+			sizes   []int
+			checked int
+			last    []byte
+			iter    = p.db.NewIterator(nil, position)
+		)
+		for checked < onlineBatchSize && iter.Next() {
+			key := iter.Key()
+			checked++
+			last = common.CopyBytes(key)
+
+			// Only the trie nodes and the contract codes stored with the legacy
+			// scheme are keyed by the plain hash.
+			if len(key) == common.HashLength && !bloom.Contain(key) {
+				keys = append(keys, last)
+				sizes = append(sizes, len(key)+len(iter.Value()))
+			}
+		}
+		exhausted := checked < onlineBatchSize
+		err := iter.Error()
+		iter.Release()
+		if err != nil {
+			return err
+		}
+		if !exhausted {
+			position = append(last, 0)
+		}
+		journal, err := rlp.EncodeToBytes(onlineJournal{
+			Position:  position,
+			BloomSize: config.BloomSize,
+			Rate:      config.Rate,
+		})
+		if err != nil {
+			return err
+		}
+		// Delete the candidates which are still not marked, the nodes may be
+		// marked in the meantime by the tracker.
+		var (
+			batch  = p.db.NewBatch()
+			pruned int
+			size   int
+		)
+		p.markLock.Lock()
+		for i, key := range keys {
+			if bloom.Contain(key) {
+				continue
+			}
+			batch.Delete(key)
+			pruned += 1
+			size += sizes[i]
+		}
+		rawdb.WriteOnlinePruning(batch, journal)
+		err = batch.Write()
+		p.markLock.Unlock()
+		if err != nil {
+			return err
+		}
+		p.nchecked.Add(uint64(checked))
+		p.npruned.Add(uint64(pruned))
+		p.size.Add(uint64(size))
+		p.position.Store(&position)
+
+		onlineCheckedMeter.Mark(int64(checked))
+		onlinePrunedMeter.Mark(int64(pruned))
+		onlinePrunedSizeMeter.Mark(int64(size))
+		onlineProgressGauge.Update(int64(sweepProgress(position) * 10000))
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "checked", p.nchecked.Load(), "nodes", p.npruned.Load(), "size", common.StorageSize(p.size.Load()),
+				"progress", fmt.Sprintf("%.2f%%", sweepProgress(position)*100), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		if exhausted {
+			break
+		}
+		if !throttle.waitN(uint64(checked), term) {
+			return errPruningAborted
+		}
+	}
+	onlineProgressGauge.Update(10000)
+	log.Info("Pruned state data", "nodes", p.npruned.Load(), "size", common.StorageSize(p.size.Load()), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Start compactions, will remove the deleted data from the disk immediately.
+	// Note for small pruning, the compaction is skipped.
+	if p.npruned.Load() >= rangeCompactionThreshold {
+		cstart := time.Now()
+		for b := 0x00; b <= 0xf0; b += 0x10 {
+			var (
+				start = []byte{byte(b)}
+				end   = []byte{byte(b + 0x10)}
+			)
+			if b == 0xf0 {
+				end = nil
+			}
+			select {
+			case <-term:
+				// The deletions are already done, leave the compaction to the
+				// database itself.
+				return nil
+			default:
+			}
+			if err := p.db.Compact(start, end); err != nil {
+				log.Error("Database compaction failed", "error", err)
+				return err
+			}
+		}
+		log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+	return nil
+}
+
+// throttler limits the rate of the online pruning, in terms of the number of
+// items processed per second.
+type throttler struct {
+	rate  uint64    // The maximum number of items processed per second, 0 means unlimited
+	start time.Time // The start time of the current window
+	count uint64    // The number of items processed in the current window
+}
+
+func newThrottler(rate uint64) *throttler {
+	return &throttler{rate: rate, start: time.Now()}
+}
+
+// wait accounts a single processed item, see waitN.
+func (t *throttler) wait(term chan struct{}) bool {
+	return t.waitN(1, term)
+}
+
+// waitN accounts the given number of processed items and blocks if the rate
+// limit of the current window is exceeded. False is returned if the pruning
+// is terminated while waiting.
+func (t *throttler) waitN(n uint64, term chan struct{}) bool {
+	if t.rate == 0 {
+		select {
+		case <-term:
+			return false
+		default:
+			return true
+		}
+	}
+	t.count += n
+	if t.count < t.rate {
+		return true
+	}
+	if elapsed := time.Since(t.start); elapsed < time.Second {
+		select {
+		case <-time.After(time.Second - elapsed):
+		case <-term:
+			return false
+		}
+	}
+	t.start, t.count = time.Now(), 0
+	return true
+}
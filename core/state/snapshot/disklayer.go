@@ -35,6 +35,8 @@ type diskLayer struct {
 	triedb *triedb.Database    // Trie node cache for reconstruction purposes
 	cache  *fastcache.Cache    // Cache to avoid hitting the disk for direct access
 
+	tracked func(common.Hash) bool // Filter of the accounts with a local storage trie, nil for all
+
 	root  common.Hash // Root hash of the base snapshot
 	stale bool        // Signals that the layer became stale (state progressed)
 
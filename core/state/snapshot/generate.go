@@ -56,7 +56,7 @@ var (
 // generateSnapshot regenerates a brand new snapshot based on an existing state
 // database and head block asynchronously. The snapshot is returned immediately
 // and generation is continued in the background until done.
-func generateSnapshot(diskdb ethdb.KeyValueStore, triedb *triedb.Database, cache int, root common.Hash) *diskLayer {
+func generateSnapshot(diskdb ethdb.KeyValueStore, triedb *triedb.Database, cache int, root common.Hash, tracked func(common.Hash) bool) *diskLayer {
 	// Create a new disk layer with an initialized state marker at zero
 	var (
 		stats     = &generatorStats{start: time.Now()}
@@ -71,6 +71,7 @@ func generateSnapshot(diskdb ethdb.KeyValueStore, triedb *triedb.Database, cache
 	base := &diskLayer{
 		diskdb:     diskdb,
 		triedb:     triedb,
+		tracked:    tracked,
 		root:       root,
 		cache:      fastcache.New(cache * 1024 * 1024),
 		genMarker:  genMarker,
@@ -618,8 +619,9 @@ func generateAccounts(ctx *generatorContext, dl *diskLayer, accMarker []byte) er
 		snapAccountWriteCounter.Inc(time.Since(start).Nanoseconds()) // let's count flush time as well
 
 		// If the iterated account is the contract, create a further loop to
-		// verify or regenerate the contract storage.
-		if acc.Root == types.EmptyRootHash {
+		// verify or regenerate the contract storage. Untracked contracts of a
+		// partial state have no local storage trie to generate from.
+		if acc.Root == types.EmptyRootHash || (dl.tracked != nil && !dl.tracked(account)) {
 			ctx.removeStorageAt(account)
 		} else {
 			var storeMarker []byte
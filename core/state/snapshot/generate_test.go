@@ -229,7 +229,7 @@ func (t *testHelper) Commit() common.Hash {
 
 func (t *testHelper) CommitAndGenerate() (common.Hash, *diskLayer) {
 	root := t.Commit()
-	snap := generateSnapshot(t.diskdb, t.triedb, 16, root)
+	snap := generateSnapshot(t.diskdb, t.triedb, 16, root, nil)
 	return root, snap
 }
 
@@ -431,7 +431,7 @@ func testGenerateCorruptAccountTrie(t *testing.T, scheme string) {
 
 	rawdb.DeleteTrieNode(helper.diskdb, common.Hash{}, targetPath, targetHash, scheme)
 
-	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root)
+	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root, nil)
 	select {
 	case <-snap.genPending:
 		// Snapshot generation succeeded
@@ -475,7 +475,7 @@ func testGenerateMissingStorageTrie(t *testing.T, scheme string) {
 	rawdb.DeleteTrieNode(helper.diskdb, acc1, nil, stRoot, scheme)
 	rawdb.DeleteTrieNode(helper.diskdb, acc3, nil, stRoot, scheme)
 
-	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root)
+	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root, nil)
 	select {
 	case <-snap.genPending:
 		// Snapshot generation succeeded
@@ -517,7 +517,7 @@ func testGenerateCorruptStorageTrie(t *testing.T, scheme string) {
 	rawdb.DeleteTrieNode(helper.diskdb, hashData([]byte("acc-1")), targetPath, targetHash, scheme)
 	rawdb.DeleteTrieNode(helper.diskdb, hashData([]byte("acc-3")), targetPath, targetHash, scheme)
 
-	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root)
+	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root, nil)
 	select {
 	case <-snap.genPending:
 		// Snapshot generation succeeded
@@ -581,7 +581,7 @@ func testGenerateWithExtraAccounts(t *testing.T, scheme string) {
 	if data := rawdb.ReadStorageSnapshot(helper.diskdb, hashData([]byte("acc-2")), hashData([]byte("b-key-1"))); data == nil {
 		t.Fatalf("expected snap storage to exist")
 	}
-	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root)
+	snap := generateSnapshot(helper.diskdb, helper.triedb, 16, root, nil)
 	select {
 	case <-snap.genPending:
 		// Snapshot generation succeeded
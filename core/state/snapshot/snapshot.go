@@ -154,6 +154,11 @@ type Config struct {
 	Recovery   bool // Indicator that the snapshots is in the recovery mode
 	NoBuild    bool // Indicator that the snapshots generation is disallowed
 	AsyncBuild bool // The snapshot generation is allowed to be constructed asynchronously
+
+	// Tracked, if set, restricts the storage snapshot to the accounts it accepts.
+	// It's used by partial state nodes, whose storage tries are only complete
+	// for the tracked contracts.
+	Tracked func(account common.Hash) bool
 }
 
 // Tree is an Ethereum state snapshot tree. It consists of one persistent base
@@ -201,7 +206,7 @@ func New(config Config, diskdb ethdb.KeyValueStore, triedb *triedb.Database, roo
 		layers: make(map[common.Hash]snapshot),
 	}
 	// Attempt to load a previously persisted snapshot and rebuild one if failed
-	head, disabled, err := loadSnapshot(diskdb, triedb, root, config.CacheSize, config.Recovery, config.NoBuild)
+	head, disabled, err := loadSnapshot(diskdb, triedb, root, config.CacheSize, config.Recovery, config.NoBuild, config.Tracked)
 	if disabled {
 		log.Warn("Snapshot maintenance disabled (syncing)")
 		return snap, nil
@@ -648,6 +653,7 @@ func diffToDisk(bottom *diffLayer) *diskLayer {
 		cache:      base.cache,
 		diskdb:     base.diskdb,
 		triedb:     base.triedb,
+		tracked:    base.tracked,
 		genMarker:  base.genMarker,
 		genPending: base.genPending,
 	}
@@ -752,7 +758,7 @@ func (t *Tree) Rebuild(root common.Hash) {
 	// generator will run a wiper first if there's not one running right now.
 	log.Info("Rebuilding state snapshot")
 	t.layers = map[common.Hash]snapshot{
-		root: generateSnapshot(t.diskdb, t.triedb, t.config.CacheSize, root),
+		root: generateSnapshot(t.diskdb, t.triedb, t.config.CacheSize, root, t.config.Tracked),
 	}
 }
 
@@ -185,15 +185,20 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 	if _, destructed := s.db.stateObjectsDestruct[s.address]; destructed {
 		return common.Hash{}
 	}
-	// If no live objects are available, attempt to use snapshots
+	// If no live objects are available, attempt to use snapshots. The storage
+	// of the contracts untracked by the partial state is not in the snapshot.
 	var (
 		enc   []byte
 		err   error
 		value common.Hash
+		snap  = s.db.snap
 	)
-	if s.db.snap != nil {
+	if snap != nil && !s.db.partial.TrackedHash(s.addrHash) {
+		snap = nil
+	}
+	if snap != nil {
 		start := time.Now()
-		enc, err = s.db.snap.Storage(s.addrHash, crypto.Keccak256Hash(key.Bytes()))
+		enc, err = snap.Storage(s.addrHash, crypto.Keccak256Hash(key.Bytes()))
 		s.db.SnapshotStorageReads += time.Since(start)
 
 		if len(enc) > 0 {
@@ -205,7 +210,7 @@ func (s *stateObject) GetCommittedState(key common.Hash) common.Hash {
 		}
 	}
 	// If the snapshot is unavailable or reading from it fails, load from the database.
-	if s.db.snap == nil || err != nil {
+	if snap == nil || err != nil {
 		start := time.Now()
 		tr, err := s.getTrie()
 		if err != nil {
@@ -63,6 +63,7 @@ type StateDB struct {
 	logger     *tracing.Hooks
 	snaps      *snapshot.Tree    // Nil if snapshot is not available
 	snap       snapshot.Snapshot // Nil if snapshot is not available
+	partial    *PartialState     // Contracts whose storage is maintained, nil for the full state
 
 	// originalRoot is the pre-state root, before any changes were made.
 	// It will be updated when the Commit is called.
@@ -167,6 +168,9 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 	if sdb.snaps != nil {
 		sdb.snap = sdb.snaps.Snapshot(root)
 	}
+	if db, ok := db.(*cachingDB); ok {
+		sdb.partial = db.partial
+	}
 	return sdb, nil
 }
 
@@ -715,8 +719,9 @@ func (s *StateDB) Copy() *StateDB {
 		// to the snapshot tree, we need to copy that as well. Otherwise, any
 		// block mined by ourselves will cause gaps in the tree, and force the
 		// miner to operate trie-backed only.
-		snaps: s.snaps,
-		snap:  s.snap,
+		snaps:   s.snaps,
+		snap:    s.snap,
+		partial: s.partial,
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range s.journal.dirties {
@@ -1236,6 +1241,20 @@ func (s *StateDB) Commit(block uint64, deleteEmptyObjects bool) (common.Hash, er
 	s.AccountUpdated, s.AccountDeleted = 0, 0
 	s.StorageUpdated, s.StorageDeleted = 0, 0
 
+	// The partial state only maintains the storage of the tracked contracts,
+	// drop the changes of the untracked ones resolved from the network.
+	if s.partial != nil {
+		for owner := range nodes.Sets {
+			if owner != (common.Hash{}) && !s.partial.TrackedHash(owner) {
+				delete(nodes.Sets, owner)
+			}
+		}
+		for addrHash := range s.storages {
+			if !s.partial.TrackedHash(addrHash) {
+				delete(s.storages, addrHash)
+			}
+		}
+	}
 	// If snapshotting is enabled, update the snapshot tree with this new version
 	if s.snap != nil {
 		start = time.Now()
@@ -26,6 +26,12 @@ import (
 
 // NewStateSync creates a new state trie download scheduler.
 func NewStateSync(root common.Hash, database ethdb.KeyValueReader, onLeaf func(keys [][]byte, leaf []byte) error, scheme string) *trie.Sync {
+	return NewPartialStateSync(root, database, onLeaf, scheme, nil)
+}
+
+// NewPartialStateSync creates a new state trie download scheduler, which only
+// retrieves the storage tries of the contracts tracked by the partial state.
+func NewPartialStateSync(root common.Hash, database ethdb.KeyValueReader, onLeaf func(keys [][]byte, leaf []byte) error, scheme string, partial *PartialState) *trie.Sync {
 	// Register the storage slot callback if the external callback is specified.
 	var onSlot func(keys [][]byte, path []byte, leaf []byte, parent common.Hash, parentPath []byte) error
 	if onLeaf != nil {
@@ -46,7 +52,9 @@ func NewStateSync(root common.Hash, database ethdb.KeyValueReader, onLeaf func(k
 		if err := rlp.DecodeBytes(leaf, &obj); err != nil {
 			return err
 		}
-		syncer.AddSubTrie(obj.Root, path, parent, parentPath, onSlot)
+		if partial.TrackedHash(common.BytesToHash(keys[0])) {
+			syncer.AddSubTrie(obj.Root, path, parent, parentPath, onSlot)
+		}
 		syncer.AddCodeEntry(common.BytesToHash(obj.CodeHash), path, parent, parentPath)
 		return nil
 	}
@@ -18,6 +18,7 @@ package state
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -735,6 +736,93 @@ func testIncompleteStateSync(t *testing.T, scheme string) {
 	}
 }
 
+// Tests that a partial state sync only retrieves the storage tries of the
+// tracked contracts, while the account trie is reconstructed in full.
+func TestPartialStateSync(t *testing.T) {
+	testPartialStateSync(t, rawdb.HashScheme)
+	testPartialStateSync(t, rawdb.PathScheme)
+}
+
+func testPartialStateSync(t *testing.T, scheme string) {
+	// Create a random state to copy, track only a single contract with storage
+	_, srcDb, ndb, srcRoot, srcAccounts := makeTestState(scheme)
+	ndb.Commit(srcRoot, false)
+
+	var (
+		tracked   = common.BytesToAddress([]byte{5})
+		untracked = common.BytesToAddress([]byte{10})
+		partial   = NewPartialState([]common.Address{tracked})
+	)
+	reader, err := ndb.Reader(srcRoot)
+	if err != nil {
+		t.Fatalf("state is not existent, %#x", srcRoot)
+	}
+	dstDb := rawdb.NewMemoryDatabase()
+	sched := NewPartialStateSync(srcRoot, dstDb, nil, ndb.Scheme(), partial)
+
+	paths, nodes, codes := sched.Missing(0)
+	for len(paths)+len(codes) > 0 {
+		for _, hash := range codes {
+			data, err := srcDb.ContractCode(common.Address{}, hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve contract bytecode for hash %x", hash)
+			}
+			if err := sched.ProcessCode(trie.CodeSyncResult{Hash: hash, Data: data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		for i, path := range paths {
+			owner, inner := trie.ResolvePath([]byte(path))
+			if owner != (common.Hash{}) && !partial.TrackedHash(owner) {
+				t.Fatalf("storage of untracked account %x requested", owner)
+			}
+			data, err := reader.Node(owner, inner, nodes[i])
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for key %v", []byte(path))
+			}
+			if err := sched.ProcessNode(trie.NodeSyncResult{Path: path, Data: data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		batch := dstDb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+
+		paths, nodes, codes = sched.Missing(0)
+	}
+	// Cross check the account fields of all accounts and the storage access
+	// of the tracked and untracked contracts.
+	config := &triedb.Config{}
+	if scheme == rawdb.PathScheme {
+		config.PathDB = pathdb.Defaults
+	}
+	state, err := New(srcRoot, NewPartialDatabase(dstDb, triedb.NewDatabase(dstDb, config), partial, nil), nil)
+	if err != nil {
+		t.Fatalf("failed to create state trie at %x: %v", srcRoot, err)
+	}
+	for i, acc := range srcAccounts {
+		if balance := state.GetBalance(acc.address); balance.Cmp(acc.balance) != 0 {
+			t.Errorf("account %d: balance mismatch: have %v, want %v", i, balance, acc.balance)
+		}
+		if code := state.GetCode(acc.address); !bytes.Equal(code, acc.code) {
+			t.Errorf("account %d: code mismatch: have %x, want %x", i, code, acc.code)
+		}
+	}
+	slot := crypto.Keccak256Hash([]byte{5, 5, 5, 5, 5, 0, 0})
+	if value := state.GetState(tracked, slot); value != slot {
+		t.Errorf("tracked slot mismatch: have %x, want %x", value, slot)
+	}
+	if err := state.Error(); err != nil {
+		t.Fatalf("unexpected error reading tracked storage: %v", err)
+	}
+	state.GetState(untracked, crypto.Keccak256Hash([]byte{10, 10, 10, 10, 10, 0, 0}))
+	if err := state.Error(); !errors.Is(err, ErrStateNotTracked) {
+		t.Fatalf("untracked storage error mismatch: have %v, want %v", err, ErrStateNotTracked)
+	}
+}
+
 func copyPreimages(srcDb, dstDb ethdb.Database) {
 	it := srcDb.NewIterator(rawdb.PreimagePrefix, nil)
 	defer it.Release()
@@ -314,6 +314,8 @@ type BlobPool struct {
 	discoverFeed event.Feed // Event feed to send out new tx events on pool discovery (reorg excluded)
 	insertFeed   event.Feed // Event feed to send out new tx events on pool inclusion (reorg included)
 
+	txEvents txpool.TxEventFeed // Event feed to send out transaction lifecycle events
+
 	lock sync.RWMutex // Mutex protecting the pool during reorg handling
 }
 
@@ -444,6 +446,8 @@ func (p *BlobPool) Close() error {
 	if err := p.store.Close(); err != nil {
 		errs = append(errs, err)
 	}
+	p.txEvents.Close()
+
 	switch {
 	case errs == nil:
 		return nil
@@ -533,6 +537,12 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
 
+			if gapped {
+				p.txEvents.Queue(txpool.TxEventNonceGap, txs[i].hash, addr, txs[i].nonce)
+			} else {
+				p.txEvents.Queue(staleEventKind(txs[i].hash, inclusions), txs[i].hash, addr, txs[i].nonce)
+			}
+
 			// Included transactions blobs need to be moved to the limbo
 			if filled && inclusions != nil {
 				p.offload(addr, txs[i].nonce, txs[i].id, inclusions)
@@ -573,6 +583,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[0].costCap)
 			p.stored -= uint64(txs[0].size)
 			delete(p.lookup, txs[0].hash)
+			p.txEvents.Queue(staleEventKind(txs[0].hash, inclusions), txs[0].hash, addr, txs[0].nonce)
 
 			// Included transactions blobs need to be moved to the limbo
 			if inclusions != nil {
@@ -629,6 +640,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 			p.stored -= uint64(txs[i].size)
 			delete(p.lookup, txs[i].hash)
+			p.txEvents.Queue(txpool.TxEventInvalidated, txs[i].hash, addr, txs[i].nonce)
 
 			if err := p.store.Delete(id); err != nil {
 				log.Error("Failed to delete blob transaction", "from", addr, "id", id, "err", err)
@@ -651,6 +663,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[j].costCap)
 			p.stored -= uint64(txs[j].size)
 			delete(p.lookup, txs[j].hash)
+			p.txEvents.Queue(txpool.TxEventNonceGap, txs[j].hash, addr, txs[j].nonce)
 		}
 		txs = txs[:i]
 
@@ -689,6 +702,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.txEvents.Queue(txpool.TxEventInvalidated, last.hash, addr, last.nonce)
 		}
 		if len(txs) == 0 {
 			delete(p.index, addr)
@@ -729,6 +743,7 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 			p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], last.costCap)
 			p.stored -= uint64(last.size)
 			delete(p.lookup, last.hash)
+			p.txEvents.Queue(txpool.TxEventAccountLimit, last.hash, addr, last.nonce)
 		}
 		p.index[addr] = txs
 
@@ -748,6 +763,16 @@ func (p *BlobPool) recheck(addr common.Address, inclusions map[common.Hash]uint6
 	}
 }
 
+// staleEventKind returns the lifecycle event kind for a transaction dropped due
+// to its nonce being used up on chain: whether it was included or invalidated
+// by another transaction.
+func staleEventKind(hash common.Hash, inclusions map[common.Hash]uint64) txpool.TxEventKind {
+	if _, ok := inclusions[hash]; ok {
+		return txpool.TxEventIncluded
+	}
+	return txpool.TxEventInvalidated
+}
+
 // offload removes a tracked blob transaction from the pool and moves it into the
 // limbo for tracking until finality.
 //
@@ -780,6 +805,8 @@ func (p *BlobPool) offload(addr common.Address, nonce uint64, id uint64, inclusi
 // Reset implements txpool.SubPool, allowing the blob pool's internal state to be
 // kept in sync with the main transaction pool's internal state.
 func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
+	defer p.txEvents.Flush()
+
 	waitStart := time.Now()
 	p.lock.Lock()
 	resetwaitHist.Update(time.Since(waitStart).Nanoseconds())
@@ -806,6 +833,7 @@ func (p *BlobPool) Reset(oldHead, newHead *types.Header) {
 			for _, tx := range txs {
 				if err := p.reinject(addr, tx.Hash()); err == nil {
 					adds = append(adds, tx.WithoutBlobTxSidecar())
+					p.txEvents.Queue(txpool.TxEventReinjected, tx.Hash(), addr, tx.Nonce())
 				}
 			}
 			// Recheck the account's pooled transactions to drop included and
@@ -1014,6 +1042,8 @@ func (p *BlobPool) reinject(addr common.Address, txhash common.Hash) error {
 // SetGasTip implements txpool.SubPool, allowing the blob pool's gas requirements
 // to be kept in sync with the main transaction pool's gas requirements.
 func (p *BlobPool) SetGasTip(tip *big.Int) {
+	defer p.txEvents.Flush()
+
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -1034,6 +1064,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 					p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], txs[i].costCap)
 					p.stored -= uint64(tx.size)
 					delete(p.lookup, tx.hash)
+					p.txEvents.Queue(txpool.TxEventUnderpriced, tx.hash, addr, tx.nonce)
 					txs[i] = nil
 
 					// Drop everything afterwards, no gaps allowed
@@ -1044,6 +1075,7 @@ func (p *BlobPool) SetGasTip(tip *big.Int) {
 						p.spent[addr] = new(uint256.Int).Sub(p.spent[addr], tx.costCap)
 						p.stored -= uint64(tx.size)
 						delete(p.lookup, tx.hash)
+						p.txEvents.Queue(txpool.TxEventUnderpriced, tx.hash, addr, tx.nonce)
 						txs[i+1+j] = nil
 					}
 					// Clear out the dropped transactions from the index
@@ -1219,6 +1251,8 @@ func (p *BlobPool) Add(txs []*types.Transaction, local bool, sync bool) []error
 			adds = append(adds, tx.WithoutBlobTxSidecar())
 		}
 	}
+	p.txEvents.Flush()
+
 	if len(adds) > 0 {
 		p.discoverFeed.Send(core.NewTxsEvent{Txs: adds})
 		p.insertFeed.Send(core.NewTxsEvent{Txs: adds})
@@ -1322,6 +1356,8 @@ func (p *BlobPool) add(tx *types.Transaction) (err error) {
 		delete(p.lookup, prev.hash)
 		p.lookup[meta.hash] = meta.id
 		p.stored += uint64(meta.size) - uint64(prev.size)
+
+		p.txEvents.QueueReplaced(prev.hash, from, prev.nonce, meta.hash)
 	} else {
 		// Transaction extends previously scheduled ones
 		p.index[from] = append(p.index[from], meta)
@@ -1420,6 +1456,7 @@ func (p *BlobPool) drop() {
 	}
 	p.stored -= uint64(drop.size)
 	delete(p.lookup, drop.hash)
+	p.txEvents.Queue(txpool.TxEventUnderpriced, drop.hash, from, drop.nonce)
 
 	// Remove the transaction from the pool's eviction heap:
 	//   - If the entire account was dropped, pop off the address
@@ -1595,6 +1632,12 @@ func (p *BlobPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool
 	}
 }
 
+// SubscribeTxEvents registers a subscription for transaction lifecycle events,
+// announcing why transactions leave (or reenter) the pool.
+func (p *BlobPool) SubscribeTxEvents(ch chan<- []*txpool.TxEvent) event.Subscription {
+	return p.txEvents.Subscribe(ch)
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *BlobPool) Nonce(addr common.Address) uint64 {
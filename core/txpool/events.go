@@ -0,0 +1,151 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxEventKind is the reason of a transaction lifecycle event.
+type TxEventKind uint8
+
+const (
+	// TxEventIncluded is emitted when a transaction leaves the pool because it
+	// got included in a block of the canonical chain.
+	TxEventIncluded TxEventKind = iota
+
+	// TxEventReplaced is emitted when a transaction is superseded by another
+	// one from the same sender with the same nonce.
+	TxEventReplaced
+
+	// TxEventUnderpriced is emitted when a transaction is evicted because its
+	// fees became too low to keep it in the pool.
+	TxEventUnderpriced
+
+	// TxEventNonceGap is emitted when a transaction is evicted because it was
+	// not executable due to a nonce gap (for too long).
+	TxEventNonceGap
+
+	// TxEventAccountLimit is emitted when a transaction is evicted because its
+	// sender exceeded the number of transactions the pool is willing to track.
+	TxEventAccountLimit
+
+	// TxEventInvalidated is emitted when a transaction becomes invalid with
+	// regard to the chain state (nonce used up by another transaction, balance
+	// too low or gas limit too high).
+	TxEventInvalidated
+
+	// TxEventReinjected is emitted when a transaction is pushed back into the
+	// pool after the block including it was reorged out.
+	TxEventReinjected
+)
+
+// String implements fmt.Stringer.
+func (k TxEventKind) String() string {
+	switch k {
+	case TxEventIncluded:
+		return "included"
+	case TxEventReplaced:
+		return "replaced"
+	case TxEventUnderpriced:
+		return "underpriced"
+	case TxEventNonceGap:
+		return "noncegap"
+	case TxEventAccountLimit:
+		return "accountlimit"
+	case TxEventInvalidated:
+		return "invalidated"
+	case TxEventReinjected:
+		return "reinjected"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(k))
+	}
+}
+
+// TxEvent is a transaction lifecycle event, announcing why a transaction left
+// (or reentered) the pool.
+type TxEvent struct {
+	Kind       TxEventKind    // Reason of the event
+	Hash       common.Hash    // Hash of the transaction the event is about
+	From       common.Address // Sender of the transaction
+	Nonce      uint64         // Nonce of the transaction
+	ReplacedBy *common.Hash   // Replacement transaction, only set for TxEventReplaced
+}
+
+// TxEventFeed is a helper for subpools to accumulate lifecycle events while
+// holding their internal locks and to deliver them in batches once the locks
+// are released. Events are only accumulated if there are live subscribers.
+type TxEventFeed struct {
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	queue []*TxEvent // Events accumulated since the last flush
+	lock  sync.Mutex // Lock protecting the event queue
+}
+
+// Subscribe registers a subscription for batches of lifecycle events.
+func (f *TxEventFeed) Subscribe(ch chan<- []*TxEvent) event.Subscription {
+	return f.scope.Track(f.feed.Subscribe(ch))
+}
+
+// Active reports whether there is anyone listening for events, allowing the
+// pools to skip any bookkeeping needed to create them.
+func (f *TxEventFeed) Active() bool {
+	return f.scope.Count() > 0
+}
+
+// Queue schedules a lifecycle event for the next flush.
+func (f *TxEventFeed) Queue(kind TxEventKind, hash common.Hash, from common.Address, nonce uint64) {
+	f.queueEvent(&TxEvent{Kind: kind, Hash: hash, From: from, Nonce: nonce})
+}
+
+// QueueReplaced schedules a replacement event for the next flush.
+func (f *TxEventFeed) QueueReplaced(hash common.Hash, from common.Address, nonce uint64, replacement common.Hash) {
+	f.queueEvent(&TxEvent{Kind: TxEventReplaced, Hash: hash, From: from, Nonce: nonce, ReplacedBy: &replacement})
+}
+
+func (f *TxEventFeed) queueEvent(ev *TxEvent) {
+	if !f.Active() {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.queue = append(f.queue, ev)
+}
+
+// Flush delivers all the events accumulated since the last flush. It must not
+// be called while holding any lock the subscribers might contend on.
+func (f *TxEventFeed) Flush() {
+	f.lock.Lock()
+	events := f.queue
+	f.queue = nil
+	f.lock.Unlock()
+
+	if len(events) > 0 {
+		f.feed.Send(events)
+	}
+}
+
+// Close unsubscribes all the listeners.
+func (f *TxEventFeed) Close() {
+	f.scope.Close()
+}
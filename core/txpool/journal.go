@@ -0,0 +1,342 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// JournalConfig are the configuration parameters of the pool-wide transaction
+// journal, which persists the remote transactions of all subpools across node
+// restarts.
+type JournalConfig struct {
+	Path      string        // Disk journal to store remote transactions in (empty = disabled)
+	Rejournal time.Duration // Time interval to regenerate the journal
+	MaxSize   uint64        // Maximum size of the journal in bytes (0 = unlimited)
+	MaxAge    time.Duration // Maximum time since first seen for a transaction to be journaled (0 = unlimited)
+}
+
+// DefaultJournalConfig contains the default configurations for the pool-wide
+// transaction journal. The journal itself is disabled by default.
+var DefaultJournalConfig = JournalConfig{
+	Rejournal: 10 * time.Minute,
+	MaxSize:   128 * 1024 * 1024,
+	MaxAge:    3 * time.Hour,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *JournalConfig) sanitize() JournalConfig {
+	conf := *config
+	if conf.Rejournal < time.Second {
+		log.Warn("Sanitizing invalid txpool remote journal time", "provided", conf.Rejournal, "updated", time.Second)
+		conf.Rejournal = time.Second
+	}
+	return conf
+}
+
+// journalEntry is a single transaction persisted into the pool journal along
+// with the time it was first seen by the node.
+type journalEntry struct {
+	Time uint64 // Unix timestamp (in milliseconds) when the transaction was first seen
+	Tx   *types.Transaction
+}
+
+// journal is a periodically regenerated snapshot of the remote transactions
+// tracked by all the subpools, with the aim of allowing the mempool to survive
+// node restarts.
+type journal struct {
+	pool   *TxPool
+	config JournalConfig
+
+	quit chan chan error // Quit channel to tear down the rotation loop
+}
+
+// EnableJournal loads any previously persisted remote transactions into the
+// pool and starts regenerating the journal periodically in the background. The
+// loaded transactions are validated against the current head like any other
+// inbound transaction, so anything no longer valid is silently dropped.
+//
+// This method must be called at most once, right after creating the pool.
+func (p *TxPool) EnableJournal(config JournalConfig) error {
+	if p.journal != nil {
+		return errors.New("journal already enabled")
+	}
+	config = (&config).sanitize()
+
+	j := &journal{
+		pool:   p,
+		config: config,
+		quit:   make(chan chan error),
+	}
+	if err := j.load(); err != nil {
+		log.Warn("Failed to load remote transaction journal", "err", err)
+	}
+	if err := j.rotate(); err != nil {
+		log.Warn("Failed to rotate remote transaction journal", "err", err)
+	}
+	p.journal = j
+	go j.loop()
+	return nil
+}
+
+// loop periodically regenerates the journal until the pool is torn down, at
+// which point a final snapshot is written.
+func (j *journal) loop() {
+	ticker := time.NewTicker(j.config.Rejournal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.rotate(); err != nil {
+				log.Warn("Failed to rotate remote transaction journal", "err", err)
+			}
+		case errc := <-j.quit:
+			errc <- j.rotate()
+			return
+		}
+	}
+}
+
+// close terminates the rotation loop, persisting the final pool contents.
+func (j *journal) close() error {
+	errc := make(chan error)
+	j.quit <- errc
+	return <-errc
+}
+
+// load parses the journal from disk, injecting its contents into the pool.
+func (j *journal) load() error {
+	input, err := os.Open(j.config.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	added, dropped, err := j.pool.Import(input, j.config.MaxAge)
+	log.Info("Loaded remote transaction journal", "transactions", added+dropped, "dropped", dropped)
+	return err
+}
+
+// rotate regenerates the transaction journal based on the current contents of
+// the transaction pool.
+func (j *journal) rotate() error {
+	replacement, err := os.OpenFile(j.config.Path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	buffer := bufio.NewWriter(replacement)
+	journaled, err := j.pool.Export(buffer, j.config.MaxSize, j.config.MaxAge)
+	if err == nil {
+		err = buffer.Flush()
+	}
+	if err != nil {
+		replacement.Close()
+		return err
+	}
+	if err = replacement.Close(); err != nil {
+		return err
+	}
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(j.config.Path+".new", j.config.Path); err != nil {
+		return err
+	}
+	log.Debug("Regenerated remote transaction journal", "transactions", journaled)
+	return nil
+}
+
+// remotes retrieves the transactions of all non-local accounts from every
+// subpool, grouped by account and sorted by nonce.
+func (p *TxPool) remotes() map[common.Address][]*types.Transaction {
+	locals := make(map[common.Address]struct{})
+	for _, addr := range p.Locals() {
+		locals[addr] = struct{}{}
+	}
+	txs := make(map[common.Address][]*types.Transaction)
+	for addr, lazies := range p.Pending(PendingFilter{}) {
+		if _, ok := locals[addr]; ok {
+			continue
+		}
+		for _, ltx := range lazies {
+			if tx := ltx.Resolve(); tx != nil {
+				txs[addr] = append(txs[addr], tx)
+			}
+		}
+	}
+	_, queued := p.Content()
+	for addr, set := range queued {
+		if _, ok := locals[addr]; ok {
+			continue
+		}
+		txs[addr] = append(txs[addr], set...)
+	}
+	for _, set := range txs {
+		sort.Sort(types.TxByNonce(set))
+	}
+	return txs
+}
+
+// Export writes the remote transactions currently tracked by the pool into the
+// given writer as an RLP stream, returning the number of exported transactions.
+//
+// If maxAge is non-zero, an account's transactions are only exported up to the
+// first one seen longer ago than the limit. If maxSize is non-zero, accounts are
+// visited round-robin, lowest nonce first, until the size limit is reached, so
+// that every account retains an executable prefix of its transactions.
+func (p *TxPool) Export(w io.Writer, maxSize uint64, maxAge time.Duration) (int, error) {
+	var (
+		all   = p.remotes()
+		addrs = make([]common.Address, 0, len(all))
+		now   = time.Now()
+	)
+	for addr, txs := range all {
+		if maxAge > 0 {
+			for i, tx := range txs {
+				if now.Sub(tx.Time()) > maxAge {
+					txs = txs[:i]
+					break
+				}
+			}
+			all[addr] = txs
+		}
+		if len(txs) > 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(addrs[i][:], addrs[j][:]) < 0
+	})
+	var (
+		size     uint64
+		exported int
+	)
+	for round := 0; len(addrs) > 0; round++ {
+		var next []common.Address
+		for _, addr := range addrs {
+			tx := all[addr][round]
+			blob, err := rlp.EncodeToBytes(&journalEntry{
+				Time: uint64(tx.Time().UnixMilli()),
+				Tx:   tx,
+			})
+			if err != nil {
+				return exported, err
+			}
+			if maxSize > 0 && size+uint64(len(blob)) > maxSize {
+				// Size cap reached, skip the account's remaining transactions
+				continue
+			}
+			if _, err := w.Write(blob); err != nil {
+				return exported, err
+			}
+			size += uint64(len(blob))
+			exported++
+
+			if round+1 < len(all[addr]) {
+				next = append(next, addr)
+			}
+		}
+		addrs = next
+	}
+	return exported, nil
+}
+
+// Import parses an RLP stream of transactions previously written by Export and
+// injects them into the pool as remote transactions. Transactions first seen
+// longer ago than maxAge (if non-zero) are skipped. The number of added and the
+// number of dropped transactions are returned.
+func (p *TxPool) Import(r io.Reader, maxAge time.Duration) (int, int, error) {
+	var (
+		stream = rlp.NewStream(r, 0)
+		now    = time.Now()
+
+		added, dropped int
+		failure        error
+		batch          types.Transactions
+	)
+	// Create a method to load a limited batch of transactions and bump the
+	// appropriate progress counters. Then use this method to load all the
+	// journaled transactions in small-ish batches.
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range p.Add(txs, false, false) {
+			if err != nil {
+				log.Debug("Failed to add journaled transaction", "err", err)
+				dropped++
+			} else {
+				added++
+			}
+		}
+	}
+	for {
+		// Parse the next transaction and terminate on error
+		entry := new(journalEntry)
+		if err := stream.Decode(entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		seen := time.UnixMilli(int64(entry.Time))
+		if maxAge > 0 && now.Sub(seen) > maxAge {
+			dropped++
+			continue
+		}
+		entry.Tx.SetTime(seen)
+
+		if batch = append(batch, entry.Tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	if batch.Len() > 0 {
+		loadBatch(batch)
+	}
+	return added, dropped, failure
+}
+
+// ImportSnapshot injects the transactions of a snapshot previously created by
+// Export into the pool. Unlike Import, the snapshot is decoded in its entirety
+// before adding anything, so a truncated or corrupt snapshot is rejected as a
+// whole instead of being partially imported.
+func (p *TxPool) ImportSnapshot(blob []byte, maxAge time.Duration) (int, int, error) {
+	stream := rlp.NewStream(bytes.NewReader(blob), uint64(len(blob)))
+	for {
+		if err := stream.Decode(new(journalEntry)); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, 0, err
+		}
+	}
+	return p.Import(bytes.NewReader(blob), maxAge)
+}
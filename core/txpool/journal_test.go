@@ -0,0 +1,344 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var testSigner = types.LatestSigner(params.TestChainConfig)
+
+// testChain is a mock blockchain that never moves its head.
+type testChain struct {
+	head *types.Header
+	feed event.Feed
+}
+
+func (c *testChain) CurrentBlock() *types.Header { return c.head }
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// testSubPool is a trivial subpool accepting any signed transaction and
+// considering all of them pending.
+type testSubPool struct {
+	lock   sync.Mutex
+	txs    map[common.Address][]*types.Transaction
+	locals map[common.Address]struct{}
+	feed   event.Feed
+	events event.Feed
+}
+
+func newTestSubPool() *testSubPool {
+	return &testSubPool{
+		txs:    make(map[common.Address][]*types.Transaction),
+		locals: make(map[common.Address]struct{}),
+	}
+}
+
+func (p *testSubPool) Filter(tx *types.Transaction) bool { return true }
+func (p *testSubPool) Init(gasTip uint64, head *types.Header, reserve AddressReserver) error {
+	return nil
+}
+func (p *testSubPool) Close() error                         { return nil }
+func (p *testSubPool) Reset(oldHead, newHead *types.Header) {}
+func (p *testSubPool) SetGasTip(tip *big.Int)               {}
+func (p *testSubPool) Has(hash common.Hash) bool            { return p.Get(hash) != nil }
+
+func (p *testSubPool) Get(hash common.Hash) *types.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, txs := range p.txs {
+		for _, tx := range txs {
+			if tx.Hash() == hash {
+				return tx
+			}
+		}
+	}
+	return nil
+}
+
+func (p *testSubPool) Add(txs []*types.Transaction, local bool, sync bool) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		from, err := types.Sender(testSigner, tx)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		p.txs[from] = append(p.txs[from], tx)
+		if local {
+			p.locals[from] = struct{}{}
+		}
+	}
+	return errs
+}
+
+func (p *testSubPool) Pending(filter PendingFilter) map[common.Address][]*LazyTransaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pending := make(map[common.Address][]*LazyTransaction)
+	for addr, txs := range p.txs {
+		for _, tx := range txs {
+			pending[addr] = append(pending[addr], &LazyTransaction{Pool: p, Hash: tx.Hash(), Tx: tx, Time: tx.Time()})
+		}
+	}
+	return pending
+}
+
+func (p *testSubPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	return p.feed.Subscribe(ch)
+}
+func (p *testSubPool) SubscribeTxEvents(ch chan<- []*TxEvent) event.Subscription {
+	return p.events.Subscribe(ch)
+}
+func (p *testSubPool) Nonce(addr common.Address) uint64 { return 0 }
+func (p *testSubPool) Stats() (int, int)                { return 0, 0 }
+
+func (p *testSubPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return nil, nil
+}
+
+func (p *testSubPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return nil, nil
+}
+
+func (p *testSubPool) Locals() []common.Address {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var locals []common.Address
+	for addr := range p.locals {
+		locals = append(locals, addr)
+	}
+	return locals
+}
+
+func (p *testSubPool) Status(hash common.Hash) TxStatus { return TxStatusUnknown }
+
+func (p *testSubPool) count() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var count int
+	for _, txs := range p.txs {
+		count += len(txs)
+	}
+	return count
+}
+
+func newTestPool(t *testing.T) (*TxPool, *testSubPool) {
+	t.Helper()
+
+	subpool := newTestSubPool()
+	pool, err := New(1, &testChain{head: &types.Header{Number: big.NewInt(0)}}, []SubPool{subpool})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	return pool, subpool
+}
+
+func makeTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, seen time.Time) *types.Transaction {
+	t.Helper()
+
+	tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), testSigner, key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	tx.SetTime(seen)
+	return tx
+}
+
+// Tests that exporting and re-importing a snapshot retains all the remote
+// transactions with their first seen timestamps, and skips local ones.
+func TestJournalExportImport(t *testing.T) {
+	src, _ := newTestPool(t)
+	defer src.Close()
+
+	var (
+		remote, _ = crypto.GenerateKey()
+		local, _  = crypto.GenerateKey()
+		seen      = time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	)
+	for i := uint64(0); i < 4; i++ {
+		src.Add([]*types.Transaction{makeTx(t, remote, i, seen)}, false, false)
+	}
+	src.Add([]*types.Transaction{makeTx(t, local, 0, seen)}, true, false)
+
+	var blob bytes.Buffer
+	if n, err := src.Export(&blob, 0, 0); err != nil || n != 4 {
+		t.Fatalf("export mismatch: have %d/%v, want %d/nil", n, err, 4)
+	}
+	dst, subpool := newTestPool(t)
+	defer dst.Close()
+
+	added, dropped, err := dst.Import(&blob, 0)
+	if err != nil || added != 4 || dropped != 0 {
+		t.Fatalf("import mismatch: have %d/%d/%v, want %d/%d/nil", added, dropped, err, 4, 0)
+	}
+	for _, txs := range subpool.txs {
+		for _, tx := range txs {
+			if !tx.Time().Equal(seen) {
+				t.Errorf("tx %x: first seen time mismatch: have %v, want %v", tx.Hash(), tx.Time(), seen)
+			}
+		}
+	}
+}
+
+// Tests that truncated or corrupt snapshots are rejected as a whole, without
+// importing the transactions preceding the damage.
+func TestJournalImportCorruptSnapshot(t *testing.T) {
+	src, _ := newTestPool(t)
+	defer src.Close()
+
+	key, _ := crypto.GenerateKey()
+	for i := uint64(0); i < 4; i++ {
+		src.Add([]*types.Transaction{makeTx(t, key, i, time.Now())}, false, false)
+	}
+	var blob bytes.Buffer
+	if _, err := src.Export(&blob, 0, 0); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	corrupt := bytes.Clone(blob.Bytes())
+	corrupt = append(corrupt, 0xff) // Dangling list header
+
+	for name, snapshot := range map[string][]byte{
+		"truncated": blob.Bytes()[:blob.Len()-1],
+		"corrupt":   corrupt,
+	} {
+		dst, subpool := newTestPool(t)
+		if _, _, err := dst.ImportSnapshot(snapshot, 0); err == nil {
+			t.Errorf("%s: snapshot accepted", name)
+		}
+		if n := subpool.count(); n != 0 {
+			t.Errorf("%s: imported %d transactions", name, n)
+		}
+		dst.Close()
+	}
+	// An intact snapshot is imported fully
+	dst, _ := newTestPool(t)
+	defer dst.Close()
+
+	if added, dropped, err := dst.ImportSnapshot(blob.Bytes(), 0); err != nil || added != 4 || dropped != 0 {
+		t.Fatalf("import mismatch: have %d/%d/%v, want %d/%d/nil", added, dropped, err, 4, 0)
+	}
+}
+
+// Tests that the export limits drop stale transactions and cap the size while
+// retaining a nonce-contiguous prefix from every account.
+func TestJournalExportLimits(t *testing.T) {
+	pool, _ := newTestPool(t)
+	defer pool.Close()
+
+	var (
+		keys = make([]*ecdsa.PrivateKey, 3)
+		now  = time.Now()
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		for j := uint64(0); j < 4; j++ {
+			pool.Add([]*types.Transaction{makeTx(t, keys[i], j, now)}, false, false)
+		}
+	}
+	// Add an account with a stale transaction at nonce 1, cutting off the rest
+	stale, _ := crypto.GenerateKey()
+	pool.Add([]*types.Transaction{
+		makeTx(t, stale, 0, now),
+		makeTx(t, stale, 1, now.Add(-2*time.Hour)),
+		makeTx(t, stale, 2, now),
+	}, false, false)
+
+	var blob bytes.Buffer
+	if n, _ := pool.Export(&blob, 0, time.Hour); n != 13 {
+		t.Fatalf("age limited export mismatch: have %d, want %d", n, 13)
+	}
+	// Cap the size to fit 7 transactions, expect 3 accounts with 2 nonces and
+	// one with a single one
+	var (
+		stream = rlp.NewStream(bytes.NewReader(blob.Bytes()), 0)
+		size   uint64
+	)
+	for {
+		raw, err := stream.Raw()
+		if err != nil {
+			break
+		}
+		size = max(size, uint64(len(raw)))
+	}
+	size *= 7
+	blob.Reset()
+	if n, _ := pool.Export(&blob, size, time.Hour); n != 7 {
+		t.Fatalf("size limited export mismatch: have %d, want %d", n, 7)
+	}
+	dst, subpool := newTestPool(t)
+	defer dst.Close()
+
+	dst.Import(&blob, 0)
+	for addr, txs := range subpool.txs {
+		for i, tx := range txs {
+			if tx.Nonce() != uint64(i) {
+				t.Errorf("account %x: nonce gap at %d: have %d", addr, i, tx.Nonce())
+			}
+		}
+	}
+}
+
+// Tests that the journal persists the pool across restarts.
+func TestJournalPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	pool, _ := newTestPool(t)
+	if err := pool.EnableJournal(JournalConfig{Path: path, Rejournal: time.Hour}); err != nil {
+		t.Fatalf("failed to enable journal: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	for i := uint64(0); i < 3; i++ {
+		pool.Add([]*types.Transaction{makeTx(t, key, i, time.Now())}, false, false)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("failed to close pool: %v", err)
+	}
+	pool, subpool := newTestPool(t)
+	defer pool.Close()
+
+	if err := pool.EnableJournal(JournalConfig{Path: path, Rejournal: time.Hour}); err != nil {
+		t.Fatalf("failed to enable journal: %v", err)
+	}
+	if count := subpool.count(); count != 3 {
+		t.Fatalf("journaled transaction count mismatch: have %d, want %d", count, 3)
+	}
+}
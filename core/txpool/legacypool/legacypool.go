@@ -205,6 +205,7 @@ type LegacyPool struct {
 	chain       BlockChain
 	gasTip      atomic.Pointer[uint256.Int]
 	txFeed      event.Feed
+	txEvents    txpool.TxEventFeed
 	signer      types.Signer
 	mu          sync.RWMutex
 
@@ -222,6 +223,8 @@ type LegacyPool struct {
 	all     *lookup                      // All transactions to allow lookups
 	priced  *pricedList                  // All transactions sorted by price
 
+	inclusions map[common.Hash]struct{} // Transactions included by the head being reset to, for event reporting
+
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
 	queueTxEventCh  chan *types.Transaction
@@ -378,12 +381,14 @@ func (pool *LegacyPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.txEvents.Queue(txpool.TxEventNonceGap, tx.Hash(), addr, tx.Nonce())
 						pool.removeTx(tx.Hash(), true, true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.txEvents.Flush()
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -407,6 +412,7 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	pool.txEvents.Close()
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -428,9 +434,17 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs
 	return pool.txFeed.Subscribe(ch)
 }
 
+// SubscribeTxEvents registers a subscription for transaction lifecycle events,
+// announcing why transactions leave (or reenter) the pool.
+func (pool *LegacyPool) SubscribeTxEvents(ch chan<- []*txpool.TxEvent) event.Subscription {
+	return pool.txEvents.Subscribe(ch)
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
 // new transaction, and drops all transactions below this threshold.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
+	defer pool.txEvents.Flush()
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -444,6 +458,10 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(tip)
 		for _, tx := range drop {
+			if pool.txEvents.Active() {
+				from, _ := types.Sender(pool.signer, tx) // already validated during insertion
+				pool.txEvents.Queue(txpool.TxEventUnderpriced, tx.Hash(), from, tx.Nonce())
+			}
 			pool.removeTx(tx.Hash(), false, true)
 		}
 		pool.priced.Removed(len(drop))
@@ -766,6 +784,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			underpricedTxMeter.Mark(1)
 
 			sender, _ := types.Sender(pool.signer, tx)
+			pool.txEvents.Queue(txpool.TxEventUnderpriced, tx.Hash(), sender, tx.Nonce())
 			dropped := pool.removeTx(tx.Hash(), false, sender != from) // Don't unreserve the sender of the tx being added if last from the acc
 
 			pool.changesSinceReorg += dropped
@@ -785,6 +804,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.txEvents.QueueReplaced(old.Hash(), from, old.Nonce(), hash)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
@@ -860,6 +880,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.txEvents.QueueReplaced(old.Hash(), from, old.Nonce(), hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -909,6 +930,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.txEvents.QueueReplaced(hash, addr, tx.Nonce(), list.txs.Get(tx.Nonce()).Hash())
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -916,6 +938,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.txEvents.QueueReplaced(old.Hash(), addr, old.Nonce(), hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
@@ -1009,6 +1032,7 @@ func (pool *LegacyPool) Add(txs []*types.Transaction, local, sync bool) []error
 	pool.mu.Lock()
 	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
 	pool.mu.Unlock()
+	pool.txEvents.Flush()
 
 	var nilSlot = 0
 	for _, err := range newErrs {
@@ -1304,6 +1328,8 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 				pool.priced.Reheap()
 			}
 		}
+		pool.inclusions = nil
+
 		// Update all accounts to the latest known pending nonce
 		nonces := make(map[common.Address]uint64, len(pool.pending))
 		for addr, list := range pool.pending {
@@ -1320,6 +1346,9 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	pool.changesSinceReorg = 0 // Reset change counter
 	pool.mu.Unlock()
 
+	// Notify subsystems for evicted transactions
+	pool.txEvents.Flush()
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1341,7 +1370,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 // of the transaction pool is valid with regard to the chain state.
 func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var reinject, included types.Transactions
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
@@ -1380,7 +1409,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 					log.Warn("Transaction pool reset with missing new head", "number", newHead.Number, "hash", newHead.Hash())
 					return
 				}
-				var discarded, included types.Transactions
+				var discarded types.Transactions
 				for rem.NumberU64() > add.NumberU64() {
 					discarded = append(discarded, rem.Transactions()...)
 					if rem = pool.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
@@ -1417,6 +1446,17 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 			}
 		}
 	}
+	// If someone is listening for lifecycle events, track the transactions
+	// included by the new head to tell them apart from invalidated ones
+	if pool.txEvents.Active() && oldHead != nil && oldHead.Hash() == newHead.ParentHash {
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included = block.Transactions()
+		}
+	}
+	pool.inclusions = make(map[common.Hash]struct{}, len(included))
+	for _, tx := range included {
+		pool.inclusions[tx.Hash()] = struct{}{}
+	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
 		newHead = pool.chain.CurrentBlock() // Special case during testing
@@ -1433,7 +1473,23 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	errs, _ := pool.addTxsLocked(reinject, false)
+	for i, tx := range reinject {
+		if errs[i] == nil && pool.txEvents.Active() {
+			from, _ := types.Sender(pool.signer, tx) // already validated during insertion
+			pool.txEvents.Queue(txpool.TxEventReinjected, tx.Hash(), from, tx.Nonce())
+		}
+	}
+}
+
+// staleEventKind returns the lifecycle event kind for a transaction dropped due
+// to its nonce being used up on chain: whether it was included or invalidated
+// by another transaction.
+func (pool *LegacyPool) staleEventKind(tx *types.Transaction) txpool.TxEventKind {
+	if _, ok := pool.inclusions[tx.Hash()]; ok {
+		return txpool.TxEventIncluded
+	}
+	return txpool.TxEventInvalidated
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1455,6 +1511,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.txEvents.Queue(pool.staleEventKind(tx), hash, addr, tx.Nonce())
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1462,6 +1519,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.txEvents.Queue(txpool.TxEventInvalidated, hash, addr, tx.Nonce())
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1484,6 +1542,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.txEvents.Queue(txpool.TxEventAccountLimit, hash, addr, tx.Nonce())
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1549,6 +1608,7 @@ func (pool *LegacyPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.txEvents.Queue(txpool.TxEventAccountLimit, hash, offenders[i], tx.Nonce())
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1576,6 +1636,7 @@ func (pool *LegacyPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.txEvents.Queue(txpool.TxEventAccountLimit, hash, addr, tx.Nonce())
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1622,6 +1683,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.txEvents.Queue(txpool.TxEventAccountLimit, tx.Hash(), addr.address, tx.Nonce())
 				pool.removeTx(tx.Hash(), true, true)
 			}
 			drop -= size
@@ -1631,6 +1693,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.txEvents.Queue(txpool.TxEventAccountLimit, txs[i].Hash(), addr.address, txs[i].Nonce())
 			pool.removeTx(txs[i].Hash(), true, true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1656,6 +1719,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.txEvents.Queue(pool.staleEventKind(tx), hash, addr, tx.Nonce())
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1664,6 +1728,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.txEvents.Queue(txpool.TxEventInvalidated, hash, addr, tx.Nonce())
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -2232,6 +2232,84 @@ func TestReplacement(t *testing.T) {
 	}
 }
 
+// Tests that transaction lifecycle events are emitted when transactions are
+// replaced, evicted due to a raised gas tip or invalidated by the chain state.
+func TestTxLifecycleEvents(t *testing.T) {
+	t.Parallel()
+
+	// Create the pool to test the event reporting with
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	pool.Init(testTxPoolConfig.PriceLimit, blockchain.CurrentBlock(), makeAddressReserver())
+	defer pool.Close()
+
+	events := make(chan []*txpool.TxEvent, 32)
+	sub := pool.SubscribeTxEvents(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+
+	expect := func(kind txpool.TxEventKind, tx *types.Transaction, replacement *types.Transaction) {
+		t.Helper()
+
+		select {
+		case batch := <-events:
+			if len(batch) != 1 {
+				t.Fatalf("event batch size mismatch: have %d, want %d", len(batch), 1)
+			}
+			ev := batch[0]
+			if ev.Kind != kind || ev.Hash != tx.Hash() || ev.From != from || ev.Nonce != tx.Nonce() {
+				t.Fatalf("event mismatch: have %v/%x/%x/%d, want %v/%x/%x/%d", ev.Kind, ev.Hash, ev.From, ev.Nonce, kind, tx.Hash(), from, tx.Nonce())
+			}
+			if replacement == nil && ev.ReplacedBy != nil {
+				t.Fatalf("unexpected replacement: %x", *ev.ReplacedBy)
+			}
+			if replacement != nil && (ev.ReplacedBy == nil || *ev.ReplacedBy != replacement.Hash()) {
+				t.Fatalf("replacement mismatch: have %v, want %x", ev.ReplacedBy, replacement.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%v event not fired", kind)
+		}
+	}
+	// Replace a pending transaction and a queued one
+	pending, replacer := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(pending); err != nil {
+		t.Fatalf("failed to add pending transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(replacer); err != nil {
+		t.Fatalf("failed to replace pending transaction: %v", err)
+	}
+	expect(txpool.TxEventReplaced, pending, replacer)
+
+	queued, requeued := pricedTransaction(2, 100000, big.NewInt(10), key), pricedTransaction(2, 100000, big.NewInt(20), key)
+	if err := pool.addRemoteSync(queued); err != nil {
+		t.Fatalf("failed to add queued transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(requeued); err != nil {
+		t.Fatalf("failed to replace queued transaction: %v", err)
+	}
+	expect(txpool.TxEventReplaced, queued, requeued)
+
+	// Raise the minimum tip, evicting the cheap pending transaction
+	pool.SetGasTip(big.NewInt(10))
+	expect(txpool.TxEventUnderpriced, replacer, nil)
+
+	// Use up the nonce of the queued transaction on chain, invalidating it
+	testSetNonce(pool, from, 3)
+	<-pool.requestReset(nil, nil)
+	expect(txpool.TxEventInvalidated, requeued, nil)
+
+	select {
+	case batch := <-events:
+		t.Fatalf("unexpected events: %d", len(batch))
+	default:
+	}
+}
+
 // Tests that the pool rejects replacement dynamic fee transactions that don't
 // meet the minimum price bump required.
 func TestReplacementDynamicFee(t *testing.T) {
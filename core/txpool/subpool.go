@@ -140,6 +140,10 @@ type SubPool interface {
 	// or also for reorged out ones.
 	SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription
 
+	// SubscribeTxEvents subscribes to transaction lifecycle events, announcing
+	// why transactions leave (or reenter) the subpool.
+	SubscribeTxEvents(ch chan<- []*TxEvent) event.Subscription
+
 	// Nonce returns the next nonce of an account, with all transactions executable
 	// by the pool already applied on top.
 	Nonce(addr common.Address) uint64
@@ -38,6 +38,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -143,11 +144,23 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 		}
 		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
+	block := b.eth.blockchain.GetBlockByNumber(uint64(number))
+	if block == nil && b.eth.blockchain.HistoryPruned(uint64(number)) {
+		return nil, ethapi.NewHistoryPrunedError()
+	}
+	return block, nil
 }
 
 func (b *EthAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(hash), nil
+	header := b.eth.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil
+	}
+	block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
+	if block == nil && b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+		return nil, ethapi.NewHistoryPrunedError()
+	}
+	return block, nil
 }
 
 // GetBody returns body of a block. It does not resolve special block numbers.
@@ -158,6 +171,9 @@ func (b *EthAPIBackend) GetBody(ctx context.Context, hash common.Hash, number rp
 	if body := b.eth.blockchain.GetBody(hash); body != nil {
 		return body, nil
 	}
+	if b.eth.blockchain.HistoryPruned(uint64(number)) {
+		return nil, ethapi.NewHistoryPrunedError()
+	}
 	return nil, errors.New("block body not found")
 }
 
@@ -175,6 +191,9 @@ func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash r
 		}
 		block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
 		if block == nil {
+			if b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+				return nil, ethapi.NewHistoryPrunedError()
+			}
 			return nil, errors.New("header found, but block body is missing")
 		}
 		return block, nil
@@ -235,7 +254,13 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	receipts := b.eth.blockchain.GetReceiptsByHash(hash)
+	if receipts == nil {
+		if header := b.eth.blockchain.GetHeaderByHash(hash); header != nil && b.eth.blockchain.HistoryPruned(header.Number.Uint64()) {
+			return nil, ethapi.NewHistoryPrunedError()
+		}
+	}
+	return receipts, nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -284,7 +309,15 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.eth.txPool.Add([]*types.Transaction{signedTx}, true, false)[0]
+	// Mark the transaction as local before adding it to the pool, so that its
+	// propagation policy applies as soon as the pool announces it.
+	b.eth.handler.txprop.markLocal(signedTx.Hash())
+	// A resubmission of a known transaction must not drop its earlier mark.
+	err := b.eth.txPool.Add([]*types.Transaction{signedTx}, true, false)[0]
+	if err != nil && !errors.Is(err, txpool.ErrAlreadyKnown) {
+		b.eth.handler.txprop.unmarkLocal(signedTx.Hash())
+	}
+	return err
 }
 
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -300,6 +333,12 @@ func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	return txs, nil
 }
 
+// PoolPending retrieves the lazy handles of the pending pool transactions,
+// without resolving the transactions themselves.
+func (b *EthAPIBackend) PoolPending() map[common.Address][]*txpool.LazyTransaction {
+	return b.eth.txPool.Pending(txpool.PendingFilter{})
+}
+
 func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction {
 	return b.eth.txPool.Get(hash)
 }
@@ -349,6 +388,10 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.txPool.SubscribeTransactions(ch, true)
 }
 
+func (b *EthAPIBackend) SubscribeTxPoolEvents(ch chan<- []*txpool.TxEvent) event.Subscription {
+	return b.eth.txPool.SubscribeTxEvents(ch)
+}
+
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
 	prog := b.eth.Downloader().Progress()
 	if txProg, err := b.eth.blockchain.TxIndexProgress(); err == nil {
@@ -366,6 +409,14 @@ func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount uint64, lastB
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
+func (b *EthAPIBackend) MempoolFeeHistory(ctx context.Context, rewardPercentiles []float64) ([]*big.Int, *big.Int, error) {
+	return b.gpo.MempoolFeeHistory(ctx, rewardPercentiles)
+}
+
+func (b *EthAPIBackend) SuggestFees(ctx context.Context) (*gasprice.SuggestedFees, error) {
+	return b.gpo.SuggestFees(ctx)
+}
+
 func (b *EthAPIBackend) BlobBaseFee(ctx context.Context) *big.Int {
 	if excess := b.CurrentHeader().ExcessBlobGas; excess != nil {
 		return eip4844.CalcBlobFee(*excess)
@@ -416,6 +467,10 @@ func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 	}
 }
 
+func (b *EthAPIBackend) LogIndexTail() *uint64 {
+	return b.eth.blockchain.LogIndexTail()
+}
+
 func (b *EthAPIBackend) Engine() consensus.Engine {
 	return b.eth.engine
 }
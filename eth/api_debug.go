@@ -26,8 +26,10 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -443,3 +445,28 @@ func (api *DebugAPI) GetTrieFlushInterval() (string, error) {
 	}
 	return api.eth.blockchain.GetTrieFlushInterval().String(), nil
 }
+
+// StartStatePruning launches the online state pruning in the background, or
+// resumes the paused one. The default configuration is applied if not given.
+func (api *DebugAPI) StartStatePruning(config *pruner.OnlineConfig) error {
+	if config == nil {
+		config = &pruner.DefaultOnlineConfig
+	}
+	return api.eth.blockchain.StartStatePruning(*config)
+}
+
+// PauseStatePruning pauses the running online state pruning, which can be
+// resumed later by StartStatePruning.
+func (api *DebugAPI) PauseStatePruning() error {
+	return api.eth.blockchain.PauseStatePruning()
+}
+
+// StatePruningProgress returns the progress report of the online state pruning.
+func (api *DebugAPI) StatePruningProgress() (*pruner.OnlineProgress, error) {
+	return api.eth.blockchain.StatePruningProgress()
+}
+
+// SnapSyncProgress returns a structured report on the progress of the snap sync.
+func (api *DebugAPI) SnapSyncProgress() *snap.SyncStatus {
+	return api.eth.Downloader().SnapSyncer.Status()
+}
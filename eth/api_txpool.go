@@ -0,0 +1,66 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TxPoolJournalAPI offers methods to export and import snapshots of the remote
+// transactions tracked by the transaction pool.
+type TxPoolJournalAPI struct {
+	eth *Ethereum
+}
+
+// NewTxPoolJournalAPI creates a new instance of TxPoolJournalAPI.
+func NewTxPoolJournalAPI(eth *Ethereum) *TxPoolJournalAPI {
+	return &TxPoolJournalAPI{eth: eth}
+}
+
+// ImportResult is the outcome of a transaction pool snapshot import.
+type ImportResult struct {
+	Added   hexutil.Uint `json:"added"`
+	Dropped hexutil.Uint `json:"dropped"`
+}
+
+// ExportTransactions returns an RLP encoded snapshot of the remote transactions
+// currently tracked by all the subpools, in the same format as the journal.
+func (api *TxPoolJournalAPI) ExportTransactions() (hexutil.Bytes, error) {
+	var (
+		config = api.eth.config.TxPoolJournal
+		buffer = new(bytes.Buffer)
+	)
+	if _, err := api.eth.TxPool().Export(buffer, config.MaxSize, config.MaxAge); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ImportTransactions injects the transactions from a snapshot created by
+// ExportTransactions into the pool, validating them against the current head.
+// A truncated or corrupt snapshot is rejected without importing anything.
+func (api *TxPoolJournalAPI) ImportTransactions(snapshot hexutil.Bytes) (*ImportResult, error) {
+	config := api.eth.config.TxPoolJournal
+
+	added, dropped, err := api.eth.TxPool().ImportSnapshot(snapshot, config.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportResult{Added: hexutil.Uint(added), Dropped: hexutil.Uint(dropped)}, nil
+}
@@ -45,6 +45,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/s3"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
@@ -115,7 +116,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", ethconfig.Defaults.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(ethconfig.Defaults.Miner.GasPrice)
 	}
-	if config.NoPruning && config.TrieDirtyCache > 0 {
+	// The path scheme retains state histories instead of the trie nodes in
+	// archive mode, the dirty cache is kept for aggregating the writes.
+	if config.NoPruning && config.TrieDirtyCache > 0 && config.StateScheme != rawdb.PathScheme {
 		if config.SnapshotCache > 0 {
 			config.TrieCleanCache += config.TrieDirtyCache * 3 / 5
 			config.SnapshotCache += config.TrieDirtyCache * 2 / 5
@@ -124,10 +127,21 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		}
 		config.TrieDirtyCache = 0
 	}
+	if len(config.PartialState) > 0 && config.NoPruning {
+		return nil, errors.New("partial state is not supported in archive mode")
+	}
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
 	// Assemble the Ethereum object
-	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+	var remote ethdb.ObjectStore
+	if config.DatabaseRemote != "" {
+		s3config, err := s3.ParseURL(config.DatabaseRemote)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ancient remote store: %v", err)
+		}
+		remote = s3.New(s3config)
+	}
+	chainDb, err := stack.OpenDatabaseWithRemoteFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, remote, "eth/db/chaindata/", false)
 	if err != nil {
 		return nil, err
 	}
@@ -198,7 +212,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
 			StateHistory:        config.StateHistory,
+			StateHistoryIndex:   config.StateHistoryIndex,
 			StateScheme:         scheme,
+			LogIndex:            config.LogIndex,
+			LogHistory:          config.LogHistory,
+			PartialState:        config.PartialState,
+			HistoryExpiry:       config.HistoryExpiry,
+			HistoryArchive:      config.HistoryArchive,
 		}
 	)
 	if config.VMTrace != "" {
@@ -244,6 +264,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	if config.TxPoolJournal.Path != "" {
+		config.TxPoolJournal.Path = stack.ResolvePath(config.TxPoolJournal.Path)
+		if err := eth.txPool.EnableJournal(config.TxPoolJournal); err != nil {
+			return nil, err
+		}
+	}
 	// Permit the downloader to use the trie cache allowance during fast sync
 	cacheLimit := cacheConfig.TrieCleanLimit + cacheConfig.TrieDirtyLimit + cacheConfig.SnapshotLimit
 	if eth.handler, err = newHandler(&handlerConfig{
@@ -256,9 +282,15 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		BloomCache:     uint64(cacheLimit),
 		EventMux:       eth.eventMux,
 		RequiredBlocks: config.RequiredBlocks,
+		TxPropagation:  config.TxPropagation,
 	}); err != nil {
 		return nil, err
 	}
+	// The partial state node resolves the storage of the untracked contracts
+	// touched by the imported blocks from its snap peers.
+	if eth.blockchain.PartialState() != nil {
+		eth.blockchain.SetPartialResolver(eth.handler.downloader.SnapSyncer.ResolveNode)
+	}
 
 	eth.miner = miner.New(eth, config.Miner, eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
@@ -340,6 +372,9 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "net",
 			Service:   s.netRPCService,
+		}, {
+			Namespace: "txpool",
+			Service:   NewTxPoolJournalAPI(s),
 		},
 	}...)
 }
@@ -394,6 +429,11 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	// Keep the relays of private transactions connected
+	for _, node := range s.handler.txprop.relays {
+		s.p2pServer.AddPeer(node)
+	}
 	return nil
 }
 
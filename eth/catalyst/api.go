@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -629,6 +630,13 @@ func (api *ConsensusAPI) newPayload(params engine.ExecutableData, versionedHashe
 	}
 	log.Trace("Inserting block without sethead", "hash", block.Hash(), "number", block.Number())
 	if err := api.eth.BlockChain().InsertBlockWithoutSetHead(block); err != nil {
+		// The partial state node might fail to resolve the storage of untracked
+		// contracts from its peers, the block is not invalid in that case.
+		if errors.Is(err, state.ErrStateUnavailable) {
+			api.remoteBlocks.put(block.Hash(), block.Header())
+			log.Warn("Partial state unavailable, ignoring new payload", "number", block.Number(), "hash", block.Hash(), "err", err)
+			return engine.PayloadStatusV1{Status: engine.SYNCING}, nil
+		}
 		log.Warn("NewPayloadV1: inserting block failed", "error", err)
 
 		api.invalidLock.Lock()
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
@@ -81,8 +82,19 @@ var (
 	ErrMergeTransition         = errors.New("legacy sync reached the merge")
 )
 
-// peerDropFn is a callback type for dropping a peer detected as malicious.
-type peerDropFn func(id string)
+// peerDropFn is a callback type for dropping a peer. The reason is set if the
+// peer was proven to misbehave, such as by delivering an invalid chain, and is
+// nil if it was dropped for being unhelpful, such as by timing out.
+type peerDropFn func(id string, reason error)
+
+// misbehaviour returns the given sync failure if it proves the remote peer to
+// be misbehaving, or nil if the peer might have been just slow or out of sync.
+func misbehaviour(err error) error {
+	if errors.Is(err, errInvalidChain) || errors.Is(err, errBadPeer) || errors.Is(err, errInvalidAncestor) {
+		return err
+	}
+	return nil
+}
 
 // badBlockFn is a callback for the async beacon sync to notify the caller that
 // the origin header requested to sync to, produced a chain with a bad block.
@@ -213,6 +225,10 @@ type BlockChain interface {
 	// TrieDB retrieves the low level trie database used for interacting
 	// with trie nodes.
 	TrieDB() *triedb.Database
+
+	// PartialState returns the contracts whose storage is tracked by the
+	// partial state node, nil if the full state is kept.
+	PartialState() *state.PartialState
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
@@ -230,7 +246,7 @@ func New(stateDb ethdb.Database, mux *event.TypeMux, chain BlockChain, lightchai
 		dropPeer:       dropPeer,
 		headerProcCh:   make(chan *headerTask, 1),
 		quitCh:         make(chan struct{}),
-		SnapSyncer:     snap.NewSyncer(stateDb, chain.TrieDB().Scheme()),
+		SnapSyncer:     snap.NewPartialSyncer(stateDb, chain.TrieDB().Scheme(), chain.PartialState()),
 		stateSyncStart: make(chan *stateSync),
 		syncStartBlock: chain.CurrentSnapBlock().Number.Uint64(),
 	}
@@ -266,6 +282,7 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		log.Error("Unknown downloader chain/mode combo", "light", d.lightchain != nil, "full", d.blockchain != nil, "mode", mode)
 	}
 	progress, pending := d.SnapSyncer.Progress()
+	status := d.SnapSyncer.Status()
 
 	return ethereum.SyncProgress{
 		StartingBlock:       d.syncStatsChainOrigin,
@@ -283,6 +300,11 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 		HealedBytecodeBytes: uint64(progress.BytecodeHealBytes),
 		HealingTrienodes:    pending.TrienodeHeal,
 		HealingBytecode:     pending.BytecodeHeal,
+		SyncedAccountRange:  uint64(status.AccountRangeProgress * 100),
+		StorageBacklog:      uint64(status.StorageBacklog + status.StorageTasks),
+		BytecodeBacklog:     uint64(status.BytecodeBacklog),
+		HealingBacklog:      uint64(status.HealingBacklog),
+		StateSyncETA:        uint64(status.ETA),
 	}
 }
 
@@ -344,7 +366,7 @@ func (d *Downloader) LegacySync(id string, head common.Hash, td, ttd *big.Int, m
 			// Timeouts can occur if e.g. compaction hits at the wrong time, and can be ignored
 			log.Warn("Downloader wants to drop peer, but peerdrop-function is not set", "peer", id)
 		} else {
-			d.dropPeer(id)
+			d.dropPeer(id, misbehaviour(err))
 		}
 		return err
 	}
@@ -1058,7 +1080,7 @@ func (d *Downloader) fetchHeaders(p *peerConnection, from uint64, head uint64) e
 		default:
 			// Header retrieval either timed out, or the peer failed in some strange way
 			// (e.g. disconnect). Consider the master peer bad and drop
-			d.dropPeer(p.id)
+			d.dropPeer(p.id, misbehaviour(err))
 
 			// Finish the sync gracefully instead of dumping the gathered data though
 			for _, ch := range []chan bool{d.queue.blockWakeCh, d.queue.receiptWakeCh} {
@@ -136,7 +136,7 @@ func (dl *downloadTester) newPeer(id string, version uint, blocks []*types.Block
 }
 
 // dropPeer simulates a hard peer removal from the connection pool.
-func (dl *downloadTester) dropPeer(id string) {
+func (dl *downloadTester) dropPeer(id string, reason error) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
@@ -344,7 +344,7 @@ func (dlp *downloadTesterPeer) RequestAccountRange(id uint64, root, origin, limi
 		Limit:  limit,
 		Bytes:  bytes,
 	}
-	slimaccs, proofs := snap.ServiceGetAccountRangeQuery(dlp.chain, req)
+	slimaccs, proofs := snap.ServiceGetAccountRangeQuery(dlp.chain, req, nil)
 
 	// We need to convert to non-slim format, delegate to the packet code
 	res := &snap.AccountRangePacket{
@@ -371,7 +371,7 @@ func (dlp *downloadTesterPeer) RequestStorageRanges(id uint64, root common.Hash,
 		Limit:    limit,
 		Bytes:    bytes,
 	}
-	storage, proofs := snap.ServiceGetStorageRangesQuery(dlp.chain, req)
+	storage, proofs := snap.ServiceGetStorageRangesQuery(dlp.chain, req, nil)
 
 	// We need to convert to demultiplex, delegate to the packet code
 	res := &snap.StorageRangesPacket{
@@ -1311,3 +1311,18 @@ func testBeaconSync(t *testing.T, protocol uint, mode SyncMode) {
 		})
 	}
 }
+
+// Tests that only the sync failures proving a peer malicious are reported as
+// misbehaviour, the slow or out of sync peers are dropped without a reason.
+func TestDropMisbehaviour(t *testing.T) {
+	for _, err := range []error{errInvalidChain, errBadPeer, errInvalidAncestor, fmt.Errorf("%w: bad header", errInvalidChain)} {
+		if misbehaviour(err) == nil {
+			t.Errorf("failure %v not reported as misbehaviour", err)
+		}
+	}
+	for _, err := range []error{errTimeout, errStallingPeer, errUnsyncedPeer, errEmptyHeaderSet, errPeersUnavailable, errTooOld} {
+		if misbehaviour(err) != nil {
+			t.Errorf("failure %v reported as misbehaviour", err)
+		}
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -46,6 +47,30 @@ var FullNodeGPO = gasprice.Config{
 	IgnorePrice:      gasprice.DefaultIgnorePrice,
 }
 
+// Propagation policies of locally submitted transactions.
+const (
+	TxPropBroadcast = "broadcast" // Broadcast and announce like any other transaction
+	TxPropStem      = "stem"      // Relay through a stem peer first, Dandelion++ style
+	TxPropPrivate   = "private"   // Only send to trusted peers and relays, never broadcast
+)
+
+// TxPropagationConfig contains the policies for propagating locally submitted
+// transactions to the network.
+type TxPropagationConfig struct {
+	Policy      string        // Propagation policy of local transactions
+	Relays      []string      // Enode URLs of the relays receiving private transactions, besides trusted peers
+	StemEpoch   time.Duration // Interval after which the stem peers are reselected
+	StemEmbargo time.Duration // Minimum time after which a stemmed transaction not seen from the network is broadcast
+}
+
+// DefaultTxPropagationConfig contains the default transaction propagation
+// policies. Local transactions are broadcast like any other by default.
+var DefaultTxPropagationConfig = TxPropagationConfig{
+	Policy:      TxPropBroadcast,
+	StemEpoch:   10 * time.Minute,
+	StemEmbargo: 30 * time.Second,
+}
+
 // Defaults contains default settings for use on the Ethereum main net.
 var Defaults = Config{
 	SyncMode:           downloader.SnapSync,
@@ -53,6 +78,7 @@ var Defaults = Config{
 	TxLookupLimit:      2350000,
 	TransactionHistory: 2350000,
 	StateHistory:       params.FullImmutabilityThreshold,
+	LogHistory:         2350000,
 	LightPeers:         100,
 	DatabaseCache:      512,
 	TrieCleanCache:     154,
@@ -63,6 +89,8 @@ var Defaults = Config{
 	Miner:              miner.DefaultConfig,
 	TxPool:             legacypool.DefaultConfig,
 	BlobPool:           blobpool.DefaultConfig,
+	TxPoolJournal:      txpool.DefaultJournalConfig,
+	TxPropagation:      DefaultTxPropagationConfig,
 	RPCGasCap:          50000000,
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
@@ -94,12 +122,29 @@ type Config struct {
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	StateHistoryIndex  bool   `toml:",omitempty"` // Whether to index state histories for historical state reads (path scheme only).
+
+	// Chain history expiry (EIP-4444) settings. The expired block bodies and
+	// receipts can be served from a local era archive if it's available.
+	HistoryExpiry  uint64 `toml:",omitempty"` // The maximum number of blocks from head whose bodies and receipts are reserved (0 = entire chain).
+	HistoryArchive string `toml:",omitempty"` // The directory of era files serving the expired chain history.
+
+	// Log index settings, replacing the bloombits for address and topic
+	// filtering in the indexed block range.
+	LogIndex   bool   `toml:",omitempty"` // Whether to maintain the address and topic log index.
+	LogHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose logs are indexed.
 
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
 	// consistent with persistent state.
 	StateScheme string `toml:",omitempty"`
 
+	// PartialState is the set of contracts whose storage is tracked. If set, the
+	// node keeps only the account trie plus the storage of these contracts. The
+	// storage of the other contracts touched by the executed blocks is resolved
+	// from the snap peers on demand. Only the hash scheme is supported.
+	PartialState []common.Address `toml:",omitempty"`
+
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
@@ -118,6 +163,7 @@ type Config struct {
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	DatabaseFreezer    string
+	DatabaseRemote     string `toml:",omitempty"` // URL of the object store to offload the ancients into
 
 	TrieCleanCache int
 	TrieDirtyCache int
@@ -132,8 +178,12 @@ type Config struct {
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool   legacypool.Config
-	BlobPool blobpool.Config
+	TxPool        legacypool.Config
+	BlobPool      blobpool.Config
+	TxPoolJournal txpool.JournalConfig
+
+	// Transaction propagation options
+	TxPropagation TxPropagationConfig
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/blobpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -27,7 +28,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		StateHistoryIndex       bool                   `toml:",omitempty"`
+		HistoryExpiry           uint64                 `toml:",omitempty"`
+		HistoryArchive          string                 `toml:",omitempty"`
+		LogIndex                bool                   `toml:",omitempty"`
+		LogHistory              uint64                 `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
+		PartialState            []common.Address       `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
 		LightIngress            int                    `toml:",omitempty"`
@@ -39,6 +46,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
 		DatabaseFreezer         string
+		DatabaseRemote          string `toml:",omitempty"`
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
@@ -48,6 +56,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
 		BlobPool                blobpool.Config
+		TxPoolJournal           txpool.JournalConfig
+		TxPropagation           TxPropagationConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		VMTrace                 string
@@ -70,7 +80,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.StateHistoryIndex = c.StateHistoryIndex
+	enc.HistoryExpiry = c.HistoryExpiry
+	enc.HistoryArchive = c.HistoryArchive
+	enc.LogIndex = c.LogIndex
+	enc.LogHistory = c.LogHistory
 	enc.StateScheme = c.StateScheme
+	enc.PartialState = c.PartialState
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -82,6 +98,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseRemote = c.DatabaseRemote
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
@@ -91,6 +108,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.BlobPool = c.BlobPool
+	enc.TxPoolJournal = c.TxPoolJournal
+	enc.TxPropagation = c.TxPropagation
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.VMTrace = c.VMTrace
@@ -117,7 +136,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		StateHistoryIndex       *bool                  `toml:",omitempty"`
+		HistoryExpiry           *uint64                `toml:",omitempty"`
+		HistoryArchive          *string                `toml:",omitempty"`
+		LogIndex                *bool                  `toml:",omitempty"`
+		LogHistory              *uint64                `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
+		PartialState            []common.Address       `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
 		LightIngress            *int                   `toml:",omitempty"`
@@ -129,6 +154,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
 		DatabaseFreezer         *string
+		DatabaseRemote          *string `toml:",omitempty"`
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
@@ -138,6 +164,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
 		BlobPool                *blobpool.Config
+		TxPoolJournal           *txpool.JournalConfig
+		TxPropagation           *TxPropagationConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		VMTrace                 *string
@@ -183,9 +211,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.StateHistoryIndex != nil {
+		c.StateHistoryIndex = *dec.StateHistoryIndex
+	}
+	if dec.HistoryExpiry != nil {
+		c.HistoryExpiry = *dec.HistoryExpiry
+	}
+	if dec.HistoryArchive != nil {
+		c.HistoryArchive = *dec.HistoryArchive
+	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.LogHistory != nil {
+		c.LogHistory = *dec.LogHistory
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
+	if dec.PartialState != nil {
+		c.PartialState = dec.PartialState
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -219,6 +265,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseFreezer != nil {
 		c.DatabaseFreezer = *dec.DatabaseFreezer
 	}
+	if dec.DatabaseRemote != nil {
+		c.DatabaseRemote = *dec.DatabaseRemote
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -246,6 +295,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.BlobPool != nil {
 		c.BlobPool = *dec.BlobPool
 	}
+	if dec.TxPoolJournal != nil {
+		c.TxPoolJournal = *dec.TxPoolJournal
+	}
+	if dec.TxPropagation != nil {
+		c.TxPropagation = *dec.TxPropagation
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -190,6 +191,83 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 	return rpcSub, nil
 }
 
+// TxPoolEventsCriteria restricts the transaction lifecycle events delivered by
+// a txpoolEvents subscription. Empty fields match everything.
+type TxPoolEventsCriteria struct {
+	Senders []common.Address `json:"senders"`
+	Hashes  []common.Hash    `json:"hashes"`
+}
+
+// rpcTxPoolEvent is the JSON representation of a transaction lifecycle event.
+type rpcTxPoolEvent struct {
+	Kind       string         `json:"kind"`
+	Hash       common.Hash    `json:"hash"`
+	From       common.Address `json:"from"`
+	Nonce      hexutil.Uint64 `json:"nonce"`
+	ReplacedBy *common.Hash   `json:"replacedBy,omitempty"`
+}
+
+// TxpoolEvents creates a subscription that is triggered each time a transaction
+// leaves the transaction pool - or reenters it after a reorg - announcing the
+// reason (included, replaced, evicted, etc). Events can be filtered by sender or
+// by transaction hash.
+func (api *FilterAPI) TxpoolEvents(ctx context.Context, crit *TxPoolEventsCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		senders = make(map[common.Address]struct{})
+		hashes  = make(map[common.Hash]struct{})
+	)
+	if crit != nil {
+		for _, addr := range crit.Senders {
+			senders[addr] = struct{}{}
+		}
+		for _, hash := range crit.Hashes {
+			hashes[hash] = struct{}{}
+		}
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan []*txpool.TxEvent, 128)
+		eventsSub := api.sys.backend.SubscribeTxPoolEvents(events)
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case events := <-events:
+				for _, ev := range events {
+					if len(senders) > 0 {
+						if _, ok := senders[ev.From]; !ok {
+							continue
+						}
+					}
+					if len(hashes) > 0 {
+						if _, ok := hashes[ev.Hash]; !ok {
+							continue
+						}
+					}
+					notifier.Notify(rpcSub.ID, &rpcTxPoolEvent{
+						Kind:       ev.Kind.String(),
+						Hash:       ev.Hash,
+						From:       ev.From,
+						Nonce:      hexutil.Uint64(ev.Nonce),
+						ReplacedBy: ev.ReplacedBy,
+					})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-eventsSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 func (api *FilterAPI) NewBlockFilter() rpc.ID {
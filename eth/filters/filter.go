@@ -24,6 +24,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -174,31 +175,144 @@ func (f *Filter) rangeLogsAsync(ctx context.Context) (chan *types.Log, chan erro
 			close(logChan)
 		}()
 
-		// Gather all indexed logs, and finish with non indexed ones
+		// If the log index covers the tail of the range, only use the bloom
+		// bits for the blocks below the index and look up the rest directly.
 		var (
-			end            = uint64(f.end)
-			size, sections = f.sys.backend.BloomStatus()
-			err            error
+			end = uint64(f.end)
+			err error
 		)
-		if indexed := sections * size; indexed > uint64(f.begin) {
-			if indexed > end {
-				indexed = end + 1
+		if tail := f.logIndexTail(); tail != nil && *tail <= end {
+			if *tail > uint64(f.begin) {
+				err = f.bloomLogs(ctx, *tail-1, logChan)
 			}
-			if err = f.indexedLogs(ctx, indexed-1, logChan); err != nil {
-				errChan <- err
-				return
+			if err == nil {
+				err = f.logIndexLogs(ctx, end, logChan)
 			}
+		} else {
+			err = f.bloomLogs(ctx, end, logChan)
 		}
+		errChan <- err
+	}()
 
-		if err := f.unindexedLogs(ctx, end, logChan); err != nil {
-			errChan <- err
-			return
+	return logChan, errChan
+}
+
+// bloomLogs returns the logs matching the filter criteria up until the given
+// block, using the bloom bits indexes where available and falling back to raw
+// block iteration for the rest.
+func (f *Filter) bloomLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
+	// Gather all indexed logs, and finish with non indexed ones
+	size, sections := f.sys.backend.BloomStatus()
+	if indexed := sections * size; indexed > uint64(f.begin) {
+		if indexed > end {
+			indexed = end + 1
+		}
+		if err := f.indexedLogs(ctx, indexed-1, logChan); err != nil {
+			return err
 		}
+	}
+	return f.unindexedLogs(ctx, end, logChan)
+}
 
-		errChan <- nil
-	}()
+// logIndexTail returns the oldest block covered by the log index, or nil if the
+// index is not available or the filter has no address or topic criteria to look
+// up in it.
+func (f *Filter) logIndexTail() *uint64 {
+	criteria := len(f.addresses) > 0
+	for _, sub := range f.topics {
+		if len(sub) > 0 {
+			criteria = true
+		}
+	}
+	if !criteria {
+		return nil
+	}
+	return f.sys.backend.LogIndexTail()
+}
 
-	return logChan, errChan
+// logIndexLogs returns the logs matching the filter criteria based on the
+// address and topic log index. The blocks referencing any of the addresses
+// and any of the topics of each clause are looked up, and only the blocks
+// present in all of them are checked for matching logs.
+func (f *Filter) logIndexLogs(ctx context.Context, end uint64, logChan chan *types.Log) error {
+	var (
+		db         = f.sys.backend.ChainDb()
+		begin      = uint64(f.begin)
+		candidates []uint64
+		filtered   bool
+	)
+	match := func(entries [][]rawdb.LogIndexEntry) {
+		var numbers []uint64
+		for _, list := range entries {
+			for _, entry := range list {
+				numbers = append(numbers, entry.Number)
+			}
+		}
+		slices.Sort(numbers)
+		numbers = slices.Compact(numbers)
+
+		if !filtered {
+			candidates, filtered = numbers, true
+			return
+		}
+		candidates = intersectNumbers(candidates, numbers)
+	}
+	if len(f.addresses) > 0 {
+		entries := make([][]rawdb.LogIndexEntry, len(f.addresses))
+		for i, address := range f.addresses {
+			entries[i] = rawdb.ReadLogIndexByAddress(db, address, begin, end)
+		}
+		match(entries)
+	}
+	for _, sub := range f.topics {
+		if len(sub) == 0 {
+			continue // empty rule set == wildcard
+		}
+		entries := make([][]rawdb.LogIndexEntry, len(sub))
+		for i, topic := range sub {
+			entries[i] = rawdb.ReadLogIndexByTopic(db, topic, begin, end)
+		}
+		match(entries)
+	}
+	for _, number := range candidates {
+		header, err := f.sys.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if header == nil || err != nil {
+			return err
+		}
+		found, err := f.checkMatches(ctx, header)
+		if err != nil {
+			return err
+		}
+		for _, log := range found {
+			select {
+			case logChan <- log:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		f.begin = int64(number) + 1
+	}
+	f.begin = int64(end) + 1
+	return nil
+}
+
+// intersectNumbers returns the block numbers present in both of the sorted,
+// deduplicated lists.
+func intersectNumbers(a, b []uint64) []uint64 {
+	var result []uint64
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
 }
 
 // indexedLogs returns the logs matching the filter criteria based on the bloom
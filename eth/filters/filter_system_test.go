@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -43,6 +44,7 @@ type testBackend struct {
 	db              ethdb.Database
 	sections        uint64
 	txFeed          event.Feed
+	txEventsFeed    event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	chainFeed       event.Feed
@@ -125,6 +127,10 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxPoolEvents(ch chan<- []*txpool.TxEvent) event.Subscription {
+	return b.txEventsFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -141,6 +147,10 @@ func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
 
+func (b *testBackend) LogIndexTail() *uint64 {
+	return rawdb.ReadLogIndexTail(b.db)
+}
+
 func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	requests := make(chan chan *bloombits.Retrieval)
 
@@ -238,6 +248,80 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestTxpoolEventsSubscription tests that transaction lifecycle events are
+// delivered over RPC with the expected payloads, honoring the sender filter,
+// and that unsubscribing releases the feed subscription.
+func TestTxpoolEventsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		server       = rpc.NewServer()
+
+		sender      = common.Address{0x01}
+		other       = common.Address{0x02}
+		replacement = common.Hash{0x13}
+	)
+	if err := server.RegisterName("eth", NewFilterAPI(sys)); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	events := make(chan *rpcTxPoolEvent, 16)
+	crit := &TxPoolEventsCriteria{Senders: []common.Address{sender}}
+	sub, err := client.EthSubscribe(context.Background(), events, "txpoolEvents", crit)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	// Wait for the subscription to reach the feed, probing with events of a
+	// sender filtered out
+	probe := []*txpool.TxEvent{{Kind: txpool.TxEventIncluded, Hash: common.Hash{0xff}, From: other}}
+	for start := time.Now(); backend.txEventsFeed.Send(probe) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("subscription not established")
+		}
+	}
+	backend.txEventsFeed.Send([]*txpool.TxEvent{
+		{Kind: txpool.TxEventReinjected, Hash: common.Hash{0x10}, From: sender, Nonce: 1},
+		{Kind: txpool.TxEventUnderpriced, Hash: common.Hash{0x11}, From: other, Nonce: 2},
+		{Kind: txpool.TxEventUnderpriced, Hash: common.Hash{0x11}, From: sender, Nonce: 2},
+	})
+	backend.txEventsFeed.Send([]*txpool.TxEvent{
+		{Kind: txpool.TxEventReplaced, Hash: common.Hash{0x12}, From: sender, Nonce: 3, ReplacedBy: &replacement},
+	})
+	want := []rpcTxPoolEvent{
+		{Kind: "reinjected", Hash: common.Hash{0x10}, From: sender, Nonce: 1},
+		{Kind: "underpriced", Hash: common.Hash{0x11}, From: sender, Nonce: 2},
+		{Kind: "replaced", Hash: common.Hash{0x12}, From: sender, Nonce: 3, ReplacedBy: &replacement},
+	}
+	for i, exp := range want {
+		select {
+		case ev := <-events:
+			if !reflect.DeepEqual(*ev, exp) {
+				t.Errorf("event %d mismatch: have %+v, want %+v", i, *ev, exp)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+	// Unsubscribing must release the feed subscription
+	sub.Unsubscribe()
+	for start := time.Now(); backend.txEventsFeed.Send(probe) != 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("feed subscription not released")
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected event: %+v", ev)
+	default:
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
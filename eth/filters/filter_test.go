@@ -374,6 +374,48 @@ func TestFilters(t *testing.T) {
 		}
 	}
 
+	t.Run("logindex", func(t *testing.T) {
+		// Index the logs of the second half of the chain and check that the
+		// lookups spanning the log index return the same logs as the bloombits.
+		cases := []struct {
+			begin, end int64
+			addresses  []common.Address
+			topics     [][]common.Hash
+		}{
+			{0, int64(rpc.LatestBlockNumber), []common.Address{contract}, [][]common.Hash{{hash1, hash2, hash3, hash4}}},
+			{0, int64(rpc.LatestBlockNumber), []common.Address{contract, contract2}, nil},
+			{0, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{{hash1, hash3}}},
+			{0, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{{common.BytesToHash([]byte("fail"))}, {hash1}}},
+			{1, 10, []common.Address{contract}, [][]common.Hash{{hash2}, {hash1}}},
+			{900, 999, []common.Address{contract}, [][]common.Hash{{hash3}}},
+			{990, int64(rpc.LatestBlockNumber), []common.Address{contract2}, [][]common.Hash{{hash3}}},
+			{990, int64(rpc.LatestBlockNumber), nil, [][]common.Hash{nil, {hash1}}},
+		}
+		want := make([][]*types.Log, len(cases))
+		for i, c := range cases {
+			if want[i], err = sys.NewRangeFilter(c.begin, c.end, c.addresses, c.topics).Logs(context.Background()); err != nil {
+				t.Fatalf("case %d: failed to filter logs: %v", i, err)
+			}
+		}
+		rawdb.IndexLogs(db, 500, uint64(len(chain))+1, nil, false)
+		defer rawdb.DeleteLogIndexTail(db)
+
+		if tail := rawdb.ReadLogIndexTail(db); tail == nil || *tail != 500 {
+			t.Fatalf("log index tail mismatch: have %v, want 500", tail)
+		}
+		for i, c := range cases {
+			logs, err := sys.NewRangeFilter(c.begin, c.end, c.addresses, c.topics).Logs(context.Background())
+			if err != nil {
+				t.Fatalf("case %d: failed to filter logs: %v", i, err)
+			}
+			have, _ := json.Marshal(logs)
+			exp, _ := json.Marshal(want[i])
+			if string(have) != string(exp) {
+				t.Fatalf("case %d: log mismatch, have:\n%s\nwant:\n%s", i, have, exp)
+			}
+		}
+	})
+
 	t.Run("timeout", func(t *testing.T) {
 		f := sys.NewRangeFilter(0, rpc.LatestBlockNumber.Int64(), nil, nil)
 		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Hour))
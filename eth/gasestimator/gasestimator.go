@@ -181,6 +181,85 @@ func Estimate(ctx context.Context, call *core.Message, opts *Options, gasCap uin
 	return hi, nil, nil
 }
 
+// ErrPrerequisiteFailed is returned for the calls of a sequence which could not
+// be estimated because an earlier call failed.
+var ErrPrerequisiteFailed = errors.New("prerequisite call failed")
+
+// ErrGasCapExhausted is returned for the calls of a sequence which could not be
+// estimated because the earlier calls used up the entire gas cap.
+var ErrGasCapExhausted = errors.New("gas cap exhausted by prerequisite calls")
+
+// Estimation is the gas estimation outcome of a single call within a sequence.
+type Estimation struct {
+	Gas    uint64 // Lowest gas limit allowing the call to run successfully
+	Revert []byte // Revert data, if the call reverted
+	Err    error  // Failure reason, if the call could not be estimated
+}
+
+// EstimateSequence estimates the gas of an ordered list of calls, each executed
+// on top of the state mutated by all the ones before it. This allows estimating
+// calls that only succeed after some prerequisites (e.g. a token approval) that
+// have not yet been sent.
+//
+// The gas cap is enforced across the whole sequence: every call may only use the
+// allowance not yet reserved by the estimates of its predecessors. If a call
+// fails, all the remaining ones are marked with ErrPrerequisiteFailed. The pre-
+// state in the options is not modified.
+func EstimateSequence(ctx context.Context, calls []*core.Message, opts *Options, gasCap uint64) []*Estimation {
+	var (
+		results = make([]*Estimation, len(calls))
+		seqOpts = *opts
+		failed  bool
+	)
+	seqOpts.State = opts.State.Copy()
+
+	for i, call := range calls {
+		if failed {
+			results[i] = &Estimation{Err: ErrPrerequisiteFailed}
+			continue
+		}
+		gas, revert, err := Estimate(ctx, call, &seqOpts, gasCap)
+		if err == nil && i < len(calls)-1 {
+			err = apply(ctx, call, &seqOpts, gas)
+		}
+		if err != nil {
+			results[i] = &Estimation{Revert: revert, Err: err}
+			failed = true
+			continue
+		}
+		results[i] = &Estimation{Gas: gas}
+
+		// Reserve the estimated gas from the allowance of the rest of the sequence
+		if gasCap != 0 && i < len(calls)-1 {
+			if gas >= gasCap {
+				for j := i + 1; j < len(calls); j++ {
+					results[j] = &Estimation{Err: ErrGasCapExhausted}
+				}
+				break
+			}
+			gasCap -= gas
+		}
+	}
+	return results
+}
+
+// apply executes a call of a sequence with the given gas limit, committing its
+// state changes into the options' state to be seen by the subsequent calls.
+func apply(ctx context.Context, call *core.Message, opts *Options, gasLimit uint64) error {
+	defer func(gas uint64) { call.GasLimit = gas }(call.GasLimit)
+	call.GasLimit = gasLimit
+
+	result, err := run(ctx, call, opts, opts.State)
+	if err != nil {
+		return err
+	}
+	if result.Failed() {
+		return fmt.Errorf("failed with %d gas: %w", gasLimit, result.Err)
+	}
+	opts.State.Finalise(opts.Config.IsEIP158(opts.Header.Number))
+	return nil
+}
+
 // execute is a helper that executes the transaction under a given gas limit and
 // returns true if the transaction fails for a reason that might be related to
 // not enough gas. A non-nil error means execution failed due to reasons unrelated
@@ -192,7 +271,7 @@ func execute(ctx context.Context, call *core.Message, opts *Options, gasLimit ui
 
 	// Execute the call and separate execution faults caused by a lack of gas or
 	// other non-fixable conditions
-	result, err := run(ctx, call, opts)
+	result, err := run(ctx, call, opts, opts.State.Copy())
 	if err != nil {
 		if errors.Is(err, core.ErrIntrinsicGas) {
 			return true, nil, nil // Special case, raise gas limit
@@ -203,15 +282,14 @@ func execute(ctx context.Context, call *core.Message, opts *Options, gasLimit ui
 }
 
 // run assembles the EVM as defined by the consensus rules and runs the requested
-// call invocation.
-func run(ctx context.Context, call *core.Message, opts *Options) (*core.ExecutionResult, error) {
+// call invocation on top of the given state.
+func run(ctx context.Context, call *core.Message, opts *Options, dirtyState *state.StateDB) (*core.ExecutionResult, error) {
 	// Assemble the call and the call context
 	var (
 		msgContext = core.NewEVMTxContext(call)
 		evmContext = core.NewEVMBlockContext(opts.Header, opts.Chain, nil)
 
-		dirtyState = opts.State.Copy()
-		evm        = vm.NewEVM(evmContext, msgContext, dirtyState, opts.Config, vm.Config{NoBaseFee: true})
+		evm = vm.NewEVM(evmContext, msgContext, dirtyState, opts.Config, vm.Config{NoBaseFee: true})
 	)
 	// Monitor the outer context and interrupt the EVM upon cancellation. To avoid
 	// a dangling goroutine until the outer estimation finishes, create an internal
@@ -26,6 +26,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -48,6 +49,7 @@ type Config struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+	Mempool          bool     // Whether to also sample the transaction pool for forward-looking suggestions
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -56,6 +58,7 @@ type OracleBackend interface {
 	BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error)
 	GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error)
 	Pending() (*types.Block, types.Receipts, *state.StateDB)
+	PoolPending() map[common.Address][]*txpool.LazyTransaction
 	ChainConfig() *params.ChainConfig
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
@@ -68,6 +71,7 @@ type Oracle struct {
 	lastPrice   *big.Int
 	maxPrice    *big.Int
 	ignorePrice *big.Int
+	mempool     bool
 	cacheLock   sync.RWMutex
 	fetchLock   sync.Mutex
 
@@ -134,6 +138,7 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		lastPrice:        params.Default,
 		maxPrice:         maxPrice,
 		ignorePrice:      ignorePrice,
+		mempool:          params.Mempool,
 		checkBlocks:      blocks,
 		percentile:       percent,
 		maxHeaderHistory: maxHeaderHistory,
@@ -212,6 +217,17 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		slices.SortFunc(results, func(a, b *big.Int) int { return a.Cmp(b) })
 		price = results[(len(results)-1)*oracle.percentile/100]
 	}
+	// If the transaction pool is sampled too, make sure the suggestion can keep
+	// up with the tips offered by the currently pending transactions
+	if oracle.mempool {
+		tips, err := oracle.mempoolTips(oracle.nextBaseFee(head), []float64{float64(oracle.percentile)})
+		if err != nil {
+			return new(big.Int).Set(lastPrice), err
+		}
+		if tips[0].Cmp(price) > 0 {
+			price = tips[0]
+		}
+	}
 	if price.Cmp(oracle.maxPrice) > 0 {
 		price = new(big.Int).Set(oracle.maxPrice)
 	}
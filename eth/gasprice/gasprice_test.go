@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -44,7 +45,8 @@ const testHead = 32
 
 type testBackend struct {
 	chain   *core.BlockChain
-	pending bool // pending block available
+	pending bool               // pending block available
+	pool    types.Transactions // transactions pending in the pool
 }
 
 func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
@@ -112,6 +114,20 @@ func (b *testBackend) Pending() (*types.Block, types.Receipts, *state.StateDB) {
 	return nil, nil, nil
 }
 
+func (b *testBackend) PoolPending() map[common.Address][]*txpool.LazyTransaction {
+	pending := make(map[common.Address][]*txpool.LazyTransaction)
+	for _, tx := range b.pool {
+		pending[common.Address{}] = append(pending[common.Address{}], &txpool.LazyTransaction{
+			Hash:      tx.Hash(),
+			Tx:        tx,
+			GasFeeCap: uint256.MustFromBig(tx.GasFeeCap()),
+			GasTipCap: uint256.MustFromBig(tx.GasTipCap()),
+			Gas:       tx.Gas(),
+		})
+	}
+	return pending
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chain.Config()
 }
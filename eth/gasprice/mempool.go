@@ -0,0 +1,262 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
+)
+
+// blobFeeHorizon is the number of blocks the excess blob gas trend is
+// extrapolated into the future when suggesting a blob fee cap.
+const blobFeeHorizon = 3
+
+// feeTierPercentiles are the tip percentiles of the slow, standard and fast
+// fee suggestion tiers.
+var feeTierPercentiles = []float64{10, 50, 90}
+
+// FeeTier is a pair of EIP-1559 fee caps expected to get a transaction included
+// with a given urgency.
+type FeeTier struct {
+	MaxPriorityFeePerGas *big.Int
+	MaxFeePerGas         *big.Int
+}
+
+// SuggestedFees contains the fee suggestions for a transaction to be included
+// in the upcoming blocks.
+type SuggestedFees struct {
+	BaseFee     *big.Int // Base fee of the next block
+	BlobBaseFee *big.Int // Suggested blob fee cap, nil before Cancun
+
+	Slow     FeeTier
+	Standard FeeTier
+	Fast     FeeTier
+}
+
+// nextBaseFee returns the base fee of the block following head.
+func (oracle *Oracle) nextBaseFee(head *types.Header) *big.Int {
+	config := oracle.backend.ChainConfig()
+	if !config.IsLondon(new(big.Int).Add(head.Number, common.Big1)) {
+		return new(big.Int)
+	}
+	return eip1559.CalcBaseFee(config, head)
+}
+
+// mempoolTips calculates the requested percentiles of the effective tips of the
+// pending transactions in the pool that could be included in the next block,
+// weighted by their gas limits. A zero row is returned for an empty pool.
+//
+// Only the fee caps tracked by the pool are used, the transactions themselves
+// are never resolved, keeping the cost of a call bounded by the pool size.
+func (oracle *Oracle) mempoolTips(baseFee *big.Int, percentiles []float64) ([]*big.Int, error) {
+	var (
+		pending  = oracle.backend.PoolPending()
+		sorter   []txGasAndReward
+		totalGas uint64
+		base     = uint256.MustFromBig(baseFee)
+		ignore   = uint256.MustFromBig(oracle.ignorePrice)
+	)
+	for _, txs := range pending {
+		for _, tx := range txs {
+			if tx.GasFeeCap.Lt(base) {
+				continue // fee cap below the next base fee, not includable
+			}
+			tip := new(uint256.Int).Sub(tx.GasFeeCap, base)
+			if tip.Gt(tx.GasTipCap) {
+				tip.Set(tx.GasTipCap)
+			}
+			if tip.Lt(ignore) {
+				continue
+			}
+			sorter = append(sorter, txGasAndReward{gasUsed: tx.Gas, reward: tip.ToBig()})
+			totalGas += tx.Gas
+		}
+	}
+	tips := make([]*big.Int, len(percentiles))
+	if len(sorter) == 0 {
+		for i := range tips {
+			tips[i] = new(big.Int)
+		}
+		return tips, nil
+	}
+	slices.SortStableFunc(sorter, func(a, b txGasAndReward) int {
+		return a.reward.Cmp(b.reward)
+	})
+	var (
+		txIndex    int
+		sumGasUsed = sorter[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(totalGas) * p / 100)
+		for sumGasUsed < threshold && txIndex < len(sorter)-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		tips[i] = sorter[txIndex].reward
+	}
+	return tips, nil
+}
+
+// MempoolFeeHistory extends the fee history with forward-looking data: the
+// requested percentiles of the tips offered by the transactions currently
+// pending in the pool, and a suggested blob fee cap. Nil values are returned
+// if the oracle was not configured to sample the transaction pool.
+func (oracle *Oracle) MempoolFeeHistory(ctx context.Context, rewardPercentiles []float64) ([]*big.Int, *big.Int, error) {
+	if !oracle.mempool {
+		return nil, nil, nil
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p <= rewardPercentiles[i-1] {
+			return nil, nil, fmt.Errorf("%w: #%d:%f >= #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tips []*big.Int
+	if len(rewardPercentiles) > 0 {
+		if tips, err = oracle.mempoolTips(oracle.nextBaseFee(head), rewardPercentiles); err != nil {
+			return nil, nil, err
+		}
+	}
+	blobFee, err := oracle.SuggestBlobFeeCap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tips, blobFee, nil
+}
+
+// SuggestBlobFeeCap returns a blob fee cap expected to remain sufficient for
+// the next few blocks. The excess blob gas trend over the recently checked
+// blocks is extrapolated, so a sustained high blob demand results in a higher
+// suggestion than the next block's blob base fee. Nil is returned before Cancun.
+func (oracle *Oracle) SuggestBlobFeeCap(ctx context.Context) (*big.Int, error) {
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if head.ExcessBlobGas == nil || head.BlobGasUsed == nil {
+		return nil, nil
+	}
+	next := eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed)
+
+	// Find the oldest checked block after Cancun to measure the trend against
+	var (
+		number = head.Number.Uint64()
+		oldest = head
+	)
+	for i := 0; i < oracle.checkBlocks && number > 0; i++ {
+		number--
+		header, err := oracle.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.ExcessBlobGas == nil {
+			break
+		}
+		oldest = header
+	}
+	// Extrapolate the excess blob gas growth, but never assume it shrinks
+	projected := next
+	if span := head.Number.Uint64() + 1 - oldest.Number.Uint64(); next > *oldest.ExcessBlobGas {
+		projected += (next - *oldest.ExcessBlobGas) / span * blobFeeHorizon
+	}
+	return eip4844.CalcBlobFee(projected), nil
+}
+
+// SuggestFees returns slow, standard and fast fee suggestions for the next
+// blocks. The tips are derived from the recent blocks and, if the oracle was
+// configured to sample the transaction pool, raised to the ones currently
+// offered by the pending transactions.
+func (oracle *Oracle) SuggestFees(ctx context.Context) (*SuggestedFees, error) {
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	_, rewards, _, gasUsedRatio, _, _, err := oracle.FeeHistory(ctx, uint64(oracle.checkBlocks), rpc.LatestBlockNumber, feeTierPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	// Average the tiers over the non-empty blocks
+	var (
+		tips   = make([]*big.Int, len(feeTierPercentiles))
+		blocks int64
+	)
+	for i := range tips {
+		tips[i] = new(big.Int)
+	}
+	for i, reward := range rewards {
+		if gasUsedRatio[i] == 0 || len(reward) != len(tips) {
+			continue
+		}
+		for j, tip := range reward {
+			tips[j].Add(tips[j], tip)
+		}
+		blocks++
+	}
+	if blocks > 0 {
+		for _, tip := range tips {
+			tip.Div(tip, big.NewInt(blocks))
+		}
+	}
+	baseFee := oracle.nextBaseFee(head)
+	if oracle.mempool {
+		pending, err := oracle.mempoolTips(baseFee, feeTierPercentiles)
+		if err != nil {
+			return nil, err
+		}
+		for i, tip := range pending {
+			if tip.Cmp(tips[i]) > 0 {
+				tips[i] = new(big.Int).Set(tip)
+			}
+		}
+	}
+	tiers := make([]FeeTier, len(tips))
+	for i, tip := range tips {
+		if tip.Cmp(oracle.maxPrice) > 0 {
+			tip = new(big.Int).Set(oracle.maxPrice)
+		}
+		tiers[i] = FeeTier{
+			MaxPriorityFeePerGas: tip,
+			MaxFeePerGas:         new(big.Int).Add(tip, new(big.Int).Mul(baseFee, big.NewInt(2))),
+		}
+	}
+	blobFee, err := oracle.SuggestBlobFeeCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &SuggestedFees{
+		BaseFee:     baseFee,
+		BlobBaseFee: blobFee,
+		Slow:        tiers[0],
+		Standard:    tiers[1],
+		Fast:        tiers[2],
+	}, nil
+}
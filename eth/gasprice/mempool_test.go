@@ -0,0 +1,141 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// makePoolTxs creates dynamic fee transactions offering the given tips with a
+// fee cap high enough to cover any base fee of the test chain.
+func makePoolTxs(tips ...int64) types.Transactions {
+	txs := make(types.Transactions, len(tips))
+	for i, tip := range tips {
+		txs[i] = types.NewTx(&types.DynamicFeeTx{
+			Nonce:     uint64(i),
+			To:        &common.Address{},
+			Gas:       21000,
+			GasFeeCap: big.NewInt(1000 * params.GWei),
+			GasTipCap: big.NewInt(tip * params.GWei),
+		})
+	}
+	return txs
+}
+
+func TestSuggestTipCapMempool(t *testing.T) {
+	config := Config{
+		Blocks:     3,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+		Mempool:    true,
+	}
+	var cases = []struct {
+		pool   types.Transactions
+		expect *big.Int
+	}{
+		// Empty pool, the historical suggestion stands
+		{nil, big.NewInt(params.GWei * int64(30))},
+		// Cheap pool, the historical suggestion stands
+		{makePoolTxs(1, 2, 3), big.NewInt(params.GWei * int64(30))},
+		// Competitive pool, the suggestion is raised
+		{makePoolTxs(10, 40, 50, 60, 70), big.NewInt(params.GWei * int64(50))},
+	}
+	for i, c := range cases {
+		backend := newTestBackend(t, big.NewInt(0), nil, false)
+		backend.pool = c.pool
+		oracle := NewOracle(backend, config)
+
+		got, err := oracle.SuggestTipCap(context.Background())
+		backend.teardown()
+		if err != nil {
+			t.Fatalf("test %d: failed to retrieve recommended gas price: %v", i, err)
+		}
+		if got.Cmp(c.expect) != 0 {
+			t.Errorf("test %d: gas price mismatch, want %d, got %d", i, c.expect, got)
+		}
+	}
+}
+
+func TestMempoolFeeHistory(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(16), big.NewInt(28), false)
+	defer backend.teardown()
+	backend.pool = makePoolTxs(10, 20, 30, 40)
+
+	// With the mempool mode disabled, no extension is expected
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60})
+	if tips, blobFee, err := oracle.MempoolFeeHistory(context.Background(), []float64{50}); tips != nil || blobFee != nil || err != nil {
+		t.Fatalf("unexpected mempool history: %v, %v, %v", tips, blobFee, err)
+	}
+	oracle = NewOracle(backend, Config{Blocks: 3, Percentile: 60, Mempool: true})
+	tips, blobFee, err := oracle.MempoolFeeHistory(context.Background(), []float64{0, 50, 100})
+	if err != nil {
+		t.Fatalf("failed to retrieve mempool history: %v", err)
+	}
+	for i, want := range []int64{10, 20, 40} {
+		if tips[i].Cmp(big.NewInt(want*params.GWei)) != 0 {
+			t.Errorf("tip %d mismatch: have %v, want %v", i, tips[i], want*params.GWei)
+		}
+	}
+	// The test chain contains more and more blobs in every block, so the blob
+	// fee suggestion must not fall below the next block's blob base fee
+	head, _ := backend.HeaderByNumber(context.Background(), testHead)
+	next := eip4844.CalcBlobFee(eip4844.CalcExcessBlobGas(*head.ExcessBlobGas, *head.BlobGasUsed))
+	if blobFee == nil || blobFee.Cmp(next) < 0 {
+		t.Errorf("blob fee suggestion %v below next blob base fee %v", blobFee, next)
+	}
+}
+
+func TestSuggestFees(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), nil, false)
+	defer backend.teardown()
+
+	oracle := NewOracle(backend, Config{Blocks: 3, Percentile: 60, MaxBlockHistory: 1024, Mempool: true})
+	fees, err := oracle.SuggestFees(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest fees: %v", err)
+	}
+	if fees.BlobBaseFee != nil {
+		t.Errorf("unexpected blob fee suggestion before Cancun: %v", fees.BlobBaseFee)
+	}
+	// The test chain includes a single transaction per block, with tips rising
+	// by one GWei per block
+	if want := big.NewInt(31 * params.GWei); fees.Standard.MaxPriorityFeePerGas.Cmp(want) != 0 {
+		t.Errorf("standard tip mismatch: have %v, want %v", fees.Standard.MaxPriorityFeePerGas, want)
+	}
+	// Pending transactions must raise the fast tier only
+	backend.pool = makePoolTxs(1, 1, 1, 100)
+	fees, err = oracle.SuggestFees(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest fees: %v", err)
+	}
+	if want := big.NewInt(31 * params.GWei); fees.Standard.MaxPriorityFeePerGas.Cmp(want) != 0 {
+		t.Errorf("standard tip mismatch: have %v, want %v", fees.Standard.MaxPriorityFeePerGas, want)
+	}
+	if want := big.NewInt(100 * params.GWei); fees.Fast.MaxPriorityFeePerGas.Cmp(want) != 0 {
+		t.Errorf("fast tip mismatch: have %v, want %v", fees.Fast.MaxPriorityFeePerGas, want)
+	}
+	if want := new(big.Int).Add(fees.Fast.MaxPriorityFeePerGas, new(big.Int).Mul(fees.BaseFee, big.NewInt(2))); fees.Fast.MaxFeePerGas.Cmp(want) != 0 {
+		t.Errorf("fast fee cap mismatch: have %v, want %v", fees.Fast.MaxFeePerGas, want)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/fetcher"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
@@ -54,6 +55,21 @@ const (
 	// All transactions with a higher size will be announced and need to be fetched
 	// by the peer.
 	txMaxBroadcastSize = 4096
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// blockRangeUpdateInterval is the number of blocks after which the range of
+	// served blocks is re-announced to eth/69 peers.
+	blockRangeUpdateInterval = 32
+
+	// dropPenalty is the reputation penalty of a peer dropped for misbehaving,
+	// e.g. for delivering an invalid chain or violating the announcements.
+	dropPenalty = 25
+
+	// invalidDataPenalty is the reputation penalty of a peer whose response
+	// failed validation.
+	invalidDataPenalty = 50
 )
 
 var syncChallengeTimeout = 15 * time.Second // Time allowance for a node to reply to the sync progress challenge
@@ -85,15 +101,16 @@ type txPool interface {
 // handlerConfig is the collection of initialization parameters to create a full
 // node network handler.
 type handlerConfig struct {
-	NodeID         enode.ID               // P2P node ID used for tx propagation topology
-	Database       ethdb.Database         // Database for direct sync insertions
-	Chain          *core.BlockChain       // Blockchain to serve data from
-	TxPool         txPool                 // Transaction pool to propagate from
-	Network        uint64                 // Network identifier to advertise
-	Sync           downloader.SyncMode    // Whether to snap or full sync
-	BloomCache     uint64                 // Megabytes to alloc for snap sync bloom
-	EventMux       *event.TypeMux         // Legacy event mux, deprecate for `feed`
-	RequiredBlocks map[uint64]common.Hash // Hard coded map of required block hashes for sync challenges
+	NodeID         enode.ID                      // P2P node ID used for tx propagation topology
+	Database       ethdb.Database                // Database for direct sync insertions
+	Chain          *core.BlockChain              // Blockchain to serve data from
+	TxPool         txPool                        // Transaction pool to propagate from
+	Network        uint64                        // Network identifier to advertise
+	Sync           downloader.SyncMode           // Whether to snap or full sync
+	BloomCache     uint64                        // Megabytes to alloc for snap sync bloom
+	EventMux       *event.TypeMux                // Legacy event mux, deprecate for `feed`
+	RequiredBlocks map[uint64]common.Hash        // Hard coded map of required block hashes for sync challenges
+	TxPropagation  ethconfig.TxPropagationConfig // Propagation policies of local transactions
 }
 
 type handler struct {
@@ -111,11 +128,16 @@ type handler struct {
 
 	downloader *downloader.Downloader
 	txFetcher  *fetcher.TxFetcher
+	txprop     *txPropagator
 	peers      *peerSet
 
+	historicLimit *snap.HistoricLimiter // Throttler of historical state reconstructions served over snap
+
 	eventMux *event.TypeMux
 	txsCh    chan core.NewTxsEvent
 	txsSub   event.Subscription
+	headCh   chan core.ChainHeadEvent
+	headSub  event.Subscription
 
 	requiredBlocks map[uint64]common.Hash
 
@@ -134,6 +156,10 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	if config.EventMux == nil {
 		config.EventMux = new(event.TypeMux) // Nicety initialization for tests
 	}
+	txprop, err := newTxPropagator(config.TxPropagation)
+	if err != nil {
+		return nil, err
+	}
 	h := &handler{
 		nodeID:         config.NodeID,
 		networkID:      config.Network,
@@ -142,7 +168,9 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		database:       config.Database,
 		txpool:         config.TxPool,
 		chain:          config.Chain,
+		txprop:         txprop,
 		peers:          newPeerSet(),
+		historicLimit:  snap.NewHistoricLimiter(),
 		requiredBlocks: config.RequiredBlocks,
 		quitSync:       make(chan struct{}),
 		handlerDoneCh:  make(chan struct{}),
@@ -164,6 +192,11 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		} else if !h.chain.HasState(fullBlock.Root) {
 			h.snapSync.Store(true)
 			log.Warn("Switch sync mode from full sync to snap sync", "reason", "head state missing")
+		} else if fullBlock.Number.Uint64() == 0 && h.chain.PartialState() != nil {
+			// The partial state node can't full sync from genesis, the storage
+			// of the untracked contracts would need to be resolved for all blocks.
+			h.snapSync.Store(true)
+			log.Warn("Switch sync mode from full sync to snap sync", "reason", "partial state")
 		}
 	} else {
 		head := h.chain.CurrentBlock()
@@ -181,7 +214,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		return nil, errors.New("snap sync not supported with snapshots disabled")
 	}
 	// Construct the downloader (long sync)
-	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, nil, h.removePeer, h.enableSyncedFeatures)
+	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, nil, h.dropPeer, h.enableSyncedFeatures)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -193,7 +226,10 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	addTxs := func(txs []*types.Transaction) []error {
 		return h.txpool.Add(txs, false, false)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer)
+	dropTxPeer := func(peer string) {
+		h.dropPeer(peer, errors.New("transaction announcement violated"))
+	}
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, dropTxPeer)
 	return h, nil
 }
 
@@ -258,7 +294,12 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		td      = h.chain.GetTd(hash, number)
 	)
 	forkID := forkid.NewID(h.chain.Config(), genesis, number, head.Time)
-	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter); err != nil {
+	blockRange := eth.BlockRangeUpdatePacket{
+		EarliestBlock:   h.chain.EarliestServedBlock(),
+		LatestBlock:     number,
+		LatestBlockHash: hash,
+	}
+	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter, blockRange); err != nil {
 		peer.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -389,6 +430,21 @@ func (h *handler) removePeer(id string) {
 	}
 }
 
+// dropPeer requests disconnection of a peer. If the peer was proven to misbehave,
+// its reputation is lowered too, so that it cannot reconnect right away if it
+// keeps misbehaving. Unhelpful peers, e.g. timing out, are not penalized, as it
+// might be caused by the local node or the network.
+func (h *handler) dropPeer(id string, reason error) {
+	peer := h.peers.peer(id)
+	if peer == nil {
+		return
+	}
+	if reason != nil {
+		peer.Peer.Penalize(dropPenalty, reason.Error())
+	}
+	peer.Peer.Disconnect(p2p.DiscUselessPeer)
+}
+
 // unregisterPeer removes a peer from the downloader, fetchers and main peer set.
 func (h *handler) unregisterPeer(id string) {
 	// Create a custom logger to avoid printing the entire id
@@ -429,6 +485,12 @@ func (h *handler) Start(maxPeers int) {
 	h.txsSub = h.txpool.SubscribeTransactions(h.txsCh, false)
 	go h.txBroadcastLoop()
 
+	// announce the range of served blocks to eth/69 peers
+	h.wg.Add(1)
+	h.headCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+	h.headSub = h.chain.SubscribeChainHeadEvent(h.headCh)
+	go h.blockRangeLoop()
+
 	// start sync handlers
 	h.txFetcher.Start()
 
@@ -438,7 +500,8 @@ func (h *handler) Start(maxPeers int) {
 }
 
 func (h *handler) Stop() {
-	h.txsSub.Unsubscribe() // quits txBroadcastLoop
+	h.txsSub.Unsubscribe()  // quits txBroadcastLoop
+	h.headSub.Unsubscribe() // quits blockRangeLoop
 	h.txFetcher.Stop()
 	h.downloader.Terminate()
 
@@ -461,6 +524,15 @@ func (h *handler) Stop() {
 // - And, separately, as announcements to all peers which are not known to
 // already have the given transaction.
 func (h *handler) BroadcastTransactions(txs types.Transactions) {
+	// Withhold the local transactions from the broadcast if the propagation
+	// policy demands it, routing them to their stem peer or relays instead.
+	txs, held := h.txprop.withhold(txs)
+	if held > 0 {
+		h.propagateHeld()
+	}
+	if len(txs) == 0 {
+		return
+	}
 	var (
 		blobTxs  int // Number of blob transactions to announce only
 		largeTxs int // Number of large transactions to announce only
@@ -530,6 +602,9 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
+	txPropBroadcastMeter.Mark(int64(directCount))
+	txPropAnnounceMeter.Mark(int64(annCount))
+
 	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs,
 		"bcastpeers", len(txset), "bcastcount", directCount, "annpeers", len(annos), "anncount", annCount)
 }
@@ -537,16 +612,66 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 // txBroadcastLoop announces new transactions to connected peers.
 func (h *handler) txBroadcastLoop() {
 	defer h.wg.Done()
+
+	// Local transactions withheld from the broadcast need to be checked
+	// periodically, unless the policy never withholds any.
+	var tick <-chan time.Time
+	if h.txprop.policy != ethconfig.TxPropBroadcast {
+		ticker := time.NewTicker(txPropTickInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 	for {
 		select {
 		case event := <-h.txsCh:
 			h.BroadcastTransactions(event.Txs)
+		case <-tick:
+			h.checkHeldTransactions()
 		case <-h.txsSub.Err():
 			return
 		}
 	}
 }
 
+// blockRangeLoop announces the range of locally served blocks to the connected
+// eth/69 peers. The range is announced periodically as the chain progresses, or
+// whenever the earliest served block changes due to history expiry.
+func (h *handler) blockRangeLoop() {
+	defer h.wg.Done()
+
+	var (
+		earliest = h.chain.EarliestServedBlock()
+		latest   = h.chain.CurrentBlock().Number.Uint64()
+	)
+	for {
+		select {
+		case event := <-h.headCh:
+			var (
+				number = event.Block.NumberU64()
+				tail   = h.chain.EarliestServedBlock()
+			)
+			// Skip the announcement if the range barely moved forward
+			if tail == earliest && number >= latest && number < latest+blockRangeUpdateInterval {
+				continue
+			}
+			earliest, latest = tail, number
+
+			update := eth.BlockRangeUpdatePacket{
+				EarliestBlock:   earliest,
+				LatestBlock:     latest,
+				LatestBlockHash: event.Block.Hash(),
+			}
+			for _, peer := range h.peers.all() {
+				if err := peer.SendBlockRangeUpdate(update); err != nil {
+					peer.Log().Debug("Failed to announce block range", "err", err)
+				}
+			}
+		case <-h.headSub.Err():
+			return
+		}
+	}
+}
+
 // enableSyncedFeatures enables the post-sync functionalities when the initial
 // sync is finished.
 func (h *handler) enableSyncedFeatures() {
@@ -43,8 +43,19 @@ func (h *snapHandler) PeerInfo(id enode.ID) interface{} {
 	return nil
 }
 
+// HistoricLimiter retrieves the throttler of the historical state reconstructions
+// requested by remote peers.
+func (h *snapHandler) HistoricLimiter() *snap.HistoricLimiter {
+	return h.historicLimit
+}
+
 // Handle is invoked from a peer's message handler when it receives a new remote
 // message that the handler couldn't consume and serve itself.
 func (h *snapHandler) Handle(peer *snap.Peer, packet snap.Packet) error {
-	return h.downloader.DeliverSnapPacket(peer, packet)
+	err := h.downloader.DeliverSnapPacket(peer, packet)
+	if err != nil {
+		// The response was rejected by the syncer, the peer will be dropped.
+		peer.Peer.Penalize(invalidDataPenalty, "invalid snap response")
+	}
+	return err
 }
@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
@@ -145,6 +146,12 @@ func newTestHandler() *testHandler {
 // newTestHandlerWithBlocks creates a new handler for testing purposes, with a
 // given number of initial blocks.
 func newTestHandlerWithBlocks(blocks int) *testHandler {
+	return newTestHandlerWithConfig(blocks, ethconfig.TxPropagationConfig{})
+}
+
+// newTestHandlerWithConfig creates a new handler for testing purposes, with a
+// given number of initial blocks and transaction propagation policies.
+func newTestHandlerWithConfig(blocks int, txprop ethconfig.TxPropagationConfig) *testHandler {
 	// Create a database pre-initialize with a genesis block
 	db := rawdb.NewMemoryDatabase()
 	gspec := &core.Genesis{
@@ -160,12 +167,13 @@ func newTestHandlerWithBlocks(blocks int) *testHandler {
 	txpool := newTestTxPool()
 
 	handler, _ := newHandler(&handlerConfig{
-		Database:   db,
-		Chain:      chain,
-		TxPool:     txpool,
-		Network:    1,
-		Sync:       downloader.SnapSync,
-		BloomCache: 1,
+		Database:      db,
+		Chain:         chain,
+		TxPool:        txpool,
+		Network:       1,
+		Sync:          downloader.SnapSync,
+		BloomCache:    1,
+		TxPropagation: txprop,
 	})
 	handler.Start(1000)
 
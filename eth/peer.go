@@ -24,7 +24,9 @@ import (
 // ethPeerInfo represents a short summary of the `eth` sub-protocol metadata known
 // about a connected peer.
 type ethPeerInfo struct {
-	Version uint `json:"version"` // Ethereum protocol version negotiated
+	Version       uint    `json:"version"`                 // Ethereum protocol version negotiated
+	EarliestBlock *uint64 `json:"earliestBlock,omitempty"` // First block served by the peer (eth/69+)
+	LatestBlock   *uint64 `json:"latestBlock,omitempty"`   // Latest block served by the peer (eth/69+)
 }
 
 // ethPeer is a wrapper around eth.Peer to maintain a few extra metadata.
@@ -35,9 +37,14 @@ type ethPeer struct {
 
 // info gathers and returns some `eth` protocol metadata known about a peer.
 func (p *ethPeer) info() *ethPeerInfo {
-	return &ethPeerInfo{
+	info := &ethPeerInfo{
 		Version: p.Version(),
 	}
+	if blockRange := p.BlockRange(); blockRange != nil {
+		info.EarliestBlock = &blockRange.EarliestBlock
+		info.LatestBlock = &blockRange.LatestBlock
+	}
+	return info
 }
 
 // snapPeerInfo represents a short summary of the `snap` sub-protocol metadata known
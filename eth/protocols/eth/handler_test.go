@@ -151,6 +151,7 @@ func (b *testBackend) Handle(*Peer, Packet) error {
 
 // Tests that block headers can be retrieved from a remote chain based on user queries.
 func TestGetBlockHeaders68(t *testing.T) { testGetBlockHeaders(t, ETH68) }
+func TestGetBlockHeaders69(t *testing.T) { testGetBlockHeaders(t, ETH69) }
 
 func testGetBlockHeaders(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -336,6 +337,7 @@ func testGetBlockHeaders(t *testing.T, protocol uint) {
 
 // Tests that block contents can be retrieved from a remote chain based on their hashes.
 func TestGetBlockBodies68(t *testing.T) { testGetBlockBodies(t, ETH68) }
+func TestGetBlockBodies69(t *testing.T) { testGetBlockBodies(t, ETH69) }
 
 func testGetBlockBodies(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -430,6 +432,7 @@ func testGetBlockBodies(t *testing.T, protocol uint) {
 
 // Tests that the transaction receipts can be retrieved based on hashes.
 func TestGetBlockReceipts68(t *testing.T) { testGetBlockReceipts(t, ETH68) }
+func TestGetBlockReceipts69(t *testing.T) { testGetBlockReceipts(t, ETH69) }
 
 func testGetBlockReceipts(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -492,10 +495,25 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		RequestId:          123,
 		GetReceiptsRequest: hashes,
 	})
-	if err := p2p.ExpectMsg(peer.app, ReceiptsMsg, &ReceiptsPacket{
+	var expect interface{} = &ReceiptsPacket{
 		RequestId:        123,
 		ReceiptsResponse: receipts,
-	}); err != nil {
+	}
+	if protocol >= ETH69 {
+		// Receipts are sent without the bloom filters since eth/69
+		response := make(ReceiptsResponse69, len(receipts))
+		for i := range receipts {
+			response[i] = make([]*Receipt69, len(receipts[i]))
+			for j, receipt := range receipts[i] {
+				response[i][j] = newReceipt69(receipt)
+			}
+		}
+		expect = &ReceiptsPacket69{
+			RequestId:          123,
+			ReceiptsResponse69: response,
+		}
+	}
+	if err := p2p.ExpectMsg(peer.app, ReceiptsMsg, expect); err != nil {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
@@ -35,26 +35,46 @@ const (
 )
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
+// network IDs, difficulties, head and genesis blocks. On eth/69 and newer the
+// total difficulty is omitted and the range of served blocks is exchanged.
+func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, blockRange BlockRangeUpdatePacket) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 
-	var status StatusPacket // safe to read after two values have been received from errc
-
-	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket{
-			ProtocolVersion: uint32(p.version),
-			NetworkID:       network,
-			TD:              td,
-			Head:            head,
-			Genesis:         genesis,
-			ForkID:          forkID,
-		})
-	}()
-	go func() {
-		errc <- p.readStatus(network, &status, genesis, forkFilter)
-	}()
+	var (
+		status   StatusPacket   // safe to read after two values have been received from errc
+		status69 StatusPacket69 // safe to read after two values have been received from errc
+	)
+	if p.version >= ETH69 {
+		go func() {
+			errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket69{
+				ProtocolVersion: uint32(p.version),
+				NetworkID:       network,
+				Genesis:         genesis,
+				ForkID:          forkID,
+				EarliestBlock:   blockRange.EarliestBlock,
+				LatestBlock:     blockRange.LatestBlock,
+				LatestBlockHash: blockRange.LatestBlockHash,
+			})
+		}()
+		go func() {
+			errc <- p.readStatus69(network, &status69, genesis, forkFilter)
+		}()
+	} else {
+		go func() {
+			errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket{
+				ProtocolVersion: uint32(p.version),
+				NetworkID:       network,
+				TD:              td,
+				Head:            head,
+				Genesis:         genesis,
+				ForkID:          forkID,
+			})
+		}()
+		go func() {
+			errc <- p.readStatus(network, &status, genesis, forkFilter)
+		}()
+	}
 	timeout := time.NewTimer(handshakeTimeout)
 	defer timeout.Stop()
 	for i := 0; i < 2; i++ {
@@ -69,6 +89,15 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 			return p2p.DiscReadTimeout
 		}
 	}
+	if p.version >= ETH69 {
+		p.td, p.head = new(big.Int), status69.LatestBlockHash
+		p.blockRange = &BlockRangeUpdatePacket{
+			EarliestBlock:   status69.EarliestBlock,
+			LatestBlock:     status69.LatestBlock,
+			LatestBlockHash: status69.LatestBlockHash,
+		}
+		return nil
+	}
 	p.td, p.head = status.TD, status.Head
 
 	// TD at mainnet block #7753254 is 76 bits. If it becomes 100 million times
@@ -79,8 +108,30 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	return nil
 }
 
-// readStatus reads the remote handshake message.
+// readStatus reads the remote eth/68 handshake message.
 func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.Hash, forkFilter forkid.Filter) error {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	return p.checkStatus(network, status.NetworkID, status.ProtocolVersion, genesis, status.Genesis, forkFilter, status.ForkID)
+}
+
+// readStatus69 reads the remote handshake message on eth/69 and newer.
+func (p *Peer) readStatus69(network uint64, status *StatusPacket69, genesis common.Hash, forkFilter forkid.Filter) error {
+	if err := p.readStatusMsg(status); err != nil {
+		return err
+	}
+	if err := p.checkStatus(network, status.NetworkID, status.ProtocolVersion, genesis, status.Genesis, forkFilter, status.ForkID); err != nil {
+		return err
+	}
+	if status.EarliestBlock > status.LatestBlock {
+		return fmt.Errorf("%w: earliest %d > latest %d", errInvalidBlockRange, status.EarliestBlock, status.LatestBlock)
+	}
+	return nil
+}
+
+// readStatusMsg reads and decodes the remote handshake message.
+func (p *Peer) readStatusMsg(status interface{}) error {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -91,20 +142,24 @@ func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.H
 	if msg.Size > maxMessageSize {
 		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
 	}
-	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if err := msg.Decode(status); err != nil {
 		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 	}
-	if status.NetworkID != network {
-		return fmt.Errorf("%w: %d (!= %d)", errNetworkIDMismatch, status.NetworkID, network)
+	return nil
+}
+
+// checkStatus makes sure the remote handshake matches the local chain.
+func (p *Peer) checkStatus(network, remoteNetwork uint64, remoteVersion uint32, genesis, remoteGenesis common.Hash, forkFilter forkid.Filter, remoteForkID forkid.ID) error {
+	if remoteNetwork != network {
+		return fmt.Errorf("%w: %d (!= %d)", errNetworkIDMismatch, remoteNetwork, network)
 	}
-	if uint(status.ProtocolVersion) != p.version {
-		return fmt.Errorf("%w: %d (!= %d)", errProtocolVersionMismatch, status.ProtocolVersion, p.version)
+	if uint(remoteVersion) != p.version {
+		return fmt.Errorf("%w: %d (!= %d)", errProtocolVersionMismatch, remoteVersion, p.version)
 	}
-	if status.Genesis != genesis {
-		return fmt.Errorf("%w: %x (!= %x)", errGenesisMismatch, status.Genesis, genesis)
+	if remoteGenesis != genesis {
+		return fmt.Errorf("%w: %x (!= %x)", errGenesisMismatch, remoteGenesis, genesis)
 	}
-	if err := forkFilter(status.ForkID); err != nil {
+	if err := forkFilter(remoteForkID); err != nil {
 		return fmt.Errorf("%w: %v", errForkIDRejected, err)
 	}
 	return nil
@@ -28,6 +28,7 @@ import (
 
 // Tests that handshake failures are detected and reported correctly.
 func TestHandshake68(t *testing.T) { testHandshake(t, ETH68) }
+func TestHandshake69(t *testing.T) { testHandshake(t, ETH69) }
 
 func testHandshake(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -42,11 +43,12 @@ func testHandshake(t *testing.T, protocol uint) {
 		td      = backend.chain.GetTd(head.Hash(), head.Number.Uint64())
 		forkID  = forkid.NewID(backend.chain.Config(), backend.chain.Genesis(), backend.chain.CurrentHeader().Number.Uint64(), backend.chain.CurrentHeader().Time)
 	)
-	tests := []struct {
+	type handshakeTest struct {
 		code uint64
 		data interface{}
 		want error
-	}{
+	}
+	tests := []handshakeTest{
 		{
 			code: TransactionsMsg, data: []interface{}{},
 			want: errNoStatusMsg,
@@ -68,6 +70,40 @@ func testHandshake(t *testing.T, protocol uint) {
 			want: errForkIDRejected,
 		},
 	}
+	if protocol >= ETH69 {
+		number := head.Number.Uint64()
+		tests = []handshakeTest{
+			{
+				code: TransactionsMsg, data: []interface{}{},
+				want: errNoStatusMsg,
+			},
+			{
+				code: StatusMsg, data: StatusPacket{uint32(protocol), 1, td, head.Hash(), genesis.Hash(), forkID},
+				want: errDecode,
+			},
+			{
+				code: StatusMsg, data: StatusPacket69{10, 1, genesis.Hash(), forkID, 0, number, head.Hash()},
+				want: errProtocolVersionMismatch,
+			},
+			{
+				code: StatusMsg, data: StatusPacket69{uint32(protocol), 999, genesis.Hash(), forkID, 0, number, head.Hash()},
+				want: errNetworkIDMismatch,
+			},
+			{
+				code: StatusMsg, data: StatusPacket69{uint32(protocol), 1, common.Hash{3}, forkID, 0, number, head.Hash()},
+				want: errGenesisMismatch,
+			},
+			{
+				code: StatusMsg, data: StatusPacket69{uint32(protocol), 1, genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}, 0, number, head.Hash()},
+				want: errForkIDRejected,
+			},
+			{
+				code: StatusMsg, data: StatusPacket69{uint32(protocol), 1, genesis.Hash(), forkID, number + 1, number, head.Hash()},
+				want: errInvalidBlockRange,
+			},
+		}
+	}
+	blockRange := BlockRangeUpdatePacket{LatestBlock: head.Number.Uint64(), LatestBlockHash: head.Hash()}
 	for i, test := range tests {
 		// Create the two peers to shake with each other
 		app, net := p2p.MsgPipe()
@@ -80,7 +116,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		// Send the junk test with one peer, check the handshake failure
 		go p2p.Send(app, test.code, test.data)
 
-		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain))
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), blockRange)
 		if err == nil {
 			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
 		} else if !errors.Is(err, test.want) {
@@ -50,8 +50,9 @@ type Peer struct {
 	rw        p2p.MsgReadWriter // Input/output streams for snap
 	version   uint              // Protocol version negotiated
 
-	head common.Hash // Latest advertised head block hash
-	td   *big.Int    // Latest advertised head block total difficulty
+	head       common.Hash             // Latest advertised head block hash
+	td         *big.Int                // Latest advertised head block total difficulty
+	blockRange *BlockRangeUpdatePacket // Latest advertised range of served blocks (eth/69+)
 
 	txpool      TxPool             // Transaction pool used by the broadcasters for liveness checks
 	knownTxs    *knownCache        // Set of transaction hashes known to be known by this peer
@@ -126,6 +127,38 @@ func (p *Peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// BlockRange retrieves the latest range of blocks the peer advertised to serve.
+// The range is only known on eth/69 and newer, otherwise nil is returned.
+func (p *Peer) BlockRange() *BlockRangeUpdatePacket {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.blockRange == nil {
+		return nil
+	}
+	blockRange := *p.blockRange
+	return &blockRange
+}
+
+// setBlockRange updates the range of blocks served by the peer, along with the
+// head hash.
+func (p *Peer) setBlockRange(update *BlockRangeUpdatePacket) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.blockRange = update
+	p.head = update.LatestBlockHash
+}
+
+// SendBlockRangeUpdate announces the range of locally served blocks to the
+// peer. It is a noop before eth/69 which doesn't support range announcements.
+func (p *Peer) SendBlockRangeUpdate(update BlockRangeUpdatePacket) error {
+	if p.version < ETH69 {
+		return nil
+	}
+	return p2p.Send(p.rw, BlockRangeUpdateMsg, &update)
+}
+
 // KnownTransaction returns whether peer is known to already have a transaction.
 func (p *Peer) KnownTransaction(hash common.Hash) bool {
 	return p.knownTxs.Contains(hash)
@@ -0,0 +1,113 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	receiptStatusFailed     = []byte{}
+	receiptStatusSuccessful = []byte{0x01}
+)
+
+// Receipt69 is the network encoding of a receipt on eth/69 and newer. Instead
+// of the typed envelope of the consensus encoding, the transaction type is a
+// plain field, and the bloom filter is omitted as it can be recomputed from the
+// logs by the receiving side.
+type Receipt69 struct {
+	TxType            uint8
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Logs              []*types.Log
+}
+
+// newReceipt69 converts a receipt into its eth/69 network encoding.
+func newReceipt69(r *types.Receipt) *Receipt69 {
+	enc := &Receipt69{
+		TxType:            r.Type,
+		PostStateOrStatus: r.PostState,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+	if len(r.PostState) == 0 {
+		enc.PostStateOrStatus = receiptStatusSuccessful
+		if r.Status == types.ReceiptStatusFailed {
+			enc.PostStateOrStatus = receiptStatusFailed
+		}
+	}
+	if enc.Logs == nil {
+		enc.Logs = []*types.Log{}
+	}
+	return enc
+}
+
+// toReceipt converts the network encoding into a receipt with the consensus
+// fields filled, recomputing the bloom filter from the logs.
+func (r *Receipt69) toReceipt() (*types.Receipt, error) {
+	receipt := &types.Receipt{
+		Type:              r.TxType,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+	switch {
+	case bytes.Equal(r.PostStateOrStatus, receiptStatusSuccessful):
+		receipt.Status = types.ReceiptStatusSuccessful
+	case bytes.Equal(r.PostStateOrStatus, receiptStatusFailed):
+		receipt.Status = types.ReceiptStatusFailed
+	case len(r.PostStateOrStatus) == common.HashLength:
+		receipt.PostState = r.PostStateOrStatus
+	default:
+		return nil, fmt.Errorf("invalid receipt status %x", r.PostStateOrStatus)
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, nil
+}
+
+// ReceiptsResponse69 is the network packet for block receipts distribution on
+// eth/69 and newer.
+type ReceiptsResponse69 [][]*Receipt69
+
+// ReceiptsPacket69 is the network packet for block receipts distribution with
+// request ID wrapping on eth/69 and newer.
+type ReceiptsPacket69 struct {
+	RequestId uint64
+	ReceiptsResponse69
+}
+
+// toResponse converts the receipts into the protocol independent format.
+func (r ReceiptsResponse69) toResponse() (ReceiptsResponse, error) {
+	res := make(ReceiptsResponse, len(r))
+	for i, list := range r {
+		res[i] = make([]*types.Receipt, len(list))
+		for j, enc := range list {
+			receipt, err := enc.toReceipt()
+			if err != nil {
+				return nil, err
+			}
+			res[i][j] = receipt
+		}
+	}
+	return res, nil
+}
+
+func (*ReceiptsResponse69) Name() string { return "Receipts" }
+func (*ReceiptsResponse69) Kind() byte   { return ReceiptsMsg }
@@ -0,0 +1,76 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that receipts sent without bloom filters on eth/69 are restored with
+// the same consensus encoding on the receiving side.
+func TestReceipt69RoundTrip(t *testing.T) {
+	logs := []*types.Log{{
+		Address: common.Address{0x11},
+		Topics:  []common.Hash{{0x22}, {0x33}},
+		Data:    []byte{0x44, 0x55},
+	}}
+	receipts := []*types.Receipt{
+		{Type: types.LegacyTxType, PostState: common.Hash{0x01}.Bytes(), CumulativeGasUsed: 21000, Logs: logs},
+		{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 42000},
+		{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 63000, Logs: logs},
+		{Type: types.BlobTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 84000, Logs: logs},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	}
+	var response ReceiptsResponse69
+	for _, receipt := range receipts {
+		response = append(response, []*Receipt69{newReceipt69(receipt)})
+	}
+	enc, err := rlp.EncodeToBytes(&ReceiptsPacket69{RequestId: 1, ReceiptsResponse69: response})
+	if err != nil {
+		t.Fatalf("failed to encode receipts: %v", err)
+	}
+	var packet ReceiptsPacket69
+	if err := rlp.DecodeBytes(enc, &packet); err != nil {
+		t.Fatalf("failed to decode receipts: %v", err)
+	}
+	decoded, err := packet.ReceiptsResponse69.toResponse()
+	if err != nil {
+		t.Fatalf("failed to convert receipts: %v", err)
+	}
+	for i, receipt := range receipts {
+		want, _ := rlp.EncodeToBytes(receipt)
+		have, _ := rlp.EncodeToBytes(decoded[i][0])
+		if !bytes.Equal(have, want) {
+			t.Errorf("receipt %d mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
+
+// Tests that malformed receipt statuses are rejected.
+func TestReceipt69InvalidStatus(t *testing.T) {
+	response := ReceiptsResponse69{{{TxType: types.LegacyTxType, PostStateOrStatus: []byte{0x02}}}}
+	if _, err := response.toResponse(); err == nil {
+		t.Fatal("invalid receipt status accepted")
+	}
+}
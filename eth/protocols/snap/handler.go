@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -79,6 +80,10 @@ type Backend interface {
 	// the remote peer. Only packets not consumed by the protocol handler will
 	// be forwarded to the backend.
 	Handle(peer *Peer, packet Packet) error
+
+	// HistoricLimiter retrieves the throttler of the historical state
+	// reconstructions requested by remote peers.
+	HistoricLimiter() *HistoricLimiter
 }
 
 // MakeProtocols constructs the P2P protocol definitions for `snap`.
@@ -161,7 +166,7 @@ func HandleMessage(backend Backend, peer *Peer) error {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
 		// Service the request, potentially returning nothing in case of errors
-		accounts, proofs := ServiceGetAccountRangeQuery(backend.Chain(), &req)
+		accounts, proofs := ServiceGetAccountRangeQuery(backend.Chain(), &req, historicAllowance(backend, peer))
 
 		// Send back anything accumulated (or empty in case of errors)
 		return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{
@@ -193,7 +198,7 @@ func HandleMessage(backend Backend, peer *Peer) error {
 			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
 		}
 		// Service the request, potentially returning nothing in case of errors
-		slots, proofs := ServiceGetStorageRangesQuery(backend.Chain(), &req)
+		slots, proofs := ServiceGetStorageRangesQuery(backend.Chain(), &req, historicAllowance(backend, peer))
 
 		// Send back anything accumulated (or empty in case of errors)
 		return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{
@@ -277,18 +282,38 @@ func HandleMessage(backend Backend, peer *Peer) error {
 	}
 }
 
+// historicAllowance returns the callback throttling the historical state
+// reconstructions requested by the given peer.
+func historicAllowance(backend Backend, peer *Peer) func() bool {
+	return func() bool { return backend.HistoricLimiter().Allow(peer.id) }
+}
+
 // ServiceGetAccountRangeQuery assembles the response to an account range query.
-// It is exposed to allow external packages to test protocol behavior.
-func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePacket) ([]*AccountData, [][]byte) {
+// It is exposed to allow external packages to test protocol behavior. The
+// optional allow callback throttles the reconstruction of historical states.
+func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePacket, allow func() bool) ([]*AccountData, [][]byte) {
 	if req.Bytes > softResponseLimit {
 		req.Bytes = softResponseLimit
 	}
 	// Retrieve the requested state and bail out if non existent
-	tr, err := trie.New(trie.StateTrieID(req.Root), chain.TrieDB())
+	db, historic := stateDatabase(chain, req.Root, allow)
+	tr, err := trie.New(trie.StateTrieID(req.Root), db)
 	if err != nil {
 		return nil, nil
 	}
-	it, err := chain.Snapshots().AccountIterator(req.Root, req.Origin)
+	// The snapshot might be disabled by the user, in which case
+	// we can only serve the historical states reconstructed from the tries.
+	if !historic && chain.Snapshots() == nil {
+		return nil, nil
+	}
+	// Historical states are not available in the snapshot tree, iterate over
+	// the reconstructed trie instead.
+	var it snapshot.AccountIterator
+	if historic {
+		it, err = newHistoricAccountIterator(tr.Copy(), req.Origin)
+	} else {
+		it, err = chain.Snapshots().AccountIterator(req.Root, req.Origin)
+	}
 	if err != nil {
 		return nil, nil
 	}
@@ -339,7 +364,7 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 	return accounts, proofs
 }
 
-func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesPacket) ([][]*StorageData, [][]byte) {
+func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesPacket, allow func() bool) ([][]*StorageData, [][]byte) {
 	if req.Bytes > softResponseLimit {
 		req.Bytes = softResponseLimit
 	}
@@ -350,18 +375,34 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 	// Calculate the hard limit at which to abort, even if mid storage trie
 	hardLimit := uint64(float64(req.Bytes) * (1 + stateLookupSlack))
 
+	// Resolve the state once, it might need to be reconstructed from the state
+	// histories if it's no longer available in the snapshot tree.
+	db, historic := stateDatabase(chain, req.Root, allow)
+
+	// The snapshot might be disabled by the user, in which case
+	// we can only serve the historical states reconstructed from the tries.
+	if !historic && chain.Snapshots() == nil {
+		return nil, nil
+	}
+
 	// Retrieve storage ranges until the packet limit is reached
 	var (
 		slots  [][]*StorageData
 		proofs [][]byte
 		size   uint64
 	)
+	partial := chain.PartialState()
 	for _, account := range req.Accounts {
 		// If we've exceeded the requested data limit, abort without opening
 		// a new storage range (that we'd need to prove due to exceeded size)
 		if size >= req.Bytes {
 			break
 		}
+		// The partial state node lacks the storage of the untracked contracts,
+		// abort at the first one as the ranges are delivered in order
+		if !partial.TrackedHash(account) {
+			break
+		}
 		// The first account might start from a different origin and end sooner
 		var origin common.Hash
 		if len(req.Origin) > 0 {
@@ -372,7 +413,15 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			limit, req.Limit = common.BytesToHash(req.Limit), nil
 		}
 		// Retrieve the requested state and bail out if non existent
-		it, err := chain.Snapshots().StorageIterator(req.Root, account, origin)
+		var (
+			it  snapshot.StorageIterator
+			err error
+		)
+		if historic {
+			it, err = newHistoricStorageIterator(db, req.Root, account, origin)
+		} else {
+			it, err = chain.Snapshots().StorageIterator(req.Root, account, origin)
+		}
 		if err != nil {
 			return nil, nil
 		}
@@ -414,7 +463,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 		if origin != (common.Hash{}) || (abort && len(storage) > 0) {
 			// Request started at a non-zero hash or was capped prematurely, add
 			// the endpoint Merkle proofs
-			accTrie, err := trie.NewStateTrie(trie.StateTrieID(req.Root), chain.TrieDB())
+			accTrie, err := trie.NewStateTrie(trie.StateTrieID(req.Root), db)
 			if err != nil {
 				return nil, nil
 			}
@@ -423,7 +472,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 				return nil, nil
 			}
 			id := trie.StorageTrieID(req.Root, account, acc.Root)
-			stTrie, err := trie.NewStateTrie(id, chain.TrieDB())
+			stTrie, err := trie.NewStateTrie(id, db)
 			if err != nil {
 				return nil, nil
 			}
@@ -495,7 +544,10 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 		return nil, nil
 	}
 	// The 'snap' might be nil, in which case we cannot serve storage slots.
-	snap := chain.Snapshots().Snapshot(req.Root)
+	var snap snapshot.Snapshot
+	if tree := chain.Snapshots(); tree != nil {
+		snap = tree.Snapshot(req.Root)
+	}
 	// Retrieve trie nodes until the packet size limit is reached
 	var (
 		nodes [][]byte
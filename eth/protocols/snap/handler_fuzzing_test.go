@@ -78,7 +78,7 @@ func doFuzz(input []byte, obj interface{}, code int) {
 		data: data,
 	}
 	peer := NewFakePeer(65, "gazonk01", cli)
-	err := HandleMessage(&dummyBackend{bc}, peer)
+	err := HandleMessage(&dummyBackend{bc, NewHistoricLimiter()}, peer)
 	switch {
 	case err == nil && cli.writeCount != 1:
 		panic(fmt.Sprintf("Expected 1 response, got %d", cli.writeCount))
@@ -134,13 +134,17 @@ func getChain() *core.BlockChain {
 }
 
 type dummyBackend struct {
-	chain *core.BlockChain
+	chain   *core.BlockChain
+	limiter *HistoricLimiter
 }
 
 func (d *dummyBackend) Chain() *core.BlockChain       { return d.chain }
 func (d *dummyBackend) RunPeer(*Peer, Handler) error  { return nil }
 func (d *dummyBackend) PeerInfo(enode.ID) interface{} { return "Foo" }
 func (d *dummyBackend) Handle(*Peer, Packet) error    { return nil }
+func (d *dummyBackend) HistoricLimiter() *HistoricLimiter {
+	return d.limiter
+}
 
 type dummyRW struct {
 	code       uint64
@@ -0,0 +1,199 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+// historicReconstructInterval is the minimum time between two reconstructions of
+// historical states triggered by the same peer. A reconstruction reverts up to
+// 128 state histories in memory, which is far more expensive than serving any
+// other request, so a single peer must not keep the node busy with them.
+const historicReconstructInterval = 10 * time.Second
+
+// HistoricLimiter tracks the last reconstruction of historical states triggered
+// by each remote peer, throttling them to one per historicReconstructInterval.
+// Peers are tracked by their ids, so reconnecting doesn't reset them.
+type HistoricLimiter struct {
+	last map[string]time.Time
+	lock sync.Mutex
+}
+
+// NewHistoricLimiter creates a throttler of historical state reconstructions.
+func NewHistoricLimiter() *HistoricLimiter {
+	return &HistoricLimiter{last: make(map[string]time.Time)}
+}
+
+// Allow reports whether the given peer may trigger a new reconstruction, and if
+// so, records it as the peer's last one.
+func (l *HistoricLimiter) Allow(peer string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if last, ok := l.last[peer]; ok && now.Sub(last) < historicReconstructInterval {
+		return false
+	}
+	// Drop the expired entries to keep the tracker from growing unbounded
+	for id, last := range l.last {
+		if now.Sub(last) >= historicReconstructInterval {
+			delete(l.last, id)
+		}
+	}
+	l.last[peer] = now
+	return true
+}
+
+// stateDatabase returns the trie database for accessing the requested state. If
+// the state is no longer available in the trie database, but the path-based
+// scheme can reconstruct it from the state histories, the reconstructed one is
+// returned instead and the historic flag is set. Such states are not available
+// in the snapshot tree either, so their ranges must be served from the tries.
+//
+// The optional allow callback is consulted before starting a new reconstruction,
+// the already available historical states are served regardless.
+func stateDatabase(chain *core.BlockChain, root common.Hash, allow func() bool) (db database.Database, historic bool) {
+	if _, err := chain.TrieDB().Reader(root); err == nil {
+		return chain.TrieDB(), false
+	}
+	if db, err := chain.TrieDB().HistoricNodes(root, allow); err == nil {
+		return db, true
+	}
+	return chain.TrieDB(), false
+}
+
+// historicAccountIterator is an account iterator stepping over the account trie
+// of a historical state, which is not available in the snapshot tree.
+type historicAccountIterator struct {
+	it      *trie.Iterator
+	account []byte // Current account in slim format
+	err     error
+}
+
+// newHistoricAccountIterator creates an account iterator over the given account
+// trie, starting at the given account hash.
+func newHistoricAccountIterator(tr *trie.Trie, origin common.Hash) (snapshot.AccountIterator, error) {
+	nodeIt, err := tr.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &historicAccountIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *historicAccountIterator) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		return false
+	}
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(it.it.Value, &account); err != nil {
+		it.err = err
+		return false
+	}
+	it.account = types.SlimAccountRLP(account)
+	return true
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *historicAccountIterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Err
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *historicAccountIterator) Hash() common.Hash {
+	return common.BytesToHash(it.it.Key)
+}
+
+// Account returns the RLP encoded slim account the iterator is currently at.
+func (it *historicAccountIterator) Account() []byte {
+	return it.account
+}
+
+// Release is a noop for the trie backed iterator.
+func (it *historicAccountIterator) Release() {}
+
+// historicStorageIterator is a storage iterator stepping over the storage trie
+// of an account in a historical state, which is not available in the snapshot
+// tree.
+type historicStorageIterator struct {
+	it *trie.Iterator // Nil if the account has no storage
+}
+
+// newHistoricStorageIterator creates a storage iterator over the storage trie of
+// the given account, starting at the given slot hash. A missing account yields
+// an empty iterator, same as the snapshot one.
+func newHistoricStorageIterator(db database.Database, root common.Hash, account common.Hash, origin common.Hash) (snapshot.StorageIterator, error) {
+	accTrie, err := trie.NewStateTrie(trie.StateTrieID(root), db)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := accTrie.GetAccountByHash(account)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil || acc.Root == types.EmptyRootHash {
+		return &historicStorageIterator{}, nil
+	}
+	stTrie, err := trie.New(trie.StorageTrieID(root, account, acc.Root), db)
+	if err != nil {
+		return nil, err
+	}
+	nodeIt, err := stTrie.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &historicStorageIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *historicStorageIterator) Next() bool {
+	return it.it != nil && it.it.Next()
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *historicStorageIterator) Error() error {
+	if it.it == nil {
+		return nil
+	}
+	return it.it.Err
+}
+
+// Hash returns the hash of the storage slot the iterator is currently at.
+func (it *historicStorageIterator) Hash() common.Hash {
+	return common.BytesToHash(it.it.Key)
+}
+
+// Slot returns the RLP encoded storage slot the iterator is currently at.
+func (it *historicStorageIterator) Slot() []byte {
+	return it.it.Value
+}
+
+// Release is a noop for the trie backed iterator.
+func (it *historicStorageIterator) Release() {}
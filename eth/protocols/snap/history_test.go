@@ -0,0 +1,147 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newHistoryTestChain creates a path-based chain with state histories, importing
+// the given blocks. The snapshots are optionally disabled.
+func newHistoryTestChain(t *testing.T, gspec *core.Genesis, blocks []*types.Block, snapshots bool) *core.BlockChain {
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	config := *core.DefaultCacheConfigWithScheme(rawdb.PathScheme)
+	config.SnapshotWait = true
+	if !snapshots {
+		config.SnapshotLimit = 0
+	}
+
+	chain, err := core.NewBlockChain(db, &config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+	return chain
+}
+
+// Tests that account and storage ranges of states which have already left the
+// snapshot tree are served from the state histories, identically to how they
+// were served while the states were still live.
+func TestServeHistoricalRanges(t *testing.T)                { testServeHistoricalRanges(t, true) }
+func TestServeHistoricalRangesWithoutSnapshot(t *testing.T) { testServeHistoricalRanges(t, false) }
+
+func testServeHistoricalRanges(t *testing.T, snapshots bool) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		signer   = types.HomesteadSigner{}
+		alloc    = types.GenesisAlloc{
+			sender: {Balance: big.NewInt(params.Ether)},
+			// SSTORE(NUMBER, NUMBER)
+			contract: {Balance: common.Big0, Code: []byte{0x43, 0x43, 0x55, 0x00}},
+		}
+	)
+	for i := 0; i < 100; i++ {
+		alloc[common.BigToAddress(big.NewInt(int64(0x1000+i)))] = types.Account{Balance: big.NewInt(int64(i + 1))}
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc}
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 160, func(i int, gen *core.BlockGen) {
+		// Touch the contract storage and fund a new account in every block
+		nonce := gen.TxNonce(sender)
+		tx, _ := types.SignTx(types.NewTransaction(nonce, contract, common.Big0, 50000, gen.BaseFee(), nil), signer, key)
+		gen.AddTx(tx)
+		tx, _ = types.SignTx(types.NewTransaction(nonce+1, common.BigToAddress(big.NewInt(int64(0x2000+i))), common.Big1, params.TxGas, gen.BaseFee(), nil), signer, key)
+		gen.AddTx(tx)
+	})
+	var (
+		number = 20
+		root   = blocks[number-1].Root()
+		live   = newHistoryTestChain(t, gspec, blocks[:number], true)
+		old    = newHistoryTestChain(t, gspec, blocks, snapshots)
+
+		limiter = NewHistoricLimiter()
+		peer    = func() bool { return limiter.Allow("peer") }
+		other   = func() bool { return limiter.Allow("other") }
+	)
+	if _, err := old.TrieDB().Reader(root); err == nil {
+		t.Fatal("state is still live")
+	}
+	// Compare the account ranges, with and without size caps
+	for _, bytes := range []uint64{softResponseLimit, 500} {
+		var (
+			req = GetAccountRangePacket{Root: root, Limit: common.MaxHash, Bytes: bytes}
+			cpy = req
+		)
+		wantAccs, wantProofs := ServiceGetAccountRangeQuery(live, &req, nil)
+		haveAccs, haveProofs := ServiceGetAccountRangeQuery(old, &cpy, peer)
+		if len(wantAccs) == 0 || len(wantProofs) == 0 {
+			t.Fatalf("bytes %d: live state not served", bytes)
+		}
+		if !reflect.DeepEqual(haveAccs, wantAccs) {
+			t.Errorf("bytes %d: account mismatch: have %d accounts, want %d", bytes, len(haveAccs), len(wantAccs))
+		}
+		if !reflect.DeepEqual(haveProofs, wantProofs) {
+			t.Errorf("bytes %d: account proof mismatch", bytes)
+		}
+	}
+	// Compare the storage ranges, including a capped one requiring proofs
+	account := crypto.Keccak256Hash(contract.Bytes())
+	for _, bytes := range []uint64{softResponseLimit, 100} {
+		var (
+			req = GetStorageRangesPacket{Root: root, Accounts: []common.Hash{account, account}, Bytes: bytes}
+			cpy = req
+		)
+		wantSlots, wantProofs := ServiceGetStorageRangesQuery(live, &req, nil)
+		haveSlots, haveProofs := ServiceGetStorageRangesQuery(old, &cpy, peer)
+		if len(wantSlots) == 0 || (bytes < softResponseLimit && len(wantProofs) == 0) {
+			t.Fatalf("bytes %d: live storage not served", bytes)
+		}
+		if !reflect.DeepEqual(haveSlots, wantSlots) {
+			t.Errorf("bytes %d: storage mismatch", bytes)
+		}
+		if !reflect.DeepEqual(haveProofs, wantProofs) {
+			t.Errorf("bytes %d: storage proof mismatch", bytes)
+		}
+	}
+	// Reconstructing another historical state is throttled for the same peer,
+	// but not for others
+	req := GetAccountRangePacket{Root: blocks[number].Root(), Limit: common.MaxHash, Bytes: softResponseLimit}
+	if accs, _ := ServiceGetAccountRangeQuery(old, &req, peer); len(accs) != 0 {
+		t.Fatal("throttled reconstruction served")
+	}
+	if accs, _ := ServiceGetAccountRangeQuery(old, &req, other); len(accs) == 0 {
+		t.Fatal("reconstruction for another peer not served")
+	}
+}
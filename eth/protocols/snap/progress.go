@@ -0,0 +1,241 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	etaSampleInterval = 10 * time.Second // Minimum time between two rate samples
+	etaRateImpact     = 0.2              // Weight of a new rate sample in the smoothed rate
+)
+
+// Phases of the snap sync, as reported in SyncStatus.
+const (
+	SyncPhaseIdle     = "idle"     // No sync cycle has been started yet
+	SyncPhaseDownload = "download" // Accounts, storage and bytecodes are downloaded
+	SyncPhaseHealing  = "healing"  // The downloaded state is being healed
+	SyncPhaseDone     = "done"     // The state is complete
+)
+
+// SyncStatus is a structured report on the progress of the snap sync.
+type SyncStatus struct {
+	Phase string `json:"phase"` // Current phase of the sync
+
+	// Download phase
+	AccountRangeProgress float64        `json:"accountRangeProgress"` // Percentage of the account hash space downloaded
+	AccountTasks         hexutil.Uint64 `json:"accountTasks"`         // Number of account ranges still being downloaded
+	StorageBacklog       hexutil.Uint64 `json:"storageBacklog"`       // Number of accounts with storage pending retrieval
+	StorageTasks         hexutil.Uint64 `json:"storageTasks"`         // Number of chunked storage ranges of large contracts pending
+	BytecodeBacklog      hexutil.Uint64 `json:"bytecodeBacklog"`      // Number of bytecodes pending retrieval
+	SyncedAccounts       hexutil.Uint64 `json:"syncedAccounts"`       // Number of accounts downloaded
+	SyncedStorage        hexutil.Uint64 `json:"syncedStorage"`        // Number of storage slots downloaded
+	SyncedBytecodes      hexutil.Uint64 `json:"syncedBytecodes"`      // Number of bytecodes downloaded
+
+	// Healing phase
+	HealingTrienodes hexutil.Uint64 `json:"healingTrienodes"` // Number of trie nodes queued for retrieval
+	HealingBytecodes hexutil.Uint64 `json:"healingBytecodes"` // Number of bytecodes queued for retrieval
+	HealingBacklog   hexutil.Uint64 `json:"healingBacklog"`   // Number of trie nodes and bytecodes known to be missing
+	HealedTrienodes  hexutil.Uint64 `json:"healedTrienodes"`  // Number of trie nodes downloaded
+	HealedBytecodes  hexutil.Uint64 `json:"healedBytecodes"`  // Number of bytecodes downloaded
+
+	// ETA is the estimated number of seconds until the current phase completes,
+	// based on the recent rate of progress. It is zero if there is no estimate.
+	ETA hexutil.Uint64 `json:"eta"`
+}
+
+// healNode is a trie node which has been retrieved during healing, but could not
+// be committed yet because parts of its subtrie are missing. These nodes are
+// checkpointed on shutdown, so that they need not be retrieved again when the
+// sync is resumed.
+type healNode struct {
+	Path []byte // Path of the trie node
+	Data []byte // Content of the trie node
+}
+
+// progressRate tracks the smoothed rate of a growing progress measure, which is
+// used for estimating the remaining time of a sync phase.
+type progressRate struct {
+	value float64   // Progress measure at the last sample
+	time  time.Time // Time of the last sample
+	rate  float64   // Smoothed progress per second
+}
+
+// update samples the progress measure.
+func (r *progressRate) update(value float64, now time.Time) {
+	// Start over if there is no previous sample, or the measure went backwards
+	// which happens if a new sync cycle is started.
+	if r.time.IsZero() || value < r.value {
+		*r = progressRate{value: value, time: now}
+		return
+	}
+	elapsed := now.Sub(r.time)
+	if elapsed < etaSampleInterval {
+		return
+	}
+	rate := (value - r.value) / elapsed.Seconds()
+	if r.rate == 0 {
+		r.rate = rate
+	} else {
+		r.rate = (1-etaRateImpact)*r.rate + etaRateImpact*rate
+	}
+	r.value, r.time = value, now
+}
+
+// eta returns the estimated time needed for the remaining progress, or zero if
+// there is no estimate yet.
+func (r *progressRate) eta(remaining float64) time.Duration {
+	if r.rate <= 0 || remaining <= 0 {
+		return 0
+	}
+	return time.Duration(remaining / r.rate * float64(time.Second))
+}
+
+// accountCompletion returns the fraction of the account hash space which has
+// been downloaded.
+func (s *Syncer) accountCompletion() float64 {
+	if len(s.tasks) == 0 {
+		return 1
+	}
+	gaps := new(big.Int)
+	for _, task := range s.tasks {
+		gaps.Add(gaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
+	}
+	fills := new(big.Float).SetInt(new(big.Int).Sub(hashSpace, gaps))
+	completion, _ := new(big.Float).Quo(fills, new(big.Float).SetInt(hashSpace)).Float64()
+	return completion
+}
+
+// updateStatus recalculates the externally visible sync status, also sampling
+// the progress rates for the ETA estimation. It must be called from the sync
+// loop with the lock held.
+func (s *Syncer) updateStatus(now time.Time) {
+	status := &SyncStatus{
+		SyncedAccounts:  hexutil.Uint64(s.accountSynced),
+		SyncedStorage:   hexutil.Uint64(s.storageSynced),
+		SyncedBytecodes: hexutil.Uint64(s.bytecodeSynced),
+		HealedTrienodes: hexutil.Uint64(s.trienodeHealSynced),
+		HealedBytecodes: hexutil.Uint64(s.bytecodeHealSynced),
+	}
+	if len(s.tasks) > 0 {
+		completion := s.accountCompletion()
+		status.Phase = SyncPhaseDownload
+		status.AccountRangeProgress = completion * 100
+		status.AccountTasks = hexutil.Uint64(len(s.tasks))
+		for _, task := range s.tasks {
+			status.StorageBacklog += hexutil.Uint64(len(task.stateTasks) + len(task.SubTasks))
+			status.BytecodeBacklog += hexutil.Uint64(len(task.codeTasks))
+			for _, subtasks := range task.SubTasks {
+				status.StorageTasks += hexutil.Uint64(len(subtasks))
+			}
+		}
+		s.downloadRate.update(completion, now)
+		status.ETA = hexutil.Uint64(s.downloadRate.eta(1-completion) / time.Second)
+	} else {
+		status.Phase = SyncPhaseHealing
+		status.AccountRangeProgress = 100
+		status.HealingTrienodes = hexutil.Uint64(len(s.healer.trieTasks))
+		status.HealingBytecodes = hexutil.Uint64(len(s.healer.codeTasks))
+		status.HealingBacklog = hexutil.Uint64(s.healer.scheduler.Pending())
+		if status.HealingBacklog == 0 {
+			status.Phase = SyncPhaseDone
+		}
+		s.healRate.update(float64(s.trienodeHealSynced+s.bytecodeHealSynced), now)
+		status.ETA = hexutil.Uint64(s.healRate.eta(float64(status.HealingBacklog)) / time.Second)
+	}
+	s.extStatus = status
+}
+
+// Status returns a structured report on the progress of the snap sync.
+func (s *Syncer) Status() *SyncStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.extStatus == nil {
+		return &SyncStatus{Phase: SyncPhaseIdle}
+	}
+	status := *s.extStatus
+	return &status
+}
+
+// healCheckpoint returns the trie nodes needed to resume healing without
+// retrieving them again. This includes the retrieved nodes which are still
+// waiting for their children, as well as any restored nodes not yet reused.
+func (s *Syncer) healCheckpoint() []*healNode {
+	if s.healer == nil || s.healer.scheduler.Pending() == 0 {
+		return nil
+	}
+	var nodes []*healNode
+	for _, res := range s.healer.scheduler.Fetched() {
+		nodes = append(nodes, &healNode{Path: []byte(res.Path), Data: res.Data})
+	}
+	for path, data := range s.healCache {
+		nodes = append(nodes, &healNode{Path: []byte(path), Data: data})
+	}
+	return nodes
+}
+
+// fillHealTasks fills the heal task queues from the state sync scheduler, up to
+// the given number of tasks. Trie nodes available from the healing checkpoint
+// are fed back into the scheduler directly instead of being queued.
+func (s *Syncer) fillHealTasks(want int) {
+	for {
+		var (
+			have  = len(s.healer.trieTasks) + len(s.healer.codeTasks)
+			reuse int
+		)
+		if have >= want {
+			return
+		}
+		paths, hashes, codes := s.healer.scheduler.Missing(want - have)
+		for i, path := range paths {
+			if data, ok := s.healCache[path]; ok {
+				delete(s.healCache, path)
+				if crypto.Keccak256Hash(data) == hashes[i] {
+					if err := s.healer.scheduler.ProcessNode(trie.NodeSyncResult{Path: path, Data: data}); err == nil {
+						reuse++
+						continue
+					}
+				}
+			}
+			s.healer.trieTasks[path] = hashes[i]
+		}
+		for _, hash := range codes {
+			s.healer.codeTasks[hash] = struct{}{}
+		}
+		// Reusing nodes might have scheduled their children, retry to fill up
+		// the queues with those.
+		if reuse == 0 {
+			return
+		}
+		s.commitHealer(false)
+	}
+}
+
+// restoreHealCheckpoint loads the trie nodes of a healing checkpoint.
+func (s *Syncer) restoreHealCheckpoint(nodes []*healNode) {
+	s.healCache = make(map[string][]byte, len(nodes))
+	for _, node := range nodes {
+		s.healCache[string(node.Path)] = node.Data
+	}
+}
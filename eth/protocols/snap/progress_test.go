@@ -18,9 +18,13 @@ package snap
 
 import (
 	"encoding/json"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Legacy sync progress definitions
@@ -152,3 +156,154 @@ func TestSyncProgressCompatibility(t *testing.T) {
 		t.Fatal("sync progress is not forward compatible")
 	}
 }
+
+// Tests that the ETA is estimated from the smoothed rate of progress.
+func TestProgressRate(t *testing.T) {
+	var (
+		r   progressRate
+		now = time.Unix(1000, 0)
+	)
+	r.update(0, now)
+	if eta := r.eta(1); eta != 0 {
+		t.Fatalf("ETA %v without rate samples", eta)
+	}
+	// Samples within the interval are ignored.
+	r.update(0.5, now.Add(time.Second))
+	if r.rate != 0 {
+		t.Fatalf("rate %v sampled before interval", r.rate)
+	}
+	now = now.Add(etaSampleInterval)
+	r.update(0.1, now)
+	if want := 0.1 / etaSampleInterval.Seconds(); r.rate != want {
+		t.Fatalf("wrong rate %v, want %v", r.rate, want)
+	}
+	if eta, want := r.eta(0.9), 9*etaSampleInterval; eta != want {
+		t.Fatalf("wrong ETA %v, want %v", eta, want)
+	}
+	// Going backwards resets the estimator.
+	r.update(0.05, now.Add(etaSampleInterval))
+	if eta := r.eta(1); eta != 0 {
+		t.Fatalf("ETA %v after reset", eta)
+	}
+}
+
+// Tests that the sync status is reported throughout the sync.
+func TestSyncStatus(t *testing.T) {
+	t.Parallel()
+
+	testSyncStatus(t, rawdb.HashScheme)
+	testSyncStatus(t, rawdb.PathScheme)
+}
+
+func testSyncStatus(t *testing.T, scheme string) {
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	nodeScheme, sourceAccountTrie, elems := makeAccountTrieNoStorage(100, scheme)
+
+	source := newTestPeer("source", t, term)
+	source.accountTrie = sourceAccountTrie.Copy()
+	source.accountValues = elems
+
+	syncer := setupSyncer(nodeScheme, source)
+	if status := syncer.Status(); status.Phase != SyncPhaseIdle {
+		t.Fatalf("wrong phase before sync: %v", status.Phase)
+	}
+	if err := syncer.Sync(sourceAccountTrie.Hash(), cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	status := syncer.Status()
+	if status.Phase != SyncPhaseDone {
+		t.Errorf("wrong phase after sync: %v", status.Phase)
+	}
+	if status.AccountRangeProgress != 100 {
+		t.Errorf("wrong account range progress after sync: %v", status.AccountRangeProgress)
+	}
+	if status.SyncedAccounts != 100 {
+		t.Errorf("wrong number of synced accounts: %d", status.SyncedAccounts)
+	}
+	if status.AccountTasks != 0 || status.HealingBacklog != 0 {
+		t.Errorf("pending work after sync: %+v", status)
+	}
+}
+
+// Tests that trie nodes retrieved by an interrupted heal phase are checkpointed
+// and not retrieved again when the sync is resumed.
+func TestSyncHealCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	testSyncHealCheckpoint(t, rawdb.HashScheme)
+	testSyncHealCheckpoint(t, rawdb.PathScheme)
+}
+
+func testSyncHealCheckpoint(t *testing.T, scheme string) {
+	var (
+		once   sync.Once
+		cancel = make(chan struct{})
+		term   = func() {
+			once.Do(func() {
+				close(cancel)
+			})
+		}
+	)
+	nodeScheme, sourceAccountTrie, elems := makeAccountTrieNoStorage(100, scheme)
+	root := sourceAccountTrie.Hash()
+
+	// Heal part of the state trie and interrupt the sync, checkpointing the
+	// retrieved nodes.
+	syncer := setupSyncer(nodeScheme)
+	syncer.healer = &healTask{
+		scheduler: trie.NewSync(root, syncer.db, nil, nodeScheme),
+		trieTasks: make(map[string]common.Hash),
+		codeTasks: make(map[common.Hash]struct{}),
+	}
+	for i := 0; i < 2; i++ {
+		paths, _, _ := syncer.healer.scheduler.Missing(4)
+		for _, path := range paths {
+			blob, _, err := sourceAccountTrie.GetNode(trie.NewSyncPath([]byte(path))[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := syncer.healer.scheduler.ProcessNode(trie.NodeSyncResult{Path: path, Data: blob}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	syncer.commitHealer(true)
+	syncer.saveSyncStatus()
+
+	checkpointed := make(map[string]bool)
+	for _, res := range syncer.healer.scheduler.Fetched() {
+		checkpointed[string(trie.NewSyncPath([]byte(res.Path))[0])] = true
+	}
+	if len(checkpointed) == 0 {
+		t.Fatal("no trie nodes checkpointed")
+	}
+
+	// Resume the sync, none of the checkpointed nodes may be requested.
+	source := newTestPeer("source", t, term)
+	source.accountTrie = sourceAccountTrie.Copy()
+	source.accountValues = elems
+	source.trieRequestHandler = func(tp *testPeer, requestId uint64, root common.Hash, paths []TrieNodePathSet, cap uint64) error {
+		for _, pathset := range paths {
+			if checkpointed[string(pathset[0])] {
+				t.Errorf("checkpointed trie node %x requested again", pathset[0])
+			}
+		}
+		return defaultTrieRequestHandler(tp, requestId, root, paths, cap)
+	}
+	resumed := NewSyncer(syncer.db, nodeScheme)
+	resumed.Register(source)
+	source.remote = resumed
+
+	if err := resumed.Sync(root, cancel); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	verifyTrie(scheme, resumed.db, root, t)
+}
@@ -113,6 +113,10 @@ var (
 // terminated.
 var ErrCancelled = errors.New("sync cancelled")
 
+// errNodeUnavailable is returned if none of the connected peers could deliver
+// a trie node requested outside of the sync.
+var errNodeUnavailable = errors.New("trie node unavailable from peers")
+
 // accountRequest tracks a pending account range request to ensure responses are
 // to actual requests and to validate any security constraints.
 //
@@ -396,6 +400,9 @@ type SyncProgress struct {
 	TrienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
 	BytecodeHealSynced uint64             // Number of bytecodes downloaded
 	BytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+
+	// Checkpoint of the healing phase
+	HealNodes []*healNode `json:",omitempty"` // Retrieved trie nodes which could not be committed yet
 }
 
 // SyncPending is analogous to SyncProgress, but it's used to report on pending
@@ -444,20 +451,24 @@ type SyncPeer interface {
 //   - The peer delivers a stale response after a previous timeout
 //   - The peer delivers a refusal to serve the requested state
 type Syncer struct {
-	db     ethdb.KeyValueStore // Database to store the trie nodes into (and dedup)
-	scheme string              // Node scheme used in node database
+	db      ethdb.KeyValueStore // Database to store the trie nodes into (and dedup)
+	scheme  string              // Node scheme used in node database
+	partial *state.PartialState // Contracts whose storage is synced, nil for the full state
 
-	root    common.Hash    // Current state trie root being synced
-	tasks   []*accountTask // Current account task set being synced
-	snapped bool           // Flag to signal that snap phase is done
-	healer  *healTask      // Current state healing task being executed
-	update  chan struct{}  // Notification channel for possible sync progression
+	root      common.Hash       // Current state trie root being synced
+	tasks     []*accountTask    // Current account task set being synced
+	snapped   bool              // Flag to signal that snap phase is done
+	healer    *healTask         // Current state healing task being executed
+	healCache map[string][]byte // Trie nodes restored from the healing checkpoint, indexed by path
+	update    chan struct{}     // Notification channel for possible sync progression
 
 	peers    map[string]SyncPeer // Currently active peers to download from
 	peerJoin *event.Feed         // Event feed to react to peers joining
 	peerDrop *event.Feed         // Event feed to react to peers dropping
 	rates    *msgrate.Trackers   // Message throughput rates for peers
 
+	nodeReqs map[uint64]chan [][]byte // Trie node requests issued outside of sync, not reset between cycles
+
 	// Request tracking during syncing phase
 	statelessPeers map[string]struct{} // Peers that failed to deliver state data
 	accountIdlers  map[string]struct{} // Peers that aren't serving account requests
@@ -476,6 +487,10 @@ type Syncer struct {
 	storageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
 
 	extProgress *SyncProgress // progress that can be exposed to external caller.
+	extStatus   *SyncStatus   // structured progress report that can be exposed to external caller.
+
+	downloadRate progressRate // Rate of account range completion, for ETA estimation
+	healRate     progressRate // Rate of healed trie nodes and bytecodes, for ETA estimation
 
 	// Request tracking during healing phase
 	trienodeHealIdlers map[string]struct{} // Peers that aren't serving trie node requests
@@ -504,8 +519,7 @@ type Syncer struct {
 	storageHealed      uint64             // Number of storage slots downloaded during the healing stage
 	storageHealedBytes common.StorageSize // Number of raw storage bytes persisted to disk during the healing stage
 
-	startTime time.Time // Time instance when snapshot sync started
-	logTime   time.Time // Time instance when status was last reported
+	logTime time.Time // Time instance when status was last reported
 
 	pend sync.WaitGroup // Tracks network request goroutines for graceful shutdown
 	lock sync.RWMutex   // Protects fields that can change outside of sync (peers, reqs, root)
@@ -514,14 +528,23 @@ type Syncer struct {
 // NewSyncer creates a new snapshot syncer to download the Ethereum state over the
 // snap protocol.
 func NewSyncer(db ethdb.KeyValueStore, scheme string) *Syncer {
+	return NewPartialSyncer(db, scheme, nil)
+}
+
+// NewPartialSyncer creates a new snapshot syncer to download the Ethereum state
+// over the snap protocol, which only retrieves the storage of the contracts
+// tracked by the partial state.
+func NewPartialSyncer(db ethdb.KeyValueStore, scheme string, partial *state.PartialState) *Syncer {
 	return &Syncer{
-		db:     db,
-		scheme: scheme,
+		db:      db,
+		scheme:  scheme,
+		partial: partial,
 
 		peers:    make(map[string]SyncPeer),
 		peerJoin: new(event.Feed),
 		peerDrop: new(event.Feed),
 		rates:    msgrate.NewTrackers(log.New("proto", "snap")),
+		nodeReqs: make(map[uint64]chan [][]byte),
 		update:   make(chan struct{}, 1),
 
 		accountIdlers:  make(map[string]struct{}),
@@ -600,6 +623,75 @@ func (s *Syncer) Unregister(id string) error {
 	return nil
 }
 
+// ResolveNode retrieves a single trie node of the given state from the connected
+// peers, independently of any running sync. It's used by the partial state node
+// to resolve the storage of the untracked contracts touched by block execution.
+func (s *Syncer) ResolveNode(root common.Hash, owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	// Assemble the composite path of the node, storage nodes are prefixed with
+	// the nibbles of the owning account
+	composite := path
+	if owner != (common.Hash{}) {
+		composite = make([]byte, 0, 2*common.HashLength+len(path))
+		for _, b := range owner {
+			composite = append(composite, b>>4, b&0x0f)
+		}
+		composite = append(composite, path...)
+	}
+	_, _, _, pathsets := sortByAccountPath([]string{string(composite)}, []common.Hash{hash})
+
+	s.lock.RLock()
+	peers := make([]SyncPeer, 0, len(s.peers))
+	for _, peer := range s.peers {
+		peers = append(peers, peer)
+	}
+	s.lock.RUnlock()
+
+	// Query the peers one by one until the node is delivered
+	for _, peer := range peers {
+		var (
+			reqid   uint64
+			deliver = make(chan [][]byte, 1)
+		)
+		s.lock.Lock()
+		for {
+			reqid = uint64(rand.Int63())
+			if reqid == 0 {
+				continue
+			}
+			if _, ok := s.trienodeHealReqs[reqid]; ok {
+				continue
+			}
+			if _, ok := s.nodeReqs[reqid]; ok {
+				continue
+			}
+			break
+		}
+		s.nodeReqs[reqid] = deliver
+		s.lock.Unlock()
+
+		var nodes [][]byte
+		if err := peer.RequestTrieNodes(reqid, root, pathsets, maxRequestSize); err != nil {
+			peer.Log().Debug("Failed to request trie node", "err", err)
+		} else {
+			timeout := time.NewTimer(s.rates.TargetTimeout())
+			select {
+			case nodes = <-deliver:
+			case <-timeout.C:
+				peer.Log().Debug("Trie node request timed out", "reqid", reqid)
+			}
+			timeout.Stop()
+		}
+		s.lock.Lock()
+		delete(s.nodeReqs, reqid)
+		s.lock.Unlock()
+
+		if len(nodes) == 1 && crypto.Keccak256Hash(nodes[0]) == hash {
+			return nodes[0], nil
+		}
+	}
+	return nil, errNodeUnavailable
+}
+
 // Sync starts (or resumes a previous) sync cycle to iterate over a state trie
 // with the given root and reconstruct the nodes based on the snapshot leaves.
 // Previously downloaded segments will not be redownloaded of fixed, rather any
@@ -610,16 +702,13 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	s.lock.Lock()
 	s.root = root
 	s.healer = &healTask{
-		scheduler: state.NewStateSync(root, s.db, s.onHealState, s.scheme),
+		scheduler: state.NewPartialStateSync(root, s.db, s.onHealState, s.scheme, s.partial),
 		trieTasks: make(map[string]common.Hash),
 		codeTasks: make(map[common.Hash]struct{}),
 	}
 	s.statelessPeers = make(map[string]struct{})
 	s.lock.Unlock()
 
-	if s.startTime == (time.Time{}) {
-		s.startTime = time.Now()
-	}
 	// Retrieve the previous sync status from LevelDB and abort if already synced
 	s.loadSyncStatus()
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
@@ -687,6 +776,9 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 		s.cleanStorageTasks()
 		s.cleanAccountTasks()
 		if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
+			s.lock.Lock()
+			s.updateStatus(time.Now())
+			s.lock.Unlock()
 			return nil
 		}
 		// Assign all the data retrieval tasks to any free peers
@@ -713,6 +805,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			BytecodeHealSynced: s.bytecodeHealSynced,
 			BytecodeHealBytes:  s.bytecodeHealBytes,
 		}
+		s.updateStatus(time.Now())
 		s.lock.Unlock()
 		// Wait for something to happen
 		select {
@@ -824,6 +917,11 @@ func (s *Syncer) loadSyncStatus() {
 			s.trienodeHealBytes = progress.TrienodeHealBytes
 			s.bytecodeHealSynced = progress.BytecodeHealSynced
 			s.bytecodeHealBytes = progress.BytecodeHealBytes
+
+			s.restoreHealCheckpoint(progress.HealNodes)
+			if len(progress.HealNodes) > 0 {
+				log.Debug("Restored healing checkpoint", "nodes", len(progress.HealNodes))
+			}
 			return
 		}
 	}
@@ -836,6 +934,7 @@ func (s *Syncer) loadSyncStatus() {
 	s.storageSynced, s.storageBytes = 0, 0
 	s.trienodeHealSynced, s.trienodeHealBytes = 0, 0
 	s.bytecodeHealSynced, s.bytecodeHealBytes = 0, 0
+	s.healCache = nil
 
 	var next common.Hash
 	step := new(big.Int).Sub(
@@ -919,6 +1018,7 @@ func (s *Syncer) saveSyncStatus() {
 		TrienodeHealBytes:  s.trienodeHealBytes,
 		BytecodeHealSynced: s.bytecodeHealSynced,
 		BytecodeHealBytes:  s.bytecodeHealBytes,
+		HealNodes:          s.healCheckpoint(),
 	}
 	status, err := json.Marshal(progress)
 	if err != nil {
@@ -1387,19 +1487,7 @@ func (s *Syncer) assignTrienodeHealTasks(success chan *trienodeHealResponse, fai
 		// If there are not enough trie tasks queued to fully assign, fill the
 		// queue from the state sync scheduler. The trie synced schedules these
 		// together with bytecodes, so we need to queue them combined.
-		var (
-			have = len(s.healer.trieTasks) + len(s.healer.codeTasks)
-			want = maxTrieRequestCount + maxCodeRequestCount
-		)
-		if have < want {
-			paths, hashes, codes := s.healer.scheduler.Missing(want - have)
-			for i, path := range paths {
-				s.healer.trieTasks[path] = hashes[i]
-			}
-			for _, hash := range codes {
-				s.healer.codeTasks[hash] = struct{}{}
-			}
-		}
+		s.fillHealTasks(maxTrieRequestCount + maxCodeRequestCount)
 		// If all the heal tasks are bytecodes or already downloading, bail
 		if len(s.healer.trieTasks) == 0 {
 			return
@@ -1515,19 +1603,7 @@ func (s *Syncer) assignBytecodeHealTasks(success chan *bytecodeHealResponse, fai
 		// If there are not enough trie tasks queued to fully assign, fill the
 		// queue from the state sync scheduler. The trie synced schedules these
 		// together with trie nodes, so we need to queue them combined.
-		var (
-			have = len(s.healer.trieTasks) + len(s.healer.codeTasks)
-			want = maxTrieRequestCount + maxCodeRequestCount
-		)
-		if have < want {
-			paths, hashes, codes := s.healer.scheduler.Missing(want - have)
-			for i, path := range paths {
-				s.healer.trieTasks[path] = hashes[i]
-			}
-			for _, hash := range codes {
-				s.healer.codeTasks[hash] = struct{}{}
-			}
-		}
+		s.fillHealTasks(maxTrieRequestCount + maxCodeRequestCount)
 		// If all the heal tasks are trienodes or already downloading, bail
 		if len(s.healer.codeTasks) == 0 {
 			return
@@ -1912,8 +1988,9 @@ func (s *Syncer) processAccountResponse(res *accountResponse) {
 				res.task.pend++
 			}
 		}
-		// Check if the account is a contract with an unknown storage trie
-		if account.Root != types.EmptyRootHash {
+		// Check if the account is a contract with an unknown storage trie. The
+		// storage of the contracts not tracked by the partial state is skipped.
+		if account.Root != types.EmptyRootHash && s.partial.TrackedHash(res.hashes[i]) {
 			// If the storage was already retrieved in the last cycle, there's no need
 			// to resync it again, regardless of whether the storage root is consistent
 			// or not.
@@ -2848,6 +2925,19 @@ func (s *Syncer) OnTrieNodes(peer SyncPeer, id uint64, trienodes [][]byte) error
 		size += common.StorageSize(len(node))
 	}
 	logger := peer.Log().New("reqid", id)
+
+	// Deliver the nodes requested outside of the sync directly
+	s.lock.Lock()
+	if deliver, ok := s.nodeReqs[id]; ok {
+		delete(s.nodeReqs, id)
+		s.lock.Unlock()
+
+		logger.Trace("Delivering resolved trienodes", "trienodes", len(trienodes), "bytes", size)
+		deliver <- trienodes
+		return nil
+	}
+	s.lock.Unlock()
+
 	logger.Trace("Delivering set of healing trienodes", "trienodes", len(trienodes), "bytes", size)
 
 	// Whether or not the response is valid, we can mark the peer as idle and
@@ -3112,8 +3202,7 @@ func (s *Syncer) reportSyncProgress(force bool) {
 	if estBytes < 1.0 {
 		return
 	}
-	elapsed := time.Since(s.startTime)
-	estTime := elapsed / time.Duration(synced) * time.Duration(estBytes)
+	eta := s.downloadRate.eta(1 - s.accountCompletion())
 
 	// Create a mega progress report
 	var (
@@ -3123,7 +3212,7 @@ func (s *Syncer) reportSyncProgress(force bool) {
 		bytecode = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.bytecodeSynced), s.bytecodeBytes.TerminalString())
 	)
 	log.Info("Syncing: state download in progress", "synced", progress, "state", synced,
-		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(estTime-elapsed))
+		"accounts", accounts, "slots", storage, "codes", bytecode, "eta", common.PrettyDuration(eta))
 }
 
 // reportHealProgress calculates various status reports and provides it to the user.
@@ -3141,8 +3230,9 @@ func (s *Syncer) reportHealProgress(force bool) {
 		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountHealed), s.accountHealedBytes.TerminalString())
 		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageHealed), s.storageHealedBytes.TerminalString())
 	)
+	pending := s.healer.scheduler.Pending()
 	log.Info("Syncing: state healing in progress", "accounts", accounts, "slots", storage,
-		"codes", bytecode, "nodes", trienode, "pending", s.healer.scheduler.Pending())
+		"codes", bytecode, "nodes", trienode, "pending", pending, "eta", common.PrettyDuration(s.healRate.eta(float64(pending))))
 }
 
 // estimateRemainingSlots tries to determine roughly how many slots are left in
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math/big"
 	mrand "math/rand"
@@ -1456,6 +1457,49 @@ func testSyncWithUnevenStorage(t *testing.T, scheme string) {
 	verifyTrie(scheme, syncer.db, accountTrie.Hash(), t)
 }
 
+// Tests that single trie nodes can be resolved from the peers outside of sync,
+// skipping the peers that don't deliver them.
+func TestResolveNode(t *testing.T) {
+	t.Parallel()
+
+	accountTrie, accounts, storageTries, storageElems := makeAccountTrieWithStorage(rawdb.HashScheme, 3, 256, false, false, false)
+
+	mkSource := func(name string, handler trieHandlerFunc) *testPeer {
+		source := newTestPeer(name, t, func() {})
+		source.accountTrie = accountTrie.Copy()
+		source.accountValues = accounts
+		source.setStorageTries(storageTries)
+		source.storageValues = storageElems
+		source.trieRequestHandler = handler
+		return source
+	}
+	syncer := setupSyncer(rawdb.HashScheme, mkSource("empty", emptyTrieRequestHandler), mkSource("source", defaultTrieRequestHandler))
+
+	// Resolve an inner node of the account trie and of a storage trie
+	for owner, tr := range map[common.Hash]*trie.Trie{{}: accountTrie, common.BytesToHash(accounts[0].k): storageTries[common.BytesToHash(accounts[0].k)]} {
+		it, err := tr.NodeIterator(nil)
+		if err != nil {
+			t.Fatalf("failed to iterate trie: %v", err)
+		}
+		for it.Next(true) {
+			if it.Hash() != (common.Hash{}) && len(it.Path()) > 0 {
+				break
+			}
+		}
+		blob, err := syncer.ResolveNode(accountTrie.Hash(), owner, it.Path(), it.Hash())
+		if err != nil {
+			t.Fatalf("failed to resolve node %x of %x: %v", it.Path(), owner, err)
+		}
+		if crypto.Keccak256Hash(blob) != it.Hash() {
+			t.Fatalf("resolved node mismatch: have %x, want %x", crypto.Keccak256Hash(blob), it.Hash())
+		}
+	}
+	// Nodes not matching the requested hash are rejected
+	if _, err := syncer.ResolveNode(accountTrie.Hash(), common.Hash{}, nil, common.Hash{0x01}); !errors.Is(err, errNodeUnavailable) {
+		t.Fatalf("unavailable node error mismatch: have %v, want %v", err, errNodeUnavailable)
+	}
+}
+
 type kv struct {
 	k, v []byte
 }
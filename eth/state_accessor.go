@@ -176,15 +176,13 @@ func (eth *Ethereum) hashState(ctx context.Context, block *types.Block, reexec u
 }
 
 func (eth *Ethereum) pathState(block *types.Block) (*state.StateDB, func(), error) {
-	// Check if the requested state is available in the live chain.
+	// Check if the requested state is available in the live chain, or
+	// reachable via the indexed state histories.
 	statedb, err := eth.blockchain.StateAt(block.Root())
 	if err == nil {
 		return statedb, noopReleaser, nil
 	}
-	// TODO historic state is not supported in path-based scheme.
-	// Fully archive node in pbss will be implemented by relying
-	// on state history, but needs more work on top.
-	return nil, nil, errors.New("historical state not available in path scheme yet")
+	return nil, nil, fmt.Errorf("historical state not available in path scheme: %w", err)
 }
 
 // stateAtBlock retrieves the state database associated with a certain block.
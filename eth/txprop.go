@@ -0,0 +1,337 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// txPropTickInterval is the interval of checking the local transactions
+	// withheld from the broadcast.
+	txPropTickInterval = time.Second
+
+	// txPropStemPeers is the number of stem peers selected per epoch.
+	txPropStemPeers = 2
+
+	// txPropLocalLifetime is the time after which a local transaction not yet
+	// announced by the pool (e.g. because of a nonce gap) is forgotten. It's the
+	// same as the default lifetime of queued transactions in the pool.
+	txPropLocalLifetime = 3 * time.Hour
+)
+
+var (
+	txPropLocalMeter     = metrics.NewRegisteredMeter("eth/txprop/local", nil)
+	txPropBroadcastMeter = metrics.NewRegisteredMeter("eth/txprop/broadcasts", nil)
+	txPropAnnounceMeter  = metrics.NewRegisteredMeter("eth/txprop/announces", nil)
+	txPropStemMeter      = metrics.NewRegisteredMeter("eth/txprop/stem", nil)
+	txPropDiffusedMeter  = metrics.NewRegisteredMeter("eth/txprop/stem/diffused", nil)
+	txPropEmbargoMeter   = metrics.NewRegisteredMeter("eth/txprop/stem/embargo", nil)
+	txPropPrivateMeter   = metrics.NewRegisteredMeter("eth/txprop/private", nil)
+	txPropHeldGauge      = metrics.NewRegisteredGauge("eth/txprop/held", nil)
+)
+
+// heldTx is a local transaction withheld from the broadcast.
+type heldTx struct {
+	tx      *types.Transaction
+	stem    string         // Stem peer the transaction was sent to, empty if not sent yet
+	sent    bool           // Whether a private transaction was sent to any trusted peer or relay
+	embargo mclock.AbsTime // Time at which a stemmed transaction is broadcast
+}
+
+// txPropagator applies the configured propagation policy to the transactions
+// submitted locally.
+//
+// With the stem policy, a local transaction is first sent to one of a few stem
+// peers selected per epoch, similar to the stem phase of Dandelion++ limited to
+// a single hop. The stem peer broadcasts it like any other transaction, so once
+// the transaction is seen from any other peer, it got diffused to the network.
+// If that doesn't happen before a randomized embargo timer expires, the
+// transaction is broadcast as usual.
+//
+// With the private policy, a local transaction is only sent to the trusted peers
+// and the configured relays, and is never broadcast or announced.
+type txPropagator struct {
+	policy  string
+	relays  map[enode.ID]*enode.Node // Relays receiving private transactions
+	epoch   time.Duration
+	embargo time.Duration
+	clock   mclock.Clock
+	rand    *rand.Rand
+
+	submitted map[common.Hash]mclock.AbsTime // Local transactions not yet announced by the pool
+	held      map[common.Hash]*heldTx        // Local transactions withheld from the broadcast
+	stems     []string                       // Stem peers of the current epoch
+	epochEnd  mclock.AbsTime                 // Time at which the stem peers are reselected
+	lock      sync.Mutex
+}
+
+// newTxPropagator creates a transaction propagator with the given policies.
+func newTxPropagator(config ethconfig.TxPropagationConfig) (*txPropagator, error) {
+	p := &txPropagator{
+		policy:    config.Policy,
+		relays:    make(map[enode.ID]*enode.Node),
+		epoch:     config.StemEpoch,
+		embargo:   config.StemEmbargo,
+		clock:     mclock.System{},
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		submitted: make(map[common.Hash]mclock.AbsTime),
+		held:      make(map[common.Hash]*heldTx),
+	}
+	switch p.policy {
+	case "":
+		p.policy = ethconfig.TxPropBroadcast
+	case ethconfig.TxPropBroadcast, ethconfig.TxPropStem, ethconfig.TxPropPrivate:
+	default:
+		return nil, fmt.Errorf("invalid transaction propagation policy %q", p.policy)
+	}
+	if p.epoch <= 0 {
+		p.epoch = ethconfig.DefaultTxPropagationConfig.StemEpoch
+	}
+	if p.embargo <= 0 {
+		p.embargo = ethconfig.DefaultTxPropagationConfig.StemEmbargo
+	}
+	for _, url := range config.Relays {
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transaction relay %q: %v", url, err)
+		}
+		p.relays[node.ID()] = node
+	}
+	return p, nil
+}
+
+// markLocal marks the transaction as submitted locally. It must be called before
+// the transaction is added to the pool. The local transactions are broadcast as
+// any other with the broadcast policy, so they are not tracked at all.
+func (p *txPropagator) markLocal(hash common.Hash) {
+	if p.policy == ethconfig.TxPropBroadcast {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.submitted[hash] = p.clock.Now()
+}
+
+// unmarkLocal reverts markLocal, for transactions rejected by the pool.
+func (p *txPropagator) unmarkLocal(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.submitted, hash)
+}
+
+// withhold splits the local transactions off the batch of transactions to be
+// broadcast, if the policy demands it. The withheld transactions are tracked
+// until they are routed to their stem peer or the relays.
+func (p *txPropagator) withhold(txs types.Transactions) (public types.Transactions, held int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.submitted) == 0 {
+		return txs, 0
+	}
+	public = make(types.Transactions, 0, len(txs))
+	for _, tx := range txs {
+		hash := tx.Hash()
+		if _, ok := p.submitted[hash]; !ok {
+			public = append(public, tx)
+			continue
+		}
+		delete(p.submitted, hash)
+		txPropLocalMeter.Mark(1)
+
+		p.held[hash] = &heldTx{tx: tx}
+		held++
+	}
+	txPropHeldGauge.Update(int64(len(p.held)))
+	return public, held
+}
+
+// withheld reports whether the transaction must not be announced, as it's a
+// local transaction not yet released to the public broadcast.
+func (p *txPropagator) withheld(hash common.Hash) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.policy == ethconfig.TxPropBroadcast {
+		return false
+	}
+	if _, ok := p.submitted[hash]; ok {
+		return true
+	}
+	_, ok := p.held[hash]
+	return ok
+}
+
+// route assigns the withheld transactions which have not been sent yet to the
+// peers they should be sent to, depending on the policy.
+func (p *txPropagator) route(peers []*ethPeer, signer types.Signer) map[*ethPeer][]common.Hash {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	sends := make(map[*ethPeer][]common.Hash)
+	for hash, held := range p.held {
+		switch p.policy {
+		case ethconfig.TxPropStem:
+			if held.stem != "" {
+				continue
+			}
+			peer := p.stemPeer(held.tx, peers, signer)
+			if peer == nil {
+				continue
+			}
+			held.stem = peer.ID()
+			held.embargo = p.clock.Now().Add(p.embargo + time.Duration(p.rand.Int63n(int64(p.embargo))))
+			sends[peer] = append(sends[peer], hash)
+			txPropStemMeter.Mark(1)
+
+		case ethconfig.TxPropPrivate:
+			if held.sent {
+				continue
+			}
+			for _, peer := range peers {
+				if _, relay := p.relays[peer.Node().ID()]; relay || peer.Peer.Trusted() {
+					sends[peer] = append(sends[peer], hash)
+					held.sent = true
+					txPropPrivateMeter.Mark(1)
+				}
+			}
+		}
+	}
+	return sends
+}
+
+// stemPeer returns the stem peer to send the transaction to, selecting new stem
+// peers if the epoch is over or all of the current ones are gone. Outbound peers
+// are preferred for the stem, as those are harder to be chosen by an attacker.
+// Transactions from the same sender are sent to the same stem peer to (try and)
+// avoid nonce gaps.
+func (p *txPropagator) stemPeer(tx *types.Transaction, peers []*ethPeer, signer types.Signer) *ethPeer {
+	connected := make(map[string]*ethPeer, len(peers))
+	for _, peer := range peers {
+		connected[peer.ID()] = peer
+	}
+	var stems []*ethPeer
+	if p.clock.Now() < p.epochEnd {
+		for _, id := range p.stems {
+			if peer := connected[id]; peer != nil {
+				stems = append(stems, peer)
+			}
+		}
+	}
+	if len(stems) == 0 {
+		var inbound, outbound []*ethPeer
+		for _, peer := range peers {
+			if peer.Peer.Inbound() {
+				inbound = append(inbound, peer)
+			} else {
+				outbound = append(outbound, peer)
+			}
+		}
+		p.rand.Shuffle(len(inbound), func(i, j int) { inbound[i], inbound[j] = inbound[j], inbound[i] })
+		p.rand.Shuffle(len(outbound), func(i, j int) { outbound[i], outbound[j] = outbound[j], outbound[i] })
+
+		candidates := append(outbound, inbound...)
+		stems = candidates[:min(txPropStemPeers, len(candidates))]
+
+		p.stems = p.stems[:0]
+		for _, peer := range stems {
+			p.stems = append(p.stems, peer.ID())
+		}
+		p.epochEnd = p.clock.Now().Add(p.epoch)
+	}
+	if len(stems) == 0 {
+		return nil
+	}
+	from, _ := types.Sender(signer, tx) // Ignore error, we only use the addr as a stem splitter
+	return stems[int(from[0])%len(stems)]
+}
+
+// expire drops the tracked transactions which left the pool, and releases the
+// stemmed transactions whose embargo expired for the public broadcast.
+func (p *txPropagator) expire(has func(common.Hash) bool) (fluff types.Transactions) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.clock.Now()
+	for hash, submitted := range p.submitted {
+		if now.Sub(submitted) > txPropLocalLifetime {
+			delete(p.submitted, hash)
+		}
+	}
+	for hash, held := range p.held {
+		switch {
+		case !has(hash):
+			delete(p.held, hash)
+		case held.stem != "" && now >= held.embargo:
+			delete(p.held, hash)
+			fluff = append(fluff, held.tx)
+			txPropEmbargoMeter.Mark(1)
+		}
+	}
+	txPropHeldGauge.Update(int64(len(p.held)))
+	return fluff
+}
+
+// notice records that the given peer has the transactions. A stemmed transaction
+// seen from any peer other than its stem peer has been diffused to the network,
+// so it's not withheld anymore.
+func (p *txPropagator) notice(peer string, hashes []common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.held) == 0 {
+		return
+	}
+	for _, hash := range hashes {
+		if held := p.held[hash]; held != nil && held.stem != "" && held.stem != peer {
+			delete(p.held, hash)
+			txPropDiffusedMeter.Mark(1)
+		}
+	}
+	txPropHeldGauge.Update(int64(len(p.held)))
+}
+
+// propagateHeld sends the withheld local transactions not sent yet to their stem
+// peer, or to the trusted peers and relays.
+func (h *handler) propagateHeld() {
+	signer := types.LatestSignerForChainID(h.chain.Config().ChainID)
+	for peer, hashes := range h.txprop.route(h.peers.all(), signer) {
+		peer.AsyncSendTransactions(hashes)
+	}
+}
+
+// checkHeldTransactions broadcasts the stemmed transactions whose embargo expired
+// and retries sending the withheld ones which had no peer to go to.
+func (h *handler) checkHeldTransactions() {
+	if fluff := h.txprop.expire(h.txpool.Has); len(fluff) > 0 {
+		h.BroadcastTransactions(fluff)
+	}
+	h.propagateHeld()
+}
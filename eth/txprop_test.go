@@ -0,0 +1,306 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// newTxPropTestPeers creates a number of unconnected peers for checking the
+// routing decisions of the transaction propagator.
+func newTxPropTestPeers(t *testing.T, n int) []*ethPeer {
+	peers := make([]*ethPeer, n)
+	for i := range peers {
+		app, net := p2p.MsgPipe()
+		t.Cleanup(func() { app.Close(); net.Close() })
+
+		peer := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, app), app, nil)
+		t.Cleanup(peer.Close)
+		peers[i] = &ethPeer{Peer: peer}
+	}
+	return peers
+}
+
+func newTxPropTestTx(nonce uint64) *types.Transaction {
+	tx := types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 100000, big.NewInt(0), nil)
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testKey)
+	return tx
+}
+
+func TestTxPropagatorStem(t *testing.T) {
+	clock := new(mclock.Simulated)
+	prop, err := newTxPropagator(ethconfig.TxPropagationConfig{Policy: ethconfig.TxPropStem, StemEmbargo: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop.clock = clock
+
+	var (
+		peers  = newTxPropTestPeers(t, 5)
+		signer = types.HomesteadSigner{}
+		local  = newTxPropTestTx(0)
+		remote = newTxPropTestTx(1)
+	)
+	prop.markLocal(local.Hash())
+
+	// Only the local transaction is withheld from the broadcast
+	public, held := prop.withhold(types.Transactions{local, remote})
+	if held != 1 || len(public) != 1 || public[0] != remote {
+		t.Fatalf("wrong split: held %d, public %v", held, public)
+	}
+	if !prop.withheld(local.Hash()) || prop.withheld(remote.Hash()) {
+		t.Fatal("wrong withheld status")
+	}
+	// The local transaction is sent to a single stem peer, once
+	sends := prop.route(peers, signer)
+	if len(sends) != 1 {
+		t.Fatalf("stemmed to %d peers, want 1", len(sends))
+	}
+	var stem *ethPeer
+	for peer := range sends {
+		stem = peer
+	}
+	if sends := prop.route(peers, signer); len(sends) != 0 {
+		t.Fatal("stemmed transaction routed again")
+	}
+	// Another transaction of the same sender goes to the same stem peer
+	next := newTxPropTestTx(2)
+	prop.markLocal(next.Hash())
+	prop.withhold(types.Transactions{next})
+	if sends := prop.route(peers, signer); len(sends[stem]) != 1 {
+		t.Fatalf("transaction of same sender not sent to the stem peer: %v", sends)
+	}
+	// Seeing the transaction from any other peer releases it
+	prop.notice(stem.ID(), []common.Hash{next.Hash()})
+	if !prop.withheld(next.Hash()) {
+		t.Fatal("transaction released by stem peer announcement")
+	}
+	for _, peer := range peers {
+		if peer != stem {
+			prop.notice(peer.ID(), []common.Hash{next.Hash()})
+			break
+		}
+	}
+	if prop.withheld(next.Hash()) {
+		t.Fatal("diffused transaction still withheld")
+	}
+	// The other one is released for broadcasting once the embargo expires
+	has := func(common.Hash) bool { return true }
+	if fluff := prop.expire(has); len(fluff) != 0 {
+		t.Fatalf("transactions released before embargo: %v", fluff)
+	}
+	clock.Run(2 * time.Minute)
+	if fluff := prop.expire(has); len(fluff) != 1 || fluff[0] != local {
+		t.Fatalf("wrong transactions released after embargo: %v", fluff)
+	}
+	if prop.withheld(local.Hash()) {
+		t.Fatal("released transaction still withheld")
+	}
+}
+
+func TestTxPropagatorPrivate(t *testing.T) {
+	prop, err := newTxPropagator(ethconfig.TxPropagationConfig{Policy: ethconfig.TxPropPrivate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		peers  = newTxPropTestPeers(t, 3)
+		signer = types.HomesteadSigner{}
+		local  = newTxPropTestTx(0)
+	)
+	prop.markLocal(local.Hash())
+	prop.withhold(types.Transactions{local})
+
+	// Without trusted peers or relays, the transaction is not sent anywhere
+	if sends := prop.route(peers, signer); len(sends) != 0 {
+		t.Fatalf("private transaction sent to untrusted peers: %v", sends)
+	}
+	prop.relays[peers[1].Node().ID()] = nil
+	sends := prop.route(peers, signer)
+	if len(sends) != 1 || len(sends[peers[1]]) != 1 {
+		t.Fatalf("private transaction not sent to relay: %v", sends)
+	}
+	// Private transactions are never released, only dropped with the pool
+	prop.notice(peers[2].ID(), []common.Hash{local.Hash()})
+	if fluff := prop.expire(func(common.Hash) bool { return true }); len(fluff) != 0 || !prop.withheld(local.Hash()) {
+		t.Fatal("private transaction released")
+	}
+	prop.expire(func(common.Hash) bool { return false })
+	if prop.withheld(local.Hash()) {
+		t.Fatal("dropped transaction still tracked")
+	}
+}
+
+func TestTxPropagatorConfig(t *testing.T) {
+	if _, err := newTxPropagator(ethconfig.TxPropagationConfig{Policy: "shout"}); err == nil {
+		t.Error("invalid policy accepted")
+	}
+	if _, err := newTxPropagator(ethconfig.TxPropagationConfig{Relays: []string{"enode://nope"}}); err == nil {
+		t.Error("invalid relay accepted")
+	}
+	key, _ := crypto.GenerateKey()
+	node := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+	prop, err := newTxPropagator(ethconfig.TxPropagationConfig{Relays: []string{node.URLv4()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prop.policy != ethconfig.TxPropBroadcast || prop.relays[node.ID()] == nil {
+		t.Errorf("wrong propagator config: policy %q, relays %v", prop.policy, prop.relays)
+	}
+}
+
+// Tests that local transactions with the private policy are only sent to the
+// relays, while remote ones are still propagated to every peer.
+func TestPrivateTransactionPropagation(t *testing.T) {
+	t.Parallel()
+
+	key, _ := crypto.GenerateKey()
+	relay := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+
+	source := newTestHandlerWithConfig(0, ethconfig.TxPropagationConfig{
+		Policy: ethconfig.TxPropPrivate,
+		Relays: []string{relay.URLv4()},
+	})
+	defer source.close()
+
+	var (
+		genesis = source.chain.Genesis()
+		head    = source.chain.CurrentBlock()
+		td      = source.chain.GetTd(head.Hash(), head.Number.Uint64())
+		ids     = []enode.ID{relay.ID(), {2}, {3}}
+		bcasts  = make([]chan []*types.Transaction, len(ids))
+		anns    = make([]chan []common.Hash, len(ids))
+	)
+	for i, id := range ids {
+		sourcePipe, sinkPipe := p2p.MsgPipe()
+		defer sourcePipe.Close()
+		defer sinkPipe.Close()
+
+		sourcePeer := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(id, "", nil, sourcePipe), sourcePipe, source.txpool)
+		sinkPeer := eth.NewPeer(eth.ETH68, p2p.NewPeerPipe(enode.ID{0}, "", nil, sinkPipe), sinkPipe, nil)
+		defer sourcePeer.Close()
+		defer sinkPeer.Close()
+
+		go source.handler.runEthPeer(sourcePeer, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		if err := sinkPeer.Handshake(1, td, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain), eth.BlockRangeUpdatePacket{LatestBlock: head.Number.Uint64(), LatestBlockHash: head.Hash()}); err != nil {
+			t.Fatalf("failed to run protocol handshake: %v", err)
+		}
+		backend := new(testEthHandler)
+		bcasts[i] = make(chan []*types.Transaction, 16)
+		anns[i] = make(chan []common.Hash, 16)
+		sub := backend.txBroadcasts.Subscribe(bcasts[i])
+		defer sub.Unsubscribe()
+		sub = backend.txAnnounces.Subscribe(anns[i])
+		defer sub.Unsubscribe()
+
+		go eth.Handle(backend, sinkPeer)
+	}
+	// Wait for all peers to be registered
+	for source.handler.peers.len() < len(ids) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Submit a local transaction, it must only arrive at the relay
+	local := newTxPropTestTx(0)
+	source.handler.txprop.markLocal(local.Hash())
+	source.txpool.Add([]*types.Transaction{local}, true, false)
+
+	select {
+	case txs := <-bcasts[0]:
+		if len(txs) != 1 || txs[0].Hash() != local.Hash() {
+			t.Fatalf("wrong transactions sent to relay: %v", txs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("private transaction not sent to relay")
+	}
+	time.Sleep(250 * time.Millisecond)
+	for i := 1; i < len(ids); i++ {
+		select {
+		case <-bcasts[i]:
+			t.Fatalf("peer %d: private transaction broadcast", i)
+		case <-anns[i]:
+			t.Fatalf("peer %d: private transaction announced", i)
+		default:
+		}
+	}
+	// Remote transactions are propagated to all peers
+	remote := newTxPropTestTx(1)
+	source.txpool.Add([]*types.Transaction{remote}, false, false)
+
+	for i := range ids {
+		select {
+		case <-bcasts[i]:
+		case <-anns[i]:
+		case <-time.After(time.Second):
+			t.Fatalf("peer %d: remote transaction not propagated", i)
+		}
+	}
+}
+
+func TestTxPropagatorBroadcast(t *testing.T) {
+	prop, err := newTxPropagator(ethconfig.TxPropagationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Local transactions are not tracked at all, as nothing would release them
+	local := newTxPropTestTx(0)
+	prop.markLocal(local.Hash())
+	if len(prop.submitted) != 0 {
+		t.Fatalf("local transaction tracked with broadcast policy: %v", prop.submitted)
+	}
+	if public, held := prop.withhold(types.Transactions{local}); held != 0 || len(public) != 1 {
+		t.Fatalf("wrong split: held %d, public %v", held, public)
+	}
+}
+
+func TestTxPropagatorExpireSubmitted(t *testing.T) {
+	clock := new(mclock.Simulated)
+	prop, err := newTxPropagator(ethconfig.TxPropagationConfig{Policy: ethconfig.TxPropStem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prop.clock = clock
+
+	// A local transaction never announced by the pool (e.g. queued) is forgotten
+	local := newTxPropTestTx(0)
+	prop.markLocal(local.Hash())
+
+	has := func(common.Hash) bool { return true }
+	prop.expire(has)
+	if !prop.withheld(local.Hash()) {
+		t.Fatal("local transaction forgotten before its lifetime")
+	}
+	clock.Run(txPropLocalLifetime + time.Second)
+	prop.expire(has)
+	if len(prop.submitted) != 0 {
+		t.Fatalf("local transaction tracked beyond its lifetime: %v", prop.submitted)
+	}
+}
@@ -695,6 +695,11 @@ type rpcProgress struct {
 	HealedBytecodeBytes    hexutil.Uint64
 	HealingTrienodes       hexutil.Uint64
 	HealingBytecode        hexutil.Uint64
+	SyncedAccountRange     hexutil.Uint64
+	StorageBacklog         hexutil.Uint64
+	BytecodeBacklog        hexutil.Uint64
+	HealingBacklog         hexutil.Uint64
+	StateSyncETA           hexutil.Uint64
 	TxIndexFinishedBlocks  hexutil.Uint64
 	TxIndexRemainingBlocks hexutil.Uint64
 }
@@ -721,6 +726,11 @@ func (p *rpcProgress) toSyncProgress() *ethereum.SyncProgress {
 		HealedBytecodeBytes:    uint64(p.HealedBytecodeBytes),
 		HealingTrienodes:       uint64(p.HealingTrienodes),
 		HealingBytecode:        uint64(p.HealingBytecode),
+		SyncedAccountRange:     uint64(p.SyncedAccountRange),
+		StorageBacklog:         uint64(p.StorageBacklog),
+		BytecodeBacklog:        uint64(p.BytecodeBacklog),
+		HealingBacklog:         uint64(p.HealingBacklog),
+		StateSyncETA:           uint64(p.StateSyncETA),
 		TxIndexFinishedBlocks:  uint64(p.TxIndexFinishedBlocks),
 		TxIndexRemainingBlocks: uint64(p.TxIndexRemainingBlocks),
 	}
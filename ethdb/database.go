@@ -68,6 +68,17 @@ type KeyValueStore interface {
 	io.Closer
 }
 
+// Keyspacer wraps the methods of a key-value data store which is split into
+// named keyspaces, each one backed by a separately tuned key-value store.
+type Keyspacer interface {
+	// Keyspaces returns the names of the keyspaces in the data store.
+	Keyspaces() []string
+
+	// Keyspace returns the key-value store backing the named keyspace, or nil
+	// if no such keyspace exists.
+	Keyspace(name string) KeyValueStore
+}
+
 // AncientReaderOp contains the methods required to read from immutable ancient data.
 type AncientReaderOp interface {
 	// HasAncient returns an indicator whether the specified data exists in the
@@ -141,6 +152,22 @@ type AncientWriteOp interface {
 	AppendRaw(kind string, number uint64, item []byte) error
 }
 
+// ObjectStore contains the methods required to offload immutable data into a
+// remote object storage, such as an S3-compatible bucket.
+type ObjectStore interface {
+	// Put uploads the object with the given key, replacing any existing one.
+	Put(key string, data []byte) error
+
+	// Get retrieves the object with the given key.
+	Get(key string) ([]byte, error)
+
+	// GetRange retrieves the given byte range of the object with the given key.
+	GetRange(key string, offset, length uint64) ([]byte, error)
+
+	// Delete removes the object with the given key.
+	Delete(key string) error
+}
+
 // AncientStater wraps the Stat method of a backing data store.
 type AncientStater interface {
 	// AncientDatadir returns the path of root ancient directory. Empty string
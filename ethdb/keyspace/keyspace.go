@@ -0,0 +1,573 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package keyspace implements a key-value store multiplexing named keyspaces,
+// each one backed by a separate key-value store.
+package keyspace
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// walKey is the key of the write-ahead record of a batch spanning multiple
+// keyspaces. It is stored directly in the keyspace receiving the largest part
+// of the batch, bypassing the router, and is hidden from the iterations.
+var walKey = []byte("KeyspaceWriteAhead")
+
+// walEntry is a single change of a write-ahead record.
+type walEntry struct {
+	Keyspace string
+	Key      []byte
+	Value    []byte
+	Delete   bool
+}
+
+// Router maps a database key to the name of the keyspace it's stored in. The
+// mapping has to be deterministic, a key is never looked up in any other
+// keyspace.
+type Router func(key []byte) string
+
+// Database is a key-value store splitting the keys into named keyspaces by
+// a router. The operations on a single key are served by its keyspace, while
+// iterations merge the content of all keyspaces.
+//
+// The batches spanning multiple keyspaces are written atomically: the changes
+// of all but the largest keyspace part are recorded in a write-ahead record,
+// which is written together with the largest part. The other parts are written
+// afterwards and the record is deleted. If the process crashes in between, the
+// record is replayed when the database is opened again.
+//
+// The write-ahead record costs an extra write of every change outside of the
+// largest part, so callers should keep their batches within a single keyspace
+// where possible. Batches touching a single keyspace skip the record entirely.
+type Database struct {
+	route    Router
+	fallback string                         // Keyspace receiving the keys of unknown keyspaces
+	names    []string                       // Keyspace names in write order, fallback last
+	stores   map[string]ethdb.KeyValueStore // Backing stores of the keyspaces
+
+	// locks guard the keyspaces against the batches spanning multiple ones.
+	// Such a batch holds the locks of the keyspaces it touches exclusively,
+	// while all the other operations hold the locks they depend on shared, so
+	// that none of them observes or interleaves with a partially written batch.
+	// The locks are always acquired in write order to avoid deadlocks.
+	locks map[string]*sync.RWMutex
+}
+
+// New creates a keyspace multiplexer over the given stores. The keys routed
+// to an unknown keyspace are stored in the fallback one, which must exist. A
+// batch interrupted by a crash is completed before the database is returned.
+func New(stores map[string]ethdb.KeyValueStore, fallback string, route Router) (*Database, error) {
+	if _, ok := stores[fallback]; !ok {
+		return nil, fmt.Errorf("fallback keyspace %q missing", fallback)
+	}
+	names := make([]string, 0, len(stores))
+	for name := range stores {
+		if name != fallback {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	db := &Database{
+		route:    route,
+		fallback: fallback,
+		names:    append(names, fallback),
+		stores:   stores,
+		locks:    make(map[string]*sync.RWMutex, len(stores)),
+	}
+	for name := range stores {
+		db.locks[name] = new(sync.RWMutex)
+	}
+	if err := db.recover(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// recover replays the write-ahead records left behind by an interrupted batch.
+func (db *Database) recover() error {
+	for _, carrier := range db.names {
+		blob, err := db.stores[carrier].Get(walKey)
+		if err != nil || len(blob) == 0 {
+			continue
+		}
+		var entries []walEntry
+		if err := rlp.DecodeBytes(blob, &entries); err != nil {
+			return fmt.Errorf("keyspace %s: invalid write-ahead record: %w", carrier, err)
+		}
+		batches := make(map[string]ethdb.Batch)
+		for _, entry := range entries {
+			store, ok := db.stores[entry.Keyspace]
+			if !ok {
+				return fmt.Errorf("keyspace %s: write-ahead record of unknown keyspace %q", carrier, entry.Keyspace)
+			}
+			kb, ok := batches[entry.Keyspace]
+			if !ok {
+				kb = store.NewBatch()
+				batches[entry.Keyspace] = kb
+			}
+			if entry.Delete {
+				err = kb.Delete(entry.Key)
+			} else {
+				err = kb.Put(entry.Key, entry.Value)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for name, kb := range batches {
+			if err := kb.Write(); err != nil {
+				return fmt.Errorf("keyspace %s: %w", name, err)
+			}
+		}
+		if err := db.stores[carrier].Delete(walKey); err != nil {
+			return fmt.Errorf("keyspace %s: %w", carrier, err)
+		}
+	}
+	return nil
+}
+
+// keyspace returns the name of the keyspace storing the given key.
+func (db *Database) keyspace(key []byte) string {
+	name := db.route(key)
+	if _, ok := db.stores[name]; !ok {
+		return db.fallback
+	}
+	return name
+}
+
+// rlockAll acquires the shared locks of all keyspaces, returning the function
+// releasing them.
+func (db *Database) rlockAll() func() {
+	for _, name := range db.names {
+		db.locks[name].RLock()
+	}
+	return func() {
+		for _, name := range db.names {
+			db.locks[name].RUnlock()
+		}
+	}
+}
+
+// Keyspaces returns the names of the keyspaces in the data store.
+func (db *Database) Keyspaces() []string {
+	return append([]string(nil), db.names...)
+}
+
+// Keyspace returns the key-value store backing the named keyspace, or nil if
+// no such keyspace exists.
+func (db *Database) Keyspace(name string) ethdb.KeyValueStore {
+	return db.stores[name]
+}
+
+// Close closes all the backing stores.
+func (db *Database) Close() error {
+	var errs error
+	for _, name := range db.names {
+		if err := db.stores[name].Close(); err != nil && errs == nil {
+			errs = fmt.Errorf("keyspace %s: %w", name, err)
+		}
+	}
+	return errs
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	name := db.keyspace(key)
+	db.locks[name].RLock()
+	defer db.locks[name].RUnlock()
+
+	return db.stores[name].Has(key)
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	name := db.keyspace(key)
+	db.locks[name].RLock()
+	defer db.locks[name].RUnlock()
+
+	return db.stores[name].Get(key)
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	name := db.keyspace(key)
+	db.locks[name].RLock()
+	defer db.locks[name].RUnlock()
+
+	return db.stores[name].Put(key, value)
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	name := db.keyspace(key)
+	db.locks[name].RLock()
+	defer db.locks[name].RUnlock()
+
+	return db.stores[name].Delete(key)
+}
+
+// Stat returns the requested statistic of every keyspace.
+func (db *Database) Stat(property string) (string, error) {
+	var out strings.Builder
+	for _, name := range db.names {
+		stat, err := db.stores[name].Stat(property)
+		if err != nil {
+			return "", fmt.Errorf("keyspace %s: %w", name, err)
+		}
+		fmt.Fprintf(&out, "Keyspace %s:\n%s\n", name, stat)
+	}
+	return out.String(), nil
+}
+
+// Compact flattens the given key range in every keyspace.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	for _, name := range db.names {
+		if err := db.stores[name].Compact(start, limit); err != nil {
+			return fmt.Errorf("keyspace %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db, batches: make(map[string]ethdb.Batch)}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+// The buffers of the keyspace batches are allocated on demand.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return db.NewBatch()
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset of database
+// content with a particular key prefix, starting at a particular initial key (or
+// after, if it does not exist). The content of all keyspaces is merged. The
+// keyspace iterators are created together, so they observe either all or none
+// of the changes of a batch spanning multiple keyspaces.
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	defer db.rlockAll()()
+
+	its := make([]ethdb.Iterator, 0, len(db.names))
+	for _, name := range db.names {
+		its = append(its, &filterIterator{
+			Iterator: db.stores[name].NewIterator(prefix, start),
+			db:       db,
+			name:     name,
+		})
+	}
+	return &mergeIterator{its: its, valid: make([]bool, len(its)), current: -1}
+}
+
+// NewSnapshot creates a database snapshot based on the current state of every
+// keyspace. The batches spanning multiple keyspaces are blocked meanwhile, so
+// the snapshot contains either all or none of their changes.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	defer db.rlockAll()()
+
+	snaps := make(map[string]ethdb.Snapshot, len(db.names))
+	for _, name := range db.names {
+		snap, err := db.stores[name].NewSnapshot()
+		if err != nil {
+			for _, snap := range snaps {
+				snap.Release()
+			}
+			return nil, err
+		}
+		snaps[name] = snap
+	}
+	return &snapshot{db: db, snaps: snaps}, nil
+}
+
+// batch is a write-only batch splitting the changes into the batches of the
+// keyspaces.
+type batch struct {
+	db      *Database
+	batches map[string]ethdb.Batch
+	size    int
+	carrier string // Keyspace whose batch holds the write-ahead record, if any
+}
+
+// keyspace returns the batch of the keyspace storing the given key.
+func (b *batch) keyspace(key []byte) ethdb.Batch {
+	name := b.db.keyspace(key)
+	kb, ok := b.batches[name]
+	if !ok {
+		kb = b.db.stores[name].NewBatch()
+		b.batches[name] = kb
+	}
+	return kb
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	if err := b.keyspace(key).Put(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	if err := b.keyspace(key).Delete(key); err != nil {
+		return err
+	}
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes the keyspace batches. A batch spanning multiple keyspaces is
+// written atomically, see Database for the details.
+func (b *batch) Write() error {
+	var parts []string
+	for _, name := range b.db.names {
+		if kb, ok := b.batches[name]; ok && kb.ValueSize() > 0 {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	if len(parts) == 1 {
+		lock := b.db.locks[parts[0]]
+		lock.RLock()
+		defer lock.RUnlock()
+
+		return b.batches[parts[0]].Write()
+	}
+	for _, name := range parts {
+		b.db.locks[name].Lock()
+		defer b.db.locks[name].Unlock()
+	}
+
+	// Record the changes of the smaller parts into the largest one, which is
+	// written first. Once it's on disk, the batch is bound to be applied.
+	if b.carrier == "" {
+		b.carrier = parts[0]
+		for _, name := range parts[1:] {
+			if b.batches[name].ValueSize() > b.batches[b.carrier].ValueSize() {
+				b.carrier = name
+			}
+		}
+	}
+	var entries []walEntry
+	for _, name := range parts {
+		if name == b.carrier {
+			continue
+		}
+		if err := b.batches[name].Replay(&walRecorder{keyspace: name, entries: &entries}); err != nil {
+			return err
+		}
+	}
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		return err
+	}
+	if err := b.batches[b.carrier].Put(walKey, blob); err != nil {
+		return err
+	}
+	if err := b.batches[b.carrier].Write(); err != nil {
+		return err
+	}
+	for _, name := range parts {
+		if name == b.carrier {
+			continue
+		}
+		if err := b.batches[name].Write(); err != nil {
+			return err
+		}
+	}
+	return b.db.stores[b.carrier].Delete(walKey)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	for _, kb := range b.batches {
+		kb.Reset()
+	}
+	b.size = 0
+	b.carrier = ""
+}
+
+// Replay replays the batch contents keyspace by keyspace. The order of the
+// changes is retained within each keyspace, thus for every single key.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, name := range b.db.names {
+		if kb, ok := b.batches[name]; ok {
+			kw := w
+			if name == b.carrier {
+				kw = &walFilter{w}
+			}
+			if err := kb.Replay(kw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// walRecorder collects the changes of a keyspace batch into a write-ahead
+// record.
+type walRecorder struct {
+	keyspace string
+	entries  *[]walEntry
+}
+
+// Put records the insertion of the given value.
+func (r *walRecorder) Put(key []byte, value []byte) error {
+	*r.entries = append(*r.entries, walEntry{Keyspace: r.keyspace, Key: key, Value: value})
+	return nil
+}
+
+// Delete records the removal of the key.
+func (r *walRecorder) Delete(key []byte) error {
+	*r.entries = append(*r.entries, walEntry{Keyspace: r.keyspace, Key: key, Delete: true})
+	return nil
+}
+
+// walFilter hides the write-ahead record from the replay of a batch.
+type walFilter struct {
+	ethdb.KeyValueWriter
+}
+
+// Put forwards the insertion of the given value, unless it's the write-ahead
+// record.
+func (f *walFilter) Put(key []byte, value []byte) error {
+	if bytes.Equal(key, walKey) {
+		return nil
+	}
+	return f.KeyValueWriter.Put(key, value)
+}
+
+// snapshot is a set of keyspace snapshots.
+type snapshot struct {
+	db    *Database
+	snaps map[string]ethdb.Snapshot
+}
+
+// Has retrieves if a key is present in the snapshot.
+func (snap *snapshot) Has(key []byte) (bool, error) {
+	return snap.snaps[snap.db.keyspace(key)].Has(key)
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (snap *snapshot) Get(key []byte) ([]byte, error) {
+	return snap.snaps[snap.db.keyspace(key)].Get(key)
+}
+
+// Release releases the keyspace snapshots.
+func (snap *snapshot) Release() {
+	for _, s := range snap.snaps {
+		s.Release()
+	}
+}
+
+// filterIterator skips the entries of a keyspace which don't belong there,
+// such as the leftovers of an interrupted migration.
+type filterIterator struct {
+	ethdb.Iterator
+	db   *Database
+	name string
+}
+
+// Next moves the iterator to the next entry belonging to the keyspace.
+func (it *filterIterator) Next() bool {
+	for it.Iterator.Next() {
+		if it.db.keyspace(it.Iterator.Key()) == it.name && !bytes.Equal(it.Iterator.Key(), walKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeIterator iterates over the union of the keyspace iterators in binary-
+// alphabetical order. The keyspaces are disjoint, so no deduplication is needed.
+type mergeIterator struct {
+	its     []ethdb.Iterator
+	valid   []bool // Whether the iterator at the same index has an entry
+	current int    // Index of the iterator holding the current entry, -1 before the first Next
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *mergeIterator) Next() bool {
+	if it.current == -1 {
+		for i, sub := range it.its {
+			it.valid[i] = sub.Next()
+		}
+	} else if it.current < len(it.its) {
+		it.valid[it.current] = it.its[it.current].Next()
+	}
+	it.current = len(it.its)
+	for i, sub := range it.its {
+		if !it.valid[i] {
+			continue
+		}
+		if it.current == len(it.its) || bytes.Compare(sub.Key(), it.its[it.current].Key()) < 0 {
+			it.current = i
+		}
+	}
+	return it.current < len(it.its)
+}
+
+// Error returns any accumulated error of the keyspace iterators.
+func (it *mergeIterator) Error() error {
+	for _, sub := range it.its {
+		if err := sub.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *mergeIterator) Key() []byte {
+	if it.current < 0 || it.current >= len(it.its) {
+		return nil
+	}
+	return it.its[it.current].Key()
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *mergeIterator) Value() []byte {
+	if it.current < 0 || it.current >= len(it.its) {
+		return nil
+	}
+	return it.its[it.current].Value()
+}
+
+// Release releases the keyspace iterators.
+func (it *mergeIterator) Release() {
+	for _, sub := range it.its {
+		sub.Release()
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keyspace
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// testRouter spreads the keys across the keyspaces by their last byte.
+func testRouter(key []byte) string {
+	if len(key) == 0 {
+		return "default"
+	}
+	switch key[len(key)-1] % 3 {
+	case 0:
+		return "a"
+	case 1:
+		return "b"
+	default:
+		return "unknown" // Stored in the fallback keyspace
+	}
+}
+
+func newTestDatabase(t testing.TB) (*Database, map[string]ethdb.KeyValueStore) {
+	stores := map[string]ethdb.KeyValueStore{
+		"a":       memorydb.New(),
+		"b":       memorydb.New(),
+		"default": memorydb.New(),
+	}
+	db, err := New(stores, "default", testRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, stores
+}
+
+func TestKeyspaceDB(t *testing.T) {
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			db, _ := newTestDatabase(t)
+			return db
+		})
+	})
+}
+
+func TestKeyspaceRouting(t *testing.T) {
+	db, stores := newTestDatabase(t)
+
+	batch := db.NewBatch()
+	for i := byte(0); i < 6; i++ {
+		batch.Put([]byte{'k', i}, []byte{i})
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	for i := byte(0); i < 6; i++ {
+		want := map[byte]string{0: "a", 1: "b", 2: "default"}[i%3]
+		for name, store := range stores {
+			if has, _ := store.Has([]byte{'k', i}); has != (name == want) {
+				t.Errorf("key %d presence in keyspace %s mismatch: have %v", i, name, has)
+			}
+		}
+	}
+	// Misplaced entries must be invisible to the iteration
+	stores["default"].Put([]byte{'k', 0}, []byte{0xff})
+
+	it := db.NewIterator([]byte{'k'}, nil)
+	defer it.Release()
+
+	var next byte
+	for it.Next() {
+		if it.Key()[1] != next || it.Value()[0] != next {
+			t.Fatalf("iterated entry mismatch: have %x=%x, want key %d", it.Key(), it.Value(), next)
+		}
+		next++
+	}
+	if next != 6 {
+		t.Fatalf("iterated entry count mismatch: have %d, want 6", next)
+	}
+}
+
+// failingStore is a key-value store whose batches fail to be written, as if
+// the process crashed before the write.
+type failingStore struct {
+	ethdb.KeyValueStore
+}
+
+func (s *failingStore) NewBatch() ethdb.Batch {
+	return &failingBatch{s.KeyValueStore.NewBatch()}
+}
+
+type failingBatch struct {
+	ethdb.Batch
+}
+
+func (b *failingBatch) Write() error {
+	return errors.New("crashed")
+}
+
+func TestKeyspaceAtomicBatch(t *testing.T) {
+	_, stores := newTestDatabase(t)
+
+	// Crash after writing the largest part of a batch spanning all keyspaces
+	crashed := map[string]ethdb.KeyValueStore{
+		"a":       &failingStore{stores["a"]},
+		"b":       stores["b"],
+		"default": &failingStore{stores["default"]},
+	}
+	db, err := New(crashed, "default", testRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte{'k', 0}, []byte{0})
+	batch.Put([]byte{'k', 1, 1}, bytes.Repeat([]byte{1}, 64))
+	batch.Delete([]byte{'k', 2})
+	if err := batch.Write(); err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if has, _ := stores["a"].Has([]byte{'k', 0}); has {
+		t.Fatal("smaller part written before recovery")
+	}
+	// Replay the batch by reopening the database
+	stores["default"].Put([]byte{'k', 2}, []byte{2})
+	db, err = New(stores, "default", testRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := db.Get([]byte{'k', 0}); !bytes.Equal(val, []byte{0}) {
+		t.Fatalf("recovered value mismatch: have %x, want 00", val)
+	}
+	if has, _ := db.Has([]byte{'k', 2}); has {
+		t.Fatal("recovered deletion missing")
+	}
+	for name, store := range stores {
+		if has, _ := store.Has(walKey); has {
+			t.Fatalf("write-ahead record left in keyspace %s", name)
+		}
+	}
+	// The record must never be visible to the users of the batch
+	batch = db.NewBatch()
+	batch.Put([]byte{'k', 0}, []byte{0})
+	batch.Put([]byte{'k', 1}, []byte{1})
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	replay := memorydb.New()
+	if err := batch.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	if has, _ := replay.Has(walKey); has {
+		t.Fatal("write-ahead record replayed")
+	}
+}
+
+func TestKeyspaceInterruptedBatch(t *testing.T) {
+	_, stores := newTestDatabase(t)
+
+	// Crash after writing the largest part and the first of the smaller ones,
+	// before the last part is committed
+	crashed := map[string]ethdb.KeyValueStore{
+		"a":       stores["a"],
+		"b":       stores["b"],
+		"default": &failingStore{stores["default"]},
+	}
+	db, err := New(crashed, "default", testRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores["default"].Put([]byte{'k', 2}, []byte{2})
+
+	batch := db.NewBatch()
+	batch.Put([]byte{'k', 0}, []byte{0})
+	batch.Put([]byte{'k', 1, 1}, bytes.Repeat([]byte{1}, 64))
+	batch.Delete([]byte{'k', 2})
+	batch.Put([]byte{'k', 5}, []byte{5})
+	if err := batch.Write(); err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if has, _ := stores["a"].Has([]byte{'k', 0}); !has {
+		t.Fatal("first smaller part not written before the crash")
+	}
+	if has, _ := stores["default"].Has([]byte{'k', 2}); !has {
+		t.Fatal("last part written before the crash")
+	}
+	if has, _ := stores["b"].Has(walKey); !has {
+		t.Fatal("write-ahead record missing after the crash")
+	}
+	// Reopening the database replays the whole record, including the part
+	// which was already committed
+	db, err = New(stores, "default", testRouter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range [][]byte{{'k', 0}, {'k', 1, 1}, {'k', 5}} {
+		if has, _ := db.Has(key); !has {
+			t.Errorf("recovered key %x missing", key)
+		}
+	}
+	if has, _ := db.Has([]byte{'k', 2}); has {
+		t.Fatal("recovered deletion missing")
+	}
+	for name, store := range stores {
+		if has, _ := store.Has(walKey); has {
+			t.Fatalf("write-ahead record left in keyspace %s", name)
+		}
+	}
+}
@@ -142,10 +142,29 @@ func (l panicLogger) Fatalf(format string, args ...interface{}) {
 	panic(fmt.Errorf("fatal: "+format, args...))
 }
 
+// Config contains the tuning parameters of a pebble database.
+type Config struct {
+	Cache   int // Memory allowance in megabytes for caching and write buffers
+	Handles int // Number of files to be open simultaneously
+
+	TargetFileSize        int64 // Size of the level-zero tables, doubled on every level (0 = 2MB on all levels)
+	L0CompactionThreshold int   // Number of level-zero files triggering a compaction (0 = pebble default)
+	NoCompression         bool  // Whether the data blocks are stored uncompressed
+	NoBloomFilter         bool  // Whether to skip the bloom filters, useful if keys are rarely missing
+}
+
 // New returns a wrapped pebble DB object. The namespace is the prefix that the
 // metrics reporting should use for surfacing internal stats.
 func New(file string, cache int, handles int, namespace string, readonly bool, ephemeral bool) (*Database, error) {
+	return NewWithConfig(file, Config{Cache: cache, Handles: handles}, namespace, readonly, ephemeral)
+}
+
+// NewWithConfig returns a wrapped pebble DB object tuned by the given config.
+// The namespace is the prefix that the metrics reporting should use for
+// surfacing internal stats.
+func NewWithConfig(file string, config Config, namespace string, readonly bool, ephemeral bool) (*Database, error) {
 	// Ensure we have some minimal caching and file guarantees
+	cache, handles := config.Cache, config.Handles
 	if cache < minCache {
 		cache = minCache
 	}
@@ -211,15 +230,10 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 
 		// Per-level options. Options for at least one level must be specified. The
 		// options for the last level are used for all subsequent levels.
-		Levels: []pebble.LevelOptions{
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-			{TargetFileSize: 2 * 1024 * 1024, FilterPolicy: bloom.FilterPolicy(10)},
-		},
+		Levels: levelOptions(config),
+
+		L0CompactionThreshold: config.L0CompactionThreshold,
+
 		ReadOnly: readonly,
 		EventListener: &pebble.EventListener{
 			CompactionBegin: db.onCompactionBegin,
@@ -259,6 +273,25 @@ func New(file string, cache int, handles int, namespace string, readonly bool, e
 	return db, nil
 }
 
+// levelOptions assembles the per-level options of the seven levels according
+// to the config.
+func levelOptions(config Config) []pebble.LevelOptions {
+	levels := make([]pebble.LevelOptions, 7)
+	for i := range levels {
+		levels[i].TargetFileSize = 2 * 1024 * 1024
+		if config.TargetFileSize != 0 {
+			levels[i].TargetFileSize = config.TargetFileSize << i
+		}
+		if !config.NoBloomFilter {
+			levels[i].FilterPolicy = bloom.FilterPolicy(10)
+		}
+		if config.NoCompression {
+			levels[i].Compression = pebble.NoCompression
+		}
+	}
+	return levels
+}
+
 // Close stops the metrics collection, flushes any pending data to disk and closes
 // all io accesses to the underlying key-value store.
 func (d *Database) Close() error {
@@ -0,0 +1,223 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package s3 implements the object store layer based on an S3-compatible
+// bucket, used to offload the frozen ancient segments. Only the few requests
+// needed by the freezer are supported: object upload, retrieval, ranged
+// retrieval and deletion, addressed in path-style.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// ErrNotFound is returned if the requested object doesn't exist in the bucket.
+var ErrNotFound = errors.New("object not found")
+
+// requestTimeout is the maximum time allowed for a single request.
+const requestTimeout = time.Minute
+
+// Config contains the settings of the S3-compatible bucket.
+type Config struct {
+	Endpoint  string // Endpoint of the service, e.g. https://s3.us-east-1.amazonaws.com
+	Bucket    string // Name of the bucket
+	Prefix    string // Key prefix of the objects in the bucket
+	Region    string // Region used for request signing
+	AccessKey string // Access key ID, anonymous requests are sent if empty
+	SecretKey string // Secret access key
+}
+
+// ParseURL parses the location of the objects in the form of
+// http(s)://<endpoint>/<bucket>/<prefix>, filling in the region and the
+// credentials from the standard AWS environment variables.
+func ParseURL(location string) (Config, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Config{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if bucket == "" {
+		return Config{}, errors.New("missing bucket")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = "us-east-1"
+	}
+	return Config{
+		Endpoint:  u.Scheme + "://" + u.Host,
+		Bucket:    bucket,
+		Prefix:    strings.TrimSuffix(prefix, "/"),
+		Region:    region,
+		AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}, nil
+}
+
+// Store is an object store backed by an S3-compatible bucket.
+type Store struct {
+	config Config
+	client *http.Client
+	signer *v4.Signer
+}
+
+var _ ethdb.ObjectStore = (*Store)(nil)
+
+// New creates a client of the S3-compatible bucket.
+func New(config Config) *Store {
+	return &Store{
+		config: config,
+		client: &http.Client{Timeout: requestTimeout},
+		signer: v4.NewSigner(),
+	}
+}
+
+// Put uploads the object with the given key.
+func (s *Store) Put(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, data, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp, http.StatusOK)
+}
+
+// Get retrieves the object with the given key.
+func (s *Store) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// GetRange retrieves the given byte range of the object with the given key.
+func (s *Store) GetRange(key string, offset, length uint64) ([]byte, error) {
+	if length == 0 {
+		return nil, nil
+	}
+	resp, err := s.do(http.MethodGet, key, nil, fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, http.StatusPartialContent); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != length {
+		return nil, fmt.Errorf("short range read of %s: have %d bytes, want %d", key, len(data), length)
+	}
+	return data, nil
+}
+
+// Delete removes the object with the given key. Deleting a non-existent
+// object is not an error.
+func (s *Store) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkResponse(resp, http.StatusNoContent)
+}
+
+// do sends a signed request for the object with the given key.
+func (s *Store) do(method, key string, body []byte, byteRange string) (*http.Response, error) {
+	if s.config.Prefix != "" {
+		key = s.config.Prefix + "/" + key
+	}
+	endpoint := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.config.Endpoint, "/"), s.config.Bucket, key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	if s.config.AccessKey != "" {
+		hash := sha256.Sum256(body)
+		payload := hex.EncodeToString(hash[:])
+		req.Header.Set("X-Amz-Content-Sha256", payload)
+
+		creds := aws.Credentials{AccessKeyID: s.config.AccessKey, SecretAccessKey: s.config.SecretKey}
+		if err := s.signer.SignHTTP(ctx, creds, req, payload, "s3", s.config.Region, time.Now()); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// checkResponse converts the unexpected response status into an error.
+func checkResponse(resp *http.Response, want int) error {
+	if resp.StatusCode == want {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected response status %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
+
+// cancelBody releases the request context when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServer is a minimal stand-in for an S3-compatible service, serving the
+// objects of a single bucket from memory.
+type testServer struct {
+	bucket string
+	auth   bool // Whether signed requests are required
+
+	lock    sync.Mutex
+	objects map[string][]byte
+	ranges  int // Number of range requests served
+}
+
+func newTestServer(bucket string, auth bool) (*testServer, *httptest.Server) {
+	s := &testServer{bucket: bucket, auth: auth, objects: make(map[string][]byte)}
+	return s, httptest.NewServer(s)
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.auth && !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = data
+
+	case http.MethodGet:
+		data, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Range") == "" {
+			w.Write(data)
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil || start > end || end >= len(data) {
+			http.Error(w, "InvalidRange", http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		s.ranges++
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	config, err := ParseURL("https://s3.example.org/ancients/mainnet/chaindata/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{
+		Endpoint:  "https://s3.example.org",
+		Bucket:    "ancients",
+		Prefix:    "mainnet/chaindata",
+		Region:    "us-east-1",
+		AccessKey: "key",
+		SecretKey: "secret",
+	}
+	if config != want {
+		t.Fatalf("config mismatch: have %+v, want %+v", config, want)
+	}
+	for _, location := range []string{"s3://ancients", "https://s3.example.org", "https://s3.example.org/"} {
+		if _, err := ParseURL(location); err == nil {
+			t.Errorf("expected error for %q", location)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	backend, server := newTestServer("ancients", true)
+	defer server.Close()
+
+	store := New(Config{
+		Endpoint:  server.URL,
+		Bucket:    "ancients",
+		Prefix:    "chain",
+		Region:    "us-east-1",
+		AccessKey: "key",
+		SecretKey: "secret",
+	})
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	if err := store.Put("headers/0000000000.dat", data); err != nil {
+		t.Fatalf("failed to put object: %v", err)
+	}
+	if _, ok := backend.objects["chain/headers/0000000000.dat"]; !ok {
+		t.Fatal("object not stored under the prefix")
+	}
+	blob, err := store.Get("headers/0000000000.dat")
+	if err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	if !bytes.Equal(blob, data) {
+		t.Fatalf("object mismatch: have %q, want %q", blob, data)
+	}
+	blob, err = store.GetRange("headers/0000000000.dat", 4, 5)
+	if err != nil {
+		t.Fatalf("failed to get range: %v", err)
+	}
+	if string(blob) != "quick" {
+		t.Fatalf("range mismatch: have %q, want %q", blob, "quick")
+	}
+	if backend.ranges != 1 {
+		t.Fatalf("range requests mismatch: have %d, want 1", backend.ranges)
+	}
+	if _, err := store.Get("headers/0000000001.dat"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing object error mismatch: have %v, want %v", err, ErrNotFound)
+	}
+	if err := store.Delete("headers/0000000000.dat"); err != nil {
+		t.Fatalf("failed to delete object: %v", err)
+	}
+	if _, err := store.Get("headers/0000000000.dat"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted object error mismatch: have %v, want %v", err, ErrNotFound)
+	}
+
+	// Unsigned requests must be rejected by the service
+	anon := New(Config{Endpoint: server.URL, Bucket: "ancients"})
+	if err := anon.Put("headers/0000000000.dat", data); err == nil {
+		t.Fatal("expected anonymous request to fail")
+	}
+}
@@ -121,6 +121,13 @@ type SyncProgress struct {
 	HealingTrienodes uint64 // Number of state trie nodes pending
 	HealingBytecode  uint64 // Number of bytecodes pending
 
+	// "snap sync" estimates
+	SyncedAccountRange uint64 // Downloaded part of the account hash space, in basis points
+	StorageBacklog     uint64 // Number of accounts and large contract ranges with storage pending
+	BytecodeBacklog    uint64 // Number of bytecodes pending during the download phase
+	HealingBacklog     uint64 // Number of trie nodes and bytecodes known to be missing during healing
+	StateSyncETA       uint64 // Estimated seconds until the current state sync phase completes
+
 	// "transaction indexing" fields
 	TxIndexFinishedBlocks  uint64 // Number of blocks whose transactions are already indexed
 	TxIndexRemainingBlocks uint64 // Number of blocks whose transactions are not indexed yet
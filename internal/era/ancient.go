@@ -0,0 +1,195 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// errUnknownKind is returned if the requested ancient kind is not one of the
+// chain freezer tables.
+var errUnknownKind = errors.New("unknown ancient kind")
+
+// Store implements the read-only chain freezer interface.
+var _ ethdb.AncientReader = (*Store)(nil)
+
+// HasAncient returns an indicator whether the specified data exists in the
+// era files.
+func (s *Store) HasAncient(kind string, number uint64) (bool, error) {
+	if !isChainKind(kind) {
+		return false, nil
+	}
+	tail, head, err := s.Bounds()
+	if err != nil {
+		return false, err
+	}
+	return tail <= number && number < head, nil
+}
+
+// Ancient retrieves the ancient item of the given kind from the era files.
+func (s *Store) Ancient(kind string, number uint64) ([]byte, error) {
+	if !isChainKind(kind) {
+		return nil, fmt.Errorf("%w: %s", errUnknownKind, kind)
+	}
+	tail, head, err := s.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if number < tail || number >= head {
+		return nil, fmt.Errorf("%w: block %d, range [%d, %d)", ErrNotArchived, number, tail, head)
+	}
+	e, err := s.open(number)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable:
+		return e.GetRawHeaderByNumber(number)
+
+	case rawdb.ChainFreezerHashTable:
+		header, err := e.GetRawHeaderByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(header), nil
+
+	case rawdb.ChainFreezerBodiesTable:
+		return e.GetRawBodyByNumber(number)
+
+	case rawdb.ChainFreezerReceiptTable:
+		blob, err := e.GetRawReceiptsByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+		return convertReceipts(blob)
+
+	default: // rawdb.ChainFreezerDifficultyTable
+		if !e.PostMerge() {
+			td, err := e.GetTotalDifficultyByNumber(number)
+			if err != nil {
+				return nil, err
+			}
+			return rlp.EncodeToBytes(td)
+		}
+		if s.mergeTd == nil {
+			return nil, fmt.Errorf("total difficulty of post-merge block %d not available", number)
+		}
+		return rlp.EncodeToBytes(s.mergeTd)
+	}
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'. It returns at most 'count' items, stopping at the end of the store.
+// If maxBytes is specified, at least one item is returned, but otherwise as
+// many items as fit into maxBytes.
+func (s *Store) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	_, head, err := s.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if start >= head {
+		return nil, fmt.Errorf("%w: block %d, head %d", ErrNotArchived, start, head)
+	}
+	if start+count > head {
+		count = head - start
+	}
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for i := uint64(0); i < count; i++ {
+		item, err := s.Ancient(kind, start+i)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+			break
+		}
+		items = append(items, item)
+		size += uint64(len(item))
+	}
+	return items, nil
+}
+
+// Ancients returns the number of the first block not covered by the era files.
+func (s *Store) Ancients() (uint64, error) {
+	_, head, err := s.Bounds()
+	return head, err
+}
+
+// Tail returns the number of the first block covered by the era files.
+func (s *Store) Tail() (uint64, error) {
+	tail, _, err := s.Bounds()
+	return tail, err
+}
+
+// AncientSize returns the total size of the era files. The items of all kinds
+// are interleaved in the files, so the size can't be attributed to a single
+// kind.
+func (s *Store) AncientSize(kind string) (uint64, error) {
+	if !isChainKind(kind) {
+		return 0, fmt.Errorf("%w: %s", errUnknownKind, kind)
+	}
+	var size uint64
+	for _, name := range append(append([]string{}, s.files...), s.merged...) {
+		info, err := os.Stat(filepath.Join(s.dir, name))
+		if err != nil {
+			return 0, err
+		}
+		size += uint64(info.Size())
+	}
+	return size, nil
+}
+
+// ReadAncients runs the given read operation. The era files are immutable, so
+// no additional locking is required.
+func (s *Store) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return fn(s)
+}
+
+// isChainKind reports whether the kind is one of the chain freezer tables.
+func isChainKind(kind string) bool {
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable, rawdb.ChainFreezerHashTable, rawdb.ChainFreezerBodiesTable,
+		rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable:
+		return true
+	}
+	return false
+}
+
+// convertReceipts converts the receipts from their consensus encoding stored in
+// the era files into the storage encoding used by the chain freezer.
+func convertReceipts(blob []byte) ([]byte, error) {
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+		return nil, err
+	}
+	stored := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		stored[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	return rlp.EncodeToBytes(stored)
+}
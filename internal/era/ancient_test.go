@@ -0,0 +1,188 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestBlocks creates a chain of blocks with receipts, transitioning to
+// proof-of-stake at the given block.
+func makeTestBlocks(n, merge int) ([]*types.Block, []types.Receipts) {
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		parent   common.Hash
+	)
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(1),
+			GasLimit:   30_000_000,
+			Time:       uint64(i),
+		}
+		if i >= merge {
+			header.Difficulty = new(big.Int)
+		}
+		tx := types.NewTransaction(uint64(i), common.Address{0xaa}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		receipt := &types.Receipt{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs:              []*types.Log{{Address: common.Address{0xbb}, Topics: []common.Hash{{byte(i)}}, Data: []byte{byte(i)}}},
+			TxHash:            tx.Hash(),
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, trie.NewStackTrie(nil))
+		blocks = append(blocks, block)
+		receipts = append(receipts, types.Receipts{receipt})
+		parent = block.Hash()
+	}
+	return blocks, receipts
+}
+
+func TestStoreAncientReader(t *testing.T) {
+	var (
+		dir              = t.TempDir()
+		merge            = 10
+		blocks, receipts = makeTestBlocks(20, merge)
+	)
+	// Write the pre-merge blocks into an era1 file and the post-merge blocks
+	// into a post-merge file of the same epoch.
+	write := func(name string, newBuilder func(io.Writer) *Builder, blocks []*types.Block, receipts []types.Receipts, tds bool) {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("error creating era file: %v", err)
+		}
+		defer f.Close()
+
+		b := newBuilder(f)
+		for i, block := range blocks {
+			var td *big.Int
+			if tds {
+				td = new(big.Int).Add(block.Number(), common.Big1)
+			}
+			if err := b.Add(block, receipts[i], td); err != nil {
+				t.Fatalf("error adding block %d: %v", block.NumberU64(), err)
+			}
+		}
+		if _, err := b.Finalize(); err != nil {
+			t.Fatalf("error finalizing era: %v", err)
+		}
+	}
+	write(Filename("test", 0, common.Hash{}), NewBuilder, blocks[:merge], receipts[:merge], true)
+	write(PostMergeFilename("test", 0, common.Hash{}), NewPostMergeBuilder, blocks[merge:], receipts[merge:], false)
+
+	store, err := OpenStore(dir, "test")
+	if err != nil {
+		t.Fatalf("error opening store: %v", err)
+	}
+	defer store.Close()
+
+	if tail, _ := store.Tail(); tail != 0 {
+		t.Fatalf("unexpected tail: %d", tail)
+	}
+	if head, _ := store.Ancients(); head != uint64(len(blocks)) {
+		t.Fatalf("unexpected head: have %d, want %d", head, len(blocks))
+	}
+	for i, block := range blocks {
+		number := uint64(i)
+
+		header, err := store.Ancient(rawdb.ChainFreezerHeaderTable, number)
+		if err != nil {
+			t.Fatalf("error reading header %d: %v", i, err)
+		}
+		if want, _ := rlp.EncodeToBytes(block.Header()); !bytes.Equal(header, want) {
+			t.Fatalf("header %d mismatch", i)
+		}
+		hash, err := store.Ancient(rawdb.ChainFreezerHashTable, number)
+		if err != nil || common.BytesToHash(hash) != block.Hash() {
+			t.Fatalf("hash %d mismatch: %v", i, err)
+		}
+		body, err := store.Ancient(rawdb.ChainFreezerBodiesTable, number)
+		if err != nil {
+			t.Fatalf("error reading body %d: %v", i, err)
+		}
+		if want, _ := rlp.EncodeToBytes(block.Body()); !bytes.Equal(body, want) {
+			t.Fatalf("body %d mismatch", i)
+		}
+		blob, err := store.Ancient(rawdb.ChainFreezerReceiptTable, number)
+		if err != nil {
+			t.Fatalf("error reading receipts %d: %v", i, err)
+		}
+		var stored []*types.ReceiptForStorage
+		if err := rlp.DecodeBytes(blob, &stored); err != nil {
+			t.Fatalf("invalid receipts %d in storage encoding: %v", i, err)
+		}
+		if len(stored) != 1 || stored[0].CumulativeGasUsed != 21000 || len(stored[0].Logs) != 1 || stored[0].Logs[0].Topics[0] != receipts[i][0].Logs[0].Topics[0] {
+			t.Fatalf("receipts %d mismatch", i)
+		}
+		blob, err = store.Ancient(rawdb.ChainFreezerDifficultyTable, number)
+		if err != nil {
+			t.Fatalf("error reading total difficulty %d: %v", i, err)
+		}
+		td := new(big.Int)
+		if err := rlp.DecodeBytes(blob, td); err != nil {
+			t.Fatalf("invalid total difficulty %d: %v", i, err)
+		}
+		want := int64(i + 1)
+		if i >= merge {
+			want = int64(merge)
+		}
+		if td.Int64() != want {
+			t.Fatalf("total difficulty %d mismatch: have %d, want %d", i, td, want)
+		}
+	}
+	if ok, _ := store.HasAncient(rawdb.ChainFreezerHeaderTable, uint64(len(blocks))); ok {
+		t.Fatal("unexpected item beyond the head")
+	}
+	if _, err := store.Ancient(rawdb.ChainFreezerHeaderTable, uint64(len(blocks))); err == nil {
+		t.Fatal("expected error reading beyond the head")
+	}
+	if _, err := store.Ancient("unknown", 0); err == nil {
+		t.Fatal("expected error reading unknown kind")
+	}
+	// Ranges crossing the merge, capped by the head and the byte limit.
+	items, err := store.AncientRange(rawdb.ChainFreezerHashTable, 5, 100, 0)
+	if err != nil || len(items) != len(blocks)-5 {
+		t.Fatalf("unexpected range: %d items, %v", len(items), err)
+	}
+	for i, item := range items {
+		if common.BytesToHash(item) != blocks[5+i].Hash() {
+			t.Fatalf("range item %d mismatch", i)
+		}
+	}
+	if items, _ := store.AncientRange(rawdb.ChainFreezerHashTable, 5, 10, 70); len(items) != 2 {
+		t.Fatalf("unexpected range with byte limit: %d items", len(items))
+	}
+	if items, _ := store.AncientRange(rawdb.ChainFreezerHashTable, 5, 10, 1); len(items) != 1 {
+		t.Fatalf("unexpected range with tiny byte limit: %d items", len(items))
+	}
+}
@@ -70,14 +70,28 @@ import (
 //
 // Due to the accumulator size limit of 8192, the maximum number of blocks in
 // an Era1 batch is also 8192.
+//
+// After the merge, the total difficulty is no longer meaningful and the blocks
+// are authenticated by the consensus layer instead of the accumulator. The
+// post-merge variant omits both of them and marks the block index with its own
+// entry type:
+//
+//	erae := Version | block-tuple* | other-entries* | ExecutionBlockIndex
+//	block-tuple :=  CompressedHeader | CompressedBody | CompressedReceipts
+//	ExecutionBlockIndex = { type: [0x67, 0x32], data: block-index }
+//
+// The bodies carry the withdrawals and the receipts are stored in their
+// consensus encoding, including the typed blob transaction receipts. Such an
+// archive is verified against a checkpointed hash of its last block.
 type Builder struct {
-	w        *e2store.Writer
-	startNum *uint64
-	startTd  *big.Int
-	indexes  []uint64
-	hashes   []common.Hash
-	tds      []*big.Int
-	written  int
+	w         *e2store.Writer
+	postMerge bool
+	startNum  *uint64
+	startTd   *big.Int
+	indexes   []uint64
+	hashes    []common.Hash
+	tds       []*big.Int
+	written   int
 
 	buf    *bytes.Buffer
 	snappy *snappy.Writer
@@ -93,8 +107,17 @@ func NewBuilder(w io.Writer) *Builder {
 	}
 }
 
+// NewPostMergeBuilder returns a new Builder instance creating post-merge era
+// archives, which carry no total difficulties and no accumulator.
+func NewPostMergeBuilder(w io.Writer) *Builder {
+	b := NewBuilder(w)
+	b.postMerge = true
+	return b
+}
+
 // Add writes a compressed block entry and compressed receipts entry to the
-// underlying e2store file.
+// underlying e2store file. The total difficulty must be nil for post-merge
+// archives.
 func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
 	eh, err := rlp.EncodeToBytes(block.Header())
 	if err != nil {
@@ -114,6 +137,12 @@ func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int)
 // AddRLP writes a compressed block entry and compressed receipts entry to the
 // underlying e2store file.
 func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash common.Hash, td, difficulty *big.Int) error {
+	if b.postMerge && td != nil {
+		return errors.New("total difficulty in post-merge era")
+	}
+	if !b.postMerge && td == nil {
+		return errors.New("missing total difficulty")
+	}
 	// Write Era1 version entry before first block.
 	if b.startNum == nil {
 		n, err := b.w.Write(TypeVersion, nil)
@@ -122,7 +151,9 @@ func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash comm
 		}
 		startNum := number
 		b.startNum = &startNum
-		b.startTd = new(big.Int).Sub(td, difficulty)
+		if !b.postMerge {
+			b.startTd = new(big.Int).Sub(td, difficulty)
+		}
 		b.written += n
 	}
 	if len(b.indexes) >= MaxEra1Size {
@@ -143,7 +174,9 @@ func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash comm
 	if err := b.snappyWrite(TypeCompressedReceipts, receipts); err != nil {
 		return err
 	}
-
+	if b.postMerge {
+		return nil
+	}
 	// Also write total difficulty, but don't snappy encode.
 	btd := bigToBytes32(td)
 	n, err := b.w.Write(TypeTotalDifficulty, btd[:])
@@ -156,20 +189,29 @@ func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash comm
 }
 
 // Finalize computes the accumulator and block index values, then writes the
-// corresponding e2store entries.
+// corresponding e2store entries. The returned root is the accumulator root, or
+// the hash of the last block for post-merge archives.
 func (b *Builder) Finalize() (common.Hash, error) {
 	if b.startNum == nil {
 		return common.Hash{}, errors.New("finalize called on empty builder")
 	}
-	// Compute accumulator root and write entry.
-	root, err := ComputeAccumulator(b.hashes, b.tds)
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("error calculating accumulator root: %w", err)
-	}
-	n, err := b.w.Write(TypeAccumulator, root[:])
-	b.written += n
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("error writing accumulator: %w", err)
+	var (
+		root      = b.hashes[len(b.hashes)-1]
+		indexType = TypeExecutionBlockIndex
+	)
+	if !b.postMerge {
+		// Compute accumulator root and write entry.
+		var err error
+		root, err = ComputeAccumulator(b.hashes, b.tds)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("error calculating accumulator root: %w", err)
+		}
+		n, err := b.w.Write(TypeAccumulator, root[:])
+		b.written += n
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("error writing accumulator: %w", err)
+		}
+		indexType = TypeBlockIndex
 	}
 	// Get beginning of index entry to calculate block relative offset.
 	base := int64(b.written)
@@ -195,7 +237,7 @@ func (b *Builder) Finalize() (common.Hash, error) {
 	binary.LittleEndian.PutUint64(index[8+count*8:], uint64(count))
 
 	// Finally, write the block index entry.
-	if _, err := b.w.Write(TypeBlockIndex, index); err != nil {
+	if _, err := b.w.Write(indexType, index); err != nil {
 		return common.Hash{}, fmt.Errorf("unable to write block index: %w", err)
 	}
 
@@ -44,44 +44,87 @@ var (
 	TypeAccumulator        uint16 = 0x07
 	TypeBlockIndex         uint16 = 0x3266
 
+	// TypeExecutionBlockIndex is the block index of the post-merge variant,
+	// which carries neither total difficulties nor an accumulator.
+	TypeExecutionBlockIndex uint16 = 0x3267
+
 	MaxEra1Size = 8192
 )
 
+var (
+	errPostMergeAccumulator = errors.New("post-merge era has no accumulator")
+	errPostMergeTD          = errors.New("post-merge era has no total difficulty")
+)
+
 // Filename returns a recognizable Era1-formatted file name for the specified
 // epoch and network.
 func Filename(network string, epoch int, root common.Hash) string {
 	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
 }
 
+// PostMergeFilename returns a recognizable file name of a post-merge era for
+// the specified epoch and network. The root is the hash of the last block in
+// the archive.
+func PostMergeFilename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.erae", network, epoch, root.Hex()[2:10])
+}
+
 // ReadDir reads all the era1 files in a directory for a given network.
 // Format: <network>-<epoch>-<hexroot>.era1
 func ReadDir(dir, network string) ([]string, error) {
+	eras, first, err := readDir(dir, network, ".era1")
+	if err != nil {
+		return nil, err
+	}
+	if len(eras) > 0 && first != 0 {
+		return nil, fmt.Errorf("missing epoch %d", 0)
+	}
+	return eras, nil
+}
+
+// ReadPostMergeDir reads all the post-merge era files in a directory for a
+// given network, alongside with the epoch of the first file. Unlike era1, the
+// post-merge archives start at the merge and not at genesis.
+// Format: <network>-<epoch>-<hexroot>.erae
+func ReadPostMergeDir(dir, network string) ([]string, int, error) {
+	return readDir(dir, network, ".erae")
+}
+
+// readDir reads all the era files with the given extension in a directory for
+// a given network. The files are required to cover consecutive epochs.
+func readDir(dir, network, ext string) ([]string, int, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+		return nil, 0, fmt.Errorf("error reading directory %s: %w", dir, err)
 	}
 	var (
-		next = uint64(0)
-		eras []string
+		first uint64
+		next  uint64
+		eras  []string
 	)
 	for _, entry := range entries {
-		if path.Ext(entry.Name()) != ".era1" {
+		if path.Ext(entry.Name()) != ext {
 			continue
 		}
 		parts := strings.Split(entry.Name(), "-")
 		if len(parts) != 3 || parts[0] != network {
-			// invalid era1 filename, skip
+			// invalid era filename, skip
 			continue
 		}
-		if epoch, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
-			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
-		} else if epoch != next {
-			return nil, fmt.Errorf("missing epoch %d", next)
+		epoch, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("malformed era filename: %s", entry.Name())
+		}
+		if len(eras) == 0 {
+			first, next = epoch, epoch
+		}
+		if epoch != next {
+			return nil, 0, fmt.Errorf("missing epoch %d", next)
 		}
 		next += 1
 		eras = append(eras, entry.Name())
 	}
-	return eras, nil
+	return eras, int(first), nil
 }
 
 type ReadAtSeekCloser interface {
@@ -151,11 +194,87 @@ func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
 	if err := rlp.Decode(r, &body); err != nil {
 		return nil, err
 	}
-	return types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles), nil
+	return types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals), nil
+}
+
+// GetRawHeaderByNumber returns the RLP-encoded header of the block with the
+// given number.
+func (e *Era) GetRawHeaderByNumber(num uint64) ([]byte, error) {
+	return e.readRecord(num, TypeCompressedHeader, 0)
+}
+
+// GetRawBodyByNumber returns the RLP-encoded body of the block with the given
+// number.
+func (e *Era) GetRawBodyByNumber(num uint64) ([]byte, error) {
+	return e.readRecord(num, TypeCompressedBody, 1)
+}
+
+// GetRawReceiptsByNumber returns the RLP-encoded receipts of the block with the
+// given number, in their consensus encoding.
+func (e *Era) GetRawReceiptsByNumber(num uint64) ([]byte, error) {
+	return e.readRecord(num, TypeCompressedReceipts, 2)
+}
+
+// GetTotalDifficultyByNumber returns the total difficulty of the block with the
+// given number. It's not available in post-merge archives.
+func (e *Era) GetTotalDifficultyByNumber(num uint64) (*big.Int, error) {
+	if e.m.postMerge {
+		return nil, errPostMergeTD
+	}
+	off, err := e.recordOffset(num, 3)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := e.s.ReaderAt(TypeTotalDifficulty, off)
+	if err != nil {
+		return nil, err
+	}
+	rawTd, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(reverseOrder(rawTd)), nil
+}
+
+// readRecord reads the compressed entry of the given type belonging to the
+// block with the given number, skipping over the block's preceding records.
+func (e *Era) readRecord(num uint64, typ uint16, skip int) ([]byte, error) {
+	off, err := e.recordOffset(num, skip)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := newSnappyReader(e.s, typ, off)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// recordOffset returns the offset of an entry belonging to the block with the
+// given number, skipping over the given number of the block's records.
+func (e *Era) recordOffset(num uint64, skip int) (int64, error) {
+	if e.m.start > num || e.m.start+e.m.count <= num {
+		return 0, errors.New("out-of-bounds")
+	}
+	off, err := e.readOffset(num)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < skip; i++ {
+		length, err := e.s.LengthAt(off)
+		if err != nil {
+			return 0, err
+		}
+		off += length
+	}
+	return off, nil
 }
 
 // Accumulator reads the accumulator entry in the Era1 file.
 func (e *Era) Accumulator() (common.Hash, error) {
+	if e.m.postMerge {
+		return common.Hash{}, errPostMergeAccumulator
+	}
 	entry, err := e.s.Find(TypeAccumulator)
 	if err != nil {
 		return common.Hash{}, err
@@ -174,7 +293,9 @@ func (e *Era) InitialTD() (*big.Int, error) {
 		off    int64
 		err    error
 	)
-
+	if e.m.postMerge {
+		return nil, errPostMergeTD
+	}
 	// Read first header.
 	if off, err = e.readOffset(e.m.start); err != nil {
 		return nil, err
@@ -218,6 +339,12 @@ func (e *Era) Count() uint64 {
 	return e.m.count
 }
 
+// PostMerge reports whether the archive is of the post-merge variant, which
+// carries no total difficulties and no accumulator.
+func (e *Era) PostMerge() bool {
+	return e.m.postMerge
+}
+
 // readOffset reads a specific block's offset from the block index. The value n
 // is the absolute block number desired.
 func (e *Era) readOffset(n uint64) (int64, error) {
@@ -250,9 +377,10 @@ func newSnappyReader(e *e2store.Reader, expectedType uint16, off int64) (io.Read
 
 // metadata wraps the metadata in the block index.
 type metadata struct {
-	start  uint64
-	count  uint64
-	length int64
+	start     uint64
+	count     uint64
+	length    int64
+	postMerge bool // whether the block index is of the post-merge variant
 }
 
 // readMetadata reads the metadata stored in an Era1 file's block index.
@@ -273,5 +401,18 @@ func readMetadata(f ReadAtSeekCloser) (m metadata, err error) {
 		return
 	}
 	m.start = binary.LittleEndian.Uint64(b[8:])
+
+	// Read the type of the block index entry, which determines the variant.
+	// It's the first field of the entry header preceding the start.
+	if _, err = f.ReadAt(b[:2], m.length-24-int64(m.count*8)); err != nil {
+		return
+	}
+	switch typ := binary.LittleEndian.Uint16(b[:2]); typ {
+	case TypeBlockIndex:
+	case TypeExecutionBlockIndex:
+		m.postMerge = true
+	default:
+		err = fmt.Errorf("invalid block index type %#x", typ)
+	}
 	return
 }
@@ -122,6 +122,105 @@ func TestEra1Builder(t *testing.T) {
 			t.Fatalf("mismatched tds: want %s, got %s", chain.tds[i], td)
 		}
 	}
+	// Verify the random access to the raw entries.
+	for i := uint64(0); i < uint64(len(chain.headers)); i++ {
+		body, err := e.GetRawBodyByNumber(i)
+		if err != nil {
+			t.Fatalf("error reading body %d: %v", i, err)
+		}
+		if !bytes.Equal(body, chain.bodies[i]) {
+			t.Fatalf("mismatched body: want %s, got %s", chain.bodies[i], body)
+		}
+		receipts, err := e.GetRawReceiptsByNumber(i)
+		if err != nil {
+			t.Fatalf("error reading receipts %d: %v", i, err)
+		}
+		if !bytes.Equal(receipts, chain.receipts[i]) {
+			t.Fatalf("mismatched receipts: want %s, got %s", chain.receipts[i], receipts)
+		}
+	}
+	if _, err := e.GetRawBodyByNumber(uint64(len(chain.headers))); err == nil {
+		t.Fatal("expected out-of-bounds error")
+	}
+}
+
+func TestPostMergeBuilder(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "erae-test")
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer f.Close()
+
+	var (
+		builder = NewPostMergeBuilder(f)
+		chain   = testchain{}
+		start   = uint64(1000)
+	)
+	for i := 0; i < 128; i++ {
+		chain.headers = append(chain.headers, []byte{byte('h'), byte(i)})
+		chain.bodies = append(chain.bodies, []byte{byte('b'), byte(i)})
+		chain.receipts = append(chain.receipts, []byte{byte('r'), byte(i)})
+	}
+	if err := builder.AddRLP(chain.headers[0], chain.bodies[0], chain.receipts[0], start, common.Hash{}, big.NewInt(1), big.NewInt(0)); err == nil {
+		t.Fatal("expected error adding total difficulty to post-merge era")
+	}
+	for i := 0; i < len(chain.headers); i++ {
+		if err := builder.AddRLP(chain.headers[i], chain.bodies[i], chain.receipts[i], start+uint64(i), common.Hash{byte(i)}, nil, big.NewInt(0)); err != nil {
+			t.Fatalf("error adding entry: %v", err)
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing era: %v", err)
+	}
+	if want := (common.Hash{byte(len(chain.headers) - 1)}); root != want {
+		t.Fatalf("unexpected root: have %x, want %x", root, want)
+	}
+	e, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	defer e.Close()
+
+	if !e.PostMerge() || e.Start() != start || e.Count() != uint64(len(chain.headers)) {
+		t.Fatalf("unexpected metadata: post-merge %v, start %d, count %d", e.PostMerge(), e.Start(), e.Count())
+	}
+	if _, err := e.Accumulator(); err == nil {
+		t.Fatal("expected missing accumulator")
+	}
+	if _, err := e.InitialTD(); err == nil {
+		t.Fatal("expected missing total difficulty")
+	}
+	it, err := NewRawIterator(e)
+	if err != nil {
+		t.Fatalf("failed to make iterator: %s", err)
+	}
+	for i := 0; i < len(chain.headers); i++ {
+		if !it.Next() {
+			t.Fatalf("expected more entries")
+		}
+		if it.Error() != nil {
+			t.Fatalf("unexpected error %v", it.Error())
+		}
+		if it.TotalDifficulty != nil {
+			t.Fatalf("unexpected total difficulty in entry %d", i)
+		}
+		header, _ := io.ReadAll(it.Header)
+		receipts, _ := io.ReadAll(it.Receipts)
+		if !bytes.Equal(header, chain.headers[i]) || !bytes.Equal(receipts, chain.receipts[i]) {
+			t.Fatalf("mismatched entry %d", i)
+		}
+		body, err := e.GetRawBodyByNumber(start + uint64(i))
+		if err != nil {
+			t.Fatalf("error reading body %d: %v", i, err)
+		}
+		if !bytes.Equal(body, chain.bodies[i]) {
+			t.Fatalf("mismatched body: want %s, got %s", chain.bodies[i], body)
+		}
+	}
+	if it.Next() {
+		t.Fatal("unexpected entry after the last block")
+	}
 }
 
 func TestEraFilename(t *testing.T) {
@@ -139,4 +238,7 @@ func TestEraFilename(t *testing.T) {
 			t.Errorf("test %d: invalid filename: want %s, got %s", i, tt.expected, got)
 		}
 	}
+	if got, want := PostMergeFilename("mainnet", 1896, common.Hash{1}), "mainnet-01896-01000000.erae"; got != want {
+		t.Errorf("invalid post-merge filename: want %s, got %s", want, got)
+	}
 }
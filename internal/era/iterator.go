@@ -73,7 +73,7 @@ func (it *Iterator) Block() (*types.Block, error) {
 	if err := rlp.Decode(it.inner.Body, &body); err != nil {
 		return nil, err
 	}
-	return types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles), nil
+	return types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals), nil
 }
 
 // Receipts returns the receipts for the iterator's current position.
@@ -101,8 +101,11 @@ func (it *Iterator) BlockAndReceipts() (*types.Block, types.Receipts, error) {
 }
 
 // TotalDifficulty returns the total difficulty for the iterator's current
-// position.
+// position. It's not available in post-merge archives.
 func (it *Iterator) TotalDifficulty() (*big.Int, error) {
+	if it.inner.TotalDifficulty == nil {
+		return nil, errPostMergeTD
+	}
 	td, err := io.ReadAll(it.inner.TotalDifficulty)
 	if err != nil {
 		return nil, err
@@ -134,7 +137,8 @@ func NewRawIterator(e *Era) (*RawIterator, error) {
 // Next moves the iterator to the next block entry. It returns false when all
 // items have been read or an error has halted its progress. Header, Body,
 // Receipts, TotalDifficulty will be set to nil in the case returning false or
-// finding an error and should therefore no longer be read from.
+// finding an error and should therefore no longer be read from. The
+// TotalDifficulty is always nil for post-merge archives.
 func (it *RawIterator) Next() bool {
 	// Clear old errors.
 	it.err = nil
@@ -166,9 +170,11 @@ func (it *RawIterator) Next() bool {
 		return true
 	}
 	off += n
-	if it.TotalDifficulty, _, it.err = it.e.s.ReaderAt(TypeTotalDifficulty, off); it.err != nil {
-		it.clear()
-		return true
+	if !it.e.m.postMerge {
+		if it.TotalDifficulty, _, it.err = it.e.s.ReaderAt(TypeTotalDifficulty, off); it.err != nil {
+			it.clear()
+			return true
+		}
 	}
 	it.next += 1
 	return true
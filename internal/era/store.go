@@ -0,0 +1,229 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ErrNotArchived is returned if the requested block is not covered by the
+// era files in the store.
+var ErrNotArchived = errors.New("block not archived")
+
+// Store provides random access to the chain history archived in a directory
+// of era files. The files are expected to follow the standard layout, each
+// of them covering MaxEra1Size blocks. The era1 files start from genesis,
+// the post-merge ones continue from the epoch of the merge.
+//
+// The store implements ethdb.AncientReader, allowing to mount the archive as
+// the read-only ancient store of the chain. The items are served in the same
+// encoding as the chain freezer:
+//
+//   - headers: the RLP-encoded header
+//   - hashes: the hash of the header
+//   - bodies: the RLP-encoded body
+//   - receipts: the receipts converted into their storage encoding
+//   - diffs: the RLP-encoded total difficulty, the post-merge blocks share the
+//     total difficulty of the last pre-merge block
+//
+// The era files are opened lazily on first access and kept open until the
+// store is closed. All methods are safe for concurrent use.
+type Store struct {
+	dir        string
+	files      []string // Era1 file names, ordered by epoch
+	merged     []string // Post-merge era file names, ordered by epoch
+	mergeEpoch int      // Epoch of the first post-merge era file
+
+	lock   sync.Mutex
+	eras   map[string]*Era // Opened era files, indexed by file name
+	closed bool
+
+	// The range of blocks [tail, head) covered by the store and the total
+	// difficulty at the merge, resolved on first use.
+	boundsOnce sync.Once
+	tail       uint64
+	head       uint64
+	mergeTd    *big.Int
+	boundsErr  error
+}
+
+// OpenStore opens the era files of the given network in the directory.
+func OpenStore(dir, network string) (*Store, error) {
+	files, err := ReadDir(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	merged, mergeEpoch, err := ReadPostMergeDir(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		dir:        dir,
+		files:      files,
+		merged:     merged,
+		mergeEpoch: mergeEpoch,
+		eras:       make(map[string]*Era),
+	}, nil
+}
+
+// GetBlock returns the block with the given number.
+func (s *Store) GetBlock(number uint64) (*types.Block, error) {
+	e, err := s.open(number)
+	if err != nil {
+		return nil, err
+	}
+	return e.GetBlockByNumber(number)
+}
+
+// GetRawBody returns the RLP-encoded body of the block with the given number.
+func (s *Store) GetRawBody(number uint64) ([]byte, error) {
+	e, err := s.open(number)
+	if err != nil {
+		return nil, err
+	}
+	return e.GetRawBodyByNumber(number)
+}
+
+// GetRawReceipts returns the RLP-encoded receipts of the block with the given
+// number, in their consensus encoding.
+func (s *Store) GetRawReceipts(number uint64) ([]byte, error) {
+	e, err := s.open(number)
+	if err != nil {
+		return nil, err
+	}
+	return e.GetRawReceiptsByNumber(number)
+}
+
+// open returns the era file covering the given block, opening it if needed.
+// The epoch of the merge is split between an era1 and a post-merge file, both
+// of them are checked.
+func (s *Store) open(number uint64) (*Era, error) {
+	var (
+		epoch = int(number / uint64(MaxEra1Size))
+		names []string
+	)
+	if epoch < len(s.files) {
+		names = append(names, s.files[epoch])
+	}
+	if epoch >= s.mergeEpoch && epoch < s.mergeEpoch+len(s.merged) {
+		names = append(names, s.merged[epoch-s.mergeEpoch])
+	}
+	if len(names) == 0 {
+		return nil, ErrNotArchived
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, name := range names {
+		e, err := s.openFile(name)
+		if err != nil {
+			return nil, err
+		}
+		if e.Start() <= number && number < e.Start()+e.Count() {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: block %d not covered by epoch %d", ErrNotArchived, number, epoch)
+}
+
+// openFile returns the era file with the given name, opening it if needed.
+// The caller must hold the lock.
+func (s *Store) openFile(name string) (*Era, error) {
+	if s.closed {
+		return nil, errors.New("era store closed")
+	}
+	if e, ok := s.eras[name]; ok {
+		return e, nil
+	}
+	e, err := Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	s.eras[name] = e
+	return e, nil
+}
+
+// Bounds returns the range of blocks [tail, head) covered by the store. The
+// era1 files start from genesis, if there are none, the store starts with the
+// first post-merge file.
+func (s *Store) Bounds() (uint64, uint64, error) {
+	s.boundsOnce.Do(func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.tail, s.head, s.boundsErr = s.resolveBounds()
+	})
+	return s.tail, s.head, s.boundsErr
+}
+
+// resolveBounds opens the boundary era files to determine the range of blocks
+// covered by the store and the total difficulty at the merge. The caller must
+// hold the lock.
+func (s *Store) resolveBounds() (uint64, uint64, error) {
+	var tail, head uint64
+	if len(s.files) > 0 {
+		last, err := s.openFile(s.files[len(s.files)-1])
+		if err != nil {
+			return 0, 0, err
+		}
+		head = last.Start() + last.Count()
+		if last.Count() > 0 {
+			if s.mergeTd, err = last.GetTotalDifficultyByNumber(head - 1); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+	if len(s.merged) > 0 {
+		first, err := s.openFile(s.merged[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(s.files) == 0 {
+			tail = first.Start()
+		} else if first.Start() != head {
+			return 0, 0, fmt.Errorf("gap between era1 and post-merge archives: era1 ends at %d, post-merge starts at %d", head, first.Start())
+		}
+		last, err := s.openFile(s.merged[len(s.merged)-1])
+		if err != nil {
+			return 0, 0, err
+		}
+		head = last.Start() + last.Count()
+	}
+	return tail, head, nil
+}
+
+// Close releases all the opened era files.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var errs []error
+	for name, e := range s.eras {
+		if err := e.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.eras, name)
+	}
+	s.closed = true
+	return errors.Join(errs...)
+}
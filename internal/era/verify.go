@@ -0,0 +1,108 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// VerifyCheckpoint verifies a post-merge archive against the trusted hash of
+// its last block. Since there is no accumulator after the merge, the archive
+// is authenticated by walking the header chain backwards from the checkpoint:
+//
+//  1. the hash of the last header matches the checkpoint
+//  2. every header is linked to its predecessor via the parent hash
+//  3. the transaction, uncle and withdrawal roots match the bodies
+//  4. the receipt root matches the receipts
+//
+// The parent hash of the first block is returned, allowing to chain the
+// verification of consecutive archives.
+func VerifyCheckpoint(e *Era, checkpoint common.Hash) (common.Hash, error) {
+	if !e.PostMerge() {
+		return common.Hash{}, errors.New("checkpoint verification of pre-merge era")
+	}
+	it, err := NewIterator(e)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("error making era iterator: %w", err)
+	}
+	var (
+		first  common.Hash // parent hash of the first block
+		parent common.Hash // hash of the previously verified block
+		number = e.Start()
+	)
+	for it.Next() {
+		if it.Error() != nil {
+			return common.Hash{}, fmt.Errorf("error reading block %d: %w", it.Number(), it.Error())
+		}
+		block, receipts, err := it.BlockAndReceipts()
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("error reading block %d: %w", it.Number(), err)
+		}
+		if block.NumberU64() != number {
+			return common.Hash{}, fmt.Errorf("block out of order: have %d, want %d", block.NumberU64(), number)
+		}
+		if number == e.Start() {
+			first = block.ParentHash()
+		} else if block.ParentHash() != parent {
+			return common.Hash{}, fmt.Errorf("block %d not linked to its parent: have %s, want %s", number, block.ParentHash(), parent)
+		}
+		if err := verifyBlock(block, receipts); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid block %d: %w", number, err)
+		}
+		parent = block.Hash()
+		number++
+	}
+	if it.Error() != nil {
+		return common.Hash{}, it.Error()
+	}
+	if number != e.Start()+e.Count() {
+		return common.Hash{}, fmt.Errorf("missing blocks: have %d, want %d", number-e.Start(), e.Count())
+	}
+	if parent != checkpoint {
+		return common.Hash{}, fmt.Errorf("checkpoint mismatch: have %s, want %s", parent, checkpoint)
+	}
+	return first, nil
+}
+
+// verifyBlock checks that the body and receipts belong to the block header.
+func verifyBlock(block *types.Block, receipts types.Receipts) error {
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != block.TxHash() {
+		return fmt.Errorf("tx root mismatch: have %s, want %s", hash, block.TxHash())
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != block.UncleHash() {
+		return fmt.Errorf("uncle hash mismatch: have %s, want %s", hash, block.UncleHash())
+	}
+	if want := block.Header().WithdrawalsHash; want != nil {
+		if block.Withdrawals() == nil {
+			return errors.New("missing withdrawals")
+		}
+		if hash := types.DeriveSha(block.Withdrawals(), trie.NewStackTrie(nil)); hash != *want {
+			return fmt.Errorf("withdrawals root mismatch: have %s, want %s", hash, *want)
+		}
+	} else if block.Withdrawals() != nil {
+		return errors.New("unexpected withdrawals")
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != block.ReceiptHash() {
+		return fmt.Errorf("receipt root mismatch: have %s, want %s", hash, block.ReceiptHash())
+	}
+	return nil
+}
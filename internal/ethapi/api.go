@@ -44,6 +44,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/gasestimator"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p"
@@ -97,6 +98,10 @@ type feeHistoryResult struct {
 	GasUsedRatio     []float64        `json:"gasUsedRatio"`
 	BlobBaseFee      []*hexutil.Big   `json:"baseFeePerBlobGas,omitempty"`
 	BlobGasUsedRatio []float64        `json:"blobGasUsedRatio,omitempty"`
+
+	// Forward-looking extensions, only set if the gas price oracle samples the pool
+	MempoolReward         []*hexutil.Big `json:"mempoolReward,omitempty"`
+	BlobBaseFeeSuggestion *hexutil.Big   `json:"blobBaseFeeSuggestion,omitempty"`
 }
 
 // FeeHistory returns the fee market history.
@@ -133,9 +138,65 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecim
 	if blobGasUsed != nil {
 		results.BlobGasUsedRatio = blobGasUsed
 	}
+	// The pool content only extends the history if it ends at the chain head,
+	// it has nothing to do with a historical range.
+	if len(gasUsed) == 0 {
+		return results, nil
+	}
+	last := new(big.Int).Add(oldest, big.NewInt(int64(len(gasUsed)-1)))
+	if last.Cmp(s.b.CurrentHeader().Number) < 0 {
+		return results, nil
+	}
+	mempoolReward, blobFee, err := s.b.MempoolFeeHistory(ctx, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	if mempoolReward != nil {
+		results.MempoolReward = make([]*hexutil.Big, len(mempoolReward))
+		for i, v := range mempoolReward {
+			results.MempoolReward[i] = (*hexutil.Big)(v)
+		}
+	}
+	results.BlobBaseFeeSuggestion = (*hexutil.Big)(blobFee)
 	return results, nil
 }
 
+// feeTier is a pair of fee caps suggested for a given urgency.
+type feeTier struct {
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas"`
+}
+
+type suggestedFeesResult struct {
+	BaseFee          *hexutil.Big `json:"baseFeePerGas"`
+	MaxFeePerBlobGas *hexutil.Big `json:"maxFeePerBlobGas,omitempty"`
+	Slow             feeTier      `json:"slow"`
+	Standard         feeTier      `json:"standard"`
+	Fast             feeTier      `json:"fast"`
+}
+
+// SuggestFees returns slow, standard and fast fee suggestions for dynamic fee
+// transactions, along with a blob fee cap suggestion after Cancun.
+func (s *EthereumAPI) SuggestFees(ctx context.Context) (*suggestedFeesResult, error) {
+	fees, err := s.b.SuggestFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tier := func(t gasprice.FeeTier) feeTier {
+		return feeTier{
+			MaxPriorityFeePerGas: (*hexutil.Big)(t.MaxPriorityFeePerGas),
+			MaxFeePerGas:         (*hexutil.Big)(t.MaxFeePerGas),
+		}
+	}
+	return &suggestedFeesResult{
+		BaseFee:          (*hexutil.Big)(fees.BaseFee),
+		MaxFeePerBlobGas: (*hexutil.Big)(fees.BlobBaseFee),
+		Slow:             tier(fees.Slow),
+		Standard:         tier(fees.Standard),
+		Fast:             tier(fees.Fast),
+	}, nil
+}
+
 // BlobBaseFee returns the base fee for blob gas at the current head.
 func (s *EthereumAPI) BlobBaseFee(ctx context.Context) *hexutil.Big {
 	return (*hexutil.Big)(s.b.BlobBaseFee(ctx))
@@ -172,6 +233,11 @@ func (s *EthereumAPI) Syncing() (interface{}, error) {
 		"healedBytecodeBytes":    hexutil.Uint64(progress.HealedBytecodeBytes),
 		"healingTrienodes":       hexutil.Uint64(progress.HealingTrienodes),
 		"healingBytecode":        hexutil.Uint64(progress.HealingBytecode),
+		"syncedAccountRange":     hexutil.Uint64(progress.SyncedAccountRange),
+		"storageBacklog":         hexutil.Uint64(progress.StorageBacklog),
+		"bytecodeBacklog":        hexutil.Uint64(progress.BytecodeBacklog),
+		"healingBacklog":         hexutil.Uint64(progress.HealingBacklog),
+		"stateSyncETA":           hexutil.Uint64(progress.StateSyncETA),
 		"txIndexFinishedBlocks":  hexutil.Uint64(progress.TxIndexFinishedBlocks),
 		"txIndexRemainingBlocks": hexutil.Uint64(progress.TxIndexRemainingBlocks),
 	}, nil
@@ -956,7 +1022,7 @@ func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.
 
 	result := make([]map[string]interface{}, len(receipts))
 	for i, receipt := range receipts {
-		result[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
+		result[i] = MarshalReceipt(receipt, block.Hash(), block.NumberU64(), signer, txs[i], i)
 	}
 
 	return result, nil
@@ -1214,18 +1280,120 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	return hexutil.Uint64(estimate), nil
 }
 
+// DoEstimateGasBundle estimates the gas of an ordered list of calls at block
+// `blockNrOrHash`, each executed on top of the state mutated by the ones before
+// it. The gas limits are capped by the calls' `Gas` fields (if non-nil & non-zero)
+// and `gasCap` (if non-zero) across the whole sequence.
+func DoEstimateGasBundle(ctx context.Context, b Backend, calls []TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, gasCap uint64) ([]*gasestimator.Estimation, error) {
+	// Retrieve the base state and mutate it with any overrides
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err = overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	// Construct the gas estimator option from the user input
+	opts := &gasestimator.Options{
+		Config:     b.ChainConfig(),
+		Chain:      NewChainContext(ctx, b),
+		Header:     header,
+		State:      state,
+		ErrorRatio: estimateGasErrorRatio,
+	}
+	msgs := make([]*core.Message, len(calls))
+	for i := range calls {
+		if err := calls[i].CallDefaults(gasCap, header.BaseFee, b.ChainConfig().ChainID); err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		msgs[i] = calls[i].ToMessage(header.BaseFee)
+	}
+	return gasestimator.EstimateSequence(ctx, msgs, opts, gasCap), nil
+}
+
 // EstimateGas returns the lowest possible gas limit that allows the transaction to run
 // successfully at block `blockNrOrHash`, or the latest block if `blockNrOrHash` is unspecified. It
 // returns error if the transaction would revert or if there are unexpected failures. The returned
 // value is capped by both `args.Gas` (if non-nil & non-zero) and the backend's RPCGasCap
 // configuration (if non-zero).
+//
+// If `prerequisites` are given, they are executed in order before the transaction
+// and the gas cap is enforced across the whole sequence. Any prerequisite failing
+// is reported as an error.
 // Note: Required blob gas is not computed in this method.
-func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Uint64, error) {
+func (s *BlockChainAPI) EstimateGas(ctx context.Context, args TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride, prerequisites *[]TransactionArgs) (hexutil.Uint64, error) {
 	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
 	if blockNrOrHash != nil {
 		bNrOrHash = *blockNrOrHash
 	}
-	return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, s.b.RPCGasCap())
+	if prerequisites == nil || len(*prerequisites) == 0 {
+		return DoEstimateGas(ctx, s.b, args, bNrOrHash, overrides, s.b.RPCGasCap())
+	}
+	calls := append(append([]TransactionArgs{}, *prerequisites...), args)
+
+	results, err := DoEstimateGasBundle(ctx, s.b, calls, bNrOrHash, overrides, s.b.RPCGasCap())
+	if err != nil {
+		return 0, err
+	}
+	for i, result := range results[:len(results)-1] {
+		if result.Err != nil {
+			if len(result.Revert) > 0 {
+				err := newRevertError(result.Revert)
+				err.error = fmt.Errorf("prerequisite call %d: %w", i, err.error)
+				return 0, err
+			}
+			return 0, fmt.Errorf("prerequisite call %d: %w", i, result.Err)
+		}
+	}
+	result := results[len(results)-1]
+	if result.Err != nil {
+		if len(result.Revert) > 0 {
+			return 0, newRevertError(result.Revert)
+		}
+		return 0, result.Err
+	}
+	return hexutil.Uint64(result.Gas), nil
+}
+
+// GasEstimate is the gas estimation outcome of a single call within a bundle.
+type GasEstimate struct {
+	Gas    hexutil.Uint64 `json:"gas"`
+	Error  string         `json:"error,omitempty"`
+	Revert hexutil.Bytes  `json:"revert,omitempty"`
+}
+
+// EstimateGasBundle returns the lowest possible gas limits that allow an ordered list
+// of transactions to run successfully at block `blockNrOrHash`, or the latest block if
+// `blockNrOrHash` is unspecified. Each transaction is executed on top of the state left
+// by the ones before it, so later calls may depend on earlier ones that were not yet
+// sent (e.g. an approval). The backend's RPCGasCap is enforced across the whole bundle.
+//
+// A failure of any call is reported in its own result, alongside the revert reason
+// if any, with all subsequent calls being skipped.
+func (s *BlockChainAPI) EstimateGasBundle(ctx context.Context, calls []TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *StateOverride) ([]*GasEstimate, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("empty call bundle")
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	results, err := DoEstimateGasBundle(ctx, s.b, calls, bNrOrHash, overrides, s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	estimates := make([]*GasEstimate, len(results))
+	for i, result := range results {
+		estimates[i] = &GasEstimate{Gas: hexutil.Uint64(result.Gas)}
+		if result.Err != nil {
+			estimates[i].Error = result.Err.Error()
+			if len(result.Revert) > 0 {
+				estimates[i].Error = newRevertError(result.Revert).Error()
+				estimates[i].Revert = result.Revert
+			}
+		}
+	}
+	return estimates, nil
 }
 
 // RPCMarshalHeader converts the given header to the RPC output .
@@ -1694,11 +1862,11 @@ func (s *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.
 
 	// Derive the sender.
 	signer := types.MakeSigner(s.b.ChainConfig(), header.Number, header.Time)
-	return marshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
+	return MarshalReceipt(receipt, blockHash, blockNumber, signer, tx, int(index)), nil
 }
 
-// marshalReceipt marshals a transaction receipt into a JSON object.
-func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int) map[string]interface{} {
+// MarshalReceipt marshals a transaction receipt into a JSON object.
+func MarshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, signer types.Signer, tx *types.Transaction, txIndex int) map[string]interface{} {
 	from, _ := types.Sender(signer, tx)
 
 	fields := map[string]interface{}{
@@ -47,10 +47,13 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/ethereum/go-ethereum/eth/gasestimator"
+	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/blocktest"
@@ -472,6 +475,12 @@ func (b testBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 func (b testBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, []*big.Int, []float64, error) {
 	return nil, nil, nil, nil, nil, nil, nil
 }
+func (b testBackend) MempoolFeeHistory(ctx context.Context, rewardPercentiles []float64) ([]*big.Int, *big.Int, error) {
+	return nil, nil, nil
+}
+func (b testBackend) SuggestFees(ctx context.Context) (*gasprice.SuggestedFees, error) {
+	panic("implement me")
+}
 func (b testBackend) BlobBaseFee(ctx context.Context) *big.Int { return new(big.Int) }
 func (b testBackend) ChainDb() ethdb.Database                  { return b.db }
 func (b testBackend) AccountManager() *accounts.Manager        { return b.accman }
@@ -606,6 +615,9 @@ func (b testBackend) TxPoolContentFrom(addr common.Address) ([]*types.Transactio
 func (b testBackend) SubscribeNewTxsEvent(events chan<- core.NewTxsEvent) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeTxPoolEvents(events chan<- []*txpool.TxEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) ChainConfig() *params.ChainConfig { return b.chain.Config() }
 func (b testBackend) Engine() consensus.Engine         { return b.chain.Engine() }
 func (b testBackend) GetLogs(ctx context.Context, blockHash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -618,6 +630,7 @@ func (b testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscripti
 	panic("implement me")
 }
 func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
+func (b testBackend) LogIndexTail() *uint64         { return nil }
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
 }
@@ -758,7 +771,7 @@ func TestEstimateGas(t *testing.T) {
 		},
 	}
 	for i, tc := range testSuite {
-		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides)
+		result, err := api.EstimateGas(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, nil)
 		if tc.expectErr != nil {
 			if err == nil {
 				t.Errorf("test %d: want error %v, have nothing", i, tc.expectErr)
@@ -779,6 +792,66 @@ func TestEstimateGas(t *testing.T) {
 	}
 }
 
+func TestEstimateGasBundle(t *testing.T) {
+	t.Parallel()
+	// Initialize test accounts
+	var (
+		accounts = newAccounts(1)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		// Contract setting slot 0 if called with any data, and reverting when
+		// called without data unless slot 0 is set.
+		gated     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		overrides = StateOverride{
+			gated: OverrideAccount{Code: hex2Bytes("36600f5760005460165760006000fd5b6001600055005b00")},
+		}
+		latest = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		unlock = TransactionArgs{From: &accounts[0].addr, To: &gated, Input: hex2Bytes("01")}
+		check  = TransactionArgs{From: &accounts[0].addr, To: &gated}
+	)
+	api := NewBlockChainAPI(newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	}))
+	// The gated call must fail on its own, both as a bundle and a plain estimate
+	if _, err := api.EstimateGas(context.Background(), check, &latest, &overrides, nil); !errors.Is(err, vm.ErrExecutionReverted) {
+		t.Fatalf("plain estimate error mismatch: have %v, want %v", err, vm.ErrExecutionReverted)
+	}
+	results, err := api.EstimateGasBundle(context.Background(), []TransactionArgs{check, unlock}, &latest, &overrides)
+	if err != nil {
+		t.Fatalf("failed to estimate bundle: %v", err)
+	}
+	if results[0].Error == "" || len(results[0].Revert) != 0 {
+		t.Errorf("reverting call result mismatch: have %q/%x", results[0].Error, results[0].Revert)
+	}
+	if results[1].Error != gasestimator.ErrPrerequisiteFailed.Error() {
+		t.Errorf("skipped call error mismatch: have %q, want %q", results[1].Error, gasestimator.ErrPrerequisiteFailed)
+	}
+	// With the prerequisite executed first, the gated call must succeed
+	results, err = api.EstimateGasBundle(context.Background(), []TransactionArgs{unlock, check}, &latest, &overrides)
+	if err != nil {
+		t.Fatalf("failed to estimate bundle: %v", err)
+	}
+	for i, result := range results {
+		if result.Error != "" {
+			t.Fatalf("call %d: unexpected error: %v", i, result.Error)
+		}
+	}
+	if results[0].Gas <= results[1].Gas {
+		t.Errorf("prerequisite estimate %d not above dependent estimate %d", results[0].Gas, results[1].Gas)
+	}
+	gas, err := api.EstimateGas(context.Background(), check, &latest, &overrides, &[]TransactionArgs{unlock})
+	if err != nil {
+		t.Fatalf("failed to estimate with prerequisites: %v", err)
+	}
+	if gas != results[1].Gas {
+		t.Errorf("estimate mismatch: have %d, want %d", gas, results[1].Gas)
+	}
+}
+
 func TestCall(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -42,6 +42,12 @@ func (e *revertError) ErrorData() interface{} {
 	return e.reason
 }
 
+// NewRevertError creates an API error carrying the provided revert data, as
+// returned by the eth_call and eth_estimateGas endpoints.
+func NewRevertError(revert []byte) error {
+	return newRevertError(revert)
+}
+
 // newRevertError creates a revertError instance with the provided revert data.
 func newRevertError(revert []byte) *revertError {
 	err := vm.ErrExecutionReverted
@@ -76,3 +82,20 @@ func (e *TxIndexingError) ErrorCode() int {
 
 // ErrorData returns the hex encoded revert reason.
 func (e *TxIndexingError) ErrorData() interface{} { return "transaction indexing is in progress" }
+
+// HistoryPrunedError is an API error that indicates the requested chain history
+// has been expired by the node and no archive is available to serve it.
+type HistoryPrunedError struct{}
+
+// NewHistoryPrunedError creates a HistoryPrunedError instance.
+func NewHistoryPrunedError() *HistoryPrunedError { return &HistoryPrunedError{} }
+
+// Error implement error interface, returning the error message.
+func (e *HistoryPrunedError) Error() string {
+	return "history pruned"
+}
+
+// ErrorCode returns the JSON error code for the expired history (EIP-4444).
+func (e *HistoryPrunedError) ErrorCode() int {
+	return 4444
+}
@@ -211,6 +211,10 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// DBKeyspaces splits new pebble databases into keyspaces, each one tuned
+	// for the kind of data it stores.
+	DBKeyspaces bool `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -729,6 +729,7 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 			Cache:     cache,
 			Handles:   handles,
 			ReadOnly:  readonly,
+			Keyspaces: n.config.DBKeyspaces,
 		})
 	}
 
@@ -744,6 +745,13 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 // database to immutable append-only files. If the node is an ephemeral one, a
 // memory database is returned.
 func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return n.OpenDatabaseWithRemoteFreezer(name, cache, handles, ancient, nil, namespace, readonly)
+}
+
+// OpenDatabaseWithRemoteFreezer is like OpenDatabaseWithFreezer, but the chain
+// freezer further offloads the completed ancient segments into the given remote
+// object store, if set.
+func (n *Node) OpenDatabaseWithRemoteFreezer(name string, cache, handles int, ancient string, remote ethdb.ObjectStore, namespace string, readonly bool) (ethdb.Database, error) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 	if n.state == closedState {
@@ -758,10 +766,12 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 			Type:              n.config.DBEngine,
 			Directory:         n.ResolvePath(name),
 			AncientsDirectory: n.ResolveAncient(name, ancient),
+			AncientsRemote:    remote,
 			Namespace:         namespace,
 			Cache:             cache,
 			Handles:           handles,
 			ReadOnly:          readonly,
+			Keyspaces:         n.config.DBKeyspaces,
 		})
 	}
 
@@ -152,6 +152,40 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that banned candidates are not dialed.
+func TestDialSchedBanned(t *testing.T) {
+	t.Parallel()
+
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.0.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "95.33.21.2:30303"),
+		newNode(uintID(0x04), "95.33.21.3:30303"),
+	}
+	config := dialConfig{
+		reputation:     newReputationStore(db),
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+	}
+	config.reputation.ban(nodes[0].ID(), nodes[0].IP(), time.Hour, "test")
+	config.reputation.ban(uintID(0x05), nodes[2].IP(), time.Hour, "test")
+	runDialTest(t, config, []dialTestRound{
+		{
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{nodes[1], nodes[3]},
+		},
+		{
+			succeeded: []enode.ID{
+				nodes[1].ID(),
+				nodes[3].ID(),
+			},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,530 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	adLifetime            = 15 * time.Minute // lifetime of a topic advertisement
+	topicQueueLimit       = 100              // max advertisements per topic
+	topicTableLimit       = 5000             // max advertisements across all topics
+	ticketValidity        = 10 * time.Second // time a ticket can be used after its waiting time
+	topicQueryResultLimit = 16               // applies in TOPICQUERY handler
+
+	topicRegistrars       = 8                // number of nodes the local node registers at
+	topicRefreshInterval  = 10 * time.Minute // re-registration interval, must be below adLifetime
+	topicRetryInterval    = 30 * time.Second // re-registration interval if all registrations failed
+	maxTicketWait         = adLifetime       // max cumulative waiting time for a registration
+	topicSearchRoundDelay = 5 * time.Second  // delay between search rounds without any results
+)
+
+var (
+	errInvalidTicket = errors.New("invalid ticket")
+	errTicketWait    = errors.New("ticket waiting time too long")
+)
+
+// topicHash returns the hash identifying the topic on the wire. The hash is also
+// the target of the lookups finding the nodes which store the advertisements.
+func topicHash(topic string) enode.ID {
+	return sha256.Sum256([]byte(topic))
+}
+
+// topicAd is an advertisement of a node under a topic.
+type topicAd struct {
+	node   *enode.Node
+	expiry mclock.AbsTime
+}
+
+// topicTable stores the topic advertisements placed by other nodes, and issues
+// the tickets for registrations which have to wait for space in the table.
+//
+// A registration is admitted right away if the topic queue has room. Otherwise
+// the registrant receives a ticket with the time until the oldest advertisement
+// of the topic expires. Presenting the ticket after the waiting time admits the
+// registration, evicting the oldest advertisement if the queue is full again.
+//
+// The table is only accessed by the dispatch loop and needs no locking.
+type topicTable struct {
+	ads        map[enode.ID][]topicAd
+	total      int
+	queueLimit int
+	tableLimit int
+	ticketKey  []byte
+}
+
+func newTopicTable() *topicTable {
+	tab := &topicTable{
+		ads:        make(map[enode.ID][]topicAd),
+		queueLimit: topicQueueLimit,
+		tableLimit: topicTableLimit,
+		ticketKey:  make([]byte, 32),
+	}
+	crand.Read(tab.ticketKey)
+	return tab
+}
+
+// register attempts to place the advertisement of the node. It returns a nil
+// ticket if the advertisement was placed, otherwise the ticket and the time to
+// wait before retrying.
+func (tab *topicTable) register(topic enode.ID, n *enode.Node, ticket []byte, now mclock.AbsTime) ([]byte, time.Duration) {
+	tab.expire(now)
+
+	queue := tab.ads[topic]
+	for i := range queue {
+		if queue[i].node.ID() == n.ID() {
+			// Refresh the existing advertisement with the latest record, moving
+			// it to the end to keep the queue sorted by expiry.
+			queue = append(queue[:i], queue[i+1:]...)
+			tab.ads[topic] = append(queue, topicAd{node: n, expiry: now.Add(adLifetime)})
+			return nil, 0
+		}
+	}
+	var admit bool
+	if len(ticket) > 0 {
+		waitUntil, err := tab.checkTicket(ticket, topic, n.ID())
+		switch {
+		case err != nil || now > waitUntil.Add(ticketValidity):
+			// Invalid or expired ticket, treat as a fresh registration.
+		case now < waitUntil:
+			// Too early, the waiting time stays the same.
+			return ticket, time.Duration(waitUntil - now)
+		default:
+			admit = true
+		}
+	}
+	if !admit && (len(queue) >= tab.queueLimit || tab.total >= tab.tableLimit) {
+		wait := tab.waitTime(topic, now)
+		return tab.newTicket(topic, n.ID(), now.Add(wait)), wait
+	}
+	// Make room for the advertisement if the registration was admitted by ticket.
+	if len(queue) >= tab.queueLimit {
+		queue = tab.evict(topic)
+	} else if tab.total >= tab.tableLimit {
+		tab.evict(tab.oldestTopic())
+		queue = tab.ads[topic]
+	}
+	tab.ads[topic] = append(queue, topicAd{node: n, expiry: now.Add(adLifetime)})
+	tab.total++
+	return nil, 0
+}
+
+// nodes returns the most recently advertised nodes of the topic.
+func (tab *topicTable) nodes(topic enode.ID, now mclock.AbsTime, limit int) []*enode.Node {
+	tab.expire(now)
+
+	queue := tab.ads[topic]
+	nodes := make([]*enode.Node, 0, min(len(queue), limit))
+	for i := len(queue) - 1; i >= 0 && len(nodes) < limit; i-- {
+		nodes = append(nodes, queue[i].node)
+	}
+	return nodes
+}
+
+// expire removes the expired advertisements. The topic queues are sorted by
+// expiry, so only their heads have to be checked.
+func (tab *topicTable) expire(now mclock.AbsTime) {
+	for topic, queue := range tab.ads {
+		n := 0
+		for n < len(queue) && queue[n].expiry <= now {
+			n++
+		}
+		if n == len(queue) {
+			delete(tab.ads, topic)
+		} else if n > 0 {
+			tab.ads[topic] = queue[n:]
+		}
+		tab.total -= n
+	}
+}
+
+// evict removes the oldest advertisement of the topic.
+func (tab *topicTable) evict(topic enode.ID) []topicAd {
+	queue := tab.ads[topic][1:]
+	if len(queue) == 0 {
+		delete(tab.ads, topic)
+	} else {
+		tab.ads[topic] = queue
+	}
+	tab.total--
+	return queue
+}
+
+// oldestTopic returns the topic containing the advertisement expiring first.
+func (tab *topicTable) oldestTopic() (oldest enode.ID) {
+	var expiry mclock.AbsTime
+	for topic, queue := range tab.ads {
+		if expiry == 0 || queue[0].expiry < expiry {
+			oldest, expiry = topic, queue[0].expiry
+		}
+	}
+	return oldest
+}
+
+// waitTime returns the time until an advertisement of the topic, or of the whole
+// table if it's full, expires.
+func (tab *topicTable) waitTime(topic enode.ID, now mclock.AbsTime) time.Duration {
+	if len(tab.ads[topic]) < tab.queueLimit {
+		topic = tab.oldestTopic()
+	}
+	return time.Duration(tab.ads[topic][0].expiry - now)
+}
+
+// topicTicket is the content of a ticket.
+type topicTicket struct {
+	Topic     enode.ID
+	Node      enode.ID
+	WaitUntil uint64
+}
+
+// newTicket creates a ticket authenticated by the table's key.
+func (tab *topicTable) newTicket(topic enode.ID, id enode.ID, waitUntil mclock.AbsTime) []byte {
+	enc, _ := rlp.EncodeToBytes(&topicTicket{Topic: topic, Node: id, WaitUntil: uint64(waitUntil)})
+	mac := hmac.New(sha256.New, tab.ticketKey)
+	mac.Write(enc)
+	return mac.Sum(enc)
+}
+
+// checkTicket verifies that the ticket was issued by the table for the given
+// registration, and returns the end of its waiting time.
+func (tab *topicTable) checkTicket(ticket []byte, topic enode.ID, id enode.ID) (mclock.AbsTime, error) {
+	if len(ticket) <= sha256.Size {
+		return 0, errInvalidTicket
+	}
+	enc, sum := ticket[:len(ticket)-sha256.Size], ticket[len(ticket)-sha256.Size:]
+	mac := hmac.New(sha256.New, tab.ticketKey)
+	mac.Write(enc)
+	if !hmac.Equal(mac.Sum(nil), sum) {
+		return 0, errInvalidTicket
+	}
+	var dec topicTicket
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		return 0, errInvalidTicket
+	}
+	if dec.Topic != topic || dec.Node != id {
+		return 0, errInvalidTicket
+	}
+	return mclock.AbsTime(dec.WaitUntil), nil
+}
+
+// handleRegtopic places the advertisement of the sender, or returns a ticket if
+// it has to wait. Only records matching the endpoint the request was sent from
+// are accepted, so nodes can't advertise addresses they don't control.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err == nil && n.ID() != fromID {
+		err = errors.New("record of wrong node")
+	}
+	if err == nil && n.IP() == nil {
+		err = errors.New("record without IP")
+	}
+	if err == nil && (!n.IP().Equal(fromAddr.IP) || n.UDP() != fromAddr.Port) {
+		err = errors.New("record endpoint mismatch")
+	}
+	if err == nil && t.netrestrict != nil && !t.netrestrict.Contains(n.IP()) {
+		err = errors.New("not contained in netrestrict list")
+	}
+	if err != nil {
+		t.log.Debug("Invalid record in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	ticket, wait := t.topics.register(p.Topic, n, p.Ticket, t.clock.Now())
+	if ticket == nil {
+		t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Topic: p.Topic})
+		return
+	}
+	// The waiting time is announced in seconds, round it up.
+	t.sendResponse(fromID, fromAddr, &v5wire.Ticket{
+		ReqID:    p.ReqID,
+		Ticket:   ticket,
+		WaitTime: uint((wait + time.Second - 1) / time.Second),
+	})
+}
+
+// handleTopicQuery returns the nodes advertised under a topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var nodes []*enode.Node
+	for _, n := range t.topics.nodes(p.Topic, t.clock.Now(), topicQueryResultLimit) {
+		if netutil.CheckRelayIP(fromAddr.IP, n.IP()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// regtopic calls REGTOPIC on a node. If the advertisement was not placed yet, it
+// returns the ticket to present after the waiting time.
+func (t *UDPv5) regtopic(n *enode.Node, topic enode.ID, ticket []byte) ([]byte, time.Duration, error) {
+	req := &v5wire.Regtopic{Topic: topic, ENR: t.Self().Record(), Ticket: ticket}
+	resp := t.callToNode(n, v5wire.TicketMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case respMsg := <-resp.ch:
+		switch respMsg := respMsg.(type) {
+		case *v5wire.Regconfirmation:
+			if respMsg.Topic != topic {
+				return nil, 0, errors.New("confirmation of wrong topic")
+			}
+			return nil, 0, nil
+		case *v5wire.Ticket:
+			if len(respMsg.Ticket) == 0 {
+				return nil, 0, errInvalidTicket
+			}
+			return respMsg.Ticket, time.Duration(respMsg.WaitTime) * time.Second, nil
+		}
+		return nil, 0, errors.New("unexpected response")
+	case err := <-resp.err:
+		return nil, 0, err
+	}
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for the advertised nodes.
+func (t *UDPv5) topicQuery(n *enode.Node, topic enode.ID) ([]*enode.Node, error) {
+	resp := t.callToNode(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
+// RegisterTopic starts advertising the local node under the given topic. The
+// node is registered at the nodes closest to the topic hash, and re-registered
+// periodically until UnregisterTopic is called or the transport is closed.
+func (t *UDPv5) RegisterTopic(topic string) {
+	t.topicMu.Lock()
+	defer t.topicMu.Unlock()
+
+	if _, ok := t.topicRegs[topic]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	t.topicRegs[topic] = cancel
+	t.wg.Add(1)
+	go t.topicRegisterLoop(ctx, topic)
+}
+
+// UnregisterTopic stops advertising the local node under the given topic. The
+// existing advertisements are left to expire.
+func (t *UDPv5) UnregisterTopic(topic string) {
+	t.topicMu.Lock()
+	defer t.topicMu.Unlock()
+
+	if cancel, ok := t.topicRegs[topic]; ok {
+		cancel()
+		delete(t.topicRegs, topic)
+	}
+}
+
+// topicRegisterLoop periodically registers the local node under a topic.
+func (t *UDPv5) topicRegisterLoop(ctx context.Context, topic string) {
+	defer t.wg.Done()
+
+	hash := topicHash(topic)
+	for {
+		nodes := t.newLookup(ctx, hash).run()
+		if len(nodes) > topicRegistrars {
+			nodes = nodes[:topicRegistrars]
+		}
+		var (
+			wg        sync.WaitGroup
+			confirmed = make(chan struct{}, len(nodes))
+		)
+		for _, n := range nodes {
+			wg.Add(1)
+			go func(n *enode.Node) {
+				defer wg.Done()
+				if err := t.registerAt(ctx, n, hash); err != nil {
+					t.log.Trace("Topic registration failed", "topic", topic, "id", n.ID(), "err", err)
+					return
+				}
+				t.log.Debug("Registered topic", "topic", topic, "id", n.ID(), "addr", &net.UDPAddr{IP: n.IP(), Port: n.UDP()})
+				confirmed <- struct{}{}
+			}(n)
+		}
+		wg.Wait()
+
+		interval := topicRefreshInterval
+		if len(confirmed) == 0 {
+			interval = topicRetryInterval
+		}
+		timer := t.clock.NewTimer(interval)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// registerAt registers the local node under a topic at the given node, waiting
+// for the tickets issued by it.
+func (t *UDPv5) registerAt(ctx context.Context, n *enode.Node, topic enode.ID) error {
+	var (
+		ticket []byte
+		waited time.Duration
+	)
+	for {
+		newTicket, wait, err := t.regtopic(n, topic, ticket)
+		if err != nil || newTicket == nil {
+			return err
+		}
+		if waited += wait; waited > maxTicketWait {
+			return errTicketWait
+		}
+		timer := t.clock.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return errClosed
+		}
+		ticket = newTicket
+	}
+}
+
+// TopicSearch returns an iterator that finds nodes advertised under the given
+// topic. It walks the DHT towards the topic hash, sending TOPICQUERY to the nodes
+// on the way.
+func (t *UDPv5) TopicSearch(topic string) enode.Iterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{
+		t:      t,
+		topic:  topicHash(topic),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// topicIterator finds the nodes advertised under a topic. The searches are
+// repeated, each search yields the advertised nodes at most once.
+type topicIterator struct {
+	t      *UDPv5
+	topic  enode.ID
+	ctx    context.Context
+	cancel func()
+	lookup *lookup
+	buffer []*enode.Node
+
+	mu      sync.Mutex
+	found   []*enode.Node     // advertised nodes found by the queries
+	seen    map[enode.ID]bool // advertised nodes found in the current search
+	results int               // number of nodes yielded in the current search
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	if len(it.buffer) == 0 {
+		return nil
+	}
+	return it.buffer[0]
+}
+
+// Next moves to the next node.
+func (it *topicIterator) Next() bool {
+	// Consume next node in buffer.
+	if len(it.buffer) > 0 {
+		it.buffer = it.buffer[1:]
+	}
+	for len(it.buffer) == 0 {
+		if it.ctx.Err() != nil {
+			it.lookup = nil
+			it.buffer = nil
+			return false
+		}
+		if it.buffer = it.takeFound(); len(it.buffer) > 0 {
+			break
+		}
+		if it.lookup == nil {
+			it.startSearch()
+			continue
+		}
+		if !it.lookup.advance() {
+			it.lookup = nil
+		}
+	}
+	return true
+}
+
+// startSearch starts a new search, after a delay if the previous search didn't
+// find anything.
+func (it *topicIterator) startSearch() {
+	it.mu.Lock()
+	idle := it.seen != nil && it.results == 0
+	it.seen, it.results = make(map[enode.ID]bool), 0
+	it.mu.Unlock()
+
+	if idle {
+		timer := it.t.clock.NewTimer(topicSearchRoundDelay)
+		select {
+		case <-timer.C():
+		case <-it.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+	it.lookup = newLookup(it.ctx, it.t.tab, it.topic, it.query)
+}
+
+// query sends TOPICQUERY to a node on the way to the topic hash, and continues
+// the walk with FINDNODE.
+func (it *topicIterator) query(n *node) ([]*node, error) {
+	ads, err := it.t.topicQuery(unwrapNode(n), it.topic)
+	if errors.Is(err, errClosed) {
+		return nil, err
+	}
+	it.mu.Lock()
+	for _, ad := range ads {
+		if ad.ID() != it.t.Self().ID() && !it.seen[ad.ID()] {
+			it.seen[ad.ID()] = true
+			it.found = append(it.found, ad)
+		}
+	}
+	it.mu.Unlock()
+
+	return it.t.lookupWorker(n, it.topic)
+}
+
+// takeFound returns the advertised nodes found since the last call.
+func (it *topicIterator) takeFound() []*enode.Node {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	found := it.found
+	it.found = nil
+	it.results += len(found)
+	return found
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
@@ -0,0 +1,288 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+)
+
+// This test checks that incoming REGTOPIC and TOPICQUERY calls are handled correctly.
+func TestUDPv5_regtopicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic   = topicHash("test")
+		remote  = test.getNode(test.remotekey, test.remoteaddr).Node()
+		key2    = newkey()
+		addr2   = &net.UDPAddr{IP: net.IP{10, 0, 1, 100}, Port: 30303}
+		remote2 = test.getNode(key2, addr2).Node()
+	)
+	test.udp.topics.queueLimit = 1
+
+	// The first registration is confirmed right away.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte{1}) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if p.Topic != topic {
+			t.Errorf("wrong topic in confirmation: %x", p.Topic)
+		}
+	})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{2}, Topic: topic})
+	test.expectNodes([]byte{2}, 1, []*enode.Node{remote})
+
+	// Other topics have no advertisements.
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{3}, Topic: topicHash("other")})
+	test.expectNodes([]byte{3}, 1, nil)
+
+	// The topic queue is full, so the next registration gets a ticket.
+	var ticket []byte
+	test.packetInFrom(key2, addr2, &v5wire.Regtopic{ReqID: []byte{4}, Topic: topic, ENR: remote2.Record()})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte{4}) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if p.WaitTime == 0 || p.WaitTime > uint(adLifetime/time.Second) {
+			t.Errorf("wrong waiting time in ticket: %d", p.WaitTime)
+		}
+		ticket = p.Ticket
+	})
+
+	// Presenting the ticket too early returns it again.
+	test.packetInFrom(key2, addr2, &v5wire.Regtopic{ReqID: []byte{5}, Topic: topic, ENR: remote2.Record(), Ticket: ticket})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.Ticket, ticket) {
+			t.Error("early registration got a different ticket")
+		}
+	})
+
+	// Re-registering an advertised node is confirmed.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{6}, Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {})
+
+	// Registrations with the record of another node are ignored.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{7}, Topic: topic, ENR: remote2.Record()})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{8}, Topic: topic})
+	test.expectNodes([]byte{8}, 1, []*enode.Node{remote})
+
+	// Registrations with a record not matching the sender endpoint are ignored.
+	var moved enr.Record
+	moved.Set(enr.IP(net.IP{10, 0, 1, 98}))
+	moved.Set(enr.UDP(test.remoteaddr.Port))
+	moved.SetSeq(remote.Seq() + 1)
+	if err := enode.SignV4(&moved, test.remotekey); err != nil {
+		t.Fatal(err)
+	}
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{9}, Topic: topicHash("moved"), ENR: &moved})
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{10}, Topic: topicHash("moved")})
+	test.expectNodes([]byte{10}, 1, nil)
+}
+
+// This test checks that tickets admit the registration after the waiting time.
+func TestTopicTable_tickets(t *testing.T) {
+	var (
+		tab   = newTopicTable()
+		topic = topicHash("test")
+		nodes = make([]*enode.Node, 3)
+		now   = mclock.AbsTime(1)
+	)
+	for i := range nodes {
+		nodes[i] = enode.SignNull(new(enr.Record), enode.ID{byte(i)})
+	}
+	tab.queueLimit = 2
+
+	if ticket, _ := tab.register(topic, nodes[0], nil, now); ticket != nil {
+		t.Fatal("first registration not admitted")
+	}
+	now = now.Add(time.Minute)
+	if ticket, _ := tab.register(topic, nodes[1], nil, now); ticket != nil {
+		t.Fatal("second registration not admitted")
+	}
+	ticket, wait := tab.register(topic, nodes[2], nil, now)
+	if ticket == nil {
+		t.Fatal("registration admitted into full queue")
+	}
+	if want := adLifetime - time.Minute; wait != want {
+		t.Fatalf("wrong waiting time %v, want %v", wait, want)
+	}
+	// The ticket is only valid for the node and topic it was issued for.
+	if _, err := tab.checkTicket(ticket, topic, nodes[1].ID()); err == nil {
+		t.Fatal("ticket accepted for other node")
+	}
+	if _, err := tab.checkTicket(ticket, topicHash("other"), nodes[2].ID()); err == nil {
+		t.Fatal("ticket accepted for other topic")
+	}
+	// The first advertisement expired, but the node registered again before
+	// the ticket was presented, so the queue is full again. The ticket still
+	// admits the registration, evicting the oldest advertisement.
+	now = now.Add(wait)
+	if _, err := tab.checkTicket(ticket, topic, nodes[2].ID()); err != nil {
+		t.Fatal("ticket rejected:", err)
+	}
+	if again, _ := tab.register(topic, nodes[0], nil, now); again != nil {
+		t.Fatal("registration after expiry not admitted")
+	}
+	if again, _ := tab.register(topic, nodes[2], ticket, now); again != nil {
+		t.Fatal("registration with ticket not admitted")
+	}
+	result := tab.nodes(topic, now, 10)
+	if len(result) != 2 || result[0].ID() != nodes[2].ID() || result[1].ID() != nodes[0].ID() {
+		t.Fatalf("wrong advertised nodes after ticket registration: %v", result)
+	}
+	// All advertisements expire eventually.
+	if result := tab.nodes(topic, now.Add(adLifetime), 10); len(result) != 0 {
+		t.Fatalf("advertisements not expired: %v", result)
+	}
+	if tab.total != 0 {
+		t.Fatalf("wrong total count %d after expiry", tab.total)
+	}
+}
+
+// This test checks that outgoing REGTOPIC calls work.
+func TestUDPv5_regtopicCall(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = topicHash("test")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+		done   = make(chan error, 1)
+		ticket []byte
+		wait   time.Duration
+	)
+	// This registration gets a ticket.
+	go func() {
+		var err error
+		ticket, wait, err = test.udp.regtopic(remote, topic, nil)
+		done <- err
+	}()
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Topic != topic {
+			t.Errorf("wrong topic in request: %x", p.Topic)
+		}
+		if len(p.Ticket) != 0 {
+			t.Errorf("unexpected ticket in first request")
+		}
+		if n, err := enode.New(enode.ValidSchemesForTesting, p.ENR); err != nil || n.ID() != test.udp.Self().ID() {
+			t.Errorf("wrong record in request")
+		}
+		test.packetIn(&v5wire.Ticket{ReqID: p.ReqID, Ticket: []byte("ticket"), WaitTime: 5})
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if string(ticket) != "ticket" || wait != 5*time.Second {
+		t.Fatalf("wrong ticket %q with waiting time %v", ticket, wait)
+	}
+
+	// This registration is confirmed.
+	go func() {
+		var err error
+		ticket, _, err = test.udp.regtopic(remote, topic, ticket)
+		done <- err
+	}()
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if string(p.Ticket) != "ticket" {
+			t.Errorf("wrong ticket in request: %q", p.Ticket)
+		}
+		test.packetIn(&v5wire.Regconfirmation{ReqID: p.ReqID, Topic: topic})
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ticket != nil {
+		t.Fatal("confirmed registration returned a ticket")
+	}
+}
+
+// This test checks that outgoing TOPICQUERY calls work.
+func TestUDPv5_topicQueryCall(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic    = topicHash("test")
+		remote   = test.getNode(test.remotekey, test.remoteaddr).Node()
+		nodes    = nodesAtDistance(remote.ID(), 250, 4)
+		done     = make(chan error, 1)
+		response []*enode.Node
+	)
+	go func() {
+		var err error
+		response, err = test.udp.topicQuery(remote, topic)
+		done <- err
+	}()
+	test.waitPacketOut(func(p *v5wire.TopicQuery, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Topic != topic {
+			t.Errorf("wrong topic in request: %x", p.Topic)
+		}
+		test.packetIn(&v5wire.Nodes{ReqID: p.ReqID, RespCount: 1, Nodes: nodesToRecords(nodes)})
+	})
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := checkNodesEqual(response, nodes); err != nil {
+		t.Fatalf("wrong nodes in response: %v", err)
+	}
+}
+
+// Real sockets, real crypto: this test checks that nodes registered under a
+// topic are found by a topic search.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			bn := nodes[0].Self()
+			cfg.Bootnodes = []*enode.Node{bn}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	nodes[1].RegisterTopic("test")
+	nodes[2].RegisterTopic("other")
+
+	it := nodes[N-1].TopicSearch("test")
+	defer it.Close()
+	timeout := time.AfterFunc(20*time.Second, it.Close)
+	defer timeout.Stop()
+
+	for it.Next() {
+		if id := it.Node().ID(); id != nodes[1].Self().ID() {
+			t.Fatalf("topic search found wrong node %v", id)
+		}
+		return
+	}
+	t.Fatal("topic search did not find the registered node")
+}
@@ -77,6 +77,11 @@ type UDPv5 struct {
 	// talkreq handler registry
 	talk *talkSystem
 
+	// topic advertisements stored for other nodes, and own registrations
+	topics    *topicTable
+	topicMu   sync.Mutex
+	topicRegs map[string]context.CancelFunc
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
@@ -124,6 +129,15 @@ type callV5 struct {
 	timeout        mclock.Timer
 }
 
+// expectsResponse reports whether a packet of the given type answers the call.
+// REGTOPIC is answered by either TICKET or REGCONFIRMATION.
+func (c *callV5) expectsResponse(kind byte) bool {
+	if c.responseType == v5wire.TicketMsg {
+		return kind == v5wire.TicketMsg || kind == v5wire.RegconfirmationMsg
+	}
+	return kind == c.responseType
+}
+
 // callTimeout is the response timeout event of a call.
 type callTimeout struct {
 	c     *callV5
@@ -175,6 +189,8 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		cancelCloseCtx: cancelCloseCtx,
 	}
 	t.talk = newTalkSystem(t)
+	t.topics = newTopicTable()
+	t.topicRegs = make(map[string]context.CancelFunc)
 	tab, err := newMeteredTable(t, t.db, cfg)
 	if err != nil {
 		return nil, err
@@ -722,7 +738,7 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr *net.UDPAddr, p v5w
 		t.log.Debug(fmt.Sprintf("%s from wrong endpoint", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
-	if p.Kind() != ac.responseType {
+	if !ac.expectsResponse(p.Kind()) {
 		t.log.Debug(fmt.Sprintf("Wrong discv5 response type %s", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
@@ -763,6 +779,14 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr *net.UDPAddr)
 		t.talk.handleRequest(fromID, fromAddr, p)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regconfirmation:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.handleTopicQuery(p, fromID, fromAddr)
 	}
 }
 
@@ -40,6 +40,7 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbRepPrefix    = "rep:" // Reputation entries, keyed by node ID or IP address
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -427,6 +428,136 @@ func (db *DB) UpdateFindFailsV5(id ID, ip net.IP, fails int) error {
 	return db.storeInt64(v5Key(id, ip, dbNodeFindFails), int64(fails))
 }
 
+// Reputation is the stored misbehavior record of a node or an IP address.
+type Reputation struct {
+	Score     uint64 // Penalty score as of the last update
+	Updated   uint64 // Unix time of the last score update
+	BanExpiry uint64 // Unix time at which the ban ends, zero if never banned
+	Reason    string // Reason of the last ban
+}
+
+// Banned reports whether the ban recorded in r is active at the given time.
+func (r *Reputation) Banned(now time.Time) bool {
+	return r != nil && r.BanExpiry > uint64(now.Unix())
+}
+
+// nodeRepKey returns the database key of the reputation entry of a node.
+func nodeRepKey(id ID) []byte {
+	return append([]byte(dbRepPrefix), id[:]...)
+}
+
+// ipRepKey returns the database key of the reputation entry of an IP address.
+func ipRepKey(ip net.IP) []byte {
+	return append([]byte(dbRepPrefix), ip.To16()...)
+}
+
+// NodeReputation retrieves the reputation entry of a node, or nil if there is none.
+func (db *DB) NodeReputation(id ID) *Reputation {
+	return db.fetchReputation(nodeRepKey(id))
+}
+
+// UpdateNodeReputation stores the reputation entry of a node.
+func (db *DB) UpdateNodeReputation(id ID, rep *Reputation) error {
+	return db.storeReputation(nodeRepKey(id), rep)
+}
+
+// DeleteNodeReputation removes the reputation entry of a node.
+func (db *DB) DeleteNodeReputation(id ID) error {
+	return db.lvl.Delete(nodeRepKey(id), nil)
+}
+
+// IPReputation retrieves the reputation entry of an IP address, or nil if there
+// is none.
+func (db *DB) IPReputation(ip net.IP) *Reputation {
+	if ip.To16() == nil {
+		return nil
+	}
+	return db.fetchReputation(ipRepKey(ip))
+}
+
+// UpdateIPReputation stores the reputation entry of an IP address.
+func (db *DB) UpdateIPReputation(ip net.IP, rep *Reputation) error {
+	if ip.To16() == nil {
+		return errInvalidIP
+	}
+	return db.storeReputation(ipRepKey(ip), rep)
+}
+
+// DeleteIPReputation removes the reputation entry of an IP address.
+func (db *DB) DeleteIPReputation(ip net.IP) error {
+	if ip.To16() == nil {
+		return errInvalidIP
+	}
+	return db.lvl.Delete(ipRepKey(ip), nil)
+}
+
+// Reputations calls fn for all stored reputation entries. Exactly one of id and
+// ip is set for each entry, the other one is the zero value.
+func (db *DB) Reputations(fn func(id ID, ip net.IP, rep *Reputation)) {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbRepPrefix)), nil)
+	defer it.Release()
+
+	for it.Next() {
+		var (
+			id  ID
+			ip  net.IP
+			rep Reputation
+			key = it.Key()[len(dbRepPrefix):]
+		)
+		if err := rlp.DecodeBytes(it.Value(), &rep); err != nil {
+			continue
+		}
+		switch len(key) {
+		case len(id):
+			copy(id[:], key)
+		case net.IPv6len:
+			ip = net.IP(bytes.Clone(key))
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+		default:
+			continue
+		}
+		fn(id, ip, &rep)
+	}
+}
+
+func (db *DB) fetchReputation(key []byte) *Reputation {
+	blob, err := db.lvl.Get(key, nil)
+	if err != nil {
+		return nil
+	}
+	rep := new(Reputation)
+	if err := rlp.DecodeBytes(blob, rep); err != nil {
+		return nil
+	}
+	return rep
+}
+
+func (db *DB) storeReputation(key []byte, rep *Reputation) error {
+	blob, err := rlp.EncodeToBytes(rep)
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(key, blob, nil)
+}
+
+// ExpireReputations deletes all reputation entries which have not been updated
+// for some time and do not hold an active ban.
+func (db *DB) ExpireReputations() {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbRepPrefix)), nil)
+	defer it.Release()
+
+	now := time.Now()
+	threshold := uint64(now.Add(-dbNodeExpiration).Unix())
+	for it.Next() {
+		var rep Reputation
+		if err := rlp.DecodeBytes(it.Value(), &rep); err != nil || (rep.Updated < threshold && !rep.Banned(now)) {
+			db.lvl.Delete(it.Key(), nil)
+		}
+	}
+}
+
 // localSeq retrieves the local record sequence counter, defaulting to the current
 // timestamp if no previous exists. This ensures that wiping all data associated
 // with a node (apart from its key) will not generate already used sequence nums.
@@ -467,3 +467,61 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// This test checks storage, iteration and expiration of reputation entries.
+func TestDBReputation(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		now      = time.Now()
+		id       = ID{1}
+		ip       = net.IP{1, 2, 3, 4}
+		staleID  = ID{2}
+		bannedIP = net.IP{5, 6, 7, 8}
+	)
+	db.UpdateNodeReputation(id, &Reputation{Score: 10, Updated: uint64(now.Unix())})
+	db.UpdateIPReputation(ip, &Reputation{Score: 20, Updated: uint64(now.Unix())})
+	db.UpdateNodeReputation(staleID, &Reputation{Score: 30, Updated: uint64(now.Add(-2 * dbNodeExpiration).Unix())})
+	db.UpdateIPReputation(bannedIP, &Reputation{
+		Updated:   uint64(now.Add(-2 * dbNodeExpiration).Unix()),
+		BanExpiry: uint64(now.Add(time.Hour).Unix()),
+		Reason:    "test",
+	})
+
+	if rep := db.NodeReputation(id); rep == nil || rep.Score != 10 {
+		t.Fatalf("wrong node reputation %+v", rep)
+	}
+	if rep := db.IPReputation(ip); rep == nil || rep.Score != 20 {
+		t.Fatalf("wrong IP reputation %+v", rep)
+	}
+	if !db.IPReputation(bannedIP).Banned(now) {
+		t.Fatal("ban not active")
+	}
+
+	// Expiration removes only the stale entry without an active ban.
+	db.ExpireReputations()
+	var (
+		ids []ID
+		ips []string
+	)
+	db.Reputations(func(id ID, ip net.IP, rep *Reputation) {
+		if ip != nil {
+			ips = append(ips, ip.String())
+		} else {
+			ids = append(ids, id)
+		}
+	})
+	if !reflect.DeepEqual(ids, []ID{id}) {
+		t.Errorf("wrong node entries after expiration: %v", ids)
+	}
+	if !reflect.DeepEqual(ips, []string{"1.2.3.4", "5.6.7.8"}) {
+		t.Errorf("wrong IP entries after expiration: %v", ips)
+	}
+
+	db.DeleteNodeReputation(id)
+	db.DeleteIPReputation(ip)
+	if db.NodeReputation(id) != nil || db.IPReputation(ip) != nil {
+		t.Error("reputation entries not deleted")
+	}
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
@@ -115,6 +116,9 @@ type Peer struct {
 	pingRecv chan struct{}
 	disc     chan DiscReason
 
+	// reputation records misbehavior if set
+	reputation *reputationStore
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -214,6 +218,20 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Penalize lowers the reputation of the peer because it misbehaved, e.g. by
+// sending invalid data. Penalty scores decay over time. Once the accumulated score
+// of the peer or its IP address reaches BanScore, the peer is disconnected and
+// cannot reconnect until the ban expires. Trusted peers are never penalized.
+func (p *Peer) Penalize(score uint64, reason string) {
+	if p.reputation == nil || p.rw.is(trustedConn) {
+		return
+	}
+	if p.reputation.penalize(p.ID(), netutil.AddrIP(p.RemoteAddr()), score, reason) {
+		p.log.Debug("Banning misbehaving peer", "addr", p.RemoteAddr(), "reason", reason)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	id := p.ID()
@@ -225,6 +243,11 @@ func (p *Peer) Inbound() bool {
 	return p.rw.is(inboundConn)
 }
 
+// Trusted returns true if the peer is a trusted peer.
+func (p *Peer) Trusted() bool {
+	return p.rw.is(trustedConn)
+}
+
 func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
 	p := &Peer{
@@ -0,0 +1,207 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+)
+
+const (
+	// BanScore is the penalty score at which a node or IP address is banned.
+	BanScore = 100
+
+	// DefaultBanDuration is the duration of bans imposed by BanPeer if no
+	// explicit duration is given.
+	DefaultBanDuration = 24 * time.Hour
+
+	reputationHalfLife    = 30 * time.Minute // Time after which penalty scores are halved
+	autoBanDuration       = 12 * time.Hour   // Duration of bans imposed by reaching BanScore
+	reputationExpiryCycle = time.Hour        // Time period for dropping stale reputation entries
+)
+
+// BanInfo describes an active ban of a node or an IP address.
+type BanInfo struct {
+	ID      string    `json:"id,omitempty"` // Banned node ID (hex)
+	IP      string    `json:"ip,omitempty"` // Banned IP address
+	Expires time.Time `json:"expires"`
+	Reason  string    `json:"reason"`
+}
+
+// reputationStore tracks misbehavior of remote nodes and enforces bans. Each node
+// ID and IP address accumulates penalty scores, which decay exponentially over
+// time. Once the score reaches BanScore, the node or address is banned for a
+// while. All entries are persisted in the node database, so bans survive
+// restarts.
+//
+// Scores are not tracked for LAN addresses because many unrelated nodes can
+// share them. The same applies to explicit bans.
+//
+// A nil store is valid and never bans anything.
+type reputationStore struct {
+	db  *enode.DB
+	now func() time.Time // for testing
+
+	mu sync.Mutex // serializes score updates
+}
+
+func newReputationStore(db *enode.DB) *reputationStore {
+	return &reputationStore{db: db, now: time.Now}
+}
+
+// penalize adds the given penalty score to the node and its IP address. It
+// reports whether the node or address got banned as a result.
+func (s *reputationStore) penalize(id enode.ID, ip net.IP, score uint64, reason string) (banned bool) {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	rep := s.addScore(s.db.NodeReputation(id), score, reason, now)
+	s.db.UpdateNodeReputation(id, rep)
+	banned = rep.Banned(now)
+	if trackIP(ip) {
+		rep := s.addScore(s.db.IPReputation(ip), score, reason, now)
+		s.db.UpdateIPReputation(ip, rep)
+		banned = banned || rep.Banned(now)
+	}
+	return banned
+}
+
+// addScore applies decay and the new penalty to a reputation entry, imposing a
+// ban if the resulting score reaches BanScore.
+func (s *reputationStore) addScore(rep *enode.Reputation, score uint64, reason string, now time.Time) *enode.Reputation {
+	if rep == nil {
+		rep = new(enode.Reputation)
+	}
+	rep.Score = decayScore(rep.Score, time.Unix(int64(rep.Updated), 0), now) + score
+	rep.Updated = uint64(now.Unix())
+	if rep.Score >= BanScore {
+		// The score is reset, so the node starts over once the ban ends.
+		rep.Score = 0
+		rep.BanExpiry = max(rep.BanExpiry, uint64(now.Add(autoBanDuration).Unix()))
+		rep.Reason = reason
+	}
+	return rep
+}
+
+// decayScore returns the value of a score set at time updated.
+func decayScore(score uint64, updated, now time.Time) uint64 {
+	elapsed := now.Sub(updated)
+	if elapsed <= 0 {
+		return score
+	}
+	return uint64(float64(score) * math.Exp2(-float64(elapsed)/float64(reputationHalfLife)))
+}
+
+// ban bans the node and its IP address for the given duration.
+func (s *reputationStore) ban(id enode.ID, ip net.IP, duration time.Duration, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	expiry := uint64(now.Add(duration).Unix())
+	update := func(rep *enode.Reputation) *enode.Reputation {
+		if rep == nil {
+			rep = &enode.Reputation{Updated: uint64(now.Unix())}
+		}
+		rep.BanExpiry = expiry
+		rep.Reason = reason
+		return rep
+	}
+	s.db.UpdateNodeReputation(id, update(s.db.NodeReputation(id)))
+	if trackIP(ip) {
+		s.db.UpdateIPReputation(ip, update(s.db.IPReputation(ip)))
+	}
+}
+
+// unban lifts the bans of the node and its IP address and clears their scores.
+func (s *reputationStore) unban(id enode.ID, ip net.IP) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.db.DeleteNodeReputation(id)
+	if trackIP(ip) {
+		s.db.DeleteIPReputation(ip)
+	}
+}
+
+// expire drops the reputation entries which have not been updated for a while
+// and do not hold an active ban.
+func (s *reputationStore) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.db.ExpireReputations()
+}
+
+// isBanned reports whether the node is banned. The IP address is optional.
+func (s *reputationStore) isBanned(id enode.ID, ip net.IP) bool {
+	if s == nil {
+		return false
+	}
+	now := s.now()
+	if s.db.NodeReputation(id).Banned(now) {
+		return true
+	}
+	return s.isBannedIP(ip)
+}
+
+// isBannedIP reports whether the IP address is banned.
+func (s *reputationStore) isBannedIP(ip net.IP) bool {
+	if s == nil || !trackIP(ip) {
+		return false
+	}
+	return s.db.IPReputation(ip).Banned(s.now())
+}
+
+// bans returns all active bans, ordered by expiry time.
+func (s *reputationStore) bans() []*BanInfo {
+	var (
+		now  = s.now()
+		list = make([]*BanInfo, 0)
+	)
+	s.db.Reputations(func(id enode.ID, ip net.IP, rep *enode.Reputation) {
+		if !rep.Banned(now) {
+			return
+		}
+		info := &BanInfo{Expires: time.Unix(int64(rep.BanExpiry), 0), Reason: rep.Reason}
+		if ip != nil {
+			info.IP = ip.String()
+		} else {
+			info.ID = id.String()
+		}
+		list = append(list, info)
+	})
+	slices.SortFunc(list, func(a, b *BanInfo) int {
+		return a.Expires.Compare(b.Expires)
+	})
+	return list
+}
+
+// trackIP reports whether reputation is tracked for the given address.
+func trackIP(ip net.IP) bool {
+	return ip != nil && !netutil.IsLAN(ip)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func newTestReputationStore(t *testing.T) (*reputationStore, *time.Time) {
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	now := time.Unix(1700000000, 0)
+	s := newReputationStore(db)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// This test checks that penalty scores decay and lead to a ban once they reach
+// the threshold.
+func TestReputationPenalize(t *testing.T) {
+	var (
+		s, now = newTestReputationStore(t)
+		id     = enode.ID{1}
+		ip     = net.IP{95, 33, 21, 2}
+	)
+	if s.penalize(id, ip, 60, "test") {
+		t.Fatal("banned below threshold")
+	}
+	// After one half-life, the score has decayed to 30.
+	*now = now.Add(reputationHalfLife)
+	if s.penalize(id, ip, 60, "test") {
+		t.Fatal("banned below threshold after decay")
+	}
+	if score := s.db.NodeReputation(id).Score; score != 90 {
+		t.Fatalf("wrong score %d, want 90", score)
+	}
+	if !s.penalize(id, ip, 10, "bad block") {
+		t.Fatal("not banned at threshold")
+	}
+	if !s.isBanned(id, nil) || !s.isBannedIP(ip) {
+		t.Fatal("ban not active")
+	}
+	// Other nodes on the banned address are banned as well.
+	if !s.isBanned(enode.ID{2}, ip) {
+		t.Fatal("ban of IP not enforced for other node")
+	}
+	bans := s.bans()
+	if len(bans) != 2 || bans[0].Reason != "bad block" {
+		t.Fatalf("wrong bans %+v", bans)
+	}
+
+	// The ban expires eventually.
+	*now = now.Add(autoBanDuration)
+	if s.isBanned(id, ip) {
+		t.Fatal("ban still active after expiry")
+	}
+	if bans := s.bans(); len(bans) != 0 {
+		t.Fatalf("expired bans listed: %+v", bans)
+	}
+}
+
+// This test checks that LAN addresses are not banned.
+func TestReputationLAN(t *testing.T) {
+	var (
+		s, _ = newTestReputationStore(t)
+		id   = enode.ID{1}
+		ip   = net.IP{127, 0, 0, 1}
+	)
+	if !s.penalize(id, ip, BanScore, "test") {
+		t.Fatal("not banned")
+	}
+	if s.isBannedIP(ip) {
+		t.Fatal("LAN address banned")
+	}
+	if s.isBanned(enode.ID{2}, ip) {
+		t.Fatal("other node on LAN address banned")
+	}
+}
+
+// This test checks explicit bans.
+func TestReputationBan(t *testing.T) {
+	var (
+		s, now = newTestReputationStore(t)
+		id     = enode.ID{1}
+		ip     = net.IP{95, 33, 21, 2}
+	)
+	s.ban(id, ip, time.Hour, "manual")
+	if !s.isBanned(id, nil) || !s.isBannedIP(ip) {
+		t.Fatal("ban not active")
+	}
+	s.unban(id, ip)
+	if s.isBanned(id, ip) {
+		t.Fatal("ban still active after unban")
+	}
+
+	s.ban(id, ip, time.Hour, "manual")
+	*now = now.Add(time.Hour)
+	if s.isBanned(id, ip) {
+		t.Fatal("ban still active after expiry")
+	}
+}
+
+// This test checks that a nil store never bans.
+func TestReputationNil(t *testing.T) {
+	var s *reputationStore
+	if s.penalize(enode.ID{1}, nil, BanScore, "test") || s.isBanned(enode.ID{1}, nil) || s.isBannedIP(net.IP{95, 33, 21, 2}) {
+		t.Fatal("nil store banned node")
+	}
+}
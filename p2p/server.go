@@ -100,6 +100,11 @@ type Config struct {
 	// protocol should be started or not.
 	DiscoveryV5 bool `toml:",omitempty"`
 
+	// DiscoveryTopics are the topics the node advertises itself under in the V5
+	// discovery protocol. Nodes found advertising the same topics are used as
+	// dial candidates.
+	DiscoveryTopics []string `toml:",omitempty"`
+
 	// Name sets the node name of this server.
 	Name string `toml:"-"`
 
@@ -188,12 +193,13 @@ type Server struct {
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	ntab      *discover.UDPv4
-	DiscV5    *discover.UDPv5
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	nodedb     *enode.DB
+	reputation *reputationStore
+	localnode  *enode.LocalNode
+	ntab       *discover.UDPv4
+	DiscV5     *discover.UDPv5
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
 
 	// This is read by the NAT port mapping loop.
 	portMappingRegister chan *portMapping
@@ -383,6 +389,40 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node) {
 	}
 }
 
+// BanPeer bans the given node and its IP address for the given duration and
+// disconnects it if it is currently connected. Banned nodes are not dialed and
+// inbound connections from them are rejected.
+func (srv *Server) BanPeer(node *enode.Node, duration time.Duration, reason string) error {
+	if srv.reputation == nil {
+		return errServerStopped
+	}
+	srv.reputation.ban(node.ID(), node.IP(), duration, reason)
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		if peer := peers[node.ID()]; peer != nil {
+			peer.Disconnect(DiscUselessPeer)
+		}
+	})
+	return nil
+}
+
+// UnbanPeer lifts the bans of the given node and its IP address, also resetting
+// their reputation.
+func (srv *Server) UnbanPeer(node *enode.Node) error {
+	if srv.reputation == nil {
+		return errServerStopped
+	}
+	srv.reputation.unban(node.ID(), node.IP())
+	return nil
+}
+
+// Bans returns all active bans of nodes and IP addresses.
+func (srv *Server) Bans() []*BanInfo {
+	if srv.reputation == nil {
+		return nil
+	}
+	return srv.reputation.bans()
+}
+
 // SubscribeEvents subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -518,6 +558,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputationStore(db)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -579,6 +620,12 @@ func (srv *Server) setupDiscovery() error {
 		if err != nil {
 			return err
 		}
+		for _, topic := range srv.DiscoveryTopics {
+			srv.DiscV5.RegisterTopic(topic)
+			srv.discmix.AddSource(srv.DiscV5.TopicSearch(topic))
+		}
+	} else if len(srv.DiscoveryTopics) > 0 {
+		srv.log.Warn("Discovery topics require discovery v5, ignoring them", "topics", srv.DiscoveryTopics)
 	}
 
 	// Add protocol-specific discovery sources.
@@ -599,6 +646,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		reputation:     srv.reputation,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -711,7 +759,13 @@ func (srv *Server) run() {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
+
+		// Stale reputation entries are dropped here rather than by the node
+		// database expirer, which only runs along with discovery.
+		expireReputations = srv.clock.NewTimer(reputationExpiryCycle)
 	)
+	defer expireReputations.Stop()
+
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup or added via AddTrustedPeer RPC.
 	for _, n := range srv.TrustedNodes {
@@ -748,6 +802,10 @@ running:
 			op(peers)
 			srv.peerOpDone <- struct{}{}
 
+		case <-expireReputations.C():
+			srv.reputation.expire()
+			expireReputations.Reset(reputationExpiryCycle)
+
 		case c := <-srv.checkpointPostHandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
@@ -829,6 +887,8 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case srv.reputation.isBanned(c.node.ID(), nil):
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -921,6 +981,10 @@ func (srv *Server) checkInboundConn(remoteIP net.IP) error {
 	if srv.NetRestrict != nil && !srv.NetRestrict.Contains(remoteIP) {
 		return errors.New("not in netrestrict list")
 	}
+	// Reject banned addresses.
+	if srv.reputation.isBannedIP(remoteIP) {
+		return errors.New("banned")
+	}
 	// Reject Internet peers that try too often.
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
@@ -1032,6 +1096,7 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.reputation = srv.reputation
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
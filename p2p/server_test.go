@@ -29,6 +29,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
@@ -579,6 +580,99 @@ func TestServerInboundThrottle(t *testing.T) {
 	}
 }
 
+// This test checks that banned nodes and addresses cannot connect.
+func TestServerBan(t *testing.T) {
+	var (
+		clientkey = newkey()
+		clientpub = &clientkey.PublicKey
+		client    = enode.NewV4(clientpub, net.IP{95, 33, 21, 2}, 30303, 30303)
+	)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Protocols:   []Protocol{discard},
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+		newTransport: func(fd net.Conn, dialDest *ecdsa.PublicKey) transport {
+			return &setupTransport{pubkey: clientpub, phs: protoHandshake{ID: crypto.FromECDSAPub(clientpub)[1:]}}
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("couldn't start server: %v", err)
+	}
+	defer srv.Stop()
+
+	if err := srv.BanPeer(client, time.Hour, "test"); err != nil {
+		t.Fatal(err)
+	}
+	bans := srv.Bans()
+	if len(bans) != 2 {
+		t.Fatalf("wrong number of bans %d, want 2", len(bans))
+	}
+	// The handshake is rejected by node ID.
+	p1, _ := net.Pipe()
+	if err := srv.SetupConn(p1, inboundConn, nil); !errors.Is(err, DiscUselessPeer) {
+		t.Fatalf("wrong error for banned node: %v", err)
+	}
+	// Inbound connections are rejected by address.
+	if err := srv.checkInboundConn(client.IP()); err == nil {
+		t.Fatal("connection from banned address accepted")
+	}
+
+	srv.UnbanPeer(client)
+	if bans := srv.Bans(); len(bans) != 0 {
+		t.Fatalf("bans not lifted: %+v", bans)
+	}
+	if err := srv.checkInboundConn(client.IP()); err != nil {
+		t.Fatalf("connection from unbanned address rejected: %v", err)
+	}
+}
+
+// This test checks that stale reputation entries are expired by the server even
+// if discovery is disabled.
+func TestServerExpireReputations(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		stale = enode.ID{1}
+		ip    = net.IP{95, 33, 21, 2}
+	)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+			clock:       clock,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("couldn't start server: %v", err)
+	}
+	defer srv.Stop()
+
+	srv.nodedb.UpdateNodeReputation(stale, &enode.Reputation{Score: 10, Updated: uint64(time.Now().Add(-48 * time.Hour).Unix())})
+	if err := srv.BanPeer(enode.NewV4(&newkey().PublicKey, ip, 30303, 30303), time.Hour, "test"); err != nil {
+		t.Fatal(err)
+	}
+	clock.WaitForTimers(1)
+	clock.Run(reputationExpiryCycle)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.nodedb.NodeReputation(stale) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("stale reputation entry not expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if bans := srv.Bans(); len(bans) != 2 {
+		t.Fatalf("active bans expired: %+v", bans)
+	}
+}
+
 func listenFakeAddr(network, laddr string, remoteAddr net.Addr) (net.Listener, error) {
 	l, err := net.Listen(network, laddr)
 	if err == nil {
@@ -467,6 +467,19 @@ func (s *Sync) Pending() int {
 	return len(s.nodeReqs) + len(s.codeReqs)
 }
 
+// Fetched returns the trie nodes which have already been retrieved, but cannot
+// be committed yet because parts of their subtries are still missing. They can
+// be used to resume an interrupted sync without retrieving them again.
+func (s *Sync) Fetched() []NodeSyncResult {
+	var results []NodeSyncResult
+	for path, req := range s.nodeReqs {
+		if req.data != nil {
+			results = append(results, NodeSyncResult{Path: path, Data: req.data})
+		}
+	}
+	return results
+}
+
 // scheduleNodeRequest inserts a new state retrieval request into the fetch queue. If there
 // is already a pending request for this node, the new request will be discarded
 // and only a parent reference added to the old one.
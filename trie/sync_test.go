@@ -1000,3 +1000,71 @@ func testSyncAbort(t *testing.T, scheme string) {
 	syncWith(t, rootC, destDisk, srcTrieDB)
 	checkTrieContents(t, destDisk, scheme, srcTrie.Hash().Bytes(), stateC, true)
 }
+
+// Tests that the trie nodes fetched by an interrupted sync can be used to resume
+// it without retrieving them again.
+func TestSyncFetchedResume(t *testing.T) {
+	testSyncFetchedResume(t, rawdb.HashScheme)
+	testSyncFetchedResume(t, rawdb.PathScheme)
+}
+
+func testSyncFetchedResume(t *testing.T, scheme string) {
+	// Create a random trie to copy
+	_, srcDb, srcTrie, srcData := makeTestTrie(scheme)
+	reader, err := srcDb.Reader(srcTrie.Hash())
+	if err != nil {
+		t.Fatalf("State is not available %x", srcTrie.Hash())
+	}
+	// Process retrieves the requested nodes, preferring the ones in the cache
+	// over the source trie.
+	var (
+		diskdb = rawdb.NewMemoryDatabase()
+		cache  = make(map[string][]byte)
+		hits   int
+	)
+	process := func(sched *Sync, count int) {
+		paths, nodes, _ := sched.Missing(count)
+		for i, path := range paths {
+			data, ok := cache[path]
+			if ok {
+				delete(cache, path)
+				hits++
+			} else {
+				owner, inner := ResolvePath([]byte(path))
+				if data, err = reader.Node(owner, inner, nodes[i]); err != nil {
+					t.Fatalf("failed to retrieve node data for %x: %v", nodes[i], err)
+				}
+			}
+			if err := sched.ProcessNode(NodeSyncResult{path, data}); err != nil {
+				t.Fatalf("failed to process result %v", err)
+			}
+		}
+		batch := diskdb.NewBatch()
+		if err := sched.Commit(batch); err != nil {
+			t.Fatalf("failed to commit data: %v", err)
+		}
+		batch.Write()
+	}
+	// Run a few rounds of the sync and abort it.
+	sched := NewSync(srcTrie.Hash(), diskdb, nil, srcDb.Scheme())
+	for i := 0; i < 3; i++ {
+		process(sched, 10)
+	}
+	fetched := sched.Fetched()
+	if len(fetched) == 0 {
+		t.Fatal("no fetched nodes reported for interrupted sync")
+	}
+	for _, res := range fetched {
+		cache[res.Path] = res.Data
+	}
+	// Resume the sync, all fetched nodes should be requested again and served
+	// from the cache.
+	sched = NewSync(srcTrie.Hash(), diskdb, nil, srcDb.Scheme())
+	for sched.Pending() > 0 {
+		process(sched, 0)
+	}
+	if hits != len(fetched) {
+		t.Fatalf("fetched nodes not reused: have %d, want %d", hits, len(fetched))
+	}
+	checkTrieContents(t, diskdb, srcDb.Scheme(), srcTrie.Hash().Bytes(), srcData, false)
+}
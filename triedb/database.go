@@ -256,6 +256,18 @@ func (db *Database) Dereference(root common.Hash) error {
 	return nil
 }
 
+// Track registers a callback which is invoked with the hash of every dirty trie
+// node currently cached, as well as every node inserted afterwards. Passing nil
+// unregisters the callback. It's only supported by hash-based database.
+func (db *Database) Track(tracker func(hash common.Hash)) error {
+	hdb, ok := db.backend.(*hashdb.Database)
+	if !ok {
+		return errors.New("not supported")
+	}
+	hdb.Track(tracker)
+	return nil
+}
+
 // Recover rollbacks the database to a specified historical point. The state is
 // supported as the rollback destination only if it's canonical state and the
 // corresponding trie histories are existent. It's only supported by path-based
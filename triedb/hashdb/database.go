@@ -102,6 +102,8 @@ type Database struct {
 	dirtiesSize  common.StorageSize // Storage size of the dirty node cache (exc. metadata)
 	childrenSize common.StorageSize // Storage size of the external children tracking
 
+	tracker func(hash common.Hash) // Callback invoked for every node inserted, used by online pruning
+
 	lock sync.RWMutex
 }
 
@@ -169,6 +171,9 @@ func (db *Database) insert(hash common.Hash, node []byte) {
 	})
 	db.dirties[hash] = entry
 
+	if db.tracker != nil {
+		db.tracker(hash)
+	}
 	// Update the flush-list endpoints
 	if db.oldest == (common.Hash{}) {
 		db.oldest, db.newest = hash, hash
@@ -178,6 +183,26 @@ func (db *Database) insert(hash common.Hash, node []byte) {
 	db.dirtiesSize += common.StorageSize(common.HashLength + len(node))
 }
 
+// Track registers a callback which is invoked with the hash of every dirty node
+// currently cached, as well as every node inserted afterwards, until it's
+// unregistered by passing nil. It's used by the online state pruner to protect
+// the freshly written nodes which are not yet persisted from deletion.
+//
+// The callback is invoked with the database lock held, it must not access the
+// database in any way.
+func (db *Database) Track(tracker func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.tracker = tracker
+	if tracker == nil {
+		return
+	}
+	for hash := range db.dirties {
+		tracker(hash)
+	}
+}
+
 // node retrieves an encoded cached trie node from memory. If it cannot be found
 // cached, the method queries the persistent database for the content.
 func (db *Database) node(hash common.Hash) ([]byte, error) {
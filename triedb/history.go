@@ -20,6 +20,9 @@ import (
 	"errors"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-ethereum/triedb/database"
 	"github.com/ethereum/go-ethereum/triedb/pathdb"
 )
 
@@ -70,3 +73,32 @@ func (db *Database) HistoryRange() (uint64, uint64, error) {
 	}
 	return pdb.HistoryRange()
 }
+
+// HistoricReader constructs a reader for accessing the flat states of the given
+// historical state root, resolved via the indexed state histories.
+//
+// This function is only supported by path mode database.
+func (db *Database) HistoricReader(root common.Hash) (*pathdb.HistoricalStateReader, error) {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return nil, errors.New("not supported")
+	}
+	return pdb.HistoricReader(root)
+}
+
+// HistoricNodes reconstructs the trie nodes of the given historical state root
+// in memory by reverting the state histories, returning a database for opening
+// the tries of that state. The returned database is only usable until the chain
+// progresses. The optional allow callback is consulted before starting a new
+// reconstruction, letting the caller throttle them.
+//
+// This function is only supported by path mode database.
+func (db *Database) HistoricNodes(root common.Hash, allow func() bool) (database.Database, error) {
+	pdb, ok := db.backend.(*pathdb.Database)
+	if !ok {
+		return nil, errors.New("not supported")
+	}
+	return pdb.HistoricNodes(root, func(db database.Database) triestate.TrieLoader {
+		return trie.NewMerkleLoader(db)
+	}, allow)
+}
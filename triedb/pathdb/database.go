@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -90,6 +91,7 @@ type layer interface {
 // Config contains the settings for database.
 type Config struct {
 	StateHistory   uint64 // Number of recent blocks to maintain state history for
+	HistoryIndex   bool   // Whether to index state histories for historical state reads
 	CleanCacheSize int    // Maximum memory allowance (in bytes) for caching clean nodes
 	DirtyCacheSize int    // Maximum memory allowance (in bytes) for caching dirty nodes
 	ReadOnly       bool   // Flag whether the database is opened in read only mode.
@@ -139,7 +141,11 @@ type Database struct {
 	diskdb     ethdb.Database           // Persistent storage for matured trie nodes
 	tree       *layerTree               // The group for all known layers
 	freezer    *rawdb.ResettableFreezer // Freezer for storing trie histories, nil possible in tests
+	indexer    *historyIndexer          // Indexer of state histories, nil if historical reads are disabled
 	lock       sync.RWMutex             // Lock to prevent mutations from happening at the same time
+
+	historics    lru.BasicLRU[common.Hash, *historicNodes] // Recently reconstructed historical states
+	historicLock sync.Mutex                                // Lock to serialize the reconstruction of historical states
 }
 
 // New attempts to load an already existing layer from a persistent key-value
@@ -157,6 +163,7 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 		bufferSize: config.DirtyCacheSize,
 		config:     config,
 		diskdb:     diskdb,
+		historics:  lru.NewBasicLRU[common.Hash, *historicNodes](historicNodesCacheSize),
 	}
 	// Construct the layer tree by resolving the in-disk singleton state
 	// and in-memory layer journal.
@@ -175,6 +182,17 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 		}
 		db.freezer = freezer
 
+		// Set up the state history indexer if historical state reads are
+		// enabled, otherwise drop the stale indexes left behind by a previous
+		// run, as they are not maintained anymore.
+		if config.HistoryIndex {
+			db.indexer = newHistoryIndexer(diskdb, freezer)
+		} else if rawdb.ReadStateHistoryIndexHead(diskdb) != nil {
+			log.Info("Dropping state history indexes")
+			if err := purgeHistoryIndexes(diskdb); err != nil {
+				log.Crit("Failed to drop state history indexes", "err", err)
+			}
+		}
 		diskLayerID := db.tree.bottom().stateID()
 		if diskLayerID == 0 {
 			// Reset the entire state histories in case the trie database is
@@ -188,9 +206,19 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 				if err != nil {
 					log.Crit("Failed to reset state histories", "err", err)
 				}
+				if db.indexer != nil {
+					if err := db.indexer.reset(); err != nil {
+						log.Crit("Failed to reset state history indexes", "err", err)
+					}
+				}
 				log.Info("Truncated extraneous state history")
 			}
 		} else {
+			if db.indexer != nil {
+				if err := db.indexer.truncateHead(diskLayerID); err != nil {
+					log.Crit("Failed to truncate extra state history indexes", "err", err)
+				}
+			}
 			// Truncate the extra state histories above in freezer in case
 			// it's not aligned with the disk layer.
 			pruned, err := truncateFromHead(db.diskdb, freezer, diskLayerID)
@@ -201,6 +229,9 @@ func New(diskdb ethdb.Database, config *Config, isVerkle bool) *Database {
 				log.Warn("Truncated extra state histories", "number", pruned)
 			}
 		}
+		if db.indexer != nil {
+			db.indexer.start()
+		}
 	}
 	// Disable database in case node is still in the initial state sync stage.
 	if rawdb.ReadSnapSyncStatusFlag(diskdb) == rawdb.StateSyncRunning && !db.readOnly {
@@ -313,6 +344,11 @@ func (db *Database) Enable(root common.Hash) error {
 			return err
 		}
 	}
+	if db.indexer != nil {
+		if err := db.indexer.reset(); err != nil {
+			return err
+		}
+	}
 	// Re-construct a new disk layer backed by persistent state
 	// with **empty clean cache and node buffer**.
 	db.tree.reset(newDiskLayer(root, 0, db, nil, newNodeBuffer(db.bufferSize, nil, 0)))
@@ -363,6 +399,11 @@ func (db *Database) Recover(root common.Hash, loader triestate.TrieLoader) error
 		db.tree.reset(dl)
 	}
 	rawdb.DeleteTrieJournal(db.diskdb)
+	if db.indexer != nil {
+		if err := db.indexer.truncateHead(dl.stateID()); err != nil {
+			return err
+		}
+	}
 	_, err := truncateFromHead(db.diskdb, db.freezer, dl.stateID())
 	if err != nil {
 		return err
@@ -416,6 +457,10 @@ func (db *Database) Close() error {
 	// Release the memory held by clean cache.
 	db.tree.bottom().resetCache()
 
+	// Terminate the state history indexing before closing the freezer.
+	if db.indexer != nil {
+		db.indexer.close()
+	}
 	// Close the attached state history freezer.
 	if db.freezer == nil {
 		return nil
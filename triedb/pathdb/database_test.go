@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/trie/trienode"
 	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-ethereum/triedb/database"
 	"github.com/holiman/uint256"
 )
 
@@ -318,7 +319,11 @@ func (t *tester) verifyState(root common.Hash) error {
 	if err != nil {
 		return err
 	}
-	_, err = reader.Node(common.Hash{}, nil, root)
+	return t.verifyReader(reader, root)
+}
+
+func (t *tester) verifyReader(reader database.Reader, root common.Hash) error {
+	_, err := reader.Node(common.Hash{}, nil, root)
 	if err != nil {
 		return errors.New("root node is not available")
 	}
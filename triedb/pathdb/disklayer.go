@@ -171,6 +171,11 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 		if err != nil {
 			return nil, err
 		}
+		if dl.db.indexer != nil {
+			if err := dl.db.indexer.extend(bottom.stateID(), bottom.states.Accounts, bottom.states.Storages); err != nil {
+				return nil, err
+			}
+		}
 		// Determine if the persisted history object has exceeded the configured
 		// limitation, set the overflow as true if so.
 		tail, err := dl.db.freezer.Tail()
@@ -212,6 +217,11 @@ func (dl *diskLayer) commit(bottom *diffLayer, force bool) (*diskLayer, error) {
 	// To remove outdated history objects from the end, we set the 'tail' parameter
 	// to 'oldest-1' due to the offset between the freezer index and the history ID.
 	if overflow {
+		if ndl.db.indexer != nil {
+			if err := ndl.db.indexer.truncateTail(oldest - 1); err != nil {
+				return nil, err
+			}
+		}
 		pruned, err := truncateFromTail(ndl.db.diskdb, ndl.db.freezer, oldest-1)
 		if err != nil {
 			return nil, err
@@ -39,4 +39,12 @@ var (
 	// errStateUnrecoverable is returned if state is required to be reverted to
 	// a destination without associated state history available.
 	errStateUnrecoverable = errors.New("state is unrecoverable")
+
+	// errHistoryIndexDisabled is returned if a historical state is requested
+	// but the state histories are not indexed.
+	errHistoryIndexDisabled = errors.New("state history indexing is disabled")
+
+	// errHistoryIndexing is returned if a historical state is requested but the
+	// state histories are still being indexed.
+	errHistoryIndexing = errors.New("state history indexing in progress")
 )
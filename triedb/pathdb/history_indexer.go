@@ -0,0 +1,322 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// historyIndexBatch is the number of state histories indexed at once by the
+// background indexer before releasing the lock.
+const historyIndexBatch = 1000
+
+// historyIndexer maintains the indexes of the accounts and storage slots mutated
+// by the state histories, which allow historical states to be resolved without
+// applying the histories one by one.
+//
+// The existing state histories are indexed in the background on startup, after
+// which the newly written histories are indexed synchronously. All the index
+// mutations are serialized by the lock.
+type historyIndexer struct {
+	disk    ethdb.KeyValueStore
+	freezer *rawdb.ResettableFreezer
+	head    uint64 // The id of the latest indexed state history
+	pruned  uint64 // The id of the latest history scheduled for tail truncation
+	lock    sync.Mutex
+
+	term      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newHistoryIndexer initializes the state history indexer. The background
+// indexing is not started until start is called.
+func newHistoryIndexer(disk ethdb.KeyValueStore, freezer *rawdb.ResettableFreezer) *historyIndexer {
+	var head uint64
+	if id := rawdb.ReadStateHistoryIndexHead(disk); id != nil {
+		head = *id
+	}
+	return &historyIndexer{
+		disk:    disk,
+		freezer: freezer,
+		head:    head,
+		term:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// start launches the background indexing of the existing state histories.
+func (i *historyIndexer) start() {
+	go i.run()
+}
+
+// run indexes the existing state histories in batches until the indexer has
+// caught up with the latest one.
+func (i *historyIndexer) run() {
+	defer close(i.done)
+
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		indexed int
+	)
+	for {
+		select {
+		case <-i.term:
+			return
+		default:
+		}
+		finished, n, err := i.indexBatch(historyIndexBatch)
+		if err != nil {
+			log.Error("Failed to index state histories", "err", err)
+			return
+		}
+		indexed += n
+		if finished {
+			if indexed > 0 {
+				log.Info("Indexed state histories", "histories", indexed, "elapsed", common.PrettyDuration(time.Since(start)))
+			}
+			return
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Indexing state histories", "histories", indexed, "head", i.indexed(), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+}
+
+// indexBatch indexes at most limit state histories above the indexed head. It
+// returns whether all the state histories have been indexed.
+func (i *historyIndexer) indexBatch(limit int) (bool, int, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	head, err := i.freezer.Ancients()
+	if err != nil {
+		return false, 0, err
+	}
+	tail, err := i.freezer.Tail()
+	if err != nil {
+		return false, 0, err
+	}
+	// The histories below the tail are already (or about to be) pruned,
+	// nothing to index
+	if tail < i.pruned {
+		tail = i.pruned
+	}
+	if i.head < tail {
+		i.head = tail
+	}
+	var (
+		n     int
+		batch = i.disk.NewBatch()
+	)
+	for ; i.head < head && n < limit; n++ {
+		h, err := readHistory(i.freezer, i.head+1)
+		if err != nil {
+			return false, 0, err
+		}
+		indexStates(batch, i.head+1, h.accounts, h.storages)
+		i.head++
+	}
+	rawdb.WriteStateHistoryIndexHead(batch, i.head)
+	if err := batch.Write(); err != nil {
+		return false, 0, err
+	}
+	return i.head == head, n, nil
+}
+
+// extend indexes the mutations of the state history with the given id, which
+// has just been written into the freezer. It's a noop if the background
+// indexing has not caught up yet, the history will be indexed by it instead.
+func (i *historyIndexer) extend(id uint64, accounts map[common.Address][]byte, storages map[common.Address]map[common.Hash][]byte) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.head+1 != id {
+		return nil
+	}
+	batch := i.disk.NewBatch()
+	indexStates(batch, id, accounts, storages)
+	rawdb.WriteStateHistoryIndexHead(batch, id)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	i.head = id
+	return nil
+}
+
+// truncateHead removes the indexes of the state histories above the new head.
+// It must be called before the histories are truncated from the freezer.
+func (i *historyIndexer) truncateHead(nhead uint64) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.head <= nhead {
+		return nil
+	}
+	ohead, err := i.freezer.Ancients()
+	if err != nil {
+		return err
+	}
+	// The indexed histories are missing from the freezer, the indexes can't be
+	// unwound precisely, rebuild them from scratch.
+	if i.head > ohead {
+		log.Warn("State history indexes beyond the freezer, rebuilding", "indexed", i.head, "head", ohead)
+		return i.resetLocked()
+	}
+	batch := i.disk.NewBatch()
+	for ; i.head > nhead; i.head-- {
+		h, err := readHistory(i.freezer, i.head)
+		if err != nil {
+			return err
+		}
+		unindexStates(batch, i.head, h.accounts, h.storages)
+	}
+	rawdb.WriteStateHistoryIndexHead(batch, i.head)
+	return batch.Write()
+}
+
+// truncateTail removes the indexes of the state histories up to and including
+// the new tail. It must be called before the histories are truncated from the
+// freezer.
+func (i *historyIndexer) truncateTail(ntail uint64) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	otail, err := i.freezer.Tail()
+	if err != nil {
+		return err
+	}
+	i.pruned = ntail
+
+	batch := i.disk.NewBatch()
+	for id := otail + 1; id <= ntail && id <= i.head; id++ {
+		h, err := readHistory(i.freezer, id)
+		if err != nil {
+			return err
+		}
+		unindexStates(batch, id, h.accounts, h.storages)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// reset drops all the indexes, e.g. when the state histories are reset.
+func (i *historyIndexer) reset() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.resetLocked()
+}
+
+// resetLocked is the internal version of reset which assumes the lock is held.
+func (i *historyIndexer) resetLocked() error {
+	if err := purgeHistoryIndexes(i.disk); err != nil {
+		return err
+	}
+	i.head = 0
+	rawdb.WriteStateHistoryIndexHead(i.disk, 0)
+	return nil
+}
+
+// indexed returns the id of the latest indexed state history.
+func (i *historyIndexer) indexed() uint64 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.head
+}
+
+// close terminates the background indexing. Safe to be called multiple times.
+func (i *historyIndexer) close() {
+	i.closeOnce.Do(func() {
+		close(i.term)
+	})
+	<-i.done
+}
+
+// indexStates writes the index entries of the accounts and storage slots
+// mutated in the state history with the given id.
+func indexStates(db ethdb.KeyValueWriter, id uint64, accounts map[common.Address][]byte, storages map[common.Address]map[common.Hash][]byte) {
+	for address := range accounts {
+		rawdb.WriteAccountHistoryIndex(db, address, id)
+	}
+	for address, slots := range storages {
+		for slot := range slots {
+			rawdb.WriteStorageHistoryIndex(db, address, slot, id)
+		}
+	}
+}
+
+// unindexStates removes the index entries of the accounts and storage slots
+// mutated in the state history with the given id.
+func unindexStates(db ethdb.KeyValueWriter, id uint64, accounts map[common.Address][]byte, storages map[common.Address]map[common.Hash][]byte) {
+	for address := range accounts {
+		rawdb.DeleteAccountHistoryIndex(db, address, id)
+	}
+	for address, slots := range storages {
+		for slot := range slots {
+			rawdb.DeleteStorageHistoryIndex(db, address, slot, id)
+		}
+	}
+}
+
+// purgeHistoryIndexes removes all the state history index entries along with
+// the index head marker from the database.
+func purgeHistoryIndexes(db ethdb.KeyValueStore) error {
+	var (
+		batch   = db.NewBatch()
+		indexes = []struct {
+			prefix []byte
+			length int
+		}{
+			{rawdb.StateHistoryAccountIndexPrefix, len(rawdb.StateHistoryAccountIndexPrefix) + common.AddressLength + 8},
+			{rawdb.StateHistoryStorageIndexPrefix, len(rawdb.StateHistoryStorageIndexPrefix) + common.AddressLength + common.HashLength + 8},
+		}
+	)
+	for _, index := range indexes {
+		it := db.NewIterator(index.prefix, nil)
+		for it.Next() {
+			if len(it.Key()) != index.length {
+				continue
+			}
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+	}
+	rawdb.DeleteStateHistoryIndexHead(batch)
+	return batch.Write()
+}
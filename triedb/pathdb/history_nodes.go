@@ -0,0 +1,244 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+const (
+	// maxHistoricNodesDepth is the maximum number of state histories which are
+	// reverted in memory for reconstructing the trie nodes of a historical state.
+	// The reconstruction cost and the size of the reverted nodes both grow with
+	// the depth, so it's capped at the number of diff layers kept in memory,
+	// rather than covering the entire retained history window. Older states
+	// must be served from an archive node instead.
+	maxHistoricNodesDepth = 128
+
+	// historicNodesCacheSize is the number of reconstructed historical states
+	// kept around for serving repeated requests.
+	historicNodesCacheSize = 4
+)
+
+// maxHistoricNodesMemory is the maximum size of the reverted trie nodes held by
+// the reconstructed historical states, both by the cached ones in total and by
+// the one being reconstructed. It's a variable for testing purposes.
+var maxHistoricNodesMemory uint64 = 64 * 1024 * 1024
+
+// errHistoricNodesThrottled is returned if the reconstruction of a historical
+// state is refused by the caller supplied limiter.
+var errHistoricNodesThrottled = errors.New("historical state reconstruction throttled")
+
+// errHistoricNodesTooLarge is returned if the reverted trie nodes of a historical
+// state exceed the memory allowance.
+var errHistoricNodesTooLarge = errors.New("historical state too large")
+
+// historicNodes is an in-memory overlay of the trie nodes of a historical state
+// which is no longer available in the layer tree. It is reconstructed by applying
+// the state histories in reverse order on top of the disk layer, without touching
+// the persistent state. Trie nodes unchanged since the historical state are read
+// from the disk layer.
+//
+// The overlay becomes unusable once the disk layer it was built upon becomes
+// stale, which happens whenever the chain progresses.
+type historicNodes struct {
+	root  common.Hash                               // State root of the (partially) reverted state
+	base  *diskLayer                                // Disk layer the overlay was built upon
+	nodes map[common.Hash]map[string]*trienode.Node // Reverted trie nodes, keyed by owner and path
+	size  uint64                                    // Approximate size of the reverted trie nodes
+}
+
+// Node implements database.Reader, retrieving the trie node with the specified
+// node info from the overlay, or from the disk layer if it's not overridden.
+func (h *historicNodes) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	var (
+		blob []byte
+		got  common.Hash
+	)
+	if n, ok := h.nodes[owner][string(path)]; ok {
+		blob, got = n.Blob, n.Hash
+	} else {
+		var err error
+		blob, got, _, err = h.base.node(owner, path, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if got != hash {
+		return nil, fmt.Errorf("unexpected historical node: (%x %v), %x!=%x", owner, path, hash, got)
+	}
+	return blob, nil
+}
+
+// Reader implements database.Database, returning the overlay itself as the node
+// reader of the reverted state.
+func (h *historicNodes) Reader(root common.Hash) (database.Reader, error) {
+	if root != h.root {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	return h, nil
+}
+
+// Preimage implements database.PreimageStore. Preimages are not tracked by the
+// overlay.
+func (h *historicNodes) Preimage(hash common.Hash) []byte {
+	return nil
+}
+
+// InsertPreimage implements database.PreimageStore. Preimages are not tracked
+// by the overlay.
+func (h *historicNodes) InsertPreimage(preimages map[common.Hash][]byte) {}
+
+// revert applies the given state history on top of the overlay, turning it into
+// the parent state of the history.
+func (h *historicNodes) revert(hist *history, loader triestate.TrieLoader) error {
+	if hist.meta.root != h.root {
+		return errUnexpectedHistory
+	}
+	nodes, err := triestate.Apply(hist.meta.parent, hist.meta.root, hist.accounts, hist.storages, loader)
+	if err != nil {
+		return err
+	}
+	for owner, subset := range nodes {
+		current, ok := h.nodes[owner]
+		if !ok {
+			current = make(map[string]*trienode.Node, len(subset))
+			h.nodes[owner] = current
+		}
+		for path, n := range subset {
+			if prev, ok := current[path]; ok {
+				h.size -= uint64(prev.Size() + len(path))
+			}
+			current[path] = n
+			h.size += uint64(n.Size() + len(path))
+		}
+	}
+	if h.size > maxHistoricNodesMemory {
+		return fmt.Errorf("%w: %v > %v", errHistoricNodesTooLarge, common.StorageSize(h.size), common.StorageSize(maxHistoricNodesMemory))
+	}
+	h.root = hist.meta.parent
+	return nil
+}
+
+// HistoricNodes reconstructs the trie nodes of the requested historical state
+// and returns them as a database for opening the tries of that state. The state
+// must be within the retained state histories and at most maxHistoricNodesDepth
+// states below the disk layer.
+//
+// The returned database is only usable until the chain progresses; afterwards
+// all node accesses fail and the state must be requested again. The loader
+// constructor is used for opening the tries of the intermediate states during
+// the reconstruction. The optional allow callback is consulted before starting
+// a new reconstruction, letting the caller throttle them; cached states are
+// served regardless.
+func (db *Database) HistoricNodes(root common.Hash, newLoader func(db database.Database) triestate.TrieLoader, allow func() bool) (database.Database, error) {
+	if db.isVerkle {
+		return nil, errors.New("historical trie nodes are not supported for verkle")
+	}
+	if db.freezer == nil {
+		return nil, errors.New("state histories are not available")
+	}
+	root = types.TrieRootHash(root)
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	dl := db.tree.bottom()
+	if *id >= dl.stateID() {
+		return nil, fmt.Errorf("state %#x is not historical", root)
+	}
+	if depth := dl.stateID() - *id; depth > maxHistoricNodesDepth {
+		return nil, fmt.Errorf("state %#x is too old, depth: %d, limit: %d", root, depth, maxHistoricNodesDepth)
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	if *id < tail {
+		return nil, fmt.Errorf("state %#x is pruned, id: %d, tail: %d", root, *id, tail)
+	}
+	// Reconstructions are serialized, both for letting concurrent requests of
+	// the same state share the result, and for bounding the resource usage.
+	db.historicLock.Lock()
+	defer db.historicLock.Unlock()
+
+	// Drop the states built upon a stale disk layer, they are unusable and only
+	// hold memory.
+	for _, key := range db.historics.Keys() {
+		if h, _ := db.historics.Peek(key); h.base.isStale() {
+			db.historics.Remove(key)
+		}
+	}
+	if h, ok := db.historics.Get(root); ok {
+		return h, nil
+	}
+	if allow != nil && !allow() {
+		return nil, errHistoricNodesThrottled
+	}
+	var (
+		start = time.Now()
+		h     = &historicNodes{
+			root:  dl.rootHash(),
+			base:  dl,
+			nodes: make(map[common.Hash]map[string]*trienode.Node),
+		}
+		loader = newLoader(h)
+	)
+	for next := dl.stateID(); next > *id; next-- {
+		hist, err := readHistory(db.freezer, next)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.revert(hist, loader); err != nil {
+			return nil, err
+		}
+	}
+	if h.root != root {
+		return nil, fmt.Errorf("%w: want %#x, got %#x", errUnexpectedHistory, root, h.root)
+	}
+	// Make room for the new state within the memory allowance, evicting the
+	// least recently used ones.
+	for db.historicsSize()+h.size > maxHistoricNodesMemory {
+		if _, _, ok := db.historics.RemoveOldest(); !ok {
+			break
+		}
+	}
+	db.historics.Add(root, h)
+	log.Debug("Reconstructed historical trie nodes", "root", root, "depth", dl.stateID()-*id, "size", common.StorageSize(h.size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return h, nil
+}
+
+// historicsSize returns the total size of the reverted trie nodes held by the
+// cached historical states. The caller must hold the historicLock.
+func (db *Database) historicsSize() uint64 {
+	var size uint64
+	for _, key := range db.historics.Keys() {
+		h, _ := db.historics.Peek(key)
+		size += h.size
+	}
+	return size
+}
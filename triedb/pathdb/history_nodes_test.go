@@ -0,0 +1,173 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/trie/triestate"
+	"github.com/ethereum/go-ethereum/triedb/database"
+)
+
+// testerLoader is a trie loader which opens the states of the given tester by
+// their roots, regardless of the backing database.
+type testerLoader struct {
+	t *tester
+}
+
+func (l *testerLoader) OpenTrie(root common.Hash) (triestate.Trie, error) {
+	return newTestHasher(common.Hash{}, root, l.t.snapAccounts[root])
+}
+
+func (l *testerLoader) OpenStorageTrie(stateRoot common.Hash, addrHash, root common.Hash) (triestate.Trie, error) {
+	return newTestHasher(addrHash, root, l.t.snapStorages[stateRoot][addrHash])
+}
+
+func TestHistoricNodes(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	var (
+		tester    = newTester(t, 0)
+		bottom    = tester.bottomIndex()
+		newLoader = func(db database.Database) triestate.TrieLoader {
+			return &testerLoader{t: tester}
+		}
+	)
+	defer tester.release()
+
+	// States in or above the disk layer are not historical
+	for i := bottom; i < len(tester.roots); i++ {
+		if _, err := tester.db.HistoricNodes(tester.roots[i], newLoader, nil); err == nil {
+			t.Fatalf("state %d: expected error for non-historical state", i)
+		}
+	}
+	if _, err := tester.db.HistoricNodes(common.Hash{0x1}, newLoader, nil); err == nil {
+		t.Fatal("expected error for unknown state")
+	}
+	// States below the disk layer are reconstructed from the state histories
+	dbs := make(map[common.Hash]database.Database)
+	for i := 0; i < bottom; i++ {
+		root := tester.roots[i]
+		db, err := tester.db.HistoricNodes(root, newLoader, nil)
+		if err != nil {
+			t.Fatalf("state %d: failed to reconstruct: %v", i, err)
+		}
+		reader, err := db.Reader(root)
+		if err != nil {
+			t.Fatalf("state %d: failed to open reader: %v", i, err)
+		}
+		if err := tester.verifyReader(reader, root); err != nil {
+			t.Fatalf("state %d: invalid state: %v", i, err)
+		}
+		dbs[root] = db
+	}
+	// Repeated requests are served from the cache
+	if db, _ := tester.db.HistoricNodes(tester.roots[0], newLoader, nil); db != dbs[tester.roots[0]] {
+		t.Fatal("historical state is not cached")
+	}
+	// Progress the chain, the reconstructed states must become unusable
+	parent := tester.lastHash()
+	root, nodes, states := tester.generate(parent)
+	if err := tester.db.Update(root, parent, uint64(len(tester.roots)), nodes, states); err != nil {
+		t.Fatalf("failed to update state changes: %v", err)
+	}
+	tester.roots = append(tester.roots, root)
+
+	old := tester.roots[0]
+	if reader, _ := dbs[old].Reader(old); tester.verifyReader(reader, old) == nil {
+		t.Fatal("stale historical state is still accessible")
+	}
+	// Requesting the state again reconstructs it upon the new disk layer
+	db, err := tester.db.HistoricNodes(old, newLoader, nil)
+	if err != nil {
+		t.Fatalf("failed to reconstruct: %v", err)
+	}
+	if db == dbs[old] {
+		t.Fatal("stale historical state is returned from cache")
+	}
+	reader, _ := db.Reader(old)
+	if err := tester.verifyReader(reader, old); err != nil {
+		t.Fatalf("invalid state: %v", err)
+	}
+}
+
+func TestHistoricNodesLimits(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	var (
+		tester    = newTester(t, 0)
+		bottom    = tester.bottomIndex()
+		newLoader = func(db database.Database) triestate.TrieLoader {
+			return &testerLoader{t: tester}
+		}
+		deny  = func() bool { return false }
+		allow = func() bool { return true }
+	)
+	defer tester.release()
+
+	// Throttled reconstructions are refused, cached states are served regardless
+	if _, err := tester.db.HistoricNodes(tester.roots[0], newLoader, deny); !errors.Is(err, errHistoricNodesThrottled) {
+		t.Fatalf("unexpected error for throttled reconstruction: %v", err)
+	}
+	if _, err := tester.db.HistoricNodes(tester.roots[0], newLoader, allow); err != nil {
+		t.Fatalf("failed to reconstruct: %v", err)
+	}
+	if _, err := tester.db.HistoricNodes(tester.roots[0], newLoader, deny); err != nil {
+		t.Fatalf("failed to retrieve cached state: %v", err)
+	}
+	// The cached states must stay within the memory allowance
+	h, _ := tester.db.historics.Peek(tester.roots[0])
+	defer func(old uint64) { maxHistoricNodesMemory = old }(maxHistoricNodesMemory)
+	maxHistoricNodesMemory = h.size + 1
+
+	if _, err := tester.db.HistoricNodes(tester.roots[bottom-1], newLoader, nil); err != nil {
+		t.Fatalf("failed to reconstruct: %v", err)
+	}
+	if tester.db.historics.Contains(tester.roots[0]) {
+		t.Fatal("cached state is not evicted for the memory allowance")
+	}
+	if size := tester.db.historicsSize(); size > maxHistoricNodesMemory {
+		t.Fatalf("cached states exceed the memory allowance: %d > %d", size, maxHistoricNodesMemory)
+	}
+	// States exceeding the allowance on their own are refused
+	maxHistoricNodesMemory = 1
+	if _, err := tester.db.HistoricNodes(tester.roots[0], newLoader, nil); !errors.Is(err, errHistoricNodesTooLarge) {
+		t.Fatalf("unexpected error for oversized state: %v", err)
+	}
+	// Progress the chain, the stale states must be dropped on the next access
+	parent := tester.lastHash()
+	root, nodes, states := tester.generate(parent)
+	if err := tester.db.Update(root, parent, uint64(len(tester.roots)), nodes, states); err != nil {
+		t.Fatalf("failed to update state changes: %v", err)
+	}
+	tester.roots = append(tester.roots, root)
+
+	tester.db.HistoricNodes(tester.roots[0], newLoader, deny)
+	if n := tester.db.historics.Len(); n != 0 {
+		t.Fatalf("stale states are not evicted, %d left", n)
+	}
+}
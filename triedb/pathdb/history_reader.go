@@ -0,0 +1,179 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// HistoricalStateReader is a reader for accessing the flat states of a historical
+// point which is no longer available in the layer tree.
+//
+// The value of an account or storage slot at the historical state is recorded
+// in the first state history mutating it afterwards, as the history contains
+// the values before the state transition. The state history is located via the
+// history indexes. If the value is left unchanged since the historical state,
+// it's resolved from the disk layer state instead, identified by DiskRoot.
+type HistoricalStateReader struct {
+	id       uint64      // The state id of the requested historical state
+	diskID   uint64      // The state id of the disk layer at construction
+	diskRoot common.Hash // The state root of the disk layer at construction
+	freezer  *rawdb.ResettableFreezer
+	db       *Database
+}
+
+// HistoricReader constructs a reader for accessing the requested historical
+// state. An error is returned if the state histories are not indexed or the
+// requested state is not reachable via the retained histories.
+func (db *Database) HistoricReader(root common.Hash) (*HistoricalStateReader, error) {
+	if db.freezer == nil || db.indexer == nil {
+		return nil, errHistoryIndexDisabled
+	}
+	root = types.TrieRootHash(root)
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return nil, fmt.Errorf("state %#x is not available", root)
+	}
+	dl := db.tree.bottom()
+	if *id >= dl.stateID() {
+		return nil, fmt.Errorf("state %#x is not historical", root)
+	}
+	tail, err := db.freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	if *id < tail {
+		return nil, fmt.Errorf("state %#x is pruned, id: %d, tail: %d", root, *id, tail)
+	}
+	if indexed := db.indexer.indexed(); indexed < dl.stateID() {
+		return nil, fmt.Errorf("%w, indexed: %d, required: %d", errHistoryIndexing, indexed, dl.stateID())
+	}
+	return &HistoricalStateReader{
+		id:       *id,
+		diskID:   dl.stateID(),
+		diskRoot: dl.rootHash(),
+		freezer:  db.freezer,
+		db:       db,
+	}, nil
+}
+
+// DiskRoot returns the root of the disk layer state, which the accounts and
+// storage slots unchanged since the historical state must be resolved from.
+func (r *HistoricalStateReader) DiskRoot() common.Hash {
+	return r.diskRoot
+}
+
+// Account returns the account in slim-RLP encoding at the historical state, or
+// an empty blob if the account was not present. The boolean flag reports whether
+// the account was resolved; if not, it's unchanged since the historical state
+// and must be read from the disk layer state.
+func (r *HistoricalStateReader) Account(address common.Address) ([]byte, bool, error) {
+	next, ok := rawdb.ReadAccountHistoryIndexNext(r.db.diskdb, address, r.id, r.diskID)
+	if !ok {
+		return nil, false, nil
+	}
+	blob, _, err := readAccountHistory(r.freezer, next, address)
+	if err != nil {
+		return nil, false, err
+	}
+	return blob, true, nil
+}
+
+// Storage returns the storage slot in RLP encoding at the historical state, or
+// an empty blob if the slot was not present. The slot is identified by the hash
+// of the raw slot key. The boolean flag reports whether the slot was resolved;
+// if not, it's unchanged since the historical state and must be read from the
+// disk layer state.
+func (r *HistoricalStateReader) Storage(address common.Address, slot common.Hash) ([]byte, bool, error) {
+	next, ok := rawdb.ReadStorageHistoryIndexNext(r.db.diskdb, address, slot, r.id, r.diskID)
+	if !ok {
+		return nil, false, nil
+	}
+	blob, err := readStorageHistory(r.freezer, next, address, slot)
+	if err != nil {
+		return nil, false, err
+	}
+	return blob, true, nil
+}
+
+// readAccountHistory reads the account data recorded in the specified state
+// history, i.e. the value of the account before the state transition, along
+// with the account index. The account is located by binary searching the
+// sorted account indexes, without decoding the entire history.
+func readAccountHistory(freezer *rawdb.ResettableFreezer, id uint64, address common.Address) ([]byte, accountIndex, error) {
+	indexes := rawdb.ReadStateAccountIndex(freezer, id)
+	if len(indexes) == 0 || len(indexes)%accountIndexSize != 0 {
+		return nil, accountIndex{}, fmt.Errorf("invalid account index of state history %d, len: %d", id, len(indexes))
+	}
+	var (
+		n   = len(indexes) / accountIndexSize
+		pos = sort.Search(n, func(i int) bool {
+			return bytes.Compare(indexes[i*accountIndexSize:i*accountIndexSize+common.AddressLength], address.Bytes()) >= 0
+		})
+	)
+	if pos == n || !bytes.Equal(indexes[pos*accountIndexSize:pos*accountIndexSize+common.AddressLength], address.Bytes()) {
+		return nil, accountIndex{}, fmt.Errorf("account %x is not in state history %d", address, id)
+	}
+	var index accountIndex
+	index.decode(indexes[pos*accountIndexSize : (pos+1)*accountIndexSize])
+
+	data := rawdb.ReadStateAccountHistory(freezer, id)
+	last := index.offset + uint32(index.length)
+	if uint32(len(data)) < last {
+		return nil, accountIndex{}, fmt.Errorf("account data of state history %d is corrupted", id)
+	}
+	return data[index.offset:last], index, nil
+}
+
+// readStorageHistory reads the storage slot data recorded in the specified state
+// history, i.e. the value of the slot before the state transition.
+func readStorageHistory(freezer *rawdb.ResettableFreezer, id uint64, address common.Address, slot common.Hash) ([]byte, error) {
+	_, accIndex, err := readAccountHistory(freezer, id, address)
+	if err != nil {
+		return nil, err
+	}
+	indexes := rawdb.ReadStateStorageIndex(freezer, id)
+	if uint64(len(indexes)) < (uint64(accIndex.storageOffset)+uint64(accIndex.storageSlots))*slotIndexSize {
+		return nil, fmt.Errorf("storage index of state history %d is corrupted", id)
+	}
+	var (
+		n     = int(accIndex.storageSlots)
+		start = int(accIndex.storageOffset) * slotIndexSize
+		pos   = sort.Search(n, func(i int) bool {
+			offset := start + i*slotIndexSize
+			return bytes.Compare(indexes[offset:offset+common.HashLength], slot.Bytes()) >= 0
+		})
+	)
+	if pos == n || !bytes.Equal(indexes[start+pos*slotIndexSize:start+pos*slotIndexSize+common.HashLength], slot.Bytes()) {
+		return nil, fmt.Errorf("storage %x:%x is not in state history %d", address, slot, id)
+	}
+	var index slotIndex
+	index.decode(indexes[start+pos*slotIndexSize : start+(pos+1)*slotIndexSize])
+
+	data := rawdb.ReadStateStorageHistory(freezer, id)
+	last := index.offset + uint32(index.length)
+	if uint32(len(data)) < last {
+		return nil, fmt.Errorf("storage data of state history %d is corrupted", id)
+	}
+	return data[index.offset:last], nil
+}
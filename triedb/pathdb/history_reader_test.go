@@ -0,0 +1,123 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func waitIndexed(t *testing.T, db *Database) {
+	for i := 0; i < 100; i++ {
+		if db.indexer.indexed() >= db.tree.bottom().stateID() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("State histories are not indexed")
+}
+
+func TestHistoricReader(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	// Reopen the database with history indexing enabled, the existing state
+	// histories should be indexed in the background.
+	if err := tester.db.Journal(tester.lastHash()); err != nil {
+		t.Fatalf("Failed to journal database, err: %v", err)
+	}
+	tester.db.Close()
+	tester.db = New(tester.db.diskdb, &Config{HistoryIndex: true}, false)
+	waitIndexed(t, tester.db)
+
+	// Extend the state, the newly written state histories should be indexed
+	// along with the commit.
+	for i := 0; i < 8; i++ {
+		parent := tester.lastHash()
+		root, nodes, states := tester.generate(parent)
+		if err := tester.db.Update(root, parent, uint64(len(tester.roots)), nodes, states); err != nil {
+			t.Fatalf("Failed to update state changes, err: %v", err)
+		}
+		tester.roots = append(tester.roots, root)
+	}
+	waitIndexed(t, tester.db)
+
+	bottom := tester.bottomIndex()
+	diskRoot := tester.roots[bottom]
+	for i := 0; i < bottom; i++ {
+		root := tester.roots[i]
+		reader, err := tester.db.HistoricReader(root)
+		if err != nil {
+			t.Fatalf("Failed to open historic reader, root: %x, err: %v", root, err)
+		}
+		if reader.DiskRoot() != diskRoot {
+			t.Fatalf("Unexpected disk root, want: %x, got: %x", diskRoot, reader.DiskRoot())
+		}
+		for addrHash, addr := range tester.preimages {
+			want := tester.snapAccounts[root][addrHash]
+			blob, found, err := reader.Account(addr)
+			if err != nil {
+				t.Fatalf("Failed to read account, err: %v", err)
+			}
+			if !found {
+				// Unresolved accounts must be unchanged since the historical state
+				blob = tester.snapAccounts[diskRoot][addrHash]
+			}
+			if !bytes.Equal(blob, want) {
+				t.Fatalf("Account is mismatched, root: %x, address: %x, want: %x, got: %x", root, addr, want, blob)
+			}
+			for slot, want := range tester.snapStorages[root][addrHash] {
+				blob, found, err := reader.Storage(addr, slot)
+				if err != nil {
+					t.Fatalf("Failed to read storage, err: %v", err)
+				}
+				if !found {
+					blob = tester.snapStorages[diskRoot][addrHash][slot]
+				}
+				if !bytes.Equal(blob, want) {
+					t.Fatalf("Storage is mismatched, root: %x, slot: %x, want: %x, got: %x", root, slot, want, blob)
+				}
+			}
+		}
+	}
+	// The states above the disk layer are not historical
+	if _, err := tester.db.HistoricReader(tester.lastHash()); err == nil {
+		t.Fatal("Expected error for non-historical state")
+	}
+	if _, err := tester.db.HistoricReader(common.Hash{0x1}); err == nil {
+		t.Fatal("Expected error for unknown state")
+	}
+}
+
+func TestHistoricReaderDisabled(t *testing.T) {
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if _, err := tester.db.HistoricReader(types.EmptyRootHash); err != errHistoryIndexDisabled {
+		t.Fatalf("Unexpected error, want: %v, got: %v", errHistoryIndexDisabled, err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/testrand"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -332,3 +333,111 @@ func compareStorageList(a, b map[common.Address][]common.Hash) bool {
 	}
 	return true
 }
+
+func TestVerifyStateHistory(t *testing.T) {
+	var (
+		hs         = makeHistories(10)
+		db         = rawdb.NewMemoryDatabase()
+		freezer, _ = openFreezer(t.TempDir(), false)
+		node       = testrand.Bytes(32)
+	)
+	defer freezer.Close()
+
+	// Link the last history to the persistent state
+	hs[len(hs)-1].meta.root = crypto.Keccak256Hash(node)
+	rawdb.WriteAccountTrieNode(db, nil, node)
+	rawdb.WritePersistentStateID(db, 8)
+
+	for i := 0; i < len(hs); i++ {
+		// Break the link between the second and the third history
+		if i == 2 {
+			hs[i].meta.parent = common.Hash{0x1}
+		}
+		accountData, storageData, accountIndex, storageIndex := hs[i].encode()
+		rawdb.WriteStateHistory(freezer, uint64(i+1), hs[i].meta.encode(), accountIndex, storageIndex, accountData, storageData)
+		if i != 5 {
+			rawdb.WriteStateID(db, hs[i].meta.root, uint64(i+1))
+		}
+	}
+	// The histories above the persistent state are disconnected from it
+	report, err := VerifyStateHistory(db, freezer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Healthy() || report.First != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	// Link the persistent state to the latest history
+	rawdb.WritePersistentStateID(db, 10)
+	if report, err = VerifyStateHistory(db, freezer); err != nil {
+		t.Fatal(err)
+	}
+	if report.First != 3 {
+		t.Fatalf("first reachable history mismatch: have %d, want 3", report.First)
+	}
+	if len(report.Lookups) != 1 || report.Lookups[0] != 6 {
+		t.Fatalf("broken lookups mismatch: have %v, want [6]", report.Lookups)
+	}
+	if _, err := RepairStateHistory(db, freezer, report); err != nil {
+		t.Fatal(err)
+	}
+	if report, err = VerifyStateHistory(db, freezer); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Healthy() || report.Tail != 2 || report.First != 3 {
+		t.Fatalf("unexpected report after repair: %+v", report)
+	}
+}
+
+func TestVerifyJournaledStateHistory(t *testing.T) {
+	// Redefine the diff layer depth allowance for faster testing.
+	maxDiffLayers = 4
+	defer func() {
+		maxDiffLayers = 128
+	}()
+
+	// Aggregate the bottom layers into the node buffer of the disk layer,
+	// large enough to never be flushed.
+	tester := newTester(t, 0)
+	defer tester.release()
+
+	if err := tester.db.SetBufferSize(maxBufferSize); err != nil {
+		t.Fatalf("Failed to resize node buffer: %v", err)
+	}
+	for i := 0; i < 4; i++ {
+		parent := tester.lastHash()
+		root, nodes, states := tester.generate(parent)
+		if err := tester.db.Update(root, parent, uint64(8+i), nodes, states); err != nil {
+			t.Fatalf("Failed to update state changes: %v", err)
+		}
+		tester.roots = append(tester.roots, root)
+	}
+	var (
+		dl      = tester.db.tree.bottom()
+		stored  = rawdb.ReadPersistentStateID(tester.db.diskdb)
+		ancient = tester.db.freezer
+	)
+	if stored >= dl.stateID() {
+		t.Fatalf("Node buffer is flushed: persistent state %d, disk layer %d", stored, dl.stateID())
+	}
+	if err := tester.db.Journal(tester.lastHash()); err != nil {
+		t.Fatalf("Failed to journal: %v", err)
+	}
+	report, err := VerifyStateHistory(tester.db.diskdb, ancient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Healthy() || report.StateID != dl.stateID() || report.Head != dl.stateID() || report.First != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if actions, err := RepairStateHistory(tester.db.diskdb, ancient, report); err != nil || len(actions) != 0 {
+		t.Fatalf("unexpected repair: %v, %v", actions, err)
+	}
+	// The histories must still be aligned with the restored disk layer
+	tester.db.Close()
+	tester.db = New(tester.db.diskdb, nil, false)
+
+	if head, err := tester.db.freezer.Ancients(); err != nil || head != dl.stateID() {
+		t.Fatalf("state histories truncated: head %d, want %d (%v)", head, dl.stateID(), err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pathdb
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// HistoryReport is the result of the state history verification.
+type HistoryReport struct {
+	Tail    uint64 `json:"tail"`    // Id of the last pruned history, the first one is Tail+1
+	Head    uint64 `json:"head"`    // Id of the latest history
+	StateID uint64 `json:"stateID"` // Id of the disk layer, the latest history should match
+
+	// First is the id of the oldest history reachable from the disk layer
+	// by following the parent roots, zero if none is reachable.
+	First uint64 `json:"first"`
+
+	// Lookups contains the ids of the reachable histories whose state root
+	// to id mapping is missing or wrong.
+	Lookups []uint64 `json:"lookups,omitempty"`
+
+	Issues []string `json:"issues,omitempty"`
+
+	roots map[uint64]common.Hash // State roots of the histories with broken lookups
+}
+
+// Healthy reports whether no inconsistency was found.
+func (r *HistoryReport) Healthy() bool {
+	return len(r.Issues) == 0
+}
+
+// diskLayerState resolves the root and the state id of the disk layer the
+// database is restored with on startup. The state histories are aligned with
+// it, rather than with the persistent state which lags behind by the state
+// transitions aggregated in the journaled node buffer.
+func diskLayerState(diskdb ethdb.Database) (common.Hash, uint64) {
+	db := New(diskdb, ReadOnly, false)
+	defer db.Close()
+
+	dl := db.tree.bottom()
+	return dl.rootHash(), dl.stateID()
+}
+
+// VerifyStateHistory checks the continuity of the state histories. Starting
+// from the disk layer, the histories are walked backwards, each one has to
+// end in the state its successor starts from. The histories which are not
+// reachable this way are useless for state rollback.
+func VerifyStateHistory(diskdb ethdb.Database, freezer ethdb.AncientReader) (*HistoryReport, error) {
+	head, err := freezer.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	tail, err := freezer.Tail()
+	if err != nil {
+		return nil, err
+	}
+	root, id := diskLayerState(diskdb)
+
+	report := &HistoryReport{
+		Tail:    tail,
+		Head:    head,
+		StateID: id,
+		roots:   make(map[uint64]common.Hash),
+	}
+	if head == tail {
+		return report, nil // No histories at all
+	}
+	last := head
+	switch {
+	case head > report.StateID:
+		report.Issues = append(report.Issues, fmt.Sprintf("%d state histories above the disk layer %d", head-max(report.StateID, tail), report.StateID))
+		if report.StateID <= tail {
+			return report, nil
+		}
+		last = report.StateID
+
+	case head < report.StateID:
+		report.Issues = append(report.Issues, fmt.Sprintf("state histories (%d, %d] missing below the disk layer", head, report.StateID))
+		return report, nil
+	}
+	// Walk the histories backwards in batches, starting from the disk layer
+	for end := last; end > tail; {
+		start := max(end-min(end-tail, 10000), tail) + 1
+		blobs, err := rawdb.ReadStateHistoryMetaList(freezer, start, end-start+1)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(blobs)) != end-start+1 {
+			return nil, fmt.Errorf("state histories [%d, %d] truncated", start, end)
+		}
+		for i := len(blobs) - 1; i >= 0; i-- {
+			id := start + uint64(i)
+
+			var m meta
+			if err := m.decode(blobs[i]); err != nil {
+				report.Issues = append(report.Issues, fmt.Sprintf("state history %d is corrupted: %v", id, err))
+				return report, nil
+			}
+			if m.root != root {
+				report.Issues = append(report.Issues, fmt.Sprintf("state history %d disconnected: root %x, want %x", id, m.root, root))
+				return report, nil
+			}
+			if stored := rawdb.ReadStateID(diskdb, m.root); stored == nil || *stored != id {
+				report.Lookups = append(report.Lookups, id)
+				report.roots[id] = m.root
+			}
+			report.First, root = id, m.parent
+		}
+		end = start - 1
+	}
+	if len(report.Lookups) > 0 {
+		report.Issues = append(report.Issues, fmt.Sprintf("%d state histories with missing state id lookups", len(report.Lookups)))
+	}
+	return report, nil
+}
+
+// RepairStateHistory fixes the inconsistencies found by VerifyStateHistory.
+// The histories above the disk layer and the unreachable ones are
+// truncated and the missing state id lookups are restored. If no history is
+// reachable, all of them are dropped, along with the in-memory layer journal
+// which refers to them. The returned list describes the applied fixes.
+func RepairStateHistory(diskdb ethdb.Database, freezer *rawdb.ResettableFreezer, report *HistoryReport) ([]string, error) {
+	if report.Healthy() {
+		return nil, nil
+	}
+	var actions []string
+
+	// Drop all histories if none of them is reachable, they are useless
+	if report.First == 0 {
+		batch := diskdb.NewBatch()
+		rawdb.DeleteTrieJournal(batch)
+		rawdb.WritePersistentStateID(batch, 0)
+		if err := batch.Write(); err != nil {
+			return nil, err
+		}
+		if err := freezer.Reset(); err != nil {
+			return nil, err
+		}
+		actions = append(actions, fmt.Sprintf("dropped all state histories (%d, %d] and the layer journal", report.Tail, report.Head))
+	} else {
+		if report.Head > report.StateID {
+			pruned, err := truncateFromHead(diskdb, freezer, report.StateID)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, fmt.Sprintf("truncated %d state histories above %d", pruned, report.StateID))
+		}
+		if report.First > report.Tail+1 {
+			pruned, err := truncateFromTail(diskdb, freezer, report.First-1)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, fmt.Sprintf("truncated %d unreachable state histories below %d", pruned, report.First))
+		}
+		if len(report.Lookups) > 0 {
+			batch := diskdb.NewBatch()
+			for _, id := range report.Lookups {
+				rawdb.WriteStateID(batch, report.roots[id], id)
+			}
+			if err := batch.Write(); err != nil {
+				return nil, err
+			}
+			actions = append(actions, fmt.Sprintf("restored %d state id lookups", len(report.Lookups)))
+		}
+	}
+	// The history indexes refer to the dropped histories, rebuild them
+	if rawdb.ReadStateHistoryIndexHead(diskdb) != nil {
+		if err := purgeHistoryIndexes(diskdb); err != nil {
+			return nil, err
+		}
+		actions = append(actions, "dropped the state history indexes for rebuild")
+	}
+	log.Info("Repaired state histories", "actions", len(actions))
+	return actions, nil
+}